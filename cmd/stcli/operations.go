@@ -8,6 +8,10 @@ package main
 
 import (
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
 
 	"github.com/urfave/cli"
 )
@@ -43,6 +47,18 @@ var operationCommand = cli.Command{
 			ArgsUsage: "[folder id]",
 			Action:    expects(1, foldersOverride),
 		},
+		{
+			Name:      "batch-export",
+			Usage:     "Export the changes to a folder after the given sequence to a change batch file",
+			ArgsUsage: "[folder id] [sequence] [file]",
+			Action:    expects(3, batchExport),
+		},
+		{
+			Name:      "batch-import",
+			Usage:     "Import a change batch file exported by another device",
+			ArgsUsage: "[file]",
+			Action:    expects(1, batchImport),
+		},
 	},
 }
 
@@ -76,3 +92,40 @@ func foldersOverride(c *cli.Context) error {
 	}
 	return fmt.Errorf("Folder " + rid + " not found")
 }
+
+func batchExport(c *cli.Context) error {
+	client := c.App.Metadata["client"].(*APIClient)
+	args := c.Args()
+	response, err := client.Get("db/batch?folder=" + url.QueryEscape(args[0]) + "&since=" + url.QueryEscape(args[1]))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	fd, err := os.Create(args[2])
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(fd, response.Body); err != nil {
+		fd.Close()
+		return err
+	}
+	return fd.Close()
+}
+
+func batchImport(c *cli.Context) error {
+	client := c.App.Metadata["client"].(*APIClient)
+	fd, err := os.Open(c.Args()[0])
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+	request, err := http.NewRequest("POST", client.Endpoint()+"rest/db/batch", fd)
+	if err != nil {
+		return err
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	return prettyPrintResponse(c, response)
+}
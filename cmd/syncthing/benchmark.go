@@ -0,0 +1,157 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/benchmark"
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/locations"
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/scanner"
+	"github.com/syncthing/syncthing/lib/syncthing"
+)
+
+const (
+	benchmarkUsage      = "syncthing benchmark [options]"
+	benchmarkExtraUsage = `
+Measures hashing throughput for each algorithm and block size, database
+insert and lookup rates in the configuration directory, and filesystem
+throughput and latency in the path of each configured folder, then prints
+a report. Please attach the report when filing performance issues.
+
+Files are written to temporary directories, which the scanner ignores and
+which are removed afterwards. Nothing is written to the real database.`
+)
+
+// benchmarkMain runs "syncthing benchmark" with the arguments after the
+// command.
+func benchmarkMain(args []string) {
+	var confDir, folderID string
+	var jsonOutput, noFolders bool
+	var dbFiles, fsFiles int
+	var fsSizeMiB int64
+	duration := time.Second
+
+	flags := flag.NewFlagSet("benchmark", flag.ExitOnError)
+	flags.StringVar(&confDir, "home", "", "Set configuration directory")
+	flags.DurationVar(&duration, "duration", duration, "Duration of each hashing measurement")
+	flags.IntVar(&dbFiles, "db-files", 10000, "Number of files to insert into the database")
+	flags.Int64Var(&fsSizeMiB, "fs-size", 64, "Size of the file to write and read in each folder, in MiB")
+	flags.IntVar(&fsFiles, "fs-files", 100, "Number of small files to write, read and rename in each folder")
+	flags.StringVar(&folderID, "folder", "", "Only measure the filesystem of this folder")
+	flags.BoolVar(&noFolders, "no-folders", false, "Don't measure the filesystem of any folder")
+	flags.BoolVar(&jsonOutput, "json", false, "Print the report as JSON")
+	flags.Usage = usageFor(flags, benchmarkUsage, benchmarkExtraUsage)
+	flags.Parse(args)
+
+	if flags.NArg() > 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	if confDir != "" {
+		if !filepath.IsAbs(confDir) {
+			var err error
+			confDir, err = filepath.Abs(confDir)
+			if err != nil {
+				l.Warnln("Failed to make options path absolute:", err)
+				os.Exit(syncthing.ExitError.AsInt())
+			}
+		}
+		if err := locations.SetBaseDir(locations.ConfigBaseDir, confDir); err != nil {
+			l.Warnln(err)
+			os.Exit(syncthing.ExitError.AsInt())
+		}
+	}
+
+	scanner.SelectHashBackends(nil)
+	report := benchmark.NewReport()
+
+	fmt.Fprintln(os.Stderr, "Measuring hashing...")
+	report.Add(benchmark.Hashing(duration)...)
+
+	// The database is measured where the real one is, if that exists.
+	dbDir := filepath.Dir(locations.Get(locations.Database))
+	if _, err := os.Stat(dbDir); err != nil {
+		dbDir = ""
+	}
+	fmt.Fprintln(os.Stderr, "Measuring the database...")
+	res, err := benchmark.Database(dbDir, dbFiles)
+	if err != nil {
+		l.Warnln("Database benchmark:", err)
+	}
+	report.Add(res...)
+
+	if !noFolders {
+		for _, fcfg := range benchmarkFolders(folderID) {
+			fmt.Fprintf(os.Stderr, "Measuring the filesystem of folder %s...\n", fcfg.Description())
+			res, err := benchmark.Filesystem(fcfg.Filesystem(), "folder "+fcfg.ID, fsSizeMiB<<20, fsFiles)
+			if err != nil {
+				l.Warnf("Filesystem benchmark of folder %s: %v", fcfg.Description(), err)
+			}
+			report.Add(res...)
+		}
+	}
+
+	if jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "    ")
+		err = enc.Encode(report)
+	} else {
+		err = report.WriteText(os.Stdout)
+	}
+	if err != nil {
+		l.Warnln("Writing report:", err)
+		os.Exit(syncthing.ExitError.AsInt())
+	}
+}
+
+// benchmarkFolders returns the configured folders with the given ID, or
+// all of them if it's empty, sorted by ID. Folders whose path is not
+// available are skipped.
+func benchmarkFolders(id string) []config.FolderConfiguration {
+	cert, err := tls.LoadX509KeyPair(locations.Get(locations.CertFile), locations.Get(locations.KeyFile))
+	if err != nil {
+		l.Warnln("No folders to measure, failed to load certificate:", err)
+		return nil
+	}
+	myID := protocol.NewDeviceID(cert.Certificate[0])
+	cfg, err := config.Load(locations.Get(locations.ConfigFile), myID, events.NoopLogger)
+	if err != nil {
+		l.Warnln("No folders to measure, failed to load configuration:", err)
+		return nil
+	}
+
+	var folders []config.FolderConfiguration
+	for _, fcfg := range cfg.Folders() {
+		if id != "" && fcfg.ID != id {
+			continue
+		}
+		if err := fcfg.CheckPath(); err != nil {
+			l.Warnf("Not measuring folder %s: %v", fcfg.Description(), err)
+			continue
+		}
+		folders = append(folders, fcfg)
+	}
+	if id != "" && len(folders) == 0 {
+		l.Warnf("Folder %q is not configured or not available", id)
+	}
+	sort.Slice(folders, func(a, b int) bool {
+		return folders[a].ID < folders[b].ID
+	})
+	return folders
+}
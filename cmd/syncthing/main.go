@@ -30,6 +30,7 @@ import (
 
 	"github.com/syncthing/syncthing/lib/build"
 	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/db/backend"
 	"github.com/syncthing/syncthing/lib/dialer"
 	"github.com/syncthing/syncthing/lib/events"
 	"github.com/syncthing/syncthing/lib/fs"
@@ -52,6 +53,12 @@ const (
 const (
 	usage      = "syncthing [options]"
 	extraUsage = `
+To run a relay server with the keys of this device instead, use "syncthing
+relay". See "syncthing relay -help" for its options.
+
+To measure the performance of hashing, the database and the filesystem of
+the configured folders, use "syncthing benchmark".
+
 The -logflags value is a sum of the following:
 
    1  Date
@@ -101,6 +108,9 @@ are mostly useful for developers. Use with care.
  STLOCKTHRESHOLD   Used for debugging internal deadlocks; sets debug
                    sensitivity.  Use only under direction of a developer.
 
+ STLOCKPROFILE     Set to a number N to sample the time waited for one in
+                   every N mutex locks, as shown by /rest/debug/locks.
+
  STNORESTART       Equivalent to the -no-restart argument. Disable the
                    Syncthing monitor process which handles restarts for some
                    configuration changes, upgrades, crashes and also log file
@@ -108,10 +118,12 @@ are mostly useful for developers. Use with care.
 
  STNOUPGRADE       Disable automatic upgrades.
 
- STHASHING         Select the SHA256 hashing package to use. Possible values
-                   are "standard" for the Go standard library implementation,
-                   "minio" for the github.com/minio/sha256-simd implementation,
-                   and blank (the default) for auto detection.
+ STHASHING         Select the hashing backend to use for its algorithm.
+                   Possible values are "standard" for the Go standard library
+                   SHA256 implementation, "minio" for the
+                   github.com/minio/sha256-simd implementation, the name of
+                   any other backend, and blank (the default) for selecting
+                   the fastest by a benchmark on the first run.
 
  STRECHECKDBEVERY  Set to a time interval to override the default database
                    check interval of 30 days (720h). The interval understands
@@ -248,6 +260,15 @@ func parseCommandLineOptions() RuntimeOptions {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "relay" {
+		relayMain(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "benchmark" {
+		benchmarkMain(os.Args[2:])
+		return
+	}
+
 	options := parseCommandLineOptions()
 	l.SetFlags(options.logFlags)
 
@@ -353,7 +374,8 @@ func main() {
 	}
 
 	if options.upgradeTo != "" {
-		err := upgrade.ToURL(options.upgradeTo)
+		cfg, _ := loadOrDefaultConfig(protocol.EmptyDeviceID, events.NoopLogger)
+		err := upgrade.ToURL(options.upgradeTo, cfg.Options().UpgradeVerification())
 		if err != nil {
 			l.Warnln("Error while Upgrading:", err)
 			os.Exit(syncthing.ExitError.AsInt())
@@ -485,10 +507,11 @@ func checkUpgrade() upgrade.Release {
 }
 
 func performUpgrade(release upgrade.Release) {
-	// Use leveldb database locks to protect against concurrent upgrades
-	_, err := syncthing.OpenGoleveldb(locations.Get(locations.Database), config.TuningAuto)
+	// Use database locks to protect against concurrent upgrades
+	cfg, _ := loadOrDefaultConfig(protocol.EmptyDeviceID, events.NoopLogger)
+	_, err := syncthing.OpenDatabase(locations.Get(locations.Database), cfg.Options().DatabaseBackend, config.TuningAuto)
 	if err == nil {
-		err = upgrade.To(release)
+		err = upgrade.To(release, cfg.Options().UpgradeVerification())
 		if err != nil {
 			l.Warnln("Upgrade:", err)
 			os.Exit(syncthing.ExitError.AsInt())
@@ -578,7 +601,7 @@ func syncthingMain(runtimeOptions RuntimeOptions) {
 	}
 
 	dbFile := locations.Get(locations.Database)
-	ldb, err := syncthing.OpenGoleveldb(dbFile, cfg.Options().DatabaseTuning)
+	ldb, err := syncthing.OpenDatabase(dbFile, cfg.Options().DatabaseBackend, cfg.Options().DatabaseTuning)
 	if err != nil {
 		l.Warnln("Error opening database:", err)
 		os.Exit(1)
@@ -729,7 +752,12 @@ func auditWriter(auditFile string) io.Writer {
 }
 
 func resetDB() error {
-	return os.RemoveAll(locations.Get(locations.Database))
+	for _, loc := range backend.Locations(locations.Get(locations.Database)) {
+		if err := os.RemoveAll(loc); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func ensureDir(dir string, mode fs.FileMode) error {
@@ -817,7 +845,7 @@ func autoUpgrade(cfg config.Wrapper, app *syncthing.App, evLogger events.Logger)
 		}
 
 		l.Infof("Automatic upgrade (current %q < latest %q)", build.Version, rel.Tag)
-		err = upgrade.To(rel)
+		err = upgrade.To(rel, opts.UpgradeVerification())
 		if err != nil {
 			l.Warnln("Automatic upgrade:", err)
 			timer.Reset(checkInterval)
@@ -0,0 +1,128 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/syncthing/syncthing/lib/build"
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/locations"
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/relay/server"
+	"github.com/syncthing/syncthing/lib/syncthing"
+)
+
+const (
+	relayUsage      = "syncthing relay [options]"
+	relayExtraUsage = `
+Runs a relay server, like strelaysrv, using the keys of this device. The
+relay ID is then the device ID. Unless pools are given, the relay is private:
+to use it, add the URI it logs on start to the listen addresses of your
+devices.
+
+The NAT settings (whether to use UPnP/NAT-PMP, lease, renewal and timeout)
+are taken from the configuration.`
+)
+
+// relayMain runs "syncthing relay" with the arguments after the command.
+func relayMain(args []string) {
+	log.SetFlags(log.LstdFlags)
+
+	var confDir, poolAddrs string
+	opts := server.DefaultOptions()
+
+	flags := flag.NewFlagSet("relay", flag.ExitOnError)
+	flags.StringVar(&confDir, "home", "", "Set configuration directory")
+	flags.StringVar(&opts.Listen, "listen", opts.Listen, "Protocol listen address")
+	flags.StringVar(&opts.ExtAddress, "ext-address", "", "An optional address to advertise as being available on")
+	flags.StringVar(&poolAddrs, "pools", "", "Comma separated list of relay pool addresses to join (blank to keep the relay private)")
+	flags.StringVar(&opts.StatusAddr, "status-srv", "", "Listen address for status service (blank to disable)")
+	flags.IntVar(&opts.SessionLimitBps, "per-session-rate", opts.SessionLimitBps, "Per session rate limit, in bytes/s")
+	flags.IntVar(&opts.GlobalLimitBps, "global-rate", opts.GlobalLimitBps, "Global rate limit, in bytes/s")
+	flags.StringVar(&opts.ProvidedBy, "provided-by", "", "An optional description about who provides the relay")
+	flags.BoolVar(&opts.Debug, "debug", false, "Enable debug output")
+	flags.Usage = usageFor(flags, relayUsage, relayExtraUsage)
+	flags.Parse(args)
+
+	if flags.NArg() > 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	opts.Pools = nil
+	for _, pool := range strings.Split(poolAddrs, ",") {
+		pool = strings.TrimSpace(pool)
+		if len(pool) > 0 {
+			opts.Pools = append(opts.Pools, pool)
+		}
+	}
+
+	if confDir != "" {
+		if !filepath.IsAbs(confDir) {
+			var err error
+			confDir, err = filepath.Abs(confDir)
+			if err != nil {
+				l.Warnln("Failed to make options path absolute:", err)
+				os.Exit(syncthing.ExitError.AsInt())
+			}
+		}
+		if err := locations.SetBaseDir(locations.ConfigBaseDir, confDir); err != nil {
+			l.Warnln(err)
+			os.Exit(syncthing.ExitError.AsInt())
+		}
+	}
+
+	if err := ensureDir(locations.GetBaseDir(locations.ConfigBaseDir), 0700); err != nil {
+		l.Warnln("Failure on home directory:", err)
+		os.Exit(syncthing.ExitError.AsInt())
+	}
+
+	cert, err := syncthing.LoadOrGenerateCertificate(
+		locations.Get(locations.CertFile),
+		locations.Get(locations.KeyFile),
+	)
+	if err != nil {
+		l.Warnln("Failed to load/generate certificate:", err)
+		os.Exit(syncthing.ExitError.AsInt())
+	}
+	myID := protocol.NewDeviceID(cert.Certificate[0])
+
+	// Without a config yet, the defaults will do.
+	cfgOpts := config.New(myID).Options
+	if cfg, err := config.Load(locations.Get(locations.ConfigFile), myID, events.NoopLogger); err == nil {
+		cfgOpts = cfg.Options()
+	}
+	opts.NATEnabled = cfgOpts.NATEnabled
+	opts.NATLeaseM = cfgOpts.NATLeaseM
+	opts.NATRenewalM = cfgOpts.NATRenewalM
+	opts.NATTimeoutS = cfgOpts.NATTimeoutS
+
+	log.Println(build.LongVersion)
+	log.Println("Relay ID:", myID)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		cancel()
+	}()
+
+	if err := server.Serve(ctx, cert, opts); err != nil {
+		l.Warnln("Relay:", err)
+		os.Exit(syncthing.ExitError.AsInt())
+	}
+}
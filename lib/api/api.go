@@ -17,6 +17,7 @@ import (
 	"io"
 	"io/ioutil"
 	"log"
+	"mime"
 	"net"
 	"net/http"
 	"net/url"
@@ -37,12 +38,14 @@ import (
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/syncthing/syncthing/lib/build"
+	"github.com/syncthing/syncthing/lib/chaos"
 	"github.com/syncthing/syncthing/lib/config"
 	"github.com/syncthing/syncthing/lib/connections"
 	"github.com/syncthing/syncthing/lib/db"
 	"github.com/syncthing/syncthing/lib/discover"
 	"github.com/syncthing/syncthing/lib/events"
 	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/ignore"
 	"github.com/syncthing/syncthing/lib/locations"
 	"github.com/syncthing/syncthing/lib/logger"
 	"github.com/syncthing/syncthing/lib/model"
@@ -59,8 +62,9 @@ import (
 var bcryptExpr = regexp.MustCompile(`^\$2[aby]\$\d+\$.{50,}`)
 
 const (
-	DefaultEventMask      = events.AllEvents &^ events.LocalChangeDetected &^ events.RemoteChangeDetected
-	DiskEventMask         = events.LocalChangeDetected | events.RemoteChangeDetected
+	DefaultEventMask      = events.AllEvents &^ events.LocalChangeDetected &^ events.RemoteChangeDetected &^ events.FolderScanSummary
+	DiskEventMask         = events.LocalChangeDetected | events.RemoteChangeDetected | events.FolderScanSummary
+	CriticalEventMask     = events.CriticalEvents
 	EventSubBufferSize    = 1000
 	defaultEventTimeout   = time.Minute
 	httpsCertLifetimeDays = 820
@@ -72,6 +76,8 @@ type service struct {
 	id                   protocol.DeviceID
 	cfg                  config.Wrapper
 	statics              *staticsServer
+	webdav               *webdavServer
+	shares               *shareServer
 	model                model.Model
 	eventSubs            map[events.EventType]events.BufferedSubscription
 	eventSubsMut         sync.Mutex
@@ -111,15 +117,18 @@ type Service interface {
 	WaitForStart() error
 }
 
-func New(id protocol.DeviceID, cfg config.Wrapper, assetDir, tlsDefaultCommonName string, m model.Model, defaultSub, diskSub events.BufferedSubscription, evLogger events.Logger, discoverer discover.CachingMux, connectionsService connections.Service, urService *ur.Service, fss model.FolderSummaryService, errors, systemLog logger.Recorder, cpu Rater, contr Controller, noUpgrade bool) Service {
+func New(id protocol.DeviceID, cfg config.Wrapper, assetDir, tlsDefaultCommonName string, m model.Model, defaultSub, diskSub, criticalSub events.BufferedSubscription, evLogger events.Logger, discoverer discover.CachingMux, connectionsService connections.Service, urService *ur.Service, fss model.FolderSummaryService, errors, systemLog logger.Recorder, cpu Rater, contr Controller, noUpgrade bool) Service {
 	s := &service{
 		id:      id,
 		cfg:     cfg,
 		statics: newStaticsServer(cfg.GUI().Theme, assetDir),
+		webdav:  newWebDAVServer(cfg, m),
+		shares:  newShareServer(cfg, locations.Get(locations.ShareLinks)),
 		model:   m,
 		eventSubs: map[events.EventType]events.BufferedSubscription{
-			DefaultEventMask: defaultSub,
-			DiskEventMask:    diskSub,
+			DefaultEventMask:  defaultSub,
+			DiskEventMask:     diskSub,
+			CriticalEventMask: criticalSub,
 		},
 		eventSubsMut:         sync.NewMutex(),
 		evLogger:             evLogger,
@@ -249,11 +258,27 @@ func (s *service) serve(ctx context.Context) {
 	getRestMux.HandleFunc("/rest/db/localchanged", s.getDBLocalChanged)          // folder
 	getRestMux.HandleFunc("/rest/db/status", s.getDBStatus)                      // folder
 	getRestMux.HandleFunc("/rest/db/browse", s.getDBBrowse)                      // folder [prefix] [dirsonly] [levels]
+	getRestMux.HandleFunc("/rest/db/batch", s.getDBBatch)                        // folder [since]
+	getRestMux.HandleFunc("/rest/db/divergence", s.getDBDivergence)              // folder device
+	getRestMux.HandleFunc("/rest/db/history", s.getDBHistory)                    // folder file
+	getRestMux.HandleFunc("/rest/db/audit", s.getDBAudit)                        // folder [since] [limit]
+	getRestMux.HandleFunc("/rest/db/audit/export", s.getDBAuditExport)           // folder
+	getRestMux.HandleFunc("/rest/db/snapshot", s.getDBSnapshot)                  // folder
+	getRestMux.HandleFunc("/rest/db/snapshot/diff", s.getDBSnapshotDiff)         // folder name
 	getRestMux.HandleFunc("/rest/folder/versions", s.getFolderVersions)          // folder
 	getRestMux.HandleFunc("/rest/folder/errors", s.getFolderErrors)              // folder
+	getRestMux.HandleFunc("/rest/folder/undo", s.getFolderUndo)                  // folder
+	getRestMux.HandleFunc("/rest/folder/skipped", s.getFolderSkipped)            // folder
+	getRestMux.HandleFunc("/rest/folder/stream", s.getFolderStream)              // folder file
+	getRestMux.HandleFunc("/rest/folder/shares", s.getFolderShares)              // [folder]
+	getRestMux.HandleFunc("/rest/folder/pushed", s.getFolderPushed)              // -
+	getRestMux.HandleFunc("/rest/folder/presets", s.getFolderPresets)            // -
+	getRestMux.HandleFunc("/rest/folder/export", s.getFolderExport)              // folder [prefix] [format] [at]
+	getRestMux.HandleFunc("/rest/folder/ignores/audit", s.getFolderIgnoresAudit) // folder
 	getRestMux.HandleFunc("/rest/folder/pullerrors", s.getFolderErrors)          // folder (deprecated)
 	getRestMux.HandleFunc("/rest/events", s.getIndexEvents)                      // [since] [limit] [timeout] [events]
 	getRestMux.HandleFunc("/rest/events/disk", s.getDiskEvents)                  // [since] [limit] [timeout]
+	getRestMux.HandleFunc("/rest/events/critical", s.getCriticalEvents)          // [since] [limit] [timeout]
 	getRestMux.HandleFunc("/rest/stats/device", s.getDeviceStats)                // -
 	getRestMux.HandleFunc("/rest/stats/folder", s.getFolderStats)                // -
 	getRestMux.HandleFunc("/rest/svc/deviceid", s.getDeviceID)                   // id
@@ -271,17 +296,36 @@ func (s *service) serve(ctx context.Context) {
 	getRestMux.HandleFunc("/rest/system/upgrade", s.getSystemUpgrade)            // -
 	getRestMux.HandleFunc("/rest/system/version", s.getSystemVersion)            // -
 	getRestMux.HandleFunc("/rest/system/debug", s.getSystemDebug)                // -
+	getRestMux.HandleFunc("/rest/system/decommission", s.getSystemDecommission)  // -
 	getRestMux.HandleFunc("/rest/system/log", s.getSystemLog)                    // [since]
 	getRestMux.HandleFunc("/rest/system/log.txt", s.getSystemLogTxt)             // [since]
+	getRestMux.HandleFunc("/rest/system/db/maintenance", s.getDBMaintenance)     // -
+	getRestMux.HandleFunc("/rest/system/db/usage", s.getDBUsage)                 // -
 
 	// The POST handlers
 	postRestMux := http.NewServeMux()
 	postRestMux.HandleFunc("/rest/db/prio", s.postDBPrio)                          // folder file [perpage] [page]
+	postRestMux.HandleFunc("/rest/db/prioritize", s.postDBPrioritize)              // folder file [requests]
 	postRestMux.HandleFunc("/rest/db/ignores", s.postDBIgnores)                    // folder
 	postRestMux.HandleFunc("/rest/db/override", s.postDBOverride)                  // folder
 	postRestMux.HandleFunc("/rest/db/revert", s.postDBRevert)                      // folder
+	postRestMux.HandleFunc("/rest/db/batch", s.postDBBatch)                        // <body>
+	postRestMux.HandleFunc("/rest/db/hydrate", s.postDBHydrate)                    // folder file
 	postRestMux.HandleFunc("/rest/db/scan", s.postDBScan)                          // folder [sub...] [delay]
+	postRestMux.HandleFunc("/rest/db/remote-scan", s.postDBRemoteScan)             // device folder [sub...]
+	postRestMux.HandleFunc("/rest/db/snapshot", s.postDBSnapshot)                  // folder name
+	postRestMux.HandleFunc("/rest/db/snapshot/rollback", s.postDBSnapshotRollback) // folder name
+	postRestMux.HandleFunc("/rest/db/snapshot/drop", s.postDBSnapshotDrop)         // folder name
 	postRestMux.HandleFunc("/rest/folder/versions", s.postFolderVersionsRestore)   // folder <body>
+	postRestMux.HandleFunc("/rest/folder/undo", s.postFolderUndo)                  // folder [id]
+	postRestMux.HandleFunc("/rest/folder/share", s.postFolderShare)                // folder file [expires] <body>
+	postRestMux.HandleFunc("/rest/folder/unshare", s.postFolderUnshare)            // token
+	postRestMux.HandleFunc("/rest/folder/pushed", s.postFolderPushed)              // folder [reject]
+	postRestMux.HandleFunc("/rest/folder/presets", s.postFolderPresets)            // folder [preset...]
+	postRestMux.HandleFunc("/rest/folder/mkdir", s.postFolderMkdir)                // folder path
+	postRestMux.HandleFunc("/rest/folder/rename", s.postFolderRename)              // folder from to
+	postRestMux.HandleFunc("/rest/folder/delete", s.postFolderDelete)              // folder path
+	postRestMux.HandleFunc("/rest/folder/ignores/test", s.postFolderIgnoresTest)   // folder <body>
 	postRestMux.HandleFunc("/rest/system/config", s.postSystemConfig)              // <body>
 	postRestMux.HandleFunc("/rest/system/error", s.postSystemError)                // <body>
 	postRestMux.HandleFunc("/rest/system/error/clear", s.postSystemErrorClear)     // -
@@ -293,6 +337,10 @@ func (s *service) serve(ctx context.Context) {
 	postRestMux.HandleFunc("/rest/system/pause", s.makeDevicePauseHandler(true))   // [device]
 	postRestMux.HandleFunc("/rest/system/resume", s.makeDevicePauseHandler(false)) // [device]
 	postRestMux.HandleFunc("/rest/system/debug", s.postSystemDebug)                // [enable] [disable]
+	postRestMux.HandleFunc("/rest/system/message", s.postSystemMessage)            // device type <body>
+	postRestMux.HandleFunc("/rest/system/decommission", s.postSystemDecommission)  // device [reject]
+	postRestMux.HandleFunc("/rest/system/db/maintenance/compact", s.postDBCompact) // -
+	postRestMux.HandleFunc("/rest/system/db/maintenance/check", s.postDBCheck)     // folder [repair]
 
 	// Debug endpoints, not for general use
 	debugMux := http.NewServeMux()
@@ -301,8 +349,16 @@ func (s *service) serve(ctx context.Context) {
 	debugMux.HandleFunc("/rest/debug/cpuprof", s.getCPUProf) // duration
 	debugMux.HandleFunc("/rest/debug/heapprof", s.getHeapProf)
 	debugMux.HandleFunc("/rest/debug/support", s.getSupportBundle)
+	debugMux.HandleFunc("/rest/debug/puller", s.getDebugPuller) // folder [queued]
+	debugMux.HandleFunc("/rest/debug/chaos", s.getDebugChaos)
+	debugMux.HandleFunc("/rest/debug/locks", s.getDebugLocks)
 	getRestMux.Handle("/rest/debug/", s.whenDebugging(debugMux))
 
+	debugPostMux := http.NewServeMux()
+	debugPostMux.HandleFunc("/rest/debug/chaos", s.postDebugChaos) // <body>
+	debugPostMux.HandleFunc("/rest/debug/locks", s.postDebugLocks)
+	postRestMux.Handle("/rest/debug/", s.whenDebugging(debugPostMux))
+
 	// A handler that splits requests between the two above and disables
 	// caching
 	restMux := noCacheMiddleware(metricsMiddleware(getPostHandler(getRestMux, postRestMux)))
@@ -311,6 +367,7 @@ func (s *service) serve(ctx context.Context) {
 	mux := http.NewServeMux()
 	mux.Handle("/rest/", restMux)
 	mux.HandleFunc("/qr/", s.getQR)
+	mux.Handle(webdavPrefix, s.webdav)
 
 	// Serve compiled in assets unless an asset directory was set (for development)
 	mux.Handle("/", s.statics)
@@ -327,11 +384,20 @@ func (s *service) serve(ctx context.Context) {
 	// Add our version and ID as a header to responses
 	handler = withDetailsMiddleware(s.id, handler)
 
-	// Wrap everything in basic auth, if user/password is set.
-	if guiCfg.IsAuthEnabled() {
+	// Check the authorizers registered for the routes, if any, once we know
+	// who the request is from.
+	handler = authorizeMiddleware(handler)
+
+	// Wrap everything in basic auth, if user/password is set or there are
+	// authenticators registered.
+	if guiCfg.IsAuthEnabled() || len(authenticators) > 0 {
 		handler = basicAuthAndSessionMiddleware("sessionid-"+s.id.String()[:5], guiCfg, s.cfg.LDAP(), handler, s.evLogger)
 	}
 
+	// Share links are for people without access to the GUI and carry
+	// their own authorization, so they skip the above.
+	handler = shareMiddleware(s.shares, handler)
+
 	// Redirect to HTTPS if we are supposed to
 	if guiCfg.UseTLS() {
 		handler = redirectToHTTPSMiddleware(handler)
@@ -485,8 +551,9 @@ func corsMiddleware(next http.Handler, allowFrameLoading bool) http.Handler {
 	//
 	// See https://www.w3.org/TR/cors/ for details.
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Process OPTIONS requests
-		if r.Method == "OPTIONS" {
+		// Process OPTIONS requests, except for WebDAV where they are part
+		// of the protocol.
+		if r.Method == "OPTIONS" && !strings.HasPrefix(r.URL.Path, webdavPrefix) {
 			// Add a generous access-control-allow-origin header for CORS requests
 			w.Header().Add("Access-Control-Allow-Origin", "*")
 			// Only GET/POST Methods are supported
@@ -952,6 +1019,31 @@ func (s *service) postSystemError(w http.ResponseWriter, r *http.Request) {
 	l.Warnln(string(bs))
 }
 
+// postSystemMessage sends the body as an application message to the
+// device, where it shows up as an ApplicationMessageReceived event.
+func (s *service) postSystemMessage(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	device, err := protocol.DeviceIDFromString(qs.Get("device"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(r.Body, protocol.MaxApplicationMessageSize+1))
+	r.Body.Close()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(data) > protocol.MaxApplicationMessageSize {
+		http.Error(w, "message too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err := s.model.SendApplicationMessage(device, qs.Get("type"), data); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+}
+
 func (s *service) postSystemErrorClear(w http.ResponseWriter, r *http.Request) {
 	s.guiErrors.Clear()
 }
@@ -1011,13 +1103,15 @@ func (s *service) getSupportBundle(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Panic files
-	if panicFiles, err := filepath.Glob(filepath.Join(locations.GetBaseDir(locations.ConfigBaseDir), "panic*")); err == nil {
-		for _, f := range panicFiles {
-			if panicFile, err := ioutil.ReadFile(f); err != nil {
-				l.Warnf("Support bundle: failed to load %s: %s", filepath.Base(f), err)
-			} else {
-				files = append(files, fileEntry{name: filepath.Base(f), data: panicFile})
+	// Panic files and diagnostics for folders that got stuck
+	for _, pattern := range []string{"panic*", "stuck-*"} {
+		if panicFiles, err := filepath.Glob(filepath.Join(locations.GetBaseDir(locations.ConfigBaseDir), pattern)); err == nil {
+			for _, f := range panicFiles {
+				if panicFile, err := ioutil.ReadFile(f); err != nil {
+					l.Warnf("Support bundle: failed to load %s: %s", filepath.Base(f), err)
+				} else {
+					files = append(files, fileEntry{name: filepath.Base(f), data: panicFile})
+				}
 			}
 		}
 	}
@@ -1154,6 +1248,7 @@ func (s *service) getDBIgnores(w http.ResponseWriter, r *http.Request) {
 	sendJSON(w, map[string][]string{
 		"ignore":   ignores,
 		"expanded": patterns,
+		"shared":   s.model.SharedIgnores(folder),
 	})
 }
 
@@ -1183,6 +1278,39 @@ func (s *service) postDBIgnores(w http.ResponseWriter, r *http.Request) {
 	s.getDBIgnores(w, r)
 }
 
+// postFolderIgnoresTest tells which paths the ignore patterns in the body
+// would ignore, and why. The body is an object with "ignore", the patterns
+// to test instead of the current ones, and "paths", the paths to test
+// instead of those in the database.
+func (s *service) postFolderIgnoresTest(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		Ignore []string `json:"ignore"`
+		Paths  []string `json:"paths"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	explanations, err := s.model.ExplainIgnores(r.URL.Query().Get("folder"), data.Ignore, data.Paths)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	sendJSON(w, explanations)
+}
+
+// getFolderIgnoresAudit reports the ignore patterns of the folder that are
+// repeated, shadowed by earlier ones or match nothing on disk.
+func (s *service) getFolderIgnoresAudit(w http.ResponseWriter, r *http.Request) {
+	entries, err := s.model.AuditIgnores(r.URL.Query().Get("folder"))
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	sendJSON(w, entries)
+}
+
 func (s *service) getIndexEvents(w http.ResponseWriter, r *http.Request) {
 	s.fss.OnEventRequest()
 	mask := s.getEventMask(r.URL.Query().Get("events"))
@@ -1195,6 +1323,11 @@ func (s *service) getDiskEvents(w http.ResponseWriter, r *http.Request) {
 	s.getEvents(w, r, sub)
 }
 
+func (s *service) getCriticalEvents(w http.ResponseWriter, r *http.Request) {
+	sub := s.getEventSub(CriticalEventMask)
+	s.getEvents(w, r, sub)
+}
+
 func (s *service) getEvents(w http.ResponseWriter, r *http.Request, eventSub events.BufferedSubscription) {
 	qs := r.URL.Query()
 	sinceStr := qs.Get("since")
@@ -1305,7 +1438,7 @@ func (s *service) postSystemUpgrade(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if upgrade.CompareVersions(rel.Tag, build.Version) > upgrade.Equal {
-		err = upgrade.To(rel)
+		err = upgrade.To(rel, opts.UpgradeVerification())
 		if err != nil {
 			l.Warnln("upgrading:", err)
 			http.Error(w, err.Error(), 500)
@@ -1317,6 +1450,31 @@ func (s *service) postSystemUpgrade(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// getSystemDecommission returns the suggestions of other devices to
+// decommission devices that wait to be accepted.
+func (s *service) getSystemDecommission(w http.ResponseWriter, r *http.Request) {
+	sendJSON(w, s.model.PendingDecommissions())
+}
+
+// postSystemDecommission decommissions the device, or rejects the
+// suggestion to do so.
+func (s *service) postSystemDecommission(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	device, err := protocol.DeviceIDFromString(qs.Get("device"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if reject, _ := strconv.ParseBool(qs.Get("reject")); reject {
+		err = s.model.ResolveDecommission(device, false)
+	} else {
+		err = s.model.DecommissionDevice(device)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
 func (s *service) makeDevicePauseHandler(paused bool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var qs = r.URL.Query()
@@ -1377,6 +1535,19 @@ func (s *service) postDBScan(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (s *service) postDBRemoteScan(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	device, err := protocol.DeviceIDFromString(qs.Get("device"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.model.RequestRemoteScan(device, qs.Get("folder"), qs["sub"]); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+}
+
 func (s *service) postDBPrio(w http.ResponseWriter, r *http.Request) {
 	qs := r.URL.Query()
 	folder := qs.Get("folder")
@@ -1385,6 +1556,21 @@ func (s *service) postDBPrio(w http.ResponseWriter, r *http.Request) {
 	s.getDBNeed(w, r)
 }
 
+func (s *service) postDBPrioritize(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	folder := qs.Get("folder")
+	file := qs.Get("file")
+	if file == "" {
+		http.Error(w, "no file given", http.StatusBadRequest)
+		return
+	}
+	bumpRequests := qs.Get("requests") != ""
+	if err := s.model.Prioritize(folder, file, bumpRequests); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+}
+
 func (s *service) getQR(w http.ResponseWriter, r *http.Request) {
 	var qs = r.URL.Query()
 	var text = qs.Get("text")
@@ -1422,6 +1608,59 @@ func (s *service) getPeerCompletion(w http.ResponseWriter, r *http.Request) {
 	sendJSON(w, comp)
 }
 
+func (s *service) getDebugPuller(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	queued, err := strconv.Atoi(qs.Get("queued"))
+	if err != nil || queued < 0 {
+		queued = 100
+	}
+	state, err := s.model.PullerState(qs.Get("folder"), queued)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	sendJSON(w, state)
+}
+
+func (s *service) getDebugChaos(w http.ResponseWriter, r *http.Request) {
+	if !chaos.Enabled {
+		http.Error(w, chaos.ErrDisabledByCompilation.Error(), http.StatusNotImplemented)
+		return
+	}
+	sendJSON(w, chaos.Current())
+}
+
+func (s *service) postDebugChaos(w http.ResponseWriter, r *http.Request) {
+	if !chaos.Enabled {
+		http.Error(w, chaos.ErrDisabledByCompilation.Error(), http.StatusNotImplemented)
+		return
+	}
+	var faults chaos.Faults
+	err := json.NewDecoder(r.Body).Decode(&faults)
+	r.Body.Close()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := chaos.Set(faults); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	sendJSON(w, chaos.Current())
+}
+
+func (s *service) getDebugLocks(w http.ResponseWriter, r *http.Request) {
+	rate, locks := sync.LockProfile()
+	sendJSON(w, map[string]interface{}{
+		"sampleRate": rate,
+		"locks":      locks,
+	})
+}
+
+func (s *service) postDebugLocks(w http.ResponseWriter, r *http.Request) {
+	sync.ResetLockProfile()
+}
+
 func (s *service) getFolderVersions(w http.ResponseWriter, r *http.Request) {
 	qs := r.URL.Query()
 	versions, err := s.model.GetFolderVersions(qs.Get("folder"))
@@ -1432,6 +1671,520 @@ func (s *service) getFolderVersions(w http.ResponseWriter, r *http.Request) {
 	sendJSON(w, versions)
 }
 
+func (s *service) getFolderUndo(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	entries, err := s.model.UndoEntries(qs.Get("folder"))
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	sendJSON(w, entries)
+}
+
+func (s *service) getFolderSkipped(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	skipped, err := s.model.SkippedChanges(qs.Get("folder"))
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	sendJSON(w, skipped)
+}
+
+func (s *service) getFolderStream(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	folder := qs.Get("folder")
+	file := qs.Get("file")
+
+	gf, ok := s.model.CurrentGlobalFile(folder, file)
+	if !ok || gf.IsDeleted() || gf.IsInvalid() || gf.Type != protocol.FileInfoTypeFile {
+		http.Error(w, "no such file", http.StatusNotFound)
+		return
+	}
+
+	// The contents are streamed as they arrive from the other devices, so
+	// by the time an error happens the headers have been sent and all we
+	// can do is to cut the response short.
+	ctype := mime.TypeByExtension(filepath.Ext(gf.Name))
+	if ctype == "" {
+		ctype = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", ctype)
+	w.Header().Set("Content-Length", strconv.FormatInt(gf.Size, 10))
+	w.Header().Set("Last-Modified", gf.ModTime().UTC().Format(http.TimeFormat))
+	if err := s.model.StreamFile(r.Context(), folder, gf, w); err != nil {
+		l.Infof("Streaming %s in folder %q: %v", gf.Name, folder, err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+func (s *service) getFolderExport(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	folder := qs.Get("folder")
+
+	var at time.Time
+	if atStr := qs.Get("at"); atStr != "" {
+		var err error
+		at, err = time.Parse(time.RFC3339, atStr)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	format := qs.Get("format")
+	if format == "" {
+		format = model.ExportFormatTar
+	}
+	ctype := "application/x-tar"
+	if format == model.ExportFormatZip {
+		ctype = "application/zip"
+	}
+	w.Header().Set("Content-Type", ctype)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": folder + "." + format}))
+
+	// The archive is streamed as it's made, so once it has started all we
+	// can do about an error is to cut the response short.
+	cw := &countingWriter{w: w}
+	if err := s.model.ExportFolder(folder, qs.Get("prefix"), format, at, cw); err != nil {
+		if cw.n > 0 {
+			l.Infof("Exporting folder %q: %v", folder, err)
+			panic(http.ErrAbortHandler)
+		}
+		w.Header().Del("Content-Disposition")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(bs []byte) (int, error) {
+	n, err := c.w.Write(bs)
+	c.n += int64(n)
+	return n, err
+}
+
+func (s *service) getFolderShares(w http.ResponseWriter, r *http.Request) {
+	links := s.shares.list(r.URL.Query().Get("folder"))
+	res := make([]map[string]interface{}, len(links))
+	for i, link := range links {
+		res[i] = jsonShareLink(link)
+	}
+	sendJSON(w, res)
+}
+
+// getFolderPushed returns the folder settings pushed by other devices that
+// wait to be accepted.
+func (s *service) getFolderPushed(w http.ResponseWriter, r *http.Request) {
+	sendJSON(w, s.model.PendingFolderSettings())
+}
+
+func (s *service) postFolderPushed(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	reject, _ := strconv.ParseBool(qs.Get("reject"))
+	if err := s.model.ResolveFolderSettings(qs.Get("folder"), !reject); err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+}
+
+func (s *service) getFolderPresets(w http.ResponseWriter, r *http.Request) {
+	presets := make(map[string][]string)
+	for _, name := range ignore.PresetNames() {
+		presets[name], _ = ignore.Preset(name)
+	}
+	sendJSON(w, presets)
+}
+
+// postFolderPresets sets the ignore presets the folder uses, replacing any
+// it used before.
+func (s *service) postFolderPresets(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	cfg, ok := s.cfg.Folder(qs.Get("folder"))
+	if !ok {
+		http.Error(w, "no such folder", http.StatusNotFound)
+		return
+	}
+	for _, name := range qs["preset"] {
+		if _, ok := ignore.Preset(name); !ok {
+			http.Error(w, fmt.Sprintf("unknown preset %q", name), http.StatusBadRequest)
+			return
+		}
+	}
+
+	cfg.IgnorePresets = qs["preset"]
+	waiter, err := s.cfg.SetFolder(cfg)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	waiter.Wait()
+	if err := s.cfg.Save(); err != nil {
+		l.Warnln("Saving config:", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (s *service) postFolderShare(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	expiry := defaultShareExpiry
+	if expStr := qs.Get("expires"); expStr != "" {
+		var err error
+		expiry, err = time.ParseDuration(expStr)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// The password, if any, is the body so that it doesn't end up in logs
+	// of URLs.
+	password, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	link, err := s.shares.create(qs.Get("folder"), qs.Get("file"), expiry, string(password))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	sendJSON(w, jsonShareLink(link))
+}
+
+func (s *service) postFolderUnshare(w http.ResponseWriter, r *http.Request) {
+	if err := s.shares.revoke(r.URL.Query().Get("token")); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+}
+
+func (s *service) postFolderUndo(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	id := int64(-1)
+	if idStr := qs.Get("id"); idStr != "" {
+		var err error
+		id, err = strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	entry, err := s.model.Undo(qs.Get("folder"), id)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	sendJSON(w, entry)
+}
+
+func (s *service) postDBHydrate(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	if err := s.model.Hydrate(qs.Get("folder"), qs.Get("file")); err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+}
+
+func (s *service) postFolderMkdir(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	if err := s.model.MakeDir(qs.Get("folder"), qs.Get("path")); err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+}
+
+func (s *service) postFolderRename(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	if err := s.model.RenamePath(qs.Get("folder"), qs.Get("from"), qs.Get("to")); err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+}
+
+func (s *service) postFolderDelete(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	if err := s.model.DeletePath(qs.Get("folder"), qs.Get("path")); err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+}
+
+// getDBDivergence tells whether and roughly where the index of the folder
+// that the device summarized on connecting differs from ours.
+func (s *service) getDBDivergence(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	device, err := protocol.DeviceIDFromString(qs.Get("device"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	div, err := s.model.IndexDivergence(qs.Get("folder"), device)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	sendJSON(w, div)
+}
+
+// getDBHistory lists the recorded global versions of a file, oldest first,
+// with the device each came from and when it became the global version.
+func (s *service) getDBHistory(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	entries, err := s.model.FileHistory(qs.Get("folder"), qs.Get("file"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	res := make([]map[string]interface{}, len(entries))
+	for i, e := range entries {
+		res[i] = map[string]interface{}{
+			"device": protocol.DeviceIDFromBytes(e.Device),
+			"time":   time.Unix(0, e.Time),
+			"file":   jsonFileInfoTrunc(e.File),
+		}
+	}
+	sendJSON(w, res)
+}
+
+// getDBAudit returns the audit log entries of a folder after the given ID,
+// oldest first.
+func (s *service) getDBAudit(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	var since int64
+	if sinceStr := qs.Get("since"); sinceStr != "" {
+		var err error
+		since, err = strconv.ParseInt(sinceStr, 10, 64)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	limit := 100
+	if limitStr := qs.Get("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	entries, err := s.model.AuditLog(qs.Get("folder"), since, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	next := since
+	if len(entries) > 0 {
+		next = entries[len(entries)-1].ID
+	}
+	sendJSON(w, map[string]interface{}{
+		"entries": entries,
+		"next":    next,
+		"more":    len(entries) == limit,
+	})
+}
+
+// getDBAuditExport returns the whole audit log of a folder as JSON lines.
+func (s *service) getDBAuditExport(w http.ResponseWriter, r *http.Request) {
+	folder := r.URL.Query().Get("folder")
+	if _, err := s.model.AuditLog(folder, 0, 1); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	// As with the batch export, an error after the headers went out can
+	// only cut the response short.
+	filename := fmt.Sprintf("syncthing-audit-%s-%s.jsonl", folder, time.Now().Format("20060102-150405"))
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	if err := s.model.ExportAuditLog(folder, w); err != nil {
+		l.Warnln("Exporting audit log:", err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// getDBSnapshot lists the index snapshots of a folder.
+func (s *service) getDBSnapshot(w http.ResponseWriter, r *http.Request) {
+	snaps, err := s.model.IndexSnapshots(r.URL.Query().Get("folder"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	res := make([]map[string]interface{}, len(snaps))
+	for i, snap := range snaps {
+		res[i] = jsonIndexSnapshot(snap)
+	}
+	sendJSON(w, res)
+}
+
+// getDBSnapshotDiff returns the files of a folder that changed since the
+// index snapshot.
+func (s *service) getDBSnapshotDiff(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	changes, err := s.model.DiffIndexSnapshot(qs.Get("folder"), qs.Get("name"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	res := make([]map[string]interface{}, len(changes))
+	for i, c := range changes {
+		entry := map[string]interface{}{
+			"action":   c.Action(),
+			"snapshot": nil,
+			"current":  nil,
+		}
+		if c.Snapshot.Name != "" {
+			entry["name"] = c.Snapshot.Name
+			entry["snapshot"] = jsonFileInfo(c.Snapshot)
+		}
+		if c.Current.Name != "" {
+			entry["name"] = c.Current.Name
+			entry["current"] = jsonFileInfo(c.Current)
+		}
+		res[i] = entry
+	}
+	sendJSON(w, res)
+}
+
+func (s *service) postDBSnapshot(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	snap, err := s.model.CreateIndexSnapshot(qs.Get("folder"), qs.Get("name"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	sendJSON(w, jsonIndexSnapshot(snap))
+}
+
+func (s *service) postDBSnapshotRollback(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	n, err := s.model.RollbackIndexSnapshot(qs.Get("folder"), qs.Get("name"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	sendJSON(w, map[string]int{"files": n})
+}
+
+func (s *service) postDBSnapshotDrop(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	if err := s.model.DropIndexSnapshot(qs.Get("folder"), qs.Get("name")); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+	}
+}
+
+func jsonIndexSnapshot(snap db.IndexSnapshot) map[string]interface{} {
+	return map[string]interface{}{
+		"name":  snap.Name,
+		"time":  time.Unix(0, snap.Time),
+		"files": snap.Files,
+	}
+}
+
+// getDBMaintenance reports how much of the database each folder takes up.
+func (s *service) getDBMaintenance(w http.ResponseWriter, r *http.Request) {
+	usage, err := s.model.DatabaseUsage()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"folders": usage.Folders,
+	})
+}
+
+// getDBUsage reports how much of the database each folder and the index
+// of each device take up.
+func (s *service) getDBUsage(w http.ResponseWriter, r *http.Request) {
+	usage, err := s.model.DatabaseUsage()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	sendJSON(w, usage)
+}
+
+// postDBCompact has the database reclaim the space of deleted and
+// overwritten entries, returning once it's done.
+func (s *service) postDBCompact(w http.ResponseWriter, r *http.Request) {
+	if err := s.model.CompactDatabase(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// postDBCheck checks the database entries of the folder for consistency,
+// repairing them if asked to.
+func (s *service) postDBCheck(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	folder := qs.Get("folder")
+	if _, ok := s.model.CurrentSequence(folder); !ok {
+		http.Error(w, "no such folder", http.StatusNotFound)
+		return
+	}
+	repair, _ := strconv.ParseBool(qs.Get("repair"))
+	check, err := s.model.CheckDatabase(folder, repair)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"ok":    check.OK(),
+		"check": check,
+	})
+}
+
+func (s *service) getDBBatch(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	folder := qs.Get("folder")
+
+	var since int64
+	if sinceStr := qs.Get("since"); sinceStr != "" {
+		var err error
+		since, err = strconv.ParseInt(sinceStr, 10, 64)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if _, ok := s.model.CurrentSequence(folder); !ok {
+		http.Error(w, "no such folder", http.StatusNotFound)
+		return
+	}
+
+	// The batch is streamed, so by the time an error happens the headers
+	// have been sent and all we can do is to cut the response short.
+	filename := fmt.Sprintf("syncthing-batch-%s.tar", time.Now().Format("20060102-150405"))
+	w.Header().Set("Content-Type", "application/x-tar")
+	w.Header().Set("Content-Disposition", "attachment; filename="+filename)
+	if _, err := s.model.ExportBatch(folder, since, w); err != nil {
+		l.Warnln("Exporting change batch:", err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+func (s *service) postDBBatch(w http.ResponseWriter, r *http.Request) {
+	hdr, err := s.model.ImportBatch(r.Body)
+	r.Body.Close()
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	sendJSON(w, &hdr)
+}
+
 func (s *service) postFolderVersionsRestore(w http.ResponseWriter, r *http.Request) {
 	qs := r.URL.Query()
 
@@ -24,7 +24,7 @@ import (
 )
 
 var (
-	sessions    = make(map[string]bool)
+	sessions    = make(map[string]string) // session ID -> user
 	sessionsMut = sync.NewMutex()
 )
 
@@ -38,21 +38,26 @@ func emitLoginAttempt(success bool, username string, evLogger events.Logger) {
 func basicAuthAndSessionMiddleware(cookieName string, guiCfg config.GUIConfiguration, ldapCfg config.LDAPConfiguration, next http.Handler, evLogger events.Logger) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if guiCfg.IsValidAPIKey(r.Header.Get("X-API-Key")) {
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(w, withAuthenticatedUser(r, ""))
 			return
 		}
 
 		cookie, err := r.Cookie(cookieName)
 		if err == nil && cookie != nil {
 			sessionsMut.Lock()
-			_, ok := sessions[cookie.Value]
+			user, ok := sessions[cookie.Value]
 			sessionsMut.Unlock()
 			if ok {
-				next.ServeHTTP(w, r)
+				next.ServeHTTP(w, withAuthenticatedUser(r, user))
 				return
 			}
 		}
 
+		if user, ok := authenticateExtensions(r); ok {
+			next.ServeHTTP(w, withAuthenticatedUser(r, user))
+			return
+		}
+
 		l.Debugln("Sessionless HTTP request with authentication; this is expensive.")
 
 		error := func() {
@@ -62,7 +67,7 @@ func basicAuthAndSessionMiddleware(cookieName string, guiCfg config.GUIConfigura
 		}
 
 		hdr := r.Header.Get("Authorization")
-		if !strings.HasPrefix(hdr, "Basic ") {
+		if !guiCfg.IsAuthEnabled() || !strings.HasPrefix(hdr, "Basic ") {
 			error()
 			return
 		}
@@ -101,7 +106,7 @@ func basicAuthAndSessionMiddleware(cookieName string, guiCfg config.GUIConfigura
 
 		sessionid := rand.String(32)
 		sessionsMut.Lock()
-		sessions[sessionid] = true
+		sessions[sessionid] = username
 		sessionsMut.Unlock()
 		http.SetCookie(w, &http.Cookie{
 			Name:   cookieName,
@@ -110,7 +115,7 @@ func basicAuthAndSessionMiddleware(cookieName string, guiCfg config.GUIConfigura
 		})
 
 		emitLoginAttempt(true, username, evLogger)
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, withAuthenticatedUser(r, username))
 	})
 }
 
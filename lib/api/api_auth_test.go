@@ -7,9 +7,14 @@
 package api
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"golang.org/x/crypto/bcrypt"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/events"
 )
 
 var passwordHashBytes []byte
@@ -44,3 +49,57 @@ func TestStaticAuthPasswordFail(t *testing.T) {
 		t.Fatalf("should fail auth")
 	}
 }
+
+func TestAuthExtensions(t *testing.T) {
+	// Not parallel, as the registered extensions are global.
+	defer func(as []Authenticator, rs []routeAuthorizer) {
+		authenticators, authorizers = as, rs
+	}(authenticators, authorizers)
+	authenticators, authorizers = nil, nil
+
+	RegisterAuthenticator(AuthenticatorFunc(func(r *http.Request) (string, bool) {
+		user := r.Header.Get("X-Remote-User")
+		return user, user != ""
+	}))
+	RegisterAuthorizer("/rest/system/", func(r *http.Request) bool {
+		user, _ := AuthenticatedUser(r)
+		return user == "admin"
+	})
+
+	var gotUser string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUser, _ = AuthenticatedUser(r)
+	})
+	guiCfg := config.GUIConfiguration{APIKey: "abc123"}
+	mw := basicAuthAndSessionMiddleware("sessionid-test", guiCfg, config.LDAPConfiguration{}, authorizeMiddleware(handler), events.NoopLogger)
+
+	cases := []struct {
+		path, user, apiKey string
+		status             int
+	}{
+		{"/rest/db/status", "", "", http.StatusUnauthorized},
+		{"/rest/db/status", "someone", "", http.StatusOK},
+		{"/rest/system/config", "someone", "", http.StatusForbidden},
+		{"/rest/system/config", "admin", "", http.StatusOK},
+		{"/rest/db/status", "", "abc123", http.StatusOK},
+		{"/rest/system/config", "", "abc123", http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		gotUser = ""
+		req := httptest.NewRequest("GET", tc.path, nil)
+		if tc.user != "" {
+			req.Header.Set("X-Remote-User", tc.user)
+		}
+		if tc.apiKey != "" {
+			req.Header.Set("X-API-Key", tc.apiKey)
+		}
+		rec := httptest.NewRecorder()
+		mw.ServeHTTP(rec, req)
+		if rec.Code != tc.status {
+			t.Errorf("%s as %q: got status %d, expected %d", tc.path, tc.user, rec.Code, tc.status)
+		}
+		if rec.Code == http.StatusOK && gotUser != tc.user {
+			t.Errorf("%s as %q: handler saw user %q", tc.path, tc.user, gotUser)
+		}
+	}
+}
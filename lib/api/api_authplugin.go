@@ -0,0 +1,107 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// An Authenticator tells who a request to the GUI or REST API is from, by
+// means other than the built in ones. Examples are headers set by an
+// authenticating reverse proxy, or checking basic auth credentials against
+// PAM. Downstream builds register them with RegisterAuthenticator.
+type Authenticator interface {
+	// Authenticate returns the name of the user making the request, and
+	// false if it can't tell. In that case the next authenticator is asked,
+	// and finally the built in authentication if it's enabled.
+	Authenticate(r *http.Request) (user string, ok bool)
+}
+
+// AuthenticatorFunc adapts a function to the Authenticator interface.
+type AuthenticatorFunc func(r *http.Request) (string, bool)
+
+func (f AuthenticatorFunc) Authenticate(r *http.Request) (string, bool) {
+	return f(r)
+}
+
+// An Authorizer decides whether a request may proceed. It can find out who
+// made it, when authentication is enabled, using AuthenticatedUser.
+type Authorizer func(r *http.Request) bool
+
+type routeAuthorizer struct {
+	prefix string
+	authz  Authorizer
+}
+
+// The registered extensions. They're set up before the API service starts
+// and not changed afterwards, so need no locking.
+var (
+	authenticators []Authenticator
+	authorizers    []routeAuthorizer
+)
+
+// RegisterAuthenticator adds an authenticator, asked after those registered
+// before it. Having one makes authentication required even when no GUI user
+// is configured. It must be called before the API service is started,
+// typically from an init function in a file with a build tag.
+func RegisterAuthenticator(a Authenticator) {
+	authenticators = append(authenticators, a)
+}
+
+// RegisterAuthorizer adds an authorizer for requests with paths starting
+// with the given prefix, such as "/rest/system/". A request must pass all
+// authorizers that apply to it. It must be called before the API service is
+// started.
+func RegisterAuthorizer(prefix string, authz Authorizer) {
+	authorizers = append(authorizers, routeAuthorizer{prefix, authz})
+}
+
+type authUserKey struct{}
+
+// AuthenticatedUser returns the user the request was authenticated as, and
+// false if it wasn't. Requests authenticated by API key have an empty user
+// name.
+func AuthenticatedUser(r *http.Request) (string, bool) {
+	user, ok := r.Context().Value(authUserKey{}).(string)
+	return user, ok
+}
+
+func withAuthenticatedUser(r *http.Request, user string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), authUserKey{}, user))
+}
+
+// authenticateExtensions asks the registered authenticators who the request
+// is from.
+func authenticateExtensions(r *http.Request) (string, bool) {
+	for _, a := range authenticators {
+		if user, ok := a.Authenticate(r); ok {
+			return user, true
+		}
+	}
+	return "", false
+}
+
+// authorizeMiddleware rejects requests that don't pass the registered
+// authorizers. It must be wrapped by the authentication.
+func authorizeMiddleware(next http.Handler) http.Handler {
+	if len(authorizers) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, ra := range authorizers {
+			if strings.HasPrefix(r.URL.Path, ra.prefix) && !ra.authz(r) {
+				user, _ := AuthenticatedUser(r)
+				l.Debugf("Request for %s by %q not authorized", r.URL.Path, user)
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,312 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/osutil"
+	"github.com/syncthing/syncthing/lib/rand"
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+const (
+	sharePrefix        = "/share/"
+	defaultShareExpiry = 24 * time.Hour
+)
+
+var (
+	errShareNoFile     = errors.New("not a regular file")
+	errShareBadExpiry  = errors.New("expiry must be positive")
+	errShareNoSuchLink = errors.New("no such share link")
+)
+
+// A shareLink gives anyone knowing its token read access to a single file,
+// until it expires.
+type shareLink struct {
+	Token        string    `json:"token"`
+	Folder       string    `json:"folder"`
+	File         string    `json:"file"`
+	Expires      time.Time `json:"expires"`
+	PasswordHash string    `json:"passwordHash,omitempty"` // bcrypt, empty if no password is needed
+}
+
+// path returns where the link is served, ending in the file name so that
+// it's kept when downloading.
+func (l shareLink) path() string {
+	return sharePrefix + l.Token + "/" + url.PathEscape(filepath.Base(l.File))
+}
+
+func jsonShareLink(l shareLink) map[string]interface{} {
+	return map[string]interface{}{
+		"token":     l.Token,
+		"folder":    l.Folder,
+		"file":      l.File,
+		"expires":   l.Expires,
+		"protected": l.PasswordHash != "",
+		"path":      l.path(),
+	}
+}
+
+// The shareServer serves files under /share/<token>/ to people outside the
+// cluster, without the GUI's authentication. Links are kept in a file so
+// that they survive restarts.
+type shareServer struct {
+	cfg          config.Wrapper
+	saveLocation string
+	links        map[string]shareLink // token -> link
+	mut          sync.Mutex
+}
+
+func newShareServer(cfg config.Wrapper, saveLocation string) *shareServer {
+	s := &shareServer{
+		cfg:          cfg,
+		saveLocation: saveLocation,
+		links:        make(map[string]shareLink),
+		mut:          sync.NewMutex(),
+	}
+	s.load()
+	return s
+}
+
+// shareMiddleware routes share links to the share server, past the
+// handlers wrapped by it.
+func shareMiddleware(shares *shareServer, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, sharePrefix) {
+			shares.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// create makes a link to the given file, valid for the given duration and
+// protected by the password unless it's empty.
+func (s *shareServer) create(folder, file string, expiry time.Duration, password string) (shareLink, error) {
+	if expiry <= 0 {
+		return shareLink{}, errShareBadExpiry
+	}
+	fcfg, ok := s.cfg.Folder(folder)
+	if !ok {
+		return shareLink{}, errors.New("no such folder")
+	}
+	file = filepath.Clean(filepath.FromSlash(file))
+	if _, err := s.stat(fcfg, file); err != nil {
+		return shareLink{}, err
+	}
+
+	link := shareLink{
+		Token:   rand.String(32),
+		Folder:  folder,
+		File:    file,
+		Expires: time.Now().Add(expiry).Truncate(time.Second),
+	}
+	if password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), 0)
+		if err != nil {
+			return shareLink{}, err
+		}
+		link.PasswordHash = string(hash)
+	}
+
+	s.mut.Lock()
+	s.links[link.Token] = link
+	s.save()
+	s.mut.Unlock()
+	return link, nil
+}
+
+// revoke removes the link with the given token.
+func (s *shareServer) revoke(token string) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if _, ok := s.links[token]; !ok {
+		return errShareNoSuchLink
+	}
+	delete(s.links, token)
+	s.save()
+	return nil
+}
+
+// list returns the links that haven't expired, for the given folder or for
+// all folders if it's empty.
+func (s *shareServer) list(folder string) []shareLink {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.pruneExpired()
+	links := make([]shareLink, 0, len(s.links))
+	for _, link := range s.links {
+		if folder == "" || link.Folder == folder {
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+func (s *shareServer) get(token string) (shareLink, bool) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.pruneExpired()
+	link, ok := s.links[token]
+	return link, ok
+}
+
+func (s *shareServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := strings.TrimPrefix(r.URL.Path, sharePrefix)
+	if i := strings.IndexByte(token, '/'); i >= 0 {
+		token = token[:i]
+	}
+	link, ok := s.get(token)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	if link.PasswordHash != "" {
+		// Any user name will do, the password is what counts.
+		_, password, _ := r.BasicAuth()
+		if bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password)) != nil {
+			w.Header().Set("WWW-Authenticate", `Basic realm="Shared file"`)
+			http.Error(w, "Password required", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	fcfg, ok := s.cfg.Folder(link.Folder)
+	if !ok || fcfg.Paused {
+		http.NotFound(w, r)
+		return
+	}
+	ffs, err := s.stat(fcfg, link.File)
+	if err != nil {
+		l.Debugf("share: %s in folder %q: %v", link.File, link.Folder, err)
+		http.NotFound(w, r)
+		return
+	}
+	fd, err := ffs.Open(link.File)
+	if err != nil {
+		l.Debugf("share: %s in folder %q: %v", link.File, link.Folder, err)
+		http.NotFound(w, r)
+		return
+	}
+	defer fd.Close()
+	info, err := fd.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filepath.Base(link.File)}))
+	w.Header().Set("Cache-Control", "no-store")
+	// ServeContent takes care of range requests and conditional headers.
+	http.ServeContent(w, r, path.Base(filepath.ToSlash(link.File)), info.ModTime(), fd)
+}
+
+// stat makes sure the named file is something we can share, returning the
+// filesystem of the folder it's in. Files below a symlink are not shared, as
+// they could be anywhere outside the folder.
+func (s *shareServer) stat(fcfg config.FolderConfiguration, file string) (fs.Filesystem, error) {
+	if file == "." || fs.IsInternal(file) || fs.IsTemporary(file) {
+		return nil, errShareNoFile
+	}
+	ffs := fcfg.Filesystem()
+	if err := osutil.TraversesSymlink(ffs, filepath.Dir(file)); err != nil {
+		return nil, err
+	}
+	info, err := ffs.Lstat(file)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsRegular() {
+		return nil, errShareNoFile
+	}
+	return ffs, nil
+}
+
+// pruneExpired forgets expired links. Must be called with mut held.
+func (s *shareServer) pruneExpired() {
+	now := time.Now()
+	changed := false
+	for token, link := range s.links {
+		if now.After(link.Expires) {
+			delete(s.links, token)
+			changed = true
+		}
+	}
+	if changed {
+		s.save()
+	}
+}
+
+// save writes the links to disk. Must be called with mut held.
+func (s *shareServer) save() {
+	// Errors are logged but otherwise ignored, the links just won't
+	// survive a restart.
+
+	if s.saveLocation == "" {
+		return
+	}
+
+	links := make([]shareLink, 0, len(s.links))
+	for _, link := range s.links {
+		links = append(links, link)
+	}
+
+	f, err := osutil.CreateAtomic(s.saveLocation)
+	if err != nil {
+		l.Warnln("Saving share links:", err)
+		return
+	}
+	if err := json.NewEncoder(f).Encode(links); err != nil {
+		l.Warnln("Saving share links:", err)
+		f.Close()
+		return
+	}
+	if err := f.Close(); err != nil {
+		l.Warnln("Saving share links:", err)
+	}
+}
+
+func (s *shareServer) load() {
+	if s.saveLocation == "" {
+		return
+	}
+
+	f, err := os.Open(s.saveLocation)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	var links []shareLink
+	if err := json.NewDecoder(f).Decode(&links); err != nil {
+		l.Warnln("Loading share links:", err)
+		return
+	}
+	for _, link := range links {
+		s.links[link.Token] = link
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package api
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+func TestShareLinks(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "share")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.Mkdir(filepath.Join(dir, config.DefaultMarkerName), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "file.txt"), []byte("0123456789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fcfg := config.NewFolderConfiguration(protocol.LocalDeviceID, "default", "", fs.FilesystemTypeBasic, dir)
+	w := config.Wrap("/dev/null", config.Configuration{Folders: []config.FolderConfiguration{fcfg}}, events.NoopLogger)
+	saveLocation := filepath.Join(dir, "sharelinks.json")
+	shares := newShareServer(w, saveLocation)
+	srv := httptest.NewServer(shareMiddleware(shares, http.NotFoundHandler()))
+	defer srv.Close()
+
+	get := func(path, password, rng string) (int, string) {
+		t.Helper()
+		req, err := http.NewRequest("GET", srv.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if password != "" {
+			req.SetBasicAuth("", password)
+		}
+		if rng != "" {
+			req.Header.Set("Range", rng)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		bs, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, string(bs)
+	}
+
+	for _, file := range []string{"missing", ".", config.DefaultMarkerName, "../file.txt"} {
+		if _, err := shares.create("default", file, time.Hour, ""); err == nil {
+			t.Errorf("should not share %q", file)
+		}
+	}
+
+	open, err := shares.create("default", "file.txt", time.Hour, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status, body := get(open.path(), "", ""); status != http.StatusOK || body != "0123456789" {
+		t.Errorf("got %d %q", status, body)
+	}
+	if status, body := get(open.path(), "", "bytes=2-4"); status != http.StatusPartialContent || body != "234" {
+		t.Errorf("got %d %q for range", status, body)
+	}
+
+	if runtime.GOOS != "windows" {
+		// Files below a symlink may be outside the folder, whether the
+		// symlink was there when the link was made or not.
+		outside, err := ioutil.TempDir("", "share")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(outside)
+		if err := ioutil.WriteFile(filepath.Join(outside, "file.txt"), []byte("secret"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(outside, filepath.Join(dir, "link")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := shares.create("default", "link/file.txt", time.Hour, ""); err == nil {
+			t.Error("should not share a file below a symlink")
+		}
+
+		if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, "sub", "file.txt"), []byte("public"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		sub, err := shares.create("default", "sub/file.txt", time.Hour, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.RemoveAll(filepath.Join(dir, "sub")); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(outside, filepath.Join(dir, "sub")); err != nil {
+			t.Fatal(err)
+		}
+		if status, body := get(sub.path(), "", ""); status != http.StatusNotFound {
+			t.Errorf("got %d %q through a symlink", status, body)
+		}
+		if err := shares.revoke(sub.Token); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	protected, err := shares.create("default", "file.txt", time.Hour, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := get(protected.path(), "", ""); status != http.StatusUnauthorized {
+		t.Errorf("got %d without password", status)
+	}
+	if status, _ := get(protected.path(), "wrong", ""); status != http.StatusUnauthorized {
+		t.Errorf("got %d with wrong password", status)
+	}
+	if status, body := get(protected.path(), "secret", ""); status != http.StatusOK || body != "0123456789" {
+		t.Errorf("got %d %q with password", status, body)
+	}
+
+	// Links survive a restart, expired or revoked ones don't.
+	if len(newShareServer(w, saveLocation).list("")) != 2 {
+		t.Error("links should have been saved")
+	}
+	if err := shares.revoke(open.Token); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := get(open.path(), "", ""); status != http.StatusNotFound {
+		t.Errorf("got %d for revoked link", status)
+	}
+	shares.mut.Lock()
+	protected.Expires = time.Now().Add(-time.Second)
+	shares.links[protected.Token] = protected
+	shares.mut.Unlock()
+	if status, _ := get(protected.path(), "secret", ""); status != http.StatusNotFound {
+		t.Errorf("got %d for expired link", status)
+	}
+	if len(newShareServer(w, saveLocation).list("")) != 0 {
+		t.Error("no links should remain")
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/net/webdav"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/model"
+	"github.com/syncthing/syncthing/lib/osutil"
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+const webdavPrefix = "/webdav/"
+
+// The webdavServer serves the folders that have WebDAV enabled under
+// /webdav/<folder ID>/, for clients that can't access the filesystem
+// directly. Changes made over WebDAV are picked up by scanning the affected
+// paths, the same as any other local change.
+type webdavServer struct {
+	cfg   config.Wrapper
+	model model.Model
+	locks map[string]webdav.LockSystem
+	mut   sync.Mutex
+}
+
+func newWebDAVServer(cfg config.Wrapper, m model.Model) *webdavServer {
+	return &webdavServer{
+		cfg:   cfg,
+		model: m,
+		locks: make(map[string]webdav.LockSystem),
+		mut:   sync.NewMutex(),
+	}
+}
+
+func (s *webdavServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	folder := strings.TrimPrefix(r.URL.Path, webdavPrefix)
+	if i := strings.IndexByte(folder, '/'); i >= 0 {
+		folder = folder[:i]
+	}
+
+	fcfg, ok := s.cfg.Folder(folder)
+	if !ok || !fcfg.WebDAVEnabled || fcfg.Paused || fcfg.FilesystemType != fs.FilesystemTypeBasic {
+		http.NotFound(w, r)
+		return
+	}
+
+	if fcfg.WebDAVReadOnly && isWebDAVWrite(r.Method) {
+		http.Error(w, "Folder is read only", http.StatusForbidden)
+		return
+	}
+
+	prefix := webdavPrefix + folder
+	ffs := fcfg.Filesystem()
+	handler := &webdav.Handler{
+		Prefix: prefix,
+		FileSystem: &webdavFilesystem{
+			Dir:      webdav.Dir(ffs.URI()),
+			fs:       ffs,
+			readOnly: fcfg.WebDAVReadOnly,
+		},
+		LockSystem: s.lockSystem(folder),
+		Logger: func(r *http.Request, err error) {
+			if err != nil {
+				l.Debugf("webdav: %s %s: %v", r.Method, r.URL.Path, err)
+			}
+		},
+	}
+	handler.ServeHTTP(w, r)
+
+	if isWebDAVWrite(r.Method) {
+		subs := []string{strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, prefix), "/")}
+		if dst, err := url.Parse(r.Header.Get("Destination")); err == nil && strings.HasPrefix(dst.Path, prefix) {
+			subs = append(subs, strings.TrimPrefix(strings.TrimPrefix(dst.Path, prefix), "/"))
+		}
+		go func() {
+			if err := s.model.ScanFolderSubdirs(folder, subs); err != nil {
+				l.Debugf("webdav: scanning %s %v: %v", folder, subs, err)
+			}
+		}()
+	}
+}
+
+func (s *webdavServer) lockSystem(folder string) webdav.LockSystem {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	ls, ok := s.locks[folder]
+	if !ok {
+		ls = webdav.NewMemLS()
+		s.locks[folder] = ls
+	}
+	return ls
+}
+
+func isWebDAVWrite(method string) bool {
+	switch method {
+	case "PUT", "DELETE", "MKCOL", "COPY", "MOVE", "PROPPATCH", "LOCK":
+		return true
+	}
+	return false
+}
+
+// webdavFilesystem is the folder root as seen over WebDAV. Internal files,
+// temporary files and symlinks are hidden, as symlinks could otherwise be
+// used to reach outside the folder.
+type webdavFilesystem struct {
+	webdav.Dir
+	fs       fs.Filesystem
+	readOnly bool
+}
+
+func (w *webdavFilesystem) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	if err := w.check(name, true); err != nil {
+		return err
+	}
+	return w.Dir.Mkdir(ctx, name, perm)
+}
+
+func (w *webdavFilesystem) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	write := flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0
+	if err := w.check(name, write); err != nil {
+		return nil, err
+	}
+	fd, err := w.Dir.OpenFile(ctx, name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &webdavFile{File: fd, name: webdavNativeName(name)}, nil
+}
+
+func (w *webdavFilesystem) RemoveAll(ctx context.Context, name string) error {
+	if err := w.check(name, true); err != nil {
+		return err
+	}
+	return w.Dir.RemoveAll(ctx, name)
+}
+
+func (w *webdavFilesystem) Rename(ctx context.Context, oldName, newName string) error {
+	if err := w.check(oldName, true); err != nil {
+		return err
+	}
+	if err := w.check(newName, true); err != nil {
+		return err
+	}
+	return w.Dir.Rename(ctx, oldName, newName)
+}
+
+func (w *webdavFilesystem) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	if err := w.check(name, false); err != nil {
+		return nil, err
+	}
+	return w.Dir.Stat(ctx, name)
+}
+
+func (w *webdavFilesystem) check(name string, write bool) error {
+	if native := webdavNativeName(name); native != "." {
+		if webdavHidden(native) {
+			return os.ErrNotExist
+		}
+		if err := osutil.TraversesSymlink(w.fs, filepath.Dir(native)); err != nil {
+			return os.ErrNotExist
+		}
+		if info, err := w.fs.Lstat(native); err == nil && info.IsSymlink() {
+			return os.ErrNotExist
+		}
+	}
+	if write && w.readOnly {
+		return os.ErrPermission
+	}
+	return nil
+}
+
+// webdavFile filters hidden entries out of directory listings.
+type webdavFile struct {
+	webdav.File
+	name string
+}
+
+func (f *webdavFile) Readdir(count int) ([]os.FileInfo, error) {
+	infos, err := f.File.Readdir(count)
+	filtered := infos[:0]
+	for _, info := range infos {
+		if info.Mode()&os.ModeSymlink != 0 || webdavHidden(filepath.Join(f.name, info.Name())) {
+			continue
+		}
+		filtered = append(filtered, info)
+	}
+	return filtered, err
+}
+
+// webdavNativeName returns the slash separated WebDAV name as a clean
+// native path relative to the folder root.
+func webdavNativeName(name string) string {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		return "."
+	}
+	return filepath.FromSlash(name)
+}
+
+func webdavHidden(native string) bool {
+	return native != "." && (fs.IsInternal(native) || fs.IsTemporary(native))
+}
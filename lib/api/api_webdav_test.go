@@ -0,0 +1,119 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package api
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+func TestWebDAV(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "webdav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.Mkdir(filepath.Join(dir, config.DefaultMarkerName), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "file"), []byte("contents"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, ".stignore"), []byte("ignored"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" {
+		if err := os.Symlink("/", filepath.Join(dir, "link")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rw := config.NewFolderConfiguration(protocol.LocalDeviceID, "rw", "", fs.FilesystemTypeBasic, dir)
+	rw.WebDAVEnabled = true
+	ro := rw
+	ro.ID = "ro"
+	ro.WebDAVReadOnly = true
+	off := rw
+	off.ID = "off"
+	off.WebDAVEnabled = false
+
+	cfg := config.Configuration{Folders: []config.FolderConfiguration{rw, ro, off}}
+	w := config.Wrap("/dev/null", cfg, events.NoopLogger)
+	srv := httptest.NewServer(newWebDAVServer(w, new(mockedModel)))
+	defer srv.Close()
+
+	do := func(method, path, body string) (int, string) {
+		t.Helper()
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		bs, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, string(bs)
+	}
+
+	cases := []struct {
+		method, path, body string
+		status             int
+	}{
+		{"GET", "/webdav/rw/file", "", http.StatusOK},
+		{"GET", "/webdav/ro/file", "", http.StatusOK},
+		{"GET", "/webdav/off/file", "", http.StatusNotFound},
+		{"GET", "/webdav/missing/file", "", http.StatusNotFound},
+		{"GET", "/webdav/rw/.stignore", "", http.StatusNotFound},
+		{"GET", "/webdav/rw/.stfolder", "", http.StatusNotFound},
+		{"PUT", "/webdav/ro/new", "data", http.StatusForbidden},
+		{"DELETE", "/webdav/ro/file", "", http.StatusForbidden},
+		{"PUT", "/webdav/rw/new", "data", http.StatusCreated},
+		{"PUT", "/webdav/rw/.stignore", "data", http.StatusNotFound},
+	}
+	if runtime.GOOS != "windows" {
+		cases = append(cases, struct {
+			method, path, body string
+			status             int
+		}{"GET", "/webdav/rw/link/etc", "", http.StatusNotFound})
+	}
+	for _, tc := range cases {
+		if status, _ := do(tc.method, tc.path, tc.body); status != tc.status {
+			t.Errorf("%s %s: got status %d, expected %d", tc.method, tc.path, status, tc.status)
+		}
+	}
+
+	if bs, err := ioutil.ReadFile(filepath.Join(dir, "new")); err != nil || string(bs) != "data" {
+		t.Errorf("PUT did not create the file: %q, %v", bs, err)
+	}
+
+	// Internal files and symlinks are not listed.
+	_, listing := do("PROPFIND", "/webdav/rw/", "")
+	if !strings.Contains(listing, "/webdav/rw/file") {
+		t.Error("listing should contain the file")
+	}
+	for _, hidden := range []string{".stignore", ".stfolder", "/link"} {
+		if strings.Contains(listing, hidden) {
+			t.Errorf("listing should not contain %s", hidden)
+		}
+	}
+}
@@ -72,7 +72,8 @@ func (c *mockedConfig) RequiresRestart() bool {
 
 func (c *mockedConfig) AddOrUpdatePendingDevice(device protocol.DeviceID, name, address string) {}
 
-func (c *mockedConfig) AddOrUpdatePendingFolder(id, label string, device protocol.DeviceID) {}
+func (c *mockedConfig) AddOrUpdatePendingFolder(folder config.ObservedFolder, device protocol.DeviceID) {
+}
 
 func (c *mockedConfig) MyName() string {
 	return ""
@@ -110,10 +111,18 @@ func (c *mockedConfig) RemoveDevice(id protocol.DeviceID) (config.Waiter, error)
 	return noopWaiter{}, nil
 }
 
+func (c *mockedConfig) DecommissionDevice(id protocol.DeviceID) (config.Waiter, error) {
+	return noopWaiter{}, nil
+}
+
 func (c *mockedConfig) IgnoredDevice(id protocol.DeviceID) bool {
 	return false
 }
 
+func (c *mockedConfig) DecommissionedDevice(id protocol.DeviceID) bool {
+	return false
+}
+
 func (c *mockedConfig) IgnoredFolder(device protocol.DeviceID, folder string) bool {
 	return false
 }
@@ -7,6 +7,8 @@
 package api
 
 import (
+	"context"
+	"io"
 	"net"
 	"time"
 
@@ -14,8 +16,10 @@ import (
 	"github.com/syncthing/syncthing/lib/db"
 	"github.com/syncthing/syncthing/lib/model"
 	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/scanner"
 	"github.com/syncthing/syncthing/lib/stats"
 	"github.com/syncthing/syncthing/lib/versioner"
+	"github.com/syncthing/syncthing/lib/watchaggregator"
 )
 
 type mockedModel struct{}
@@ -75,6 +79,22 @@ func (m *mockedModel) GetIgnores(folder string) ([]string, []string, error) {
 	return nil, nil, nil
 }
 
+func (m *mockedModel) SharedIgnores(folder string) []string {
+	return nil
+}
+
+func (m *mockedModel) MakeDir(folder, name string) error {
+	return nil
+}
+
+func (m *mockedModel) RenamePath(folder, from, to string) error {
+	return nil
+}
+
+func (m *mockedModel) DeletePath(folder, name string) error {
+	return nil
+}
+
 func (m *mockedModel) SetIgnores(folder string, content []string) error {
 	return nil
 }
@@ -87,6 +107,10 @@ func (m *mockedModel) RestoreFolderVersions(folder string, versions map[string]t
 	return nil, nil
 }
 
+func (m *mockedModel) ExportFolder(folder, prefix, format string, at time.Time, w io.Writer) error {
+	return nil
+}
+
 func (m *mockedModel) PauseDevice(device protocol.DeviceID) {
 }
 
@@ -108,7 +132,139 @@ func (m *mockedModel) ScanFolderSubdirs(folder string, subs []string) error {
 
 func (m *mockedModel) BringToFront(folder, file string) {}
 
-func (m *mockedModel) Connection(deviceID protocol.DeviceID) (connections.Connection, bool) {
+func (m *mockedModel) UndoEntries(folder string) ([]model.UndoEntry, error) {
+	return nil, nil
+}
+
+func (m *mockedModel) Undo(folder string, id int64) (model.UndoEntry, error) {
+	return model.UndoEntry{}, nil
+}
+
+func (m *mockedModel) SkippedChanges(folder string) ([]model.SkippedChange, error) {
+	return nil, nil
+}
+
+func (m *mockedModel) PullerState(folder string, queued int) (model.PullerState, error) {
+	return model.PullerState{}, nil
+}
+
+func (m *mockedModel) SendApplicationMessage(device protocol.DeviceID, msgType string, data []byte) error {
+	return nil
+}
+
+func (m *mockedModel) RequestRemoteScan(device protocol.DeviceID, folder string, subdirs []string) error {
+	return nil
+}
+
+func (m *mockedModel) PendingFolderSettings() []model.PendingFolderSettings {
+	return nil
+}
+
+func (m *mockedModel) ResolveFolderSettings(folder string, accept bool) error {
+	return nil
+}
+
+func (m *mockedModel) DecommissionDevice(device protocol.DeviceID) error {
+	return nil
+}
+
+func (m *mockedModel) PendingDecommissions() []model.PendingDecommission {
+	return nil
+}
+
+func (m *mockedModel) ResolveDecommission(device protocol.DeviceID, accept bool) error {
+	return nil
+}
+
+func (m *mockedModel) ExplainIgnores(folder string, lines []string, paths []string) ([]model.IgnoreExplanation, error) {
+	return nil, nil
+}
+
+func (m *mockedModel) AuditIgnores(folder string) ([]model.IgnoreAuditEntry, error) {
+	return nil, nil
+}
+
+func (m *mockedModel) IndexDivergence(folder string, device protocol.DeviceID) (model.IndexDivergence, error) {
+	return model.IndexDivergence{}, nil
+}
+
+func (m *mockedModel) DatabaseUsage() (db.DatabaseUsage, error) {
+	return db.DatabaseUsage{}, nil
+}
+
+func (m *mockedModel) CompactDatabase() error {
+	return nil
+}
+
+func (m *mockedModel) CheckDatabase(folder string, repair bool) (db.FolderCheck, error) {
+	return db.FolderCheck{}, nil
+}
+
+func (m *mockedModel) FileHistory(folder, file string) ([]db.FileHistoryEntry, error) {
+	return nil, nil
+}
+
+func (m *mockedModel) AuditLog(folder string, since int64, limit int) ([]model.AuditLogEntry, error) {
+	return nil, nil
+}
+
+func (m *mockedModel) ExportAuditLog(folder string, w io.Writer) error {
+	return nil
+}
+
+func (m *mockedModel) CreateIndexSnapshot(folder, name string) (db.IndexSnapshot, error) {
+	return db.IndexSnapshot{}, nil
+}
+
+func (m *mockedModel) IndexSnapshots(folder string) ([]db.IndexSnapshot, error) {
+	return nil, nil
+}
+
+func (m *mockedModel) DiffIndexSnapshot(folder, name string) ([]db.IndexSnapshotChange, error) {
+	return nil, nil
+}
+
+func (m *mockedModel) RollbackIndexSnapshot(folder, name string) (int, error) {
+	return 0, nil
+}
+
+func (m *mockedModel) DropIndexSnapshot(folder, name string) error {
+	return nil
+}
+
+func (m *mockedModel) ScanProgress(folder string) (scanner.ScanProgress, bool) {
+	return scanner.ScanProgress{}, false
+}
+
+func (m *mockedModel) Hydrate(folder, file string) error {
+	return nil
+}
+
+func (m *mockedModel) StreamFile(ctx context.Context, folder string, file protocol.FileInfo, w io.Writer) error {
+	return nil
+}
+
+func (m *mockedModel) ReadGlobalAt(ctx context.Context, folder string, file protocol.FileInfo, buf []byte, offset int64) (int, error) {
+	return 0, nil
+}
+
+func (m *mockedModel) GlobalDirectoryEntries(folder, dir string) ([]db.FileInfoTruncated, error) {
+	return nil, nil
+}
+
+func (m *mockedModel) ExportBatch(folder string, since int64, w io.Writer) (model.BatchHeader, error) {
+	return model.BatchHeader{}, nil
+}
+
+func (m *mockedModel) ImportBatch(r io.Reader) (model.BatchHeader, error) {
+	return model.BatchHeader{}, nil
+}
+
+func (m *mockedModel) Prioritize(folder, file string, bumpRequests bool) error {
+	return nil
+}
+
+func (m *mockedModel) Connection(deviceID protocol.DeviceID) (*connections.ConnectionSet, bool) {
 	return nil, false
 }
 
@@ -148,6 +304,10 @@ func (m *mockedModel) WatchError(folder string) error {
 	return nil
 }
 
+func (m *mockedModel) WatchAggregationMode(folder string) watchaggregator.Mode {
+	return watchaggregator.ModeNormal
+}
+
 func (m *mockedModel) LocalChangedFiles(folder string, page, perpage int) []db.FileInfoTruncated {
 	return nil
 }
@@ -163,7 +323,7 @@ func (m *mockedModel) IndexUpdate(deviceID protocol.DeviceID, folder string, fil
 	return nil
 }
 
-func (m *mockedModel) Request(deviceID protocol.DeviceID, folder, name string, size int32, offset int64, hash []byte, weakHash uint32, fromTemporary bool) (protocol.RequestResponse, error) {
+func (m *mockedModel) Request(deviceID protocol.DeviceID, folder, name string, size int32, offset int64, hash []byte, weakHash uint32, fromTemporary bool, priority protocol.RequestPriority) (protocol.RequestResponse, error) {
 	return nil, nil
 }
 
@@ -177,6 +337,14 @@ func (m *mockedModel) DownloadProgress(deviceID protocol.DeviceID, folder string
 	return nil
 }
 
+func (m *mockedModel) ApplicationMessage(device protocol.DeviceID, msgType string, data []byte) error {
+	return nil
+}
+
+func (m *mockedModel) ScanRequest(device protocol.DeviceID, folder string, subdirs []string) error {
+	return nil
+}
+
 func (m *mockedModel) AddConnection(conn connections.Connection, hello protocol.HelloResult) {}
 
 func (m *mockedModel) OnHello(protocol.DeviceID, net.Addr, protocol.HelloResult) error {
@@ -0,0 +1,83 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// Package benchmark measures the performance of the parts of the system
+// that usually limit Syncthing: hashing, the database and the filesystem.
+package benchmark
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"text/tabwriter"
+
+	"github.com/syncthing/syncthing/lib/build"
+)
+
+// A Result is a single measurement.
+type Result struct {
+	Group string  `json:"group"` // e.g. "hashing", or the folder measured
+	Name  string  `json:"name"`
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit"`
+}
+
+// A Report is a set of results along with a description of the system
+// they were measured on.
+type Report struct {
+	Version string   `json:"version"`
+	OS      string   `json:"os"`
+	Arch    string   `json:"arch"`
+	CPUs    int      `json:"cpus"`
+	Results []Result `json:"results"`
+}
+
+// NewReport returns an empty report for the running system.
+func NewReport() *Report {
+	return &Report{
+		Version: build.LongVersion,
+		OS:      runtime.GOOS,
+		Arch:    runtime.GOARCH,
+		CPUs:    runtime.NumCPU(),
+	}
+}
+
+// Add adds the results to the report.
+func (r *Report) Add(results ...Result) {
+	r.Results = append(r.Results, results...)
+}
+
+// WriteText writes the report as a table, suitable for pasting into an
+// issue.
+func (r *Report) WriteText(w io.Writer) error {
+	fmt.Fprintf(w, "%s\n%s-%s, %d CPUs\n\n", r.Version, r.OS, r.Arch, r.CPUs)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	group := ""
+	for _, res := range r.Results {
+		if res.Group != group {
+			if group != "" {
+				fmt.Fprintln(tw)
+			}
+			fmt.Fprintln(tw, res.Group)
+			group = res.Group
+		}
+		fmt.Fprintf(tw, "  %s\t%.*f\t%s\n", res.Name, decimals(res.Value), res.Value, res.Unit)
+	}
+	return tw.Flush()
+}
+
+func decimals(v float64) int {
+	switch {
+	case v < 1:
+		return 3
+	case v < 10:
+		return 2
+	case v < 100:
+		return 1
+	default:
+		return 0
+	}
+}
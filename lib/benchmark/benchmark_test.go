@@ -0,0 +1,63 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package benchmark
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/fs"
+)
+
+func TestBenchmarks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benchmark")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	report := NewReport()
+	report.Add(Hashing(time.Millisecond)...)
+
+	res, err := Database(dir, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report.Add(res...)
+
+	filesystem := fs.NewFilesystem(fs.FilesystemTypeBasic, dir)
+	res, err = Filesystem(filesystem, "folder default", 1<<20, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report.Add(res...)
+
+	for _, res := range report.Results {
+		if res.Value <= 0 {
+			t.Errorf("expected a positive value for %s %s, got %v", res.Group, res.Name, res.Value)
+		}
+	}
+
+	// Nothing is left behind.
+	if names, err := filesystem.DirNames("."); err != nil || len(names) != 0 {
+		t.Errorf("expected an empty directory, got %v, %v", names, err)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := report.WriteText(buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, group := range []string{"hashing", "database", "folder default"} {
+		if !strings.Contains(buf.String(), "\n"+group+"\n") {
+			t.Errorf("expected the report to include %s:\n%s", group, buf)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package benchmark
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/db/backend"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// The number of files per index update, as when scanning.
+const databaseBatchSize = 1000
+
+// Database measures how fast files are inserted into, looked up in and
+// iterated over in a database created in a temporary directory within the
+// given one, which is removed afterwards.
+func Database(dir string, files int) ([]Result, error) {
+	tmp, err := ioutil.TempDir(dir, "stbenchmark")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	be, err := backend.Open(tmp, backend.TuningAuto)
+	if err != nil {
+		return nil, err
+	}
+	ldb := db.NewLowlevel(be)
+	defer ldb.Close()
+
+	fset := db.NewFileSet("benchmark", fs.NewFilesystem(fs.FilesystemTypeBasic, tmp), ldb)
+	version := protocol.Vector{}.Update(1)
+	names := make([]string, files)
+	for i := range names {
+		names[i] = fmt.Sprintf("dir%d/file%d", i%100, i)
+	}
+
+	res := make([]Result, 0, 4)
+	add := func(name string, n int, d time.Duration) {
+		res = append(res, Result{Group: "database", Name: name, Value: float64(n) / d.Seconds(), Unit: "files/s"})
+	}
+
+	t0 := time.Now()
+	batch := make([]protocol.FileInfo, 0, databaseBatchSize)
+	for i, name := range names {
+		blocks := benchmarkBlocks(i)
+		batch = append(batch, protocol.FileInfo{
+			Name:     name,
+			Size:     int64(len(blocks)) * protocol.MinBlockSize,
+			Sequence: int64(i + 1),
+			Version:  version,
+			Blocks:   blocks,
+		})
+		if len(batch) == cap(batch) || i == len(names)-1 {
+			fset.Update(protocol.LocalDeviceID, batch)
+			batch = batch[:0]
+		}
+	}
+	add("insert", files, time.Since(t0))
+
+	rand.Shuffle(len(names), func(i, j int) { names[i], names[j] = names[j], names[i] })
+
+	t0 = time.Now()
+	for _, name := range names {
+		if _, ok := fset.Get(protocol.LocalDeviceID, name); !ok {
+			return nil, fmt.Errorf("file %q not found after inserting it", name)
+		}
+	}
+	add("local lookup", files, time.Since(t0))
+
+	t0 = time.Now()
+	for _, name := range names {
+		if _, ok := fset.GetGlobalTruncated(name); !ok {
+			return nil, fmt.Errorf("file %q not found in global list", name)
+		}
+	}
+	add("global lookup", files, time.Since(t0))
+
+	t0 = time.Now()
+	n := 0
+	fset.WithHaveTruncated(protocol.LocalDeviceID, func(db.FileIntf) bool {
+		n++
+		return true
+	})
+	add("iteration", n, time.Since(t0))
+
+	return res, nil
+}
+
+// benchmarkBlocks returns a few blocks, with hashes unique to the file.
+func benchmarkBlocks(file int) []protocol.BlockInfo {
+	blocks := make([]protocol.BlockInfo, file%10)
+	for i := range blocks {
+		hash := make([]byte, 32)
+		rand.Read(hash)
+		blocks[i] = protocol.BlockInfo{
+			Offset: int64(i) * protocol.MinBlockSize,
+			Size:   protocol.MinBlockSize,
+			Hash:   hash,
+		}
+	}
+	return blocks
+}
@@ -0,0 +1,125 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package benchmark
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/fs"
+)
+
+const (
+	smallFileSize  = 4 << 10
+	largeChunkSize = 128 << 10
+)
+
+// Filesystem measures the throughput of writing and reading a file of the
+// given size, and the latency of writing, reading and renaming small files,
+// in a temporary directory that the scanner ignores. The directory is
+// removed afterwards.
+func Filesystem(filesystem fs.Filesystem, group string, size int64, files int) ([]Result, error) {
+	dir := fs.TempName("stbenchmark")
+	if err := filesystem.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	defer filesystem.RemoveAll(dir)
+
+	res := make([]Result, 0, 5)
+	throughput := func(name string, d time.Duration) {
+		res = append(res, Result{Group: group, Name: name, Value: float64(size) / megabyte / d.Seconds(), Unit: "MB/s"})
+	}
+	latency := func(name string, d time.Duration) {
+		res = append(res, Result{Group: group, Name: name, Value: d.Seconds() * 1000 / float64(files), Unit: "ms"})
+	}
+
+	buf := make([]byte, largeChunkSize)
+	rand.Read(buf)
+	large := filepath.Join(dir, "large")
+
+	t0 := time.Now()
+	if err := writeFile(filesystem, large, buf, size); err != nil {
+		return nil, err
+	}
+	throughput("write", time.Since(t0))
+
+	t0 = time.Now()
+	if err := readFile(filesystem, large); err != nil {
+		return nil, err
+	}
+	throughput("read", time.Since(t0))
+
+	small := make([]string, files)
+	for i := range small {
+		small[i] = filepath.Join(dir, fmt.Sprintf("small%d", i))
+	}
+
+	t0 = time.Now()
+	for _, name := range small {
+		if err := writeFile(filesystem, name, buf[:smallFileSize], smallFileSize); err != nil {
+			return nil, err
+		}
+	}
+	latency("small file write", time.Since(t0))
+
+	t0 = time.Now()
+	for _, name := range small {
+		if err := readFile(filesystem, name); err != nil {
+			return nil, err
+		}
+	}
+	latency("small file read", time.Since(t0))
+
+	t0 = time.Now()
+	for _, name := range small {
+		if err := filesystem.Rename(name, name+".renamed"); err != nil {
+			return nil, err
+		}
+	}
+	latency("rename", time.Since(t0))
+
+	return res, nil
+}
+
+// writeFile writes size bytes to the file, repeating buf, and syncs it.
+func writeFile(filesystem fs.Filesystem, name string, buf []byte, size int64) error {
+	fd, err := filesystem.Create(name)
+	if err != nil {
+		return err
+	}
+	for written := int64(0); written < size; {
+		chunk := buf
+		if rem := size - written; rem < int64(len(chunk)) {
+			chunk = chunk[:rem]
+		}
+		n, err := fd.Write(chunk)
+		if err != nil {
+			fd.Close()
+			return err
+		}
+		written += int64(n)
+	}
+	if err := fd.Sync(); err != nil {
+		fd.Close()
+		return err
+	}
+	return fd.Close()
+}
+
+func readFile(filesystem fs.Filesystem, name string) error {
+	fd, err := filesystem.Open(name)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+	_, err = io.Copy(ioutil.Discard, fd)
+	return err
+}
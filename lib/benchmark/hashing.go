@@ -0,0 +1,84 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package benchmark
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"hash"
+	"hash/adler32"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/scanner"
+)
+
+const megabyte = 1 << 20
+
+// Hashing measures the single threaded throughput of each available hashing
+// backend and of the weak hash, and that of hashing files into blocks of
+// each size, with the selected SHA256 implementation and weak hashes, as the
+// scanner does. Each measurement runs for the given duration.
+func Hashing(duration time.Duration) []Result {
+	buf := make([]byte, protocol.MaxBlockSize)
+	rand.Read(buf)
+
+	var res []Result
+	hashRate := func(name string, h hash.Hash) {
+		res = append(res, Result{
+			Group: "hashing",
+			Name:  name,
+			Value: rate(duration, func() int {
+				h.Write(buf)
+				return len(buf)
+			}),
+			Unit: "MB/s",
+		})
+	}
+	for _, b := range scanner.HashBackends() {
+		if b.Available() {
+			algo := strings.TrimPrefix(b.Algorithm().String(), "HASH_ALGORITHM_")
+			hashRate(algo+", "+b.Name(), b.New())
+		}
+	}
+	hashRate("Adler32 (weak hash)", adler32.New())
+
+	for _, size := range protocol.BlockSizes {
+		res = append(res, Result{
+			Group: "hashing",
+			Name:  "blocks of " + blockSizeString(size),
+			Value: rate(duration, func() int {
+				scanner.Blocks(context.Background(), bytes.NewReader(buf), size, int64(len(buf)), nil, true)
+				return len(buf)
+			}),
+			Unit: "MB/s",
+		})
+	}
+
+	return res
+}
+
+// rate calls fn, which returns the number of bytes it processed, until the
+// duration has passed and returns the rate in megabytes per second.
+func rate(duration time.Duration, fn func() int) float64 {
+	t0 := time.Now()
+	n := 0
+	for time.Since(t0) < duration {
+		n += fn()
+	}
+	return float64(n) / megabyte / time.Since(t0).Seconds()
+}
+
+func blockSizeString(size int) string {
+	if size < megabyte {
+		return fmt.Sprintf("%d KiB", size>>10)
+	}
+	return fmt.Sprintf("%d MiB", size>>20)
+}
@@ -0,0 +1,252 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// Package blake3 implements the BLAKE3 hash function, in its default
+// hashing mode with 32 bytes of output, following the reference
+// implementation at https://github.com/BLAKE3-team/BLAKE3.
+package blake3
+
+import (
+	"encoding/binary"
+	"hash"
+	"math/bits"
+)
+
+const (
+	// Size is the size of a BLAKE3 checksum in bytes.
+	Size = 32
+	// BlockSize is the block size of BLAKE3 in bytes.
+	BlockSize = 64
+
+	chunkLen = 1024
+
+	flagChunkStart = 1 << 0
+	flagChunkEnd   = 1 << 1
+	flagParent     = 1 << 2
+	flagRoot       = 1 << 3
+)
+
+var iv = [8]uint32{
+	0x6A09E667, 0xBB67AE85, 0x3C6EF372, 0xA54FF53A,
+	0x510E527F, 0x9B05688C, 0x1F83D9AB, 0x5BE0CD19,
+}
+
+// The message words used by each round, which are those of the previous
+// round permuted.
+var schedule = [7][16]uint8{
+	{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+	{2, 6, 3, 10, 7, 0, 4, 13, 1, 11, 12, 5, 9, 14, 15, 8},
+	{3, 4, 10, 12, 13, 2, 7, 14, 6, 5, 9, 0, 11, 15, 8, 1},
+	{10, 7, 12, 9, 14, 3, 13, 15, 4, 0, 11, 2, 5, 8, 1, 6},
+	{12, 13, 9, 11, 15, 10, 14, 8, 7, 2, 5, 3, 0, 1, 6, 4},
+	{9, 14, 11, 5, 8, 12, 15, 1, 13, 3, 0, 10, 2, 6, 4, 7},
+	{11, 15, 5, 0, 1, 9, 8, 6, 14, 10, 2, 12, 3, 4, 7, 13},
+}
+
+// g is the quarter round, small enough to be inlined.
+func g(a, b, c, d, mx, my uint32) (uint32, uint32, uint32, uint32) {
+	a += b + mx
+	d = bits.RotateLeft32(d^a, -16)
+	c += d
+	b = bits.RotateLeft32(b^c, -12)
+	a += b + my
+	d = bits.RotateLeft32(d^a, -8)
+	c += d
+	b = bits.RotateLeft32(b^c, -7)
+	return a, b, c, d
+}
+
+func compress(cv *[8]uint32, m *[16]uint32, counter uint64, blockLen, flags uint32) [16]uint32 {
+	s0, s1, s2, s3, s4, s5, s6, s7 := cv[0], cv[1], cv[2], cv[3], cv[4], cv[5], cv[6], cv[7]
+	s8, s9, s10, s11 := iv[0], iv[1], iv[2], iv[3]
+	s12, s13, s14, s15 := uint32(counter), uint32(counter>>32), blockLen, flags
+
+	for r := range schedule {
+		w := &schedule[r]
+		// Mix the columns.
+		s0, s4, s8, s12 = g(s0, s4, s8, s12, m[w[0]], m[w[1]])
+		s1, s5, s9, s13 = g(s1, s5, s9, s13, m[w[2]], m[w[3]])
+		s2, s6, s10, s14 = g(s2, s6, s10, s14, m[w[4]], m[w[5]])
+		s3, s7, s11, s15 = g(s3, s7, s11, s15, m[w[6]], m[w[7]])
+		// Mix the diagonals.
+		s0, s5, s10, s15 = g(s0, s5, s10, s15, m[w[8]], m[w[9]])
+		s1, s6, s11, s12 = g(s1, s6, s11, s12, m[w[10]], m[w[11]])
+		s2, s7, s8, s13 = g(s2, s7, s8, s13, m[w[12]], m[w[13]])
+		s3, s4, s9, s14 = g(s3, s4, s9, s14, m[w[14]], m[w[15]])
+	}
+
+	return [16]uint32{
+		s0 ^ s8, s1 ^ s9, s2 ^ s10, s3 ^ s11, s4 ^ s12, s5 ^ s13, s6 ^ s14, s7 ^ s15,
+		s8 ^ cv[0], s9 ^ cv[1], s10 ^ cv[2], s11 ^ cv[3], s12 ^ cv[4], s13 ^ cv[5], s14 ^ cv[6], s15 ^ cv[7],
+	}
+}
+
+func wordsFromBytes(b []byte, words *[16]uint32) {
+	for i := range words {
+		words[i] = binary.LittleEndian.Uint32(b[4*i:])
+	}
+}
+
+func firstEight(s [16]uint32) [8]uint32 {
+	var cv [8]uint32
+	copy(cv[:], s[:8])
+	return cv
+}
+
+// output is the input to the last compression of a chunk or parent node,
+// which is done differently for the root node.
+type output struct {
+	cv       [8]uint32
+	block    [16]uint32
+	counter  uint64
+	blockLen uint32
+	flags    uint32
+}
+
+func (o output) chainingValue() [8]uint32 {
+	return firstEight(compress(&o.cv, &o.block, o.counter, o.blockLen, o.flags))
+}
+
+func (o output) rootBytes(b []byte) []byte {
+	s := compress(&o.cv, &o.block, 0, o.blockLen, o.flags|flagRoot)
+	for _, w := range s[:Size/4] {
+		b = append(b, byte(w), byte(w>>8), byte(w>>16), byte(w>>24))
+	}
+	return b
+}
+
+func parentOutput(left, right [8]uint32) output {
+	o := output{cv: iv, blockLen: BlockSize, flags: flagParent}
+	copy(o.block[:8], left[:])
+	copy(o.block[8:], right[:])
+	return o
+}
+
+type chunkState struct {
+	cv               [8]uint32
+	counter          uint64
+	block            [BlockSize]byte
+	blockLen         int
+	blocksCompressed int
+}
+
+func newChunkState(counter uint64) chunkState {
+	return chunkState{cv: iv, counter: counter}
+}
+
+func (c *chunkState) len() int {
+	return BlockSize*c.blocksCompressed + c.blockLen
+}
+
+func (c *chunkState) startFlag() uint32 {
+	if c.blocksCompressed == 0 {
+		return flagChunkStart
+	}
+	return 0
+}
+
+func (c *chunkState) update(p []byte) {
+	for len(p) > 0 {
+		// The last block of a chunk is compressed differently, so a full
+		// block is only compressed once there is more input.
+		if c.blockLen == BlockSize {
+			var words [16]uint32
+			wordsFromBytes(c.block[:], &words)
+			c.cv = firstEight(compress(&c.cv, &words, c.counter, BlockSize, c.startFlag()))
+			c.blocksCompressed++
+			c.block = [BlockSize]byte{}
+			c.blockLen = 0
+		}
+		n := copy(c.block[c.blockLen:], p)
+		c.blockLen += n
+		p = p[n:]
+	}
+}
+
+func (c *chunkState) output() output {
+	o := output{
+		cv:       c.cv,
+		counter:  c.counter,
+		blockLen: uint32(c.blockLen),
+		flags:    c.startFlag() | flagChunkEnd,
+	}
+	wordsFromBytes(c.block[:], &o.block)
+	return o
+}
+
+type digest struct {
+	chunk chunkState
+	// The chaining values of completed subtrees, whose number is at most
+	// the number of bits in the chunk counter.
+	stack [54][8]uint32
+	depth int
+}
+
+// New returns a new hash.Hash computing the BLAKE3 checksum.
+func New() hash.Hash {
+	d := new(digest)
+	d.Reset()
+	return d
+}
+
+// Sum256 returns the BLAKE3 checksum of the data.
+func Sum256(data []byte) [Size]byte {
+	var d digest
+	d.Reset()
+	d.Write(data)
+	var sum [Size]byte
+	d.Sum(sum[:0])
+	return sum
+}
+
+func (d *digest) Size() int      { return Size }
+func (d *digest) BlockSize() int { return BlockSize }
+
+func (d *digest) Reset() {
+	d.chunk = newChunkState(0)
+	d.depth = 0
+}
+
+// addChunk merges the chaining value of a completed chunk into the stack of
+// subtrees. The number of trailing zero bits of the total number of chunks
+// is the number of subtrees that are completed by it.
+func (d *digest) addChunk(cv [8]uint32, total uint64) {
+	for total&1 == 0 {
+		d.depth--
+		cv = parentOutput(d.stack[d.depth], cv).chainingValue()
+		total >>= 1
+	}
+	d.stack[d.depth] = cv
+	d.depth++
+}
+
+func (d *digest) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		// As with blocks, a full chunk is only finished once there is
+		// more input, as the last one may be the root.
+		if d.chunk.len() == chunkLen {
+			total := d.chunk.counter + 1
+			d.addChunk(d.chunk.output().chainingValue(), total)
+			d.chunk = newChunkState(total)
+		}
+		take := chunkLen - d.chunk.len()
+		if take > len(p) {
+			take = len(p)
+		}
+		d.chunk.update(p[:take])
+		p = p[take:]
+	}
+	return n, nil
+}
+
+func (d *digest) Sum(b []byte) []byte {
+	o := d.chunk.output()
+	for i := d.depth - 1; i >= 0; i-- {
+		o = parentOutput(d.stack[i], o.chainingValue())
+	}
+	return o.rootBytes(b)
+}
@@ -0,0 +1,81 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package blake3
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// From the official test vectors, whose input is the repeating sequence of
+// bytes 0 to 250.
+var testVectors = []struct {
+	len  int
+	hash string
+}{
+	{0, "af1349b9f5f9a1a6a0404dea36dcc9499bcb25c9adc112b7cc9a93cae41f3262"},
+	{1, "2d3adedff11b61f14c886e35afa036736dcd87a74d27b5c1510225d0f592e213"},
+	{1023, "10108970eeda3eb932baac1428c7a2163b0e924c9a9e25b35bba72b28f70bd11"},
+	{1024, "42214739f095a406f3fc83deb889744ac00df831c10daa55189b5d121c855af7"},
+	{1025, "d00278ae47eb27b34faecf67b4fe263f82d5412916c1ffd97c8cb7fb814b8444"},
+	{2048, "e776b6028c7cd22a4d0ba182a8bf62205d2ef576467e838ed6f2529b85fba24a"},
+}
+
+func testInput(n int) []byte {
+	buf := make([]byte, n)
+	for i := range buf {
+		buf[i] = byte(i % 251)
+	}
+	return buf
+}
+
+func TestSum256(t *testing.T) {
+	for _, tc := range testVectors {
+		sum := Sum256(testInput(tc.len))
+		if got := hex.EncodeToString(sum[:]); got != tc.hash {
+			t.Errorf("length %d: got %s, expected %s", tc.len, got, tc.hash)
+		}
+	}
+}
+
+func TestIncrementalWrites(t *testing.T) {
+	// Writes of any size, across block and chunk boundaries, give the same
+	// result as a single one. Summing doesn't change the state.
+	input := testInput(100 << 10)
+	expected := Sum256(input)
+
+	for _, size := range []int{1, 63, 64, 65, 1000, 1024, 4097} {
+		h := New()
+		for rest := input; len(rest) > 0; {
+			n := size
+			if n > len(rest) {
+				n = len(rest)
+			}
+			h.Write(rest[:n])
+			rest = rest[n:]
+			h.Sum(nil)
+		}
+		if got := h.Sum(nil); string(got) != string(expected[:]) {
+			t.Errorf("writes of %d bytes: got %x, expected %x", size, got, expected)
+		}
+
+		h.Reset()
+		h.Write(input)
+		if got := h.Sum(nil); string(got) != string(expected[:]) {
+			t.Errorf("after reset: got %x, expected %x", got, expected)
+		}
+	}
+}
+
+func BenchmarkWrite(b *testing.B) {
+	buf := testInput(128 << 10)
+	h := New()
+	b.SetBytes(int64(len(buf)))
+	for i := 0; i < b.N; i++ {
+		h.Write(buf)
+	}
+}
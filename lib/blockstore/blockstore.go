@@ -0,0 +1,256 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// Package blockstore implements a content addressed cache of blocks on
+// disk. Each block is stored once, regardless of how many files in how many
+// folders it appears in, and the least recently used blocks are evicted to
+// keep the cache within its size limit.
+package blockstore
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/rand"
+	"github.com/syncthing/syncthing/lib/sha256"
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+const (
+	hashLength = 32
+	tempSuffix = ".tmp"
+)
+
+var (
+	ErrNotFound = errors.New("block not in store")
+	errTooLarge = errors.New("block larger than the store")
+	errBadHash  = errors.New("invalid block hash")
+)
+
+// A Store holds blocks by their SHA-256 hash, in files named by the hex
+// encoded hash under a directory per first byte.
+type Store struct {
+	fs      fs.Filesystem
+	maxSize int64
+
+	mut     sync.Mutex
+	size    int64
+	lru     *list.List // of *entry, most recently used first
+	entries map[string]*list.Element
+}
+
+type entry struct {
+	key  string
+	size int64
+}
+
+// Open opens the store in the given directory, creating it if necessary,
+// and evicts blocks as necessary to make it fit in maxSize bytes.
+func Open(dir string, maxSize int64) (*Store, error) {
+	s := &Store{
+		fs:      fs.NewFilesystem(fs.FilesystemTypeBasic, dir),
+		maxSize: maxSize,
+		mut:     sync.NewMutex(),
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+	if err := s.fs.MkdirAll(".", 0700); err != nil {
+		return nil, err
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	s.mut.Lock()
+	s.evictLocked()
+	s.mut.Unlock()
+
+	return s, nil
+}
+
+// load indexes the blocks already in the store, taking the modification
+// time of each as its last use.
+func (s *Store) load() error {
+	type found struct {
+		key     string
+		size    int64
+		modTime time.Time
+	}
+	var blocks []found
+
+	err := s.fs.Walk(".", func(path string, info fs.FileInfo, err error) error {
+		if err != nil || !info.IsRegular() {
+			return nil
+		}
+		if filepath.Ext(path) == tempSuffix {
+			// Left over from an interrupted Put.
+			s.fs.Remove(path)
+			return nil
+		}
+		key := filepath.Base(path)
+		if hash, err := hex.DecodeString(key); err != nil || len(hash) != hashLength || path != keyPath(key) {
+			return nil
+		}
+		blocks = append(blocks, found{key, info.Size(), info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(blocks, func(a, b int) bool {
+		return blocks[a].modTime.After(blocks[b].modTime)
+	})
+	for _, b := range blocks {
+		s.entries[b.key] = s.lru.PushBack(&entry{key: b.key, size: b.size})
+		s.size += b.size
+	}
+	return nil
+}
+
+// Get returns the block with the given hash. Blocks that turn out not to
+// match their hash are removed, and ErrNotFound returned.
+func (s *Store) Get(hash []byte) ([]byte, error) {
+	if len(hash) != hashLength {
+		return nil, ErrNotFound
+	}
+	key := hex.EncodeToString(hash)
+
+	s.mut.Lock()
+	elem, ok := s.entries[key]
+	if ok {
+		s.lru.MoveToFront(elem)
+	}
+	s.mut.Unlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	fd, err := s.fs.Open(keyPath(key))
+	if err != nil {
+		s.forget(key)
+		return nil, ErrNotFound
+	}
+	defer fd.Close()
+	data, err := ioutil.ReadAll(fd)
+	if err != nil {
+		return nil, err
+	}
+
+	if actual := sha256.Sum256(data); !bytes.Equal(actual[:], hash) {
+		s.forget(key)
+		s.fs.Remove(keyPath(key))
+		return nil, ErrNotFound
+	}
+
+	// Keep the last use across restarts. Not important enough to care
+	// about errors.
+	now := time.Now()
+	s.fs.Chtimes(keyPath(key), now, now)
+
+	return data, nil
+}
+
+// Has returns true if the block with the given hash is in the store.
+func (s *Store) Has(hash []byte) bool {
+	s.mut.Lock()
+	_, ok := s.entries[hex.EncodeToString(hash)]
+	s.mut.Unlock()
+	return ok
+}
+
+// Put adds the block with the given hash to the store, evicting the least
+// recently used blocks as necessary. The data must match the hash.
+func (s *Store) Put(hash, data []byte) error {
+	if len(hash) != hashLength {
+		return errBadHash
+	}
+	size := int64(len(data))
+	if size > s.maxSize {
+		return errTooLarge
+	}
+
+	key := hex.EncodeToString(hash)
+	if s.Has(hash) {
+		return nil
+	}
+
+	path := keyPath(key)
+	if err := s.fs.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	// Concurrent puts of the same block each write their own temp file.
+	tmp := path + "." + rand.String(8) + tempSuffix
+	fd, err := s.fs.Create(tmp)
+	if err != nil {
+		return err
+	}
+	_, err = fd.Write(data)
+	if cerr := fd.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = s.fs.Rename(tmp, path)
+	}
+	if err != nil {
+		s.fs.Remove(tmp)
+		return err
+	}
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if _, ok := s.entries[key]; ok {
+		// Raced with another Put of the same block.
+		return nil
+	}
+	s.entries[key] = s.lru.PushFront(&entry{key: key, size: size})
+	s.size += size
+	s.evictLocked()
+	return nil
+}
+
+// Size returns the total size of the blocks in the store.
+func (s *Store) Size() int64 {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.size
+}
+
+func (s *Store) forget(key string) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if elem, ok := s.entries[key]; ok {
+		s.removeLocked(elem)
+	}
+}
+
+func (s *Store) evictLocked() {
+	for s.size > s.maxSize {
+		elem := s.lru.Back()
+		if elem == nil {
+			return
+		}
+		s.removeLocked(elem)
+		s.fs.Remove(keyPath(elem.Value.(*entry).key))
+	}
+}
+
+func (s *Store) removeLocked(elem *list.Element) {
+	e := s.lru.Remove(elem).(*entry)
+	delete(s.entries, e.key)
+	s.size -= e.size
+}
+
+func keyPath(key string) string {
+	return filepath.Join(key[:2], key)
+}
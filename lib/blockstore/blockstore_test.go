@@ -0,0 +1,139 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package blockstore
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/sha256"
+)
+
+func block(i byte) ([]byte, []byte) {
+	data := bytes.Repeat([]byte{i}, 100)
+	hash := sha256.Sum256(data)
+	return hash[:], data
+}
+
+func TestPutGet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blockstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := Open(dir, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, data := block(1)
+	if _, err := s.Get(hash); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound before Put, got %v", err)
+	}
+	if err := s.Put(hash, data); err != nil {
+		t.Fatal(err)
+	}
+	// Storing the same block again doesn't take more space.
+	if err := s.Put(hash, data); err != nil {
+		t.Fatal(err)
+	}
+	if s.Size() != 100 {
+		t.Errorf("store has size %d, expected 100", s.Size())
+	}
+	if got, err := s.Get(hash); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(got, data) {
+		t.Error("got different data than was put")
+	}
+
+	// Blocks survive reopening the store.
+	s, err = Open(dir, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Has(hash) || s.Size() != 100 {
+		t.Error("block should be there after reopening")
+	}
+}
+
+func TestEviction(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blockstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := Open(dir, 250)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := byte(0); i < 2; i++ {
+		if err := s.Put(block(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Use the first block, so that the second one is evicted next.
+	hash0, _ := block(0)
+	if _, err := s.Get(hash0); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put(block(2)); err != nil {
+		t.Fatal(err)
+	}
+
+	hash1, _ := block(1)
+	hash2, _ := block(2)
+	if !s.Has(hash0) || s.Has(hash1) || !s.Has(hash2) {
+		t.Error("expected the least recently used block to be evicted")
+	}
+	if s.Size() != 200 {
+		t.Errorf("store has size %d, expected 200", s.Size())
+	}
+
+	// Shrinking the store on open evicts more.
+	s, err = Open(dir, 150)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Size() != 100 {
+		t.Errorf("store has size %d after reopening smaller, expected 100", s.Size())
+	}
+}
+
+func TestCorruptBlock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blockstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := Open(dir, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, data := block(1)
+	if err := s.Put(hash, data); err != nil {
+		t.Fatal(err)
+	}
+
+	key := filepath.Join(dir, keyPath(hex.EncodeToString(hash)))
+	if err := ioutil.WriteFile(key, []byte("garbage"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(hash); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound for corrupt block, got %v", err)
+	}
+	if s.Has(hash) || s.Size() != 0 {
+		t.Error("corrupt block should have been removed")
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// Package chaos injects faults into the filesystem, the protocol and the
+// database, to exercise failure handling that is hard to trigger
+// otherwise. Faults can only be injected in builds with the chaos tag; in
+// all other builds the hooks compile to nothing.
+package chaos
+
+import (
+	"errors"
+
+	"github.com/syncthing/syncthing/lib/logger"
+)
+
+var (
+	l = logger.DefaultLogger.NewFacility("chaos", "Fault injection")
+
+	// ErrInjected is the underlying error of injected filesystem faults.
+	ErrInjected = errors.New("injected fault")
+
+	ErrDisabledByCompilation = errors.New("fault injection is not compiled in (build with -tags chaos)")
+)
+
+// Faults describes the faults to inject. The zero value injects nothing.
+type Faults struct {
+	FSErrorPct       int      `json:"fsErrorPct"`       // percentage of filesystem operations that fail
+	FSErrorPath      string   `json:"fsErrorPath"`      // only fail operations on paths containing this, if set
+	DropMessagePct   int      `json:"dropMessagePct"`   // percentage of outgoing protocol messages silently dropped
+	DropMessageTypes []string `json:"dropMessageTypes"` // only drop messages of these types, e.g. "REQUEST", if set
+	DBCommitDelayMs  int      `json:"dbCommitDelayMs"`  // delay before each database commit
+}
+
+func (f Faults) validate() error {
+	if f.FSErrorPct < 0 || f.FSErrorPct > 100 || f.DropMessagePct < 0 || f.DropMessagePct > 100 {
+		return errors.New("percentages must be between 0 and 100")
+	}
+	if f.DBCommitDelayMs < 0 {
+		return errors.New("commit delay must not be negative")
+	}
+	return nil
+}
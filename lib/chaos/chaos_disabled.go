@@ -0,0 +1,29 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// +build !chaos
+
+package chaos
+
+const Enabled = false
+
+func Set(Faults) error {
+	return ErrDisabledByCompilation
+}
+
+func Current() Faults {
+	return Faults{}
+}
+
+func FSError(op, name string) error {
+	return nil
+}
+
+func DropMessage(msgType string) bool {
+	return false
+}
+
+func DelayDBCommit() {}
@@ -0,0 +1,95 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// +build chaos
+
+package chaos
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/rand"
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+const Enabled = true
+
+var (
+	mut     = sync.NewMutex()
+	current Faults
+)
+
+func init() {
+	l.Warnln("This build has fault injection compiled in and is not suitable for real data")
+}
+
+// Set replaces the faults being injected.
+func Set(f Faults) error {
+	if err := f.validate(); err != nil {
+		return err
+	}
+	mut.Lock()
+	current = f
+	mut.Unlock()
+	l.Infof("Injecting faults: %+v", f)
+	return nil
+}
+
+// Current returns the faults being injected.
+func Current() Faults {
+	mut.Lock()
+	defer mut.Unlock()
+	return current
+}
+
+// FSError returns an error if the filesystem operation op on name should
+// fail, and nil otherwise.
+func FSError(op, name string) error {
+	f := Current()
+	if f.FSErrorPct == 0 || !strings.Contains(name, f.FSErrorPath) {
+		return nil
+	}
+	if rand.Intn(100) >= f.FSErrorPct {
+		return nil
+	}
+	l.Debugln("Failing", op, name)
+	return &os.PathError{Op: op, Path: name, Err: ErrInjected}
+}
+
+// DropMessage returns whether an outgoing message of the given type, as
+// given by protocol.MessageType.String(), should be dropped.
+func DropMessage(msgType string) bool {
+	f := Current()
+	if f.DropMessagePct == 0 {
+		return false
+	}
+	if len(f.DropMessageTypes) > 0 {
+		found := false
+		for _, t := range f.DropMessageTypes {
+			if strings.EqualFold(t, msgType) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if rand.Intn(100) >= f.DropMessagePct {
+		return false
+	}
+	l.Debugln("Dropping", msgType, "message")
+	return true
+}
+
+// DelayDBCommit sleeps for the configured database commit delay.
+func DelayDBCommit() {
+	if d := Current().DBCommitDelayMs; d > 0 {
+		time.Sleep(time.Duration(d) * time.Millisecond)
+	}
+}
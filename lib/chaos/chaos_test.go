@@ -0,0 +1,49 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// +build chaos
+
+package chaos
+
+import (
+	"os"
+	"testing"
+)
+
+func TestFaults(t *testing.T) {
+	defer Set(Faults{})
+
+	if err := Set(Faults{FSErrorPct: 101}); err == nil {
+		t.Error("expected an error for an invalid percentage")
+	}
+
+	if err := Set(Faults{FSErrorPct: 100, FSErrorPath: "dir/", DropMessagePct: 100, DropMessageTypes: []string{"request"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := FSError("open", "dir/file"); err == nil {
+		t.Error("expected an error on a matching path")
+	} else if pe, ok := err.(*os.PathError); !ok || pe.Err != ErrInjected {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err := FSError("open", "other/file"); err != nil {
+		t.Errorf("unexpected error %v on a path not matching", err)
+	}
+
+	if !DropMessage("REQUEST") {
+		t.Error("expected a request to be dropped")
+	}
+	if DropMessage("INDEX") {
+		t.Error("expected an index not to be dropped")
+	}
+
+	if err := Set(Faults{}); err != nil {
+		t.Fatal(err)
+	}
+	if FSError("open", "dir/file") != nil || DropMessage("REQUEST") {
+		t.Error("expected no faults after reset")
+	}
+}
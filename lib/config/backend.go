@@ -0,0 +1,48 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package config
+
+// Backend is the kind of database keeping the index.
+type Backend int
+
+const (
+	// N.b. these constants must match those in lib/db/backend.Type!
+	BackendLevelDB Backend = iota // default is leveldb
+	BackendBadger
+	BackendSQLite
+)
+
+func (b Backend) String() string {
+	switch b {
+	case BackendLevelDB:
+		return "leveldb"
+	case BackendBadger:
+		return "badger"
+	case BackendSQLite:
+		return "sqlite"
+	default:
+		return "unknown"
+	}
+}
+
+func (b Backend) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+func (b *Backend) UnmarshalText(bs []byte) error {
+	switch string(bs) {
+	case "leveldb":
+		*b = BackendLevelDB
+	case "badger":
+		*b = BackendBadger
+	case "sqlite":
+		*b = BackendSQLite
+	default:
+		*b = BackendLevelDB
+	}
+	return nil
+}
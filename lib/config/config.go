@@ -40,6 +40,8 @@ var (
 	DefaultTCPPort = 22000
 	// DefaultQUICPort defines default QUIC port used if the URI does not specify one, for example quic://0.0.0.0
 	DefaultQUICPort = 22000
+	// DefaultWSSPort defines default WebSocket port used if the URI does not specify one, for example wss://0.0.0.0
+	DefaultWSSPort = 443
 	// DefaultListenAddresses should be substituted when the configuration
 	// contains <listenAddress>default</listenAddress>. This is done by the
 	// "consumer" of the configuration as we don't want these saved to the
@@ -96,6 +98,7 @@ var (
 	errFolderIDEmpty     = errors.New("folder has empty ID")
 	errFolderIDDuplicate = errors.New("folder has duplicate ID")
 	errFolderPathEmpty   = errors.New("folder has empty path")
+	errSQLiteUnsupported = errors.New("sqlite database backend not supported by this build")
 )
 
 func New(myID protocol.DeviceID) Configuration {
@@ -191,6 +194,7 @@ type Configuration struct {
 	Options        OptionsConfiguration  `xml:"options" json:"options"`
 	IgnoredDevices []ObservedDevice      `xml:"remoteIgnoredDevice" json:"remoteIgnoredDevices"`
 	PendingDevices []ObservedDevice      `xml:"pendingDevice" json:"pendingDevices"`
+	Decommissioned []ObservedDevice      `xml:"decommissionedDevice" json:"decommissionedDevices"`
 	XMLName        xml.Name              `xml:"configuration" json:"-"`
 
 	MyID            protocol.DeviceID `xml:"-" json:"-"` // Provided by the instantiator.
@@ -222,6 +226,9 @@ func (cfg Configuration) Copy() Configuration {
 	newCfg.PendingDevices = make([]ObservedDevice, len(cfg.PendingDevices))
 	copy(newCfg.PendingDevices, cfg.PendingDevices)
 
+	newCfg.Decommissioned = make([]ObservedDevice, len(cfg.Decommissioned))
+	copy(newCfg.Decommissioned, cfg.Decommissioned)
+
 	return newCfg
 }
 
@@ -305,6 +312,10 @@ func (cfg *Configuration) clean() error {
 		existingFolders[folder.ID] = folder
 	}
 
+	if cfg.Options.DatabaseBackend == BackendSQLite && !sqliteSupported {
+		return errSQLiteUnsupported
+	}
+
 	cfg.Options.RawListenAddresses = util.UniqueTrimmedStrings(cfg.Options.RawListenAddresses)
 	cfg.Options.RawGlobalAnnServers = util.UniqueTrimmedStrings(cfg.Options.RawGlobalAnnServers)
 
@@ -370,6 +381,17 @@ func (cfg *Configuration) clean() error {
 	}
 	cfg.IgnoredDevices = newIgnoredDevices
 
+	// Likewise for decommissioned devices, which are otherwise treated as
+	// ignored.
+	var newDecommissioned []ObservedDevice
+	for _, dev := range cfg.Decommissioned {
+		if !existingDevices[dev.ID] {
+			ignoredDevices[dev.ID] = true
+			newDecommissioned = append(newDecommissioned, dev)
+		}
+	}
+	cfg.Decommissioned = newDecommissioned
+
 	// The list of pending devices should not contain devices that were added manually, nor should it contain
 	// ignored devices.
 
@@ -413,12 +435,21 @@ nextPendingDevice:
 	if cfg.PendingDevices == nil {
 		cfg.PendingDevices = []ObservedDevice{}
 	}
+	if cfg.Decommissioned == nil {
+		cfg.Decommissioned = []ObservedDevice{}
+	}
 	if cfg.Options.AlwaysLocalNets == nil {
 		cfg.Options.AlwaysLocalNets = []string{}
 	}
 	if cfg.Options.UnackedNotificationIDs == nil {
 		cfg.Options.UnackedNotificationIDs = []string{}
 	}
+	if cfg.Options.RawExternalAddresses == nil {
+		cfg.Options.RawExternalAddresses = []string{}
+	}
+	if cfg.Options.UpgradeSigningKeys == nil {
+		cfg.Options.UpgradeSigningKeys = []string{}
+	}
 
 	return nil
 }
@@ -75,6 +75,10 @@ func TestDefaultValues(t *testing.T) {
 		StunKeepaliveStartS:     180,
 		StunKeepaliveMinS:       20,
 		RawStunServers:          []string{"default"},
+		RawExternalAddresses:    []string{},
+		ExternalAddressResolveS: 300,
+		UpgradeSigningKeys:      []string{},
+		UpgradeMinSignatures:    1,
 	}
 
 	cfg := New(device1)
@@ -223,6 +227,19 @@ func TestOverriddenValues(t *testing.T) {
 		StunKeepaliveStartS:     9000,
 		StunKeepaliveMinS:       900,
 		RawStunServers:          []string{"foo"},
+		RawExternalAddresses:    []string{"tcp://myhost.example.com:${port}"},
+		ExternalAddressResolveS: 60,
+		StuckScanTimeoutS:       600,
+		StuckSyncTimeoutS:       1200,
+		UpgradeSigningKeys:      []string{"key1", "key2"},
+		UpgradeMinSignatures:    2,
+		UpgradeTransparencyLog:  "https://localhost/log",
+		BlockCacheMiB:           512,
+		MaxRequestReads:         2,
+		MaxRequestReadKiBs:      10240,
+		IndexBatchKiB:           256,
+		IndexBatchFiles:         100,
+		IndexReceiveWindowKiB:   4096,
 	}
 
 	os.Unsetenv("STNOUPGRADE")
@@ -654,6 +671,49 @@ func TestPullOrder(t *testing.T) {
 	}
 }
 
+func TestDurability(t *testing.T) {
+	wrapper, err := load("testdata/durability.xml", device1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []struct {
+		name       string
+		durability Durability
+	}{
+		{"f1", DurabilityData}, // empty value, default
+		{"f2", DurabilityNone}, // explicit
+		{"f3", DurabilityData}, // explicit
+		{"f4", DurabilityFull}, // explicit
+		{"f5", DurabilityData}, // unknown value, default
+	}
+
+	check := func(folders map[string]FolderConfiguration) {
+		t.Helper()
+		for _, tc := range expected {
+			if actual := folders[tc.name].Durability; actual != tc.durability {
+				t.Errorf("Incorrect durability for %q: %v != %v", tc.name, actual, tc.durability)
+			}
+		}
+	}
+	check(wrapper.Folders())
+
+	// Serialize and deserialize again to verify it survives the transformation
+
+	buf := new(bytes.Buffer)
+	cfg := wrapper.RawCopy()
+	cfg.WriteXML(buf)
+	cfg, err = ReadXML(buf, device1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(wrap("testdata/durability.xml", cfg).Folders())
+
+	if !DurabilityData.SyncFiles() || !DurabilityFull.SyncDirs() || DurabilityNone.SyncFiles() || DurabilityNone.SyncDirs() {
+		t.Error("Unexpected fsync policy")
+	}
+}
+
 func TestLargeRescanInterval(t *testing.T) {
 	wrapper, err := load("testdata/largeinterval.xml", device1)
 	if err != nil {
@@ -1154,3 +1214,41 @@ func load(path string, myID protocol.DeviceID) (Wrapper, error) {
 func wrap(path string, cfg Configuration) Wrapper {
 	return Wrap(path, cfg, events.NoopLogger)
 }
+
+func TestTempFilesystemPerFolder(t *testing.T) {
+	tempPath, err := ioutil.TempDir("", "syncthing-temppath-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempPath)
+
+	seen := make(map[string]string)
+	for _, id := range []string{"abcde-fghij", "a b", "a+b", "a/b", "..", ".", "a%2Eb"} {
+		fcfg := NewFolderConfiguration(protocol.LocalDeviceID, id, "", fs.FilesystemTypeBasic, "folder")
+		fcfg.TempPath = tempPath
+		uri := fcfg.TempFilesystem().URI()
+		if filepath.Dir(uri) != tempPath {
+			t.Errorf("temp filesystem of %q at %q, not directly in the temp path", id, uri)
+		}
+		if other, ok := seen[uri]; ok {
+			t.Errorf("folders %q and %q share temp filesystem %q", id, other, uri)
+		}
+		seen[uri] = id
+	}
+
+	fcfg := NewFolderConfiguration(protocol.LocalDeviceID, "abcde-fghij", "", fs.FilesystemTypeBasic, "folder")
+	if uri := fcfg.TempFilesystem().URI(); uri != fcfg.Filesystem().URI() {
+		t.Errorf("expected the folder itself without a temp path, got %q", uri)
+	}
+}
+
+func TestSQLiteBackendRequiresSupport(t *testing.T) {
+	cfg := New(device1)
+	cfg.Options.DatabaseBackend = BackendSQLite
+	err := cfg.prepare(device1)
+	if sqliteSupported && err != nil {
+		t.Error("Unexpected error for a supported backend:", err)
+	} else if !sqliteSupported && err != errSQLiteUnsupported {
+		t.Error("Expected error for an unsupported backend, got", err)
+	}
+}
@@ -14,22 +14,29 @@ import (
 )
 
 type DeviceConfiguration struct {
-	DeviceID                 protocol.DeviceID    `xml:"id,attr" json:"deviceID"`
-	Name                     string               `xml:"name,attr,omitempty" json:"name"`
-	Addresses                []string             `xml:"address,omitempty" json:"addresses" default:"dynamic"`
-	Compression              protocol.Compression `xml:"compression,attr" json:"compression"`
-	CertName                 string               `xml:"certName,attr,omitempty" json:"certName"`
-	Introducer               bool                 `xml:"introducer,attr" json:"introducer"`
-	SkipIntroductionRemovals bool                 `xml:"skipIntroductionRemovals,attr" json:"skipIntroductionRemovals"`
-	IntroducedBy             protocol.DeviceID    `xml:"introducedBy,attr" json:"introducedBy"`
-	Paused                   bool                 `xml:"paused" json:"paused"`
-	AllowedNetworks          []string             `xml:"allowedNetwork,omitempty" json:"allowedNetworks"`
-	AutoAcceptFolders        bool                 `xml:"autoAcceptFolders" json:"autoAcceptFolders"`
-	MaxSendKbps              int                  `xml:"maxSendKbps" json:"maxSendKbps"`
-	MaxRecvKbps              int                  `xml:"maxRecvKbps" json:"maxRecvKbps"`
-	IgnoredFolders           []ObservedFolder     `xml:"ignoredFolder" json:"ignoredFolders"`
-	PendingFolders           []ObservedFolder     `xml:"pendingFolder" json:"pendingFolders"`
-	MaxRequestKiB            int                  `xml:"maxRequestKiB" json:"maxRequestKiB"`
+	DeviceID                 protocol.DeviceID             `xml:"id,attr" json:"deviceID"`
+	Name                     string                        `xml:"name,attr,omitempty" json:"name"`
+	Addresses                []string                      `xml:"address,omitempty" json:"addresses" default:"dynamic"`
+	Compression              protocol.Compression          `xml:"compression,attr" json:"compression"`
+	CompressionAlgorithm     protocol.CompressionAlgorithm `xml:"compressionAlgorithm,attr" json:"compressionAlgorithm"`
+	CertName                 string                        `xml:"certName,attr,omitempty" json:"certName"`
+	Introducer               bool                          `xml:"introducer,attr" json:"introducer"`
+	SkipIntroductionRemovals bool                          `xml:"skipIntroductionRemovals,attr" json:"skipIntroductionRemovals"`
+	IntroducedBy             protocol.DeviceID             `xml:"introducedBy,attr" json:"introducedBy"`
+	Paused                   bool                          `xml:"paused" json:"paused"`
+	AllowedNetworks          []string                      `xml:"allowedNetwork,omitempty" json:"allowedNetworks"`
+	AutoAcceptFolders        bool                          `xml:"autoAcceptFolders" json:"autoAcceptFolders"`
+	MaxSendKbps              int                           `xml:"maxSendKbps" json:"maxSendKbps"`
+	MaxRecvKbps              int                           `xml:"maxRecvKbps" json:"maxRecvKbps"`
+	IgnoredFolders           []ObservedFolder              `xml:"ignoredFolder" json:"ignoredFolders"`
+	PendingFolders           []ObservedFolder              `xml:"pendingFolder" json:"pendingFolders"`
+	MaxRequestKiB            int                           `xml:"maxRequestKiB" json:"maxRequestKiB"`
+	Multipath                bool                          `xml:"multipath" json:"multipath"`
+	Proxy                    string                        `xml:"proxy,omitempty" json:"proxy"`           // SOCKS5 proxy URL, or "direct"; overrides the environment
+	PadRelayed               bool                          `xml:"padRelayed" json:"padRelayed"`           // pad messages and send cover traffic when relayed, if the device does too
+	AllowRemoteScan          bool                          `xml:"allowRemoteScan" json:"allowRemoteScan"` // the device may ask us to rescan shared folders
+	SettingsPolicy           SettingsPolicy                `xml:"settingsPolicy" json:"settingsPolicy"`   // what to do with folder settings the device pushes
+	DecommissionPolicy       SettingsPolicy                `xml:"decommissionPolicy" json:"decommissionPolicy"`
 }
 
 func NewDeviceConfiguration(id protocol.DeviceID, name string) DeviceConfiguration {
@@ -0,0 +1,59 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package config
+
+// Durability is how much a folder makes sure that pulled files are on disk
+// before they are recorded as done, at the cost of waiting on the disk.
+type Durability int
+
+const (
+	DurabilityData Durability = iota // default is to fsync file contents, and directories in batches
+	DurabilityNone                   // leave writing back to the operating system
+	DurabilityFull                   // also fsync the directory after every file is moved into place
+)
+
+func (d Durability) String() string {
+	switch d {
+	case DurabilityData:
+		return "data"
+	case DurabilityNone:
+		return "none"
+	case DurabilityFull:
+		return "full"
+	default:
+		return "unknown"
+	}
+}
+
+// SyncFiles returns whether the contents of pulled files are fsynced
+// before they are moved into place.
+func (d Durability) SyncFiles() bool {
+	return d != DurabilityNone
+}
+
+// SyncDirs returns whether directories are fsynced after changes to them.
+func (d Durability) SyncDirs() bool {
+	return d != DurabilityNone
+}
+
+func (d Durability) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Durability) UnmarshalText(bs []byte) error {
+	switch string(bs) {
+	case "data":
+		*d = DurabilityData
+	case "none":
+		*d = DurabilityNone
+	case "full":
+		*d = DurabilityFull
+	default:
+		*d = DurabilityData
+	}
+	return nil
+}
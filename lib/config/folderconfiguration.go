@@ -9,6 +9,8 @@ package config
 import (
 	"errors"
 	"fmt"
+	"net/url"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"time"
@@ -40,6 +42,7 @@ type FolderConfiguration struct {
 	FSWatcherDelayS         int                         `xml:"fsWatcherDelayS,attr" json:"fsWatcherDelayS" default:"10"`
 	IgnorePerms             bool                        `xml:"ignorePerms,attr" json:"ignorePerms"`
 	AutoNormalize           bool                        `xml:"autoNormalize,attr" json:"autoNormalize" default:"true"`
+	Normalization           fs.Normalization            `xml:"normalization" json:"normalization"` // Unicode normalization of file names: auto, nfc, nfd or preserve
 	MinDiskFree             Size                        `xml:"minDiskFree" json:"minDiskFree" default:"1%"`
 	Versioning              VersioningConfiguration     `xml:"versioning" json:"versioning"`
 	Copiers                 int                         `xml:"copiers" json:"copiers"` // This defines how many files are handled concurrently.
@@ -57,6 +60,50 @@ type FolderConfiguration struct {
 	MarkerName              string                      `xml:"markerName" json:"markerName"`
 	CopyOwnershipFromParent bool                        `xml:"copyOwnershipFromParent" json:"copyOwnershipFromParent"`
 	RawModTimeWindowS       int                         `xml:"modTimeWindowS" json:"modTimeWindowS"`
+	TempPath                string                      `xml:"tempPath" json:"tempPath"`                             // Where to keep temporary files while pulling, if not in the folder itself.
+	CaseInsensitiveIgnores  bool                        `xml:"caseInsensitiveIgnores" json:"caseInsensitiveIgnores"` // Ignore patterns match regardless of case, unless prefixed with (?-i).
+	VerifyAfterWrite        bool                        `xml:"verifyAfterWrite" json:"verifyAfterWrite"`             // Re-read and verify pulled files before moving them into place.
+	ScrubIntervalS          int                         `xml:"scrubIntervalS" json:"scrubIntervalS"`                 // Time between background passes verifying all file contents. Zero disables.
+	ScrubRateKiBs           int                         `xml:"scrubRateKiBs" json:"scrubRateKiBs"`                   // Read rate limit when verifying. Zero means 1 MiB/s.
+	ScrubAutoRepair         bool                        `xml:"scrubAutoRepair" json:"scrubAutoRepair"`               // Fetch corrupted blocks from other devices.
+	WebDAVEnabled           bool                        `xml:"webdavEnabled" json:"webdavEnabled"`                   // Serve the folder under /webdav/<id>/ in the GUI server.
+	WebDAVReadOnly          bool                        `xml:"webdavReadOnly" json:"webdavReadOnly"`                 // Reject changes made over WebDAV.
+	UndoBufferSize          int                         `xml:"undoBufferSize" json:"undoBufferSize"`                 // Number of files deleted or replaced by remote changes to keep for undo. Zero disables.
+	SyncXattrs              bool                        `xml:"syncXattrs" json:"syncXattrs"`                         // Sync extended attributes of files and directories.
+	SyncOwnership           bool                        `xml:"syncOwnership" json:"syncOwnership"`                   // Sync owners and groups by name rather than by numeric ID.
+	UserMappings            []NameMapping               `xml:"userMapping" json:"userMappings"`                      // Local users for owners on other devices, when syncing ownership.
+	GroupMappings           []NameMapping               `xml:"groupMapping" json:"groupMappings"`                    // Local groups for groups on other devices, when syncing ownership.
+	Placeholders            bool                        `xml:"placeholders" json:"placeholders"`                     // Represent remote files by zero-sized placeholders until their contents are requested.
+	MountPath               string                      `xml:"mountPath" json:"mountPath"`                           // Mount the global tree read only at this path using FUSE, where supported.
+	AcceptPolicy            AcceptPolicy                `xml:"acceptPolicy" json:"acceptPolicy"`                     // Which changes from other devices to apply.
+	CompressTempFiles       bool                        `xml:"compressTempFiles" json:"compressTempFiles"`           // Write pulled data compressed to temp files, unless the filesystem compresses by itself.
+	QuickStart              bool                        `xml:"quickStart" json:"quickStart"`                         // Check only a sample of files at startup and do the full scan in the background.
+	VirtualRoots            []VirtualRoot               `xml:"virtualRoot" json:"virtualRoots"`                      // Further directories, each appearing as a top level directory of the folder.
+	TieringColdDays         int                         `xml:"tieringColdDays" json:"tieringColdDays"`               // Offload files neither accessed nor modified for this many days to the tiering path. Zero disables.
+	TieringFilesystemType   fs.FilesystemType           `xml:"tieringFilesystemType" json:"tieringFilesystemType"`   // The kind of filesystem at the tiering path.
+	TieringPath             string                      `xml:"tieringPath" json:"tieringPath"`                       // Where to keep the contents of offloaded files, which leave stubs in the folder.
+	ScanSummaryPaths        int                         `xml:"scanSummaryPaths" json:"scanSummaryPaths"`             // List up to this many changed paths of each kind in FolderScanSummary events.
+	PushSettings            bool                        `xml:"pushSettings" json:"pushSettings"`                     // Send the ignore patterns and versioning settings to the devices sharing the folder, for them to adopt if they accept settings from us.
+	ShareIgnores            bool                        `xml:"shareIgnores" json:"shareIgnores"`                     // Recommend the ignore patterns to the devices sharing the folder, which apply them after their own.
+	Subtrees                []string                    `xml:"subtree" json:"subtrees"`                              // Sync only these paths in the folder, asking the other devices to send index entries for them only; empty for all of it.
+	IgnorePresets           []string                    `xml:"ignorePreset" json:"ignorePresets"`                    // Named sets of ignore patterns that apply after those in .stignore.
+	AdaptiveRescan          bool                        `xml:"adaptiveRescan" json:"adaptiveRescan"`                 // Lengthen the rescan interval while the folder doesn't change and shorten it while it does, starting from rescanIntervalS.
+	RescanIntervalMinS      int                         `xml:"rescanIntervalMinS" json:"rescanIntervalMinS"`         // Shortest adaptive rescan interval. Zero means one minute.
+	RescanIntervalMaxS      int                         `xml:"rescanIntervalMaxS" json:"rescanIntervalMaxS"`         // Longest adaptive rescan interval. Zero means one day.
+	DataTransports          []string                    `xml:"dataTransport" json:"dataTransports"`                  // Transfer file data only over these transports, such as tcp or quic, or "lan" for any connection on the LAN; empty for all. Indexes are exchanged over any connection.
+	Disk                    string                      `xml:"disk" json:"disk"`                                     // Name of the physical disk the folder is on, for limiting hashing per disk; empty to detect it where possible.
+	DiskHashers             int                         `xml:"diskHashers" json:"diskHashers"`                       // Hash at most this many files at a time on the disk, across the folders on it; the lowest setting among them applies. Zero means one on spinning disks and no limit otherwise.
+	ContentDefinedChunking  bool                        `xml:"contentDefinedChunking" json:"contentDefinedChunking"` // Divide new and changed files into blocks at content defined boundaries (FastCDC), so that inserting data into a file only changes the blocks around it. Devices that don't understand such files see them as invalid.
+	BLAKE3Hashing           bool                        `xml:"blake3Hashing" json:"blake3Hashing"`                   // Hash the blocks of new and changed files with BLAKE3 rather than SHA-256, once every device sharing the folder has announced that it understands it.
+	StabilityWindowS        int                         `xml:"stabilityWindowS" json:"stabilityWindowS"`             // Leave new and changed files modified within this many seconds out of scans, as they are likely still being written, and scan them once they have been left alone for that long. Zero disables.
+	FSWatcherMode           fs.WatchMode                `xml:"fsWatcherMode" json:"fsWatcherMode"`                   // How to watch for changes: auto (hybrid on network shares, native elsewhere), native, hybrid (notifications, and re-polling the directories with recent changes, as notifications are unreliable on network shares) or poll (walking the whole folder every minute).
+	EncryptionPassphrase    string                      `xml:"encryptionPassphrase" json:"encryptionPassphrase"`     // Encrypts the names and contents of files on disk with this passphrase. Only for new, empty folders.
+	MaxFolderSize           Size                        `xml:"maxFolderSize" json:"maxFolderSize"`                   // Pulls that would make the folder larger than this are refused. Either an absolute size or a percentage of the disk. Zero means no limit.
+	SyncPlatformData        bool                        `xml:"syncPlatformData" json:"syncPlatformData"`             // Sync the alternate data streams of files and directories, and junctions, on Windows.
+	Durability              Durability                  `xml:"durability" json:"durability"`                         // How much to make sure pulled files are on disk: none (leave it to the operating system), data (fsync files, and directories in batches) or full (also fsync the directory after each file).
+	FileHistory             int                         `xml:"fileHistory" json:"fileHistory"`                       // Record the global versions of each file, with the device each came from and when, keeping the latest this many. Zero disables.
+	AuditLogEntries         int                         `xml:"auditLogEntries" json:"auditLogEntries"`               // Keep a log of the changes applied from other devices and of the local changes sent to them, with the latest this many entries. Zero disables.
+	TombstoneRetentionDays  int                         `xml:"tombstoneRetentionDays" json:"tombstoneRetentionDays"` // Forget deleted files this many days after every device sharing the folder has the deletion, if all of them are set to; the longest setting among them applies. Zero keeps them for good.
 
 	cachedFilesystem    fs.Filesystem
 	cachedModTimeWindow time.Duration
@@ -66,6 +113,36 @@ type FolderConfiguration struct {
 	DeprecatedPullers        int     `xml:"pullers,omitempty" json:"-"`
 }
 
+// A NameMapping makes items owned by a user or group of the remote name on
+// other devices be owned by the one of the local name here.
+type NameMapping struct {
+	Remote string `xml:"remote,attr" json:"remote"`
+	Local  string `xml:"local,attr" json:"local"`
+}
+
+// A VirtualRoot is a directory outside the folder path that is synced as
+// part of the folder, as a top level directory of the given name.
+type VirtualRoot struct {
+	Name string `xml:"name,attr" json:"name"`
+	Path string `xml:"path,attr" json:"path"`
+}
+
+// An AcceptPolicy limits which changes from other devices are applied to
+// the folder. Changes that aren't accepted stay out of sync and are listed
+// for review.
+type AcceptPolicy struct {
+	RejectDeletes           bool     `xml:"rejectDeletes" json:"rejectDeletes"`
+	RejectPermissionChanges bool     `xml:"rejectPermissionChanges" json:"rejectPermissionChanges"`
+	Patterns                []string `xml:"pattern" json:"patterns"` // If set, only files and symlinks matching one of these are accepted.
+}
+
+func (p AcceptPolicy) Copy() AcceptPolicy {
+	c := p
+	c.Patterns = make([]string, len(p.Patterns))
+	copy(c.Patterns, p.Patterns)
+	return c
+}
+
 type FolderDeviceConfiguration struct {
 	DeviceID     protocol.DeviceID `xml:"id,attr" json:"deviceID"`
 	IntroducedBy protocol.DeviceID `xml:"introducedBy,attr" json:"introducedBy"`
@@ -91,23 +168,113 @@ func (f FolderConfiguration) Copy() FolderConfiguration {
 	c.Devices = make([]FolderDeviceConfiguration, len(f.Devices))
 	copy(c.Devices, f.Devices)
 	c.Versioning = f.Versioning.Copy()
+	c.UserMappings = make([]NameMapping, len(f.UserMappings))
+	copy(c.UserMappings, f.UserMappings)
+	c.GroupMappings = make([]NameMapping, len(f.GroupMappings))
+	copy(c.GroupMappings, f.GroupMappings)
+	c.AcceptPolicy = f.AcceptPolicy.Copy()
+	c.VirtualRoots = make([]VirtualRoot, len(f.VirtualRoots))
+	copy(c.VirtualRoots, f.VirtualRoots)
+	c.Subtrees = append([]string(nil), f.Subtrees...)
+	c.IgnorePresets = append([]string(nil), f.IgnorePresets...)
+	c.DataTransports = append([]string(nil), f.DataTransports...)
 	return c
 }
 
+// LocalUserName returns the name of the local user that should own items
+// owned by the named user on other devices.
+func (f FolderConfiguration) LocalUserName(remote string) string {
+	return mapName(f.UserMappings, remote)
+}
+
+// LocalGroupName returns the name of the local group that items of the
+// named group on other devices should belong to.
+func (f FolderConfiguration) LocalGroupName(remote string) string {
+	return mapName(f.GroupMappings, remote)
+}
+
+func mapName(mappings []NameMapping, remote string) string {
+	for _, m := range mappings {
+		if m.Remote == remote {
+			return m.Local
+		}
+	}
+	return remote
+}
+
 func (f FolderConfiguration) Filesystem() fs.Filesystem {
 	// This is intentionally not a pointer method, because things like
 	// cfg.Folders["default"].Filesystem() should be valid.
 	if f.cachedFilesystem == nil {
 		l.Infoln("bug: uncached filesystem call (should only happen in tests)")
-		return fs.NewFilesystem(f.FilesystemType, f.Path)
+		return f.newFilesystem()
 	}
 	return f.cachedFilesystem
 }
 
+func (f FolderConfiguration) newFilesystem() fs.Filesystem {
+	filesystem := fs.NewWatchModeFilesystem(f.newBaseFilesystem(), f.FSWatcherMode)
+	if f.TieringColdDays > 0 && f.TieringPath != "" {
+		filesystem = fs.NewTieredFilesystem(filesystem, fs.NewEncryptedFilesystem(fs.NewFilesystem(f.TieringFilesystemType, f.TieringPath), f.EncryptionPassphrase))
+	}
+	return filesystem
+}
+
+func (f FolderConfiguration) newBaseFilesystem() fs.Filesystem {
+	// Virtual roots are encrypted each on their own, as they are separate
+	// directories on disk.
+	base := fs.NewEncryptedFilesystem(fs.NewFilesystem(f.FilesystemType, f.Path), f.EncryptionPassphrase)
+	if len(f.VirtualRoots) == 0 {
+		return base
+	}
+
+	roots := make(map[string]fs.Filesystem, len(f.VirtualRoots))
+	for _, root := range f.VirtualRoots {
+		// A root must be a single directory level, and can't hide the
+		// marker or ignores.
+		if root.Name == "" || root.Name != filepath.Base(root.Name) || root.Name == "." || root.Name == ".." || fs.IsInternal(root.Name) {
+			l.Warnf("Folder %s: ignoring virtual root with invalid name %q", f.Description(), root.Name)
+			continue
+		}
+		if _, ok := roots[root.Name]; ok {
+			l.Warnf("Folder %s: ignoring duplicate virtual root %q", f.Description(), root.Name)
+			continue
+		}
+		roots[root.Name] = fs.NewEncryptedFilesystem(fs.NewFilesystem(f.FilesystemType, root.Path), f.EncryptionPassphrase)
+	}
+	return fs.NewCompositeFilesystem(base, roots)
+}
+
+// TempFilesystem returns the filesystem that temporary files are created
+// in while pulling. This is the folder's own filesystem unless a TempPath
+// is set, in which case it's a directory in there named after the folder
+// ID, so that folders sharing a TempPath don't mix up their files.
+func (f FolderConfiguration) TempFilesystem() fs.Filesystem {
+	if f.TempPath == "" {
+		return f.Filesystem()
+	}
+	return fs.NewEncryptedFilesystem(fs.NewFilesystem(f.FilesystemType, filepath.Join(f.TempPath, tempDirName(f.ID))), f.EncryptionPassphrase)
+}
+
+// tempDirName returns the folder ID escaped so that it's a single, valid
+// file name, and distinct for every ID.
+func tempDirName(id string) string {
+	return strings.Replace(url.QueryEscape(id), ".", "%2E", -1)
+}
+
 func (f FolderConfiguration) ModTimeWindow() time.Duration {
 	return f.cachedModTimeWindow
 }
 
+// BlockScheme returns the scheme by which new and changed files are
+// divided into blocks.
+func (f FolderConfiguration) BlockScheme() protocol.BlockScheme {
+	if f.ContentDefinedChunking {
+		return protocol.BlockSchemeFastCDC
+	}
+	return protocol.BlockSchemeFixed
+}
+
 func (f *FolderConfiguration) CreateMarker() error {
 	if err := f.CheckPath(); err != ErrMarkerMissing {
 		return err
@@ -206,7 +373,7 @@ func (f *FolderConfiguration) DeviceIDs() []protocol.DeviceID {
 }
 
 func (f *FolderConfiguration) prepare() {
-	f.cachedFilesystem = fs.NewFilesystem(f.FilesystemType, f.Path)
+	f.cachedFilesystem = f.newFilesystem()
 
 	if f.RescanIntervalS > MaxRescanIntervalS {
 		f.RescanIntervalS = MaxRescanIntervalS
@@ -223,6 +390,11 @@ func (f *FolderConfiguration) prepare() {
 		f.Versioning.Params = make(map[string]string)
 	}
 
+	if f.FilesystemType == fs.FilesystemTypeS3 || f.FilesystemType == fs.FilesystemTypeWebDAV {
+		// Remote files have no permissions, so there's nothing to keep.
+		f.IgnorePerms = true
+	}
+
 	if f.WeakHashThresholdPct == 0 {
 		f.WeakHashThresholdPct = 25
 	}
@@ -231,6 +403,14 @@ func (f *FolderConfiguration) prepare() {
 		f.MarkerName = DefaultMarkerName
 	}
 
+	for i, subtree := range f.Subtrees {
+		f.Subtrees[i] = filepath.ToSlash(filepath.Clean(filepath.FromSlash(subtree)))
+	}
+
+	for i, transport := range f.DataTransports {
+		f.DataTransports[i] = strings.ToLower(strings.TrimSpace(transport))
+	}
+
 	switch {
 	case f.RawModTimeWindowS > 0:
 		f.cachedModTimeWindow = time.Duration(f.RawModTimeWindowS) * time.Second
@@ -275,6 +455,23 @@ func (f *FolderConfiguration) SharedWith(device protocol.DeviceID) bool {
 	return false
 }
 
+// MaxFolderBytes returns the size in bytes that the folder may grow to by
+// pulling, or zero if there is no limit.
+func (f *FolderConfiguration) MaxFolderBytes() int64 {
+	val := f.MaxFolderSize.BaseValue()
+	if val <= 0 {
+		return 0
+	}
+	if !f.MaxFolderSize.Percentage() {
+		return int64(val)
+	}
+	usage, err := f.Filesystem().Usage(".")
+	if err != nil || usage.Total <= 0 {
+		return 0
+	}
+	return int64(float64(usage.Total) * val / 100)
+}
+
 func (f *FolderConfiguration) CheckAvailableSpace(req int64) error {
 	val := f.MinDiskFree.BaseValue()
 	if val <= 0 {
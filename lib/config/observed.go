@@ -13,9 +13,11 @@ import (
 )
 
 type ObservedFolder struct {
-	Time  time.Time `xml:"time,attr" json:"time"`
-	ID    string    `xml:"id,attr" json:"id"`
-	Label string    `xml:"label,attr" json:"label"`
+	Time        time.Time `xml:"time,attr" json:"time"`
+	ID          string    `xml:"id,attr" json:"id"`
+	Label       string    `xml:"label,attr" json:"label"`
+	GlobalBytes int64     `xml:"globalBytes,attr,omitempty" json:"globalBytes"` // As announced by the device; zero if unknown.
+	GlobalFiles int64     `xml:"globalFiles,attr,omitempty" json:"globalFiles"`
 }
 
 type ObservedDevice struct {
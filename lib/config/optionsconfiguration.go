@@ -8,8 +8,10 @@ package config
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/syncthing/syncthing/lib/rand"
+	"github.com/syncthing/syncthing/lib/upgrade"
 	"github.com/syncthing/syncthing/lib/util"
 )
 
@@ -59,6 +61,21 @@ type OptionsConfiguration struct {
 	StunKeepaliveMinS       int      `xml:"stunKeepaliveMinS" json:"stunKeepaliveMinS" default:"20"`      // 0 for off
 	RawStunServers          []string `xml:"stunServer" json:"stunServers" default:"default"`
 	DatabaseTuning          Tuning   `xml:"databaseTuning" json:"databaseTuning" restart:"true"`
+	DatabaseBackend         Backend  `xml:"databaseBackend" json:"databaseBackend" restart:"true"` // leveldb, badger or sqlite (needs cgo); the database is migrated at startup after a change
+	RawExternalAddresses    []string `xml:"externalAddress" json:"externalAddresses"`
+	ExternalAddressResolveS int      `xml:"externalAddressResolveS" json:"externalAddressResolveS" default:"300"`
+	StuckScanTimeoutS       int      `xml:"stuckScanTimeoutS" json:"stuckScanTimeoutS"`  // 0 for off
+	StuckSyncTimeoutS       int      `xml:"stuckSyncTimeoutS" json:"stuckSyncTimeoutS"`  // 0 for off
+	UpgradeSigningKeys      []string `xml:"upgradeSigningKey" json:"upgradeSigningKeys"` // PEM encoded; empty for the built in key
+	UpgradeMinSignatures    int      `xml:"upgradeMinSignatures" json:"upgradeMinSignatures" default:"1"`
+	UpgradeTransparencyLog  string   `xml:"upgradeTransparencyLog" json:"upgradeTransparencyLog"` // URL; empty for off
+	BlockCacheMiB           int      `xml:"blockCacheMiB" json:"blockCacheMiB" restart:"true"`    // 0 for off
+	MaxRequestReads         int      `xml:"maxRequestReads" json:"maxRequestReads"`               // concurrent disk reads serving requests from other devices; 0 for no limit
+	MaxRequestReadKiBs      int      `xml:"maxRequestReadKiBs" json:"maxRequestReadKiBs"`         // disk read rate serving requests from other devices; 0 for no limit
+	SharedBlockStorage      bool     `xml:"sharedBlockStorage" json:"sharedBlockStorage" restart:"true"`
+	IndexBatchKiB           int      `xml:"indexBatchKiB" json:"indexBatchKiB"`                 // largest index message we send; 0 for the default
+	IndexBatchFiles         int      `xml:"indexBatchFiles" json:"indexBatchFiles"`             // most files per index message we send; 0 for the default
+	IndexReceiveWindowKiB   int      `xml:"indexReceiveWindowKiB" json:"indexReceiveWindowKiB"` // index data other devices may send before we've handled it; 0 for no limit
 
 	DeprecatedUPnPEnabled        bool     `xml:"upnpEnabled,omitempty" json:"-"`
 	DeprecatedUPnPLeaseM         int      `xml:"upnpLeaseMinutes,omitempty" json:"-"`
@@ -78,6 +95,10 @@ func (opts OptionsConfiguration) Copy() OptionsConfiguration {
 	copy(optsCopy.AlwaysLocalNets, opts.AlwaysLocalNets)
 	optsCopy.UnackedNotificationIDs = make([]string, len(opts.UnackedNotificationIDs))
 	copy(optsCopy.UnackedNotificationIDs, opts.UnackedNotificationIDs)
+	optsCopy.RawExternalAddresses = make([]string, len(opts.RawExternalAddresses))
+	copy(optsCopy.RawExternalAddresses, opts.RawExternalAddresses)
+	optsCopy.UpgradeSigningKeys = make([]string, len(opts.UpgradeSigningKeys))
+	copy(optsCopy.UpgradeSigningKeys, opts.UpgradeSigningKeys)
 	return optsCopy
 }
 
@@ -112,6 +133,28 @@ func (opts OptionsConfiguration) ListenAddresses() []string {
 	return util.UniqueTrimmedStrings(addresses)
 }
 
+// ExternalAddresses returns the configured external address templates, for
+// example "tcp://myhost.dyndns.org:${port}".
+func (opts OptionsConfiguration) ExternalAddresses() []string {
+	return util.UniqueTrimmedStrings(opts.RawExternalAddresses)
+}
+
+// UpgradeVerification returns the requirements an upgrade must meet before
+// it is installed.
+func (opts OptionsConfiguration) UpgradeVerification() upgrade.Verification {
+	var keys [][]byte
+	for _, key := range opts.UpgradeSigningKeys {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, []byte(key))
+		}
+	}
+	return upgrade.Verification{
+		SigningKeys:        keys,
+		MinSignatures:      opts.UpgradeMinSignatures,
+		TransparencyLogURL: opts.UpgradeTransparencyLog,
+	}
+}
+
 func (opts OptionsConfiguration) StunServers() []string {
 	var addresses []string
 	for _, addr := range opts.RawStunServers {
@@ -0,0 +1,46 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package config
+
+// SettingsPolicy is what we do with the folder settings a device pushes to
+// us.
+type SettingsPolicy int
+
+const (
+	SettingsPolicyIgnore SettingsPolicy = iota // default is to ignore them
+	SettingsPolicyAsk                          // wait for the user to accept them
+	SettingsPolicyApply                        // apply them right away
+)
+
+func (p SettingsPolicy) String() string {
+	switch p {
+	case SettingsPolicyIgnore:
+		return "ignore"
+	case SettingsPolicyAsk:
+		return "ask"
+	case SettingsPolicyApply:
+		return "apply"
+	default:
+		return "unknown"
+	}
+}
+
+func (p SettingsPolicy) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+func (p *SettingsPolicy) UnmarshalText(bs []byte) error {
+	switch string(bs) {
+	case "ask":
+		*p = SettingsPolicyAsk
+	case "apply":
+		*p = SettingsPolicyApply
+	default:
+		*p = SettingsPolicyIgnore
+	}
+	return nil
+}
@@ -77,12 +77,14 @@ type Wrapper interface {
 	Device(id protocol.DeviceID) (DeviceConfiguration, bool)
 	Devices() map[protocol.DeviceID]DeviceConfiguration
 	RemoveDevice(id protocol.DeviceID) (Waiter, error)
+	DecommissionDevice(id protocol.DeviceID) (Waiter, error)
 	SetDevice(DeviceConfiguration) (Waiter, error)
 	SetDevices([]DeviceConfiguration) (Waiter, error)
 
 	AddOrUpdatePendingDevice(device protocol.DeviceID, name, address string)
-	AddOrUpdatePendingFolder(id, label string, device protocol.DeviceID)
+	AddOrUpdatePendingFolder(folder ObservedFolder, device protocol.DeviceID)
 	IgnoredDevice(id protocol.DeviceID) bool
+	DecommissionedDevice(id protocol.DeviceID) bool
 	IgnoredFolder(device protocol.DeviceID, folder string) bool
 
 	Subscribe(c Committer)
@@ -283,6 +285,34 @@ func (w *wrapper) RemoveDevice(id protocol.DeviceID) (Waiter, error) {
 	return noopWaiter{}, nil
 }
 
+// DecommissionDevice removes the device from the configuration, like
+// RemoveDevice, and remembers it as decommissioned so that it is neither
+// connected to nor introduced again.
+func (w *wrapper) DecommissionDevice(id protocol.DeviceID) (Waiter, error) {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	newCfg := w.cfg.Copy()
+	decommissioned := ObservedDevice{
+		Time: time.Now().Round(time.Second),
+		ID:   id,
+	}
+	for i := range newCfg.Devices {
+		if newCfg.Devices[i].DeviceID == id {
+			decommissioned.Name = newCfg.Devices[i].Name
+			newCfg.Devices = append(newCfg.Devices[:i], newCfg.Devices[i+1:]...)
+			break
+		}
+	}
+	for _, dev := range newCfg.Decommissioned {
+		if dev.ID == id {
+			return w.replaceLocked(newCfg)
+		}
+	}
+	newCfg.Decommissioned = append(newCfg.Decommissioned, decommissioned)
+	return w.replaceLocked(newCfg)
+}
+
 // Folders returns a map of folders. Folder structures should not be changed,
 // other than for the purpose of updating via SetFolder().
 func (w *wrapper) Folders() map[string]FolderConfiguration {
@@ -372,6 +402,23 @@ func (w *wrapper) IgnoredDevice(id protocol.DeviceID) bool {
 			return true
 		}
 	}
+	for _, device := range w.cfg.Decommissioned {
+		if device.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// DecommissionedDevice returns whether or not the device was decommissioned.
+func (w *wrapper) DecommissionedDevice(id protocol.DeviceID) bool {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+	for _, device := range w.cfg.Decommissioned {
+		if device.ID == id {
+			return true
+		}
+	}
 	return false
 }
 
@@ -472,24 +519,20 @@ func (w *wrapper) AddOrUpdatePendingDevice(device protocol.DeviceID, name, addre
 	})
 }
 
-func (w *wrapper) AddOrUpdatePendingFolder(id, label string, device protocol.DeviceID) {
+func (w *wrapper) AddOrUpdatePendingFolder(folder ObservedFolder, device protocol.DeviceID) {
 	w.mut.Lock()
 	defer w.mut.Unlock()
 
+	folder.Time = time.Now().Round(time.Second)
 	for i := range w.cfg.Devices {
 		if w.cfg.Devices[i].DeviceID == device {
 			for j := range w.cfg.Devices[i].PendingFolders {
-				if w.cfg.Devices[i].PendingFolders[j].ID == id {
-					w.cfg.Devices[i].PendingFolders[j].Label = label
-					w.cfg.Devices[i].PendingFolders[j].Time = time.Now().Round(time.Second)
+				if w.cfg.Devices[i].PendingFolders[j].ID == folder.ID {
+					w.cfg.Devices[i].PendingFolders[j] = folder
 					return
 				}
 			}
-			w.cfg.Devices[i].PendingFolders = append(w.cfg.Devices[i].PendingFolders, ObservedFolder{
-				Time:  time.Now().Round(time.Second),
-				ID:    id,
-				Label: label,
-			})
+			w.cfg.Devices[i].PendingFolders = append(w.cfg.Devices[i].PendingFolders, folder)
 			return
 		}
 	}
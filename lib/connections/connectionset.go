@@ -0,0 +1,217 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+// A ConnectionSet is a Connection made up of all the connections to one
+// device, at most one per transport. The first connection is the primary
+// one: it carries the cluster config, index and download progress messages
+// and provides the metadata. Requests are spread over all connections in
+// the set, preferring the one with the fewest requests outstanding, and
+// are retried on another connection when the one they were sent on closes.
+// Requests can be limited to some transports using WithTransports. Closing
+// the primary connection closes the set. The Quality of requests made
+// through the set is tracked.
+type ConnectionSet struct {
+	Connection // the primary connection
+
+	multipath bool
+	quality   *qualityTracker
+	mut       sync.Mutex
+	conns     []*setConn // conns[0] is the primary connection
+}
+
+// ErrNoAllowedConnection is returned for requests limited to transports
+// that none of the connections in the set use.
+var ErrNoAllowedConnection = errors.New("no connection over an allowed transport")
+
+type setConn struct {
+	Connection
+	outstanding int
+}
+
+// NewConnectionSet returns a set with the given primary connection. Other
+// connections can only be added if multipath is true.
+func NewConnectionSet(primary Connection, multipath bool) *ConnectionSet {
+	return &ConnectionSet{
+		Connection: primary,
+		multipath:  multipath,
+		quality:    newQualityTracker(),
+		mut:        sync.NewMutex(),
+		conns:      []*setConn{{Connection: primary}},
+	}
+}
+
+// Multipath returns true if connections can be added to the set.
+func (s *ConnectionSet) Multipath() bool {
+	return s.multipath
+}
+
+// Quality returns how requests made through the set have been going.
+func (s *ConnectionSet) Quality() Quality {
+	return s.quality.get()
+}
+
+// CanAdd returns true if a connection over the given transport would be
+// added to the set, as opposed to replacing it.
+func (s *ConnectionSet) CanAdd(transport string) bool {
+	if !s.multipath {
+		return false
+	}
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	for _, c := range s.conns {
+		if c.Transport() == transport {
+			return false
+		}
+	}
+	return true
+}
+
+// Add adds a secondary connection to the set.
+func (s *ConnectionSet) Add(conn Connection) {
+	s.mut.Lock()
+	s.conns = append(s.conns, &setConn{Connection: conn})
+	s.mut.Unlock()
+}
+
+// Remove removes a secondary connection from the set, returning false if
+// it isn't one. Connections are told apart by name, which is the same for
+// a Connection and the protocol.Connection it wraps.
+func (s *ConnectionSet) Remove(conn protocol.Connection) bool {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	for i, c := range s.conns[1:] {
+		if c.Name() == conn.Name() {
+			s.conns = append(s.conns[:i+1], s.conns[i+2:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Connections returns the connections in the set, the primary one first.
+func (s *ConnectionSet) Connections() []Connection {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	conns := make([]Connection, len(s.conns))
+	for i, c := range s.conns {
+		conns[i] = c.Connection
+	}
+	return conns
+}
+
+// Allows returns true if any connection in the set is allowed for the
+// given transports, as by TransportAllowed.
+func (s *ConnectionSet) Allows(transports []string) bool {
+	for _, c := range s.Connections() {
+		if TransportAllowed(c.Transport(), c.IsLocal(), transports) {
+			return true
+		}
+	}
+	return false
+}
+
+// Close closes all connections in the set.
+func (s *ConnectionSet) Close(err error) {
+	for _, conn := range s.Connections() {
+		conn.Close(err)
+	}
+}
+
+func (s *ConnectionSet) Request(ctx context.Context, folder string, name string, offset int64, size int, hash []byte, weakHash uint32, fromTemporary bool) ([]byte, error) {
+	t0 := time.Now()
+	for {
+		c := s.take(contextTransports(ctx))
+		if c == nil {
+			return nil, ErrNoAllowedConnection
+		}
+		buf, err := c.Request(ctx, folder, name, offset, size, hash, weakHash, fromTemporary)
+		if s.done(c, err) {
+			s.quality.record(ctx, len(buf), time.Since(t0), err)
+			return buf, err
+		}
+	}
+}
+
+func (s *ConnectionSet) BatchRequest(ctx context.Context, folder string, files []protocol.BatchRequestFile) ([][]byte, []error, error) {
+	t0 := time.Now()
+	for {
+		c := s.take(contextTransports(ctx))
+		if c == nil {
+			return nil, nil, ErrNoAllowedConnection
+		}
+		bufs, errs, err := c.BatchRequest(ctx, folder, files)
+		if s.done(c, err) {
+			bytes := 0
+			for _, buf := range bufs {
+				bytes += len(buf)
+			}
+			s.quality.record(ctx, bytes, time.Since(t0), err)
+			return bufs, errs, err
+		}
+	}
+}
+
+func (s *ConnectionSet) Statistics() protocol.Statistics {
+	stats := s.Connection.Statistics()
+	for _, conn := range s.Connections()[1:] {
+		other := conn.Statistics()
+		stats.InBytesTotal += other.InBytesTotal
+		stats.OutBytesTotal += other.OutBytesTotal
+	}
+	return stats
+}
+
+// take returns the connection with the fewest outstanding requests,
+// preferring better priority ones among equals, and counts a request on it.
+// Only connections allowed for the given transports are considered; it
+// returns nil if there are none.
+func (s *ConnectionSet) take(transports []string) *setConn {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	var best *setConn
+	for _, c := range s.conns {
+		if len(transports) > 0 && !TransportAllowed(c.Transport(), c.IsLocal(), transports) {
+			continue
+		}
+		if best == nil || c.outstanding < best.outstanding || c.outstanding == best.outstanding && c.Priority() < best.Priority() {
+			best = c
+		}
+	}
+	if best != nil {
+		best.outstanding++
+	}
+	return best
+}
+
+// done finishes a request taken on the given connection, returning false
+// if it should be retried on another connection.
+func (s *ConnectionSet) done(c *setConn, err error) bool {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	c.outstanding--
+	if err != protocol.ErrClosed || c == s.conns[0] {
+		return true
+	}
+	// The secondary connection went away; those remaining will do.
+	for i, other := range s.conns {
+		if other == c {
+			s.conns = append(s.conns[:i], s.conns[i+1:]...)
+			break
+		}
+	}
+	return false
+}
@@ -0,0 +1,200 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+type fakeSetConn struct {
+	Connection
+	name      string
+	transport string
+	priority  int
+	local     bool
+	closed    bool
+	unblock   chan struct{}
+}
+
+func (c *fakeSetConn) Name() string      { return c.name }
+func (c *fakeSetConn) Transport() string { return c.transport }
+func (c *fakeSetConn) Priority() int     { return c.priority }
+func (c *fakeSetConn) IsLocal() bool     { return c.local }
+
+func (c *fakeSetConn) Request(ctx context.Context, folder string, name string, offset int64, size int, hash []byte, weakHash uint32, fromTemporary bool) ([]byte, error) {
+	if c.closed {
+		return nil, protocol.ErrClosed
+	}
+	if c.unblock != nil {
+		<-c.unblock
+	}
+	return []byte(c.name), nil
+}
+
+func TestConnectionSet(t *testing.T) {
+	primary := &fakeSetConn{name: "primary", transport: "relay", priority: 200}
+	set := NewConnectionSet(primary, true)
+
+	if set.CanAdd("relay") {
+		t.Error("should not add a second relay connection")
+	}
+	if !set.CanAdd("tcp") {
+		t.Fatal("should add a tcp connection")
+	}
+	secondary := &fakeSetConn{name: "secondary", transport: "tcp", priority: 10}
+	set.Add(secondary)
+	if set.CanAdd("tcp") {
+		t.Error("should not add a second tcp connection")
+	}
+
+	// The better connection is used when both are idle.
+	if buf, err := set.Request(context.Background(), "f", "n", 0, 0, nil, 0, false); err != nil || string(buf) != "secondary" {
+		t.Fatal("expected request on secondary connection, got", string(buf), err)
+	}
+
+	// A busy connection isn't used when there's an idle one.
+	secondary.unblock = make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		set.Request(context.Background(), "f", "n", 0, 0, nil, 0, false)
+		close(done)
+	}()
+	for {
+		set.mut.Lock()
+		outstanding := set.conns[1].outstanding
+		set.mut.Unlock()
+		if outstanding == 1 {
+			break
+		}
+	}
+	if buf, _ := set.Request(context.Background(), "f", "n", 0, 0, nil, 0, false); string(buf) != "primary" {
+		t.Error("expected request on primary connection, got", string(buf))
+	}
+	close(secondary.unblock)
+	<-done
+	secondary.unblock = nil
+
+	// Requests fail over when a secondary connection closes.
+	secondary.closed = true
+	if buf, err := set.Request(context.Background(), "f", "n", 0, 0, nil, 0, false); err != nil || string(buf) != "primary" {
+		t.Fatal("expected request on primary connection, got", string(buf), err)
+	}
+	if conns := set.Connections(); len(conns) != 1 {
+		t.Error("closed connection should have been removed, got", len(conns))
+	}
+
+	if set.Remove(primary) {
+		t.Error("the primary connection can't be removed")
+	}
+	if NewConnectionSet(primary, false).CanAdd("tcp") {
+		t.Error("should not add connections without multipath")
+	}
+}
+
+func TestConnectionSetTransports(t *testing.T) {
+	primary := &fakeSetConn{name: "primary", transport: "relay4", priority: 200}
+	set := NewConnectionSet(primary, true)
+	lan := WithTransports(context.Background(), []string{TransportLAN})
+
+	if set.Allows([]string{TransportLAN}) {
+		t.Error("a relay connection should not be allowed for LAN only")
+	}
+	if _, err := set.Request(lan, "f", "n", 0, 0, nil, 0, false); err != ErrNoAllowedConnection {
+		t.Fatal("expected no allowed connection, got", err)
+	}
+
+	secondary := &fakeSetConn{name: "secondary", transport: "tcp4", priority: 10, local: true}
+	set.Add(secondary)
+	if !set.Allows([]string{TransportLAN}) {
+		t.Error("a LAN connection should be allowed for LAN only")
+	}
+
+	// Even when busy, the only allowed connection is used.
+	secondary.unblock = make(chan struct{})
+	done := make(chan struct{}, 2)
+	for i := 1; i <= 2; i++ {
+		go func() {
+			set.Request(lan, "f", "n", 0, 0, nil, 0, false)
+			done <- struct{}{}
+		}()
+		for {
+			set.mut.Lock()
+			outstanding := set.conns[1].outstanding
+			set.mut.Unlock()
+			if outstanding == i {
+				break
+			}
+		}
+	}
+	close(secondary.unblock)
+	<-done
+	<-done
+	secondary.unblock = nil
+
+	if buf, err := set.Request(lan, "f", "n", 0, 0, nil, 0, false); err != nil || string(buf) != "secondary" {
+		t.Fatal("expected request on secondary connection, got", string(buf), err)
+	}
+	if buf, _ := set.Request(WithTransports(context.Background(), []string{"relay"}), "f", "n", 0, 0, nil, 0, false); string(buf) != "primary" {
+		t.Error("expected request on primary connection, got", string(buf))
+	}
+}
+
+func TestTransportAllowed(t *testing.T) {
+	cases := []struct {
+		transport  string
+		local      bool
+		transports []string
+		allowed    bool
+	}{
+		{"relay4", false, nil, true},
+		{"tcp4", true, []string{"lan"}, true},
+		{"tcp4", false, []string{"lan"}, false},
+		{"quic6", false, []string{"tcp", "quic"}, true},
+		{"quic6", false, []string{"quic4"}, false},
+		{"relay4", true, []string{"tcp"}, false},
+	}
+	for _, tc := range cases {
+		if allowed := TransportAllowed(tc.transport, tc.local, tc.transports); allowed != tc.allowed {
+			t.Errorf("TransportAllowed(%q, %v, %v) = %v, expected %v", tc.transport, tc.local, tc.transports, allowed, tc.allowed)
+		}
+	}
+}
+
+func TestQualityTracker(t *testing.T) {
+	tr := newQualityTracker()
+	ctx := context.Background()
+
+	tr.record(ctx, 1000, time.Second, nil)
+	if q := tr.get(); q.Requests != 1 || q.Latency != time.Second || q.Throughput != 1000 || q.ErrorRate != 0 {
+		t.Fatalf("unexpected quality after first request: %+v", q)
+	}
+	if est := tr.get().Estimate(2000); est != 2*time.Second {
+		t.Errorf("expected estimate of 2s, got %v", est)
+	}
+
+	// Failures raise the error rate, and with it the estimate, but don't
+	// affect the averages of successful requests.
+	tr.record(ctx, 0, time.Minute, protocol.ErrNoSuchFile)
+	if q := tr.get(); q.Requests != 2 || q.Latency != time.Second || q.ErrorRate != qualityWeight {
+		t.Errorf("unexpected quality after failed request: %+v", q)
+	}
+	if est := tr.get().Estimate(2000); est <= 2*time.Second {
+		t.Errorf("expected estimate above 2s, got %v", est)
+	}
+
+	// Cancelled requests don't count.
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	tr.record(cancelled, 0, time.Minute, context.Canceled)
+	if q := tr.get(); q.Requests != 2 {
+		t.Errorf("cancelled request should be ignored: %+v", q)
+	}
+}
@@ -0,0 +1,194 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/sync"
+	"github.com/syncthing/syncthing/lib/util"
+)
+
+const defaultExternalAddressResolveInterval = 5 * time.Minute
+
+// The externalAddresses service expands the configured external address
+// templates and periodically resolves any host names in them. This lets
+// users with manual port forwards and dynamic DNS announce addresses that
+// can't be discovered via UPnP or STUN. A change in the set of resolved
+// addresses is announced as a ListenAddressesChanged event, which causes
+// the discovery clients to reannounce.
+type externalAddresses struct {
+	cfg      config.Wrapper
+	evLogger events.Logger
+	lookup   func(ctx context.Context, host string) ([]string, error)
+	refresh  chan struct{}
+
+	mut   sync.RWMutex
+	addrs []string
+}
+
+func newExternalAddresses(cfg config.Wrapper, evLogger events.Logger) *externalAddresses {
+	return &externalAddresses{
+		cfg:      cfg,
+		evLogger: evLogger,
+		lookup:   net.DefaultResolver.LookupHost,
+		refresh:  make(chan struct{}, 1),
+		mut:      sync.NewRWMutex(),
+	}
+}
+
+func (e *externalAddresses) serve(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+		case <-e.refresh:
+			timer.Stop()
+		case <-ctx.Done():
+			return
+		}
+
+		e.update(ctx)
+
+		intv := time.Duration(e.cfg.Options().ExternalAddressResolveS) * time.Second
+		if intv <= 0 {
+			intv = defaultExternalAddressResolveInterval
+		}
+		timer.Reset(intv)
+	}
+}
+
+// Refresh schedules an immediate re-resolution, for example after the
+// configuration has changed.
+func (e *externalAddresses) Refresh() {
+	select {
+	case e.refresh <- struct{}{}:
+	default:
+	}
+}
+
+// Addresses returns the most recently resolved external addresses.
+func (e *externalAddresses) Addresses() []string {
+	e.mut.RLock()
+	defer e.mut.RUnlock()
+	addrs := make([]string, len(e.addrs))
+	copy(addrs, e.addrs)
+	return addrs
+}
+
+func (e *externalAddresses) update(ctx context.Context) {
+	opts := e.cfg.Options()
+	vars := templateVariables(opts)
+
+	var addrs []string
+	for _, tpl := range opts.ExternalAddresses() {
+		addr := expandAddressTemplate(tpl, vars)
+		uri, err := url.Parse(addr)
+		if err != nil || uri.Host == "" {
+			l.Infof("Invalid external address %q: %v", addr, err)
+			continue
+		}
+		addrs = append(addrs, addr)
+		addrs = append(addrs, e.resolve(ctx, uri)...)
+	}
+	addrs = util.UniqueTrimmedStrings(addrs)
+	sort.Strings(addrs)
+
+	e.mut.Lock()
+	changed := !reflect.DeepEqual(addrs, e.addrs)
+	e.addrs = addrs
+	e.mut.Unlock()
+
+	if changed {
+		l.Debugln("external addresses changed:", addrs)
+		e.evLogger.Log(events.ListenAddressesChanged, map[string]interface{}{
+			"address": "external",
+			"lan":     []string{},
+			"wan":     addrs,
+		})
+	}
+}
+
+// resolve returns the given address with the host name replaced by each of
+// the IP addresses it currently resolves to. Addresses that already contain
+// an IP are not resolved.
+func (e *externalAddresses) resolve(ctx context.Context, uri *url.URL) []string {
+	host, port, err := net.SplitHostPort(uri.Host)
+	if err != nil {
+		host = uri.Host
+	}
+	if host == "" || net.ParseIP(host) != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	ips, err := e.lookup(ctx, host)
+	if err != nil {
+		l.Debugf("resolving external address %s: %v", uri, err)
+		return nil
+	}
+
+	addrs := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		resolved := *uri
+		if port != "" {
+			resolved.Host = net.JoinHostPort(ip, port)
+		} else if strings.Contains(ip, ":") {
+			resolved.Host = "[" + ip + "]"
+		} else {
+			resolved.Host = ip
+		}
+		addrs = append(addrs, resolved.String())
+	}
+	return addrs
+}
+
+// templateVariables returns the values available for substitution in
+// external address templates.
+func templateVariables(opts config.OptionsConfiguration) map[string]string {
+	vars := make(map[string]string)
+	if hostname, err := os.Hostname(); err == nil {
+		vars["hostname"] = hostname
+	}
+	for _, addr := range opts.ListenAddresses() {
+		uri, err := url.Parse(addr)
+		if err != nil || !strings.HasPrefix(uri.Scheme, "tcp") {
+			continue
+		}
+		if _, port, err := net.SplitHostPort(uri.Host); err == nil {
+			if _, err := strconv.Atoi(port); err == nil {
+				vars["port"] = port
+				break
+			}
+		}
+	}
+	return vars
+}
+
+// expandAddressTemplate replaces ${name} in the template with the value of
+// the corresponding variable. Unknown variables are left as is.
+func expandAddressTemplate(tpl string, vars map[string]string) string {
+	return os.Expand(tpl, func(name string) string {
+		if val, ok := vars[name]; ok {
+			return val
+		}
+		return "${" + name + "}"
+	})
+}
@@ -0,0 +1,60 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"context"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/config"
+)
+
+func TestExpandAddressTemplate(t *testing.T) {
+	vars := map[string]string{"port": "22001", "hostname": "box"}
+	cases := [][2]string{
+		{"tcp://example.com:${port}", "tcp://example.com:22001"},
+		{"tcp://${hostname}.dyndns.org:22000", "tcp://box.dyndns.org:22000"},
+		{"tcp://example.com:${unknown}", "tcp://example.com:${unknown}"},
+		{"tcp://1.2.3.4:22000", "tcp://1.2.3.4:22000"},
+	}
+	for _, tc := range cases {
+		if res := expandAddressTemplate(tc[0], vars); res != tc[1] {
+			t.Errorf("expandAddressTemplate(%q) => %q, expected %q", tc[0], res, tc[1])
+		}
+	}
+}
+
+func TestTemplateVariablesPort(t *testing.T) {
+	opts := config.OptionsConfiguration{
+		RawListenAddresses: []string{"relay://relay.example.com:443", "tcp://0.0.0.0:23000"},
+	}
+	if port := templateVariables(opts)["port"]; port != "23000" {
+		t.Errorf("expected port 23000, got %q", port)
+	}
+}
+
+func TestExternalAddressResolve(t *testing.T) {
+	e := &externalAddresses{
+		lookup: func(_ context.Context, host string) ([]string, error) {
+			return []string{"192.0.2.1", "2001:db8::1"}, nil
+		},
+	}
+
+	uri, _ := url.Parse("tcp://myhost.example.com:22000")
+	expected := []string{"tcp://192.0.2.1:22000", "tcp://[2001:db8::1]:22000"}
+	if res := e.resolve(context.Background(), uri); !reflect.DeepEqual(res, expected) {
+		t.Errorf("resolve => %v, expected %v", res, expected)
+	}
+
+	// IP addresses are not resolved again
+	uri, _ = url.Parse("tcp://192.0.2.7:22000")
+	if res := e.resolve(context.Background(), uri); len(res) != 0 {
+		t.Errorf("unexpected resolution of IP address: %v", res)
+	}
+}
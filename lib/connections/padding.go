@@ -0,0 +1,153 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/rand"
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+// Relays see the size and timing of what passes through them, even though
+// they can't read it. On connections to devices that ask for it, each write
+// is framed and padded to one of a few fixed sizes, and empty frames are
+// sent now and then as cover traffic. A frame is the length of the data and
+// the length of the padding, as 32 bit integers, followed by the data and
+// that many zero bytes of padding.
+
+const (
+	paddingHeaderSize = 8
+	minPaddedSize     = 512      // the smallest frame
+	maxPaddedStep     = 64 << 10 // larger frames are padded to multiples of this
+	coverInterval     = 10 * time.Second
+	coverMaxSize      = 4 << 10 // the largest frame sent as cover traffic
+)
+
+var errBadPadding = errors.New("padding: invalid frame")
+
+// paddedSize returns the size of the frame for the given amount of data:
+// the next power of two from minPaddedSize up to maxPaddedStep, and the next
+// multiple of that beyond.
+func paddedSize(n int) int {
+	n += paddingHeaderSize
+	if n > maxPaddedStep {
+		return (n + maxPaddedStep - 1) / maxPaddedStep * maxPaddedStep
+	}
+	size := minPaddedSize
+	for size < n {
+		size *= 2
+	}
+	return size
+}
+
+type paddedWriter struct {
+	w   io.Writer
+	mut sync.Mutex // frames are written whole
+}
+
+// newPaddedWriter returns a writer that pads each write, and sends cover
+// traffic until the context is cancelled or a write fails.
+func newPaddedWriter(ctx context.Context, w io.Writer) io.Writer {
+	pw := &paddedWriter{
+		w:   w,
+		mut: sync.NewMutex(),
+	}
+	go pw.cover(ctx)
+	return pw
+}
+
+func (w *paddedWriter) Write(data []byte) (int, error) {
+	if err := w.writeFrame(data, paddedSize(len(data))); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+func (w *paddedWriter) writeFrame(data []byte, size int) error {
+	// One write per frame, so that what the relay sees doesn't give the
+	// size of the data away.
+	buf := protocol.BufferPool.Get(size)
+	defer protocol.BufferPool.Put(buf)
+	binary.BigEndian.PutUint32(buf, uint32(len(data)))
+	binary.BigEndian.PutUint32(buf[4:], uint32(size-paddingHeaderSize-len(data)))
+	n := copy(buf[paddingHeaderSize:], data)
+	for i := paddingHeaderSize + n; i < size; i++ {
+		buf[i] = 0
+	}
+	w.mut.Lock()
+	_, err := w.w.Write(buf)
+	w.mut.Unlock()
+	return err
+}
+
+// cover sends an empty frame of random size at random intervals averaging
+// coverInterval.
+func (w *paddedWriter) cover(ctx context.Context) {
+	for {
+		t := time.NewTimer(time.Duration(rand.Int63() % int64(2*coverInterval)))
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return
+		}
+		size := minPaddedSize << uint(rand.Intn(4))
+		if size > coverMaxSize {
+			size = coverMaxSize
+		}
+		if err := w.writeFrame(nil, size); err != nil {
+			l.Debugln("Cover traffic stopped:", err)
+			return
+		}
+	}
+}
+
+type paddedReader struct {
+	r       io.Reader
+	data    int64 // left to read of the current frame
+	padding int64
+	hdr     [paddingHeaderSize]byte
+}
+
+// newPaddedReader returns a reader that strips the framing and padding
+// added by a paddedWriter on the other side, and skips cover traffic.
+func newPaddedReader(r io.Reader) io.Reader {
+	return &paddedReader{r: r}
+}
+
+func (r *paddedReader) Read(p []byte) (int, error) {
+	for r.data == 0 {
+		if r.padding > 0 {
+			if _, err := io.CopyN(ioutil.Discard, r.r, r.padding); err != nil {
+				return 0, err
+			}
+			r.padding = 0
+		}
+		if _, err := io.ReadFull(r.r, r.hdr[:]); err != nil {
+			return 0, err
+		}
+		r.data = int64(binary.BigEndian.Uint32(r.hdr[:]))
+		r.padding = int64(binary.BigEndian.Uint32(r.hdr[4:]))
+		if r.padding >= maxPaddedStep {
+			return 0, errBadPadding
+		}
+	}
+
+	if int64(len(p)) > r.data {
+		p = p[:r.data]
+	}
+	n, err := r.r.Read(p)
+	r.data -= int64(n)
+	return n, err
+}
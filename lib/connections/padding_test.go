@@ -0,0 +1,91 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+func TestPaddedSize(t *testing.T) {
+	cases := []struct {
+		data, size int
+	}{
+		{0, 512},
+		{504, 512},
+		{505, 1024},
+		{10000, 16 << 10},
+		{64<<10 - 8, 64 << 10},
+		{64<<10 - 7, 128 << 10},
+		{200 << 10, 256 << 10},
+		{1 << 20, 1<<20 + 64<<10},
+	}
+	for _, tc := range cases {
+		if size := paddedSize(tc.data); size != tc.size {
+			t.Errorf("paddedSize(%d) = %d, expected %d", tc.data, size, tc.size)
+		}
+	}
+}
+
+func TestPaddedRoundTrip(t *testing.T) {
+	// Every write to the wire is a whole frame of one of the padded sizes.
+	// The cover traffic is sent by hand, not by the timer.
+	wire := new(bytes.Buffer)
+	w := &paddedWriter{w: writeChecker{wire, t}, mut: sync.NewMutex()}
+
+	var msgs [][]byte
+	for _, size := range []int{0, 1, 100, 504, 505, 70 << 10} {
+		msg := make([]byte, size)
+		for i := range msg {
+			msg[i] = byte(i + size)
+		}
+		msgs = append(msgs, msg)
+		if n, err := w.Write(msg); err != nil || n != size {
+			t.Fatal(n, err)
+		}
+		if err := w.writeFrame(nil, coverMaxSize); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := newPaddedReader(wire)
+	for _, msg := range msgs {
+		buf := make([]byte, len(msg))
+		if _, err := io.ReadFull(r, buf); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf, msg) {
+			t.Errorf("message of %d bytes mangled", len(msg))
+		}
+	}
+	if n, err := r.Read(make([]byte, 10)); err != io.EOF {
+		t.Errorf("expected EOF after the last frame, got %d bytes, %v", n, err)
+	}
+}
+
+func TestPaddedReaderBadFrame(t *testing.T) {
+	frame := []byte{0, 0, 0, 1, 0xff, 0xff, 0xff, 0xff, 42}
+	if _, err := newPaddedReader(bytes.NewReader(frame)).Read(make([]byte, 1)); err != errBadPadding {
+		t.Error("expected a bad padding error, got", err)
+	}
+}
+
+type writeChecker struct {
+	w io.Writer
+	t *testing.T
+}
+
+func (c writeChecker) Write(bs []byte) (int, error) {
+	n := len(bs)
+	if n%maxPaddedStep != 0 && (n < minPaddedSize || n&(n-1) != 0) {
+		c.t.Errorf("unexpected frame of %d bytes", n)
+	}
+	return c.w.Write(bs)
+}
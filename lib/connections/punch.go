@@ -0,0 +1,290 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/relay/client"
+	"github.com/syncthing/syncthing/lib/sync"
+	"github.com/syncthing/syncthing/lib/util"
+)
+
+// Hole punching upgrades relayed connections to direct QUIC ones. The side
+// that dialed through the relay sends its QUIC addresses to the other side
+// through the relay, which answers with its own. Both sides then dial each
+// other at once, from the sockets they listen on, so that each NAT has seen
+// packets going out to the other side by the time the other side's arrive.
+// This is retried, backing off, for as long as the connection is relayed.
+
+const (
+	punchInterval   = 30 * time.Second // how often we look for connections to upgrade
+	punchMinBackoff = time.Minute
+	punchMaxBackoff = time.Hour
+	punchTimeout    = 10 * time.Second
+
+	maxPunchAddresses = 16 // as many as the relay protocol carries
+)
+
+type punchPhase int
+
+const (
+	punchIdle       punchPhase = iota // waiting for the next attempt
+	punchRequesting                   // waiting for the addresses of the other side
+	punchDialing                      // dialing the addresses we got
+)
+
+type punchState struct {
+	relay    *url.URL
+	phase    punchPhase
+	failures int
+	next     time.Time
+}
+
+type punchDial struct {
+	deviceID protocol.DeviceID
+	addrs    []string
+}
+
+// The holePuncher keeps track of the relayed connections we dialed, and
+// when to try upgrading them next.
+type holePuncher struct {
+	mut   sync.Mutex
+	peers map[protocol.DeviceID]*punchState
+	dials chan punchDial // requests to punch from the other side
+}
+
+func newHolePuncher() *holePuncher {
+	return &holePuncher{
+		mut:   sync.NewMutex(),
+		peers: make(map[protocol.DeviceID]*punchState),
+		dials: make(chan punchDial, 4),
+	}
+}
+
+// relayed records that we're connected to the device through the given
+// relay. Attempts already made still count towards the backoff.
+func (p *holePuncher) relayed(deviceID protocol.DeviceID, relay *url.URL, now time.Time) {
+	if !client.SupportsPunching(relay) {
+		return
+	}
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	if st, ok := p.peers[deviceID]; ok {
+		st.relay = relay
+		return
+	}
+	p.peers[deviceID] = &punchState{relay: relay, next: now}
+}
+
+// due returns the relays through which to try punching to the devices
+// that are due, moving them on to requesting.
+func (p *holePuncher) due(now time.Time) map[protocol.DeviceID]*url.URL {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	res := make(map[protocol.DeviceID]*url.URL)
+	for deviceID, st := range p.peers {
+		if st.phase == punchIdle && !now.Before(st.next) {
+			st.phase = punchRequesting
+			res[deviceID] = st.relay
+		}
+	}
+	return res
+}
+
+// dialing records that we got the addresses to dial from the other side.
+func (p *holePuncher) dialing(deviceID protocol.DeviceID) {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	if st, ok := p.peers[deviceID]; ok {
+		st.phase = punchDialing
+	}
+}
+
+// finished records the outcome of an attempt. After a successful one we're
+// done with the device, after a failed one it waits for the next.
+func (p *holePuncher) finished(deviceID protocol.DeviceID, ok bool, now time.Time) {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	st, exists := p.peers[deviceID]
+	if !exists {
+		return
+	}
+	if ok {
+		delete(p.peers, deviceID)
+		return
+	}
+	st.phase = punchIdle
+	st.failures++
+	st.next = now.Add(punchBackoff(st.failures))
+}
+
+func (p *holePuncher) forget(deviceID protocol.DeviceID) {
+	p.mut.Lock()
+	delete(p.peers, deviceID)
+	p.mut.Unlock()
+}
+
+// punchBackoff returns how long to wait after the given number of failed
+// attempts, doubling from punchMinBackoff up to punchMaxBackoff.
+func punchBackoff(failures int) time.Duration {
+	d := punchMinBackoff
+	for i := 1; i < failures && d < punchMaxBackoff; i++ {
+		d *= 2
+	}
+	if d > punchMaxBackoff {
+		d = punchMaxBackoff
+	}
+	return d
+}
+
+// A punchListener passes on the punch invitations it receives to the
+// handler, which returns the addresses to answer with, if any.
+type punchListener interface {
+	OnPunch(func(deviceID protocol.DeviceID, addrs []string) ([]string, bool))
+}
+
+func (s *service) punch(ctx context.Context) {
+	ticker := time.NewTicker(punchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			for deviceID, relay := range s.puncher.due(time.Now()) {
+				if !s.isRelayed(deviceID) {
+					s.puncher.forget(deviceID)
+					continue
+				}
+				go s.requestPunch(ctx, deviceID, relay)
+			}
+
+		case d := <-s.puncher.dials:
+			go s.dialPunched(ctx, d.deviceID, d.addrs)
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *service) requestPunch(ctx context.Context, deviceID protocol.DeviceID, relay *url.URL) {
+	ok := false
+	defer func() {
+		if !ok && !s.isRelayed(deviceID) {
+			// Upgraded some other way, or gone.
+			s.puncher.forget(deviceID)
+			return
+		}
+		s.puncher.finished(deviceID, ok, time.Now())
+	}()
+
+	addrs := s.punchAddresses()
+	if len(addrs) == 0 {
+		l.Debugln("Not punching to", deviceID, "as we have no QUIC addresses")
+		return
+	}
+	theirs, err := client.RequestPunch(ctx, relay, deviceID, s.tlsCfg.Certificates, addrs, punchTimeout)
+	if err != nil {
+		l.Debugln("Requesting punch to", deviceID, "via", relay, "failed:", err)
+		return
+	}
+	s.puncher.dialing(deviceID)
+	ok = s.dialPunched(ctx, deviceID, theirs)
+}
+
+// acceptPunch handles a punch invitation from a device we're relayed to,
+// returning our addresses for the answer and dialing the device's.
+func (s *service) acceptPunch(deviceID protocol.DeviceID, addrs []string) ([]string, bool) {
+	if cfg, ok := s.cfg.Device(deviceID); !ok || cfg.Paused {
+		return nil, false
+	}
+	if !s.isRelayed(deviceID) {
+		return nil, false
+	}
+	ours := s.punchAddresses()
+	if len(ours) == 0 {
+		return nil, false
+	}
+	select {
+	case s.puncher.dials <- punchDial{deviceID, addrs}:
+	default:
+		return nil, false
+	}
+	return ours, true
+}
+
+// dialPunched dials the QUIC addresses of the device, returning whether we
+// got a connection.
+func (s *service) dialPunched(ctx context.Context, deviceID protocol.DeviceID, addrs []string) bool {
+	cfg := s.cfg.RawCopy()
+	var dialTargets []dialTarget
+	for _, addr := range addrs {
+		uri, err := url.Parse(addr)
+		if err != nil || !strings.HasPrefix(uri.Scheme, "quic") {
+			continue
+		}
+		dialerFactory, err := getDialerFactory(cfg, uri)
+		if err != nil {
+			continue
+		}
+		dialTargets = append(dialTargets, dialTarget{
+			addr:     addr,
+			dialer:   dialerFactory.New(s.cfg.Options(), s.tlsCfg),
+			priority: dialerFactory.Priority(),
+			deviceID: deviceID,
+			uri:      uri,
+		})
+	}
+	if len(dialTargets) == 0 {
+		return false
+	}
+
+	l.Debugln("Punching to", deviceID, addrs)
+	conn, ok := s.dialParallel(ctx, deviceID, dialTargets)
+	if !ok {
+		return false
+	}
+	select {
+	case s.conns <- conn:
+		return true
+	case <-ctx.Done():
+		conn.Close()
+		return false
+	}
+}
+
+// punchAddresses returns the addresses of our QUIC listeners.
+func (s *service) punchAddresses() []string {
+	s.listenersMut.RLock()
+	var addrs []string
+	for _, listener := range s.listeners {
+		if !strings.HasPrefix(listener.URI().Scheme, "quic") {
+			continue
+		}
+		for _, lanAddr := range listener.LANAddresses() {
+			addrs = append(addrs, lanAddr.String())
+		}
+		for _, wanAddr := range listener.WANAddresses() {
+			addrs = append(addrs, wanAddr.String())
+		}
+	}
+	s.listenersMut.RUnlock()
+	addrs = util.UniqueTrimmedStrings(addrs)
+	if len(addrs) > maxPunchAddresses {
+		addrs = addrs[:maxPunchAddresses]
+	}
+	return addrs
+}
+
+func (s *service) isRelayed(deviceID protocol.DeviceID) bool {
+	ct, ok := s.model.Connection(deviceID)
+	return ok && ct.Priority() >= relayPriority
+}
@@ -0,0 +1,79 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+func TestHolePuncher(t *testing.T) {
+	p := newHolePuncher()
+	now := time.Now()
+
+	old, _ := url.Parse("relay://192.0.2.1:22067/?protocolVersion=2")
+	relay, _ := url.Parse("relay://192.0.2.1:22067/?protocolVersion=3")
+
+	p.relayed(protocol.LocalDeviceID, old, now)
+	if due := p.due(now); len(due) != 0 {
+		t.Fatal("relay without support should be skipped, got", due)
+	}
+
+	p.relayed(protocol.LocalDeviceID, relay, now)
+	due := p.due(now)
+	if len(due) != 1 || due[protocol.LocalDeviceID] != relay {
+		t.Fatal("expected the device to be due, got", due)
+	}
+	if due := p.due(now); len(due) != 0 {
+		t.Fatal("an attempt in progress shouldn't be due again, got", due)
+	}
+
+	// Failed attempts back off.
+	p.dialing(protocol.LocalDeviceID)
+	p.finished(protocol.LocalDeviceID, false, now)
+	if due := p.due(now.Add(punchMinBackoff - time.Second)); len(due) != 0 {
+		t.Fatal("expected to wait out the backoff, got", due)
+	}
+	if due := p.due(now.Add(punchMinBackoff)); len(due) != 1 {
+		t.Fatal("expected the device to be due after the backoff, got", due)
+	}
+
+	// Reconnecting through the relay doesn't reset the backoff.
+	p.finished(protocol.LocalDeviceID, false, now)
+	p.relayed(protocol.LocalDeviceID, relay, now)
+	if due := p.due(now.Add(punchMinBackoff)); len(due) != 0 {
+		t.Fatal("expected to wait out the longer backoff, got", due)
+	}
+
+	// Success ends it.
+	p.due(now.Add(2 * punchMinBackoff))
+	p.finished(protocol.LocalDeviceID, true, now)
+	if due := p.due(now.Add(punchMaxBackoff)); len(due) != 0 {
+		t.Fatal("expected nothing due after success, got", due)
+	}
+}
+
+func TestPunchBackoff(t *testing.T) {
+	cases := []struct {
+		failures int
+		backoff  time.Duration
+	}{
+		{1, punchMinBackoff},
+		{2, 2 * punchMinBackoff},
+		{3, 4 * punchMinBackoff},
+		{7, punchMaxBackoff},
+		{100, punchMaxBackoff},
+	}
+	for _, tc := range cases {
+		if b := punchBackoff(tc.failures); b != tc.backoff {
+			t.Errorf("backoff after %d failures is %v, expected %v", tc.failures, b, tc.backoff)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+// qualityWeight is how much the latest request counts in the moving
+// averages of Quality.
+const qualityWeight = 0.1
+
+// maxErrorRate caps the error rate used in estimates, so that a device
+// that failed every request so far still gets another chance eventually.
+const maxErrorRate = 0.9
+
+// Quality describes how requests to a device have been going lately, as
+// exponentially weighted moving averages over the requests made.
+type Quality struct {
+	Requests   int64         // number of requests made
+	Latency    time.Duration // time taken by a successful request
+	Throughput float64       // bytes per second received by a successful request
+	ErrorRate  float64       // fraction of requests that failed
+}
+
+func (q Quality) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"requests":   q.Requests,
+		"latencyMs":  float64(q.Latency) / float64(time.Millisecond),
+		"throughput": q.Throughput,
+		"errorRate":  q.ErrorRate,
+	})
+}
+
+// Estimate returns how long a request for the given number of bytes can be
+// expected to take, counting the retries needed when requests fail. It's
+// zero when no request has succeeded yet.
+func (q Quality) Estimate(bytes int) time.Duration {
+	var d time.Duration
+	switch {
+	case q.Throughput > 0 && bytes > 0:
+		d = time.Duration(float64(bytes) / q.Throughput * float64(time.Second))
+	default:
+		d = q.Latency
+	}
+	errorRate := q.ErrorRate
+	if errorRate > maxErrorRate {
+		errorRate = maxErrorRate
+	}
+	return time.Duration(float64(d) / (1 - errorRate))
+}
+
+// qualityTracker keeps the Quality of a device up to date as requests
+// complete. It is safe for use from multiple goroutines.
+type qualityTracker struct {
+	mut     sync.Mutex
+	quality Quality
+}
+
+func newQualityTracker() *qualityTracker {
+	return &qualityTracker{mut: sync.NewMutex()}
+}
+
+// record accounts for a request that returned the given number of bytes
+// after the given time. Requests cancelled by the caller say nothing about
+// the device and are ignored.
+func (t *qualityTracker) record(ctx context.Context, bytes int, d time.Duration, err error) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	q := &t.quality
+
+	failed := 0.0
+	if err != nil {
+		failed = 1
+	}
+	if q.Requests == 0 {
+		q.ErrorRate = failed
+	} else {
+		q.ErrorRate = ewma(q.ErrorRate, failed)
+	}
+	q.Requests++
+	if err != nil || d <= 0 {
+		return
+	}
+
+	throughput := float64(bytes) / d.Seconds()
+	if q.Latency == 0 {
+		// The first success, nothing to average with.
+		q.Latency = d
+		q.Throughput = throughput
+		return
+	}
+	q.Latency = time.Duration(ewma(float64(q.Latency), float64(d)))
+	q.Throughput = ewma(q.Throughput, throughput)
+}
+
+func (t *qualityTracker) get() Quality {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	return t.quality
+}
+
+func ewma(avg, sample float64) float64 {
+	return (1-qualityWeight)*avg + qualityWeight*sample
+}
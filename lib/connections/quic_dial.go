@@ -20,6 +20,7 @@ import (
 
 	"github.com/syncthing/syncthing/lib/config"
 	"github.com/syncthing/syncthing/lib/connections/registry"
+	"github.com/syncthing/syncthing/lib/dialer"
 	"github.com/syncthing/syncthing/lib/protocol"
 )
 
@@ -45,6 +46,12 @@ type quicDialer struct {
 func (d *quicDialer) Dial(ctx context.Context, _ protocol.DeviceID, uri *url.URL) (internalConn, error) {
 	uri = fixupPort(uri, config.DefaultQUICPort)
 
+	if proxyURL, ok := dialer.ProxyFromContext(ctx); ok && proxyURL != dialer.Direct {
+		// QUIC can't go through the proxy, and going around it could give
+		// away what it's there to hide.
+		return internalConn{}, errProxyUnsupported
+	}
+
 	addr, err := net.ResolveUDPAddr("udp", uri.Host)
 	if err != nil {
 		return internalConn{}, err
@@ -115,6 +122,10 @@ func (quicDialerFactory) Valid(_ config.Configuration) error {
 	return nil
 }
 
+func (quicDialerFactory) Transport() string {
+	return "quic"
+}
+
 func (quicDialerFactory) String() string {
 	return "QUIC Dialer"
 }
@@ -34,15 +34,19 @@ func (d *relayDialer) Dial(ctx context.Context, id protocol.DeviceID, uri *url.U
 		return internalConn{}, err
 	}
 
-	conn, err := client.JoinSession(ctx, inv)
+	conn, err := client.JoinSharedSession(ctx, uri, inv)
 	if err != nil {
 		return internalConn{}, err
 	}
 
-	err = dialer.SetTCPOptions(conn)
-	if err != nil {
-		conn.Close()
-		return internalConn{}, err
+	// Sessions sharing a relay connection aren't TCP connections of their
+	// own; the options are set on the shared connection instead.
+	if !client.SupportsMultiplexing(uri) {
+		err = dialer.SetTCPOptions(conn)
+		if err != nil {
+			conn.Close()
+			return internalConn{}, err
+		}
 	}
 
 	err = dialer.SetTrafficClass(conn, d.trafficClass)
@@ -91,6 +95,10 @@ func (relayDialerFactory) Valid(cfg config.Configuration) error {
 	return nil
 }
 
+func (relayDialerFactory) Transport() string {
+	return "relay"
+}
+
 func (relayDialerFactory) String() string {
 	return "Relay Dialer"
 }
@@ -18,7 +18,9 @@ import (
 	"github.com/syncthing/syncthing/lib/config"
 	"github.com/syncthing/syncthing/lib/dialer"
 	"github.com/syncthing/syncthing/lib/nat"
+	"github.com/syncthing/syncthing/lib/protocol"
 	"github.com/syncthing/syncthing/lib/relay/client"
+	relayprotocol "github.com/syncthing/syncthing/lib/relay/protocol"
 	"github.com/syncthing/syncthing/lib/util"
 )
 
@@ -39,8 +41,9 @@ type relayListener struct {
 	conns   chan internalConn
 	factory listenerFactory
 
-	client client.RelayClient
-	mut    sync.RWMutex
+	client       client.RelayClient
+	punchHandler func(protocol.DeviceID, []string) ([]string, bool)
+	mut          sync.RWMutex
 }
 
 func (t *relayListener) serve(ctx context.Context) error {
@@ -50,6 +53,7 @@ func (t *relayListener) serve(ctx context.Context) error {
 		return err
 	}
 	invitations := clnt.Invitations()
+	punches := clnt.Punches()
 
 	t.mut.Lock()
 	t.client = clnt
@@ -72,7 +76,7 @@ func (t *relayListener) serve(ctx context.Context) error {
 				return err
 			}
 
-			conn, err := client.JoinSession(ctx, inv)
+			conn, err := client.JoinSharedSession(ctx, clnt.URI(), inv)
 			if err != nil {
 				if errors.Cause(err) != context.Canceled {
 					l.Infoln("Listen (BEP/relay): joining session:", err)
@@ -106,6 +110,9 @@ func (t *relayListener) serve(ctx context.Context) error {
 
 			t.conns <- internalConn{tc, connTypeRelayServer, relayPriority}
 
+		case inv := <-punches:
+			go t.replyPunch(ctx, clnt, inv)
+
 		// Poor mans notifier that informs the connection service that the
 		// relay URI has changed. This can only happen when we connect to a
 		// relay via dynamic+http(s) pool, which upon a relay failing/dropping
@@ -123,6 +130,32 @@ func (t *relayListener) serve(ctx context.Context) error {
 	}
 }
 
+// replyPunch answers the punch invitation with the addresses the handler
+// gives, if it wants to.
+func (t *relayListener) replyPunch(ctx context.Context, clnt client.RelayClient, inv relayprotocol.PunchInvitation) {
+	t.mut.RLock()
+	handler := t.punchHandler
+	t.mut.RUnlock()
+	if handler == nil {
+		return
+	}
+	addrs, ok := handler(protocol.DeviceIDFromBytes(inv.From), inv.Addresses)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, punchTimeout)
+	defer cancel()
+	if err := clnt.ReplyPunch(ctx, inv, addrs); err != nil {
+		l.Debugln("Listen (BEP/relay): replying to punch invitation:", err)
+	}
+}
+
+func (t *relayListener) OnPunch(handler func(protocol.DeviceID, []string) ([]string, bool)) {
+	t.mut.Lock()
+	t.punchHandler = handler
+	t.mut.Unlock()
+}
+
 func (t *relayListener) URI() *url.URL {
 	return t.uri
 }
@@ -12,12 +12,14 @@ import (
 	"fmt"
 	"net"
 	"net/url"
+	"reflect"
 	"sort"
 	"strings"
 	stdsync "sync"
 	"time"
 
 	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/dialer"
 	"github.com/syncthing/syncthing/lib/discover"
 	"github.com/syncthing/syncthing/lib/events"
 	"github.com/syncthing/syncthing/lib/nat"
@@ -43,6 +45,9 @@ var (
 var (
 	errDisabled   = errors.New("disabled by configuration")
 	errDeprecated = errors.New("deprecated protocol")
+
+	errProxyUnsupported = errors.New("not possible through a proxy")
+	errSystemWoke       = errors.New("system woke from sleep")
 )
 
 const (
@@ -115,12 +120,15 @@ type service struct {
 	model                Model
 	tlsCfg               *tls.Config
 	discoverer           discover.Finder
+	addressCache         *discover.AddressCache
 	conns                chan internalConn
 	bepProtocolName      string
 	tlsDefaultCommonName string
 	limiter              *limiter
 	natService           *nat.Service
 	natServiceToken      *suture.ServiceToken
+	external             *externalAddresses
+	puncher              *holePuncher
 	evLogger             events.Logger
 
 	listenersMut       sync.RWMutex
@@ -132,7 +140,7 @@ type service struct {
 	connectionStatus    map[string]ConnectionStatusEntry // address -> latest error/status
 }
 
-func NewService(cfg config.Wrapper, myID protocol.DeviceID, mdl Model, tlsCfg *tls.Config, discoverer discover.Finder, bepProtocolName string, tlsDefaultCommonName string, evLogger events.Logger) Service {
+func NewService(cfg config.Wrapper, myID protocol.DeviceID, mdl Model, tlsCfg *tls.Config, discoverer discover.Finder, addressCache *discover.AddressCache, bepProtocolName string, tlsDefaultCommonName string, evLogger events.Logger) Service {
 	service := &service{
 		Supervisor: suture.New("connections.Service", suture.Spec{
 			Log: func(line string) {
@@ -145,11 +153,14 @@ func NewService(cfg config.Wrapper, myID protocol.DeviceID, mdl Model, tlsCfg *t
 		model:                mdl,
 		tlsCfg:               tlsCfg,
 		discoverer:           discoverer,
+		addressCache:         addressCache,
 		conns:                make(chan internalConn),
 		bepProtocolName:      bepProtocolName,
 		tlsDefaultCommonName: tlsDefaultCommonName,
 		limiter:              newLimiter(cfg),
 		natService:           nat.NewService(myID, cfg),
+		external:             newExternalAddresses(cfg, evLogger),
+		puncher:              newHolePuncher(),
 		evLogger:             evLogger,
 
 		listenersMut:   sync.NewRWMutex(),
@@ -189,6 +200,8 @@ func NewService(cfg config.Wrapper, myID protocol.DeviceID, mdl Model, tlsCfg *t
 	service.Add(util.AsService(service.connect, fmt.Sprintf("%s/connect", service)))
 	service.Add(util.AsService(service.handle, fmt.Sprintf("%s/handle", service)))
 	service.Add(service.listenerSupervisor)
+	service.Add(util.AsService(service.external.serve, fmt.Sprintf("%s/external", service)))
+	service.Add(util.AsService(service.punch, fmt.Sprintf("%s/punch", service)))
 
 	return service
 }
@@ -240,7 +253,8 @@ func (s *service) handle(ctx context.Context) {
 		}
 
 		_ = c.SetDeadline(time.Now().Add(20 * time.Second))
-		hello, err := protocol.ExchangeHello(c, s.model.GetHello(remoteID))
+		ourHello := s.model.GetHello(remoteID)
+		hello, err := protocol.ExchangeHello(c, ourHello)
 		if err != nil {
 			if protocol.IsVersionMismatch(err) {
 				// The error will be a relatively user friendly description
@@ -280,6 +294,10 @@ func (s *service) handle(ctx context.Context) {
 		// Lower priority is better, just like nice etc.
 		if connected && ct.Priority() > c.priority {
 			l.Debugf("Switching connections %s (existing: %s new: %s)", remoteID, ct, c)
+		} else if connected && ct.CanAdd(c.Transport()) {
+			// Both sides want to use several transports at once, and
+			// this one isn't in use yet.
+			l.Debugf("Adding connection %s (existing: %s new: %s)", remoteID, ct, c)
 		} else if connected {
 			// We should not already be connected to the other party. TODO: This
 			// could use some better handling. If the old connection is dead but
@@ -321,8 +339,30 @@ func (s *service) handle(ctx context.Context) {
 		isLAN := s.isLAN(c.RemoteAddr())
 		rd, wr := s.limiter.getLimiters(remoteID, c, isLAN)
 
-		protoConn := protocol.NewConnection(remoteID, rd, wr, s.model, c.String(), deviceCfg.Compression)
-		modelConn := completeConn{c, protoConn}
+		// Both sides pad relayed connections if both want to. The padding
+		// counts towards the rate limits.
+		if c.connType.isRelayed() && deviceCfg.PadRelayed && hello.SupportsPadding {
+			l.Debugln("Padding relayed connection to", remoteID)
+			rd, wr = newPaddedReader(rd), newPaddedWriter(ctx, wr)
+		}
+
+		// Older devices only understand LZ4 compressed messages.
+		algorithm := hello.CompressionAlgorithmFor(deviceCfg.CompressionAlgorithm)
+		// Requests for the data of folders restricted to other transports
+		// are refused.
+		receiver := &restrictedModel{Model: s.model, cfg: s.cfg, transport: c.Transport(), local: isLAN}
+		// Having announced an index window, we acknowledge the index
+		// messages we've handled for the other side to send more.
+		opts := protocol.ConnectionOptions{
+			Compression:          deviceCfg.Compression,
+			CompressionAlgorithm: algorithm,
+			IndexSendWindow:      int(hello.IndexWindow),
+		}
+		if h, ok := ourHello.(*protocol.Hello); ok {
+			opts.AckIndexes = h.IndexWindow > 0
+		}
+		protoConn := protocol.NewConnectionWithOptions(remoteID, rd, wr, receiver, c.String(), opts)
+		modelConn := completeConn{c, protoConn, isLAN}
 
 		l.Infof("Established secure connection to %s at %s", remoteID, c)
 
@@ -341,6 +381,9 @@ func (s *service) connect(ctx context.Context) {
 	// Calculated from actual dialers reconnectInterval
 	var sleep time.Duration
 
+	wakeSub := s.evLogger.Subscribe(events.SystemWoke)
+	defer wakeSub.Unsubscribe()
+
 	for {
 		cfg := s.cfg.RawCopy()
 
@@ -371,108 +414,144 @@ func (s *service) connect(ctx context.Context) {
 
 			ct, connected := s.model.Connection(deviceID)
 
-			if connected && ct.Priority() == bestDialerPrio {
+			if connected && ct.Priority() == bestDialerPrio && !ct.Multipath() {
 				// Things are already as good as they can get.
 				continue
 			}
 
 			var addrs []string
+			dynamic := false
 			for _, addr := range deviceCfg.Addresses {
 				if addr == "dynamic" {
-					if s.discoverer != nil {
-						if t, err := s.discoverer.Lookup(deviceID); err == nil {
-							addrs = append(addrs, t...)
-						}
-					}
+					dynamic = true
 				} else {
 					addrs = append(addrs, addr)
 				}
 			}
 
-			addrs = util.UniqueTrimmedStrings(addrs)
+			tried := make(map[string]struct{})
+			dial := func(addrs []string) bool {
+				addrs = util.UniqueTrimmedStrings(addrs)
 
-			l.Debugln("Reconnect loop for", deviceID, addrs)
+				l.Debugln("Reconnect loop for", deviceID, addrs)
 
-			dialTargets := make([]dialTarget, 0)
+				dialTargets := make([]dialTarget, 0)
 
-			for _, addr := range addrs {
-				// Use a special key that is more than just the address, as you might have two devices connected to the same relay
-				nextDialKey := deviceID.String() + "/" + addr
-				seen = append(seen, nextDialKey)
-				nextDialAt, ok := nextDial[nextDialKey]
-				if ok && initialRampup >= sleep && nextDialAt.After(now) {
-					l.Debugf("Not dialing %s via %v as sleep is %v, next dial is at %s and current time is %s", deviceID, addr, sleep, nextDialAt, now)
-					continue
-				}
-				// If we fail at any step before actually getting the dialer
-				// retry in a minute
-				nextDial[nextDialKey] = now.Add(time.Minute)
+				for _, addr := range addrs {
+					if _, ok := tried[addr]; ok {
+						continue
+					}
+					tried[addr] = struct{}{}
+
+					// Use a special key that is more than just the address, as you might have two devices connected to the same relay
+					nextDialKey := deviceID.String() + "/" + addr
+					seen = append(seen, nextDialKey)
+					nextDialAt, ok := nextDial[nextDialKey]
+					if ok && initialRampup >= sleep && nextDialAt.After(now) {
+						l.Debugf("Not dialing %s via %v as sleep is %v, next dial is at %s and current time is %s", deviceID, addr, sleep, nextDialAt, now)
+						continue
+					}
+					// If we fail at any step before actually getting the dialer
+					// retry in a minute
+					nextDial[nextDialKey] = now.Add(time.Minute)
+
+					uri, err := url.Parse(addr)
+					if err != nil {
+						s.setConnectionStatus(addr, err)
+						l.Infof("Parsing dialer address %s: %v", addr, err)
+						continue
+					}
 
-				uri, err := url.Parse(addr)
-				if err != nil {
-					s.setConnectionStatus(addr, err)
-					l.Infof("Parsing dialer address %s: %v", addr, err)
-					continue
-				}
+					// The address may ask for a proxy of its own.
+					proxyURL := deviceCfg.Proxy
+					if q := uri.Query(); q.Get("proxy") != "" {
+						proxyURL = q.Get("proxy")
+						q.Del("proxy")
+						uri.RawQuery = q.Encode()
+					}
+					proxied := proxyURL != "" && proxyURL != dialer.Direct
 
-				if len(deviceCfg.AllowedNetworks) > 0 {
-					if !IsAllowedNetwork(uri.Host, deviceCfg.AllowedNetworks) {
-						s.setConnectionStatus(addr, errors.New("network disallowed"))
-						l.Debugln("Network for", uri, "is disallowed")
-						continue
+					if len(deviceCfg.AllowedNetworks) > 0 {
+						if !IsAllowedNetwork(uri.Host, deviceCfg.AllowedNetworks) {
+							s.setConnectionStatus(addr, errors.New("network disallowed"))
+							l.Debugln("Network for", uri, "is disallowed")
+							continue
+						}
 					}
-				}
 
-				dialerFactory, err := getDialerFactory(cfg, uri)
-				if err != nil {
-					s.setConnectionStatus(addr, err)
-				}
-				switch err {
-				case nil:
-					// all good
-				case errDisabled:
-					l.Debugln("Dialer for", uri, "is disabled")
-					continue
-				case errDeprecated:
-					l.Debugln("Dialer for", uri, "is deprecated")
-					continue
-				default:
-					l.Infof("Dialer for %v: %v", uri, err)
-					continue
-				}
+					dialerFactory, err := getDialerFactory(cfg, uri)
+					if err != nil {
+						s.setConnectionStatus(addr, err)
+					}
+					switch err {
+					case nil:
+						// all good
+					case errDisabled:
+						l.Debugln("Dialer for", uri, "is disabled")
+						continue
+					case errDeprecated:
+						l.Debugln("Dialer for", uri, "is deprecated")
+						continue
+					default:
+						l.Infof("Dialer for %v: %v", uri, err)
+						continue
+					}
 
-				priority := dialerFactory.Priority()
+					priority := dialerFactory.Priority()
 
-				if connected && priority >= ct.Priority() {
-					l.Debugf("Not dialing using %s as priority is less than current connection (%d >= %d)", dialerFactory, dialerFactory.Priority(), ct.Priority())
-					continue
-				}
+					if connected && priority >= ct.Priority() && !ct.CanAdd(dialerFactory.Transport()) {
+						l.Debugf("Not dialing using %s as priority is less than current connection (%d >= %d)", dialerFactory, dialerFactory.Priority(), ct.Priority())
+						continue
+					}
 
-				dialer := dialerFactory.New(s.cfg.Options(), s.tlsCfg)
-				nextDial[nextDialKey] = now.Add(dialer.RedialFrequency())
+					dialer := dialerFactory.New(s.cfg.Options(), s.tlsCfg)
+					nextDial[nextDialKey] = now.Add(dialer.RedialFrequency())
+
+					// For LAN addresses, increase the priority so that we
+					// try these first.
+					switch {
+					case dialerFactory.AlwaysWAN():
+						// Do nothing.
+					case proxied:
+						// Resolving the name here could give away what
+						// the proxy is there to hide.
+					case s.isLANHost(uri.Host):
+						priority -= 1
+					}
 
-				// For LAN addresses, increase the priority so that we
-				// try these first.
-				switch {
-				case dialerFactory.AlwaysWAN():
-					// Do nothing.
-				case s.isLANHost(uri.Host):
-					priority -= 1
+					dialTargets = append(dialTargets, dialTarget{
+						addr:     addr,
+						dialer:   dialer,
+						priority: priority,
+						deviceID: deviceID,
+						uri:      uri,
+						remember: dynamic,
+						proxy:    proxyURL,
+					})
 				}
 
-				dialTargets = append(dialTargets, dialTarget{
-					addr:     addr,
-					dialer:   dialer,
-					priority: priority,
-					deviceID: deviceID,
-					uri:      uri,
-				})
+				conn, ok := s.dialParallel(ctx, deviceCfg.DeviceID, dialTargets)
+				if ok {
+					s.conns <- conn
+				}
+				return ok
 			}
 
-			conn, ok := s.dialParallel(ctx, deviceCfg.DeviceID, dialTargets)
-			if ok {
-				s.conns <- conn
+			// Addresses that worked before are tried first on their own, as
+			// discovery may take a while to answer, if it does at all.
+			if dynamic && s.addressCache != nil {
+				if cached := s.addressCache.Addresses(deviceID); len(cached) > 0 {
+					if dial(append(addrs, cached...)) {
+						continue
+					}
+				}
 			}
+			if dynamic && s.discoverer != nil {
+				if t, err := s.discoverer.Lookup(deviceID); err == nil {
+					addrs = append(addrs, t...)
+				}
+			}
+			dial(addrs)
 		}
 
 		nextDial, sleep = filterAndFindSleepDuration(nextDial, seen, now)
@@ -487,12 +566,29 @@ func (s *service) connect(ctx context.Context) {
 
 		select {
 		case <-time.After(sleep):
+		case <-wakeSub.C():
+			// Connections that were up when we went to sleep are most
+			// likely dead now, but it could take minutes for the ping
+			// timeouts to tell. Drop them and redial everything from
+			// scratch.
+			s.closeAllConnections(cfg, errSystemWoke)
+			nextDial = make(map[string]time.Time)
+			initialRampup = time.Second
 		case <-ctx.Done():
 			return
 		}
 	}
 }
 
+func (s *service) closeAllConnections(cfg config.Configuration, err error) {
+	for _, deviceCfg := range cfg.Devices {
+		if ct, ok := s.model.Connection(deviceCfg.DeviceID); ok {
+			l.Infof("Closing connection to %s at %s: %v", deviceCfg.DeviceID, ct.Name(), err)
+			ct.Close(err)
+		}
+	}
+}
+
 func (s *service) isLANHost(host string) bool {
 	// Probably we are called with an ip:port combo which we can resolve as
 	// a TCP address.
@@ -517,8 +613,10 @@ func (s *service) isLAN(addr net.Addr) bool {
 		ip = addr.IP
 	case *net.UDPAddr:
 		ip = addr.IP
+	case *net.UnixAddr:
+		// Never leaves this host.
+		return true
 	default:
-		// From the standard library, just Unix sockets.
 		// If you invent your own, handle it.
 		return false
 	}
@@ -555,6 +653,9 @@ func (s *service) createListener(factory listenerFactory, uri *url.URL) bool {
 
 	listener := factory.New(uri, s.cfg, s.tlsCfg, s.conns, s.natService)
 	listener.OnAddressesChanged(s.logListenAddressesChangedEvent)
+	if pl, ok := listener.(punchListener); ok {
+		pl.OnPunch(s.acceptPunch)
+	}
 	s.listeners[uri.String()] = listener
 	s.listenerTokens[uri.String()] = s.listenerSupervisor.Add(listener)
 	return true
@@ -646,6 +747,10 @@ func (s *service) CommitConfiguration(from, to config.Configuration) bool {
 		s.natServiceToken = nil
 	}
 
+	if !reflect.DeepEqual(from.Options.RawExternalAddresses, to.Options.RawExternalAddresses) || !reflect.DeepEqual(from.Options.ListenAddresses(), to.Options.ListenAddresses()) {
+		s.external.Refresh()
+	}
+
 	return true
 }
 
@@ -661,6 +766,7 @@ func (s *service) AllAddresses() []string {
 		}
 	}
 	s.listenersMut.RUnlock()
+	addrs = append(addrs, s.external.Addresses()...)
 	return util.UniqueTrimmedStrings(addrs)
 }
 
@@ -673,6 +779,7 @@ func (s *service) ExternalAddresses() []string {
 		}
 	}
 	s.listenersMut.RUnlock()
+	addrs = append(addrs, s.external.Addresses()...)
 	return util.UniqueTrimmedStrings(addrs)
 }
 
@@ -867,10 +974,20 @@ func (s *service) dialParallel(ctx context.Context, deviceID protocol.DeviceID,
 					err = s.validateIdentity(conn, deviceID)
 				}
 				s.setConnectionStatus(tgt.addr, err)
+				if tgt.remember && s.addressCache != nil {
+					if err != nil {
+						s.addressCache.Failed(deviceID, tgt.addr)
+					} else {
+						s.addressCache.Succeeded(deviceID, tgt.addr)
+					}
+				}
 				if err != nil {
 					l.Debugln("dialing", deviceID, tgt.uri, "error:", err)
 				} else {
 					l.Debugln("dialing", deviceID, tgt.uri, "success:", conn)
+					if conn.connType == connTypeRelayClient {
+						s.puncher.relayed(deviceID, tgt.uri, time.Now())
+					}
 					res <- conn
 				}
 				wg.Done()
@@ -16,6 +16,7 @@ import (
 	"time"
 
 	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/dialer"
 	"github.com/syncthing/syncthing/lib/nat"
 	"github.com/syncthing/syncthing/lib/protocol"
 )
@@ -30,6 +31,7 @@ type Connection interface {
 	Priority() int
 	String() string
 	Crypto() string
+	IsLocal() bool
 }
 
 // completeConn is the aggregation of an internalConn and the
@@ -38,6 +40,13 @@ type Connection interface {
 type completeConn struct {
 	internalConn
 	protocol.Connection
+	local bool
+}
+
+// IsLocal returns true if the connection is to the LAN, as for rate
+// limiting.
+func (c completeConn) IsLocal() bool {
+	return c.local
 }
 
 func (c completeConn) Close(err error) {
@@ -71,8 +80,16 @@ const (
 	connTypeTCPServer
 	connTypeQUICClient
 	connTypeQUICServer
+	connTypeWSSClient
+	connTypeWSSServer
+	connTypeUnixClient
+	connTypeUnixServer
 )
 
+func (t connType) isRelayed() bool {
+	return t == connTypeRelayClient || t == connTypeRelayServer
+}
+
 func (t connType) String() string {
 	switch t {
 	case connTypeRelayClient:
@@ -87,6 +104,14 @@ func (t connType) String() string {
 		return "quic-client"
 	case connTypeQUICServer:
 		return "quic-server"
+	case connTypeWSSClient:
+		return "wss-client"
+	case connTypeWSSServer:
+		return "wss-server"
+	case connTypeUnixClient:
+		return "unix-client"
+	case connTypeUnixServer:
+		return "unix-server"
 	default:
 		return "unknown-type"
 	}
@@ -100,6 +125,10 @@ func (t connType) Transport() string {
 		return "tcp"
 	case connTypeQUICClient, connTypeQUICServer:
 		return "quic"
+	case connTypeWSSClient, connTypeWSSServer:
+		return "wss"
+	case connTypeUnixClient, connTypeUnixServer:
+		return "unix"
 	default:
 		return "unknown"
 	}
@@ -151,6 +180,7 @@ type dialerFactory interface {
 	Priority() int
 	AlwaysWAN() bool
 	Valid(config.Configuration) error
+	Transport() string
 	String() string
 }
 
@@ -197,7 +227,7 @@ type genericListener interface {
 type Model interface {
 	protocol.Model
 	AddConnection(conn Connection, hello protocol.HelloResult)
-	Connection(remoteID protocol.DeviceID) (Connection, bool)
+	Connection(remoteID protocol.DeviceID) (*ConnectionSet, bool)
 	OnHello(protocol.DeviceID, net.Addr, protocol.HelloResult) error
 	GetHello(protocol.DeviceID) protocol.HelloIntf
 }
@@ -222,9 +252,14 @@ type dialTarget struct {
 	priority int
 	uri      *url.URL
 	deviceID protocol.DeviceID
+	remember bool   // in the address cache
+	proxy    string // overrides the environment if set
 }
 
 func (t dialTarget) Dial(ctx context.Context) (internalConn, error) {
 	l.Debugln("dialing", t.deviceID, t.uri, "prio", t.priority)
+	if t.proxy != "" {
+		ctx = dialer.WithProxy(ctx, t.proxy)
+	}
 	return t.dialer.Dial(ctx, t.deviceID, t.uri)
 }
@@ -83,6 +83,10 @@ func (tcpDialerFactory) Valid(_ config.Configuration) error {
 	return nil
 }
 
+func (tcpDialerFactory) Transport() string {
+	return "tcp"
+}
+
 func (tcpDialerFactory) String() string {
 	return "TCP Dialer"
 }
@@ -0,0 +1,72 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"context"
+	"strings"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// TransportLAN allows any connection to the LAN, whatever its transport.
+const TransportLAN = "lan"
+
+// TransportAllowed returns whether file data of a folder restricted to the
+// given transports may be transferred over a connection with the given
+// transport, as returned by Connection.Transport, that is or isn't to the
+// LAN. The restrictions name transports with or without the address
+// family, or are TransportLAN. No restrictions allow any connection.
+func TransportAllowed(transport string, local bool, transports []string) bool {
+	if len(transports) == 0 {
+		return true
+	}
+	for _, t := range transports {
+		switch t {
+		case TransportLAN:
+			if local {
+				return true
+			}
+		case transport, strings.TrimRight(transport, "46"):
+			return true
+		}
+	}
+	return false
+}
+
+type transportsKey struct{}
+
+// WithTransports returns a context that makes requests sent through a
+// ConnectionSet with it use only the connections allowed for the given
+// transports, as by TransportAllowed.
+func WithTransports(ctx context.Context, transports []string) context.Context {
+	return context.WithValue(ctx, transportsKey{}, transports)
+}
+
+func contextTransports(ctx context.Context) []string {
+	transports, _ := ctx.Value(transportsKey{}).([]string)
+	return transports
+}
+
+// A restrictedModel receives the messages coming in on one connection,
+// refusing requests for the data of folders restricted to transports other
+// than that of the connection.
+type restrictedModel struct {
+	protocol.Model
+	cfg       config.Wrapper
+	transport string
+	local     bool
+}
+
+func (m *restrictedModel) Request(deviceID protocol.DeviceID, folder, name string, size int32, offset int64, hash []byte, weakHash uint32, fromTemporary bool, priority protocol.RequestPriority) (protocol.RequestResponse, error) {
+	if cfg, ok := m.cfg.Folder(folder); ok && !TransportAllowed(m.transport, m.local, cfg.DataTransports) {
+		l.Debugf("Refusing request from %s for %s in folder %q over %s", deviceID, name, folder, m.transport)
+		return nil, protocol.ErrGeneric
+	}
+	return m.Model.Request(deviceID, folder, name, size, offset, hash, weakHash, fromTemporary, priority)
+}
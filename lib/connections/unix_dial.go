@@ -0,0 +1,82 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+func init() {
+	dialers["unix"] = &unixDialerFactory{}
+}
+
+type unixDialer struct {
+	commonDialer
+}
+
+func (d *unixDialer) Dial(ctx context.Context, _ protocol.DeviceID, uri *url.URL) (internalConn, error) {
+	path, err := unixSocketPath(uri)
+	if err != nil {
+		return internalConn{}, err
+	}
+
+	// A proxy is of no use for a socket on this host, so this doesn't go
+	// through lib/dialer.
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	var nd net.Dialer
+	conn, err := nd.DialContext(timeoutCtx, "unix", path)
+	if err != nil {
+		return internalConn{}, err
+	}
+
+	tc := tls.Client(conn, d.tlsCfg)
+	err = tlsTimedHandshake(tc)
+	if err != nil {
+		tc.Close()
+		return internalConn{}, err
+	}
+
+	return internalConn{tc, connTypeUnixClient, unixPriority}, nil
+}
+
+type unixDialerFactory struct{}
+
+func (unixDialerFactory) New(opts config.OptionsConfiguration, tlsCfg *tls.Config) genericDialer {
+	return &unixDialer{commonDialer{
+		reconnectInterval: time.Duration(opts.ReconnectIntervalS) * time.Second,
+		tlsCfg:            tlsCfg,
+	}}
+}
+
+func (unixDialerFactory) Priority() int {
+	return unixPriority
+}
+
+func (unixDialerFactory) AlwaysWAN() bool {
+	return false
+}
+
+func (unixDialerFactory) Valid(_ config.Configuration) error {
+	// Always valid
+	return nil
+}
+
+func (unixDialerFactory) Transport() string {
+	return "unix"
+}
+
+func (unixDialerFactory) String() string {
+	return "UNIX Socket Dialer"
+}
@@ -0,0 +1,53 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// The unix transport connects two devices on the same host through a UNIX
+// domain socket, for example instances run by different users. Who may
+// connect is decided by the permissions on the socket file, in addition to
+// the usual device checks. An address looks like unix:///path/to/socket,
+// optionally with ?mode=0660 to set the permissions of the socket created
+// by the listener.
+
+// Better than anything over the network, as it never leaves the host.
+const unixPriority = 5
+
+// unixSocketPath returns the path of the socket in the given address.
+func unixSocketPath(uri *url.URL) (string, error) {
+	// Host is usually empty, but may be a drive letter on Windows or the
+	// first element of a relative path.
+	path := uri.Host + uri.Path
+	if path == "" {
+		path = uri.Opaque
+	}
+	if path == "" {
+		return "", errors.New("missing socket path")
+	}
+	return path, nil
+}
+
+// unixSocketMode returns the permissions asked for in the address, or zero
+// to leave them as created.
+func unixSocketMode(uri *url.URL) (os.FileMode, error) {
+	mode := uri.Query().Get("mode")
+	if mode == "" {
+		return 0, nil
+	}
+	m, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || m&^0777 != 0 {
+		return 0, errors.Errorf("invalid socket mode %q", mode)
+	}
+	return os.FileMode(m), nil
+}
@@ -0,0 +1,162 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/tlsutil"
+)
+
+func TestUnixSocketPath(t *testing.T) {
+	cases := []struct {
+		addr string
+		path string
+	}{
+		{"unix:///var/run/st.sock", "/var/run/st.sock"},
+		{"unix://st.sock", "st.sock"},
+		{"unix://run/st.sock", "run/st.sock"},
+		{"unix:st.sock", "st.sock"},
+		{"unix://C:/st.sock", "C:/st.sock"},
+		{"unix:///var/run/st.sock?mode=0660", "/var/run/st.sock"},
+	}
+	for _, tc := range cases {
+		uri, err := url.Parse(tc.addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if path, err := unixSocketPath(uri); err != nil || path != tc.path {
+			t.Errorf("unixSocketPath(%q) => %q, %v, expected %q", tc.addr, path, err, tc.path)
+		}
+	}
+
+	if _, err := unixSocketPath(&url.URL{Scheme: "unix"}); err == nil {
+		t.Error("expected an error for a missing path")
+	}
+}
+
+func TestUnixSocketMode(t *testing.T) {
+	cases := []struct {
+		addr string
+		mode os.FileMode
+		ok   bool
+	}{
+		{"unix:///st.sock", 0, true},
+		{"unix:///st.sock?mode=0660", 0660, true},
+		{"unix:///st.sock?mode=600", 0600, true},
+		{"unix:///st.sock?mode=0999", 0, false},
+		{"unix:///st.sock?mode=01777", 0, false},
+	}
+	for _, tc := range cases {
+		uri, err := url.Parse(tc.addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mode, err := unixSocketMode(uri)
+		if (err == nil) != tc.ok || mode != tc.mode {
+			t.Errorf("unixSocketMode(%q) => %o, %v, expected %o", tc.addr, mode, err, tc.mode)
+		}
+	}
+}
+
+func TestUnixDialListen(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs UNIX domain sockets")
+	}
+
+	dir, err := ioutil.TempDir("", "unix")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tlsCfg := func(name string) *tls.Config {
+		cert, err := tlsutil.NewCertificate(filepath.Join(dir, name+".pem"), filepath.Join(dir, name+"-key.pem"), "syncthing", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg := tlsutil.SecureDefault()
+		cfg.Certificates = []tls.Certificate{cert}
+		cfg.NextProtos = []string{"bep/1.0"}
+		cfg.ClientAuth = tls.RequestClientCert
+		cfg.InsecureSkipVerify = true
+		return cfg
+	}
+
+	// Left behind by a listener that didn't clean up.
+	path := filepath.Join(dir, "st.sock")
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	uri := &url.URL{Scheme: "unix", Path: path, RawQuery: "mode=0600"}
+	conns := make(chan internalConn, 1)
+	w := config.Wrap("/dev/null", config.New(protocol.LocalDeviceID), events.NoopLogger)
+	lst := (&unixListenerFactory{}).New(uri, w, tlsCfg("server"), conns, nil)
+	go lst.Serve()
+	defer lst.Stop()
+
+	d := (&unixDialerFactory{}).New(config.OptionsConfiguration{}, tlsCfg("client"))
+	var client internalConn
+	for i := 0; ; i++ {
+		// The listener may not have started yet.
+		client, err = d.Dial(context.Background(), protocol.LocalDeviceID, uri)
+		if err == nil {
+			break
+		}
+		if i == 50 {
+			t.Fatal(err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	defer client.Close()
+
+	var server internalConn
+	select {
+	case server = <-conns:
+	case <-time.After(10 * time.Second):
+		t.Fatal("no connection accepted")
+	}
+	defer server.Close()
+
+	if info, err := os.Stat(path); err != nil {
+		t.Error(err)
+	} else if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("socket has mode %o, expected 0600", perm)
+	}
+	if client.Type() != "unix-client" || server.Type() != "unix-server" {
+		t.Errorf("unexpected types %s and %s", client.Type(), server.Type())
+	}
+	if tr := server.Transport(); tr != "unix" {
+		t.Errorf("unexpected transport %s", tr)
+	}
+	if !(&service{cfg: w}).isLAN(server.RemoteAddr()) {
+		t.Error("expected a connection over a UNIX socket to be local")
+	}
+
+	go client.Write([]byte("hello over a socket"))
+	buf := make([]byte, len("hello over a socket"))
+	if _, err := io.ReadFull(server, buf); err != nil || string(buf) != "hello over a socket" {
+		t.Errorf("read %q, %v", buf, err)
+	}
+}
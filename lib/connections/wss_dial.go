@@ -0,0 +1,113 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"context"
+	"crypto/tls"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/websocket"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/dialer"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+func init() {
+	dialers["wss"] = &wssDialerFactory{}
+}
+
+type wssDialer struct {
+	commonDialer
+	outerTLSCfg *tls.Config
+}
+
+func (d *wssDialer) Dial(ctx context.Context, _ protocol.DeviceID, uri *url.URL) (internalConn, error) {
+	uri = fixupPort(uri, config.DefaultWSSPort)
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	conn, err := dialThroughProxy(timeoutCtx, uri.Host)
+	if err != nil {
+		return internalConn{}, err
+	}
+
+	err = dialer.SetTCPOptions(conn)
+	if err != nil {
+		l.Debugln("Dial (BEP/wss): setting tcp options:", err)
+	}
+
+	outerCfg := d.outerTLSCfg.Clone()
+	outerCfg.ServerName = uri.Hostname()
+	outer := tls.Client(conn, outerCfg)
+	if err := tlsTimedHandshake(outer); err != nil {
+		outer.Close()
+		return internalConn{}, errors.Wrap(err, "outer TLS")
+	}
+
+	location := &url.URL{Scheme: "wss", Host: uri.Host, Path: uri.Path}
+	if location.Path == "" {
+		location.Path = "/"
+	}
+	wsCfg, err := websocket.NewConfig(location.String(), "https://"+uri.Host)
+	if err != nil {
+		outer.Close()
+		return internalConn{}, err
+	}
+	outer.SetDeadline(time.Now().Add(tlsHandshakeTimeout))
+	ws, err := websocket.NewClient(wsCfg, outer)
+	outer.SetDeadline(time.Time{})
+	if err != nil {
+		outer.Close()
+		return internalConn{}, errors.Wrap(err, "websocket")
+	}
+
+	tc := tls.Client(newWSConn(ws, conn.LocalAddr(), conn.RemoteAddr()), d.tlsCfg)
+	err = tlsTimedHandshake(tc)
+	if err != nil {
+		tc.Close()
+		return internalConn{}, err
+	}
+
+	return internalConn{tc, connTypeWSSClient, wssPriority}, nil
+}
+
+type wssDialerFactory struct{}
+
+func (wssDialerFactory) New(opts config.OptionsConfiguration, tlsCfg *tls.Config) genericDialer {
+	return &wssDialer{
+		commonDialer: commonDialer{
+			reconnectInterval: time.Duration(opts.ReconnectIntervalS) * time.Second,
+			tlsCfg:            tlsCfg,
+		},
+		outerTLSCfg: wssOuterTLSConfig(tlsCfg),
+	}
+}
+
+func (wssDialerFactory) Priority() int {
+	return wssPriority
+}
+
+func (wssDialerFactory) AlwaysWAN() bool {
+	return false
+}
+
+func (wssDialerFactory) Valid(_ config.Configuration) error {
+	// Always valid
+	return nil
+}
+
+func (wssDialerFactory) Transport() string {
+	return "wss"
+}
+
+func (wssDialerFactory) String() string {
+	return "WebSocket Dialer"
+}
@@ -0,0 +1,179 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"context"
+	"crypto/tls"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/dialer"
+	"github.com/syncthing/syncthing/lib/nat"
+	"github.com/syncthing/syncthing/lib/util"
+)
+
+func init() {
+	listeners["wss"] = &wssListenerFactory{}
+}
+
+type wssListener struct {
+	util.ServiceWithError
+	onAddressesChangedNotifier
+
+	uri     *url.URL
+	cfg     config.Wrapper
+	tlsCfg  *tls.Config
+	conns   chan internalConn
+	factory listenerFactory
+}
+
+func (t *wssListener) serve(ctx context.Context) error {
+	listener, err := net.Listen("tcp", t.uri.Host)
+	if err != nil {
+		l.Infoln("Listen (BEP/wss):", err)
+		return err
+	}
+	defer listener.Close()
+
+	l.Infof("WebSocket listener (%v) starting", listener.Addr())
+	defer l.Infof("WebSocket listener (%v) shutting down", listener.Addr())
+
+	path := t.uri.Path
+	if path == "" {
+		path = "/"
+	}
+	mux := http.NewServeMux()
+	mux.Handle(path, websocket.Server{
+		// Any origin will do, the BEP handshake decides who gets in.
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			t.handle(ctx, ws)
+		},
+	})
+	srv := &http.Server{
+		Handler:      mux,
+		ReadTimeout:  tlsHandshakeTimeout,
+		WriteTimeout: tlsHandshakeTimeout,
+		// Failed handshakes from scanners aren't worth logging.
+		ErrorLog: log.New(ioutil.Discard, "", 0),
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- srv.Serve(tls.NewListener(tcpOptionsListener{listener}, wssOuterTLSConfig(t.tlsCfg)))
+	}()
+
+	select {
+	case <-ctx.Done():
+		srv.Close()
+		return nil
+	case err := <-errChan:
+		l.Warnln("Listen (BEP/wss):", err)
+		return err
+	}
+}
+
+// handle runs the BEP handshake on an accepted WebSocket connection and
+// hands it on, then waits for it to be closed as the connection ends when
+// we return.
+func (t *wssListener) handle(ctx context.Context, ws *websocket.Conn) {
+	req := ws.Request()
+	local, _ := req.Context().Value(http.LocalAddrContextKey).(net.Addr)
+	remote, err := net.ResolveTCPAddr("tcp", req.RemoteAddr)
+	if err != nil {
+		l.Debugln("Listen (BEP/wss): remote address:", err)
+		return
+	}
+	l.Debugln("Listen (BEP/wss): connect from", remote)
+
+	// The deadlines set by the HTTP server for the upgrade don't apply to
+	// the connection from now on.
+	ws.SetDeadline(time.Time{})
+
+	conn := newWSConn(ws, local, remote)
+	tc := tls.Server(conn, t.tlsCfg)
+	if err := tlsTimedHandshake(tc); err != nil {
+		l.Infoln("Listen (BEP/wss): TLS handshake:", err)
+		tc.Close()
+		return
+	}
+
+	select {
+	case t.conns <- internalConn{tc, connTypeWSSServer, wssPriority}:
+	case <-ctx.Done():
+		tc.Close()
+		return
+	}
+	select {
+	case <-conn.closed:
+	case <-ctx.Done():
+	}
+}
+
+func (t *wssListener) URI() *url.URL {
+	return t.uri
+}
+
+func (t *wssListener) WANAddresses() []*url.URL {
+	return t.LANAddresses()
+}
+
+func (t *wssListener) LANAddresses() []*url.URL {
+	return []*url.URL{t.uri}
+}
+
+func (t *wssListener) String() string {
+	return t.uri.String()
+}
+
+func (t *wssListener) Factory() listenerFactory {
+	return t.factory
+}
+
+func (t *wssListener) NATType() string {
+	return "unknown"
+}
+
+// tcpOptionsListener sets our usual TCP options on accepted connections.
+type tcpOptionsListener struct {
+	net.Listener
+}
+
+func (l tcpOptionsListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err == nil {
+		_ = dialer.SetTCPOptions(conn)
+	}
+	return conn, err
+}
+
+type wssListenerFactory struct{}
+
+func (f *wssListenerFactory) New(uri *url.URL, cfg config.Wrapper, tlsCfg *tls.Config, conns chan internalConn, natService *nat.Service) genericListener {
+	l := &wssListener{
+		uri:     fixupPort(uri, config.DefaultWSSPort),
+		cfg:     cfg,
+		tlsCfg:  tlsCfg,
+		conns:   conns,
+		factory: f,
+	}
+	l.ServiceWithError = util.AsServiceWithError(l.serve, l.String())
+	return l
+}
+
+func (wssListenerFactory) Valid(_ config.Configuration) error {
+	// Always valid
+	return nil
+}
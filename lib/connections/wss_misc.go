@@ -0,0 +1,138 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/syncthing/syncthing/lib/dialer"
+)
+
+// The WebSocket transport carries BEP, including its own TLS handshake,
+// inside binary WebSocket messages on an HTTPS connection. To anything in
+// between it looks like a browser talking to a web server. The outer TLS
+// layer is there only to look the part; it uses the device certificate
+// but isn't verified, as the inner TLS handshake authenticates the device.
+
+const wssPriority = 150
+
+// wssOuterTLSConfig returns the config for the outer TLS layer, based on
+// the one for BEP.
+func wssOuterTLSConfig(tlsCfg *tls.Config) *tls.Config {
+	cfg := tlsCfg.Clone()
+	cfg.NextProtos = []string{"http/1.1"}
+	cfg.ClientAuth = tls.NoClientCert
+	cfg.InsecureSkipVerify = true
+	return cfg
+}
+
+// wsConn is a WebSocket connection reporting the addresses of the
+// connection underneath, which tells when it's closed.
+type wsConn struct {
+	*websocket.Conn
+	local, remote net.Addr
+	closeOnce     sync.Once
+	closed        chan struct{}
+}
+
+func newWSConn(ws *websocket.Conn, local, remote net.Addr) *wsConn {
+	ws.PayloadType = websocket.BinaryFrame
+	return &wsConn{
+		Conn:   ws,
+		local:  local,
+		remote: remote,
+		closed: make(chan struct{}),
+	}
+}
+
+func (c *wsConn) LocalAddr() net.Addr {
+	return c.local
+}
+
+func (c *wsConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+func (c *wsConn) Close() error {
+	err := c.Conn.Close()
+	c.closeOnce.Do(func() { close(c.closed) })
+	return err
+}
+
+// dialThroughProxy connects to the given address, through the HTTP proxy
+// set in the environment for HTTPS connections, if any, using CONNECT. A
+// proxy set for the device takes precedence.
+func dialThroughProxy(ctx context.Context, address string) (net.Conn, error) {
+	if _, ok := dialer.ProxyFromContext(ctx); ok {
+		return dialer.DialContext(ctx, "tcp", address)
+	}
+	proxyURL, err := http.ProxyFromEnvironment(&http.Request{URL: &url.URL{Scheme: "https", Host: address}})
+	if err != nil {
+		return nil, err
+	}
+	if proxyURL == nil {
+		return dialer.DialContext(ctx, "tcp", address)
+	}
+
+	l.Debugln("Dial (BEP/wss): connecting to", address, "through proxy", proxyURL.Host)
+	conn, err := dialer.DialContext(ctx, "tcp", proxyURL.Host)
+	if err != nil {
+		return nil, err
+	}
+	if proxyURL.Scheme == "https" {
+		tc := tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname()})
+		if err := tlsTimedHandshake(tc); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tc
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: make(http.Header),
+	}
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		auth := base64.StdEncoding.EncodeToString([]byte(proxyURL.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+auth)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	// Nothing is sent after the response until we start talking, so it's
+	// safe to read it through a buffer we then throw away.
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy: %s", resp.Status)
+	}
+	return conn, nil
+}
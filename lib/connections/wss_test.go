@@ -0,0 +1,99 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/tlsutil"
+)
+
+func TestWSSDialListen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wss")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tlsCfg := func(name string) *tls.Config {
+		cert, err := tlsutil.NewCertificate(filepath.Join(dir, name+".pem"), filepath.Join(dir, name+"-key.pem"), "syncthing", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg := tlsutil.SecureDefault()
+		cfg.Certificates = []tls.Certificate{cert}
+		cfg.NextProtos = []string{"bep/1.0"}
+		cfg.ClientAuth = tls.RequestClientCert
+		cfg.InsecureSkipVerify = true
+		return cfg
+	}
+
+	// Find a free port to listen on.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	uri := &url.URL{Scheme: "wss", Host: ln.Addr().String(), Path: "/bep"}
+	ln.Close()
+
+	conns := make(chan internalConn, 1)
+	w := config.Wrap("/dev/null", config.New(protocol.LocalDeviceID), events.NoopLogger)
+	lst := (&wssListenerFactory{}).New(uri, w, tlsCfg("server"), conns, nil)
+	go lst.Serve()
+	defer lst.Stop()
+
+	d := (&wssDialerFactory{}).New(config.OptionsConfiguration{}, tlsCfg("client"))
+	var client internalConn
+	for i := 0; ; i++ {
+		// The listener may not have started yet.
+		client, err = d.Dial(context.Background(), protocol.LocalDeviceID, uri)
+		if err == nil {
+			break
+		}
+		if i == 50 {
+			t.Fatal(err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	defer client.Close()
+
+	var server internalConn
+	select {
+	case server = <-conns:
+	case <-time.After(10 * time.Second):
+		t.Fatal("no connection accepted")
+	}
+	defer server.Close()
+
+	if client.Type() != "wss-client" || server.Type() != "wss-server" {
+		t.Errorf("unexpected types %s and %s", client.Type(), server.Type())
+	}
+	if tr := server.Transport(); tr != "wss4" {
+		t.Errorf("unexpected transport %s", tr)
+	}
+	if len(server.ConnectionState().PeerCertificates) != 1 {
+		t.Error("expected the client certificate in the inner TLS handshake")
+	}
+
+	go client.Write([]byte("hello over websocket"))
+	buf := make([]byte, len("hello over websocket"))
+	if _, err := io.ReadFull(server, buf); err != nil || string(buf) != "hello over websocket" {
+		t.Errorf("read %q, %v", buf, err)
+	}
+}
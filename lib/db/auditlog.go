@@ -0,0 +1,125 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package db
+
+import (
+	"encoding/binary"
+
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+// An AuditLog records the changes to the files of a folder, each entry
+// getting the next ID. Only the latest entries are kept, up to a limit.
+type AuditLog struct {
+	db     *Lowlevel
+	folder []byte
+	mut    sync.Mutex
+	first  int64 // the ID of the oldest entry kept
+	next   int64 // the ID of the next entry
+}
+
+// NewAuditLog returns the audit log of the given folder, continuing the
+// entries in the database.
+func NewAuditLog(db *Lowlevel, folder string) (*AuditLog, error) {
+	a := &AuditLog{
+		db:     db,
+		folder: []byte(folder),
+		mut:    sync.NewMutex(),
+		first:  1,
+		next:   1,
+	}
+	it, err := db.NewPrefixIterator(auditLogPrefix(a.folder))
+	if err != nil {
+		return nil, err
+	}
+	defer it.Release()
+	for i := 0; it.Next(); i++ {
+		id := a.idFromKey(it.Key())
+		if i == 0 {
+			a.first = id
+		}
+		a.next = id + 1
+	}
+	return a, it.Error()
+}
+
+func auditLogPrefix(folder []byte) []byte {
+	prefix := make([]byte, 0, len(folder)+2)
+	prefix = append(prefix, KeyTypeAuditLog)
+	prefix = append(prefix, folder...)
+	return append(prefix, 0)
+}
+
+func (a *AuditLog) key(id int64) []byte {
+	key := auditLogPrefix(a.folder)
+	key = append(key, make([]byte, 8)...)
+	binary.BigEndian.PutUint64(key[len(key)-8:], uint64(id))
+	return key
+}
+
+func (a *AuditLog) idFromKey(key []byte) int64 {
+	return int64(binary.BigEndian.Uint64(key[len(key)-8:]))
+}
+
+// Append adds the entries to the log, setting their IDs, and removes the
+// oldest ones beyond the given number to keep.
+func (a *AuditLog) Append(entries []AuditEntry, keep int) error {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	t, err := a.db.newReadWriteTransaction()
+	if err != nil {
+		return err
+	}
+	defer t.close()
+
+	next := a.next
+	for i := range entries {
+		entries[i].ID = next
+		next++
+		if err := t.Put(a.key(entries[i].ID), mustMarshal(&entries[i])); err != nil {
+			return err
+		}
+	}
+	first := a.first
+	for ; first < next-int64(keep); first++ {
+		if err := t.Delete(a.key(first)); err != nil {
+			return err
+		}
+	}
+	if err := t.commit(); err != nil {
+		return err
+	}
+	a.first, a.next = first, next
+	return nil
+}
+
+// Entries returns the entries after the one with the given ID, oldest
+// first, at most limit of them unless it's zero.
+func (a *AuditLog) Entries(since int64, limit int) ([]AuditEntry, error) {
+	if since < 0 {
+		since = 0
+	}
+	it, err := a.db.NewRangeIterator(a.key(since+1), a.key(maxInt64))
+	if err != nil {
+		return nil, err
+	}
+	defer it.Release()
+	var entries []AuditEntry
+	for (limit == 0 || len(entries) < limit) && it.Next() {
+		var entry AuditEntry
+		if err := entry.Unmarshal(it.Value()); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, it.Error()
+}
+
+func (db *Lowlevel) dropAuditLog(folder []byte) error {
+	return db.dropPrefix(auditLogPrefix(folder))
+}
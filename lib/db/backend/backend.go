@@ -7,6 +7,7 @@
 package backend
 
 import (
+	"fmt"
 	"sync"
 )
 
@@ -102,6 +103,12 @@ type Backend interface {
 	Close() error
 }
 
+// A Compacter is a Backend that can be told to reclaim the space of deleted
+// and overwritten entries, which it otherwise does in its own time.
+type Compacter interface {
+	Compact() error
+}
+
 type Tuning int
 
 const (
@@ -111,10 +118,49 @@ const (
 	TuningLarge
 )
 
+// Type is the kind of database implementing the Backend.
+type Type int
+
+const (
+	// N.b. these constants must match those in lib/config.Backend!
+	TypeLevelDB Type = iota
+	TypeBadger
+	TypeSQLite
+)
+
+func (t Type) String() string {
+	switch t {
+	case TypeLevelDB:
+		return "leveldb"
+	case TypeBadger:
+		return "badger"
+	case TypeSQLite:
+		return "sqlite"
+	default:
+		return "unknown"
+	}
+}
+
 func Open(path string, tuning Tuning) (Backend, error) {
 	return OpenLevelDB(path, tuning)
 }
 
+// OpenType opens the database of the given type at the given location,
+// which is the file or directory itself rather than the base path taken by
+// OpenMigrating.
+func OpenType(location string, typ Type, tuning Tuning) (Backend, error) {
+	switch typ {
+	case TypeLevelDB:
+		return OpenLevelDB(location, tuning)
+	case TypeBadger:
+		return OpenBadger(location, tuning)
+	case TypeSQLite:
+		return OpenSQLite(location, tuning)
+	default:
+		return nil, fmt.Errorf("unknown database backend %d", typ)
+	}
+}
+
 func OpenMemory() Backend {
 	return OpenLevelDBMemory()
 }
@@ -168,3 +214,44 @@ func (r releaser) Release() {
 		r.wg.Done()
 	})
 }
+
+// snapshotRefs delays ending a snapshot until the iterators made from it
+// are released, as transactions may be released or committed before their
+// iterators.
+type snapshotRefs struct {
+	mut      sync.Mutex
+	iters    int
+	released bool
+	end      func()
+}
+
+func newSnapshotRefs(end func()) *snapshotRefs {
+	return &snapshotRefs{end: end}
+}
+
+func (r *snapshotRefs) addIterator() {
+	r.mut.Lock()
+	r.iters++
+	r.mut.Unlock()
+}
+
+func (r *snapshotRefs) releaseIterator() {
+	r.mut.Lock()
+	r.iters--
+	r.maybeEndLocked()
+	r.mut.Unlock()
+}
+
+func (r *snapshotRefs) release() {
+	r.mut.Lock()
+	r.released = true
+	r.maybeEndLocked()
+	r.mut.Unlock()
+}
+
+func (r *snapshotRefs) maybeEndLocked() {
+	if r.released && r.iters == 0 && r.end != nil {
+		r.end()
+		r.end = nil
+	}
+}
@@ -6,7 +6,10 @@
 
 package backend
 
-import "testing"
+import (
+	"bytes"
+	"testing"
+)
 
 // testBackendBehavior is the generic test suite that must be fulfilled by
 // every backend implementation. It should be called by each implementation
@@ -14,6 +17,9 @@ import "testing"
 func testBackendBehavior(t *testing.T, open func() Backend) {
 	t.Run("WriteIsolation", func(t *testing.T) { testWriteIsolation(t, open) })
 	t.Run("DeleteNonexisten", func(t *testing.T) { testDeleteNonexistent(t, open) })
+	t.Run("Iterators", func(t *testing.T) { testIterators(t, open) })
+	t.Run("NotFound", func(t *testing.T) { testNotFound(t, open) })
+	t.Run("Compact", func(t *testing.T) { testCompact(t, open) })
 }
 
 func testWriteIsolation(t *testing.T, open func() Backend) {
@@ -51,3 +57,114 @@ func testDeleteNonexistent(t *testing.T, open func() Backend) {
 		t.Error(err)
 	}
 }
+
+func testIterators(t *testing.T, open func() Backend) {
+	// Prefix and range iterators return the keys in order, with their
+	// values, both on the database and in transactions.
+
+	db := open()
+	defer db.Close()
+
+	for _, k := range []string{"b", "ab", "a", "c", "aa", "b\xff"} {
+		if err := db.Put([]byte(k), []byte("v"+k)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	check := func(r Reader, name string) {
+		t.Helper()
+		collect := func(it Iterator, err error) []string {
+			t.Helper()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer it.Release()
+			var keys []string
+			for it.Next() {
+				if !bytes.Equal(it.Value(), append([]byte("v"), it.Key()...)) {
+					t.Errorf("%s: wrong value %q for key %q", name, it.Value(), it.Key())
+				}
+				keys = append(keys, string(it.Key()))
+			}
+			if err := it.Error(); err != nil {
+				t.Fatal(err)
+			}
+			return keys
+		}
+		expect := func(keys []string, expected ...string) {
+			t.Helper()
+			if len(keys) != len(expected) {
+				t.Errorf("%s: got keys %q, expected %q", name, keys, expected)
+				return
+			}
+			for i := range keys {
+				if keys[i] != expected[i] {
+					t.Errorf("%s: got keys %q, expected %q", name, keys, expected)
+					return
+				}
+			}
+		}
+		expect(collect(r.NewPrefixIterator([]byte("a"))), "a", "aa", "ab")
+		expect(collect(r.NewPrefixIterator([]byte("b"))), "b", "b\xff")
+		expect(collect(r.NewPrefixIterator(nil)), "a", "aa", "ab", "b", "b\xff", "c")
+		expect(collect(r.NewRangeIterator([]byte("aa"), []byte("b"))), "aa", "ab")
+		expect(collect(r.NewRangeIterator([]byte("b"), nil)), "b", "b\xff", "c")
+	}
+
+	check(db, "db")
+	rt, err := db.NewReadTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(rt, "read transaction")
+	rt.Release()
+	wt, err := db.NewWriteTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(wt, "write transaction")
+	wt.Release()
+}
+
+func testNotFound(t *testing.T, open func() Backend) {
+	// Getting a non-existent key gives an error recognized by IsNotFound
+
+	db := open()
+	defer db.Close()
+
+	if _, err := db.Get([]byte("a")); !IsNotFound(err) {
+		t.Error("expected not found, got", err)
+	}
+	tx, err := db.NewReadTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Release()
+	if _, err := tx.Get([]byte("a")); !IsNotFound(err) {
+		t.Error("expected not found in transaction, got", err)
+	}
+}
+
+func testCompact(t *testing.T, open func() Backend) {
+	// Compacting keeps what's there and drops what isn't
+
+	db := open()
+	defer db.Close()
+
+	c, ok := db.(Compacter)
+	if !ok {
+		t.Skip("backend can't compact")
+	}
+	_ = db.Put([]byte("a"), []byte("a"))
+	_ = db.Put([]byte("b"), []byte("b"))
+	_ = db.Delete([]byte("b"))
+	if err := c.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := db.Get([]byte("a")); err != nil || string(v) != "a" {
+		t.Errorf("got %q, %v after compaction", v, err)
+	}
+	if _, err := db.Get([]byte("b")); !IsNotFound(err) {
+		t.Error("expected deleted key to be gone, got", err)
+	}
+}
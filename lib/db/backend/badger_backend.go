@@ -0,0 +1,396 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package backend
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v2"
+
+	"github.com/syncthing/syncthing/lib/chaos"
+)
+
+const (
+	// How often to try to reclaim space in the value log, and the share of
+	// a value log file that must be garbage for it to be rewritten.
+	badgerGCInterval     = 10 * time.Minute
+	badgerGCDiscardRatio = 0.5
+)
+
+// OpenBadger opens the Badger database in the given directory, creating it
+// if necessary.
+func OpenBadger(location string, tuning Tuning) (Backend, error) {
+	opts := badger.DefaultOptions(location).WithLogger(badgerLogger{})
+	if tuning == TuningSmall {
+		opts = opts.WithMaxTableSize(16 << MiB).WithValueLogFileSize(64 << MiB).WithNumMemtables(2)
+	}
+	return openBadger(opts)
+}
+
+// OpenBadgerMemory returns a new Backend referencing an in-memory Badger
+// database.
+func OpenBadgerMemory() Backend {
+	opts := badger.DefaultOptions("").WithInMemory(true).WithLogger(badgerLogger{})
+	// Memory is allocated up front for the memtables and caches, which
+	// need not be large here.
+	opts = opts.WithMaxTableSize(4 << MiB).WithMaxCacheSize(8 << MiB)
+	b, err := openBadger(opts)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func openBadger(opts badger.Options) (*badgerBackend, error) {
+	bdb, err := badger.Open(opts)
+	if err != nil {
+		return nil, err
+	}
+	b := &badgerBackend{
+		bdb:  bdb,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	if opts.InMemory {
+		close(b.done)
+	} else {
+		go b.gcRoutine()
+	}
+	return b, nil
+}
+
+// badgerBackend implements Backend on top of a Badger database
+type badgerBackend struct {
+	bdb       *badger.DB
+	closeWG   sync.WaitGroup
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (b *badgerBackend) NewReadTransaction() (ReadTransaction, error) {
+	return b.newSnapshot(), nil
+}
+
+func (b *badgerBackend) newSnapshot() badgerSnapshot {
+	txn := b.bdb.NewTransaction(false)
+	return badgerSnapshot{
+		txn:  txn,
+		refs: newSnapshotRefs(txn.Discard),
+		rel:  newReleaser(&b.closeWG),
+	}
+}
+
+func (b *badgerBackend) NewWriteTransaction() (WriteTransaction, error) {
+	// Reads go to a snapshot taken now, not seeing the writes of the
+	// transaction, as with the other backends.
+	return &badgerTransaction{
+		badgerSnapshot: b.newSnapshot(),
+		bdb:            b.bdb,
+		rel:            newReleaser(&b.closeWG),
+	}, nil
+}
+
+func (b *badgerBackend) Close() error {
+	b.closeOnce.Do(func() {
+		close(b.stop)
+	})
+	<-b.done
+	b.closeWG.Wait()
+	return b.bdb.Close()
+}
+
+func (b *badgerBackend) Get(key []byte) ([]byte, error) {
+	snap := b.newSnapshot()
+	defer snap.Release()
+	return snap.Get(key)
+}
+
+func (b *badgerBackend) NewPrefixIterator(prefix []byte) (Iterator, error) {
+	snap := b.newSnapshot()
+	defer snap.Release()
+	return snap.newPrefixIterator(prefix), nil
+}
+
+func (b *badgerBackend) NewRangeIterator(first, last []byte) (Iterator, error) {
+	snap := b.newSnapshot()
+	defer snap.Release()
+	return snap.newRangeIterator(first, last), nil
+}
+
+func (b *badgerBackend) Put(key, val []byte) error {
+	return wrapBadgerErr(b.bdb.Update(func(txn *badger.Txn) error {
+		return txn.Set(key, val)
+	}))
+}
+
+func (b *badgerBackend) Delete(key []byte) error {
+	return wrapBadgerErr(b.bdb.Update(func(txn *badger.Txn) error {
+		return txn.Delete(key)
+	}))
+}
+
+// Compact merges the levels of the tree and rewrites the value log files
+// that are mostly garbage.
+func (b *badgerBackend) Compact() error {
+	if err := b.bdb.Flatten(1); err != nil {
+		return wrapBadgerErr(err)
+	}
+	return b.runValueLogGC()
+}
+
+// runValueLogGC rewrites value log files as long as there are some with
+// enough garbage. Each run rewrites at most one.
+func (b *badgerBackend) runValueLogGC() error {
+	for {
+		select {
+		case <-b.stop:
+			return nil
+		default:
+		}
+		if err := b.bdb.RunValueLogGC(badgerGCDiscardRatio); err == badger.ErrNoRewrite || err == badger.ErrRejected || err == badger.ErrGCInMemoryMode {
+			return nil
+		} else if err != nil {
+			return wrapBadgerErr(err)
+		}
+	}
+}
+
+// gcRoutine reclaims the space of deleted and overwritten values, which
+// Badger doesn't do by itself.
+func (b *badgerBackend) gcRoutine() {
+	defer close(b.done)
+	t := time.NewTicker(badgerGCInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-t.C:
+		}
+		if err := b.runValueLogGC(); err != nil {
+			l.Debugln("badger value log gc:", err)
+		}
+	}
+}
+
+// badgerSnapshot implements backend.ReadTransaction
+type badgerSnapshot struct {
+	txn  *badger.Txn
+	refs *snapshotRefs
+	rel  *releaser
+}
+
+func (s badgerSnapshot) Get(key []byte) ([]byte, error) {
+	item, err := s.txn.Get(key)
+	if err != nil {
+		return nil, wrapBadgerErr(err)
+	}
+	val, err := item.ValueCopy(nil)
+	return val, wrapBadgerErr(err)
+}
+
+func (s badgerSnapshot) NewPrefixIterator(prefix []byte) (Iterator, error) {
+	return s.newPrefixIterator(prefix), nil
+}
+
+func (s badgerSnapshot) NewRangeIterator(first, last []byte) (Iterator, error) {
+	return s.newRangeIterator(first, last), nil
+}
+
+func (s badgerSnapshot) newPrefixIterator(prefix []byte) *badgerIterator {
+	opts := badger.DefaultIteratorOptions
+	opts.Prefix = prefix
+	s.refs.addIterator()
+	return &badgerIterator{
+		it:    s.txn.NewIterator(opts),
+		first: prefix,
+		refs:  s.refs,
+	}
+}
+
+func (s badgerSnapshot) newRangeIterator(first, last []byte) *badgerIterator {
+	s.refs.addIterator()
+	return &badgerIterator{
+		it:    s.txn.NewIterator(badger.DefaultIteratorOptions),
+		first: first,
+		last:  last,
+		refs:  s.refs,
+	}
+}
+
+func (s badgerSnapshot) Release() {
+	s.refs.release()
+	s.rel.Release()
+}
+
+// badgerTransaction implements backend.WriteTransaction. Like the leveldb
+// batch, the changes are kept in memory and written when committing or
+// when they grow large.
+type badgerTransaction struct {
+	badgerSnapshot
+	bdb  *badger.DB
+	ops  []badgerOp
+	size int
+	rel  *releaser
+}
+
+type badgerOp struct {
+	key, val []byte
+	delete   bool
+}
+
+func (t *badgerTransaction) Put(key, val []byte) error {
+	op := badgerOp{key: append([]byte(nil), key...), val: append([]byte{}, val...)}
+	t.ops = append(t.ops, op)
+	t.size += len(op.key) + len(op.val)
+	return t.checkFlush(dbFlushBatchMax)
+}
+
+func (t *badgerTransaction) Delete(key []byte) error {
+	t.ops = append(t.ops, badgerOp{key: append([]byte(nil), key...), delete: true})
+	t.size += len(key)
+	return t.checkFlush(dbFlushBatchMax)
+}
+
+func (t *badgerTransaction) Checkpoint() error {
+	return t.checkFlush(dbFlushBatchMin)
+}
+
+func (t *badgerTransaction) Commit() error {
+	chaos.DelayDBCommit()
+	err := t.flush()
+	t.badgerSnapshot.Release()
+	t.rel.Release()
+	return err
+}
+
+func (t *badgerTransaction) Release() {
+	t.badgerSnapshot.Release()
+	t.rel.Release()
+}
+
+// checkFlush flushes and resets the batch if its size exceeds the given size.
+func (t *badgerTransaction) checkFlush(size int) error {
+	if t.size < size {
+		return nil
+	}
+	return t.flush()
+}
+
+// flush writes the changes in as few Badger transactions as they fit in.
+func (t *badgerTransaction) flush() error {
+	txn := t.bdb.NewTransaction(true)
+	defer func() {
+		txn.Discard()
+	}()
+	for _, op := range t.ops {
+		apply := func() error {
+			if op.delete {
+				return txn.Delete(op.key)
+			}
+			return txn.Set(op.key, op.val)
+		}
+		err := apply()
+		if err == badger.ErrTxnTooBig {
+			if err := txn.Commit(); err != nil {
+				return wrapBadgerErr(err)
+			}
+			txn = t.bdb.NewTransaction(true)
+			err = apply()
+		}
+		if err != nil {
+			return wrapBadgerErr(err)
+		}
+	}
+	if err := txn.Commit(); err != nil {
+		return wrapBadgerErr(err)
+	}
+	t.ops = t.ops[:0]
+	t.size = 0
+	return nil
+}
+
+// badgerIterator implements backend.Iterator over the keys with a prefix,
+// or from first up to but not including last.
+type badgerIterator struct {
+	it      *badger.Iterator
+	first   []byte
+	last    []byte
+	started bool
+	key     []byte
+	val     []byte
+	err     error
+	refs    *snapshotRefs
+	once    sync.Once
+}
+
+func (i *badgerIterator) Next() bool {
+	if i.err != nil {
+		return false
+	}
+	if !i.started {
+		i.it.Seek(i.first)
+		i.started = true
+	} else {
+		i.it.Next()
+	}
+	if !i.it.Valid() {
+		return false
+	}
+	item := i.it.Item()
+	if i.last != nil && bytes.Compare(item.Key(), i.last) >= 0 {
+		return false
+	}
+	i.key = item.KeyCopy(i.key[:0])
+	i.val, i.err = item.ValueCopy(i.val[:0])
+	return i.err == nil
+}
+
+func (i *badgerIterator) Key() []byte {
+	return i.key
+}
+
+func (i *badgerIterator) Value() []byte {
+	return i.val
+}
+
+func (i *badgerIterator) Error() error {
+	return wrapBadgerErr(i.err)
+}
+
+func (i *badgerIterator) Release() {
+	i.once.Do(func() {
+		i.it.Close()
+		i.refs.releaseIterator()
+	})
+}
+
+// wrapBadgerErr wraps errors so that the backend package can recognize them
+func wrapBadgerErr(err error) error {
+	if err == nil {
+		return nil
+	}
+	if err == badger.ErrBlockedWrites {
+		return errClosed{}
+	}
+	if err == badger.ErrKeyNotFound {
+		return errNotFound{}
+	}
+	return err
+}
+
+// badgerLogger passes what Badger has to say on to our logger, as debug
+// output unless it's a problem.
+type badgerLogger struct{}
+
+func (badgerLogger) Errorf(format string, args ...interface{})   { l.Warnf("Badger: "+format, args...) }
+func (badgerLogger) Warningf(format string, args ...interface{}) { l.Infof("Badger: "+format, args...) }
+func (badgerLogger) Infof(format string, args ...interface{})    { l.Debugf("Badger: "+format, args...) }
+func (badgerLogger) Debugf(format string, args ...interface{})   { l.Debugf("Badger: "+format, args...) }
@@ -9,6 +9,7 @@ package backend
 import (
 	"sync"
 
+	"github.com/syncthing/syncthing/lib/chaos"
 	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/syndtr/goleveldb/leveldb/util"
 )
@@ -59,6 +60,11 @@ func (b *leveldbBackend) Close() error {
 	return wrapLeveldbErr(b.ldb.Close())
 }
 
+// Compact compacts the whole database.
+func (b *leveldbBackend) Compact() error {
+	return wrapLeveldbErr(b.ldb.CompactRange(util.Range{}))
+}
+
 func (b *leveldbBackend) Get(key []byte) ([]byte, error) {
 	val, err := b.ldb.Get(key, nil)
 	return val, wrapLeveldbErr(err)
@@ -128,6 +134,7 @@ func (t *leveldbTransaction) Checkpoint() error {
 }
 
 func (t *leveldbTransaction) Commit() error {
+	chaos.DelayDBCommit()
 	err := wrapLeveldbErr(t.flush())
 	t.leveldbSnapshot.Release()
 	t.rel.Release()
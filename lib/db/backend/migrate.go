@@ -0,0 +1,147 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package backend
+
+import (
+	"os"
+	"time"
+)
+
+var allTypes = []Type{TypeLevelDB, TypeBadger, TypeSQLite}
+
+// Location returns where the database of the given type based at path is
+// kept. The leveldb database is at path itself, the others next to it.
+func Location(path string, typ Type) string {
+	switch typ {
+	case TypeBadger:
+		return path + ".badger"
+	case TypeSQLite:
+		return path + ".sqlite"
+	default:
+		return path
+	}
+}
+
+// Locations returns where databases based at path are kept, for all types.
+func Locations(path string) []string {
+	locs := make([]string, 0, len(allTypes))
+	for _, typ := range allTypes {
+		locs = append(locs, Location(path, typ))
+	}
+	return locs
+}
+
+// OpenMigrating opens the database of the given type based at path. If
+// there is none, but there is one of another type, its contents are
+// copied over first and the old database is removed.
+func OpenMigrating(path string, typ Type, tuning Tuning) (Backend, error) {
+	loc := Location(path, typ)
+	if exists(loc) {
+		return OpenType(loc, typ, tuning)
+	}
+	for _, from := range allTypes {
+		if from == typ || !exists(Location(path, from)) {
+			continue
+		}
+		if err := migrate(path, from, typ, tuning); err != nil {
+			return nil, err
+		}
+		break
+	}
+	return OpenType(loc, typ, tuning)
+}
+
+// migrate copies the database of type from to one of type to. The copy is
+// made under a temporary name, so that an interrupted migration is started
+// over rather than leaving an incomplete database.
+func migrate(path string, from, to Type, tuning Tuning) error {
+	l.Infof("Migrating database from %v to %v, this may take a while", from, to)
+	t0 := time.Now()
+
+	fromLoc, toLoc := Location(path, from), Location(path, to)
+	tmpLoc := toLoc + ".migrating"
+	removeDatabase(tmpLoc)
+
+	src, err := OpenType(fromLoc, from, tuning)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := OpenType(tmpLoc, to, tuning)
+	if err != nil {
+		return err
+	}
+	n, err := Copy(dst, src)
+	if err != nil {
+		dst.Close()
+		removeDatabase(tmpLoc)
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpLoc, toLoc); err != nil {
+		return err
+	}
+	src.Close()
+	removeDatabase(fromLoc)
+
+	l.Infof("Migrated %d database entries from %v to %v in %v", n, from, to, time.Since(t0).Truncate(time.Millisecond))
+	return nil
+}
+
+// Copy copies all the keys and values of src to dst, returning how many
+// there were.
+func Copy(dst, src Backend) (int, error) {
+	rt, err := src.NewReadTransaction()
+	if err != nil {
+		return 0, err
+	}
+	defer rt.Release()
+	it, err := rt.NewPrefixIterator(nil)
+	if err != nil {
+		return 0, err
+	}
+	defer it.Release()
+	wt, err := dst.NewWriteTransaction()
+	if err != nil {
+		return 0, err
+	}
+	defer wt.Release()
+
+	n := 0
+	for it.Next() {
+		if err := wt.Put(it.Key(), it.Value()); err != nil {
+			return n, err
+		}
+		n++
+		if n%1000 == 0 {
+			if err := wt.Checkpoint(); err != nil {
+				return n, err
+			}
+		}
+	}
+	if err := it.Error(); err != nil {
+		return n, err
+	}
+	return n, wt.Commit()
+}
+
+func exists(loc string) bool {
+	_, err := os.Stat(loc)
+	return err == nil
+}
+
+// removeDatabase removes the database at loc, including the files SQLite
+// keeps next to it.
+func removeDatabase(loc string) {
+	for _, name := range []string{loc, loc + "-wal", loc + "-shm"} {
+		if err := os.RemoveAll(name); err != nil {
+			l.Warnln("Removing old database:", err)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package backend
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenMigrating(t *testing.T) {
+	dir, err := ioutil.TempDir("", "migrate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "index")
+
+	const entries = 2500
+
+	db, err := OpenMigrating(path, TypeLevelDB, TuningAuto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < entries; i++ {
+		if err := db.Put([]byte(fmt.Sprintf("key%05d", i)), []byte(fmt.Sprint(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Close()
+
+	for _, typ := range []Type{TypeBadger, TypeSQLite, TypeLevelDB} {
+		db, err := OpenMigrating(path, typ, TuningAuto)
+		if err != nil {
+			if typ == TypeSQLite {
+				t.Log("sqlite unavailable:", err)
+				continue
+			}
+			t.Fatal(err)
+		}
+		for _, i := range []int{0, 1234, entries - 1} {
+			if v, err := db.Get([]byte(fmt.Sprintf("key%05d", i))); err != nil {
+				t.Errorf("%v: %v", typ, err)
+			} else if string(v) != fmt.Sprint(i) {
+				t.Errorf("%v: got %q for entry %d", typ, v, i)
+			}
+		}
+		db.Close()
+
+		for _, other := range allTypes {
+			if exists(Location(path, other)) != (other == typ) {
+				t.Errorf("after migrating to %v, database of type %v exists: %v", typ, other, !(other == typ))
+			}
+		}
+		if exists(Location(path, typ) + ".migrating") {
+			t.Errorf("temporary database for %v left behind", typ)
+		}
+	}
+}
@@ -0,0 +1,357 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// +build cgo
+
+package backend
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"sync"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/syncthing/syncthing/lib/chaos"
+)
+
+const (
+	// Never flush transactions smaller than this, even on Checkpoint()
+	sqliteFlushBatchMin = 1 << MiB
+	// Once a transaction reaches this size, flush it unconditionally.
+	sqliteFlushBatchMax = 128 << MiB
+)
+
+const (
+	sqliteCreate = `CREATE TABLE IF NOT EXISTS kv (key BLOB NOT NULL PRIMARY KEY, value BLOB NOT NULL) WITHOUT ROWID`
+	sqliteGet    = `SELECT value FROM kv WHERE key = ?`
+	sqlitePut    = `INSERT OR REPLACE INTO kv (key, value) VALUES (?, ?)`
+	sqliteDelete = `DELETE FROM kv WHERE key = ?`
+)
+
+// OpenSQLite opens the SQLite database in the given file, creating it if
+// necessary. The keys and values are kept in a single table, with the
+// write ahead log letting reads go on while writing.
+func OpenSQLite(location string, tuning Tuning) (Backend, error) {
+	cacheKiB := 16 << 10
+	if tuning == TuningSmall {
+		cacheKiB = 2 << 10
+	}
+	dsn := fmt.Sprintf("file:%s?_journal_mode=WAL&_synchronous=NORMAL&_busy_timeout=60000&_cache_size=-%d", (&url.URL{Path: location}).EscapedPath(), cacheKiB)
+	sdb, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := sdb.Exec(sqliteCreate); err != nil {
+		sdb.Close()
+		return nil, err
+	}
+	return &sqliteBackend{sdb: sdb}, nil
+}
+
+// sqliteBackend implements Backend on top of an SQLite database
+type sqliteBackend struct {
+	sdb     *sql.DB
+	closeWG sync.WaitGroup
+}
+
+func (b *sqliteBackend) NewReadTransaction() (ReadTransaction, error) {
+	return b.newSnapshot()
+}
+
+func (b *sqliteBackend) newSnapshot() (sqliteSnapshot, error) {
+	tx, err := b.sdb.Begin()
+	if err != nil {
+		return sqliteSnapshot{}, wrapSQLiteErr(err)
+	}
+	return sqliteSnapshot{
+		q: tx,
+		refs: newSnapshotRefs(func() {
+			// Only read from, so there is nothing to commit.
+			_ = tx.Rollback()
+		}),
+		rel: newReleaser(&b.closeWG),
+	}, nil
+}
+
+func (b *sqliteBackend) NewWriteTransaction() (WriteTransaction, error) {
+	snap, err := b.newSnapshot()
+	if err != nil {
+		return nil, err // already wrapped
+	}
+	return &sqliteTransaction{
+		sqliteSnapshot: snap,
+		sdb:            b.sdb,
+		rel:            newReleaser(&b.closeWG),
+	}, nil
+}
+
+func (b *sqliteBackend) Close() error {
+	b.closeWG.Wait()
+	return wrapSQLiteErr(b.sdb.Close())
+}
+
+// Compact moves the write ahead log into the database and rebuilds it
+// without the free pages.
+func (b *sqliteBackend) Compact() error {
+	if _, err := b.sdb.Exec(`PRAGMA wal_checkpoint(TRUNCATE)`); err != nil {
+		return wrapSQLiteErr(err)
+	}
+	_, err := b.sdb.Exec(`VACUUM`)
+	return wrapSQLiteErr(err)
+}
+
+func (b *sqliteBackend) Get(key []byte) ([]byte, error) {
+	return sqliteGetFrom(b.sdb, key)
+}
+
+func (b *sqliteBackend) NewPrefixIterator(prefix []byte) (Iterator, error) {
+	return newSQLiteIterator(b.sdb, nil, prefix, prefixLimit(prefix))
+}
+
+func (b *sqliteBackend) NewRangeIterator(first, last []byte) (Iterator, error) {
+	return newSQLiteIterator(b.sdb, nil, first, last)
+}
+
+func (b *sqliteBackend) Put(key, val []byte) error {
+	_, err := b.sdb.Exec(sqlitePut, key, nonNil(val))
+	return wrapSQLiteErr(err)
+}
+
+func (b *sqliteBackend) Delete(key []byte) error {
+	_, err := b.sdb.Exec(sqliteDelete, key)
+	return wrapSQLiteErr(err)
+}
+
+// sqliteQueryer is what's common to the database and its transactions.
+type sqliteQueryer interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// sqliteSnapshot implements backend.ReadTransaction. In WAL mode a
+// transaction sees the database as of its first read.
+type sqliteSnapshot struct {
+	q    sqliteQueryer
+	refs *snapshotRefs
+	rel  *releaser
+}
+
+func (s sqliteSnapshot) Get(key []byte) ([]byte, error) {
+	return sqliteGetFrom(s.q, key)
+}
+
+func (s sqliteSnapshot) NewPrefixIterator(prefix []byte) (Iterator, error) {
+	return newSQLiteIterator(s.q, s.refs, prefix, prefixLimit(prefix))
+}
+
+func (s sqliteSnapshot) NewRangeIterator(first, last []byte) (Iterator, error) {
+	return newSQLiteIterator(s.q, s.refs, first, last)
+}
+
+func (s sqliteSnapshot) Release() {
+	s.refs.release()
+	s.rel.Release()
+}
+
+type sqliteOp struct {
+	key, val []byte
+	delete   bool
+}
+
+// sqliteTransaction implements backend.WriteTransaction. Like the leveldb
+// batch, the changes are kept in memory and written when committing or
+// when they grow large.
+type sqliteTransaction struct {
+	sqliteSnapshot
+	sdb  *sql.DB
+	ops  []sqliteOp
+	size int
+	rel  *releaser
+}
+
+func (t *sqliteTransaction) Put(key, val []byte) error {
+	op := sqliteOp{key: append([]byte(nil), key...), val: append([]byte{}, val...)}
+	t.ops = append(t.ops, op)
+	t.size += len(op.key) + len(op.val)
+	return t.checkFlush(sqliteFlushBatchMax)
+}
+
+func (t *sqliteTransaction) Delete(key []byte) error {
+	t.ops = append(t.ops, sqliteOp{key: append([]byte(nil), key...), delete: true})
+	t.size += len(key)
+	return t.checkFlush(sqliteFlushBatchMax)
+}
+
+func (t *sqliteTransaction) Checkpoint() error {
+	return t.checkFlush(sqliteFlushBatchMin)
+}
+
+func (t *sqliteTransaction) Commit() error {
+	chaos.DelayDBCommit()
+	// The snapshot is let go of first, for the write not to wait on it.
+	t.sqliteSnapshot.Release()
+	err := t.flush()
+	t.rel.Release()
+	return err
+}
+
+func (t *sqliteTransaction) Release() {
+	t.sqliteSnapshot.Release()
+	t.rel.Release()
+}
+
+// checkFlush flushes and resets the batch if its size exceeds the given size.
+func (t *sqliteTransaction) checkFlush(size int) error {
+	if t.size < size {
+		return nil
+	}
+	return t.flush()
+}
+
+func (t *sqliteTransaction) flush() error {
+	if len(t.ops) == 0 {
+		return nil
+	}
+	tx, err := t.sdb.Begin()
+	if err != nil {
+		return wrapSQLiteErr(err)
+	}
+	defer tx.Rollback()
+	put, err := tx.Prepare(sqlitePut)
+	if err != nil {
+		return wrapSQLiteErr(err)
+	}
+	defer put.Close()
+	del, err := tx.Prepare(sqliteDelete)
+	if err != nil {
+		return wrapSQLiteErr(err)
+	}
+	defer del.Close()
+	for _, op := range t.ops {
+		if op.delete {
+			_, err = del.Exec(op.key)
+		} else {
+			_, err = put.Exec(op.key, op.val)
+		}
+		if err != nil {
+			return wrapSQLiteErr(err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return wrapSQLiteErr(err)
+	}
+	t.ops = t.ops[:0]
+	t.size = 0
+	return nil
+}
+
+// sqliteIterator implements backend.Iterator on query results.
+type sqliteIterator struct {
+	rows *sql.Rows
+	key  []byte
+	val  []byte
+	err  error
+	refs *snapshotRefs // of the snapshot iterated over, if any
+	once sync.Once
+}
+
+// newSQLiteIterator iterates over the keys from first up to but not
+// including last, where nil means no limit.
+func newSQLiteIterator(q sqliteQueryer, refs *snapshotRefs, first, last []byte) (*sqliteIterator, error) {
+	query := `SELECT key, value FROM kv WHERE key >= ?`
+	args := []interface{}{nonNil(first)}
+	if last != nil {
+		query += ` AND key < ?`
+		args = append(args, last)
+	}
+	rows, err := q.Query(query+` ORDER BY key`, args...)
+	if err != nil {
+		return nil, wrapSQLiteErr(err)
+	}
+	if refs != nil {
+		refs.addIterator()
+	}
+	return &sqliteIterator{rows: rows, refs: refs}, nil
+}
+
+func (i *sqliteIterator) Next() bool {
+	if i.err != nil || !i.rows.Next() {
+		return false
+	}
+	i.err = i.rows.Scan(&i.key, &i.val)
+	return i.err == nil
+}
+
+func (i *sqliteIterator) Key() []byte {
+	return i.key
+}
+
+func (i *sqliteIterator) Value() []byte {
+	return i.val
+}
+
+func (i *sqliteIterator) Error() error {
+	if i.err != nil {
+		return wrapSQLiteErr(i.err)
+	}
+	return wrapSQLiteErr(i.rows.Err())
+}
+
+func (i *sqliteIterator) Release() {
+	i.once.Do(func() {
+		i.rows.Close()
+		if i.refs != nil {
+			i.refs.releaseIterator()
+		}
+	})
+}
+
+func sqliteGetFrom(q sqliteQueryer, key []byte) ([]byte, error) {
+	var val []byte
+	if err := q.QueryRow(sqliteGet, key).Scan(&val); err != nil {
+		return nil, wrapSQLiteErr(err)
+	}
+	return val, nil
+}
+
+// prefixLimit returns the first key after all those with the given prefix,
+// or nil if there is none.
+func prefixLimit(prefix []byte) []byte {
+	for i := len(prefix) - 1; i >= 0; i-- {
+		if prefix[i] < 0xff {
+			limit := make([]byte, i+1)
+			copy(limit, prefix)
+			limit[i]++
+			return limit
+		}
+	}
+	return nil
+}
+
+// nonNil makes sure empty keys and values are stored as blobs, not NULL.
+func nonNil(bs []byte) []byte {
+	if bs == nil {
+		return []byte{}
+	}
+	return bs
+}
+
+// wrapSQLiteErr wraps errors so that the backend package can recognize them
+func wrapSQLiteErr(err error) error {
+	if err == nil {
+		return nil
+	}
+	if err == sql.ErrNoRows {
+		return errNotFound{}
+	}
+	// database/sql doesn't export the error for using a closed database.
+	if err.Error() == "sql: database is closed" {
+		return errClosed{}
+	}
+	return err
+}
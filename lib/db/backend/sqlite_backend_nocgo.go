@@ -0,0 +1,18 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// +build !cgo
+
+package backend
+
+import "errors"
+
+// The SQLite driver needs cgo, so builds without it have no SQLite backend.
+var errSQLiteUnsupported = errors.New("sqlite database backend not supported in builds without cgo")
+
+func OpenSQLite(location string, tuning Tuning) (Backend, error) {
+	return nil, errSQLiteUnsupported
+}
@@ -0,0 +1,35 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// +build cgo
+
+package backend
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSQLiteBackendBehavior(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	n := 0
+	testBackendBehavior(t, func() Backend {
+		n++
+		db, err := OpenSQLite(filepath.Join(dir, fmt.Sprintf("db%d", n)), TuningAuto)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return db
+	})
+}
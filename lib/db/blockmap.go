@@ -39,6 +39,26 @@ func (f *BlockFinder) String() string {
 // reason. The iterator finally returns the result, whether or not a
 // satisfying block was eventually found.
 func (f *BlockFinder) Iterate(folders []string, hash []byte, iterFn func(string, string, int32) bool) bool {
+	return f.iterate(folders, hash, func(folder, file string, index int32, _ []byte) bool {
+		return iterFn(folder, file, index)
+	})
+}
+
+// IterateOffsets is like Iterate, but gives the offset of each block in
+// its file rather than the index, as blocks may vary in size. Entries
+// recorded by older versions, without offsets, are assumed to be of the
+// given block size.
+func (f *BlockFinder) IterateOffsets(folders []string, hash []byte, blockSize int, iterFn func(string, string, int64) bool) bool {
+	return f.iterate(folders, hash, func(folder, file string, index int32, val []byte) bool {
+		offset := int64(index) * int64(blockSize)
+		if len(val) >= 12 {
+			offset = int64(binary.BigEndian.Uint64(val[4:]))
+		}
+		return iterFn(folder, file, offset)
+	})
+}
+
+func (f *BlockFinder) iterate(folders []string, hash []byte, iterFn func(string, string, int32, []byte) bool) bool {
 	t, err := f.db.newReadOnlyTransaction()
 	if err != nil {
 		return false
@@ -58,8 +78,9 @@ func (f *BlockFinder) Iterate(folders []string, hash []byte, iterFn func(string,
 
 		for iter.Next() && iter.Error() == nil {
 			file := string(f.db.keyer.NameFromBlockMapKey(iter.Key()))
-			index := int32(binary.BigEndian.Uint32(iter.Value()))
-			if iterFn(folder, osutil.NativeFilename(file), index) {
+			val := iter.Value()
+			index := int32(binary.BigEndian.Uint32(val))
+			if iterFn(folder, osutil.NativeFilename(file), index, val) {
 				iter.Release()
 				return true
 			}
@@ -11,6 +11,7 @@ import (
 	"testing"
 
 	"github.com/syncthing/syncthing/lib/db/backend"
+	"github.com/syncthing/syncthing/lib/fs"
 	"github.com/syncthing/syncthing/lib/protocol"
 )
 
@@ -256,3 +257,45 @@ func TestBlockFinderLookup(t *testing.T) {
 
 	f1.Deleted = false
 }
+
+func TestBlockFinderOffsets(t *testing.T) {
+	db, f := setup()
+
+	// Content defined blocks, of varying sizes.
+	blocks := genBlocks(3)
+	blocks[0].Offset, blocks[0].Size = 0, 1000
+	blocks[1].Offset, blocks[1].Size = 1000, 3000
+	blocks[2].Offset, blocks[2].Size = 4000, 2000
+	file := protocol.FileInfo{
+		Name:        "cdc",
+		Version:     protocol.Vector{}.Update(1),
+		Blocks:      blocks,
+		BlockScheme: protocol.BlockSchemeFastCDC,
+	}
+	s := NewFileSet("folder1", fs.NewFilesystem(fs.FilesystemTypeBasic, "."), db)
+	s.Update(protocol.LocalDeviceID, []protocol.FileInfo{file})
+
+	// An entry written without offsets, by an older version.
+	if err := addToBlockMap(db, []byte("folder2"), []protocol.FileInfo{f1}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		hash   []byte
+		folder string
+		offset int64
+	}{
+		{blocks[2].Hash, "folder1", 4000},
+		{f1.Blocks[3].Hash, "folder2", 3 * 128},
+	} {
+		found := f.IterateOffsets(folders, tc.hash, 128, func(folder, file string, offset int64) bool {
+			if folder != tc.folder || offset != tc.offset {
+				t.Errorf("expected offset %d in %s, got %d in %s", tc.offset, tc.folder, offset, folder)
+			}
+			return true
+		})
+		if !found {
+			t.Errorf("block not found in %s", tc.folder)
+		}
+	}
+}
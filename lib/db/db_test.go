@@ -253,3 +253,146 @@ func TestDowngrade(t *testing.T) {
 		t.Fatalf("Error has %v as min Syncthing version, expected %v", err.minSyncthingVersion, dbMinSyncthingVersion)
 	}
 }
+
+func TestCheckRepair(t *testing.T) {
+	db := NewLowlevel(backend.OpenMemory())
+	defer db.Close()
+
+	folder := "test"
+	s := NewFileSet(folder, fs.NewFilesystem(fs.FilesystemTypeBasic, "."), db)
+
+	remoteDevice, _ := protocol.DeviceIDFromString("AIR6LPZ-7K4PTTV-UXQSMUU-CPQ5YWH-OEDFIIQ-JUG777G-2YQXXR5-YD6AWQR")
+	v1 := protocol.Vector{Counters: []protocol.Counter{{ID: 1, Value: 1}}}
+	v2 := protocol.Vector{Counters: []protocol.Counter{{ID: 1, Value: 2}}}
+	s.Update(protocol.LocalDeviceID, []protocol.FileInfo{
+		{Name: "a", Version: v1, Blocks: genBlocks(1)},
+		{Name: "b", Version: v1, Blocks: genBlocks(2)},
+		{Name: "c", Version: v1, Blocks: genBlocks(3)},
+	})
+	s.Update(remoteDevice, []protocol.FileInfo{
+		{Name: "a", Version: v1, Blocks: genBlocks(1)},
+		{Name: "b", Version: v2, Blocks: genBlocks(4)},
+	})
+
+	check, err := s.Check(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !check.OK() {
+		t.Fatalf("check of consistent database failed: %+v", check)
+	}
+	if check.Files != 5 || check.Globals != 3 || check.Sequences != 3 {
+		t.Errorf("unexpected number of entries checked: %+v", check)
+	}
+
+	// Lose the version list of b, the sequence index entry of c and put a
+	// sequence index entry for a missing file.
+
+	gk, err := db.keyer.GenerateGlobalVersionKey(nil, []byte(folder), []byte("b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Delete(gk); err != nil {
+		t.Fatal(err)
+	}
+	c, ok := s.Get(protocol.LocalDeviceID, "c")
+	if !ok {
+		t.Fatal("c missing")
+	}
+	sk, err := db.keyer.GenerateSequenceKey(nil, []byte(folder), c.Sequence)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Delete(sk); err != nil {
+		t.Fatal(err)
+	}
+	dk, err := db.keyer.GenerateDeviceFileKey(nil, []byte(folder), protocol.LocalDeviceID[:], []byte("x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sk, err = db.keyer.GenerateSequenceKey(nil, []byte(folder), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Put(sk, dk); err != nil {
+		t.Fatal(err)
+	}
+
+	check, err = s.Check(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if check.OK() || check.Repaired {
+		t.Fatalf("check of inconsistent database passed: %+v", check)
+	}
+	if check.MissingGlobals != 2 || check.MissingSequences != 1 || check.DanglingSequences != 1 || check.MetadataCorrect {
+		t.Errorf("unexpected check result: %+v", check)
+	}
+
+	check, err = s.Check(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !check.Repaired {
+		t.Fatalf("database not repaired: %+v", check)
+	}
+	check, err = s.Check(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !check.OK() {
+		t.Fatalf("check of repaired database failed: %+v", check)
+	}
+	if g, ok := s.GetGlobal("b"); !ok || !g.Version.Equal(v2) {
+		t.Errorf("global b is %v, %v, expected version %v", g.Version, ok, v2)
+	}
+	if gs := s.GlobalSize(); gs.Files != 3 {
+		t.Errorf("global size is %+v, expected 3 files", gs)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	db := NewLowlevel(backend.OpenMemory())
+	defer db.Close()
+
+	remoteDevice, _ := protocol.DeviceIDFromString("AIR6LPZ-7K4PTTV-UXQSMUU-CPQ5YWH-OEDFIIQ-JUG777G-2YQXXR5-YD6AWQR")
+	v1 := protocol.Vector{Counters: []protocol.Counter{{ID: 1, Value: 1}}}
+	s := NewFileSet("test", fs.NewFilesystem(fs.FilesystemTypeBasic, "."), db)
+	s.Update(protocol.LocalDeviceID, []protocol.FileInfo{
+		{Name: "a", Version: v1, Blocks: genBlocks(2)},
+	})
+	s.Update(remoteDevice, []protocol.FileInfo{
+		{Name: "a", Version: v1, Blocks: genBlocks(2)},
+		{Name: "b", Version: v1, Blocks: genBlocks(2)},
+	})
+	if err := NewMiscDataNamespace(db).PutInt64("test", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	usage, err := db.Usage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, ok := usage.Folders["test"]
+	if !ok {
+		t.Fatal("no usage for folder")
+	}
+	if u.Files == 0 || u.Globals == 0 || u.Blocks == 0 || u.Sequences == 0 || u.Other == 0 {
+		t.Errorf("missing usage: %+v", u)
+	}
+	if u.Total != u.Files+u.Globals+u.Blocks+u.Sequences+u.Needs+u.Other {
+		t.Errorf("total doesn't add up: %+v", u)
+	}
+	if l, r := u.Devices[protocol.LocalDeviceID], u.Devices[remoteDevice]; l.Keys != 1 || r.Keys != 2 || l.Bytes+r.Bytes != u.Files {
+		t.Errorf("unexpected device usage: local %+v, remote %+v", l, r)
+	}
+	if usage.Devices[remoteDevice] != u.Devices[remoteDevice] {
+		t.Errorf("device usage %+v doesn't match folder device usage %+v", usage.Devices[remoteDevice], u.Devices[remoteDevice])
+	}
+	if usage.Other.Keys == 0 {
+		t.Error("misc data not accounted")
+	}
+	if usage.Total.Keys != u.Keys+usage.Other.Keys || usage.Total.Bytes != u.Total+usage.Other.Bytes {
+		t.Errorf("database total %+v doesn't add up", usage.Total)
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package db
+
+import (
+	"time"
+
+	"github.com/syncthing/syncthing/lib/osutil"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// addFileHistory records the file of the device as the new global version,
+// removing the oldest entries of the file beyond those to keep.
+func (t readWriteTransaction) addFileHistory(keyBuf, folder, device []byte, file protocol.FileInfo) ([]byte, error) {
+	now := time.Now().UnixNano()
+	hk, err := t.keyer.GenerateFileHistoryKey(keyBuf, folder, []byte(file.Name), now)
+	if err != nil {
+		return nil, err
+	}
+
+	// The entries are in time order, and the new one isn't among them
+	// yet as reads don't see the writes of the transaction.
+	it, err := t.NewPrefixIterator(hk.WithoutTime())
+	if err != nil {
+		return nil, err
+	}
+	var keys [][]byte
+	for it.Next() {
+		keys = append(keys, append([]byte(nil), it.Key()...))
+	}
+	it.Release()
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	for len(keys) > 0 && len(keys) >= t.keepHistory {
+		if err := t.Delete(keys[0]); err != nil {
+			return nil, err
+		}
+		keys = keys[1:]
+	}
+
+	// The blocks aren't kept, so the file goes in truncated, which has
+	// the same encoding otherwise.
+	file.Blocks = nil
+	entry := FileHistoryEntry{
+		Device: device,
+		Time:   now,
+	}
+	if err := entry.File.Unmarshal(mustMarshal(&file)); err != nil {
+		return nil, err
+	}
+	if err := t.Put(hk, mustMarshal(&entry)); err != nil {
+		return nil, err
+	}
+	return hk, nil
+}
+
+// history returns the recorded global versions of the file, oldest first.
+func (db *Lowlevel) history(folder, file []byte) ([]FileHistoryEntry, error) {
+	t, err := db.newReadOnlyTransaction()
+	if err != nil {
+		return nil, err
+	}
+	defer t.close()
+
+	hk, err := db.keyer.GenerateFileHistoryKey(nil, folder, file, 0)
+	if err != nil {
+		return nil, err
+	}
+	it, err := t.NewPrefixIterator(hk.WithoutTime())
+	if err != nil {
+		return nil, err
+	}
+	defer it.Release()
+	var entries []FileHistoryEntry
+	for it.Next() {
+		var entry FileHistoryEntry
+		if err := entry.Unmarshal(it.Value()); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, it.Error()
+}
+
+// SetFileHistory sets how many global versions of each file to keep in the
+// file history, as they change from now on; 0 to record none.
+func (s *FileSet) SetFileHistory(keep int) {
+	s.updateMutex.Lock()
+	s.keepHistory = keep
+	s.updateMutex.Unlock()
+}
+
+// FileHistory returns the recorded global versions of the file, oldest
+// first, with the device each came from and when it became the global
+// version.
+func (s *FileSet) FileHistory(file string) ([]FileHistoryEntry, error) {
+	l.Debugf("%s FileHistory(%v)", s.folder, file)
+	entries, err := s.db.history([]byte(s.folder), []byte(osutil.NormalizedFilename(file)))
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		entries[i].File.Name = osutil.NativeFilename(entries[i].File.Name)
+	}
+	return entries, nil
+}
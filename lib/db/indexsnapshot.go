@@ -0,0 +1,272 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package db
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/db/backend"
+	"github.com/syncthing/syncthing/lib/osutil"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+var (
+	errInvalidSnapshotName = errors.New("invalid index snapshot name")
+	errSnapshotExists      = errors.New("index snapshot already exists")
+	errNoSnapshot          = errors.New("no such index snapshot")
+)
+
+// An IndexSnapshotChange is a file that differs between an index snapshot
+// and the local index as it is now. Either may be empty, when the file
+// isn't there.
+type IndexSnapshotChange struct {
+	Snapshot protocol.FileInfo
+	Current  protocol.FileInfo
+}
+
+// Action returns whether the file was added, modified or deleted since the
+// snapshot.
+func (c IndexSnapshotChange) Action() string {
+	inSnapshot := c.Snapshot.Name != "" && !c.Snapshot.IsDeleted()
+	inCurrent := c.Current.Name != "" && !c.Current.IsDeleted()
+	switch {
+	case !inSnapshot && inCurrent:
+		return "added"
+	case inSnapshot && !inCurrent:
+		return "deleted"
+	default:
+		return "modified"
+	}
+}
+
+// CreateIndexSnapshot keeps a copy of the local index of the folder under
+// the given name. Only the index is copied, not the contents of the files.
+func (s *FileSet) CreateIndexSnapshot(name string) (IndexSnapshot, error) {
+	l.Debugf("%s CreateIndexSnapshot(%v)", s.folder, name)
+	if name == "" || strings.ContainsRune(name, 0) {
+		return IndexSnapshot{}, errInvalidSnapshotName
+	}
+
+	s.updateMutex.Lock()
+	defer s.updateMutex.Unlock()
+
+	return s.db.createIndexSnapshot([]byte(s.folder), []byte(name))
+}
+
+// IndexSnapshots returns the index snapshots of the folder, by name.
+func (s *FileSet) IndexSnapshots() ([]IndexSnapshot, error) {
+	l.Debugf("%s IndexSnapshots()", s.folder)
+	return s.db.indexSnapshots([]byte(s.folder))
+}
+
+// DiffIndexSnapshot returns the files that differ between the snapshot and
+// the local index, by name.
+func (s *FileSet) DiffIndexSnapshot(name string) ([]IndexSnapshotChange, error) {
+	l.Debugf("%s DiffIndexSnapshot(%v)", s.folder, name)
+	changes, err := s.db.diffIndexSnapshot([]byte(s.folder), []byte(name))
+	if err != nil {
+		return nil, err
+	}
+	for i := range changes {
+		changes[i].Snapshot.Name = osutil.NativeFilename(changes[i].Snapshot.Name)
+		changes[i].Current.Name = osutil.NativeFilename(changes[i].Current.Name)
+	}
+	return changes, nil
+}
+
+// DropIndexSnapshot removes the snapshot.
+func (s *FileSet) DropIndexSnapshot(name string) error {
+	l.Debugf("%s DropIndexSnapshot(%v)", s.folder, name)
+	return s.db.dropIndexSnapshot([]byte(s.folder), []byte(name))
+}
+
+func (db *Lowlevel) createIndexSnapshot(folder, name []byte) (IndexSnapshot, error) {
+	t, err := db.newReadWriteTransaction()
+	if err != nil {
+		return IndexSnapshot{}, err
+	}
+	defer t.close()
+
+	sk, err := db.keyer.GenerateIndexSnapshotKey(nil, folder, name)
+	if err != nil {
+		return IndexSnapshot{}, err
+	}
+	if _, err := t.Get(sk); err == nil {
+		return IndexSnapshot{}, errSnapshotExists
+	} else if !backend.IsNotFound(err) {
+		return IndexSnapshot{}, err
+	}
+
+	dk, err := db.keyer.GenerateDeviceFileKey(nil, folder, protocol.LocalDeviceID[:], nil)
+	if err != nil {
+		return IndexSnapshot{}, err
+	}
+	dbi, err := t.NewPrefixIterator(dk)
+	if err != nil {
+		return IndexSnapshot{}, err
+	}
+	defer dbi.Release()
+
+	snap := IndexSnapshot{
+		Name: string(name),
+		Time: time.Now().UnixNano(),
+	}
+	var fk []byte
+	for dbi.Next() {
+		fk, err = db.keyer.GenerateIndexSnapshotFileKey(fk, folder, name, db.keyer.NameFromDeviceFileKey(dbi.Key()))
+		if err != nil {
+			return IndexSnapshot{}, err
+		}
+		if err := t.Put(fk, dbi.Value()); err != nil {
+			return IndexSnapshot{}, err
+		}
+		snap.Files++
+		if err := t.Checkpoint(); err != nil {
+			return IndexSnapshot{}, err
+		}
+	}
+	if err := dbi.Error(); err != nil {
+		return IndexSnapshot{}, err
+	}
+
+	if err := t.Put(sk, mustMarshal(&snap)); err != nil {
+		return IndexSnapshot{}, err
+	}
+	return snap, t.commit()
+}
+
+func (db *Lowlevel) indexSnapshots(folder []byte) ([]IndexSnapshot, error) {
+	sk, err := db.keyer.GenerateIndexSnapshotKey(nil, folder, nil)
+	if err != nil {
+		return nil, err
+	}
+	it, err := db.NewPrefixIterator(sk.WithoutSnapshot())
+	if err != nil {
+		return nil, err
+	}
+	defer it.Release()
+	var snaps []IndexSnapshot
+	for it.Next() {
+		var snap IndexSnapshot
+		if err := snap.Unmarshal(it.Value()); err != nil {
+			return nil, err
+		}
+		snaps = append(snaps, snap)
+	}
+	return snaps, it.Error()
+}
+
+// diffIndexSnapshot goes through the files of the snapshot and of the
+// local index side by side, both being in name order.
+func (db *Lowlevel) diffIndexSnapshot(folder, name []byte) ([]IndexSnapshotChange, error) {
+	t, err := db.newReadOnlyTransaction()
+	if err != nil {
+		return nil, err
+	}
+	defer t.close()
+
+	sk, err := db.keyer.GenerateIndexSnapshotKey(nil, folder, name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := t.Get(sk); backend.IsNotFound(err) {
+		return nil, errNoSnapshot
+	} else if err != nil {
+		return nil, err
+	}
+
+	fk, err := db.keyer.GenerateIndexSnapshotFileKey(nil, folder, name, nil)
+	if err != nil {
+		return nil, err
+	}
+	si, err := t.NewPrefixIterator(fk)
+	if err != nil {
+		return nil, err
+	}
+	defer si.Release()
+	dk, err := db.keyer.GenerateDeviceFileKey(nil, folder, protocol.LocalDeviceID[:], nil)
+	if err != nil {
+		return nil, err
+	}
+	di, err := t.NewPrefixIterator(dk)
+	if err != nil {
+		return nil, err
+	}
+	defer di.Release()
+
+	var changes []IndexSnapshotChange
+	sOK, dOK := si.Next(), di.Next()
+	for sOK || dOK {
+		var c IndexSnapshotChange
+		cmp := 0
+		switch {
+		case !sOK:
+			cmp = 1
+		case !dOK:
+			cmp = -1
+		default:
+			cmp = bytes.Compare(db.keyer.NameFromIndexSnapshotFileKey(si.Key()), db.keyer.NameFromDeviceFileKey(di.Key()))
+		}
+		if cmp <= 0 {
+			if err := c.Snapshot.Unmarshal(append([]byte{}, si.Value()...)); err != nil {
+				return nil, err
+			}
+			sOK = si.Next()
+		}
+		if cmp >= 0 {
+			if err := c.Current.Unmarshal(append([]byte{}, di.Value()...)); err != nil {
+				return nil, err
+			}
+			dOK = di.Next()
+		}
+		if cmp != 0 || !sameSnapshotFile(c.Snapshot, c.Current) {
+			changes = append(changes, c)
+		}
+	}
+	if err := si.Error(); err != nil {
+		return nil, err
+	}
+	return changes, di.Error()
+}
+
+// sameSnapshotFile returns whether the file is as it was in the snapshot,
+// which it is when it's the same version with the same local flags.
+func sameSnapshotFile(snap, cur protocol.FileInfo) bool {
+	return snap.Version.Equal(cur.Version) && snap.LocalFlags == cur.LocalFlags && snap.Deleted == cur.Deleted
+}
+
+func (db *Lowlevel) dropIndexSnapshot(folder, name []byte) error {
+	t, err := db.newReadWriteTransaction()
+	if err != nil {
+		return err
+	}
+	defer t.close()
+
+	sk, err := db.keyer.GenerateIndexSnapshotKey(nil, folder, name)
+	if err != nil {
+		return err
+	}
+	if _, err := t.Get(sk); backend.IsNotFound(err) {
+		return errNoSnapshot
+	} else if err != nil {
+		return err
+	}
+	fk, err := db.keyer.GenerateIndexSnapshotFileKey(nil, folder, name, nil)
+	if err != nil {
+		return err
+	}
+	if err := t.deleteKeyPrefix(fk); err != nil {
+		return err
+	}
+	if err := t.Delete(sk); err != nil {
+		return err
+	}
+	return t.commit()
+}
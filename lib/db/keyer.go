@@ -15,6 +15,7 @@ const (
 	keyFolderLen   = 4 // indexed
 	keyDeviceLen   = 4 // indexed
 	keySequenceLen = 8
+	keyTimeLen     = 8
 	keyHashLen     = 32
 
 	maxInt64 int64 = 1<<63 - 1
@@ -59,6 +60,24 @@ const (
 
 	// KeyTypeNeed <int32 folder ID> <file name> = <nothing>
 	KeyTypeNeed = 12
+
+	// KeyTypeScanJournal <folder ID as string> <0x00> <some string> = some value
+	KeyTypeScanJournal = 13
+
+	// KeyTypeFileHistory <int32 folder ID> <file name> <0x00> <int64 unix nanos> = FileHistoryEntry
+	KeyTypeFileHistory = 14
+
+	// KeyTypeAuditLog <folder ID as string> <0x00> <int64 entry ID> = AuditEntry
+	KeyTypeAuditLog = 15
+
+	// KeyTypeTombstone <int32 folder ID> <file name> = int64 unix time since every device has the deletion
+	KeyTypeTombstone = 16
+
+	// KeyTypeIndexSnapshot <int32 folder ID> <snapshot name> = IndexSnapshot
+	KeyTypeIndexSnapshot = 17
+
+	// KeyTypeIndexSnapshotFile <int32 folder ID> <snapshot name> <0x00> <file name> = FileInfo
+	KeyTypeIndexSnapshotFile = 18
 )
 
 type keyer interface {
@@ -93,6 +112,18 @@ type keyer interface {
 
 	// Folder metadata
 	GenerateFolderMetaKey(key, folder []byte) (folderMetaKey, error)
+
+	// File history
+	GenerateFileHistoryKey(key, folder, name []byte, t int64) (fileHistoryKey, error)
+
+	// Deleted files every device has
+	GenerateTombstoneKey(key, folder, name []byte) (tombstoneKey, error)
+	NameFromTombstoneKey(key []byte) []byte
+
+	// Index snapshots
+	GenerateIndexSnapshotKey(key, folder, snapshot []byte) (indexSnapshotKey, error)
+	GenerateIndexSnapshotFileKey(key, folder, snapshot, name []byte) (indexSnapshotFileKey, error)
+	NameFromIndexSnapshotFileKey(key []byte) []byte
 }
 
 // defaultKeyer implements our key scheme. It needs folder and device
@@ -281,6 +312,105 @@ func (k defaultKeyer) GenerateFolderMetaKey(key, folder []byte) (folderMetaKey,
 	return key, nil
 }
 
+type fileHistoryKey []byte
+
+func (k fileHistoryKey) WithoutNameAndTime() []byte {
+	return k[:keyPrefixLen+keyFolderLen]
+}
+
+// WithoutTime is the prefix of the keys of all history entries of the file.
+func (k fileHistoryKey) WithoutTime() []byte {
+	return k[:len(k)-keyTimeLen]
+}
+
+func (k defaultKeyer) GenerateFileHistoryKey(key, folder, name []byte, t int64) (fileHistoryKey, error) {
+	folderID, err := k.folderIdx.ID(folder)
+	if err != nil {
+		return nil, err
+	}
+	// File names don't contain zero bytes, so that the zero byte after
+	// the name ends it.
+	key = resize(key, keyPrefixLen+keyFolderLen+len(name)+1+keyTimeLen)
+	key[0] = KeyTypeFileHistory
+	binary.BigEndian.PutUint32(key[keyPrefixLen:], folderID)
+	copy(key[keyPrefixLen+keyFolderLen:], name)
+	key[keyPrefixLen+keyFolderLen+len(name)] = 0
+	binary.BigEndian.PutUint64(key[len(key)-keyTimeLen:], uint64(t))
+	return key, nil
+}
+
+type tombstoneKey []byte
+
+func (k tombstoneKey) WithoutName() []byte {
+	return k[:keyPrefixLen+keyFolderLen]
+}
+
+func (k defaultKeyer) GenerateTombstoneKey(key, folder, name []byte) (tombstoneKey, error) {
+	folderID, err := k.folderIdx.ID(folder)
+	if err != nil {
+		return nil, err
+	}
+	key = resize(key, keyPrefixLen+keyFolderLen+len(name))
+	key[0] = KeyTypeTombstone
+	binary.BigEndian.PutUint32(key[keyPrefixLen:], folderID)
+	copy(key[keyPrefixLen+keyFolderLen:], name)
+	return key, nil
+}
+
+func (k defaultKeyer) NameFromTombstoneKey(key []byte) []byte {
+	return key[keyPrefixLen+keyFolderLen:]
+}
+
+type indexSnapshotKey []byte
+
+func (k indexSnapshotKey) WithoutSnapshot() []byte {
+	return k[:keyPrefixLen+keyFolderLen]
+}
+
+func (k defaultKeyer) GenerateIndexSnapshotKey(key, folder, snapshot []byte) (indexSnapshotKey, error) {
+	folderID, err := k.folderIdx.ID(folder)
+	if err != nil {
+		return nil, err
+	}
+	key = resize(key, keyPrefixLen+keyFolderLen+len(snapshot))
+	key[0] = KeyTypeIndexSnapshot
+	binary.BigEndian.PutUint32(key[keyPrefixLen:], folderID)
+	copy(key[keyPrefixLen+keyFolderLen:], snapshot)
+	return key, nil
+}
+
+type indexSnapshotFileKey []byte
+
+func (k indexSnapshotFileKey) WithoutSnapshotAndName() []byte {
+	return k[:keyPrefixLen+keyFolderLen]
+}
+
+func (k defaultKeyer) GenerateIndexSnapshotFileKey(key, folder, snapshot, name []byte) (indexSnapshotFileKey, error) {
+	folderID, err := k.folderIdx.ID(folder)
+	if err != nil {
+		return nil, err
+	}
+	// Snapshot names don't contain zero bytes, so that the zero byte
+	// after the name ends it.
+	key = resize(key, keyPrefixLen+keyFolderLen+len(snapshot)+1+len(name))
+	key[0] = KeyTypeIndexSnapshotFile
+	binary.BigEndian.PutUint32(key[keyPrefixLen:], folderID)
+	copy(key[keyPrefixLen+keyFolderLen:], snapshot)
+	key[keyPrefixLen+keyFolderLen+len(snapshot)] = 0
+	copy(key[keyPrefixLen+keyFolderLen+len(snapshot)+1:], name)
+	return key, nil
+}
+
+func (k defaultKeyer) NameFromIndexSnapshotFileKey(key []byte) []byte {
+	name := key[keyPrefixLen+keyFolderLen:]
+	for i, b := range name {
+		if b == 0 {
+			return name[i+1:]
+		}
+	}
+	return nil
+}
+
 // resize returns a byte slice of the specified size, reusing bs if possible
 func resize(bs []byte, size int) []byte {
 	if cap(bs) < size {
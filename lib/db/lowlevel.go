@@ -24,6 +24,7 @@ type Lowlevel struct {
 	folderIdx *smallIndex
 	deviceIdx *smallIndex
 	keyer     keyer
+	writes    *writeQueue
 }
 
 func NewLowlevel(backend backend.Backend) *Lowlevel {
@@ -31,6 +32,7 @@ func NewLowlevel(backend backend.Backend) *Lowlevel {
 		Backend:   backend,
 		folderIdx: newSmallIndex(backend, []byte{KeyTypeFolderIdx}),
 		deviceIdx: newSmallIndex(backend, []byte{KeyTypeDeviceIdx}),
+		writes:    newWriteQueue(),
 	}
 	db.keyer = newDefaultKeyer(db.folderIdx, db.deviceIdx)
 	return db
@@ -43,12 +45,19 @@ func (db *Lowlevel) ListFolders() []string {
 
 // updateRemoteFiles adds a list of fileinfos to the database and updates the
 // global versionlist and metadata.
-func (db *Lowlevel) updateRemoteFiles(folder, device []byte, fs []protocol.FileInfo, meta *metadataTracker) error {
+func (db *Lowlevel) updateRemoteFiles(folder, device []byte, fs []protocol.FileInfo, meta *metadataTracker, keepHistory int) error {
+	return db.writes.inTurns(folder, fs, func(fs []protocol.FileInfo) error {
+		return db.updateRemoteFilesTurn(folder, device, fs, meta, keepHistory)
+	})
+}
+
+func (db *Lowlevel) updateRemoteFilesTurn(folder, device []byte, fs []protocol.FileInfo, meta *metadataTracker, keepHistory int) error {
 	t, err := db.newReadWriteTransaction()
 	if err != nil {
 		return err
 	}
 	defer t.close()
+	t.keepHistory = keepHistory
 
 	var dk, gk, keyBuf []byte
 	devID := protocol.DeviceIDFromBytes(device)
@@ -96,15 +105,22 @@ func (db *Lowlevel) updateRemoteFiles(folder, device []byte, fs []protocol.FileI
 
 // updateLocalFiles adds fileinfos to the db, and updates the global versionlist,
 // metadata, sequence and blockmap buckets.
-func (db *Lowlevel) updateLocalFiles(folder []byte, fs []protocol.FileInfo, meta *metadataTracker) error {
+func (db *Lowlevel) updateLocalFiles(folder []byte, fs []protocol.FileInfo, meta *metadataTracker, keepHistory int) error {
+	return db.writes.inTurns(folder, fs, func(fs []protocol.FileInfo) error {
+		return db.updateLocalFilesTurn(folder, fs, meta, keepHistory)
+	})
+}
+
+func (db *Lowlevel) updateLocalFilesTurn(folder []byte, fs []protocol.FileInfo, meta *metadataTracker, keepHistory int) error {
 	t, err := db.newReadWriteTransaction()
 	if err != nil {
 		return err
 	}
 	defer t.close()
+	t.keepHistory = keepHistory
 
 	var dk, gk, keyBuf []byte
-	blockBuf := make([]byte, 4)
+	blockBuf := make([]byte, 12) // index and offset of the block
 	for _, f := range fs {
 		name := []byte(f.Name)
 		dk, err = db.keyer.GenerateDeviceFileKey(dk, folder, protocol.LocalDeviceID[:], name)
@@ -176,6 +192,7 @@ func (db *Lowlevel) updateLocalFiles(folder []byte, fs []protocol.FileInfo, meta
 		if !f.IsDirectory() && !f.IsDeleted() && !f.IsInvalid() {
 			for i, block := range f.Blocks {
 				binary.BigEndian.PutUint32(blockBuf, uint32(i))
+				binary.BigEndian.PutUint64(blockBuf[4:], uint64(block.Offset))
 				keyBuf, err = db.keyer.GenerateBlockMapKey(keyBuf, folder, block.Hash, name)
 				if err != nil {
 					return err
@@ -645,6 +662,40 @@ func (db *Lowlevel) dropFolder(folder []byte) error {
 		return err
 	}
 
+	// Remove the file history of the folder
+	k5, err := db.keyer.GenerateFileHistoryKey(nil, folder, nil, 0)
+	if err != nil {
+		return err
+	}
+	if err := t.deleteKeyPrefix(k5.WithoutNameAndTime()); err != nil {
+		return err
+	}
+
+	// Remove the deletion times of the folder
+	k6, err := db.keyer.GenerateTombstoneKey(nil, folder, nil)
+	if err != nil {
+		return err
+	}
+	if err := t.deleteKeyPrefix(k6.WithoutName()); err != nil {
+		return err
+	}
+
+	// Remove the index snapshots of the folder
+	k7, err := db.keyer.GenerateIndexSnapshotKey(nil, folder, nil)
+	if err != nil {
+		return err
+	}
+	if err := t.deleteKeyPrefix(k7.WithoutSnapshot()); err != nil {
+		return err
+	}
+	k8, err := db.keyer.GenerateIndexSnapshotFileKey(nil, folder, nil, nil)
+	if err != nil {
+		return err
+	}
+	if err := t.deleteKeyPrefix(k8.WithoutSnapshotAndName()); err != nil {
+		return err
+	}
+
 	return t.commit()
 }
 
@@ -0,0 +1,325 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package db
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/syncthing/syncthing/lib/db/backend"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+var errCompactionUnsupported = errors.New("the database backend doesn't support compaction")
+
+// Compact makes the backend reclaim the space of deleted and overwritten
+// entries now, rather than in its own time.
+func (db *Lowlevel) Compact() error {
+	c, ok := db.Backend.(backend.Compacter)
+	if !ok {
+		return errCompactionUnsupported
+	}
+	return c.Compact()
+}
+
+// FolderCheck is what checking the entries of a folder against each other
+// and against the folder metadata found.
+type FolderCheck struct {
+	Files             int  `json:"files"`             // file entries of all devices checked
+	Globals           int  `json:"globals"`           // version lists checked
+	Sequences         int  `json:"sequences"`         // sequence index entries checked
+	MissingGlobals    int  `json:"missingGlobals"`    // files not in their version list
+	DanglingGlobals   int  `json:"danglingGlobals"`   // versions in version lists without the file
+	MissingSequences  int  `json:"missingSequences"`  // local files not in the sequence index
+	DanglingSequences int  `json:"danglingSequences"` // sequence index entries without the file
+	MetadataCorrect   bool `json:"metadataCorrect"`   // whether the counts of files and bytes are right
+	Repaired          bool `json:"repaired"`          // whether what was found was repaired
+}
+
+// OK returns whether nothing was found to be wrong.
+func (c FolderCheck) OK() bool {
+	return c.MissingGlobals == 0 && c.DanglingGlobals == 0 && c.MissingSequences == 0 && c.DanglingSequences == 0 && c.MetadataCorrect
+}
+
+// folderRepairs is what checkFolder found to need fixing: the keys of
+// files missing from their version list or the sequence index, and of
+// sequence index entries without their file.
+type folderRepairs struct {
+	globals      [][]byte
+	sequences    [][]byte
+	danglingSeqs [][]byte
+}
+
+// checkFolder goes through the file entries, version lists and sequence
+// index of the folder, checking that they agree, and recalculates the
+// folder metadata for comparison. Nothing is changed.
+func (db *Lowlevel) checkFolder(folder []byte) (FolderCheck, *metadataTracker, folderRepairs, error) {
+	var check FolderCheck
+	var repairs folderRepairs
+	meta := newMetadataTracker()
+
+	t, err := db.newReadOnlyTransaction()
+	if err != nil {
+		return check, nil, repairs, err
+	}
+	defer t.close()
+
+	// The files of all devices, which should be in their version lists
+	// and for the local device in the sequence index.
+
+	dk, err := db.keyer.GenerateDeviceFileKey(nil, folder, nil, nil)
+	if err != nil {
+		return check, nil, repairs, err
+	}
+	dbi, err := t.NewPrefixIterator(dk.WithoutNameAndDevice())
+	if err != nil {
+		return check, nil, repairs, err
+	}
+	defer dbi.Release()
+
+	var gk, sk []byte
+	var deviceID protocol.DeviceID
+	for dbi.Next() {
+		device, ok := db.keyer.DeviceFromDeviceFileKey(dbi.Key())
+		if !ok {
+			continue
+		}
+		var f FileInfoTruncated
+		if err := f.Unmarshal(append([]byte{}, dbi.Value()...)); err != nil {
+			return check, nil, repairs, err
+		}
+		check.Files++
+		copy(deviceID[:], device)
+		meta.addFile(deviceID, f)
+
+		gk, err = db.keyer.GenerateGlobalVersionKey(gk, folder, []byte(f.Name))
+		if err != nil {
+			return check, nil, repairs, err
+		}
+		inGlobal := false
+		if bs, err := t.Get(gk); err == nil {
+			if vl, ok := unmarshalVersionList(bs); ok {
+				fv, ok := vl.Get(device)
+				inGlobal = ok && fv.Version.Equal(f.Version)
+			}
+		} else if !backend.IsNotFound(err) {
+			return check, nil, repairs, err
+		}
+		if !inGlobal {
+			l.Debugf("check %q: %v missing from version list of %q", folder, deviceID, f.Name)
+			check.MissingGlobals++
+			repairs.globals = append(repairs.globals, append([]byte{}, dbi.Key()...))
+		}
+
+		if deviceID != protocol.LocalDeviceID {
+			continue
+		}
+		sk, err = db.keyer.GenerateSequenceKey(sk, folder, f.Sequence)
+		if err != nil {
+			return check, nil, repairs, err
+		}
+		bs, err := t.Get(sk)
+		if err != nil && !backend.IsNotFound(err) {
+			return check, nil, repairs, err
+		}
+		if err != nil || !bytes.Equal(bs, dbi.Key()) {
+			l.Debugf("check %q: %q missing from sequence index at %d", folder, f.Name, f.Sequence)
+			check.MissingSequences++
+			repairs.sequences = append(repairs.sequences, append([]byte{}, dbi.Key()...))
+		}
+	}
+	if err := dbi.Error(); err != nil {
+		return check, nil, repairs, err
+	}
+	dbi.Release()
+
+	// The version lists, which should only list existing files. The global
+	// counts are those of the first version that exists.
+
+	gk, err = db.keyer.GenerateGlobalVersionKey(gk, folder, nil)
+	if err != nil {
+		return check, nil, repairs, err
+	}
+	gi, err := t.NewPrefixIterator(globalVersionKey(gk).WithoutName())
+	if err != nil {
+		return check, nil, repairs, err
+	}
+	defer gi.Release()
+	for gi.Next() {
+		vl, ok := unmarshalVersionList(gi.Value())
+		if !ok {
+			continue
+		}
+		check.Globals++
+		name := db.keyer.NameFromGlobalVersionKey(gi.Key())
+		counted := false
+		for _, version := range vl.Versions {
+			dk, err = db.keyer.GenerateDeviceFileKey(dk, folder, version.Device, name)
+			if err != nil {
+				return check, nil, repairs, err
+			}
+			fi, ok, err := t.getFileTrunc(dk, true)
+			if err != nil {
+				return check, nil, repairs, err
+			}
+			if !ok {
+				l.Debugf("check %q: version list of %q lists missing file", folder, name)
+				check.DanglingGlobals++
+				continue
+			}
+			if !counted {
+				meta.addFile(protocol.GlobalDeviceID, fi)
+				counted = true
+			}
+		}
+	}
+	if err := gi.Error(); err != nil {
+		return check, nil, repairs, err
+	}
+	gi.Release()
+
+	// The sequence index, which should only point at local files of the
+	// same sequence.
+
+	sk, err = db.keyer.GenerateSequenceKey(sk, folder, 0)
+	if err != nil {
+		return check, nil, repairs, err
+	}
+	si, err := t.NewPrefixIterator(sequenceKey(sk).WithoutSequence())
+	if err != nil {
+		return check, nil, repairs, err
+	}
+	defer si.Release()
+	for si.Next() {
+		check.Sequences++
+		seq := db.keyer.SequenceFromSequenceKey(si.Key())
+		f, ok, err := t.getFileTrunc(si.Value(), true)
+		if err != nil {
+			return check, nil, repairs, err
+		}
+		if ok && f.SequenceNo() == seq {
+			continue
+		}
+		l.Debugf("check %q: sequence %d points at missing or other file", folder, seq)
+		check.DanglingSequences++
+		repairs.danglingSeqs = append(repairs.danglingSeqs, append([]byte{}, si.Key()...))
+	}
+	if err := si.Error(); err != nil {
+		return check, nil, repairs, err
+	}
+
+	return check, meta, repairs, nil
+}
+
+// repairFolder fixes what checkFolder found, apart from the version lists
+// listing missing files, which checkGlobals takes care of.
+func (db *Lowlevel) repairFolder(folder []byte, repairs folderRepairs) error {
+	// Each file goes in its own transaction, as the transaction reads
+	// don't see its own writes and there may be several files to add to
+	// the same version list.
+	for _, dk := range repairs.globals {
+		if err := db.repairGlobal(folder, dk); err != nil {
+			return err
+		}
+	}
+
+	t, err := db.newReadWriteTransaction()
+	if err != nil {
+		return err
+	}
+	defer t.close()
+
+	// The dangling entries go first, as a file may be put at a sequence
+	// taken by one of them.
+	var sk []byte
+	for _, key := range repairs.danglingSeqs {
+		if err := t.Delete(key); err != nil {
+			return err
+		}
+	}
+	for _, dk := range repairs.sequences {
+		f, ok, err := t.getFileTrunc(dk, true)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if sk, err = db.keyer.GenerateSequenceKey(sk, folder, f.SequenceNo()); err != nil {
+			return err
+		}
+		if err := t.Put(sk, dk); err != nil {
+			return err
+		}
+		if err := t.Checkpoint(); err != nil {
+			return err
+		}
+	}
+	return t.commit()
+}
+
+// repairGlobal adds the file under the device file key to its version list.
+func (db *Lowlevel) repairGlobal(folder, dk []byte) error {
+	t, err := db.newReadWriteTransaction()
+	if err != nil {
+		return err
+	}
+	defer t.close()
+
+	device, ok := db.keyer.DeviceFromDeviceFileKey(dk)
+	if !ok {
+		return nil
+	}
+	fi, ok, err := t.getFileByKey(dk)
+	if err != nil || !ok {
+		return err
+	}
+	gk, err := db.keyer.GenerateGlobalVersionKey(nil, folder, []byte(fi.Name))
+	if err != nil {
+		return err
+	}
+	// The counts are recalculated afterwards.
+	if _, _, err := t.updateGlobal(gk, nil, folder, device, fi, newMetadataTracker()); err != nil {
+		return err
+	}
+	return t.commit()
+}
+
+// sameCounts returns whether the file, directory, symlink, deletion and byte
+// counts of the two are the same, leaving aside the sequence numbers.
+func sameCounts(a, b *metadataTracker) bool {
+	ac, bc := nonZeroCounts(a), nonZeroCounts(b)
+	if len(ac) != len(bc) {
+		return false
+	}
+	for key, c := range ac {
+		if bc[key] != c {
+			return false
+		}
+	}
+	return true
+}
+
+type countsValue struct {
+	files, directories, symlinks, deleted int32
+	bytes                                 int64
+}
+
+func nonZeroCounts(m *metadataTracker) map[metaKey]countsValue {
+	m.mut.RLock()
+	defer m.mut.RUnlock()
+
+	counts := make(map[metaKey]countsValue, len(m.indexes))
+	for key, idx := range m.indexes {
+		c := m.counts.Counts[idx]
+		v := countsValue{c.Files, c.Directories, c.Symlinks, c.Deleted, c.Bytes}
+		if v != (countsValue{}) {
+			counts[key] = v
+		}
+	}
+	return counts
+}
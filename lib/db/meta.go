@@ -260,6 +260,38 @@ func (m *metadataTracker) resetCounts(dev protocol.DeviceID) {
 	m.mut.Unlock()
 }
 
+// replaceCounts replaces the file, dir, etc. counters with those of other,
+// while retaining the sequence numbers where they are higher
+func (m *metadataTracker) replaceCounts(other *metadataTracker) {
+	other.mut.RLock()
+	defer other.mut.RUnlock()
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	m.dirty = true
+
+	for i, c := range m.counts.Counts {
+		m.counts.Counts[i] = Counts{
+			DeviceID:   c.DeviceID,
+			Sequence:   c.Sequence,
+			LocalFlags: c.LocalFlags,
+		}
+	}
+	for key, idx := range other.indexes {
+		cp := m.countsPtr(key.dev, key.flags)
+		seq := cp.Sequence
+		oc := other.counts.Counts[idx]
+		cp.Files = oc.Files
+		cp.Directories = oc.Directories
+		cp.Symlinks = oc.Symlinks
+		cp.Deleted = oc.Deleted
+		cp.Bytes = oc.Bytes
+		if oc.Sequence > seq {
+			cp.Sequence = oc.Sequence
+		}
+	}
+}
+
 // Counts returns the counts for the given device ID and flag. `flag` should
 // be zero or have exactly one bit set.
 func (m *metadataTracker) Counts(dev protocol.DeviceID, flag uint32) Counts {
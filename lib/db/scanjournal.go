@@ -0,0 +1,114 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package db
+
+import (
+	"github.com/syncthing/syncthing/lib/db/backend"
+)
+
+const (
+	scanJournalStart = 's' // <id> of the journaled scan
+	scanJournalDir   = 'd' // <dir name> = <nothing>, a completed directory
+)
+
+// A ScanJournal records the directories that a full scan of a folder has
+// completed, so that a scan interrupted by e.g. a restart can resume
+// without walking them again. The journal is kept until the scan finishes.
+type ScanJournal struct {
+	db      *Lowlevel
+	folder  []byte
+	resumed map[string]struct{}
+}
+
+// NewScanJournal returns the scan journal of the given folder.
+func NewScanJournal(db *Lowlevel, folder string) *ScanJournal {
+	return &ScanJournal{
+		db:     db,
+		folder: []byte(folder),
+	}
+}
+
+func scanJournalPrefix(folder []byte) []byte {
+	prefix := make([]byte, 0, len(folder)+2)
+	prefix = append(prefix, KeyTypeScanJournal)
+	prefix = append(prefix, folder...)
+	return append(prefix, 0)
+}
+
+func (j *ScanJournal) key(typ byte, name string) []byte {
+	key := scanJournalPrefix(j.folder)
+	key = append(key, typ)
+	return append(key, name...)
+}
+
+// Start begins a scan. The id identifies what the scan depends on, such as
+// the ignore patterns. If the journal holds an unfinished scan with the
+// same id, the directories it completed are loaded and the number of them
+// is returned. Otherwise the journal is reset and zero is returned.
+func (j *ScanJournal) Start(id string) (int, error) {
+	j.resumed = nil
+	prev, err := j.db.Get(j.key(scanJournalStart, ""))
+	if err != nil && !backend.IsNotFound(err) {
+		return 0, err
+	}
+	if err == nil && string(prev) == id {
+		return j.load()
+	}
+	if err := j.db.dropScanJournal(j.folder); err != nil {
+		return 0, err
+	}
+	return 0, j.db.Put(j.key(scanJournalStart, ""), []byte(id))
+}
+
+func (j *ScanJournal) load() (int, error) {
+	prefix := j.key(scanJournalDir, "")
+	it, err := j.db.NewPrefixIterator(prefix)
+	if err != nil {
+		return 0, err
+	}
+	defer it.Release()
+	j.resumed = make(map[string]struct{})
+	for it.Next() {
+		j.resumed[string(it.Key()[len(prefix):])] = struct{}{}
+	}
+	return len(j.resumed), it.Error()
+}
+
+// Completed returns whether the scan that was resumed by Start had already
+// completed the given directory.
+func (j *ScanJournal) Completed(dir string) bool {
+	_, ok := j.resumed[dir]
+	return ok
+}
+
+// Complete records the given directories as completed.
+func (j *ScanJournal) Complete(dirs []string) error {
+	if len(dirs) == 0 {
+		return nil
+	}
+	t, err := j.db.newReadWriteTransaction()
+	if err != nil {
+		return err
+	}
+	defer t.close()
+	for _, dir := range dirs {
+		if err := t.Put(j.key(scanJournalDir, dir), nil); err != nil {
+			return err
+		}
+	}
+	return t.commit()
+}
+
+// Finish removes the journal, as the scan has finished.
+func (j *ScanJournal) Finish() error {
+	j.resumed = nil
+	return j.db.dropScanJournal(j.folder)
+}
+
+func (db *Lowlevel) dropScanJournal(folder []byte) error {
+	return db.dropPrefix(scanJournalPrefix(folder))
+}
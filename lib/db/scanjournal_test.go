@@ -0,0 +1,76 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package db
+
+import (
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/db/backend"
+)
+
+func TestScanJournal(t *testing.T) {
+	ldb := NewLowlevel(backend.OpenMemory())
+
+	j := NewScanJournal(ldb, "foo")
+	other := NewScanJournal(ldb, "foobar")
+	if n, err := j.Start("a"); err != nil || n != 0 {
+		t.Fatalf("Start on an empty journal = %v, %v", n, err)
+	}
+	if _, err := other.Start("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Complete([]string{"dir1", "dir2"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Complete([]string{"dir3"}); err != nil {
+		t.Fatal(err)
+	}
+	if j.Completed("dir1") {
+		t.Error("directories completed by this scan should not count as resumed")
+	}
+
+	// Resuming with the same id loads the completed directories of this
+	// folder only.
+
+	j = NewScanJournal(ldb, "foo")
+	if n, err := j.Start("a"); err != nil || n != 2 {
+		t.Fatalf("Start resuming = %v, %v, expected 2", n, err)
+	}
+	if !j.Completed("dir1") || !j.Completed("dir2") {
+		t.Error("expected dir1 and dir2 to be completed")
+	}
+	if j.Completed("dir3") {
+		t.Error("dir3 was completed in another folder")
+	}
+
+	// A different id resets the journal.
+
+	if n, err := j.Start("b"); err != nil || n != 0 {
+		t.Fatalf("Start with another id = %v, %v, expected 0", n, err)
+	}
+	if j.Completed("dir1") {
+		t.Error("expected the journal to be reset")
+	}
+	if err := j.Complete([]string{"dir1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Finishing removes the journal.
+
+	if err := j.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := j.Start("b"); err != nil || n != 0 {
+		t.Fatalf("Start after finishing = %v, %v, expected 0", n, err)
+	}
+
+	// The other folder is left alone.
+
+	if n, err := other.Start("a"); err != nil || n != 1 {
+		t.Fatalf("Start on the other folder = %v, %v, expected 1", n, err)
+	}
+}
@@ -30,6 +30,7 @@ type FileSet struct {
 	meta   *metadataTracker
 
 	updateMutex sync.Mutex // protects database updates and the corresponding metadata changes
+	keepHistory int        // global versions of each file to keep in the file history
 }
 
 // FileIntf is the set of methods implemented by both protocol.FileInfo and
@@ -119,6 +120,43 @@ func (s *FileSet) recalcCounts() error {
 	return s.meta.toDB(s.db, []byte(s.folder))
 }
 
+// Check checks the database entries of the folder against each other and
+// against the folder metadata. With repair set, what was found to be wrong
+// is fixed and the metadata recalculated.
+func (s *FileSet) Check(repair bool) (FolderCheck, error) {
+	l.Debugf("%s Check(%v)", s.folder, repair)
+
+	s.updateMutex.Lock()
+	defer s.updateMutex.Unlock()
+
+	folder := []byte(s.folder)
+	check, meta, repairs, err := s.db.checkFolder(folder)
+	if err != nil {
+		return check, err
+	}
+	check.MetadataCorrect = sameCounts(s.meta, meta)
+	if !repair || check.OK() {
+		return check, nil
+	}
+
+	if err := s.db.repairFolder(folder, repairs); err != nil {
+		return check, err
+	}
+	if err := s.db.checkGlobals(folder, newMetadataTracker()); err != nil {
+		return check, err
+	}
+	if _, meta, _, err = s.db.checkFolder(folder); err != nil {
+		return check, err
+	}
+	s.meta.replaceCounts(meta)
+	s.meta.SetCreated()
+	if err := s.meta.toDB(s.db, folder); err != nil {
+		return check, err
+	}
+	check.Repaired = true
+	return check, nil
+}
+
 func (s *FileSet) Drop(device protocol.DeviceID) {
 	l.Debugf("%s Drop(%v)", s.folder, device)
 
@@ -172,13 +210,13 @@ func (s *FileSet) Update(device protocol.DeviceID, fs []protocol.FileInfo) {
 
 	if device == protocol.LocalDeviceID {
 		// For the local device we have a bunch of metadata to track.
-		if err := s.db.updateLocalFiles([]byte(s.folder), fs, s.meta); err != nil && !backend.IsClosed(err) {
+		if err := s.db.updateLocalFiles([]byte(s.folder), fs, s.meta, s.keepHistory); err != nil && !backend.IsClosed(err) {
 			panic(err)
 		}
 		return
 	}
 	// Easy case, just update the files and we're done.
-	if err := s.db.updateRemoteFiles([]byte(s.folder), device[:], fs, s.meta); err != nil && !backend.IsClosed(err) {
+	if err := s.db.updateRemoteFiles([]byte(s.folder), device[:], fs, s.meta, s.keepHistory); err != nil && !backend.IsClosed(err) {
 		panic(err)
 	}
 }
@@ -211,6 +249,23 @@ func (s *FileSet) WithHaveTruncated(device protocol.DeviceID, fn Iterator) {
 	}
 }
 
+// IndexSummary summarizes the index of the device, leaving out invalid
+// files.
+func (s *FileSet) IndexSummary(device protocol.DeviceID) protocol.IndexSummary {
+	l.Debugf("%s IndexSummary(%v)", s.folder, device)
+	var b protocol.IndexSummaryBuilder
+	err := s.db.withHave([]byte(s.folder), device[:], nil, true, func(f FileIntf) bool {
+		if !f.IsInvalid() {
+			b.Add(f.FileName(), f.FileVersion(), f.IsDeleted())
+		}
+		return true
+	})
+	if err != nil && !backend.IsClosed(err) {
+		panic(err)
+	}
+	return b.Summary()
+}
+
 func (s *FileSet) WithHaveSequence(startSeq int64, fn Iterator) {
 	l.Debugf("%s WithHaveSequence(%v)", s.folder, startSeq)
 	if err := s.db.withHaveSequence([]byte(s.folder), startSeq, nativeFileIterator(fn)); err != nil && !backend.IsClosed(err) {
@@ -276,6 +331,17 @@ func (s *FileSet) GetGlobal(file string) (protocol.FileInfo, bool) {
 	return f, true
 }
 
+// NeedsFile returns true if the local device needs the global version of the
+// file.
+func (s *FileSet) NeedsFile(file string) bool {
+	global, ok := s.GetGlobalTruncated(file)
+	if !ok {
+		return false
+	}
+	local, haveLocal := s.Get(protocol.LocalDeviceID, file)
+	return need(global, haveLocal, local.Version)
+}
+
 func (s *FileSet) GetGlobalTruncated(file string) (FileInfoTruncated, bool) {
 	fi, ok, err := s.db.getGlobalDirty([]byte(s.folder), []byte(osutil.NormalizedFilename(file)), true)
 	if backend.IsClosed(err) {
@@ -372,6 +438,8 @@ func DropFolder(db *Lowlevel, folder string) {
 		db.dropFolder,
 		db.dropMtimes,
 		db.dropFolderMeta,
+		db.dropScanJournal,
+		db.dropAuditLog,
 		db.folderIdx.Delete,
 	}
 	for _, drop := range droppers {
@@ -537,6 +537,12 @@ func TestNeed(t *testing.T) {
 	if fmt.Sprint(need) != fmt.Sprint(shouldNeed) {
 		t.Errorf("Need incorrect;\n%v !=\n%v", need, shouldNeed)
 	}
+
+	for name, needed := range map[string]bool{"a": false, "b": true, "c": true, "d": false, "e": true, "f": false} {
+		if m.NeedsFile(name) != needed {
+			t.Errorf("NeedsFile(%q) != %v", name, needed)
+		}
+	}
 }
 
 func TestSequence(t *testing.T) {
@@ -1458,7 +1464,197 @@ func TestIgnoreAfterReceiveOnly(t *testing.T) {
 	}
 }
 
+func TestFileHistory(t *testing.T) {
+	ldb := db.NewLowlevel(backend.OpenMemory())
+	defer ldb.Close()
+
+	s := db.NewFileSet("test", fs.NewFilesystem(fs.FilesystemTypeBasic, "."), ldb)
+
+	file := protocol.FileInfo{Name: "foo", Type: protocol.FileInfoTypeDirectory, Version: protocol.Vector{}.Update(myID)}
+	s.Update(protocol.LocalDeviceID, []protocol.FileInfo{file})
+	if h, err := s.FileHistory("foo"); err != nil || len(h) != 0 {
+		t.Fatalf("expected no history while it's off, got %v, %v", h, err)
+	}
+
+	s.SetFileHistory(2)
+	for i := 0; i < 3; i++ {
+		file.Version = file.Version.Update(remoteDevice0.Short())
+		file.Deleted = i == 2
+		s.Update(remoteDevice0, []protocol.FileInfo{file})
+	}
+	// An older version doesn't become the global version.
+	s.Update(remoteDevice1, []protocol.FileInfo{{Name: "foo", Type: protocol.FileInfoTypeDirectory, Version: protocol.Vector{}.Update(myID)}})
+
+	h, err := s.FileHistory("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h) != 2 {
+		t.Fatalf("expected the last two versions, got %d", len(h))
+	}
+	if !h[1].File.IsDeleted() || h[0].File.IsDeleted() || !h[1].File.Version.Equal(file.Version) {
+		t.Errorf("expected the deletion last, got %v", h)
+	}
+	if protocol.DeviceIDFromBytes(h[1].Device) != remoteDevice0 || h[0].Time > h[1].Time {
+		t.Errorf("expected the deletion by %v last, got %v", remoteDevice0, h)
+	}
+}
+
 func replace(fs *db.FileSet, device protocol.DeviceID, files []protocol.FileInfo) {
 	fs.Drop(device)
 	fs.Update(device, files)
 }
+
+func TestAuditLog(t *testing.T) {
+	ldb := db.NewLowlevel(backend.OpenMemory())
+	defer ldb.Close()
+
+	a, err := db.NewAuditLog(ldb, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		entry := db.AuditEntry{Action: "modified", Path: fmt.Sprintf("file%d", i), ModifiedBy: remoteDevice0.Short()}
+		if err := a.Append([]db.AuditEntry{entry}, 3); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := a.Entries(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[0].ID != 3 || entries[0].Path != "file2" || entries[2].ID != 5 {
+		t.Fatalf("expected the latest three entries, got %v", entries)
+	}
+	if entries, _ := a.Entries(3, 1); len(entries) != 1 || entries[0].ID != 4 {
+		t.Errorf("expected the entry after 3, got %v", entries)
+	}
+
+	// Reopened, the log continues where it was.
+	a, err = db.NewAuditLog(ldb, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Append([]db.AuditEntry{{Action: "deleted", Path: "file0"}}, 3); err != nil {
+		t.Fatal(err)
+	}
+	entries, err = a.Entries(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[0].ID != 4 || entries[2].ID != 6 || entries[2].Action != "deleted" {
+		t.Errorf("expected entries 4 to 6, got %v", entries)
+	}
+}
+
+func TestDropTombstones(t *testing.T) {
+	ldb := db.NewLowlevel(backend.OpenMemory())
+	defer ldb.Close()
+
+	s := db.NewFileSet("test", fs.NewFilesystem(fs.FilesystemTypeBasic, "."), ldb)
+
+	deleted := protocol.FileInfo{Name: "deleted", Deleted: true, Version: protocol.Vector{}.Update(myID)}
+	stale := protocol.FileInfo{Name: "stale", Deleted: true, Version: protocol.Vector{}.Update(myID).Update(myID)}
+	kept := protocol.FileInfo{Name: "kept", Type: protocol.FileInfoTypeDirectory, Version: protocol.Vector{}.Update(myID)}
+	s.Update(protocol.LocalDeviceID, []protocol.FileInfo{deleted, stale, kept})
+	s.Update(remoteDevice0, []protocol.FileInfo{deleted, {Name: "stale", Type: protocol.FileInfoTypeDirectory, Version: protocol.Vector{}.Update(myID)}, kept})
+
+	devices := []protocol.DeviceID{remoteDevice0, remoteDevice1}
+	if n, err := s.DropTombstones(devices, 0); err != nil || n != 0 {
+		t.Fatalf("expected nothing dropped before every device has the deletion, got %d, %v", n, err)
+	}
+
+	// Once all devices have it, the deletion is only dropped after the
+	// given time since.
+	s.Update(remoteDevice1, []protocol.FileInfo{deleted, stale, kept})
+	if n, err := s.DropTombstones(devices, time.Hour); err != nil || n != 0 {
+		t.Fatalf("expected nothing dropped yet, got %d, %v", n, err)
+	}
+	if n, err := s.DropTombstones(devices, 0); err != nil || n != 1 {
+		t.Fatalf("expected one dropped, got %d, %v", n, err)
+	}
+
+	if _, ok := s.Get(protocol.LocalDeviceID, "deleted"); ok {
+		t.Error("expected the local deletion to be gone")
+	}
+	if _, ok := s.GetGlobal("deleted"); ok {
+		t.Error("expected the global deletion to be gone")
+	}
+	if _, ok := s.Get(protocol.LocalDeviceID, "stale"); !ok {
+		t.Error("expected the deletion of a file a device has an old version of to be kept")
+	}
+	if c := s.LocalSize(); c.Deleted != 1 || c.Directories != 1 {
+		t.Errorf("expected one deleted file and one directory locally, got %+v", c)
+	}
+	if c := s.GlobalSize(); c.Deleted != 1 || c.Directories != 1 {
+		t.Errorf("expected one deleted file and one directory globally, got %+v", c)
+	}
+	n := 0
+	s.WithHaveSequence(0, func(db.FileIntf) bool {
+		n++
+		return true
+	})
+	if n != 2 {
+		t.Errorf("expected two files in the sequence index, got %d", n)
+	}
+}
+
+func TestIndexSnapshot(t *testing.T) {
+	ldb := db.NewLowlevel(backend.OpenMemory())
+	defer ldb.Close()
+
+	s := db.NewFileSet("test", fs.NewFilesystem(fs.FilesystemTypeBasic, "."), ldb)
+
+	a := protocol.FileInfo{Name: "a", Type: protocol.FileInfoTypeDirectory, Version: protocol.Vector{}.Update(myID)}
+	b := protocol.FileInfo{Name: "b", Type: protocol.FileInfoTypeDirectory, Version: protocol.Vector{}.Update(myID)}
+	c := protocol.FileInfo{Name: "c", Type: protocol.FileInfoTypeDirectory, Version: protocol.Vector{}.Update(myID)}
+	s.Update(protocol.LocalDeviceID, []protocol.FileInfo{a, b, c})
+
+	snap, err := s.CreateIndexSnapshot("before")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.Files != 3 {
+		t.Errorf("expected three files in the snapshot, got %d", snap.Files)
+	}
+	if _, err := s.CreateIndexSnapshot("before"); err == nil {
+		t.Error("expected an error creating a snapshot under an existing name")
+	}
+
+	b.Version = b.Version.Update(myID)
+	b.Deleted = true
+	c.SetIgnored(myID)
+	d := protocol.FileInfo{Name: "d", Type: protocol.FileInfoTypeDirectory, Version: protocol.Vector{}.Update(myID)}
+	s.Update(protocol.LocalDeviceID, []protocol.FileInfo{b, c, d})
+
+	changes, err := s.DiffIndexSnapshot("before")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actions []string
+	for _, change := range changes {
+		name := change.Snapshot.Name
+		if name == "" {
+			name = change.Current.Name
+		}
+		actions = append(actions, name+" "+change.Action())
+	}
+	if exp := []string{"b deleted", "c modified", "d added"}; fmt.Sprint(actions) != fmt.Sprint(exp) {
+		t.Errorf("expected changes %v, got %v", exp, actions)
+	}
+
+	snaps, err := s.IndexSnapshots()
+	if err != nil || len(snaps) != 1 || snaps[0].Name != "before" {
+		t.Fatalf("expected the one snapshot, got %v, %v", snaps, err)
+	}
+	if err := s.DropIndexSnapshot("before"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.DiffIndexSnapshot("before"); err == nil {
+		t.Error("expected an error comparing against a dropped snapshot")
+	}
+	if snaps, _ := s.IndexSnapshots(); len(snaps) != 0 {
+		t.Errorf("expected no snapshots left, got %v", snaps)
+	}
+}
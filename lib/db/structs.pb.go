@@ -239,63 +239,201 @@ func (m *CountsSet) XXX_DiscardUnknown() {
 
 var xxx_messageInfo_CountsSet proto.InternalMessageInfo
 
+// A global version of a file, with the device it came from and when it
+// became the global version.
+type FileHistoryEntry struct {
+	Device []byte            `protobuf:"bytes,1,opt,name=device,proto3" json:"device,omitempty"`
+	Time   int64             `protobuf:"varint,2,opt,name=time,proto3" json:"time,omitempty"`
+	File   FileInfoTruncated `protobuf:"bytes,3,opt,name=file,proto3" json:"file"`
+}
+
+func (m *FileHistoryEntry) Reset()         { *m = FileHistoryEntry{} }
+func (m *FileHistoryEntry) String() string { return proto.CompactTextString(m) }
+func (*FileHistoryEntry) ProtoMessage()    {}
+func (*FileHistoryEntry) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e774e8f5f348d14d, []int{5}
+}
+func (m *FileHistoryEntry) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *FileHistoryEntry) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_FileHistoryEntry.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *FileHistoryEntry) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FileHistoryEntry.Merge(m, src)
+}
+func (m *FileHistoryEntry) XXX_Size() int {
+	return m.ProtoSize()
+}
+func (m *FileHistoryEntry) XXX_DiscardUnknown() {
+	xxx_messageInfo_FileHistoryEntry.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FileHistoryEntry proto.InternalMessageInfo
+
+// A change to a file, as recorded in the audit log of a folder.
+type AuditEntry struct {
+	ID         int64                                               `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Time       int64                                               `protobuf:"varint,2,opt,name=time,proto3" json:"time,omitempty"`
+	Remote     bool                                                `protobuf:"varint,3,opt,name=remote,proto3" json:"remote,omitempty"`
+	Action     string                                              `protobuf:"bytes,4,opt,name=action,proto3" json:"action,omitempty"`
+	Type       protocol.FileInfoType                               `protobuf:"varint,5,opt,name=type,proto3,enum=protocol.FileInfoType" json:"type,omitempty"`
+	Path       string                                              `protobuf:"bytes,6,opt,name=path,proto3" json:"path,omitempty"`
+	ModifiedBy github_com_syncthing_syncthing_lib_protocol.ShortID `protobuf:"varint,7,opt,name=modified_by,json=modifiedBy,proto3,customtype=github.com/syncthing/syncthing/lib/protocol.ShortID" json:"modified_by"`
+	Version    protocol.Vector                                     `protobuf:"bytes,8,opt,name=version,proto3" json:"version"`
+}
+
+func (m *AuditEntry) Reset()         { *m = AuditEntry{} }
+func (m *AuditEntry) String() string { return proto.CompactTextString(m) }
+func (*AuditEntry) ProtoMessage()    {}
+func (*AuditEntry) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e774e8f5f348d14d, []int{6}
+}
+func (m *AuditEntry) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *AuditEntry) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_AuditEntry.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *AuditEntry) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AuditEntry.Merge(m, src)
+}
+func (m *AuditEntry) XXX_Size() int {
+	return m.ProtoSize()
+}
+func (m *AuditEntry) XXX_DiscardUnknown() {
+	xxx_messageInfo_AuditEntry.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AuditEntry proto.InternalMessageInfo
+
+// A named copy of the local index of a folder.
+type IndexSnapshot struct {
+	Name  string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Time  int64  `protobuf:"varint,2,opt,name=time,proto3" json:"time,omitempty"`
+	Files int32  `protobuf:"varint,3,opt,name=files,proto3" json:"files,omitempty"`
+}
+
+func (m *IndexSnapshot) Reset()         { *m = IndexSnapshot{} }
+func (m *IndexSnapshot) String() string { return proto.CompactTextString(m) }
+func (*IndexSnapshot) ProtoMessage()    {}
+func (*IndexSnapshot) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e774e8f5f348d14d, []int{7}
+}
+func (m *IndexSnapshot) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *IndexSnapshot) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_IndexSnapshot.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *IndexSnapshot) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_IndexSnapshot.Merge(m, src)
+}
+func (m *IndexSnapshot) XXX_Size() int {
+	return m.ProtoSize()
+}
+func (m *IndexSnapshot) XXX_DiscardUnknown() {
+	xxx_messageInfo_IndexSnapshot.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_IndexSnapshot proto.InternalMessageInfo
+
 func init() {
 	proto.RegisterType((*FileVersion)(nil), "db.FileVersion")
 	proto.RegisterType((*VersionList)(nil), "db.VersionList")
 	proto.RegisterType((*FileInfoTruncated)(nil), "db.FileInfoTruncated")
 	proto.RegisterType((*Counts)(nil), "db.Counts")
 	proto.RegisterType((*CountsSet)(nil), "db.CountsSet")
+	proto.RegisterType((*FileHistoryEntry)(nil), "db.FileHistoryEntry")
+	proto.RegisterType((*AuditEntry)(nil), "db.AuditEntry")
+	proto.RegisterType((*IndexSnapshot)(nil), "db.IndexSnapshot")
 }
 
 func init() { proto.RegisterFile("structs.proto", fileDescriptor_e774e8f5f348d14d) }
 
 var fileDescriptor_e774e8f5f348d14d = []byte{
-	// 705 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x53, 0x4d, 0x6f, 0xda, 0x4a,
-	0x14, 0xc5, 0x01, 0x13, 0xb8, 0x40, 0x5e, 0x32, 0x2f, 0x8a, 0x2c, 0xa4, 0x67, 0x2c, 0x9e, 0x2a,
-	0x59, 0x5d, 0x40, 0x9b, 0xec, 0xda, 0x1d, 0x8d, 0x22, 0x21, 0x55, 0x6d, 0x35, 0x44, 0x59, 0x55,
-	0x42, 0xfe, 0x18, 0xc8, 0x28, 0xc6, 0x43, 0x3c, 0xe3, 0x44, 0x64, 0xdd, 0x1f, 0xd0, 0x65, 0x97,
-	0xf9, 0x39, 0x59, 0x66, 0x59, 0x75, 0x81, 0x5a, 0xe8, 0xa2, 0x3f, 0xa3, 0x9a, 0x19, 0x63, 0x9c,
-	0xae, 0xba, 0xbb, 0xe7, 0xdc, 0x3b, 0x33, 0xf7, 0x9e, 0x7b, 0x06, 0x5a, 0x5c, 0x24, 0x69, 0x20,
-	0x78, 0x6f, 0x9e, 0x30, 0xc1, 0xd0, 0x4e, 0xe8, 0xb7, 0xff, 0x4f, 0xc8, 0x9c, 0xf1, 0xbe, 0x22,
-	0xfc, 0x74, 0xd2, 0x9f, 0xb2, 0x29, 0x53, 0x40, 0x45, 0xba, 0xb0, 0x7d, 0x14, 0x51, 0x5f, 0x97,
-	0x04, 0x2c, 0xea, 0xfb, 0x64, 0xae, 0xf9, 0xee, 0x35, 0x34, 0xce, 0x68, 0x44, 0x2e, 0x48, 0xc2,
-	0x29, 0x8b, 0xd1, 0x0b, 0xd8, 0xbd, 0xd1, 0xa1, 0x65, 0x38, 0x86, 0xdb, 0x38, 0xde, 0xef, 0x6d,
-	0x0e, 0xf5, 0x2e, 0x48, 0x20, 0x58, 0x32, 0xa8, 0x3c, 0x2c, 0x3b, 0x25, 0xbc, 0x29, 0x43, 0x47,
-	0x50, 0x0d, 0xc9, 0x0d, 0x0d, 0x88, 0xb5, 0xe3, 0x18, 0x6e, 0x13, 0x67, 0x08, 0x59, 0xb0, 0x4b,
-	0xe3, 0x1b, 0x2f, 0xa2, 0xa1, 0x55, 0x76, 0x0c, 0xb7, 0x86, 0x37, 0xb0, 0x7b, 0x06, 0x8d, 0xec,
-	0xb9, 0xb7, 0x94, 0x0b, 0xf4, 0x12, 0x6a, 0xd9, 0x5d, 0xdc, 0x32, 0x9c, 0xb2, 0xdb, 0x38, 0xfe,
-	0xa7, 0x17, 0xfa, 0xbd, 0x42, 0x57, 0xd9, 0x93, 0x79, 0xd9, 0xab, 0xca, 0x97, 0xfb, 0x4e, 0xa9,
-	0xfb, 0xc9, 0x84, 0x03, 0x59, 0x35, 0x8c, 0x27, 0xec, 0x3c, 0x49, 0xe3, 0xc0, 0x13, 0x24, 0x44,
-	0x08, 0x2a, 0xb1, 0x37, 0x23, 0xaa, 0xfd, 0x3a, 0x56, 0xb1, 0xe4, 0x38, 0xbd, 0x23, 0xaa, 0x91,
-	0x32, 0x56, 0x31, 0xfa, 0x0f, 0x60, 0xc6, 0x42, 0x3a, 0xa1, 0x24, 0x1c, 0x73, 0xcb, 0x54, 0x99,
-	0xfa, 0x86, 0x19, 0xa1, 0x8f, 0xd0, 0xc8, 0xd3, 0xfe, 0xc2, 0x6a, 0x3a, 0x86, 0x5b, 0x19, 0xbc,
-	0x96, 0x7d, 0x7c, 0x5b, 0x76, 0x4e, 0xa6, 0x54, 0x5c, 0xa6, 0x7e, 0x2f, 0x60, 0xb3, 0x3e, 0x5f,
-	0xc4, 0x81, 0xb8, 0xa4, 0xf1, 0xb4, 0x10, 0x15, 0xb5, 0xee, 0x8d, 0x2e, 0x59, 0x22, 0x86, 0xa7,
-	0x38, 0x7f, 0x6e, 0xb0, 0x28, 0xca, 0x5c, 0xff, 0x3b, 0x99, 0xdb, 0x50, 0xe3, 0xe4, 0x3a, 0x25,
-	0x71, 0x40, 0x2c, 0x50, 0xcd, 0xe6, 0x18, 0x3d, 0x83, 0x3d, 0xbe, 0x98, 0x45, 0x34, 0xbe, 0x1a,
-	0x0b, 0x2f, 0x99, 0x12, 0x61, 0x1d, 0xa8, 0xe1, 0x5b, 0x19, 0x7b, 0xae, 0x48, 0xf4, 0x1c, 0x2a,
-	0x62, 0x31, 0xd7, 0x7b, 0xda, 0x3b, 0x3e, 0xda, 0xbe, 0x98, 0x8b, 0xb8, 0x98, 0x13, 0xac, 0x6a,
-	0x90, 0x03, 0x8d, 0x39, 0x49, 0x66, 0x94, 0xeb, 0xbd, 0x54, 0x1c, 0xc3, 0x6d, 0xe1, 0x22, 0x85,
-	0x3a, 0x05, 0x81, 0x62, 0x6e, 0x35, 0x1c, 0xc3, 0x35, 0xb7, 0x33, 0xbe, 0xe3, 0xa8, 0x0f, 0xe0,
-	0x47, 0x2c, 0xb8, 0x1a, 0x2b, 0xe9, 0x5b, 0x32, 0x3f, 0xd8, 0x5f, 0x2d, 0x3b, 0x4d, 0xec, 0xdd,
-	0x0e, 0x64, 0x62, 0x44, 0xef, 0x08, 0xae, 0xfb, 0x9b, 0x10, 0xed, 0x43, 0x79, 0x4a, 0x43, 0x0b,
-	0xa9, 0x9b, 0x64, 0x28, 0x99, 0x94, 0x86, 0xd6, 0xbf, 0x9a, 0x49, 0x69, 0x28, 0xfb, 0x8a, 0x58,
-	0xe0, 0x45, 0xe3, 0x49, 0xe4, 0x4d, 0xb9, 0xf5, 0x6b, 0x57, 0x35, 0x06, 0x8a, 0x3b, 0x93, 0x94,
-	0xf4, 0x5d, 0x48, 0x22, 0x22, 0x48, 0x68, 0x55, 0xb5, 0xef, 0x32, 0x88, 0xdc, 0xad, 0x23, 0xe5,
-	0xb1, 0xda, 0x60, 0x6f, 0xb5, 0xec, 0x00, 0xf6, 0x6e, 0x87, 0x9a, 0xcd, 0x1d, 0x2a, 0x05, 0x8d,
-	0xd9, 0xb8, 0x28, 0x40, 0x4d, 0x5d, 0xd5, 0x8a, 0xd9, 0x87, 0x2d, 0x99, 0xd9, 0xf0, 0xa7, 0x01,
-	0xd5, 0x37, 0x2c, 0x8d, 0x05, 0x47, 0x87, 0x60, 0x4e, 0x68, 0x44, 0xb8, 0x32, 0x9f, 0x89, 0x35,
-	0x90, 0x3d, 0x87, 0x34, 0x51, 0x5b, 0xa5, 0x84, 0x2b, 0xf9, 0x4d, 0x5c, 0xa4, 0xd4, 0x72, 0xf5,
-	0xaa, 0xb8, 0xf2, 0xa8, 0x89, 0x73, 0x5c, 0x9c, 0xa7, 0xa2, 0x52, 0xf9, 0x3c, 0x87, 0x60, 0xfa,
-	0x0b, 0x41, 0x36, 0xe6, 0xd5, 0xe0, 0x89, 0x51, 0xaa, 0x7f, 0x18, 0xa5, 0x0d, 0x35, 0xfd, 0x3b,
-	0x87, 0xa7, 0xca, 0x22, 0x4d, 0x9c, 0x63, 0x64, 0x43, 0x41, 0x45, 0xb5, 0x84, 0x27, 0xba, 0x76,
-	0xdf, 0x43, 0x5d, 0x4f, 0x39, 0x22, 0x02, 0xb9, 0x50, 0x0d, 0x14, 0xc8, 0x7e, 0x2c, 0xc8, 0x1f,
-	0xab, 0xd3, 0x99, 0x71, 0xb3, 0xbc, 0x6c, 0x3f, 0x48, 0x88, 0xfc, 0x99, 0x6a, 0xf0, 0x32, 0xde,
-	0xc0, 0x81, 0xf3, 0xf0, 0xc3, 0x2e, 0x3d, 0xac, 0x6c, 0xe3, 0x71, 0x65, 0x1b, 0xdf, 0x57, 0x76,
-	0xe9, 0xf3, 0xda, 0x2e, 0xdd, 0xaf, 0x6d, 0xe3, 0x71, 0x6d, 0x97, 0xbe, 0xae, 0xed, 0x92, 0x5f,
-	0x55, 0x0e, 0x3d, 0xf9, 0x1d, 0x00, 0x00, 0xff, 0xff, 0xb0, 0x46, 0x07, 0x1d, 0xf4, 0x04, 0x00,
-	0x00,
+	// 857 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x54, 0xcd, 0x6e, 0x23, 0x45,
+	0x10, 0xf6, 0xd8, 0x33, 0x8e, 0x5d, 0x8e, 0x43, 0xb6, 0x59, 0xa2, 0x51, 0x24, 0xc6, 0x23, 0x23,
+	0xa4, 0x11, 0x07, 0x1b, 0xb2, 0x37, 0x38, 0x61, 0x42, 0x84, 0x25, 0xfe, 0xd4, 0x59, 0xed, 0x09,
+	0xc9, 0x9a, 0x9f, 0xb6, 0xdd, 0xda, 0xf1, 0xb4, 0x77, 0xba, 0x27, 0xcb, 0xec, 0x99, 0x07, 0xe0,
+	0xc8, 0x71, 0x1f, 0x83, 0x47, 0xc8, 0x71, 0x8f, 0x88, 0x83, 0x05, 0x0e, 0x07, 0x1e, 0x03, 0x75,
+	0xcd, 0x4f, 0x26, 0x01, 0xa4, 0x5c, 0xf6, 0x56, 0xdf, 0xd7, 0x35, 0x53, 0xd5, 0x5f, 0x7f, 0x55,
+	0x30, 0x94, 0x2a, 0xcd, 0x42, 0x25, 0x27, 0xdb, 0x54, 0x28, 0x41, 0xda, 0x51, 0x70, 0xfa, 0x41,
+	0xca, 0xb6, 0x42, 0x4e, 0x91, 0x08, 0xb2, 0xe5, 0x74, 0x25, 0x56, 0x02, 0x01, 0x46, 0x45, 0xe2,
+	0xe9, 0x49, 0xcc, 0x83, 0x22, 0x25, 0x14, 0xf1, 0x34, 0x60, 0xdb, 0x82, 0x1f, 0xbf, 0x80, 0xc1,
+	0x05, 0x8f, 0xd9, 0x33, 0x96, 0x4a, 0x2e, 0x12, 0xf2, 0x31, 0x1c, 0x5c, 0x15, 0xa1, 0x6d, 0xb8,
+	0x86, 0x37, 0x38, 0x3b, 0x9e, 0x54, 0x1f, 0x4d, 0x9e, 0xb1, 0x50, 0x89, 0x74, 0x66, 0x5e, 0xef,
+	0x46, 0x2d, 0x5a, 0xa5, 0x91, 0x13, 0xe8, 0x46, 0xec, 0x8a, 0x87, 0xcc, 0x6e, 0xbb, 0x86, 0x77,
+	0x48, 0x4b, 0x44, 0x6c, 0x38, 0xe0, 0xc9, 0x95, 0x1f, 0xf3, 0xc8, 0xee, 0xb8, 0x86, 0xd7, 0xa3,
+	0x15, 0x1c, 0x5f, 0xc0, 0xa0, 0x2c, 0xf7, 0x35, 0x97, 0x8a, 0x7c, 0x02, 0xbd, 0xf2, 0x5f, 0xd2,
+	0x36, 0xdc, 0x8e, 0x37, 0x38, 0x7b, 0x67, 0x12, 0x05, 0x93, 0x46, 0x57, 0x65, 0xc9, 0x3a, 0xed,
+	0x53, 0xf3, 0x97, 0xd7, 0xa3, 0xd6, 0xf8, 0x27, 0x0b, 0x1e, 0xe9, 0xac, 0x79, 0xb2, 0x14, 0x4f,
+	0xd3, 0x2c, 0x09, 0x7d, 0xc5, 0x22, 0x42, 0xc0, 0x4c, 0xfc, 0x0d, 0xc3, 0xf6, 0xfb, 0x14, 0x63,
+	0xcd, 0x49, 0xfe, 0x8a, 0x61, 0x23, 0x1d, 0x8a, 0x31, 0x79, 0x1f, 0x60, 0x23, 0x22, 0xbe, 0xe4,
+	0x2c, 0x5a, 0x48, 0xdb, 0xc2, 0x93, 0x7e, 0xc5, 0x5c, 0x92, 0x1f, 0x60, 0x50, 0x1f, 0x07, 0xb9,
+	0x7d, 0xe8, 0x1a, 0x9e, 0x39, 0xfb, 0x4c, 0xf7, 0xf1, 0xfb, 0x6e, 0xf4, 0x64, 0xc5, 0xd5, 0x3a,
+	0x0b, 0x26, 0xa1, 0xd8, 0x4c, 0x65, 0x9e, 0x84, 0x6a, 0xcd, 0x93, 0x55, 0x23, 0x6a, 0x6a, 0x3d,
+	0xb9, 0x5c, 0x8b, 0x54, 0xcd, 0xcf, 0x69, 0x5d, 0x6e, 0x96, 0x37, 0x65, 0xee, 0x3f, 0x4c, 0xe6,
+	0x53, 0xe8, 0x49, 0xf6, 0x22, 0x63, 0x49, 0xc8, 0x6c, 0xc0, 0x66, 0x6b, 0x4c, 0x3e, 0x84, 0x23,
+	0x99, 0x6f, 0x62, 0x9e, 0x3c, 0x5f, 0x28, 0x3f, 0x5d, 0x31, 0x65, 0x3f, 0xc2, 0xcb, 0x0f, 0x4b,
+	0xf6, 0x29, 0x92, 0xe4, 0x23, 0x30, 0x55, 0xbe, 0x2d, 0xde, 0xe9, 0xe8, 0xec, 0xe4, 0xb6, 0x62,
+	0x2d, 0x62, 0xbe, 0x65, 0x14, 0x73, 0x88, 0x0b, 0x83, 0x2d, 0x4b, 0x37, 0x5c, 0x16, 0xef, 0x62,
+	0xba, 0x86, 0x37, 0xa4, 0x4d, 0x8a, 0x8c, 0x1a, 0x02, 0x25, 0xd2, 0x1e, 0xb8, 0x86, 0x67, 0xdd,
+	0xde, 0xf1, 0x5b, 0x49, 0xa6, 0x00, 0x41, 0x2c, 0xc2, 0xe7, 0x0b, 0x94, 0x7e, 0xa8, 0xcf, 0x67,
+	0xc7, 0xfb, 0xdd, 0xe8, 0x90, 0xfa, 0x2f, 0x67, 0xfa, 0xe0, 0x92, 0xbf, 0x62, 0xb4, 0x1f, 0x54,
+	0x21, 0x39, 0x86, 0xce, 0x8a, 0x47, 0x36, 0xc1, 0x3f, 0xe9, 0x50, 0x33, 0x19, 0x8f, 0xec, 0x77,
+	0x0b, 0x26, 0xe3, 0x91, 0xee, 0x2b, 0x16, 0xa1, 0x1f, 0x2f, 0x96, 0xb1, 0xbf, 0x92, 0xf6, 0xdf,
+	0x07, 0xd8, 0x18, 0x20, 0x77, 0xa1, 0x29, 0xed, 0xbb, 0x88, 0xc5, 0x4c, 0xb1, 0xc8, 0xee, 0x16,
+	0xbe, 0x2b, 0x21, 0xf1, 0x6e, 0x1d, 0xa9, 0x3f, 0xeb, 0xcd, 0x8e, 0xf6, 0xbb, 0x11, 0x50, 0xff,
+	0xe5, 0xbc, 0x60, 0x6b, 0x87, 0x6a, 0x41, 0x13, 0xb1, 0x68, 0x0a, 0xd0, 0xc3, 0x5f, 0x0d, 0x13,
+	0xf1, 0xfd, 0x2d, 0x59, 0xda, 0xf0, 0x2f, 0x03, 0xba, 0x5f, 0x88, 0x2c, 0x51, 0x92, 0x3c, 0x06,
+	0x6b, 0xc9, 0x63, 0x26, 0xd1, 0x7c, 0x16, 0x2d, 0x80, 0xee, 0x39, 0xe2, 0x29, 0xbe, 0x2a, 0x67,
+	0x12, 0xe5, 0xb7, 0x68, 0x93, 0xc2, 0xc7, 0x2d, 0x9e, 0x4a, 0xa2, 0x47, 0x2d, 0x5a, 0xe3, 0xe6,
+	0x7d, 0x4c, 0x3c, 0xaa, 0xef, 0xf3, 0x18, 0xac, 0x20, 0x57, 0xac, 0x32, 0x6f, 0x01, 0xee, 0x18,
+	0xa5, 0x7b, 0xcf, 0x28, 0xa7, 0xd0, 0x2b, 0xa6, 0x73, 0x7e, 0x8e, 0x16, 0x39, 0xa4, 0x35, 0x26,
+	0x0e, 0x34, 0x54, 0xb4, 0xc9, 0x7d, 0x5d, 0xc7, 0xdf, 0x41, 0xbf, 0xb8, 0xe5, 0x25, 0x53, 0xc4,
+	0x83, 0x6e, 0x88, 0xa0, 0x9c, 0x58, 0xd0, 0x13, 0x5b, 0x1c, 0x97, 0xc6, 0x2d, 0xcf, 0x75, 0xfb,
+	0x61, 0xca, 0xf4, 0x64, 0xe2, 0xc5, 0x3b, 0xb4, 0x82, 0x63, 0x01, 0xc7, 0xda, 0x78, 0x5f, 0x71,
+	0xa9, 0x44, 0x9a, 0x7f, 0x99, 0xa8, 0x34, 0x6f, 0x2c, 0x13, 0xe3, 0xce, 0x32, 0x21, 0x60, 0x2a,
+	0xbe, 0x61, 0xe5, 0x2f, 0x30, 0x26, 0x53, 0x30, 0xb5, 0xbe, 0x28, 0xd8, 0xe0, 0xec, 0xbd, 0x6a,
+	0x67, 0xdc, 0xd9, 0x06, 0x65, 0x33, 0x98, 0x38, 0xfe, 0xb5, 0x0d, 0xf0, 0x79, 0x16, 0x71, 0x55,
+	0xd5, 0x6a, 0xf3, 0x08, 0xeb, 0x74, 0x66, 0xdd, 0xfd, 0x6e, 0xd4, 0x9e, 0x9f, 0xd3, 0x36, 0x8f,
+	0xfe, 0xb3, 0xd6, 0x09, 0x74, 0x53, 0xb6, 0x11, 0x8a, 0x95, 0xbb, 0xac, 0x44, 0x9a, 0xf7, 0x43,
+	0xa5, 0xc7, 0xd8, 0xc4, 0x89, 0x2b, 0x51, 0x3d, 0x6a, 0xd6, 0x03, 0x46, 0x8d, 0x80, 0xb9, 0xf5,
+	0xd5, 0x1a, 0x1f, 0xab, 0x4f, 0x31, 0xbe, 0xbf, 0x7d, 0x0e, 0xde, 0xda, 0xf6, 0xe9, 0x3d, 0x68,
+	0xfb, 0x8c, 0xbf, 0x81, 0xe1, 0x3c, 0x89, 0xd8, 0x8f, 0x97, 0x89, 0xbf, 0x95, 0x6b, 0xa1, 0xfe,
+	0x6f, 0xcb, 0xfe, 0x4b, 0xb8, 0x7a, 0x22, 0x3a, 0x8d, 0x89, 0x98, 0xb9, 0xd7, 0x7f, 0x3a, 0xad,
+	0xeb, 0xbd, 0x63, 0xbc, 0xd9, 0x3b, 0xc6, 0x1f, 0x7b, 0xa7, 0xf5, 0xf3, 0x8d, 0xd3, 0x7a, 0x7d,
+	0xe3, 0x18, 0x6f, 0x6e, 0x9c, 0xd6, 0x6f, 0x37, 0x4e, 0x2b, 0xe8, 0x62, 0x43, 0x4f, 0xfe, 0x19,
+	0x00, 0x5d, 0xfd, 0xf7, 0x49, 0xef, 0x06, 0x00, 0x00,
 }
 
 func (m *FileVersion) Marshal() (dAtA []byte, err error) {
@@ -636,6 +774,168 @@ func (m *CountsSet) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	return len(dAtA) - i, nil
 }
 
+func (m *FileHistoryEntry) Marshal() (dAtA []byte, err error) {
+	size := m.ProtoSize()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *FileHistoryEntry) MarshalTo(dAtA []byte) (int, error) {
+	size := m.ProtoSize()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *FileHistoryEntry) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	{
+		size, err := m.File.MarshalToSizedBuffer(dAtA[:i])
+		if err != nil {
+			return 0, err
+		}
+		i -= size
+		i = encodeVarintStructs(dAtA, i, uint64(size))
+	}
+	i--
+	dAtA[i] = 0x1a
+	if m.Time != 0 {
+		i = encodeVarintStructs(dAtA, i, uint64(m.Time))
+		i--
+		dAtA[i] = 0x10
+	}
+	if len(m.Device) > 0 {
+		i -= len(m.Device)
+		copy(dAtA[i:], m.Device)
+		i = encodeVarintStructs(dAtA, i, uint64(len(m.Device)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *AuditEntry) Marshal() (dAtA []byte, err error) {
+	size := m.ProtoSize()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *AuditEntry) MarshalTo(dAtA []byte) (int, error) {
+	size := m.ProtoSize()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *AuditEntry) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	{
+		size, err := m.Version.MarshalToSizedBuffer(dAtA[:i])
+		if err != nil {
+			return 0, err
+		}
+		i -= size
+		i = encodeVarintStructs(dAtA, i, uint64(size))
+	}
+	i--
+	dAtA[i] = 0x42
+	if m.ModifiedBy != 0 {
+		i = encodeVarintStructs(dAtA, i, uint64(m.ModifiedBy))
+		i--
+		dAtA[i] = 0x38
+	}
+	if len(m.Path) > 0 {
+		i -= len(m.Path)
+		copy(dAtA[i:], m.Path)
+		i = encodeVarintStructs(dAtA, i, uint64(len(m.Path)))
+		i--
+		dAtA[i] = 0x32
+	}
+	if m.Type != 0 {
+		i = encodeVarintStructs(dAtA, i, uint64(m.Type))
+		i--
+		dAtA[i] = 0x28
+	}
+	if len(m.Action) > 0 {
+		i -= len(m.Action)
+		copy(dAtA[i:], m.Action)
+		i = encodeVarintStructs(dAtA, i, uint64(len(m.Action)))
+		i--
+		dAtA[i] = 0x22
+	}
+	if m.Remote {
+		i--
+		if m.Remote {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x18
+	}
+	if m.Time != 0 {
+		i = encodeVarintStructs(dAtA, i, uint64(m.Time))
+		i--
+		dAtA[i] = 0x10
+	}
+	if m.ID != 0 {
+		i = encodeVarintStructs(dAtA, i, uint64(m.ID))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *IndexSnapshot) Marshal() (dAtA []byte, err error) {
+	size := m.ProtoSize()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *IndexSnapshot) MarshalTo(dAtA []byte) (int, error) {
+	size := m.ProtoSize()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *IndexSnapshot) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.Files != 0 {
+		i = encodeVarintStructs(dAtA, i, uint64(m.Files))
+		i--
+		dAtA[i] = 0x18
+	}
+	if m.Time != 0 {
+		i = encodeVarintStructs(dAtA, i, uint64(m.Time))
+		i--
+		dAtA[i] = 0x10
+	}
+	if len(m.Name) > 0 {
+		i -= len(m.Name)
+		copy(dAtA[i:], m.Name)
+		i = encodeVarintStructs(dAtA, i, uint64(len(m.Name)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
 func encodeVarintStructs(dAtA []byte, offset int, v uint64) int {
 	offset -= sovStructs(v)
 	base := offset
@@ -793,6 +1093,77 @@ func (m *CountsSet) ProtoSize() (n int) {
 	return n
 }
 
+func (m *FileHistoryEntry) ProtoSize() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Device)
+	if l > 0 {
+		n += 1 + l + sovStructs(uint64(l))
+	}
+	if m.Time != 0 {
+		n += 1 + sovStructs(uint64(m.Time))
+	}
+	l = m.File.ProtoSize()
+	n += 1 + l + sovStructs(uint64(l))
+	return n
+}
+
+func (m *AuditEntry) ProtoSize() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.ID != 0 {
+		n += 1 + sovStructs(uint64(m.ID))
+	}
+	if m.Time != 0 {
+		n += 1 + sovStructs(uint64(m.Time))
+	}
+	if m.Remote {
+		n += 2
+	}
+	l = len(m.Action)
+	if l > 0 {
+		n += 1 + l + sovStructs(uint64(l))
+	}
+	if m.Type != 0 {
+		n += 1 + sovStructs(uint64(m.Type))
+	}
+	l = len(m.Path)
+	if l > 0 {
+		n += 1 + l + sovStructs(uint64(l))
+	}
+	if m.ModifiedBy != 0 {
+		n += 1 + sovStructs(uint64(m.ModifiedBy))
+	}
+	l = m.Version.ProtoSize()
+	n += 1 + l + sovStructs(uint64(l))
+	return n
+}
+
+func (m *IndexSnapshot) ProtoSize() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Name)
+	if l > 0 {
+		n += 1 + l + sovStructs(uint64(l))
+	}
+	if m.Time != 0 {
+		n += 1 + sovStructs(uint64(m.Time))
+	}
+	if m.Files != 0 {
+		n += 1 + sovStructs(uint64(m.Files))
+	}
+	return n
+}
+
 func sovStructs(x uint64) (n int) {
 	return (math_bits.Len64(x|1) + 6) / 7
 }
@@ -1771,6 +2142,514 @@ func (m *CountsSet) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
+func (m *FileHistoryEntry) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowStructs
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: FileHistoryEntry: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: FileHistoryEntry: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Device", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowStructs
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthStructs
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthStructs
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Device = append(m.Device[:0], dAtA[iNdEx:postIndex]...)
+			if m.Device == nil {
+				m.Device = []byte{}
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Time", wireType)
+			}
+			m.Time = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowStructs
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Time |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field File", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowStructs
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthStructs
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthStructs
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.File.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipStructs(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthStructs
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthStructs
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *AuditEntry) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowStructs
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: AuditEntry: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: AuditEntry: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ID", wireType)
+			}
+			m.ID = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowStructs
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ID |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Time", wireType)
+			}
+			m.Time = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowStructs
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Time |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Remote", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowStructs
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Remote = bool(v != 0)
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Action", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowStructs
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthStructs
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthStructs
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Action = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Type", wireType)
+			}
+			m.Type = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowStructs
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Type |= protocol.FileInfoType(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 6:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Path", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowStructs
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthStructs
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthStructs
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Path = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ModifiedBy", wireType)
+			}
+			m.ModifiedBy = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowStructs
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ModifiedBy |= github_com_syncthing_syncthing_lib_protocol.ShortID(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 8:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Version", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowStructs
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthStructs
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthStructs
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.Version.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipStructs(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthStructs
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthStructs
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *IndexSnapshot) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowStructs
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: IndexSnapshot: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: IndexSnapshot: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowStructs
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthStructs
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthStructs
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Name = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Time", wireType)
+			}
+			m.Time = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowStructs
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Time |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Files", wireType)
+			}
+			m.Files = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowStructs
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Files |= int32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipStructs(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthStructs
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthStructs
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
 func skipStructs(dAtA []byte) (n int, err error) {
 	l := len(dAtA)
 	iNdEx := 0
@@ -0,0 +1,220 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package db
+
+import (
+	"bytes"
+	"encoding/binary"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/db/backend"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// DropTombstones forgets the deleted files of which every one of the given
+// devices has had the deletion for longer than maxAge, returning how many
+// there were. Once forgotten, the deletion can't win against an old
+// version of the file, so a device that still has one would bring it back;
+// hence the wait for all of them.
+func (s *FileSet) DropTombstones(devices []protocol.DeviceID, maxAge time.Duration) (int, error) {
+	l.Debugf("%s DropTombstones(%v, %v)", s.folder, devices, maxAge)
+
+	s.updateMutex.Lock()
+	defer s.updateMutex.Unlock()
+
+	devs := make([][]byte, len(devices))
+	for i := range devices {
+		devs[i] = devices[i][:]
+	}
+	dropped, err := s.db.dropTombstones([]byte(s.folder), devs, time.Now().Add(-maxAge), s.meta)
+	if dropped > 0 {
+		if err := s.meta.toDB(s.db, []byte(s.folder)); err != nil {
+			return dropped, err
+		}
+	}
+	return dropped, err
+}
+
+// dropTombstones goes through the version lists of the folder, noting when
+// every device was first found to have the deletion of a file, and removes
+// all entries of the files where that was before the given time.
+func (db *Lowlevel) dropTombstones(folder []byte, devices [][]byte, before time.Time, meta *metadataTracker) (int, error) {
+	t, err := db.newReadWriteTransaction()
+	if err != nil {
+		return 0, err
+	}
+	defer t.close()
+
+	gk, err := db.keyer.GenerateGlobalVersionKey(nil, folder, nil)
+	if err != nil {
+		return 0, err
+	}
+	dbi, err := t.NewPrefixIterator(gk.WithoutName())
+	if err != nil {
+		return 0, err
+	}
+	defer dbi.Release()
+
+	now := make([]byte, keyTimeLen)
+	binary.BigEndian.PutUint64(now, uint64(time.Now().Unix()))
+	var tk []byte
+	dropped := 0
+	for dbi.Next() {
+		name := db.keyer.NameFromGlobalVersionKey(dbi.Key())
+		tk, err = db.keyer.GenerateTombstoneKey(tk, folder, name)
+		if err != nil {
+			return dropped, err
+		}
+		vl, ok := unmarshalVersionList(dbi.Value())
+		if !ok {
+			continue
+		}
+		global, ok, err := t.deletionEverywhere(folder, name, vl, devices)
+		if err != nil {
+			return dropped, err
+		}
+
+		since, err := t.Get(tk)
+		if err != nil && !backend.IsNotFound(err) {
+			return dropped, err
+		}
+		switch {
+		case !ok:
+			if err == nil {
+				if err := t.Delete(tk); err != nil {
+					return dropped, err
+				}
+			}
+			continue
+		case err != nil:
+			if err := t.Put(tk, now); err != nil {
+				return dropped, err
+			}
+			continue
+		case len(since) != keyTimeLen || time.Unix(int64(binary.BigEndian.Uint64(since)), 0).After(before):
+			continue
+		}
+
+		l.Debugf("dropping tombstone; folder=%q file=%q", folder, name)
+		if err := t.dropFile(folder, name, vl, global, meta); err != nil {
+			return dropped, err
+		}
+		if err := t.Delete(dbi.Key()); err != nil {
+			return dropped, err
+		}
+		if err := t.Delete(tk); err != nil {
+			return dropped, err
+		}
+		dropped++
+		if err := t.Checkpoint(); err != nil {
+			return dropped, err
+		}
+	}
+	if err := dbi.Error(); err != nil {
+		return dropped, err
+	}
+	dbi.Release()
+
+	// The deletion times of files that are gone altogether.
+	tk, err = db.keyer.GenerateTombstoneKey(tk, folder, nil)
+	if err != nil {
+		return dropped, err
+	}
+	ti, err := t.NewPrefixIterator(tombstoneKey(tk).WithoutName())
+	if err != nil {
+		return dropped, err
+	}
+	defer ti.Release()
+	for ti.Next() {
+		gk, err = db.keyer.GenerateGlobalVersionKey(gk, folder, db.keyer.NameFromTombstoneKey(ti.Key()))
+		if err != nil {
+			return dropped, err
+		}
+		if _, err := t.Get(gk); backend.IsNotFound(err) {
+			if err := t.Delete(ti.Key()); err != nil {
+				return dropped, err
+			}
+		} else if err != nil {
+			return dropped, err
+		}
+	}
+	if err := ti.Error(); err != nil {
+		return dropped, err
+	}
+
+	return dropped, t.commit()
+}
+
+// deletionEverywhere returns the global version of the file if it is a
+// deletion, every device that has the file has that same deletion, and
+// all of the given devices have it. We needn't have it ourselves, as we
+// can't bring back what we never had.
+func (t readWriteTransaction) deletionEverywhere(folder, name []byte, vl VersionList, devices [][]byte) (FileIntf, bool, error) {
+	if len(vl.Versions) == 0 {
+		return nil, false, nil
+	}
+	first := vl.Versions[0]
+	for _, fv := range vl.Versions {
+		if fv.Invalid || !fv.Version.Equal(first.Version) {
+			return nil, false, nil
+		}
+	}
+	for _, dev := range devices {
+		if _, ok := vl.Get(dev); !ok {
+			return nil, false, nil
+		}
+	}
+	dk, err := t.keyer.GenerateDeviceFileKey(nil, folder, first.Device, name)
+	if err != nil {
+		return nil, false, err
+	}
+	global, ok, err := t.getFileTrunc(dk, true)
+	if err != nil || !ok || !global.IsDeleted() {
+		return nil, false, err
+	}
+	return global, true, nil
+}
+
+// dropFile removes the entries of all devices for the file, along with our
+// sequence index entry and need entry for it. The version list is left to
+// the caller.
+func (t readWriteTransaction) dropFile(folder, name []byte, vl VersionList, global FileIntf, meta *metadataTracker) error {
+	var dk, keyBuf []byte
+	var err error
+	for _, fv := range vl.Versions {
+		dk, err = t.keyer.GenerateDeviceFileKey(dk, folder, fv.Device, name)
+		if err != nil {
+			return err
+		}
+		f, ok, err := t.getFileTrunc(dk, true)
+		if err != nil {
+			return err
+		}
+		if ok {
+			meta.removeFile(protocol.DeviceIDFromBytes(fv.Device), f)
+			if bytes.Equal(fv.Device, protocol.LocalDeviceID[:]) {
+				keyBuf, err = t.keyer.GenerateSequenceKey(keyBuf, folder, f.SequenceNo())
+				if err != nil {
+					return err
+				}
+				if err := t.Delete(keyBuf); err != nil {
+					return err
+				}
+			}
+		}
+		if err := t.Delete(dk); err != nil {
+			return err
+		}
+	}
+	meta.removeFile(protocol.GlobalDeviceID, global)
+
+	keyBuf, err = t.keyer.GenerateNeedFileKey(keyBuf, folder, name)
+	if err != nil {
+		return err
+	}
+	return t.Delete(keyBuf)
+}
@@ -100,6 +100,7 @@ func (t readOnlyTransaction) getGlobal(keyBuf, folder, file []byte, truncate boo
 type readWriteTransaction struct {
 	backend.WriteTransaction
 	readOnlyTransaction
+	keepHistory int // global versions of each file to keep in the file history; 0 to record none
 }
 
 func (db *Lowlevel) newReadWriteTransaction() (readWriteTransaction, error) {
@@ -155,6 +156,11 @@ func (t readWriteTransaction) updateGlobal(gk, keyBuf, folder, device []byte, fi
 	if insertedAt == 0 {
 		// Inserted a new newest version
 		global = file
+		if t.keepHistory > 0 && !file.IsInvalid() {
+			if keyBuf, err = t.addFileHistory(keyBuf, folder, device, file); err != nil {
+				return nil, false, err
+			}
+		}
 	} else {
 		keyBuf, err = t.keyer.GenerateDeviceFileKey(keyBuf, folder, fl.Versions[0].Device, name)
 		if err != nil {
@@ -0,0 +1,184 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package db
+
+import (
+	"encoding/binary"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// Usage is how much of the database the keys and values of the entries
+// of a folder or device take up. How much space that is on disk depends on
+// the backend's compression and overhead.
+type Usage struct {
+	// Keys is the number of entries.
+	Keys int64 `json:"keys"`
+	// Bytes is the size of their keys and values.
+	Bytes int64 `json:"bytes"`
+}
+
+func (u *Usage) add(size int) {
+	u.Keys++
+	u.Bytes += int64(size)
+}
+
+// FolderUsage is how much of the database the entries of a folder take up,
+// in bytes by kind of entry and in total, and by the device whose index the
+// entries are of.
+type FolderUsage struct {
+	Files     int64 `json:"files"`     // the files of all devices
+	Globals   int64 `json:"globals"`   // the version lists
+	Blocks    int64 `json:"blocks"`    // the block map
+	Sequences int64 `json:"sequences"` // the sequence index
+	Needs     int64 `json:"needs"`     // the list of needed files
+	History   int64 `json:"history"`   // the file history
+	Snapshots int64 `json:"snapshots"` // the index snapshots
+	Other     int64 `json:"other"`     // mtimes, metadata, index IDs and deletion times
+	Total     int64 `json:"total"`
+	Keys      int64 `json:"keys"`
+
+	// Devices are the files and index IDs of the folder by device,
+	// including the local device.
+	Devices map[protocol.DeviceID]Usage `json:"devices"`
+}
+
+// DatabaseUsage is how much of the database each folder and the index of
+// each device take up.
+type DatabaseUsage struct {
+	Folders map[string]FolderUsage `json:"folders"`
+	// Devices are the indexes of each device over all folders.
+	Devices map[protocol.DeviceID]Usage `json:"devices"`
+	// Other are the entries that belong to no folder, such as statistics.
+	Other Usage `json:"other"`
+	Total Usage `json:"total"`
+}
+
+// Usage goes through the whole database, accounting each entry to the
+// folder and device it belongs to.
+func (db *Lowlevel) Usage() (DatabaseUsage, error) {
+	usage := DatabaseUsage{
+		Folders: make(map[string]FolderUsage),
+		Devices: make(map[protocol.DeviceID]Usage),
+	}
+
+	t, err := db.newReadOnlyTransaction()
+	if err != nil {
+		return usage, err
+	}
+	defer t.close()
+
+	dbi, err := t.NewPrefixIterator(nil)
+	if err != nil {
+		return usage, err
+	}
+	defer dbi.Release()
+
+	// The names of the folders and devices by index, as they are looked
+	// up for every entry.
+	folders := make(map[uint32]string)
+	devices := make(map[uint32]protocol.DeviceID)
+	folderName := func(bs []byte) (string, bool) {
+		idx := binary.BigEndian.Uint32(bs)
+		if name, ok := folders[idx]; ok {
+			return name, true
+		}
+		name, ok := db.folderIdx.Val(idx)
+		if !ok {
+			return "", false
+		}
+		folders[idx] = string(name)
+		return string(name), true
+	}
+	deviceID := func(bs []byte) (protocol.DeviceID, bool) {
+		idx := binary.BigEndian.Uint32(bs)
+		if id, ok := devices[idx]; ok {
+			return id, true
+		}
+		dev, ok := db.deviceIdx.Val(idx)
+		if !ok {
+			return protocol.EmptyDeviceID, false
+		}
+		id := protocol.DeviceIDFromBytes(dev)
+		devices[idx] = id
+		return id, true
+	}
+
+	for dbi.Next() {
+		key := dbi.Key()
+		size := len(key) + len(dbi.Value())
+		usage.Total.add(size)
+
+		if len(key) == 0 {
+			usage.Other.add(size)
+			continue
+		}
+
+		var folderBs, deviceBs []byte
+		switch key[0] {
+		case KeyTypeDevice:
+			if len(key) >= keyPrefixLen+keyFolderLen+keyDeviceLen {
+				folderBs = key[keyPrefixLen:]
+				deviceBs = key[keyPrefixLen+keyFolderLen:]
+			}
+		case KeyTypeIndexID:
+			if len(key) >= keyPrefixLen+keyDeviceLen+keyFolderLen {
+				deviceBs = key[keyPrefixLen:]
+				folderBs = key[keyPrefixLen+keyDeviceLen:]
+			}
+		case KeyTypeGlobal, KeyTypeBlock, KeyTypeVirtualMtime, KeyTypeFolderMeta, KeyTypeSequence, KeyTypeNeed, KeyTypeFileHistory, KeyTypeTombstone, KeyTypeIndexSnapshot, KeyTypeIndexSnapshotFile:
+			if len(key) >= keyPrefixLen+keyFolderLen {
+				folderBs = key[keyPrefixLen:]
+			}
+		}
+		folder, ok := "", false
+		if folderBs != nil {
+			folder, ok = folderName(folderBs)
+		}
+		if !ok {
+			usage.Other.add(size)
+			continue
+		}
+
+		fu := usage.Folders[folder]
+		switch key[0] {
+		case KeyTypeDevice:
+			fu.Files += int64(size)
+		case KeyTypeGlobal:
+			fu.Globals += int64(size)
+		case KeyTypeBlock:
+			fu.Blocks += int64(size)
+		case KeyTypeSequence:
+			fu.Sequences += int64(size)
+		case KeyTypeNeed:
+			fu.Needs += int64(size)
+		case KeyTypeFileHistory:
+			fu.History += int64(size)
+		case KeyTypeIndexSnapshot, KeyTypeIndexSnapshotFile:
+			fu.Snapshots += int64(size)
+		default:
+			fu.Other += int64(size)
+		}
+		fu.Total += int64(size)
+		fu.Keys++
+		if deviceBs != nil {
+			if id, ok := deviceID(deviceBs); ok {
+				if fu.Devices == nil {
+					fu.Devices = make(map[protocol.DeviceID]Usage)
+				}
+				du := fu.Devices[id]
+				du.add(size)
+				fu.Devices[id] = du
+				du = usage.Devices[id]
+				du.add(size)
+				usage.Devices[id] = du
+			}
+		}
+		usage.Folders[folder] = fu
+	}
+	return usage, dbi.Error()
+}
@@ -0,0 +1,95 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package db
+
+import (
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+// Updates are committed in turns of at most this many files, so that other
+// folders get to write in between.
+const maxFilesPerTurn = 1000
+
+// The writeQueue lets one writer at a time write to the database. Writers
+// wait in per folder queues and the folders take turns in round robin
+// order, so that a folder committing a huge update (e.g. the initial scan
+// of millions of files) does not hold up the small updates of other
+// folders for the whole duration.
+type writeQueue struct {
+	busy    bool
+	waiting map[string][]chan struct{}
+	order   []string // folders with waiting writers, next turn first
+	mut     sync.Mutex
+}
+
+func newWriteQueue() *writeQueue {
+	return &writeQueue{
+		waiting: make(map[string][]chan struct{}),
+		mut:     sync.NewMutex(),
+	}
+}
+
+// acquire blocks until it is the given folder's turn to write. Every
+// acquire must be followed by a release.
+func (q *writeQueue) acquire(folder string) {
+	q.mut.Lock()
+	if !q.busy {
+		q.busy = true
+		q.mut.Unlock()
+		return
+	}
+	ready := make(chan struct{})
+	if len(q.waiting[folder]) == 0 {
+		q.order = append(q.order, folder)
+	}
+	q.waiting[folder] = append(q.waiting[folder], ready)
+	q.mut.Unlock()
+
+	<-ready
+}
+
+// release ends the current turn, handing it to the first waiter of the next
+// folder in line.
+func (q *writeQueue) release() {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+
+	if len(q.order) == 0 {
+		q.busy = false
+		return
+	}
+
+	folder := q.order[0]
+	q.order = q.order[1:]
+	waiting := q.waiting[folder]
+	close(waiting[0])
+	if len(waiting) > 1 {
+		q.waiting[folder] = waiting[1:]
+		q.order = append(q.order, folder)
+	} else {
+		delete(q.waiting, folder)
+	}
+}
+
+// inTurns calls fn for consecutive parts of fs, each in a turn of its own.
+func (q *writeQueue) inTurns(folder []byte, fs []protocol.FileInfo, fn func([]protocol.FileInfo) error) error {
+	for len(fs) > 0 {
+		n := len(fs)
+		if n > maxFilesPerTurn {
+			n = maxFilesPerTurn
+		}
+		q.acquire(string(folder))
+		err := fn(fs[:n])
+		q.release()
+		if err != nil {
+			return err
+		}
+		fs = fs[n:]
+	}
+	return nil
+}
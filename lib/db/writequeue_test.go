@@ -0,0 +1,90 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package db
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/db/backend"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+func TestWriteQueueRoundRobin(t *testing.T) {
+	q := newWriteQueue()
+	q.acquire("busy")
+
+	// The busy folder queues up three more writes before the other folder
+	// gets in line, but doesn't get to do all of them first.
+
+	turns := make(chan string, 5)
+	queue := func(folder string) {
+		q.mut.Lock()
+		queued := len(q.waiting[folder])
+		q.mut.Unlock()
+		go func() {
+			q.acquire(folder)
+			turns <- folder
+		}()
+		// Wait for the writer to be in line, to get a predictable order.
+		for {
+			q.mut.Lock()
+			n := len(q.waiting[folder])
+			q.mut.Unlock()
+			if n > queued {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		queue("busy")
+	}
+	queue("other")
+	queue("other")
+
+	var got []string
+	for i := 0; i < 5; i++ {
+		q.release()
+		got = append(got, <-turns)
+	}
+	q.release()
+
+	expected := "[busy other busy other busy]"
+	if fmt.Sprint(got) != expected {
+		t.Errorf("turns taken in order %v, expected %v", got, expected)
+	}
+	if q.busy {
+		t.Error("queue should be idle after the last release")
+	}
+}
+
+func TestUpdateInTurns(t *testing.T) {
+	ldb := NewLowlevel(backend.OpenMemory())
+	remote := protocol.DeviceID{1}
+	s := NewFileSet("test", fs.NewFilesystem(fs.FilesystemTypeBasic, "."), ldb)
+
+	files := make([]protocol.FileInfo, 2*maxFilesPerTurn+10)
+	for i := range files {
+		files[i] = protocol.FileInfo{Name: fmt.Sprintf("file%d", i), Version: protocol.Vector{}.Update(remote.Short())}
+	}
+	s.Update(protocol.LocalDeviceID, files)
+	s.Update(remote, files)
+
+	if n := s.LocalSize().Files; int(n) != len(files) {
+		t.Errorf("expected %d local files, got %d", len(files), n)
+	}
+	if n := s.GlobalSize().Files; int(n) != len(files) {
+		t.Errorf("expected %d global files, got %d", len(files), n)
+	}
+	if seq := s.Sequence(protocol.LocalDeviceID); seq != int64(len(files)) {
+		t.Errorf("expected sequence %d, got %d", len(files), seq)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/url"
 	"time"
 
 	"golang.org/x/net/ipv4"
@@ -101,7 +102,59 @@ func dialContextWithFallback(ctx context.Context, fallback proxy.ContextDialer,
 
 // DialContext dials via context and/or directly, depending on how it is configured.
 // If dialing via proxy and allowing fallback, dialing for both happens simultaneously
-// and the proxy connection is returned if successful.
+// and the proxy connection is returned if successful. A proxy set on the
+// context with WithProxy takes precedence over the environment, without
+// fallback.
 func DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if proxyURL, ok := ProxyFromContext(ctx); ok {
+		return dialContextThrough(ctx, proxyURL, network, addr)
+	}
 	return dialContextWithFallback(ctx, proxy.Direct, network, addr)
 }
+
+// Direct is the proxy setting for not using any proxy.
+const Direct = "direct"
+
+type proxyContextKey struct{}
+
+// WithProxy returns a context in which DialContext goes through the given
+// proxy, such as socks5://127.0.0.1:9050, instead of the one set in the
+// environment. Direct means no proxy at all.
+func WithProxy(ctx context.Context, proxyURL string) context.Context {
+	return context.WithValue(ctx, proxyContextKey{}, proxyURL)
+}
+
+// ProxyFromContext returns the proxy set with WithProxy, if any.
+func ProxyFromContext(ctx context.Context) (string, bool) {
+	proxyURL, ok := ctx.Value(proxyContextKey{}).(string)
+	return proxyURL, ok
+}
+
+func proxyDialer(proxyURL string) (proxy.ContextDialer, error) {
+	if proxyURL == Direct {
+		return proxy.Direct, nil
+	}
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, err
+	}
+	d, err := proxy.FromURL(u, proxy.Direct)
+	if err != nil {
+		return nil, err
+	}
+	cd, ok := d.(proxy.ContextDialer)
+	if !ok {
+		return nil, errUnexpectedInterfaceType
+	}
+	return cd, nil
+}
+
+func dialContextThrough(ctx context.Context, proxyURL, network, addr string) (net.Conn, error) {
+	d, err := proxyDialer(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("proxy: %v", err)
+	}
+	// Falling back to a direct connection would defeat the purpose of
+	// asking for this proxy, e.g. for Tor.
+	return d.DialContext(ctx, network, addr)
+}
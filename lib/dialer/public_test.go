@@ -0,0 +1,49 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package dialer
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+func TestDialContextWithProxy(t *testing.T) {
+	target, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer target.Close()
+
+	// Nothing listens where the proxy is supposed to be.
+	dead, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadAddr := dead.Addr().String()
+	dead.Close()
+
+	ctx := WithProxy(context.Background(), Direct)
+	conn, err := DialContext(ctx, "tcp", target.Addr().String())
+	if err != nil {
+		t.Fatal("dialing directly:", err)
+	}
+	conn.Close()
+
+	// The target is reachable, but not through the proxy, and there must
+	// be no falling back to a direct connection.
+	ctx = WithProxy(context.Background(), "socks5://"+deadAddr)
+	if conn, err := DialContext(ctx, "tcp", target.Addr().String()); err == nil {
+		conn.Close()
+		t.Error("expected dialing through a dead proxy to fail")
+	}
+
+	ctx = WithProxy(context.Background(), "gopher://"+deadAddr)
+	if _, err := DialContext(ctx, "tcp", target.Addr().String()); err == nil {
+		t.Error("expected an error for an unknown proxy type")
+	}
+}
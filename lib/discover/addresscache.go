@@ -0,0 +1,168 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package discover
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+const (
+	// Addresses that haven't worked for this long are forgotten.
+	addressCacheMaxAge = 30 * 24 * time.Hour
+	// The score moves this far towards one on success, towards zero on
+	// failure, and addresses scoring below the minimum are forgotten.
+	addressCacheWeight   = 0.3
+	addressCacheMinScore = 0.05
+	// At most this many addresses are kept per device.
+	addressCacheMaxEntries = 8
+)
+
+// The AddressCache remembers the addresses devices were last reached at,
+// with how reliable they have been since, in the database. After a restart
+// they can be dialed right away, without waiting for discovery, which may
+// be slow or unreachable.
+type AddressCache struct {
+	ldb     *db.Lowlevel
+	entries map[protocol.DeviceID][]CachedAddress // loaded on first use
+	mut     sync.Mutex
+}
+
+type CachedAddress struct {
+	Address     string    `json:"address"`
+	Score       float64   `json:"score"`
+	LastSuccess time.Time `json:"lastSuccess"`
+	LastFailure time.Time `json:"lastFailure,omitempty"`
+}
+
+func NewAddressCache(ldb *db.Lowlevel) *AddressCache {
+	return &AddressCache{
+		ldb:     ldb,
+		entries: make(map[protocol.DeviceID][]CachedAddress),
+		mut:     sync.NewMutex(),
+	}
+}
+
+// Addresses returns the addresses the device may be reached at, the most
+// reliable first.
+func (c *AddressCache) Addresses(device protocol.DeviceID) []string {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	entries := c.get(device)
+	addrs := make([]string, len(entries))
+	for i, e := range entries {
+		addrs[i] = e.Address
+	}
+	return addrs
+}
+
+// Entries returns what is known about the addresses of the device, the most
+// reliable first.
+func (c *AddressCache) Entries(device protocol.DeviceID) []CachedAddress {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	entries := c.get(device)
+	return append([]CachedAddress(nil), entries...)
+}
+
+// Succeeded records that the device was reached at the address.
+func (c *AddressCache) Succeeded(device protocol.DeviceID, addr string) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	entries := c.get(device)
+	i := indexOfAddress(entries, addr)
+	if i < 0 {
+		entries = append(entries, CachedAddress{Address: addr})
+		i = len(entries) - 1
+	}
+	entries[i].Score += (1 - entries[i].Score) * addressCacheWeight
+	entries[i].LastSuccess = time.Now()
+	c.set(device, entries)
+}
+
+// Failed records that the device couldn't be reached at the address. Only
+// addresses that have worked before are remembered.
+func (c *AddressCache) Failed(device protocol.DeviceID, addr string) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	entries := c.get(device)
+	i := indexOfAddress(entries, addr)
+	if i < 0 {
+		return
+	}
+	entries[i].Score -= entries[i].Score * addressCacheWeight
+	entries[i].LastFailure = time.Now()
+	c.set(device, entries)
+}
+
+// get returns the entries for the device, fresh and sorted. Must be called
+// with the lock held.
+func (c *AddressCache) get(device protocol.DeviceID) []CachedAddress {
+	entries, ok := c.entries[device]
+	if !ok {
+		bs, ok, err := c.namespace(device).Bytes("addressCache")
+		if err != nil {
+			l.Debugln("loading address cache for", device, err)
+		} else if ok {
+			if err := json.Unmarshal(bs, &entries); err != nil {
+				l.Debugln("loading address cache for", device, err)
+			}
+		}
+	}
+	entries = c.prune(entries)
+	c.entries[device] = entries
+	return entries
+}
+
+// set sorts, prunes and stores the entries for the device. Must be called
+// with the lock held.
+func (c *AddressCache) set(device protocol.DeviceID, entries []CachedAddress) {
+	entries = c.prune(entries)
+	c.entries[device] = entries
+	bs, err := json.Marshal(entries)
+	if err == nil {
+		err = c.namespace(device).PutBytes("addressCache", bs)
+	}
+	if err != nil {
+		l.Debugln("saving address cache for", device, err)
+	}
+}
+
+func (c *AddressCache) prune(entries []CachedAddress) []CachedAddress {
+	cutoff := time.Now().Add(-addressCacheMaxAge)
+	kept := entries[:0]
+	for _, e := range entries {
+		if e.Score >= addressCacheMinScore && e.LastSuccess.After(cutoff) {
+			kept = append(kept, e)
+		}
+	}
+	sort.SliceStable(kept, func(a, b int) bool {
+		return kept[a].Score > kept[b].Score
+	})
+	if len(kept) > addressCacheMaxEntries {
+		kept = kept[:addressCacheMaxEntries]
+	}
+	return kept
+}
+
+func (c *AddressCache) namespace(device protocol.DeviceID) *db.NamespacedKV {
+	return db.NewDeviceStatisticsNamespace(c.ldb, device.String())
+}
+
+func indexOfAddress(entries []CachedAddress, addr string) int {
+	for i, e := range entries {
+		if e.Address == addr {
+			return i
+		}
+	}
+	return -1
+}
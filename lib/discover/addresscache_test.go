@@ -0,0 +1,81 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package discover
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/db/backend"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+func TestAddressCache(t *testing.T) {
+	ldb := db.NewLowlevel(backend.OpenMemory())
+	defer ldb.Close()
+
+	dev := protocol.LocalDeviceID
+	c := NewAddressCache(ldb)
+
+	// Failures of unknown addresses aren't remembered.
+	c.Failed(dev, "tcp://192.0.2.1:22000")
+	if addrs := c.Addresses(dev); len(addrs) != 0 {
+		t.Fatal("unexpected addresses", addrs)
+	}
+
+	c.Succeeded(dev, "tcp://192.0.2.1:22000")
+	c.Succeeded(dev, "tcp://192.0.2.2:22000")
+	c.Succeeded(dev, "tcp://192.0.2.2:22000")
+	expected := []string{"tcp://192.0.2.2:22000", "tcp://192.0.2.1:22000"}
+	if addrs := c.Addresses(dev); !reflect.DeepEqual(addrs, expected) {
+		t.Errorf("got %v, expected %v", addrs, expected)
+	}
+
+	// Failing enough times moves an address down, then out.
+	c.Failed(dev, "tcp://192.0.2.2:22000")
+	c.Failed(dev, "tcp://192.0.2.2:22000")
+	expected = []string{"tcp://192.0.2.1:22000", "tcp://192.0.2.2:22000"}
+	if addrs := c.Addresses(dev); !reflect.DeepEqual(addrs, expected) {
+		t.Errorf("got %v, expected %v", addrs, expected)
+	}
+	for i := 0; i < 10; i++ {
+		c.Failed(dev, "tcp://192.0.2.2:22000")
+	}
+	expected = []string{"tcp://192.0.2.1:22000"}
+	if addrs := c.Addresses(dev); !reflect.DeepEqual(addrs, expected) {
+		t.Errorf("got %v, expected %v", addrs, expected)
+	}
+
+	// It's all still there after a restart.
+	c = NewAddressCache(ldb)
+	if addrs := c.Addresses(dev); !reflect.DeepEqual(addrs, expected) {
+		t.Errorf("after reload got %v, expected %v", addrs, expected)
+	}
+	if addrs := c.Addresses(protocol.EmptyDeviceID); len(addrs) != 0 {
+		t.Error("unexpected addresses for another device", addrs)
+	}
+}
+
+func TestAddressCacheStale(t *testing.T) {
+	ldb := db.NewLowlevel(backend.OpenMemory())
+	defer ldb.Close()
+
+	dev := protocol.LocalDeviceID
+	c := NewAddressCache(ldb)
+	c.Succeeded(dev, "tcp://192.0.2.1:22000")
+	c.Succeeded(dev, "tcp://192.0.2.2:22000")
+
+	// One of them last worked long ago.
+	c.entries[dev][1].LastSuccess = time.Now().Add(-addressCacheMaxAge - time.Hour)
+
+	entries := c.Entries(dev)
+	if len(entries) != 1 || entries[0].Address != "tcp://192.0.2.1:22000" {
+		t.Errorf("unexpected entries %v", entries)
+	}
+}
@@ -200,14 +200,16 @@ func (c *globalClient) serve(ctx context.Context) {
 	timer := time.NewTimer(0)
 	defer timer.Stop()
 
-	eventSub := c.evLogger.Subscribe(events.ListenAddressesChanged)
+	eventSub := c.evLogger.Subscribe(events.ListenAddressesChanged | events.SystemWoke)
 	defer eventSub.Unsubscribe()
 
 	for {
 		select {
 		case <-eventSub.C():
-			// Defer announcement by 2 seconds, essentially debouncing
-			// if we have a stream of events incoming in quick succession.
+			// Our addresses changed, or we woke up from sleep and the
+			// server may have forgotten about us. Defer announcement by 2
+			// seconds, essentially debouncing if we have a stream of
+			// events incoming in quick succession.
 			timer.Reset(2 * time.Second)
 
 		case <-timer.C:
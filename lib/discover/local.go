@@ -141,6 +141,8 @@ func (c *localClient) sendLocalAnnouncements(ctx context.Context) {
 	var msg []byte
 	var ok bool
 	instanceID := rand.Int63()
+	wakeSub := c.evLogger.Subscribe(events.SystemWoke)
+	defer wakeSub.Unsubscribe()
 	for {
 		if msg, ok = c.announcementPkt(instanceID, msg[:0]); ok {
 			c.beacon.Send(msg)
@@ -149,6 +151,8 @@ func (c *localClient) sendLocalAnnouncements(ctx context.Context) {
 		select {
 		case <-c.localBcastTick:
 		case <-c.forcedBcastTick:
+		case <-wakeSub.C():
+			// We may be on another network now, let it know right away.
 		case <-ctx.Done():
 			return
 		}
@@ -52,10 +52,63 @@ const (
 	FolderWatchStateChanged
 	ListenAddressesChanged
 	LoginAttempt
+	ItemVerified
+	LocalCorruptionDetected
+	FolderStuck
+	FolderStopped
+	DatabaseError
+	FolderScanSummary
+	ApplicationMessageReceived
+	SystemWoke
+	FolderSettingsPending
+	DeviceDecommissionPending
 
 	AllEvents = (1 << iota) - 1
+
+	// Events that need attention and are delivered ahead of all others.
+	CriticalEvents = DeviceRejected | FolderStopped | DatabaseError | FolderStuck | LocalCorruptionDetected
+	// Events that point out a problem, but nothing urgent.
+	WarningEvents = DeviceDisconnected | FolderRejected | FolderErrors
 )
 
+// Severity tells how important an event is to the user.
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityCritical
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityCritical:
+		return "critical"
+	default:
+		return "unknown"
+	}
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Severity) UnmarshalText(bs []byte) error {
+	switch string(bs) {
+	case "warning":
+		*s = SeverityWarning
+	case "critical":
+		*s = SeverityCritical
+	default:
+		*s = SeverityInfo
+	}
+	return nil
+}
+
 var (
 	runningTests = false
 	errNoop      = errors.New("method of a noop object called")
@@ -121,11 +174,43 @@ func (t EventType) String() string {
 		return "LoginAttempt"
 	case FolderWatchStateChanged:
 		return "FolderWatchStateChanged"
+	case ItemVerified:
+		return "ItemVerified"
+	case LocalCorruptionDetected:
+		return "LocalCorruptionDetected"
+	case FolderStuck:
+		return "FolderStuck"
+	case FolderStopped:
+		return "FolderStopped"
+	case DatabaseError:
+		return "DatabaseError"
+	case FolderScanSummary:
+		return "FolderScanSummary"
+	case ApplicationMessageReceived:
+		return "ApplicationMessageReceived"
+	case SystemWoke:
+		return "SystemWoke"
+	case FolderSettingsPending:
+		return "FolderSettingsPending"
+	case DeviceDecommissionPending:
+		return "DeviceDecommissionPending"
 	default:
 		return "Unknown"
 	}
 }
 
+// Severity returns the severity of events of this type.
+func (t EventType) Severity() Severity {
+	switch {
+	case t&CriticalEvents != 0:
+		return SeverityCritical
+	case t&WarningEvents != 0:
+		return SeverityWarning
+	default:
+		return SeverityInfo
+	}
+}
+
 func (t EventType) MarshalText() ([]byte, error) {
 	return []byte(t.String()), nil
 }
@@ -200,6 +285,26 @@ func UnmarshalEventType(s string) EventType {
 		return LoginAttempt
 	case "FolderWatchStateChanged":
 		return FolderWatchStateChanged
+	case "ItemVerified":
+		return ItemVerified
+	case "LocalCorruptionDetected":
+		return LocalCorruptionDetected
+	case "FolderStuck":
+		return FolderStuck
+	case "FolderStopped":
+		return FolderStopped
+	case "DatabaseError":
+		return DatabaseError
+	case "FolderScanSummary":
+		return FolderScanSummary
+	case "ApplicationMessageReceived":
+		return ApplicationMessageReceived
+	case "SystemWoke":
+		return SystemWoke
+	case "FolderSettingsPending":
+		return FolderSettingsPending
+	case "DeviceDecommissionPending":
+		return DeviceDecommissionPending
 	default:
 		return 0
 	}
@@ -220,6 +325,7 @@ type logger struct {
 	nextGlobalID        int
 	timeout             *time.Timer
 	events              chan Event
+	critical            chan Event // critical events skip the queue of others
 	funcs               chan func()
 	toUnsubscribe       chan *subscription
 	stop                chan struct{}
@@ -232,6 +338,7 @@ type Event struct {
 	GlobalID int         `json:"globalID"`
 	Time     time.Time   `json:"time"`
 	Type     EventType   `json:"type"`
+	Severity Severity    `json:"severity"`
 	Data     interface{} `json:"data"`
 }
 
@@ -257,6 +364,7 @@ func NewLogger() Logger {
 	l := &logger{
 		timeout:       time.NewTimer(time.Second),
 		events:        make(chan Event, BufferSize),
+		critical:      make(chan Event, BufferSize),
 		funcs:         make(chan func()),
 		toUnsubscribe: make(chan *subscription),
 	}
@@ -272,7 +380,18 @@ func NewLogger() Logger {
 func (l *logger) serve(ctx context.Context) {
 loop:
 	for {
+		// Critical events are sent before anything else that's waiting.
 		select {
+		case e := <-l.critical:
+			l.sendEvent(e)
+			continue
+		default:
+		}
+
+		select {
+		case e := <-l.critical:
+			l.sendEvent(e)
+
 		case e := <-l.events:
 			// Incoming events get sent
 			l.sendEvent(e)
@@ -298,12 +417,18 @@ loop:
 }
 
 func (l *logger) Log(t EventType, data interface{}) {
-	l.events <- Event{
-		Time: time.Now(),
-		Type: t,
-		Data: data,
+	e := Event{
+		Time:     time.Now(),
+		Type:     t,
+		Severity: t.Severity(),
+		Data:     data,
 		// SubscriptionID and GlobalID are set in sendEvent
 	}
+	if e.Severity == SeverityCritical {
+		l.critical <- e
+		return
+	}
+	l.events <- e
 }
 
 func (l *logger) sendEvent(e Event) {
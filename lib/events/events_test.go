@@ -359,12 +359,57 @@ func TestUnmarshalEvent(t *testing.T) {
 		"globalID": 1,
 		"time": "2006-01-02T15:04:05.999999999Z",
 		"type": "Starting",
+		"severity": "critical",
 		"data": {}
 	}`
 
 	if err := json.Unmarshal([]byte(s), &event); err != nil {
 		t.Fatal("Failed to unmarshal event:", err)
 	}
+	if event.Severity != SeverityCritical {
+		t.Errorf("unexpected severity %v", event.Severity)
+	}
+}
+
+func TestCriticalEventsFirst(t *testing.T) {
+	l := NewLogger().(*logger)
+
+	// Queue up events before anything is sent, with the critical one last.
+
+	l.Log(DeviceConnected, "foo")
+	l.Log(FolderSummary, "bar")
+	l.Log(DeviceRejected, "baz")
+
+	s := &subscription{
+		mask:   AllEvents,
+		events: make(chan Event, BufferSize),
+	}
+	l.subs = append(l.subs, s)
+	l.nextSubscriptionIDs = append(l.nextSubscriptionIDs, 1)
+
+	defer l.Stop()
+	go l.Serve()
+
+	var got []EventType
+	for i := 0; i < 3; i++ {
+		select {
+		case ev := <-s.events:
+			if ev.Severity != ev.Type.Severity() {
+				t.Errorf("%v has severity %v, expected %v", ev.Type, ev.Severity, ev.Type.Severity())
+			}
+			got = append(got, ev.Type)
+		case <-time.After(timeout):
+			t.Fatal("timed out waiting for events")
+		}
+	}
+
+	expected := []EventType{DeviceRejected, DeviceConnected, FolderSummary}
+	if fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("events sent in order %v, expected %v", got, expected)
+	}
+	if DeviceRejected.Severity() != SeverityCritical || FolderRejected.Severity() != SeverityWarning || FolderSummary.Severity() != SeverityInfo {
+		t.Error("unexpected event severities")
+	}
 }
 
 func TestUnsubscribeContention(t *testing.T) {
@@ -16,6 +16,8 @@ import (
 	"time"
 
 	"github.com/shirou/gopsutil/disk"
+
+	"github.com/syncthing/syncthing/lib/protocol"
 )
 
 var (
@@ -173,9 +175,35 @@ func (f *BasicFilesystem) Rename(oldpath, newpath string) error {
 	if err != nil {
 		return err
 	}
+	if IsCaseOnlyRename(oldpath, newpath) {
+		// Some case insensitive filesystems do nothing, or fail, when
+		// renaming to the same name in another case, so go through a
+		// temporary name.
+		tempPath := TempName(newpath)
+		if err := os.Rename(oldpath, tempPath); err != nil {
+			return err
+		}
+		if err := os.Rename(tempPath, newpath); err != nil {
+			os.Rename(tempPath, oldpath)
+			return err
+		}
+		return nil
+	}
 	return os.Rename(oldpath, newpath)
 }
 
+func (f *BasicFilesystem) Hardlink(oldname, newname string) error {
+	oldname, err := f.rooted(oldname)
+	if err != nil {
+		return err
+	}
+	newname, err = f.rooted(newname)
+	if err != nil {
+		return err
+	}
+	return os.Link(oldname, newname)
+}
+
 func (f *BasicFilesystem) Stat(name string) (FileInfo, error) {
 	name, err := f.rooted(name)
 	if err != nil {
@@ -281,7 +309,7 @@ func (f *BasicFilesystem) Type() FilesystemType {
 }
 
 func (f *BasicFilesystem) URI() string {
-	return strings.TrimPrefix(f.root, `\\?\`)
+	return withoutLongFilenamePrefix(f.root)
 }
 
 func (f *BasicFilesystem) SameFile(fi1, fi2 FileInfo) bool {
@@ -296,6 +324,85 @@ func (f *BasicFilesystem) SameFile(fi1, fi2 FileInfo) bool {
 	return os.SameFile(f1.FileInfo, f2.FileInfo)
 }
 
+func (f *BasicFilesystem) CloneRange(src File, srcOffset int64, dst File, dstOffset, length int64) error {
+	srcFd, ok := osFile(src)
+	if !ok {
+		return ErrCloneNotSupported
+	}
+	dstFd, ok := osFile(dst)
+	if !ok {
+		return ErrCloneNotSupported
+	}
+	return cloneRange(srcFd, srcOffset, dstFd, dstOffset, length)
+}
+
+func (f *BasicFilesystem) DedupeRange(src File, srcOffset int64, dst File, dstOffset, length int64) error {
+	srcFd, ok := osFile(src)
+	if !ok {
+		return ErrCloneNotSupported
+	}
+	dstFd, ok := osFile(dst)
+	if !ok {
+		return ErrCloneNotSupported
+	}
+	return dedupeRange(srcFd, srcOffset, dstFd, dstOffset, length)
+}
+
+func (f *BasicFilesystem) GetXattr(name string) ([]protocol.Xattr, error) {
+	name, err := f.rooted(name)
+	if err != nil {
+		return nil, err
+	}
+	return getXattr(name)
+}
+
+func (f *BasicFilesystem) SetXattr(name string, xattrs []protocol.Xattr) error {
+	name, err := f.rooted(name)
+	if err != nil {
+		return err
+	}
+	return setXattr(name, xattrs)
+}
+
+func (f *BasicFilesystem) GetPlatformData(name string) (protocol.PlatformData, error) {
+	name, err := f.rooted(name)
+	if err != nil {
+		return protocol.PlatformData{}, err
+	}
+	return getPlatformData(name)
+}
+
+func (f *BasicFilesystem) SetPlatformData(name string, data protocol.PlatformData) error {
+	name, err := f.rooted(name)
+	if err != nil {
+		return err
+	}
+	return setPlatformData(name, data)
+}
+
+// Holes returns the holes in the file, the ranges that take no space on
+// disk and read as zeroes, in order. Files that aren't on a basic
+// filesystem, or on a platform or filesystem that can't tell, have none.
+func Holes(file File) ([]protocol.FileHole, error) {
+	fd, ok := osFile(file)
+	if !ok {
+		return nil, nil
+	}
+	return holes(fd)
+}
+
+// osFile returns the os.File underlying the given file, if there is one.
+func osFile(file File) (*os.File, bool) {
+	switch f := file.(type) {
+	case basicFile:
+		return f.File, true
+	case *mtimeFile:
+		return osFile(f.File)
+	default:
+		return nil, false
+	}
+}
+
 // basicFile implements the fs.File interface on top of an os.File
 type basicFile struct {
 	*os.File
@@ -330,15 +437,34 @@ func (e basicFileInfo) IsRegular() bool {
 }
 
 // longFilenameSupport adds the necessary prefix to the path to enable long
-// filename support on windows if necessary.
+// filename support on windows if necessary: \\?\ before drive paths, and
+// \\?\UNC\ in place of the leading \\ of UNC paths. Paths with a prefix
+// already, and device paths, are left as they are.
 // This does NOT check the current system, i.e. will also take effect on unix paths.
 func longFilenameSupport(path string) string {
-	if filepath.IsAbs(path) && !strings.HasPrefix(path, `\\`) {
+	switch {
+	case strings.HasPrefix(path, `\\?\`), strings.HasPrefix(path, `\\.\`):
+		return path
+	case strings.HasPrefix(path, `\\`):
+		return `\\?\UNC\` + path[2:]
+	case filepath.IsAbs(path):
 		return `\\?\` + path
 	}
 	return path
 }
 
+// withoutLongFilenamePrefix undoes longFilenameSupport, for paths shown to
+// users or compared with paths that may lack the prefix.
+func withoutLongFilenamePrefix(path string) string {
+	switch {
+	case len(path) >= 8 && strings.EqualFold(path[:8], `\\?\UNC\`):
+		return `\\` + path[8:]
+	case strings.HasPrefix(path, `\\?\`):
+		return path[4:]
+	}
+	return path
+}
+
 type ErrWatchEventOutsideRoot struct{ msg string }
 
 func (e *ErrWatchEventOutsideRoot) Error() string {
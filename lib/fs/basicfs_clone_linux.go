@@ -0,0 +1,102 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// +build linux
+
+package fs
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// FICLONERANGE from linux/fs.h, _IOW(0x94, 13, struct file_clone_range)
+const ficloneRange = 0x4020940d
+
+// fileCloneRange mirrors struct file_clone_range from linux/fs.h
+type fileCloneRange struct {
+	srcFd      int64
+	srcOffset  uint64
+	srcLength  uint64
+	destOffset uint64
+}
+
+func cloneRange(src *os.File, srcOffset int64, dst *os.File, dstOffset, length int64) error {
+	arg := fileCloneRange{
+		srcFd:      int64(src.Fd()),
+		srcOffset:  uint64(srcOffset),
+		srcLength:  uint64(length),
+		destOffset: uint64(dstOffset),
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, dst.Fd(), ficloneRange, uintptr(unsafe.Pointer(&arg)))
+	switch errno {
+	case 0:
+		return nil
+	case syscall.EOPNOTSUPP, syscall.ENOTTY, syscall.EXDEV, syscall.ENOSYS:
+		// Not supported by the filesystem or across filesystems.
+		return ErrCloneNotSupported
+	default:
+		return &os.PathError{Op: "clone", Path: dst.Name(), Err: errno}
+	}
+}
+
+// FIDEDUPERANGE from linux/fs.h, _IOWR(0x94, 54, struct file_dedupe_range)
+const fideduperange = 0xc0189436
+
+const (
+	fileDedupeRangeSame    = 0
+	fileDedupeRangeDiffers = 1
+)
+
+// fileDedupeRange mirrors struct file_dedupe_range from linux/fs.h, with
+// room for a single struct file_dedupe_range_info.
+type fileDedupeRange struct {
+	srcOffset uint64
+	srcLength uint64
+	destCount uint16
+	reserved1 uint16
+	reserved2 uint32
+
+	destFd       int64
+	destOffset   uint64
+	bytesDeduped uint64
+	status       int32
+	reserved     uint32
+}
+
+func dedupeRange(src *os.File, srcOffset int64, dst *os.File, dstOffset, length int64) error {
+	arg := fileDedupeRange{
+		srcOffset:  uint64(srcOffset),
+		srcLength:  uint64(length),
+		destCount:  1,
+		destFd:     int64(dst.Fd()),
+		destOffset: uint64(dstOffset),
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, src.Fd(), fideduperange, uintptr(unsafe.Pointer(&arg)))
+	switch errno {
+	case 0:
+	case syscall.EOPNOTSUPP, syscall.ENOTTY, syscall.EXDEV, syscall.ENOSYS:
+		return ErrCloneNotSupported
+	default:
+		return &os.PathError{Op: "dedupe", Path: dst.Name(), Err: errno}
+	}
+
+	switch {
+	case arg.status == fileDedupeRangeDiffers:
+		return ErrRangesDiffer
+	case arg.status < 0:
+		errno := syscall.Errno(-arg.status)
+		if errno == syscall.EOPNOTSUPP || errno == syscall.EXDEV {
+			return ErrCloneNotSupported
+		}
+		return &os.PathError{Op: "dedupe", Path: dst.Name(), Err: errno}
+	case arg.bytesDeduped != uint64(length):
+		// Only part of the range is shared now, which is harmless.
+		return ErrRangesDiffer
+	}
+	return nil
+}
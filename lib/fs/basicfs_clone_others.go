@@ -0,0 +1,19 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// +build !linux
+
+package fs
+
+import "os"
+
+func cloneRange(src *os.File, srcOffset int64, dst *os.File, dstOffset, length int64) error {
+	return ErrCloneNotSupported
+}
+
+func dedupeRange(src *os.File, srcOffset int64, dst *os.File, dstOffset, length int64) error {
+	return ErrCloneNotSupported
+}
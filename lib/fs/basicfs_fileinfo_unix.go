@@ -27,3 +27,10 @@ func (e basicFileInfo) Group() int {
 	}
 	return -1
 }
+
+func (e basicFileInfo) Inode() uint64 {
+	if st, ok := e.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Ino)
+	}
+	return 0
+}
@@ -37,6 +37,13 @@ func (e basicFileInfo) Mode() FileMode {
 		// NTFS deduped files. Remove the symlink bit.
 		m &^= os.ModeSymlink
 	}
+	if m&os.ModeIrregular != 0 && m&os.ModeDir != 0 {
+		// Newer Go reports junctions as irregular directories, where
+		// older Go reported them as symlinks, which is how they are
+		// represented either way.
+		m &^= os.ModeIrregular | os.ModeDir
+		m |= os.ModeSymlink
+	}
 	// Set executable bits on files with executable extenions (.exe, .bat, etc).
 	if isWindowsExecutable(e.Name()) {
 		m |= 0111
@@ -56,3 +63,7 @@ func (e basicFileInfo) Owner() int {
 func (e basicFileInfo) Group() int {
 	return -1
 }
+
+func (e basicFileInfo) Inode() uint64 {
+	return 0
+}
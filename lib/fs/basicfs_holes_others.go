@@ -0,0 +1,19 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// +build !linux,!darwin,!freebsd,!solaris
+
+package fs
+
+import (
+	"os"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+func holes(fd *os.File) ([]protocol.FileHole, error) {
+	return nil, nil
+}
@@ -0,0 +1,60 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// +build linux darwin freebsd solaris
+
+package fs
+
+import (
+	"io"
+	"os"
+	"syscall"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+func holes(fd *os.File) ([]protocol.FileHole, error) {
+	info, err := fd.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := info.Size()
+	defer fd.Seek(0, io.SeekStart)
+
+	var holes []protocol.FileHole
+	var offset int64
+	for offset < size {
+		hole, err := fd.Seek(offset, seekHole)
+		if err != nil {
+			if isSeekErrno(err, syscall.EINVAL) {
+				// Not supported by the filesystem.
+				return nil, nil
+			}
+			return nil, err
+		}
+		if hole >= size {
+			// Just the virtual hole at the end of the file.
+			break
+		}
+		data, err := fd.Seek(hole, seekData)
+		if isSeekErrno(err, syscall.ENXIO) {
+			// The hole goes on to the end of the file.
+			data = size
+		} else if err != nil {
+			return nil, err
+		}
+		holes = append(holes, protocol.FileHole{Offset: hole, Length: data - hole})
+		offset = data
+	}
+	return holes, nil
+}
+
+func isSeekErrno(err error, errno syscall.Errno) bool {
+	if perr, ok := err.(*os.PathError); ok {
+		err = perr.Err
+	}
+	return err == errno
+}
@@ -0,0 +1,28 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import "syscall"
+
+func isNetworkFilesystem(path string) bool {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return false
+	}
+	var fsType []byte
+	for _, c := range st.Fstypename {
+		if c == 0 {
+			break
+		}
+		fsType = append(fsType, byte(c))
+	}
+	switch string(fsType) {
+	case "nfs", "smbfs", "afpfs", "webdav":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// +build linux
+
+package fs
+
+import "syscall"
+
+// Superblock magic numbers from linux/magic.h and the CIFS sources.
+const (
+	nfsSuperMagic  = 0x6969
+	smbSuperMagic  = 0x517b
+	cifsSuperMagic = 0xff534d42
+	smb2SuperMagic = 0xfe534d42
+	afsSuperMagic  = 0x5346414f
+	codaSuperMagic = 0x73757245
+	v9fsMagic      = 0x01021997
+)
+
+func isNetworkFilesystem(path string) bool {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return false
+	}
+	switch uint32(st.Type) {
+	case nfsSuperMagic, smbSuperMagic, cifsSuperMagic, smb2SuperMagic, afsSuperMagic, codaSuperMagic, v9fsMagic:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,19 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// +build !windows
+
+package fs
+
+import "github.com/syncthing/syncthing/lib/protocol"
+
+func getPlatformData(path string) (protocol.PlatformData, error) {
+	return protocol.PlatformData{}, ErrPlatformDataNotSupported
+}
+
+func setPlatformData(path string, data protocol.PlatformData) error {
+	return ErrPlatformDataNotSupported
+}
@@ -0,0 +1,226 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// +build windows
+
+package fs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// Streams with more data are not synced.
+const maxStreamSize = 64 << 10
+
+const (
+	ioReparseTagMountPoint = 0xA0000003
+	fsctlSetReparsePoint   = 0x000900A4
+	findStreamInfoStandard = 0
+)
+
+var (
+	modkernel32          = syscall.NewLazyDLL("kernel32.dll")
+	procFindFirstStreamW = modkernel32.NewProc("FindFirstStreamW")
+	procFindNextStreamW  = modkernel32.NewProc("FindNextStreamW")
+)
+
+// WIN32_FIND_STREAM_DATA
+type win32FindStreamData struct {
+	StreamSize int64
+	StreamName [syscall.MAX_PATH + 36]uint16
+}
+
+func getPlatformData(path string) (protocol.PlatformData, error) {
+	var data protocol.PlatformData
+	junction, err := isJunction(path)
+	if err != nil {
+		return data, err
+	}
+	data.Windows.Junction = junction
+	if junction {
+		// The streams would be those of the link, not of the target.
+		return data, nil
+	}
+
+	streams, err := listStreams(path)
+	if err != nil {
+		return data, err
+	}
+	for name, size := range streams {
+		if size > maxStreamSize {
+			continue
+		}
+		bs, err := ioutil.ReadFile(path + ":" + name)
+		if IsNotExist(err) {
+			// Removed since we listed it.
+			continue
+		}
+		if err != nil {
+			return data, err
+		}
+		data.Windows.Streams = append(data.Windows.Streams, protocol.DataStream{
+			Name: name,
+			Data: bs,
+		})
+	}
+
+	sort.Slice(data.Windows.Streams, func(a, b int) bool {
+		return data.Windows.Streams[a].Name < data.Windows.Streams[b].Name
+	})
+	return data, nil
+}
+
+// setPlatformData makes the streams of the item those given. Whether it's
+// a junction is up to how it was created.
+func setPlatformData(path string, data protocol.PlatformData) error {
+	if junction, err := isJunction(path); err != nil {
+		return err
+	} else if junction {
+		return nil
+	}
+
+	streams, err := listStreams(path)
+	if err != nil {
+		return err
+	}
+
+	wanted := make(map[string][]byte, len(data.Windows.Streams))
+	for _, s := range data.Windows.Streams {
+		wanted[s.Name] = s.Data
+	}
+
+	for name, size := range streams {
+		if value, ok := wanted[name]; ok {
+			if int64(len(value)) == size {
+				if bs, err := ioutil.ReadFile(path + ":" + name); err == nil && bytes.Equal(bs, value) {
+					delete(wanted, name)
+				}
+			}
+			continue
+		}
+		if size > maxStreamSize {
+			// Neither synced nor removed.
+			continue
+		}
+		if err := os.Remove(path + ":" + name); err != nil && !IsNotExist(err) {
+			return err
+		}
+	}
+
+	for _, s := range data.Windows.Streams {
+		value, ok := wanted[s.Name]
+		if !ok || s.Name == "" || strings.ContainsAny(s.Name, `:\/`) {
+			continue
+		}
+		if err := ioutil.WriteFile(path+":"+s.Name, value, 0666); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listStreams returns the sizes of the alternate data streams of the item,
+// by name.
+func listStreams(path string) (map[string]int64, error) {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	var data win32FindStreamData
+	h, _, err := procFindFirstStreamW.Call(uintptr(unsafe.Pointer(p)), findStreamInfoStandard, uintptr(unsafe.Pointer(&data)), 0)
+	if syscall.Handle(h) == syscall.InvalidHandle {
+		if err == syscall.ERROR_HANDLE_EOF {
+			// No streams at all, as for most directories.
+			return nil, nil
+		}
+		return nil, &os.PathError{Op: "findfirststream", Path: path, Err: err}
+	}
+	defer syscall.FindClose(syscall.Handle(h))
+
+	streams := make(map[string]int64)
+	for {
+		// Named like ":name:$DATA", with the main stream being "::$DATA".
+		full := syscall.UTF16ToString(data.StreamName[:])
+		if name := strings.TrimSuffix(strings.TrimPrefix(full, ":"), ":$DATA"); name != "" && name != full {
+			streams[name] = data.StreamSize
+		}
+		if r, _, err := procFindNextStreamW.Call(h, uintptr(unsafe.Pointer(&data))); r == 0 {
+			if err == syscall.ERROR_HANDLE_EOF {
+				return streams, nil
+			}
+			return nil, &os.PathError{Op: "findnextstream", Path: path, Err: err}
+		}
+	}
+}
+
+// isJunction returns whether the item is a junction (a mount point
+// reparse point), as opposed to a symbolic link or anything else.
+func isJunction(path string) (bool, error) {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return false, err
+	}
+	var data syscall.Win32finddata
+	h, err := syscall.FindFirstFile(p, &data)
+	if err != nil {
+		return false, &os.PathError{Op: "findfirstfile", Path: path, Err: err}
+	}
+	syscall.FindClose(h)
+	return data.FileAttributes&syscall.FILE_ATTRIBUTE_REPARSE_POINT != 0 && data.Reserved0 == ioReparseTagMountPoint, nil
+}
+
+// createJunction creates a junction at path to the absolute target, which
+// unlike a symbolic link needs no privileges.
+func createJunction(target, path string) error {
+	if err := os.Mkdir(path, 0777); err != nil {
+		return err
+	}
+	if err := setMountPoint(target, path); err != nil {
+		os.Remove(path)
+		return &os.PathError{Op: "junction", Path: path, Err: err}
+	}
+	return nil
+}
+
+func setMountPoint(target, path string) error {
+	substitute := syscall.StringToUTF16(`\??\` + target)
+	print := syscall.StringToUTF16(target)
+
+	// REPARSE_DATA_BUFFER with the MountPointReparseBuffer, the names NUL
+	// terminated one after the other.
+	names := append(substitute, print...)
+	buf := make([]byte, 16+2*len(names))
+	binary.LittleEndian.PutUint32(buf[0:], ioReparseTagMountPoint)
+	binary.LittleEndian.PutUint16(buf[4:], uint16(len(buf)-8))
+	binary.LittleEndian.PutUint16(buf[8:], 0)
+	binary.LittleEndian.PutUint16(buf[10:], uint16(2*(len(substitute)-1)))
+	binary.LittleEndian.PutUint16(buf[12:], uint16(2*len(substitute)))
+	binary.LittleEndian.PutUint16(buf[14:], uint16(2*(len(print)-1)))
+	for i, c := range names {
+		binary.LittleEndian.PutUint16(buf[16+2*i:], c)
+	}
+
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return err
+	}
+	h, err := syscall.CreateFile(p, syscall.GENERIC_WRITE, 0, nil, syscall.OPEN_EXISTING, syscall.FILE_FLAG_OPEN_REPARSE_POINT|syscall.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		return err
+	}
+	defer syscall.CloseHandle(h)
+	var returned uint32
+	return syscall.DeviceIoControl(h, fsctlSetReparsePoint, &buf[0], uint32(len(buf)), nil, 0, &returned, nil)
+}
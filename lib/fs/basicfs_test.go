@@ -7,15 +7,18 @@
 package fs
 
 import (
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"sort"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/syncthing/syncthing/lib/protocol"
 	"github.com/syncthing/syncthing/lib/rand"
 )
 
@@ -355,6 +358,162 @@ func TestUsage(t *testing.T) {
 	}
 }
 
+func TestCloneRange(t *testing.T) {
+	fs, dir := setup(t)
+	defer os.RemoveAll(dir)
+
+	data := make([]byte, 256<<10)
+	io.ReadFull(rand.Reader, data)
+	if err := ioutil.WriteFile(filepath.Join(dir, "src"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := fs.Open("src")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	dst, err := fs.Create("dst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+
+	// Clone the second half of the source to the start of the destination.
+	half := int64(len(data) / 2)
+	if err := fs.CloneRange(src, half, dst, 0, half); err == ErrCloneNotSupported {
+		t.Skip("clones not supported on", dir)
+	} else if err != nil {
+		t.Fatal(err)
+	}
+
+	bs, err := ioutil.ReadFile(filepath.Join(dir, "dst"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(bs) != string(data[half:]) {
+		t.Error("cloned data does not match source")
+	}
+}
+
+func TestDedupeRange(t *testing.T) {
+	fs, dir := setup(t)
+	defer os.RemoveAll(dir)
+
+	data := make([]byte, 256<<10)
+	io.ReadFull(rand.Reader, data)
+	other := append([]byte(nil), data...)
+	other[0]++
+	for name, bs := range map[string][]byte{"src": data, "same": data, "other": other} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), bs, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	src, err := fs.Open("src")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	same, err := fs.OpenFile("same", OptReadWrite, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer same.Close()
+	otherFd, err := fs.OpenFile("other", OptReadWrite, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer otherFd.Close()
+
+	length := int64(len(data))
+	if err := fs.DedupeRange(src, 0, same, 0, length); err == ErrCloneNotSupported {
+		t.Skip("deduplication not supported on", dir)
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.DedupeRange(src, 0, otherFd, 0, length); err != ErrRangesDiffer {
+		t.Error("expected the differing ranges to be refused, got", err)
+	}
+
+	bs, err := ioutil.ReadFile(filepath.Join(dir, "other"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(bs) != string(other) {
+		t.Error("refused deduplication changed the data")
+	}
+}
+
+func TestHardlink(t *testing.T) {
+	fs, dir := setup(t)
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "src"), []byte("contents"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Hardlink("src", "dst"); err != nil {
+		t.Fatal(err)
+	}
+
+	srcInfo, err := fs.Lstat("src")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dstInfo, err := fs.Lstat("dst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fs.SameFile(srcInfo, dstInfo) {
+		t.Error("link is not the same file as the source")
+	}
+
+	// Removing the source leaves the link intact.
+	if err := fs.Remove("src"); err != nil {
+		t.Fatal(err)
+	}
+	if bs, err := ioutil.ReadFile(filepath.Join(dir, "dst")); err != nil {
+		t.Fatal(err)
+	} else if string(bs) != "contents" {
+		t.Errorf("link has contents %q", bs)
+	}
+}
+
+func TestXattr(t *testing.T) {
+	fs, dir := setup(t)
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "file"), []byte("contents"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []protocol.Xattr{
+		{Name: "user.a", Value: []byte("first")},
+		{Name: "user.b", Value: []byte("second")},
+	}
+	if err := fs.SetXattr("file", want); err == ErrXattrsNotSupported {
+		t.Skip("extended attributes not supported on", dir)
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := fs.GetXattr("file"); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, expected %v", got, want)
+	}
+
+	// Attributes missing from the new set are removed.
+	want = []protocol.Xattr{{Name: "user.b", Value: []byte("changed")}}
+	if err := fs.SetXattr("file", want); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := fs.GetXattr("file"); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, expected %v", got, want)
+	}
+}
+
 func TestRooted(t *testing.T) {
 	type testcase struct {
 		root   string
@@ -572,3 +731,119 @@ func TestBasicWalkSkipSymlink(t *testing.T) {
 	defer os.RemoveAll(dir)
 	testWalkSkipSymlink(t, FilesystemTypeBasic, dir)
 }
+
+// longTestPath returns a relative path, made of several directories, which
+// is longer than the 260 characters Windows allows without a prefix.
+func longTestPath(file string) string {
+	parts := make([]string, 0, 6)
+	for i := 0; i < 5; i++ {
+		parts = append(parts, strings.Repeat(string('a'+rune(i)), 60))
+	}
+	return filepath.Join(append(parts, file)...)
+}
+
+func TestBasicLongPaths(t *testing.T) {
+	fs, dir := setup(t)
+	defer os.RemoveAll(dir)
+
+	name := longTestPath("file")
+	if abs := filepath.Join(dir, name); len(abs) <= 260 {
+		t.Fatalf("Path %q isn't long enough", abs)
+	}
+
+	if err := fs.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		t.Fatal(err)
+	}
+	fd, err := fs.OpenFile(name, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd.Write([]byte("contents")); err != nil {
+		t.Fatal(err)
+	}
+	fd.Close()
+
+	renamed := filepath.Join(filepath.Dir(name), "renamed")
+	if err := fs.Rename(name, renamed); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Lstat(name); !IsNotExist(err) {
+		t.Error("Expected the old name not to exist, got", err)
+	}
+	info, err := fs.Lstat(renamed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != int64(len("contents")) {
+		t.Errorf("Unexpected size %d", info.Size())
+	}
+
+	if names, err := fs.DirNames(filepath.Dir(name)); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(names, []string{"renamed"}) {
+		t.Errorf("Unexpected names %v", names)
+	}
+
+	found := false
+	err = NewWalkFilesystem(fs).Walk(".", func(path string, info FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == renamed {
+			found = true
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found {
+		t.Errorf("Didn't walk %q", renamed)
+	}
+
+	if err := fs.Remove(renamed); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.RemoveAll(strings.Split(name, string(PathSeparator))[0]); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHoles(t *testing.T) {
+	fs, dir := setup(t)
+	defer os.RemoveAll(dir)
+
+	fd, err := fs.Create("sparse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+	const size, dataOffset, dataLength = 1 << 20, 512 << 10, 4 << 10
+	if err := fd.Truncate(size); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd.WriteAt(make([]byte, dataLength), dataOffset); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd.WriteAt([]byte{1}, dataOffset); err != nil {
+		t.Fatal(err)
+	}
+
+	holes, err := Holes(fd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(holes) == 0 {
+		t.Skip("No holes found, probably not supported here")
+	}
+	var total int64
+	for _, h := range holes {
+		if h.Offset < dataOffset+dataLength && h.Offset+h.Length > dataOffset {
+			t.Errorf("Hole %v overlaps the data", h)
+		}
+		total += h.Length
+	}
+	if total > size-dataLength {
+		t.Errorf("Holes of %d bytes in total, expected at most %d", total, size-dataLength)
+	}
+}
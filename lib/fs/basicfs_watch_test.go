@@ -395,6 +395,34 @@ func TestWatchIssue4877(t *testing.T) {
 	testScenario(t, name, testCase, expectedEvents, allowedEvents, fakeMatcher{})
 }
 
+// TestWatchLongPath checks that events are reported for files with paths
+// too long for Windows without the long filename prefix.
+func TestWatchLongPath(t *testing.T) {
+	if runtime.GOOS == "openbsd" {
+		t.Skip(failsOnOpenBSD)
+	}
+	name := "longpath"
+
+	file := longTestPath("file")
+	if err := testFs.MkdirAll(filepath.Join(name, filepath.Dir(file)), 0755); err != nil {
+		t.Fatal(err)
+	}
+	sleepMs(100)
+
+	testCase := func() {
+		createTestFile(name, file)
+	}
+
+	expectedEvents := []Event{
+		{file, NonRemove},
+	}
+	allowedEvents := []Event{
+		{name, NonRemove},
+	}
+
+	testScenario(t, name, testCase, expectedEvents, allowedEvents, fakeMatcher{})
+}
+
 // path relative to folder root, also creates parent dirs if necessary
 func createTestFile(name string, file string) string {
 	joined := filepath.Join(name, file)
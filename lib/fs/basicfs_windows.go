@@ -25,12 +25,46 @@ func (BasicFilesystem) SymlinksSupported() bool {
 	return false
 }
 
-func (BasicFilesystem) ReadSymlink(path string) (string, error) {
-	return "", errNotSupported
+// ReadSymlink reads the target of a junction; symbolic links aren't
+// supported. Targets within the folder are made relative, like those of
+// symbolic links elsewhere usually are, and have forward slashes.
+func (f *BasicFilesystem) ReadSymlink(name string) (string, error) {
+	path, err := f.rooted(name)
+	if err != nil {
+		return "", err
+	}
+	if junction, err := isJunction(path); err != nil {
+		return "", err
+	} else if !junction {
+		return "", errNotSupported
+	}
+	target, err := os.Readlink(path)
+	if err != nil {
+		return "", err
+	}
+	target = strings.TrimPrefix(target, `\??\`)
+
+	root := withoutLongFilenamePrefix(f.root)
+	if rel, err := filepath.Rel(root, target); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(PathSeparator)) {
+		if rel, err := filepath.Rel(filepath.Dir(withoutLongFilenamePrefix(path)), target); err == nil {
+			target = rel
+		}
+	}
+	return filepath.ToSlash(target), nil
 }
 
-func (BasicFilesystem) CreateSymlink(target, name string) error {
-	return errNotSupported
+// CreateSymlink creates a junction, as that needs no privileges. Junctions
+// can only point at directories, by absolute path.
+func (f *BasicFilesystem) CreateSymlink(target, name string) error {
+	path, err := f.rooted(name)
+	if err != nil {
+		return err
+	}
+	target = filepath.FromSlash(target)
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(withoutLongFilenamePrefix(path)), target)
+	}
+	return createJunction(target, path)
 }
 
 // Required due to https://github.com/golang/go/issues/10900
@@ -157,9 +191,12 @@ func (f *BasicFilesystem) Roots() ([]string, error) {
 // special case when the given path is the folder root without a trailing
 // pathseparator.
 func (f *BasicFilesystem) unrootedChecked(absPath string, roots []string) (string, error) {
-	absPath = f.resolveWin83(absPath)
+	// Paths may or may not have the long filename prefix, depending on
+	// where they are from, so they are compared with it.
+	absPath = longFilenameSupport(f.resolveWin83(absPath))
 	lowerAbsPath := UnicodeLowercase(absPath)
 	for _, root := range roots {
+		root = longFilenameSupport(root)
 		lowerRoot := UnicodeLowercase(root)
 		if lowerAbsPath+string(PathSeparator) == lowerRoot {
 			return ".", nil
@@ -272,9 +309,9 @@ func getFinalPathName(in string) (string, error) {
 
 func evalSymlinks(in string) (string, error) {
 	out, err := filepath.EvalSymlinks(in)
-	if err != nil && strings.HasPrefix(in, `\\?\`) {
-		// Try again without the `\\?\` prefix
-		out, err = filepath.EvalSymlinks(in[4:])
+	if short := withoutLongFilenamePrefix(in); err != nil && short != in {
+		// Try again without the long filename prefix
+		out, err = filepath.EvalSymlinks(short)
 	}
 	if err != nil {
 		// Try to get a normalized path from Win-API
@@ -285,11 +322,7 @@ func evalSymlinks(in string) (string, error) {
 		}
 		// Trim UNC prefix, equivalent to
 		// https://github.com/golang/go/blob/2396101e0590cb7d77556924249c26af0ccd9eff/src/os/file_windows.go#L470
-		if strings.HasPrefix(out, `\\?\UNC\`) {
-			out = `\` + out[7:] // path like \\server\share\...
-		} else {
-			out = strings.TrimPrefix(out, `\\?\`)
-		}
+		out = withoutLongFilenamePrefix(out)
 	}
 	return longFilenameSupport(out), nil
 }
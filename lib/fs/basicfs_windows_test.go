@@ -9,10 +9,14 @@
 package fs
 
 import (
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/syncthing/syncthing/lib/protocol"
 )
 
 func TestWindowsPaths(t *testing.T) {
@@ -23,7 +27,8 @@ func TestWindowsPaths(t *testing.T) {
 	}{
 		{`e:\`, `\\?\e:\`, `e:\`},
 		{`\\?\e:\`, `\\?\e:\`, `e:\`},
-		{`\\192.0.2.22\network\share`, `\\192.0.2.22\network\share`, `\\192.0.2.22\network\share`},
+		{`\\192.0.2.22\network\share`, `\\?\UNC\192.0.2.22\network\share`, `\\192.0.2.22\network\share`},
+		{`\\?\UNC\192.0.2.22\network\share`, `\\?\UNC\192.0.2.22\network\share`, `\\192.0.2.22\network\share`},
 	}
 
 	for _, testCase := range testCases {
@@ -140,6 +145,87 @@ func TestRelUnrootedCheckedWindows(t *testing.T) {
 	}
 }
 
+func TestLongFilenamePrefix(t *testing.T) {
+	testCases := []struct {
+		short string
+		long  string
+	}{
+		{`c:\foo`, `\\?\c:\foo`},
+		{`\\server\share\foo`, `\\?\UNC\server\share\foo`},
+		{`\\.\pipe\foo`, `\\.\pipe\foo`},
+		{`relative\foo`, `relative\foo`},
+	}
+
+	for _, tc := range testCases {
+		if res := longFilenameSupport(tc.short); res != tc.long {
+			t.Errorf(`longFilenameSupport("%v") == "%v", expected "%v"`, tc.short, res, tc.long)
+		}
+		if res := longFilenameSupport(tc.long); res != tc.long {
+			t.Errorf(`longFilenameSupport("%v") == "%v", expected it unchanged`, tc.long, res)
+		}
+		if res := withoutLongFilenamePrefix(tc.long); res != tc.short {
+			t.Errorf(`withoutLongFilenamePrefix("%v") == "%v", expected "%v"`, tc.long, res, tc.short)
+		}
+	}
+}
+
+// TestUnrootedCheckedPrefixes checks that watch events are matched to the
+// root whether or not either has the long filename prefix.
+func TestUnrootedCheckedPrefixes(t *testing.T) {
+	testCases := []struct {
+		root string
+		abs  string
+	}{
+		{`c:\foo`, `\\?\c:\foo\bar`},
+		{`\\?\c:\foo`, `c:\foo\bar`},
+		{`\\server\share`, `\\?\UNC\server\share\bar`},
+		{`\\?\UNC\server\share`, `\\server\share\bar`},
+		{`\\?\unc\server\share`, `\\Server\Share\bar`},
+	}
+
+	for _, tc := range testCases {
+		fs := BasicFilesystem{root: tc.root}
+		if res, err := fs.unrootedChecked(tc.abs, []string{tc.root}); err != nil {
+			t.Errorf(`Unexpected error from unrootedChecked("%v", "%v"): %v`, tc.abs, tc.root, err)
+		} else if res != "bar" {
+			t.Errorf(`unrootedChecked("%v", "%v") == "%v", expected "bar"`, tc.abs, tc.root, res)
+		}
+	}
+}
+
+// TestUNCRoot checks basic operations on a folder given as a UNC path,
+// through the administrative share of the temporary directory's drive.
+func TestUNCRoot(t *testing.T) {
+	_, dir := setup(t)
+	defer os.RemoveAll(dir)
+
+	vol := filepath.VolumeName(dir)
+	if len(vol) != 2 || vol[1] != ':' {
+		t.Skip("Temporary directory isn't on a drive")
+	}
+	unc := `\\localhost\` + vol[:1] + `$` + dir[len(vol):]
+	if _, err := os.Stat(unc); err != nil {
+		t.Skip("Administrative share not available:", err)
+	}
+
+	fs := newBasicFilesystem(unc)
+	name := longTestPath("file")
+	if err := fs.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		t.Fatal(err)
+	}
+	fd, err := fs.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd.Close()
+	if err := fs.Rename(name, name+"-renamed"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(longFilenameSupport(filepath.Join(dir, name+"-renamed"))); err != nil {
+		t.Error("Renamed file not found through the drive:", err)
+	}
+}
+
 // TestMultipleRoot checks that fs.unrootedChecked returns the correct path
 // when given more than one possible root path.
 func TestMultipleRoot(t *testing.T) {
@@ -193,3 +279,64 @@ func TestGetFinalPath(t *testing.T) {
 		}
 	}
 }
+
+func TestPlatformDataWindows(t *testing.T) {
+	dir, err := ioutil.TempDir("", "syncthing-platformdata-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fs := newBasicFilesystem(dir)
+
+	fd, err := fs.Create("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd.Close()
+
+	var data protocol.PlatformData
+	data.Windows.Streams = []protocol.DataStream{
+		{Name: "a", Data: []byte("first")},
+		{Name: "b", Data: []byte("second")},
+	}
+	if err := fs.SetPlatformData("file", data); err != nil {
+		t.Fatal(err)
+	}
+	got, err := fs.GetPlatformData("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, data) {
+		t.Errorf("Got %+v, expected %+v", got, data)
+	}
+
+	// Streams that aren't wanted are removed.
+	data.Windows.Streams = data.Windows.Streams[1:]
+	if err := fs.SetPlatformData("file", data); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := fs.GetPlatformData("file"); err != nil || !reflect.DeepEqual(got, data) {
+		t.Errorf("Got %+v, %v, expected %+v", got, err, data)
+	}
+
+	// Junctions read like relative symlinks.
+	if err := fs.Mkdir("target", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.CreateSymlink("target", "link"); err != nil {
+		t.Fatal(err)
+	}
+	info, err := fs.Lstat("link")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.IsSymlink() {
+		t.Error("A junction should be a symlink")
+	}
+	if got, err := fs.GetPlatformData("link"); err != nil || !got.Windows.Junction {
+		t.Errorf("Expected a junction, got %+v, %v", got, err)
+	}
+	if target, err := fs.ReadSymlink("link"); err != nil || target != "target" {
+		t.Errorf("Got target %q, %v", target, err)
+	}
+}
@@ -0,0 +1,17 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import "golang.org/x/sys/unix"
+
+const errNoXattr = unix.ENOATTR
+
+// There are no namespaces on macOS; Finder tags, quarantine flags and so
+// on are all plain attributes.
+func isSyncedXattr(name string) bool {
+	return true
+}
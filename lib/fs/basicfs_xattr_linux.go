@@ -0,0 +1,23 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// +build linux
+
+package fs
+
+import (
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+const errNoXattr = unix.ENODATA
+
+// Only the user and security namespaces are synced; the others are either
+// managed by the kernel or require privileges we don't expect to have.
+func isSyncedXattr(name string) bool {
+	return strings.HasPrefix(name, "user.") || strings.HasPrefix(name, "security.")
+}
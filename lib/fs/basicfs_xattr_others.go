@@ -0,0 +1,19 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// +build !linux,!darwin
+
+package fs
+
+import "github.com/syncthing/syncthing/lib/protocol"
+
+func getXattr(path string) ([]protocol.Xattr, error) {
+	return nil, ErrXattrsNotSupported
+}
+
+func setXattr(path string, xattrs []protocol.Xattr) error {
+	return ErrXattrsNotSupported
+}
@@ -0,0 +1,138 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// +build linux darwin
+
+package fs
+
+import (
+	"bytes"
+	"os"
+	"sort"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// Attributes with larger values are not synced.
+const maxXattrValueSize = 64 << 10
+
+func getXattr(path string) ([]protocol.Xattr, error) {
+	names, err := listXattr(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var xattrs []protocol.Xattr
+	buf := make([]byte, 1024)
+	for _, name := range names {
+		if !isSyncedXattr(name) {
+			continue
+		}
+		n, err := unix.Lgetxattr(path, name, buf)
+		if err == unix.ERANGE {
+			size, err := unix.Lgetxattr(path, name, nil)
+			if err != nil {
+				return nil, xattrError("getxattr", path, err)
+			}
+			if size > maxXattrValueSize {
+				continue
+			}
+			buf = make([]byte, size)
+			n, err = unix.Lgetxattr(path, name, buf)
+		}
+		if err == errNoXattr {
+			// Removed since we listed it.
+			continue
+		}
+		if err != nil {
+			return nil, xattrError("getxattr", path, err)
+		}
+		if n > maxXattrValueSize {
+			continue
+		}
+		xattrs = append(xattrs, protocol.Xattr{
+			Name:  name,
+			Value: append([]byte(nil), buf[:n]...),
+		})
+	}
+
+	sort.Slice(xattrs, func(a, b int) bool {
+		return xattrs[a].Name < xattrs[b].Name
+	})
+	return xattrs, nil
+}
+
+func setXattr(path string, xattrs []protocol.Xattr) error {
+	names, err := listXattr(path)
+	if err != nil {
+		return err
+	}
+
+	wanted := make(map[string][]byte, len(xattrs))
+	for _, xa := range xattrs {
+		wanted[xa.Name] = xa.Value
+	}
+
+	buf := make([]byte, maxXattrValueSize)
+	for _, name := range names {
+		if !isSyncedXattr(name) {
+			continue
+		}
+		if value, ok := wanted[name]; ok {
+			if n, err := unix.Lgetxattr(path, name, buf); err == nil && bytes.Equal(buf[:n], value) {
+				delete(wanted, name)
+			}
+			continue
+		}
+		if err := unix.Lremovexattr(path, name); err != nil && err != errNoXattr {
+			return xattrError("removexattr", path, err)
+		}
+	}
+
+	for _, xa := range xattrs {
+		value, ok := wanted[xa.Name]
+		if !ok || !isSyncedXattr(xa.Name) {
+			continue
+		}
+		if err := unix.Lsetxattr(path, xa.Name, value, 0); err != nil {
+			return xattrError("setxattr", path, err)
+		}
+	}
+	return nil
+}
+
+func listXattr(path string) ([]string, error) {
+	size, err := unix.Llistxattr(path, nil)
+	if err != nil {
+		return nil, xattrError("listxattr", path, err)
+	}
+	if size == 0 {
+		return nil, nil
+	}
+	buf := make([]byte, size)
+	size, err = unix.Llistxattr(path, buf)
+	if err != nil {
+		return nil, xattrError("listxattr", path, err)
+	}
+
+	// The names are NUL terminated, one after the other.
+	var names []string
+	for _, name := range bytes.Split(buf[:size], []byte{0}) {
+		if len(name) > 0 {
+			names = append(names, string(name))
+		}
+	}
+	return names, nil
+}
+
+func xattrError(op, path string, err error) error {
+	if err == unix.ENOTSUP || err == unix.EOPNOTSUPP {
+		return ErrXattrsNotSupported
+	}
+	return &os.PathError{Op: op, Path: path, Err: err}
+}
@@ -0,0 +1,138 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import (
+	"time"
+
+	"github.com/syncthing/syncthing/lib/chaos"
+)
+
+// The chaosFilesystem fails operations as configured in the chaos package.
+// It's only used in builds with the chaos tag.
+type chaosFilesystem struct {
+	Filesystem
+}
+
+func (fs *chaosFilesystem) Chmod(name string, mode FileMode) error {
+	if err := chaos.FSError("chmod", name); err != nil {
+		return err
+	}
+	return fs.Filesystem.Chmod(name, mode)
+}
+
+func (fs *chaosFilesystem) Lchown(name string, uid, gid int) error {
+	if err := chaos.FSError("lchown", name); err != nil {
+		return err
+	}
+	return fs.Filesystem.Lchown(name, uid, gid)
+}
+
+func (fs *chaosFilesystem) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	if err := chaos.FSError("chtimes", name); err != nil {
+		return err
+	}
+	return fs.Filesystem.Chtimes(name, atime, mtime)
+}
+
+func (fs *chaosFilesystem) Create(name string) (File, error) {
+	if err := chaos.FSError("create", name); err != nil {
+		return nil, err
+	}
+	return fs.Filesystem.Create(name)
+}
+
+func (fs *chaosFilesystem) CreateSymlink(target, name string) error {
+	if err := chaos.FSError("symlink", name); err != nil {
+		return err
+	}
+	return fs.Filesystem.CreateSymlink(target, name)
+}
+
+func (fs *chaosFilesystem) DirNames(name string) ([]string, error) {
+	if err := chaos.FSError("readdir", name); err != nil {
+		return nil, err
+	}
+	return fs.Filesystem.DirNames(name)
+}
+
+func (fs *chaosFilesystem) Lstat(name string) (FileInfo, error) {
+	if err := chaos.FSError("lstat", name); err != nil {
+		return nil, err
+	}
+	return fs.Filesystem.Lstat(name)
+}
+
+func (fs *chaosFilesystem) Mkdir(name string, perm FileMode) error {
+	if err := chaos.FSError("mkdir", name); err != nil {
+		return err
+	}
+	return fs.Filesystem.Mkdir(name, perm)
+}
+
+func (fs *chaosFilesystem) MkdirAll(name string, perm FileMode) error {
+	if err := chaos.FSError("mkdir", name); err != nil {
+		return err
+	}
+	return fs.Filesystem.MkdirAll(name, perm)
+}
+
+func (fs *chaosFilesystem) Open(name string) (File, error) {
+	if err := chaos.FSError("open", name); err != nil {
+		return nil, err
+	}
+	return fs.Filesystem.Open(name)
+}
+
+func (fs *chaosFilesystem) OpenFile(name string, flags int, mode FileMode) (File, error) {
+	if err := chaos.FSError("open", name); err != nil {
+		return nil, err
+	}
+	return fs.Filesystem.OpenFile(name, flags, mode)
+}
+
+func (fs *chaosFilesystem) ReadSymlink(name string) (string, error) {
+	if err := chaos.FSError("readlink", name); err != nil {
+		return "", err
+	}
+	return fs.Filesystem.ReadSymlink(name)
+}
+
+func (fs *chaosFilesystem) Remove(name string) error {
+	if err := chaos.FSError("remove", name); err != nil {
+		return err
+	}
+	return fs.Filesystem.Remove(name)
+}
+
+func (fs *chaosFilesystem) RemoveAll(name string) error {
+	if err := chaos.FSError("remove", name); err != nil {
+		return err
+	}
+	return fs.Filesystem.RemoveAll(name)
+}
+
+func (fs *chaosFilesystem) Rename(oldname, newname string) error {
+	if err := chaos.FSError("rename", oldname); err != nil {
+		return err
+	}
+	return fs.Filesystem.Rename(oldname, newname)
+}
+
+func (fs *chaosFilesystem) Hardlink(oldname, newname string) error {
+	if err := chaos.FSError("link", oldname); err != nil {
+		return err
+	}
+	return fs.Filesystem.Hardlink(oldname, newname)
+}
+
+func (fs *chaosFilesystem) Stat(name string) (FileInfo, error) {
+	if err := chaos.FSError("stat", name); err != nil {
+		return nil, err
+	}
+	return fs.Filesystem.Stat(name)
+}
@@ -0,0 +1,482 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+var (
+	errCrossRoot   = errors.New("cannot move directories between virtual roots")
+	errVirtualRoot = errors.New("virtual roots cannot be removed or replaced")
+)
+
+// The compositeFilesystem merges several filesystems into one. Each of the
+// roots appears as a top level directory with the given name, and
+// everything else is in the base filesystem. The base is also where the
+// folder marker, ignores and such live.
+type compositeFilesystem struct {
+	base  Filesystem
+	roots map[string]Filesystem
+	names []string // sorted root names
+}
+
+// NewCompositeFilesystem returns a filesystem where each of the roots
+// appears as a top level directory of the base filesystem, shadowing
+// anything of the same name there.
+func NewCompositeFilesystem(base Filesystem, roots map[string]Filesystem) Filesystem {
+	names := make([]string, 0, len(roots))
+	for name := range roots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var fs Filesystem = &compositeFilesystem{
+		base:  base,
+		roots: roots,
+		names: names,
+	}
+	if l.ShouldDebug("fs") {
+		fs = &logFilesystem{fs}
+	}
+	return NewWalkFilesystem(fs)
+}
+
+// route returns the filesystem the named file is in and its name there.
+// The name of a root itself is "." in that root.
+func (f *compositeFilesystem) route(name string) (Filesystem, string) {
+	fs, rel, _ := f.routePrefix(name)
+	return fs, rel
+}
+
+// routePrefix is like route, but also returns what to prefix names in the
+// returned filesystem with to get the name in the composite filesystem.
+func (f *compositeFilesystem) routePrefix(name string) (Filesystem, string, string) {
+	name = filepath.Clean(name)
+	first, rest := name, "."
+	if i := strings.IndexRune(name, PathSeparator); i >= 0 {
+		first, rest = name[:i], name[i+1:]
+	}
+	if root, ok := f.roots[first]; ok {
+		return root, rest, first + string(PathSeparator)
+	}
+	return f.base, name, ""
+}
+
+// prefixed returns the name in the composite filesystem of the name
+// relative to a root, given the prefix from routePrefix.
+func prefixed(prefix, name string) string {
+	if name == "." && prefix != "" {
+		return strings.TrimSuffix(prefix, string(PathSeparator))
+	}
+	return prefix + name
+}
+
+// isRoot returns true if the name is that of one of the roots.
+func (f *compositeFilesystem) isRoot(name string) bool {
+	_, ok := f.roots[filepath.Clean(name)]
+	return ok
+}
+
+func (f *compositeFilesystem) Chmod(name string, mode FileMode) error {
+	fs, name := f.route(name)
+	return fs.Chmod(name, mode)
+}
+
+func (f *compositeFilesystem) Lchown(name string, uid, gid int) error {
+	fs, name := f.route(name)
+	return fs.Lchown(name, uid, gid)
+}
+
+func (f *compositeFilesystem) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	fs, name := f.route(name)
+	return fs.Chtimes(name, atime, mtime)
+}
+
+func (f *compositeFilesystem) Create(name string) (File, error) {
+	if f.isRoot(name) {
+		return nil, errVirtualRoot
+	}
+	fs, name := f.route(name)
+	return fs.Create(name)
+}
+
+func (f *compositeFilesystem) CreateSymlink(target, name string) error {
+	if f.isRoot(name) {
+		return errVirtualRoot
+	}
+	fs, name := f.route(name)
+	return fs.CreateSymlink(target, name)
+}
+
+func (f *compositeFilesystem) DirNames(name string) ([]string, error) {
+	fs, rel := f.route(name)
+	names, err := fs.DirNames(rel)
+	if err != nil || fs != f.base || filepath.Clean(name) != "." {
+		return names, err
+	}
+
+	// The roots replace anything of the same name in the base.
+	res := make([]string, 0, len(names)+len(f.names))
+	for _, n := range names {
+		if _, ok := f.roots[n]; !ok {
+			res = append(res, n)
+		}
+	}
+	return append(res, f.names...), nil
+}
+
+func (f *compositeFilesystem) Lstat(name string) (FileInfo, error) {
+	fs, rel := f.route(name)
+	info, err := fs.Lstat(rel)
+	if err != nil || !f.isRoot(name) {
+		return info, err
+	}
+	return compositeRootInfo{info, filepath.Clean(name)}, nil
+}
+
+func (f *compositeFilesystem) Stat(name string) (FileInfo, error) {
+	fs, rel := f.route(name)
+	info, err := fs.Stat(rel)
+	if err != nil || !f.isRoot(name) {
+		return info, err
+	}
+	return compositeRootInfo{info, filepath.Clean(name)}, nil
+}
+
+func (f *compositeFilesystem) Mkdir(name string, perm FileMode) error {
+	if f.isRoot(name) {
+		return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrExist}
+	}
+	fs, name := f.route(name)
+	return fs.Mkdir(name, perm)
+}
+
+func (f *compositeFilesystem) MkdirAll(name string, perm FileMode) error {
+	fs, name := f.route(name)
+	return fs.MkdirAll(name, perm)
+}
+
+func (f *compositeFilesystem) Open(name string) (File, error) {
+	fs, name := f.route(name)
+	return fs.Open(name)
+}
+
+func (f *compositeFilesystem) OpenFile(name string, flags int, mode FileMode) (File, error) {
+	if f.isRoot(name) {
+		return nil, errVirtualRoot
+	}
+	fs, name := f.route(name)
+	return fs.OpenFile(name, flags, mode)
+}
+
+func (f *compositeFilesystem) ReadSymlink(name string) (string, error) {
+	fs, name := f.route(name)
+	return fs.ReadSymlink(name)
+}
+
+func (f *compositeFilesystem) Remove(name string) error {
+	if f.isRoot(name) {
+		return errVirtualRoot
+	}
+	fs, name := f.route(name)
+	return fs.Remove(name)
+}
+
+func (f *compositeFilesystem) RemoveAll(name string) error {
+	if f.isRoot(name) {
+		return errVirtualRoot
+	}
+	fs, name := f.route(name)
+	return fs.RemoveAll(name)
+}
+
+func (f *compositeFilesystem) Rename(oldname, newname string) error {
+	if f.isRoot(oldname) || f.isRoot(newname) {
+		return errVirtualRoot
+	}
+	oldfs, oldname := f.route(oldname)
+	newfs, newname := f.route(newname)
+	if oldfs != newfs {
+		// Such as when versioning a file into .stversions in the base.
+		return moveFile(oldfs, newfs, oldname, newname)
+	}
+	return oldfs.Rename(oldname, newname)
+}
+
+func (f *compositeFilesystem) Hardlink(oldname, newname string) error {
+	if f.isRoot(newname) {
+		return errVirtualRoot
+	}
+	oldfs, oldname := f.route(oldname)
+	newfs, newname := f.route(newname)
+	if oldfs != newfs {
+		return errCrossRoot
+	}
+	return oldfs.Hardlink(oldname, newname)
+}
+
+func (f *compositeFilesystem) SymlinksSupported() bool {
+	if !f.base.SymlinksSupported() {
+		return false
+	}
+	for _, root := range f.roots {
+		if !root.SymlinksSupported() {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *compositeFilesystem) Walk(name string, walkFn WalkFunc) error {
+	// Provided by the walkFilesystem wrapping us, based on DirNames and
+	// Lstat.
+	return errors.New("not implemented")
+}
+
+// Watch watches the named directory, which for the top level means
+// watching the base and each of the roots.
+func (f *compositeFilesystem) Watch(name string, ignore Matcher, ctx context.Context, ignorePerms bool) (<-chan Event, <-chan error, error) {
+	if filepath.Clean(name) != "." {
+		fs, rel, prefix := f.routePrefix(name)
+		if fs == f.base {
+			return f.base.Watch(rel, ignore, ctx, ignorePerms)
+		}
+		return f.watchRoot(fs, prefix, rel, ignore, ctx, ignorePerms)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	baseEvents, baseErrs, err := f.base.Watch(".", ignore, ctx, ignorePerms)
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+
+	events := make(chan Event)
+	errs := make(chan error, 1+len(f.names))
+	forward := func(in <-chan Event, inErrs <-chan error, filter func(Event) bool) {
+		for {
+			select {
+			case ev, ok := <-in:
+				if !ok {
+					return
+				}
+				if filter != nil && !filter(ev) {
+					continue
+				}
+				select {
+				case events <- ev:
+				case <-ctx.Done():
+					return
+				}
+			case err := <-inErrs:
+				// A failing watch stops all of them, as for a single
+				// filesystem.
+				errs <- err
+				cancel()
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+
+	// Changes in the base under the name of a root are shadowed.
+	go forward(baseEvents, baseErrs, func(ev Event) bool {
+		fs, _ := f.route(ev.Name)
+		return fs == f.base
+	})
+	for _, rootName := range f.names {
+		rootEvents, rootErrs, err := f.watchRoot(f.roots[rootName], rootName+string(PathSeparator), ".", ignore, ctx, ignorePerms)
+		if err != nil {
+			cancel()
+			return nil, nil, err
+		}
+		go forward(rootEvents, rootErrs, nil)
+	}
+
+	return events, errs, nil
+}
+
+// watchRoot watches the named directory in the given root, returning
+// events with the prefix added to their names.
+func (f *compositeFilesystem) watchRoot(fs Filesystem, prefix, name string, ignore Matcher, ctx context.Context, ignorePerms bool) (<-chan Event, <-chan error, error) {
+	in, errs, err := fs.Watch(name, prefixedMatcher{ignore, prefix}, ctx, ignorePerms)
+	if err != nil {
+		return nil, nil, err
+	}
+	out := make(chan Event)
+	go func() {
+		for {
+			select {
+			case ev, ok := <-in:
+				if !ok {
+					return
+				}
+				ev.Name = prefixed(prefix, ev.Name)
+				select {
+				case out <- ev:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, errs, nil
+}
+
+func (f *compositeFilesystem) Hide(name string) error {
+	fs, name := f.route(name)
+	return fs.Hide(name)
+}
+
+func (f *compositeFilesystem) Unhide(name string) error {
+	fs, name := f.route(name)
+	return fs.Unhide(name)
+}
+
+func (f *compositeFilesystem) Glob(pattern string) ([]string, error) {
+	fs, rel, prefix := f.routePrefix(pattern)
+	matches, err := fs.Glob(rel)
+	if err != nil {
+		return nil, err
+	}
+	for i := range matches {
+		matches[i] = prefixed(prefix, matches[i])
+	}
+	return matches, nil
+}
+
+func (f *compositeFilesystem) Roots() ([]string, error) {
+	return f.base.Roots()
+}
+
+func (f *compositeFilesystem) Usage(name string) (Usage, error) {
+	fs, name := f.route(name)
+	return fs.Usage(name)
+}
+
+func (f *compositeFilesystem) Type() FilesystemType {
+	return f.base.Type()
+}
+
+func (f *compositeFilesystem) URI() string {
+	return f.base.URI()
+}
+
+func (f *compositeFilesystem) SameFile(fi1, fi2 FileInfo) bool {
+	if ri, ok := fi1.(compositeRootInfo); ok {
+		fi1 = ri.FileInfo
+	}
+	if ri, ok := fi2.(compositeRootInfo); ok {
+		fi2 = ri.FileInfo
+	}
+	return f.base.SameFile(fi1, fi2)
+}
+
+func (f *compositeFilesystem) CloneRange(src File, srcOffset int64, dst File, dstOffset, length int64) error {
+	return f.base.CloneRange(src, srcOffset, dst, dstOffset, length)
+}
+
+func (f *compositeFilesystem) DedupeRange(src File, srcOffset int64, dst File, dstOffset, length int64) error {
+	return f.base.DedupeRange(src, srcOffset, dst, dstOffset, length)
+}
+
+func (f *compositeFilesystem) GetXattr(name string) ([]protocol.Xattr, error) {
+	fs, name := f.route(name)
+	return fs.GetXattr(name)
+}
+
+func (f *compositeFilesystem) SetXattr(name string, xattrs []protocol.Xattr) error {
+	fs, name := f.route(name)
+	return fs.SetXattr(name, xattrs)
+}
+
+func (f *compositeFilesystem) GetPlatformData(name string) (protocol.PlatformData, error) {
+	fs, name := f.route(name)
+	return fs.GetPlatformData(name)
+}
+
+func (f *compositeFilesystem) SetPlatformData(name string, data protocol.PlatformData) error {
+	fs, name := f.route(name)
+	return fs.SetPlatformData(name, data)
+}
+
+// moveFile moves a regular file between filesystems by copying and
+// removing it, keeping its permissions and modification time. The file is
+// first copied to a temporary name, so that an existing file at the
+// destination is only replaced once the copy is complete.
+func moveFile(srcFs, dstFs Filesystem, from, to string) error {
+	info, err := srcFs.Lstat(from)
+	if err != nil {
+		return err
+	}
+	if !info.IsRegular() {
+		return errCrossRoot
+	}
+
+	src, err := srcFs.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp := TempName(to)
+	dst, err := dstFs.OpenFile(tmp, OptWriteOnly|OptCreate|OptTruncate, info.Mode()&ModePerm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		dstFs.Remove(tmp)
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		dstFs.Remove(tmp)
+		return err
+	}
+	dstFs.Chtimes(tmp, info.ModTime(), info.ModTime())
+
+	if err := dstFs.Rename(tmp, to); err != nil {
+		dstFs.Remove(tmp)
+		return err
+	}
+	return srcFs.Remove(from)
+}
+
+// compositeRootInfo is the FileInfo of a root, named as it appears in the
+// composite filesystem.
+type compositeRootInfo struct {
+	FileInfo
+	name string
+}
+
+func (i compositeRootInfo) Name() string {
+	return i.name
+}
+
+// prefixedMatcher matches names relative to a root as the names they have
+// in the composite filesystem.
+type prefixedMatcher struct {
+	Matcher
+	prefix string
+}
+
+func (m prefixedMatcher) ShouldIgnore(name string) bool {
+	return m.Matcher.ShouldIgnore(prefixed(m.prefix, name))
+}
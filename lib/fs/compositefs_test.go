@@ -0,0 +1,106 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestCompositeFilesystem(t *testing.T) {
+	base, baseDir := setup(t)
+	defer os.RemoveAll(baseDir)
+	docs, docsDir := setup(t)
+	defer os.RemoveAll(docsDir)
+	pics, picsDir := setup(t)
+	defer os.RemoveAll(picsDir)
+
+	for _, name := range []string{
+		filepath.Join(baseDir, "top"),
+		filepath.Join(baseDir, "Docs", "shadowed"),
+		filepath.Join(docsDir, "dir", "doc"),
+		filepath.Join(picsDir, "pic"),
+	} {
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fs := NewCompositeFilesystem(base, map[string]Filesystem{
+		"Docs":     docs,
+		"Pictures": pics,
+	})
+
+	// The roots appear as directories in the top level, hiding the
+	// directory of the same name in the base.
+	var walked []string
+	err := fs.Walk(".", func(path string, info FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != "." && info.Name() != filepath.Base(path) {
+			t.Errorf("name of %s is %s", path, info.Name())
+		}
+		walked = append(walked, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(walked)
+	expected := []string{
+		".",
+		"Docs",
+		filepath.Join("Docs", "dir"),
+		filepath.Join("Docs", "dir", "doc"),
+		"Pictures",
+		filepath.Join("Pictures", "pic"),
+		"top",
+	}
+	if !reflect.DeepEqual(walked, expected) {
+		t.Errorf("walked %v, expected %v", walked, expected)
+	}
+
+	// Files are created in the directory of their root.
+	fd, err := fs.Create(filepath.Join("Pictures", "new"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd.Close()
+	if _, err := os.Stat(filepath.Join(picsDir, "new")); err != nil {
+		t.Error("file should have been created in the root:", err)
+	}
+
+	// Files can be moved between roots, directories can't.
+	if err := fs.Rename(filepath.Join("Pictures", "new"), filepath.Join("Docs", "new")); err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Stat(filepath.Join(docsDir, "new")); err != nil {
+		t.Error("file should have been moved to the other root:", err)
+	}
+	if _, err := os.Stat(filepath.Join(picsDir, "new")); !os.IsNotExist(err) {
+		t.Error("file should have been removed from the root it was moved from:", err)
+	}
+	if err := fs.Rename(filepath.Join("Docs", "dir"), filepath.Join("Pictures", "dir")); err != errCrossRoot {
+		t.Error("expected error moving a directory between roots, got", err)
+	}
+
+	// The roots themselves can't be removed.
+	if err := fs.RemoveAll("Docs"); err != errVirtualRoot {
+		t.Error("expected error removing a root, got", err)
+	}
+	if _, err := os.Stat(docsDir); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,17 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+// TransparentCompression returns true if the filesystem is of a kind that
+// can compress data by itself, making it pointless to compress it on the
+// way there.
+func TransparentCompression(filesystem Filesystem) bool {
+	if filesystem.Type() != FilesystemTypeBasic {
+		return false
+	}
+	return transparentCompression(filesystem.URI())
+}
@@ -0,0 +1,994 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+const (
+	// Kept in the root of the underlying filesystem, and hidden by the
+	// encrypted one.
+	encryptionConfigName = ".stencryption"
+
+	encHeaderSize  = 18   // version and file ID
+	encBlockSize   = 4096 // of plaintext
+	encNonceSize   = 12
+	encOverhead    = encNonceSize + 16 // nonce and GCM tag
+	encCipherBlock = encBlockSize + encOverhead
+	encNameIVSize  = 16
+	encVersion     = 1
+
+	// Names longer than this don't fit in the 255 bytes most filesystems
+	// allow once encrypted and encoded.
+	encMaxNameLen = 255*5/8 - encNameIVSize
+
+	// The scrypt parameters recommended for interactive logins.
+	encScryptN = 1 << 15
+	encScryptR = 8
+	encScryptP = 1
+)
+
+var (
+	errEncryptionPassphrase = errors.New("wrong passphrase for encrypted folder")
+	errEncryptionNotEmpty   = errors.New("directory is not empty and not encrypted; encryption can only be enabled for an empty directory")
+	errEncryptedName        = errors.New("not an encrypted name")
+	errEncryptedNameTooLong = errors.New("name too long to encrypt")
+	errEncryptedBlock       = errors.New("encrypted file is corrupt")
+)
+
+// Lower case base32 works on case insensitive filesystems.
+var encNameEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// encryptionConfig is the contents of the encryption config file.
+type encryptionConfig struct {
+	Version int    `json:"version"`
+	Salt    []byte `json:"salt"`
+	Check   []byte `json:"check"`
+}
+
+// encryptionKeySet holds the keys derived from a passphrase and salt.
+type encryptionKeySet struct {
+	content cipher.AEAD
+	nameEnc cipher.Block
+	nameMac []byte
+	check   []byte
+}
+
+// Deriving keys is slow on purpose, so they are kept for when the folder's
+// filesystem is created again.
+var (
+	encryptionKeySetsMut = sync.NewMutex()
+	encryptionKeySets    = make(map[[sha256.Size]byte]*encryptionKeySet)
+)
+
+func deriveEncryptionKeys(passphrase string, salt []byte) (*encryptionKeySet, error) {
+	id := sha256.Sum256(append([]byte(passphrase+"\x00"), salt...))
+	encryptionKeySetsMut.Lock()
+	defer encryptionKeySetsMut.Unlock()
+	if keys, ok := encryptionKeySets[id]; ok {
+		return keys, nil
+	}
+
+	master, err := scrypt.Key([]byte(passphrase), salt, encScryptN, encScryptR, encScryptP, 32)
+	if err != nil {
+		return nil, err
+	}
+	subkey := func(purpose string) []byte {
+		h := hmac.New(sha256.New, master)
+		h.Write([]byte(purpose))
+		return h.Sum(nil)
+	}
+	contentBlock, err := aes.NewCipher(subkey("syncthing encrypted content"))
+	if err != nil {
+		return nil, err
+	}
+	content, err := cipher.NewGCM(contentBlock)
+	if err != nil {
+		return nil, err
+	}
+	nameEnc, err := aes.NewCipher(subkey("syncthing encrypted names"))
+	if err != nil {
+		return nil, err
+	}
+	keys := &encryptionKeySet{
+		content: content,
+		nameEnc: nameEnc,
+		nameMac: subkey("syncthing authenticated names"),
+		check:   subkey("syncthing passphrase check"),
+	}
+	encryptionKeySets[id] = keys
+	return keys, nil
+}
+
+// encryptName encrypts deterministically, so that the same name is always
+// found under the same encrypted name, with an IV derived from the name
+// that also authenticates it (SIV).
+func (k *encryptionKeySet) encryptName(name string) string {
+	mac := hmac.New(sha256.New, k.nameMac)
+	mac.Write([]byte(name))
+	iv := mac.Sum(nil)[:encNameIVSize]
+	out := make([]byte, encNameIVSize+len(name))
+	copy(out, iv)
+	cipher.NewCTR(k.nameEnc, iv).XORKeyStream(out[encNameIVSize:], []byte(name))
+	return encNameEncoding.EncodeToString(out)
+}
+
+func (k *encryptionKeySet) decryptName(encrypted string) (string, error) {
+	bs, err := encNameEncoding.DecodeString(encrypted)
+	if err != nil || len(bs) < encNameIVSize {
+		return "", errEncryptedName
+	}
+	iv := bs[:encNameIVSize]
+	name := make([]byte, len(bs)-encNameIVSize)
+	cipher.NewCTR(k.nameEnc, iv).XORKeyStream(name, bs[encNameIVSize:])
+	mac := hmac.New(sha256.New, k.nameMac)
+	mac.Write(name)
+	if !hmac.Equal(mac.Sum(nil)[:encNameIVSize], iv) {
+		return "", errEncryptedName
+	}
+	return string(name), nil
+}
+
+// The encryptedFilesystem encrypts the contents and names of files in the
+// underlying filesystem, for folders on disks that aren't trusted, while
+// everything using it, like the scanner, sees the plaintext.
+//
+// Contents are encrypted with AES-GCM in blocks of 4 KiB, each with a
+// random nonce and authenticated along with its number and a random ID of
+// the file, so that blocks can't be moved around. Each name in a path is
+// encrypted separately and deterministically, so equal names in different
+// directories are equal once encrypted. Sizes and times stay visible.
+//
+// The keys are derived from the passphrase with scrypt and a random salt,
+// which is kept along with a check of the passphrase in the root of the
+// underlying filesystem.
+type encryptedFilesystem struct {
+	Filesystem
+	passphrase string
+
+	mut  sync.Mutex
+	keys *encryptionKeySet
+}
+
+// NewEncryptedFilesystem returns a filesystem that encrypts the files kept
+// in the given one with the passphrase. An empty passphrase means no
+// encryption.
+func NewEncryptedFilesystem(fs Filesystem, passphrase string) Filesystem {
+	if passphrase == "" {
+		return fs
+	}
+	return &encryptedFilesystem{
+		Filesystem: fs,
+		passphrase: passphrase,
+		mut:        sync.NewMutex(),
+	}
+}
+
+// keySet returns the keys, setting up encryption of the underlying
+// filesystem if it's new.
+func (f *encryptedFilesystem) keySet() (*encryptionKeySet, error) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	if f.keys != nil {
+		return f.keys, nil
+	}
+
+	var cfg encryptionConfig
+	fd, err := f.Filesystem.Open(encryptionConfigName)
+	switch {
+	case err == nil:
+		err = json.NewDecoder(fd).Decode(&cfg)
+		fd.Close()
+		if err != nil {
+			return nil, err
+		}
+		keys, err := deriveEncryptionKeys(f.passphrase, cfg.Salt)
+		if err != nil {
+			return nil, err
+		}
+		if !hmac.Equal(keys.check, cfg.Check) {
+			return nil, errEncryptionPassphrase
+		}
+		f.keys = keys
+
+	case IsNotExist(err):
+		// Files already there would look like they were deleted.
+		names, err := f.Filesystem.DirNames(".")
+		if err != nil {
+			return nil, err
+		}
+		if len(names) > 0 {
+			return nil, errEncryptionNotEmpty
+		}
+		cfg.Version = encVersion
+		cfg.Salt = make([]byte, 32)
+		if _, err := rand.Read(cfg.Salt); err != nil {
+			return nil, err
+		}
+		keys, err := deriveEncryptionKeys(f.passphrase, cfg.Salt)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Check = keys.check
+		bs, _ := json.Marshal(cfg)
+		fd, err := f.Filesystem.Create(encryptionConfigName)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := fd.Write(bs); err != nil {
+			fd.Close()
+			return nil, err
+		}
+		if err := fd.Close(); err != nil {
+			return nil, err
+		}
+		l.Debugln(f.Type(), f.URI(), "Started encrypting")
+		f.keys = keys
+
+	default:
+		return nil, err
+	}
+	return f.keys, nil
+}
+
+// encryptPath returns the underlying path of the named file.
+func (f *encryptedFilesystem) encryptPath(name string) (string, error) {
+	name, err := Canonicalize(name)
+	if err != nil {
+		return "", err
+	}
+	if name == "." {
+		return name, nil
+	}
+	keys, err := f.keySet()
+	if err != nil {
+		return "", err
+	}
+	parts := strings.Split(name, string(PathSeparator))
+	for i, part := range parts {
+		if len(part) > encMaxNameLen {
+			return "", &os.PathError{Op: "encrypt", Path: name, Err: errEncryptedNameTooLong}
+		}
+		parts[i] = keys.encryptName(part)
+	}
+	return filepath.Join(parts...), nil
+}
+
+// decryptPath returns the name of the file of the underlying path.
+func (f *encryptedFilesystem) decryptPath(encrypted string) (string, error) {
+	if encrypted == "." {
+		return encrypted, nil
+	}
+	keys, err := f.keySet()
+	if err != nil {
+		return "", err
+	}
+	parts := strings.Split(encrypted, string(PathSeparator))
+	for i, part := range parts {
+		if parts[i], err = keys.decryptName(part); err != nil {
+			return "", err
+		}
+	}
+	return filepath.Join(parts...), nil
+}
+
+func (f *encryptedFilesystem) Chmod(name string, mode FileMode) error {
+	encrypted, err := f.encryptPath(name)
+	if err != nil {
+		return err
+	}
+	return f.Filesystem.Chmod(encrypted, mode)
+}
+
+func (f *encryptedFilesystem) Lchown(name string, uid, gid int) error {
+	encrypted, err := f.encryptPath(name)
+	if err != nil {
+		return err
+	}
+	return f.Filesystem.Lchown(encrypted, uid, gid)
+}
+
+func (f *encryptedFilesystem) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	encrypted, err := f.encryptPath(name)
+	if err != nil {
+		return err
+	}
+	return f.Filesystem.Chtimes(encrypted, atime, mtime)
+}
+
+func (f *encryptedFilesystem) Create(name string) (File, error) {
+	return f.OpenFile(name, OptReadWrite|OptCreate|OptTruncate, 0666)
+}
+
+// CreateSymlink encrypts the target as a whole.
+func (f *encryptedFilesystem) CreateSymlink(target, name string) error {
+	encrypted, err := f.encryptPath(name)
+	if err != nil {
+		return err
+	}
+	keys, err := f.keySet()
+	if err != nil {
+		return err
+	}
+	return f.Filesystem.CreateSymlink(keys.encryptName(target), encrypted)
+}
+
+func (f *encryptedFilesystem) DirNames(name string) ([]string, error) {
+	encrypted, err := f.encryptPath(name)
+	if err != nil {
+		return nil, err
+	}
+	names, err := f.Filesystem.DirNames(encrypted)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := f.keySet()
+	if err != nil {
+		return nil, err
+	}
+	plain := names[:0]
+	for _, n := range names {
+		// Skips the config file, and anything put there other than
+		// through this filesystem.
+		if p, err := keys.decryptName(n); err == nil {
+			plain = append(plain, p)
+		} else if encrypted != "." || n != encryptionConfigName {
+			l.Debugln(f.Type(), f.URI(), "Skipping unencrypted file", filepath.Join(encrypted, n))
+		}
+	}
+	return plain, nil
+}
+
+func (f *encryptedFilesystem) Lstat(name string) (FileInfo, error) {
+	encrypted, err := f.encryptPath(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Filesystem.Lstat(encrypted)
+	if err != nil {
+		return nil, err
+	}
+	return newEncryptedFileInfo(info, name), nil
+}
+
+func (f *encryptedFilesystem) Mkdir(name string, perm FileMode) error {
+	encrypted, err := f.encryptPath(name)
+	if err != nil {
+		return err
+	}
+	return f.Filesystem.Mkdir(encrypted, perm)
+}
+
+func (f *encryptedFilesystem) MkdirAll(name string, perm FileMode) error {
+	encrypted, err := f.encryptPath(name)
+	if err != nil {
+		return err
+	}
+	return f.Filesystem.MkdirAll(encrypted, perm)
+}
+
+func (f *encryptedFilesystem) Open(name string) (File, error) {
+	return f.OpenFile(name, OptReadOnly, 0)
+}
+
+func (f *encryptedFilesystem) OpenFile(name string, flags int, mode FileMode) (File, error) {
+	encrypted, err := f.encryptPath(name)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := f.keySet()
+	if err != nil {
+		return nil, err
+	}
+
+	// Partial blocks are read before being written, and appending is
+	// done by the encrypted file, as it can't be by the underlying one.
+	underlyingFlags := flags &^ OptAppend
+	if underlyingFlags&OptWriteOnly != 0 {
+		underlyingFlags = underlyingFlags&^OptWriteOnly | OptReadWrite
+	}
+	fd, err := f.Filesystem.OpenFile(encrypted, underlyingFlags, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &encryptedFile{
+		fd:   fd,
+		name: name,
+		keys: keys,
+		mut:  sync.NewMutex(),
+	}
+	if flags&OptAppend != 0 {
+		if _, err := file.Seek(0, io.SeekEnd); err != nil {
+			fd.Close()
+			return nil, err
+		}
+	}
+	return file, nil
+}
+
+func (f *encryptedFilesystem) ReadSymlink(name string) (string, error) {
+	encrypted, err := f.encryptPath(name)
+	if err != nil {
+		return "", err
+	}
+	target, err := f.Filesystem.ReadSymlink(encrypted)
+	if err != nil {
+		return "", err
+	}
+	keys, err := f.keySet()
+	if err != nil {
+		return "", err
+	}
+	return keys.decryptName(target)
+}
+
+func (f *encryptedFilesystem) Remove(name string) error {
+	encrypted, err := f.encryptPath(name)
+	if err != nil {
+		return err
+	}
+	return f.Filesystem.Remove(encrypted)
+}
+
+func (f *encryptedFilesystem) RemoveAll(name string) error {
+	encrypted, err := f.encryptPath(name)
+	if err != nil {
+		return err
+	}
+	return f.Filesystem.RemoveAll(encrypted)
+}
+
+func (f *encryptedFilesystem) Rename(oldname, newname string) error {
+	oldEncrypted, err := f.encryptPath(oldname)
+	if err != nil {
+		return err
+	}
+	newEncrypted, err := f.encryptPath(newname)
+	if err != nil {
+		return err
+	}
+	return f.Filesystem.Rename(oldEncrypted, newEncrypted)
+}
+
+func (f *encryptedFilesystem) Hardlink(oldname, newname string) error {
+	oldEncrypted, err := f.encryptPath(oldname)
+	if err != nil {
+		return err
+	}
+	newEncrypted, err := f.encryptPath(newname)
+	if err != nil {
+		return err
+	}
+	return f.Filesystem.Hardlink(oldEncrypted, newEncrypted)
+}
+
+func (f *encryptedFilesystem) Stat(name string) (FileInfo, error) {
+	encrypted, err := f.encryptPath(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Filesystem.Stat(encrypted)
+	if err != nil {
+		return nil, err
+	}
+	return newEncryptedFileInfo(info, name), nil
+}
+
+// Walk walks the plaintext names, as given by DirNames and Lstat.
+func (f *encryptedFilesystem) Walk(name string, walkFn WalkFunc) error {
+	return NewWalkFilesystem(f).Walk(name, walkFn)
+}
+
+func (f *encryptedFilesystem) Watch(name string, ignore Matcher, ctx context.Context, ignorePerms bool) (<-chan Event, <-chan error, error) {
+	encrypted, err := f.encryptPath(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	in, errChan, err := f.Filesystem.Watch(encrypted, &encryptedMatcher{f, ignore}, ctx, ignorePerms)
+	if err != nil {
+		return nil, nil, err
+	}
+	outChan := make(chan Event)
+	go func() {
+		for {
+			select {
+			case ev := <-in:
+				name, err := f.decryptPath(ev.Name)
+				if err != nil {
+					continue
+				}
+				select {
+				case outChan <- Event{Name: name, Type: ev.Type}:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return outChan, errChan, nil
+}
+
+func (f *encryptedFilesystem) Hide(name string) error {
+	encrypted, err := f.encryptPath(name)
+	if err != nil {
+		return err
+	}
+	return f.Filesystem.Hide(encrypted)
+}
+
+func (f *encryptedFilesystem) Unhide(name string) error {
+	encrypted, err := f.encryptPath(name)
+	if err != nil {
+		return err
+	}
+	return f.Filesystem.Unhide(encrypted)
+}
+
+// Glob supports patterns in the last path component only.
+func (f *encryptedFilesystem) Glob(pattern string) ([]string, error) {
+	dir, base := filepath.Split(pattern)
+	names, err := f.DirNames(dir)
+	if IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var matches []string
+	for _, name := range names {
+		ok, err := filepath.Match(base, name)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matches = append(matches, filepath.Join(dir, name))
+		}
+	}
+	return matches, nil
+}
+
+func (f *encryptedFilesystem) Usage(name string) (Usage, error) {
+	encrypted, err := f.encryptPath(name)
+	if err != nil {
+		return Usage{}, err
+	}
+	return f.Filesystem.Usage(encrypted)
+}
+
+func (f *encryptedFilesystem) SameFile(fi1, fi2 FileInfo) bool {
+	i1, ok1 := fi1.(*encryptedFileInfo)
+	i2, ok2 := fi2.(*encryptedFileInfo)
+	return ok1 && ok2 && f.Filesystem.SameFile(i1.FileInfo, i2.FileInfo)
+}
+
+// Ranges of encrypted files don't line up with the plaintext, and holding
+// the same plaintext they differ anyway.
+func (f *encryptedFilesystem) CloneRange(src File, srcOffset int64, dst File, dstOffset, length int64) error {
+	return ErrCloneNotSupported
+}
+
+func (f *encryptedFilesystem) DedupeRange(src File, srcOffset int64, dst File, dstOffset, length int64) error {
+	return ErrCloneNotSupported
+}
+
+// Extended attributes would be kept in plaintext.
+func (f *encryptedFilesystem) GetXattr(name string) ([]protocol.Xattr, error) {
+	return nil, ErrXattrsNotSupported
+}
+
+func (f *encryptedFilesystem) SetXattr(name string, xattrs []protocol.Xattr) error {
+	return ErrXattrsNotSupported
+}
+
+// As are alternate data streams.
+func (f *encryptedFilesystem) GetPlatformData(name string) (protocol.PlatformData, error) {
+	return protocol.PlatformData{}, ErrPlatformDataNotSupported
+}
+
+func (f *encryptedFilesystem) SetPlatformData(name string, data protocol.PlatformData) error {
+	return ErrPlatformDataNotSupported
+}
+
+// encryptedMatcher matches the plaintext names of the underlying files,
+// ignoring those that aren't encrypted.
+type encryptedMatcher struct {
+	fs   *encryptedFilesystem
+	next Matcher
+}
+
+func (m *encryptedMatcher) ShouldIgnore(name string) bool {
+	plain, err := m.fs.decryptPath(name)
+	if err != nil {
+		return true
+	}
+	return m.next.ShouldIgnore(plain)
+}
+
+func (m *encryptedMatcher) SkipIgnoredDirs() bool {
+	return m.next.SkipIgnoredDirs()
+}
+
+// encryptedPlainSize returns the size of the plaintext of an encrypted file
+// of the given size.
+func encryptedPlainSize(size int64) int64 {
+	if size <= encHeaderSize {
+		return 0
+	}
+	size -= encHeaderSize
+	plain := size / encCipherBlock * encBlockSize
+	if rest := size % encCipherBlock; rest > encOverhead {
+		plain += rest - encOverhead
+	}
+	return plain
+}
+
+// encryptedSize returns the size of the encrypted file of a plaintext of
+// the given size.
+func encryptedSize(plain int64) int64 {
+	if plain == 0 {
+		return 0
+	}
+	size := encHeaderSize + plain/encBlockSize*encCipherBlock
+	if rest := plain % encBlockSize; rest > 0 {
+		size += rest + encOverhead
+	}
+	return size
+}
+
+// encryptedFile is a file as seen through the encryptedFilesystem. It
+// starts with a header holding a random ID, followed by the encrypted
+// blocks. Empty files have no header.
+type encryptedFile struct {
+	fd   File
+	name string
+	keys *encryptionKeySet
+
+	mut    sync.Mutex // as writes read and write whole blocks
+	fileID []byte
+	offset int64
+}
+
+func (f *encryptedFile) Name() string {
+	return f.name
+}
+
+func (f *encryptedFile) plainSize() (int64, error) {
+	info, err := f.fd.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return encryptedPlainSize(info.Size()), nil
+}
+
+// header reads the file ID, or creates one if the file is empty.
+func (f *encryptedFile) header(create bool) error {
+	if f.fileID != nil {
+		return nil
+	}
+	hdr := make([]byte, encHeaderSize)
+	_, err := f.fd.ReadAt(hdr, 0)
+	switch {
+	case err == nil:
+		if binary.BigEndian.Uint16(hdr) != encVersion {
+			return errEncryptedBlock
+		}
+		f.fileID = hdr[2:]
+		return nil
+	case err == io.EOF && create:
+		binary.BigEndian.PutUint16(hdr, encVersion)
+		if _, err := rand.Read(hdr[2:]); err != nil {
+			return err
+		}
+		if _, err := f.fd.WriteAt(hdr, 0); err != nil {
+			return err
+		}
+		f.fileID = hdr[2:]
+		return nil
+	default:
+		return err
+	}
+}
+
+func (f *encryptedFile) blockData(block int64) []byte {
+	data := make([]byte, len(f.fileID)+8)
+	copy(data, f.fileID)
+	binary.BigEndian.PutUint64(data[len(f.fileID):], uint64(block))
+	return data
+}
+
+// readBlock returns the plaintext of the block, which is of the given
+// length.
+func (f *encryptedFile) readBlock(block int64, length int) ([]byte, error) {
+	buf := make([]byte, length+encOverhead)
+	if _, err := f.fd.ReadAt(buf, encHeaderSize+block*encCipherBlock); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	plain, err := f.keys.content.Open(buf[encNonceSize:encNonceSize], buf[:encNonceSize], buf[encNonceSize:], f.blockData(block))
+	if err != nil {
+		return nil, errEncryptedBlock
+	}
+	return plain, nil
+}
+
+func (f *encryptedFile) writeBlock(block int64, plain []byte) error {
+	buf := make([]byte, encNonceSize, len(plain)+encOverhead)
+	if _, err := rand.Read(buf); err != nil {
+		return err
+	}
+	buf = f.keys.content.Seal(buf, buf, plain, f.blockData(block))
+	_, err := f.fd.WriteAt(buf, encHeaderSize+block*encCipherBlock)
+	return err
+}
+
+func (f *encryptedFile) Read(p []byte) (int, error) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	n, err := f.readAtLocked(p, f.offset)
+	f.offset += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+func (f *encryptedFile) ReadAt(p []byte, off int64) (int, error) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	return f.readAtLocked(p, off)
+}
+
+func (f *encryptedFile) readAtLocked(p []byte, off int64) (int, error) {
+	size, err := f.plainSize()
+	if err != nil {
+		return 0, err
+	}
+	if off >= size {
+		return 0, io.EOF
+	}
+	if err := f.header(false); err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for n < len(p) && off < size {
+		block := off / encBlockSize
+		start := block * encBlockSize
+		length := int64(encBlockSize)
+		if rest := size - start; length > rest {
+			length = rest
+		}
+		plain, err := f.readBlock(block, int(length))
+		if err != nil {
+			return n, err
+		}
+		c := copy(p[n:], plain[off-start:])
+		n += c
+		off += int64(c)
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (f *encryptedFile) Seek(offset int64, whence int) (int64, error) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		size, err := f.plainSize()
+		if err != nil {
+			return 0, err
+		}
+		offset += size
+	}
+	if offset < 0 {
+		return 0, &os.PathError{Op: "seek", Path: f.name, Err: os.ErrInvalid}
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *encryptedFile) Write(p []byte) (int, error) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	n, err := f.writeAtLocked(p, f.offset)
+	f.offset += int64(n)
+	return n, err
+}
+
+func (f *encryptedFile) WriteAt(p []byte, off int64) (int, error) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	return f.writeAtLocked(p, off)
+}
+
+func (f *encryptedFile) writeAtLocked(p []byte, off int64) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	size, err := f.plainSize()
+	if err != nil {
+		return 0, err
+	}
+	if err := f.header(true); err != nil {
+		return 0, err
+	}
+	if off > size {
+		// Every block but the last is whole, so the gap is filled.
+		if err := f.fillZeros(size, off); err != nil {
+			return 0, err
+		}
+		size = off
+	}
+	if err := f.writeRange(p, off, size); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// writeRange writes p at off, which is at most the current size.
+func (f *encryptedFile) writeRange(p []byte, off, size int64) error {
+	for len(p) > 0 {
+		block := off / encBlockSize
+		start := block * encBlockSize
+		inner := int(off - start)
+		n := encBlockSize - inner
+		if n > len(p) {
+			n = len(p)
+		}
+
+		// What's there already and not overwritten is kept.
+		existing := size - start
+		if existing > encBlockSize {
+			existing = encBlockSize
+		}
+		var plain []byte
+		if existing > 0 && (inner > 0 || int64(n) < existing) {
+			var err error
+			if plain, err = f.readBlock(block, int(existing)); err != nil {
+				return err
+			}
+		}
+		if len(plain) < inner+n {
+			plain = append(plain, make([]byte, inner+n-len(plain))...)
+		}
+		copy(plain[inner:], p[:n])
+		if err := f.writeBlock(block, plain); err != nil {
+			return err
+		}
+
+		p = p[n:]
+		off += int64(n)
+		if off > size {
+			size = off
+		}
+	}
+	return nil
+}
+
+// fillZeros writes zeros from the current size up to the given size.
+func (f *encryptedFile) fillZeros(size, to int64) error {
+	zeros := make([]byte, 16*encBlockSize)
+	for size < to {
+		n := int64(len(zeros))
+		if rest := to - size; n > rest {
+			n = rest
+		}
+		if err := f.writeRange(zeros[:n], size, size); err != nil {
+			return err
+		}
+		size += n
+	}
+	return nil
+}
+
+func (f *encryptedFile) Truncate(size int64) error {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	cur, err := f.plainSize()
+	if err != nil {
+		return err
+	}
+	switch {
+	case size == cur:
+		return nil
+	case size == 0:
+		f.fileID = nil
+		return f.fd.Truncate(0)
+	case size > cur:
+		if err := f.header(true); err != nil {
+			return err
+		}
+		return f.fillZeros(cur, size)
+	}
+
+	if err := f.header(false); err != nil {
+		return err
+	}
+	if rest := size % encBlockSize; rest > 0 {
+		// The new last block is shorter.
+		block := size / encBlockSize
+		length := cur - block*encBlockSize
+		if length > encBlockSize {
+			length = encBlockSize
+		}
+		plain, err := f.readBlock(block, int(length))
+		if err != nil {
+			return err
+		}
+		if err := f.writeBlock(block, plain[:rest]); err != nil {
+			return err
+		}
+	}
+	return f.fd.Truncate(encryptedSize(size))
+}
+
+func (f *encryptedFile) Stat() (FileInfo, error) {
+	info, err := f.fd.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return newEncryptedFileInfo(info, f.name), nil
+}
+
+func (f *encryptedFile) Sync() error {
+	return f.fd.Sync()
+}
+
+func (f *encryptedFile) Close() error {
+	return f.fd.Close()
+}
+
+// encryptedFileInfo has the plaintext name and size.
+type encryptedFileInfo struct {
+	FileInfo
+	name string
+}
+
+func newEncryptedFileInfo(info FileInfo, name string) *encryptedFileInfo {
+	return &encryptedFileInfo{FileInfo: info, name: filepath.Base(name)}
+}
+
+func (i *encryptedFileInfo) Name() string {
+	return i.name
+}
+
+func (i *encryptedFileInfo) Size() int64 {
+	if !i.IsRegular() {
+		return i.FileInfo.Size()
+	}
+	return encryptedPlainSize(i.FileInfo.Size())
+}
+
@@ -0,0 +1,207 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func setupEncryptedFilesystem(t *testing.T) (Filesystem, Filesystem, func()) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "syncthing-encrypted-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	underlying := newBasicFilesystem(dir)
+	return NewEncryptedFilesystem(underlying, "secret"), underlying, func() {
+		os.RemoveAll(dir)
+	}
+}
+
+func TestEncryptedFilesystemFiles(t *testing.T) {
+	fs, underlying, cleanup := setupEncryptedFilesystem(t)
+	defer cleanup()
+
+	if err := fs.MkdirAll(filepath.Join("secrets", "plans"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	contents := strings.Repeat("attack at dawn ", 1000)
+	writeRemoteFile(t, fs, filepath.Join("secrets", "plans", "attack.txt"), contents)
+	if err := fs.CreateSymlink("attack.txt", filepath.Join("secrets", "plans", "link")); err != nil && runtime.GOOS != "windows" {
+		t.Fatal(err)
+	}
+
+	if got := readRemoteFile(t, fs, filepath.Join("secrets", "plans", "attack.txt")); got != contents {
+		t.Error("Contents differ after reading back")
+	}
+	info, err := fs.Lstat(filepath.Join("secrets", "plans", "attack.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Name() != "attack.txt" || info.Size() != int64(len(contents)) {
+		t.Errorf("Unexpected name %q or size %d", info.Name(), info.Size())
+	}
+	if target, err := fs.ReadSymlink(filepath.Join("secrets", "plans", "link")); err == nil && target != "attack.txt" {
+		t.Errorf("Unexpected symlink target %q", target)
+	}
+
+	// Nothing of the plaintext is found in the underlying filesystem.
+	var walked []string
+	err = NewWalkFilesystem(underlying).Walk(".", func(path string, info FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		for _, word := range []string{"secrets", "plans", "attack", "link"} {
+			if strings.Contains(path, word) {
+				t.Errorf("Underlying path %q contains %q", path, word)
+			}
+		}
+		if info.IsRegular() && path != encryptionConfigName {
+			bs, err := ioutil.ReadFile(filepath.Join(underlying.URI(), path))
+			if err != nil {
+				return err
+			}
+			if bytes.Contains(bs, []byte("attack")) {
+				t.Errorf("Underlying file %q contains the plaintext", path)
+			}
+			if int64(len(bs)) != encryptedSize(int64(len(contents))) {
+				t.Errorf("Underlying file has size %d, expected %d", len(bs), encryptedSize(int64(len(contents))))
+			}
+		}
+		walked = append(walked, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	walked = walked[:0]
+	err = fs.Walk(".", func(path string, info FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsSymlink() {
+			walked = append(walked, path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{".", "secrets", filepath.Join("secrets", "plans"), filepath.Join("secrets", "plans", "attack.txt")}
+	if !reflect.DeepEqual(walked, expected) {
+		t.Errorf("Walked %v, expected %v", walked, expected)
+	}
+
+	if err := fs.Rename(filepath.Join("secrets", "plans"), "plans"); err != nil {
+		t.Fatal(err)
+	}
+	if matches, err := fs.Glob(filepath.Join("plans", "*.txt")); err != nil || !reflect.DeepEqual(matches, []string{filepath.Join("plans", "attack.txt")}) {
+		t.Errorf("Globbed %v, %v", matches, err)
+	}
+	if _, err := fs.Lstat(filepath.Join("secrets", "plans")); !IsNotExist(err) {
+		t.Error("Expected not to exist, got", err)
+	}
+}
+
+func TestEncryptedFilesystemWrites(t *testing.T) {
+	fs, _, cleanup := setupEncryptedFilesystem(t)
+	defer cleanup()
+
+	fd, err := fs.Create("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	// Random writes and truncations at and around block boundaries give
+	// the same as they would for a plain file.
+	rnd := rand.New(rand.NewSource(42))
+	var expected []byte
+	for i := 0; i < 200; i++ {
+		off := rnd.Int63n(4 * encBlockSize)
+		if rnd.Intn(5) == 0 {
+			if err := fd.Truncate(off); err != nil {
+				t.Fatal(err)
+			}
+			if off < int64(len(expected)) {
+				expected = expected[:off]
+			} else {
+				expected = append(expected, make([]byte, off-int64(len(expected)))...)
+			}
+			continue
+		}
+
+		data := make([]byte, rnd.Intn(2*encBlockSize)+1)
+		rnd.Read(data)
+		if _, err := fd.WriteAt(data, off); err != nil {
+			t.Fatal(err)
+		}
+		if end := off + int64(len(data)); end > int64(len(expected)) {
+			expected = append(expected, make([]byte, end-int64(len(expected)))...)
+		}
+		copy(expected[off:], data)
+
+		info, err := fd.Stat()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() != int64(len(expected)) {
+			t.Fatalf("Size is %d after write %d, expected %d", info.Size(), i, len(expected))
+		}
+	}
+
+	if _, err := fd.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadAll(fd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, expected) {
+		t.Error("Contents differ from the expected")
+	}
+}
+
+func TestEncryptedFilesystemPassphrase(t *testing.T) {
+	fs, underlying, cleanup := setupEncryptedFilesystem(t)
+	defer cleanup()
+
+	writeRemoteFile(t, fs, "file", "contents")
+
+	wrong := NewEncryptedFilesystem(underlying, "wrong")
+	if _, err := wrong.Lstat("file"); err != errEncryptionPassphrase {
+		t.Error("Expected the wrong passphrase to fail, got", err)
+	}
+	right := NewEncryptedFilesystem(underlying, "secret")
+	if got := readRemoteFile(t, right, "file"); got != "contents" {
+		t.Errorf("Got contents %q", got)
+	}
+
+	// Unencrypted files must not be taken over.
+	dir, err := ioutil.TempDir("", "syncthing-encrypted-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "plain"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	plain := NewEncryptedFilesystem(newBasicFilesystem(dir), "secret")
+	if _, err := plain.DirNames("."); err != errEncryptionNotEmpty {
+		t.Error("Expected a directory that isn't empty to be refused, got", err)
+	}
+}
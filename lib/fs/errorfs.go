@@ -9,6 +9,8 @@ package fs
 import (
 	"context"
 	"time"
+
+	"github.com/syncthing/syncthing/lib/protocol"
 )
 
 type errorFilesystem struct {
@@ -32,6 +34,7 @@ func (fs *errorFilesystem) ReadSymlink(name string) (string, error)
 func (fs *errorFilesystem) Remove(name string) error                                    { return fs.err }
 func (fs *errorFilesystem) RemoveAll(name string) error                                 { return fs.err }
 func (fs *errorFilesystem) Rename(oldname, newname string) error                        { return fs.err }
+func (fs *errorFilesystem) Hardlink(oldname, newname string) error                      { return fs.err }
 func (fs *errorFilesystem) Stat(name string) (FileInfo, error)                          { return nil, fs.err }
 func (fs *errorFilesystem) SymlinksSupported() bool                                     { return false }
 func (fs *errorFilesystem) Walk(root string, walkFn WalkFunc) error                     { return fs.err }
@@ -44,6 +47,14 @@ func (fs *errorFilesystem) Usage(name string) (Usage, error)
 func (fs *errorFilesystem) Type() FilesystemType                                        { return fs.fsType }
 func (fs *errorFilesystem) URI() string                                                 { return fs.uri }
 func (fs *errorFilesystem) SameFile(fi1, fi2 FileInfo) bool                             { return false }
+func (fs *errorFilesystem) CloneRange(File, int64, File, int64, int64) error            { return fs.err }
+func (fs *errorFilesystem) DedupeRange(File, int64, File, int64, int64) error           { return fs.err }
+func (fs *errorFilesystem) GetXattr(name string) ([]protocol.Xattr, error)              { return nil, fs.err }
+func (fs *errorFilesystem) SetXattr(name string, xattrs []protocol.Xattr) error         { return fs.err }
+func (fs *errorFilesystem) GetPlatformData(name string) (protocol.PlatformData, error) {
+	return protocol.PlatformData{}, fs.err
+}
+func (fs *errorFilesystem) SetPlatformData(name string, data protocol.PlatformData) error { return fs.err }
 func (fs *errorFilesystem) Watch(path string, ignore Matcher, ctx context.Context, ignorePerms bool) (<-chan Event, <-chan error, error) {
 	return nil, nil, fs.err
 }
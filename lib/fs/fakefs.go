@@ -21,6 +21,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/syncthing/syncthing/lib/protocol"
 )
 
 // see readShortAt()
@@ -560,6 +562,34 @@ func (fs *fakefs) Usage(name string) (Usage, error) {
 	return Usage{}, errors.New("not implemented")
 }
 
+func (fs *fakefs) Hardlink(oldname, newname string) error {
+	return errors.New("not implemented")
+}
+
+func (fs *fakefs) CloneRange(src File, srcOffset int64, dst File, dstOffset, length int64) error {
+	return ErrCloneNotSupported
+}
+
+func (fs *fakefs) DedupeRange(src File, srcOffset int64, dst File, dstOffset, length int64) error {
+	return ErrCloneNotSupported
+}
+
+func (fs *fakefs) GetXattr(name string) ([]protocol.Xattr, error) {
+	return nil, ErrXattrsNotSupported
+}
+
+func (fs *fakefs) SetXattr(name string, xattrs []protocol.Xattr) error {
+	return ErrXattrsNotSupported
+}
+
+func (fs *fakefs) GetPlatformData(name string) (protocol.PlatformData, error) {
+	return protocol.PlatformData{}, ErrPlatformDataNotSupported
+}
+
+func (fs *fakefs) SetPlatformData(name string, data protocol.PlatformData) error {
+	return ErrPlatformDataNotSupported
+}
+
 func (fs *fakefs) Type() FilesystemType {
 	return FilesystemTypeFake
 }
@@ -826,3 +856,7 @@ func (f *fakeFileInfo) Owner() int {
 func (f *fakeFileInfo) Group() int {
 	return f.gid
 }
+
+func (f *fakeFileInfo) Inode() uint64 {
+	return 0
+}
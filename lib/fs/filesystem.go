@@ -14,6 +14,9 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/syncthing/syncthing/lib/chaos"
+	"github.com/syncthing/syncthing/lib/protocol"
 )
 
 // The Filesystem interface abstracts access to the file system.
@@ -33,6 +36,7 @@ type Filesystem interface {
 	Remove(name string) error
 	RemoveAll(name string) error
 	Rename(oldname, newname string) error
+	Hardlink(oldname, newname string) error
 	Stat(name string) (FileInfo, error)
 	SymlinksSupported() bool
 	Walk(name string, walkFn WalkFunc) error
@@ -48,6 +52,28 @@ type Filesystem interface {
 	Type() FilesystemType
 	URI() string
 	SameFile(fi1, fi2 FileInfo) bool
+	// CloneRange makes length bytes at dstOffset in dst share storage with
+	// the same range at srcOffset in src (a copy-on-write clone, or
+	// "reflink"). Returns ErrCloneNotSupported when the filesystem can't
+	// do that, in which case the caller should copy the data instead.
+	CloneRange(src File, srcOffset int64, dst File, dstOffset, length int64) error
+	// DedupeRange is like CloneRange, except that the filesystem first
+	// checks that both ranges hold the same data and returns
+	// ErrRangesDiffer if they don't. It is safe to use on files that are in
+	// use.
+	DedupeRange(src File, srcOffset int64, dst File, dstOffset, length int64) error
+	// GetXattr returns the extended attributes of the named file or
+	// directory that are subject to syncing, sorted by name. SetXattr makes
+	// those attributes equal to the given set. Both return
+	// ErrXattrsNotSupported when the filesystem doesn't support them.
+	GetXattr(name string) ([]protocol.Xattr, error)
+	SetXattr(name string, xattrs []protocol.Xattr) error
+	// GetPlatformData returns the metadata of the named item that only the
+	// platform has: on Windows, its alternate data streams and whether it
+	// is a junction. SetPlatformData makes the item's streams those given.
+	// Both return ErrPlatformDataNotSupported on other platforms.
+	GetPlatformData(name string) (protocol.PlatformData, error)
+	SetPlatformData(name string, data protocol.PlatformData) error
 }
 
 // The File interface abstracts access to a regular file, being a somewhat
@@ -80,6 +106,9 @@ type FileInfo interface {
 	IsSymlink() bool
 	Owner() int
 	Group() int
+	// Inode returns the inode number, or zero where there is no such
+	// thing or it isn't available.
+	Inode() uint64
 }
 
 // FileMode is similar to os.FileMode
@@ -137,6 +166,14 @@ func (evType EventType) String() string {
 
 var ErrWatchNotSupported = errors.New("watching is not supported")
 
+var ErrCloneNotSupported = errors.New("copy-on-write clones are not supported")
+
+var ErrRangesDiffer = errors.New("ranges to deduplicate differ")
+
+var ErrXattrsNotSupported = errors.New("extended attributes are not supported")
+
+var ErrPlatformDataNotSupported = errors.New("platform data is not supported")
+
 // Equivalents from os package.
 
 const ModePerm = FileMode(os.ModePerm)
@@ -179,6 +216,26 @@ func NewFilesystem(fsType FilesystemType, uri string) Filesystem {
 		fs = newBasicFilesystem(uri)
 	case FilesystemTypeFake:
 		fs = newFakeFilesystem(uri)
+	case FilesystemTypeS3:
+		var err error
+		if fs, err = newS3Filesystem(uri); err != nil {
+			l.Debugln("Invalid S3 filesystem", uri, err)
+			fs = &errorFilesystem{
+				fsType: fsType,
+				uri:    uri,
+				err:    err,
+			}
+		}
+	case FilesystemTypeWebDAV:
+		var err error
+		if fs, err = newWebdavFilesystem(uri); err != nil {
+			l.Debugln("Invalid WebDAV filesystem", uri, err)
+			fs = &errorFilesystem{
+				fsType: fsType,
+				uri:    uri,
+				err:    err,
+			}
+		}
 	default:
 		l.Debugln("Unknown filesystem", fsType, uri)
 		fs = &errorFilesystem{
@@ -188,15 +245,26 @@ func NewFilesystem(fsType FilesystemType, uri string) Filesystem {
 		}
 	}
 
+	if chaos.Enabled {
+		fs = &chaosFilesystem{fs}
+	}
+
+	// The remote filesystems walk with fewer requests than it takes to
+	// list each directory and then stat each entry.
+	walkFs := NewWalkFilesystem
+	if fsType == FilesystemTypeS3 || fsType == FilesystemTypeWebDAV {
+		walkFs = func(fs Filesystem) Filesystem { return fs }
+	}
+
 	if l.ShouldDebug("walkfs") {
-		return NewWalkFilesystem(&logFilesystem{fs})
+		return walkFs(&logFilesystem{fs})
 	}
 
 	if l.ShouldDebug("fs") {
-		return &logFilesystem{NewWalkFilesystem(fs)}
+		return &logFilesystem{walkFs(fs)}
 	}
 
-	return NewWalkFilesystem(fs)
+	return walkFs(fs)
 }
 
 // IsInternal returns true if the file, as a path relative to the folder
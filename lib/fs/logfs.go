@@ -12,6 +12,8 @@ import (
 	"path/filepath"
 	"runtime"
 	"time"
+
+	"github.com/syncthing/syncthing/lib/protocol"
 )
 
 type logFilesystem struct {
@@ -110,6 +112,12 @@ func (fs *logFilesystem) Rename(oldname, newname string) error {
 	return err
 }
 
+func (fs *logFilesystem) Hardlink(oldname, newname string) error {
+	err := fs.Filesystem.Hardlink(oldname, newname)
+	l.Debugln(getCaller(), fs.Type(), fs.URI(), "Hardlink", oldname, newname, err)
+	return err
+}
+
 func (fs *logFilesystem) Stat(name string) (FileInfo, error) {
 	info, err := fs.Filesystem.Stat(name)
 	l.Debugln(getCaller(), fs.Type(), fs.URI(), "Stat", name, info, err)
@@ -163,3 +171,39 @@ func (fs *logFilesystem) Usage(name string) (Usage, error) {
 	l.Debugln(getCaller(), fs.Type(), fs.URI(), "Usage", name, usage, err)
 	return usage, err
 }
+
+func (fs *logFilesystem) CloneRange(src File, srcOffset int64, dst File, dstOffset, length int64) error {
+	err := fs.Filesystem.CloneRange(src, srcOffset, dst, dstOffset, length)
+	l.Debugln(getCaller(), fs.Type(), fs.URI(), "CloneRange", src.Name(), srcOffset, dst.Name(), dstOffset, length, err)
+	return err
+}
+
+func (fs *logFilesystem) DedupeRange(src File, srcOffset int64, dst File, dstOffset, length int64) error {
+	err := fs.Filesystem.DedupeRange(src, srcOffset, dst, dstOffset, length)
+	l.Debugln(getCaller(), fs.Type(), fs.URI(), "DedupeRange", src.Name(), srcOffset, dst.Name(), dstOffset, length, err)
+	return err
+}
+
+func (fs *logFilesystem) GetXattr(name string) ([]protocol.Xattr, error) {
+	xattrs, err := fs.Filesystem.GetXattr(name)
+	l.Debugln(getCaller(), fs.Type(), fs.URI(), "GetXattr", name, len(xattrs), err)
+	return xattrs, err
+}
+
+func (fs *logFilesystem) SetXattr(name string, xattrs []protocol.Xattr) error {
+	err := fs.Filesystem.SetXattr(name, xattrs)
+	l.Debugln(getCaller(), fs.Type(), fs.URI(), "SetXattr", name, len(xattrs), err)
+	return err
+}
+
+func (fs *logFilesystem) GetPlatformData(name string) (protocol.PlatformData, error) {
+	data, err := fs.Filesystem.GetPlatformData(name)
+	l.Debugln(getCaller(), fs.Type(), fs.URI(), "GetPlatformData", name, len(data.Windows.Streams), data.Windows.Junction, err)
+	return data, err
+}
+
+func (fs *logFilesystem) SetPlatformData(name string, data protocol.PlatformData) error {
+	err := fs.Filesystem.SetPlatformData(name, data)
+	l.Debugln(getCaller(), fs.Type(), fs.URI(), "SetPlatformData", name, len(data.Windows.Streams), data.Windows.Junction, err)
+	return err
+}
@@ -0,0 +1,75 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import (
+	"runtime"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalization is the Unicode normalization form file names take on disk
+// and in the database. Names on the wire are always NFC.
+type Normalization int
+
+const (
+	NormalizationAuto     Normalization = iota // NFD on macOS, NFC elsewhere
+	NormalizationNFC                           // composed
+	NormalizationNFD                           // decomposed
+	NormalizationPreserve                      // as found on disk and as received
+)
+
+func (n Normalization) String() string {
+	switch n {
+	case NormalizationAuto:
+		return "auto"
+	case NormalizationNFC:
+		return "nfc"
+	case NormalizationNFD:
+		return "nfd"
+	case NormalizationPreserve:
+		return "preserve"
+	default:
+		return "unknown"
+	}
+}
+
+func (n Normalization) MarshalText() ([]byte, error) {
+	return []byte(n.String()), nil
+}
+
+func (n *Normalization) UnmarshalText(bs []byte) error {
+	switch string(bs) {
+	case "nfc":
+		*n = NormalizationNFC
+	case "nfd":
+		*n = NormalizationNFD
+	case "preserve":
+		*n = NormalizationPreserve
+	default:
+		*n = NormalizationAuto
+	}
+	return nil
+}
+
+// Normalize returns the name in the normalization form, unchanged when
+// names are preserved.
+func (n Normalization) Normalize(name string) string {
+	switch n {
+	case NormalizationNFC:
+		return norm.NFC.String(name)
+	case NormalizationNFD:
+		return norm.NFD.String(name)
+	case NormalizationPreserve:
+		return name
+	default:
+		if runtime.GOOS == "darwin" {
+			return norm.NFD.String(name)
+		}
+		return norm.NFC.String(name)
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Directory listings kept by a caching CaseChecker before starting over.
+const maxCaseCacheDirs = 256
+
+// IsCaseOnlyRename returns whether renaming oldname to newname only changes
+// the case of the names, which case insensitive filesystems see as renaming
+// something to itself.
+func IsCaseOnlyRename(oldname, newname string) bool {
+	return oldname != newname && UnicodeLowercase(oldname) == UnicodeLowercase(newname)
+}
+
+// A CaseChecker finds out how names are cased on disk. On a case
+// insensitive filesystem an item is found under any case of its name, so
+// whether it exists doesn't tell whether that is its name.
+type CaseChecker struct {
+	fs    Filesystem
+	cache bool
+
+	mut         sync.Mutex
+	insensitive *bool
+	dirs        map[string][]string
+}
+
+// NewCaseChecker returns a CaseChecker that lists directories every time,
+// for use while the filesystem changes.
+func NewCaseChecker(fs Filesystem) *CaseChecker {
+	return &CaseChecker{fs: fs}
+}
+
+// NewCachingCaseChecker returns a CaseChecker that keeps the directory
+// listings, for a pass over many names while nothing is changed.
+func NewCachingCaseChecker(fs Filesystem) *CaseChecker {
+	return &CaseChecker{
+		fs:    fs,
+		cache: true,
+		dirs:  make(map[string][]string),
+	}
+}
+
+// RealCase returns name as it is cased on disk. It is returned as is if
+// the filesystem is case sensitive, or if the item isn't found.
+func (c *CaseChecker) RealCase(name string) (string, error) {
+	name = filepath.Clean(name)
+	if name == "." {
+		return name, nil
+	}
+	if insensitive, err := c.isInsensitive(name); err != nil || !insensitive {
+		return name, err
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	real := make([]string, 0, strings.Count(name, string(PathSeparator))+1)
+	for _, comp := range strings.Split(name, string(PathSeparator)) {
+		dir := filepath.Join(real...)
+		if dir == "" {
+			dir = "."
+		}
+		names, err := c.dirNames(dir)
+		if err != nil {
+			if IsNotExist(err) {
+				return name, nil
+			}
+			return name, err
+		}
+		found := ""
+		lower := UnicodeLowercase(comp)
+		for _, n := range names {
+			if n == comp {
+				found = n
+				break
+			}
+			if found == "" && UnicodeLowercase(n) == lower {
+				found = n
+			}
+		}
+		if found == "" {
+			// Not there, or named differently than just by case, e.g.
+			// in another unicode normalization.
+			return name, nil
+		}
+		real = append(real, found)
+	}
+	return filepath.Join(real...), nil
+}
+
+// IsRealCase returns whether name, which exists, is cased as on disk.
+// Errors finding out give true, as the name then can't be known to be
+// wrong.
+func (c *CaseChecker) IsRealCase(name string) bool {
+	real, err := c.RealCase(name)
+	return err != nil || real == filepath.Clean(name)
+}
+
+// isInsensitive finds out whether the filesystem is case insensitive by
+// looking up name with the case of its letters swapped, the first time
+// there is a name with letters that exists.
+func (c *CaseChecker) isInsensitive(name string) (bool, error) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if c.insensitive != nil {
+		return *c.insensitive, nil
+	}
+
+	swapped := swapCase(name)
+	if swapped == name {
+		return false, nil
+	}
+	info, err := c.fs.Lstat(name)
+	if IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	insensitive := false
+	if swappedInfo, err := c.fs.Lstat(swapped); err == nil {
+		insensitive = c.fs.SameFile(info, swappedInfo)
+	} else if !IsNotExist(err) {
+		return false, err
+	}
+	c.insensitive = &insensitive
+	return insensitive, nil
+}
+
+func (c *CaseChecker) dirNames(dir string) ([]string, error) {
+	if !c.cache {
+		return c.fs.DirNames(dir)
+	}
+	if names, ok := c.dirs[dir]; ok {
+		return names, nil
+	}
+	names, err := c.fs.DirNames(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.dirs) >= maxCaseCacheDirs {
+		c.dirs = make(map[string][]string)
+	}
+	c.dirs[dir] = names
+	return names, nil
+}
+
+func swapCase(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsUpper(r) {
+			return unicode.ToLower(r)
+		}
+		return unicode.ToUpper(r)
+	}, s)
+}
@@ -0,0 +1,93 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsCaseOnlyRename(t *testing.T) {
+	testCases := []struct {
+		oldname, newname string
+		expected         bool
+	}{
+		{"Foo.txt", "foo.txt", true},
+		{filepath.Join("dir", "FOO"), filepath.Join("dir", "foo"), true},
+		{"foo.txt", "foo.txt", false},
+		{"foo.txt", "bar.txt", false},
+		{"Ärger", "ärger", true},
+	}
+	for _, tc := range testCases {
+		if res := IsCaseOnlyRename(tc.oldname, tc.newname); res != tc.expected {
+			t.Errorf("IsCaseOnlyRename(%q, %q) == %v, expected %v", tc.oldname, tc.newname, res, tc.expected)
+		}
+	}
+}
+
+func TestRealCase(t *testing.T) {
+	for _, insens := range []bool{false, true} {
+		uri := "/realcase-sensitive"
+		if insens {
+			uri = "/realcase-insensitive?insens=true"
+		}
+		fs := newFakeFilesystem(uri)
+		if err := fs.MkdirAll(filepath.Join("Dir", "Sub"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		fd, err := fs.Create(filepath.Join("Dir", "Sub", "File.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		fd.Close()
+
+		for _, checker := range []*CaseChecker{NewCaseChecker(fs), NewCachingCaseChecker(fs)} {
+			name := filepath.Join("dir", "SUB", "file.txt")
+			expected := name
+			if insens {
+				expected = filepath.Join("Dir", "Sub", "File.txt")
+			}
+			if real, err := checker.RealCase(name); err != nil {
+				t.Error(err)
+			} else if real != expected {
+				t.Errorf("RealCase(%q) == %q, expected %q (insensitive: %v)", name, real, expected, insens)
+			}
+			if checker.IsRealCase(name) == insens {
+				t.Errorf("IsRealCase(%q) == %v (insensitive: %v)", name, !insens, insens)
+			}
+			if name := filepath.Join("Dir", "Sub", "File.txt"); !checker.IsRealCase(name) {
+				t.Errorf("IsRealCase(%q) == false", name)
+			}
+			if name := filepath.Join("Dir", "missing"); !checker.IsRealCase(name) {
+				t.Errorf("IsRealCase(%q) == false", name)
+			}
+		}
+	}
+}
+
+func TestBasicCaseOnlyRename(t *testing.T) {
+	fs, dir := setup(t)
+	defer os.RemoveAll(dir)
+
+	fd, err := fs.Create("Foo.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd.Close()
+
+	if err := fs.Rename("Foo.txt", "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	names, err := fs.DirNames(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 || names[0] != "foo.txt" {
+		t.Errorf("Expected only foo.txt, got %v", names)
+	}
+}
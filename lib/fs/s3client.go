@@ -0,0 +1,375 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	s3TimeFormat      = "20060102T150405Z"
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+// s3Client talks to an S3 compatible object store, such as AWS S3 or
+// MinIO, using path style addressing and requests signed with AWS
+// signature version 4.
+type s3Client struct {
+	endpoint  url.URL // scheme and host
+	bucket    string
+	region    string
+	accessKey string
+	secretKey string
+	http      *http.Client
+}
+
+// s3Error is an error response of the object store.
+type s3Error struct {
+	StatusCode int    `xml:"-"`
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+func (e *s3Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("s3: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("s3: %s: %s", e.Code, e.Message)
+}
+
+func isS3NotFound(err error) bool {
+	s3err, ok := err.(*s3Error)
+	return ok && (s3err.StatusCode == http.StatusNotFound || s3err.Code == "NoSuchKey" || s3err.Code == "NoSuchUpload")
+}
+
+// s3Object is an object as returned by HEAD or LIST requests.
+type s3Object struct {
+	Key          string    `xml:"Key"`
+	Size         int64     `xml:"Size"`
+	LastModified time.Time `xml:"LastModified"`
+}
+
+type s3ListResult struct {
+	Contents       []s3Object `xml:"Contents"`
+	CommonPrefixes []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+type s3CompletePart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+func (c *s3Client) headObject(key string) (s3Object, error) {
+	resp, err := c.do(http.MethodHead, key, nil, nil, nil, 0)
+	if err != nil {
+		return s3Object{}, err
+	}
+	resp.Body.Close()
+	obj := s3Object{Key: key, Size: resp.ContentLength}
+	if t, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		obj.LastModified = t
+	}
+	return obj, nil
+}
+
+// getObject returns the contents of the object from offset on, up to
+// length bytes or the whole rest of it when length is negative.
+func (c *s3Client) getObject(key string, offset, length int64) (io.ReadCloser, error) {
+	header := make(http.Header)
+	switch {
+	case length > 0:
+		header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	case offset > 0:
+		header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := c.do(http.MethodGet, key, nil, header, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (c *s3Client) putObject(key string, body io.Reader, size int64) error {
+	resp, err := c.do(http.MethodPut, key, nil, nil, body, size)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (c *s3Client) deleteObject(key string) error {
+	resp, err := c.do(http.MethodDelete, key, nil, nil, nil, 0)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// copyObject copies the object within the bucket. Objects larger than
+// 5 GiB can't be copied in one request and fail.
+func (c *s3Client) copyObject(src, dst string) error {
+	header := make(http.Header)
+	header.Set("X-Amz-Copy-Source", "/"+c.bucket+"/"+s3Escape(src, false))
+	resp, err := c.do(http.MethodPut, dst, nil, header, nil, 0)
+	if err != nil {
+		return err
+	}
+	// A copy may fail after the response status has been sent.
+	return s3CheckBody(resp)
+}
+
+// listObjects calls fn with each page of objects below the prefix, and of
+// the common prefixes when a delimiter is given, until fn returns an error
+// or the listing is done.
+func (c *s3Client) listObjects(prefix, delimiter string, maxKeys int, fn func(objects []s3Object, prefixes []string) error) error {
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if delimiter != "" {
+			query.Set("delimiter", delimiter)
+		}
+		if maxKeys > 0 {
+			query.Set("max-keys", strconv.Itoa(maxKeys))
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		resp, err := c.do(http.MethodGet, "", query, nil, nil, 0)
+		if err != nil {
+			return err
+		}
+		var res s3ListResult
+		err = xml.NewDecoder(resp.Body).Decode(&res)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		prefixes := make([]string, len(res.CommonPrefixes))
+		for i, p := range res.CommonPrefixes {
+			prefixes[i] = p.Prefix
+		}
+		if err := fn(res.Contents, prefixes); err != nil {
+			return err
+		}
+		if !res.IsTruncated || res.NextContinuationToken == "" || maxKeys > 0 {
+			return nil
+		}
+		token = res.NextContinuationToken
+	}
+}
+
+func (c *s3Client) createMultipartUpload(key string) (string, error) {
+	resp, err := c.do(http.MethodPost, key, url.Values{"uploads": {""}}, nil, nil, 0)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var res struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return "", err
+	}
+	return res.UploadID, nil
+}
+
+// uploadPart uploads a part of a multipart upload, numbered from one, and
+// returns its ETag.
+func (c *s3Client) uploadPart(key, uploadID string, number int, body io.Reader, size int64) (string, error) {
+	query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {uploadID}}
+	resp, err := c.do(http.MethodPut, key, query, nil, body, size)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return resp.Header.Get("ETag"), nil
+}
+
+func (c *s3Client) completeMultipartUpload(key, uploadID string, parts []s3CompletePart) error {
+	bs, err := xml.Marshal(struct {
+		XMLName xml.Name         `xml:"CompleteMultipartUpload"`
+		Parts   []s3CompletePart `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(http.MethodPost, key, url.Values{"uploadId": {uploadID}}, nil, bytes.NewReader(bs), int64(len(bs)))
+	if err != nil {
+		return err
+	}
+	return s3CheckBody(resp)
+}
+
+func (c *s3Client) abortMultipartUpload(key, uploadID string) error {
+	resp, err := c.do(http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, nil, nil, 0)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// do sends a signed request for the object with the given key, or for the
+// bucket when the key is empty, and returns the response if it succeeded.
+func (c *s3Client) do(method, key string, query url.Values, header http.Header, body io.Reader, size int64) (*http.Response, error) {
+	u := c.endpoint
+	u.Path = "/" + c.bucket + "/" + key
+	u.RawPath = "/" + c.bucket + "/" + s3Escape(key, false)
+	u.RawQuery = s3CanonicalQuery(query)
+
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	for k, vs := range header {
+		req.Header[k] = vs
+	}
+	req.Header.Set("X-Amz-Content-Sha256", s3UnsignedPayload)
+	c.sign(req, time.Now())
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		s3err := &s3Error{}
+		if method != http.MethodHead {
+			bs, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 64<<10))
+			_ = xml.Unmarshal(bs, s3err)
+		}
+		s3err.StatusCode = resp.StatusCode
+		return nil, s3err
+	}
+	return resp, nil
+}
+
+// s3CheckBody closes the response, returning the error it holds if any.
+func s3CheckBody(resp *http.Response) error {
+	defer resp.Body.Close()
+	bs, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	s3err := &s3Error{StatusCode: resp.StatusCode}
+	if xml.Unmarshal(bs, s3err) == nil && s3err.Code != "" {
+		return s3err
+	}
+	return nil
+}
+
+// sign adds the signature version 4 Authorization header to the request.
+// The payload hash must already be set in the X-Amz-Content-Sha256
+// header.
+func (c *s3Client) sign(req *http.Request, t time.Time) {
+	t = t.UTC()
+	amzDate := t.Format(s3TimeFormat)
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, vs := range req.Header {
+		k = strings.ToLower(k)
+		if k == "range" || k == "content-md5" || k == "content-type" || strings.HasPrefix(k, "x-amz-") {
+			headers[k] = strings.TrimSpace(strings.Join(vs, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		req.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+	scope := date + "/" + c.region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := s3HMAC([]byte("AWS4"+c.secretKey), date)
+	key = s3HMAC(key, c.region)
+	key = s3HMAC(key, "s3")
+	key = s3HMAC(key, "aws4_request")
+	signature := hex.EncodeToString(s3HMAC(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+c.accessKey+"/"+scope+", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func s3HMAC(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// s3CanonicalQuery returns the query string sorted and escaped as required
+// for signing.
+func s3CanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, s3Escape(k, true)+"="+s3Escape(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3Escape percent-encodes everything but unreserved characters, and
+// slashes unless escapeSlash is set.
+func s3Escape(s string, escapeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '.', c == '_', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !escapeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,937 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+var (
+	errS3NotDirectory  = errors.New("not a directory")
+	errS3IsDirectory   = errors.New("is a directory")
+	errS3NotEmpty      = errors.New("directory not empty")
+	errS3ReadOnlyFile  = errors.New("file is not open for writing")
+	errS3NotSupported  = errors.New("not supported on object storage")
+	errS3NoCredentials = errors.New("s3: no credentials given in the URI or environment")
+)
+
+// Not meant to be changed, but must be changeable for tests
+var (
+	// Files larger than this are uploaded in parts of this size. S3 allows
+	// at most 10000 parts of at least 5 MiB.
+	s3PartSize int64 = 16 << 20
+)
+
+// The s3Filesystem keeps files as objects in a bucket of an S3 compatible
+// object store. The URI is of the form
+//
+//	https://[access key:secret key@]host[:port]/bucket[/prefix][?region=...&staging=...]
+//
+// where the credentials default to the AWS_ACCESS_KEY_ID and
+// AWS_SECRET_ACCESS_KEY environment variables, the region to AWS_REGION or
+// us-east-1, and the staging directory, where files open for writing are
+// kept until uploaded, to the system temporary directory.
+//
+// Directories are objects with a trailing slash in their key, or implied
+// by the keys of the objects below them. Objects have no permissions, and
+// their modification time is when they were uploaded, which the mtime
+// database maps to the times files should have.
+type s3Filesystem struct {
+	client  *s3Client
+	uri     string // without credentials
+	prefix  string // of all keys, empty or ending in a slash
+	staging string
+}
+
+func newS3Filesystem(uri string) (*s3Filesystem, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errors.New("s3: URI must be of the form https://host/bucket/prefix")
+	}
+	parts := strings.SplitN(strings.Trim(u.Path, "/"), "/", 2)
+	if parts[0] == "" {
+		return nil, errors.New("s3: no bucket given in the URI")
+	}
+	prefix := ""
+	if len(parts) == 2 && parts[1] != "" {
+		prefix = strings.Trim(parts[1], "/") + "/"
+	}
+
+	client := &s3Client{
+		endpoint:  url.URL{Scheme: u.Scheme, Host: u.Host},
+		bucket:    parts[0],
+		region:    u.Query().Get("region"),
+		accessKey: os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		http:      &http.Client{},
+	}
+	if u.User != nil {
+		client.accessKey = u.User.Username()
+		client.secretKey, _ = u.User.Password()
+	}
+	if client.accessKey == "" || client.secretKey == "" {
+		return nil, errS3NoCredentials
+	}
+	if client.region == "" {
+		client.region = os.Getenv("AWS_REGION")
+	}
+	if client.region == "" {
+		client.region = "us-east-1"
+	}
+
+	staging := u.Query().Get("staging")
+	if staging == "" {
+		staging = os.TempDir()
+	}
+
+	// The credentials must not end up in logs and the GUI.
+	u.User = nil
+	return &s3Filesystem{
+		client:  client,
+		uri:     u.String(),
+		prefix:  prefix,
+		staging: staging,
+	}, nil
+}
+
+// key returns the key of the object for the named file, which is the
+// prefix itself for the root.
+func (f *s3Filesystem) key(name string) (string, error) {
+	name, err := Canonicalize(name)
+	if err != nil {
+		return "", err
+	}
+	if name == "." {
+		return f.prefix, nil
+	}
+	return f.prefix + filepath.ToSlash(name), nil
+}
+
+// dirPrefix returns the prefix of the keys of the objects in the directory
+// with the given key.
+func (f *s3Filesystem) dirPrefix(key string) string {
+	if key == f.prefix {
+		return key
+	}
+	return key + "/"
+}
+
+func (f *s3Filesystem) stat(op, name string) (*s3FileInfo, error) {
+	key, err := f.key(name)
+	if err != nil {
+		return nil, err
+	}
+	if key == f.prefix {
+		return &s3FileInfo{key: key, name: filepath.Base(name), dir: true}, nil
+	}
+
+	obj, err := f.client.headObject(key)
+	if err == nil {
+		return &s3FileInfo{key: key, name: filepath.Base(name), size: obj.Size, modTime: obj.LastModified}, nil
+	}
+	if !isS3NotFound(err) {
+		return nil, &os.PathError{Op: op, Path: name, Err: err}
+	}
+
+	// It's a directory if there is its marker or any object below it. The
+	// marker sorts first, so its time is used when there is one.
+	var info *s3FileInfo
+	err = f.client.listObjects(key+"/", "", 1, func(objects []s3Object, _ []string) error {
+		if len(objects) > 0 {
+			info = &s3FileInfo{key: key, name: filepath.Base(name), dir: true}
+			if objects[0].Key == key+"/" {
+				info.modTime = objects[0].LastModified
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, &os.PathError{Op: op, Path: name, Err: err}
+	}
+	if info == nil {
+		return nil, &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+	}
+	return info, nil
+}
+
+// Chmod only checks that the file exists, as objects have no permissions.
+func (f *s3Filesystem) Chmod(name string, mode FileMode) error {
+	_, err := f.stat("chmod", name)
+	return err
+}
+
+func (f *s3Filesystem) Lchown(name string, uid, gid int) error {
+	_, err := f.stat("lchown", name)
+	return err
+}
+
+// Chtimes only checks that the file exists, as the modification time of an
+// object can't be set. The mtime database keeps the requested time.
+func (f *s3Filesystem) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	_, err := f.stat("chtimes", name)
+	return err
+}
+
+func (f *s3Filesystem) Create(name string) (File, error) {
+	return f.OpenFile(name, OptReadWrite|OptCreate|OptTruncate, 0666)
+}
+
+func (f *s3Filesystem) CreateSymlink(target, name string) error {
+	return &os.PathError{Op: "symlink", Path: name, Err: errS3NotSupported}
+}
+
+func (f *s3Filesystem) DirNames(name string) ([]string, error) {
+	key, err := f.key(name)
+	if err != nil {
+		return nil, err
+	}
+	prefix := f.dirPrefix(key)
+
+	seen := make(map[string]struct{})
+	err = f.client.listObjects(prefix, "/", 0, func(objects []s3Object, prefixes []string) error {
+		for _, obj := range objects {
+			if obj.Key != prefix {
+				seen[strings.TrimPrefix(obj.Key, prefix)] = struct{}{}
+			}
+		}
+		for _, p := range prefixes {
+			if child := strings.TrimSuffix(strings.TrimPrefix(p, prefix), "/"); child != "" {
+				seen[child] = struct{}{}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, &os.PathError{Op: "readdirent", Path: name, Err: err}
+	}
+
+	if len(seen) == 0 {
+		// Empty, or not a directory at all.
+		info, err := f.stat("readdirent", name)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			return nil, &os.PathError{Op: "readdirent", Path: name, Err: errS3NotDirectory}
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for child := range seen {
+		names = append(names, child)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (f *s3Filesystem) Lstat(name string) (FileInfo, error) {
+	info, err := f.stat("lstat", name)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (f *s3Filesystem) Mkdir(name string, perm FileMode) error {
+	if _, err := f.stat("mkdir", name); err == nil {
+		return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrExist}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	parent, err := f.stat("mkdir", filepath.Dir(name))
+	if err != nil {
+		return err
+	}
+	if !parent.IsDir() {
+		return &os.PathError{Op: "mkdir", Path: name, Err: errS3NotDirectory}
+	}
+	return f.putDirMarker("mkdir", name)
+}
+
+func (f *s3Filesystem) MkdirAll(name string, perm FileMode) error {
+	name, err := Canonicalize(name)
+	if err != nil {
+		return err
+	}
+	if name == "." {
+		return nil
+	}
+	dir := ""
+	for _, part := range strings.Split(name, string(PathSeparator)) {
+		dir = filepath.Join(dir, part)
+		info, err := f.stat("mkdir", dir)
+		switch {
+		case os.IsNotExist(err):
+			if err := f.putDirMarker("mkdir", dir); err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		case !info.IsDir():
+			return &os.PathError{Op: "mkdir", Path: dir, Err: errS3NotDirectory}
+		}
+	}
+	return nil
+}
+
+func (f *s3Filesystem) putDirMarker(op, name string) error {
+	key, err := f.key(name)
+	if err != nil {
+		return err
+	}
+	if err := f.client.putObject(key+"/", nil, 0); err != nil {
+		return &os.PathError{Op: op, Path: name, Err: err}
+	}
+	return nil
+}
+
+func (f *s3Filesystem) Open(name string) (File, error) {
+	return f.OpenFile(name, OptReadOnly, 0)
+}
+
+// OpenFile reads files open read only directly from the object store.
+// Files open for writing are staged in a local temporary file, initially
+// holding the current contents unless truncated, which is uploaded when
+// the file is synced or closed.
+func (f *s3Filesystem) OpenFile(name string, flags int, mode FileMode) (File, error) {
+	key, err := f.key(name)
+	if err != nil {
+		return nil, err
+	}
+	writing := flags&(OptWriteOnly|OptReadWrite) != 0
+	info, err := f.stat("open", name)
+	switch {
+	case err == nil && info.IsDir():
+		return nil, &os.PathError{Op: "open", Path: name, Err: errS3IsDirectory}
+	case err == nil && flags&OptCreate != 0 && flags&OptExclusive != 0:
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrExist}
+	case err != nil && (!os.IsNotExist(err) || flags&OptCreate == 0 || !writing):
+		return nil, err
+	}
+
+	file := &s3File{fs: f, name: name, key: key}
+	if !writing {
+		file.size = info.Size()
+		file.modTime = info.ModTime()
+		return file, nil
+	}
+
+	if info == nil {
+		parent, err := f.stat("open", filepath.Dir(name))
+		if err != nil {
+			return nil, err
+		}
+		if !parent.IsDir() {
+			return nil, &os.PathError{Op: "open", Path: name, Err: errS3NotDirectory}
+		}
+	}
+
+	staged, err := ioutil.TempFile(f.staging, "syncthing-s3-")
+	if err != nil {
+		return nil, err
+	}
+	file.staged = staged
+	// New and truncated files are uploaded even if they aren't written to.
+	file.dirty = info == nil || flags&OptTruncate != 0
+	if !file.dirty && info.Size() > 0 {
+		if err := file.download(); err != nil {
+			file.discard()
+			return nil, &os.PathError{Op: "open", Path: name, Err: err}
+		}
+	}
+	if flags&OptAppend != 0 {
+		if _, err := staged.Seek(0, io.SeekEnd); err != nil {
+			file.discard()
+			return nil, err
+		}
+	}
+	return file, nil
+}
+
+func (f *s3Filesystem) ReadSymlink(name string) (string, error) {
+	return "", &os.PathError{Op: "readlink", Path: name, Err: errS3NotSupported}
+}
+
+func (f *s3Filesystem) Remove(name string) error {
+	info, err := f.stat("remove", name)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		if err := f.client.deleteObject(info.key); err != nil {
+			return &os.PathError{Op: "remove", Path: name, Err: err}
+		}
+		return nil
+	}
+
+	names, err := f.DirNames(name)
+	if err != nil {
+		return err
+	}
+	if len(names) > 0 {
+		return &os.PathError{Op: "remove", Path: name, Err: errS3NotEmpty}
+	}
+	if err := f.client.deleteObject(f.dirPrefix(info.key)); err != nil && !isS3NotFound(err) {
+		return &os.PathError{Op: "remove", Path: name, Err: err}
+	}
+	return nil
+}
+
+func (f *s3Filesystem) RemoveAll(name string) error {
+	key, err := f.key(name)
+	if err != nil {
+		return err
+	}
+	if key != f.prefix {
+		if err := f.client.deleteObject(key); err != nil && !isS3NotFound(err) {
+			return &os.PathError{Op: "removeall", Path: name, Err: err}
+		}
+	}
+	err = f.client.listObjects(f.dirPrefix(key), "", 0, func(objects []s3Object, _ []string) error {
+		for _, obj := range objects {
+			if err := f.client.deleteObject(obj.Key); err != nil && !isS3NotFound(err) {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return &os.PathError{Op: "removeall", Path: name, Err: err}
+	}
+	return nil
+}
+
+// Rename copies the objects to their new keys and then deletes the old
+// ones, so unlike on other filesystems it isn't atomic and renaming a
+// directory takes time in proportion to its size.
+func (f *s3Filesystem) Rename(oldname, newname string) error {
+	info, err := f.stat("rename", oldname)
+	if err != nil {
+		return err
+	}
+	newKey, err := f.key(newname)
+	if err != nil {
+		return err
+	}
+	if newInfo, err := f.stat("rename", newname); err == nil && newInfo.IsDir() != info.IsDir() {
+		return &os.PathError{Op: "rename", Path: newname, Err: os.ErrExist}
+	}
+
+	if !info.IsDir() {
+		if err := f.client.copyObject(info.key, newKey); err != nil {
+			return &os.PathError{Op: "rename", Path: oldname, Err: err}
+		}
+		if err := f.client.deleteObject(info.key); err != nil {
+			return &os.PathError{Op: "rename", Path: oldname, Err: err}
+		}
+		return nil
+	}
+
+	oldPrefix, newPrefix := f.dirPrefix(info.key), f.dirPrefix(newKey)
+	if oldPrefix == f.prefix || strings.HasPrefix(newPrefix, oldPrefix) {
+		return &os.PathError{Op: "rename", Path: oldname, Err: os.ErrInvalid}
+	}
+	err = f.client.listObjects(oldPrefix, "", 0, func(objects []s3Object, _ []string) error {
+		for _, obj := range objects {
+			if err := f.client.copyObject(obj.Key, newPrefix+strings.TrimPrefix(obj.Key, oldPrefix)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		err = f.RemoveAll(oldname)
+	}
+	if err != nil {
+		return &os.PathError{Op: "rename", Path: oldname, Err: err}
+	}
+	return nil
+}
+
+func (f *s3Filesystem) Hardlink(oldname, newname string) error {
+	return &os.PathError{Op: "link", Path: newname, Err: errS3NotSupported}
+}
+
+func (f *s3Filesystem) Stat(name string) (FileInfo, error) {
+	info, err := f.stat("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (f *s3Filesystem) SymlinksSupported() bool {
+	return false
+}
+
+// Walk lists all objects below the root at once, rather than one directory
+// at a time, and then walks them in lexical order like the walkFilesystem.
+func (f *s3Filesystem) Walk(root string, walkFn WalkFunc) error {
+	root, err := Canonicalize(root)
+	if err != nil {
+		return err
+	}
+	info, err := f.stat("lstat", root)
+	if err != nil {
+		return walkFn(root, nil, err)
+	}
+	if err := walkFn(root, info, nil); err != nil || !info.IsDir() {
+		if info.IsDir() && err == SkipDir {
+			return nil
+		}
+		return err
+	}
+
+	tree, err := f.listTree(f.dirPrefix(info.key))
+	if err != nil {
+		return walkFn(root, info, err)
+	}
+	if err := f.walkTree(tree, root, "", walkFn); err != nil && err != SkipDir {
+		return err
+	}
+	return nil
+}
+
+// An s3Tree holds the entries of each directory below a prefix, by the
+// slash separated path of the directory relative to it.
+type s3Tree map[string]map[string]*s3FileInfo
+
+func (f *s3Filesystem) listTree(prefix string) (s3Tree, error) {
+	tree := s3Tree{"": make(map[string]*s3FileInfo)}
+	add := func(dir string, info *s3FileInfo) {
+		entries, ok := tree[dir]
+		if !ok {
+			entries = make(map[string]*s3FileInfo)
+			tree[dir] = entries
+		}
+		if existing, ok := entries[info.name]; ok && existing.dir {
+			// A directory shadows a file of the same name, and the
+			// directory marker has the time of a directory.
+			if info.dir && !info.modTime.IsZero() {
+				existing.modTime = info.modTime
+			}
+			return
+		}
+		entries[info.name] = info
+	}
+
+	err := f.client.listObjects(prefix, "", 0, func(objects []s3Object, _ []string) error {
+	nextObject:
+		for _, obj := range objects {
+			rel := strings.TrimPrefix(obj.Key, prefix)
+			isDir := strings.HasSuffix(rel, "/")
+			rel = strings.TrimSuffix(rel, "/")
+			if rel == "" {
+				continue
+			}
+			parts := strings.Split(rel, "/")
+			for _, part := range parts {
+				if part == "" || part == "." || part == ".." {
+					// Can't be represented as a file.
+					continue nextObject
+				}
+			}
+			for i, part := range parts[:len(parts)-1] {
+				dir := strings.Join(parts[:i], "/")
+				add(dir, &s3FileInfo{key: prefix + strings.Join(parts[:i+1], "/"), name: part, dir: true})
+			}
+			info := &s3FileInfo{key: strings.TrimSuffix(obj.Key, "/"), name: parts[len(parts)-1], dir: isDir, modTime: obj.LastModified}
+			if !isDir {
+				info.size = obj.Size
+			}
+			add(strings.Join(parts[:len(parts)-1], "/"), info)
+		}
+		return nil
+	})
+	return tree, err
+}
+
+func (f *s3Filesystem) walkTree(tree s3Tree, path, dir string, walkFn WalkFunc) error {
+	entries := tree[dir]
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		info := entries[name]
+		filename := filepath.Join(path, name)
+		if err := walkFn(filename, info, nil); err != nil {
+			if info.IsDir() && err == SkipDir {
+				continue
+			}
+			// SkipDir on a file skips the rest of its directory.
+			return err
+		}
+		if !info.IsDir() {
+			continue
+		}
+		if err := f.walkTree(tree, filename, strings.TrimPrefix(dir+"/"+name, "/"), walkFn); err != nil && err != SkipDir {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *s3Filesystem) Watch(path string, ignore Matcher, ctx context.Context, ignorePerms bool) (<-chan Event, <-chan error, error) {
+	return nil, nil, ErrWatchNotSupported
+}
+
+func (f *s3Filesystem) Hide(name string) error {
+	return nil
+}
+
+func (f *s3Filesystem) Unhide(name string) error {
+	return nil
+}
+
+// Glob supports patterns in the last path component only.
+func (f *s3Filesystem) Glob(pattern string) ([]string, error) {
+	dir, base := filepath.Split(pattern)
+	names, err := f.DirNames(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var matches []string
+	for _, name := range names {
+		ok, err := filepath.Match(base, name)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matches = append(matches, filepath.Join(dir, name))
+		}
+	}
+	return matches, nil
+}
+
+func (f *s3Filesystem) Roots() ([]string, error) {
+	return []string{"/"}, nil
+}
+
+// Usage isn't known, as buckets have no fixed size.
+func (f *s3Filesystem) Usage(name string) (Usage, error) {
+	return Usage{}, errS3NotSupported
+}
+
+func (f *s3Filesystem) Type() FilesystemType {
+	return FilesystemTypeS3
+}
+
+func (f *s3Filesystem) URI() string {
+	return f.uri
+}
+
+func (f *s3Filesystem) SameFile(fi1, fi2 FileInfo) bool {
+	i1, ok1 := fi1.(*s3FileInfo)
+	i2, ok2 := fi2.(*s3FileInfo)
+	return ok1 && ok2 && i1.key == i2.key
+}
+
+func (f *s3Filesystem) CloneRange(src File, srcOffset int64, dst File, dstOffset, length int64) error {
+	return ErrCloneNotSupported
+}
+
+func (f *s3Filesystem) DedupeRange(src File, srcOffset int64, dst File, dstOffset, length int64) error {
+	return ErrCloneNotSupported
+}
+
+func (f *s3Filesystem) GetXattr(name string) ([]protocol.Xattr, error) {
+	return nil, ErrXattrsNotSupported
+}
+
+func (f *s3Filesystem) SetXattr(name string, xattrs []protocol.Xattr) error {
+	return ErrXattrsNotSupported
+}
+
+func (f *s3Filesystem) GetPlatformData(name string) (protocol.PlatformData, error) {
+	return protocol.PlatformData{}, ErrPlatformDataNotSupported
+}
+
+func (f *s3Filesystem) SetPlatformData(name string, data protocol.PlatformData) error {
+	return ErrPlatformDataNotSupported
+}
+
+// s3File is an object open for reading, or a staged file open for writing.
+type s3File struct {
+	fs   *s3Filesystem
+	name string
+	key  string
+
+	// Objects open for reading. Sequential reads share one request.
+	size    int64
+	modTime time.Time
+	offset  int64
+	body    io.ReadCloser
+	bodyPos int64
+
+	// Files open for writing.
+	staged *os.File
+	dirty  bool
+}
+
+func (f *s3File) Name() string {
+	return f.name
+}
+
+func (f *s3File) Read(p []byte) (int, error) {
+	if f.staged != nil {
+		return f.staged.Read(p)
+	}
+	if f.offset >= f.size {
+		return 0, io.EOF
+	}
+	if f.body != nil && f.bodyPos != f.offset {
+		f.body.Close()
+		f.body = nil
+	}
+	if f.body == nil {
+		body, err := f.fs.client.getObject(f.key, f.offset, -1)
+		if err != nil {
+			return 0, &os.PathError{Op: "read", Path: f.name, Err: err}
+		}
+		f.body, f.bodyPos = body, f.offset
+	}
+	if rest := f.size - f.offset; int64(len(p)) > rest {
+		p = p[:rest]
+	}
+	n, err := io.ReadFull(f.body, p)
+	f.offset += int64(n)
+	f.bodyPos += int64(n)
+	if err == io.ErrUnexpectedEOF || err == io.EOF {
+		// Changed since opened; the next read starts over.
+		f.body.Close()
+		f.body = nil
+		if n > 0 {
+			err = nil
+		}
+	}
+	return n, err
+}
+
+func (f *s3File) ReadAt(p []byte, off int64) (int, error) {
+	if f.staged != nil {
+		return f.staged.ReadAt(p, off)
+	}
+	if off >= f.size {
+		return 0, io.EOF
+	}
+	length := int64(len(p))
+	if rest := f.size - off; length > rest {
+		length = rest
+	}
+	body, err := f.fs.client.getObject(f.key, off, length)
+	if err != nil {
+		return 0, &os.PathError{Op: "read", Path: f.name, Err: err}
+	}
+	defer body.Close()
+	n, err := io.ReadFull(body, p[:length])
+	if err == nil && n < len(p) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func (f *s3File) Seek(offset int64, whence int) (int64, error) {
+	if f.staged != nil {
+		return f.staged.Seek(offset, whence)
+	}
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.size
+	}
+	if offset < 0 {
+		return 0, &os.PathError{Op: "seek", Path: f.name, Err: os.ErrInvalid}
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *s3File) Write(p []byte) (int, error) {
+	if f.staged == nil {
+		return 0, &os.PathError{Op: "write", Path: f.name, Err: errS3ReadOnlyFile}
+	}
+	f.dirty = true
+	return f.staged.Write(p)
+}
+
+func (f *s3File) WriteAt(p []byte, off int64) (int, error) {
+	if f.staged == nil {
+		return 0, &os.PathError{Op: "write", Path: f.name, Err: errS3ReadOnlyFile}
+	}
+	f.dirty = true
+	return f.staged.WriteAt(p, off)
+}
+
+func (f *s3File) Truncate(size int64) error {
+	if f.staged == nil {
+		return &os.PathError{Op: "truncate", Path: f.name, Err: errS3ReadOnlyFile}
+	}
+	f.dirty = true
+	return f.staged.Truncate(size)
+}
+
+func (f *s3File) Stat() (FileInfo, error) {
+	info := &s3FileInfo{key: f.key, name: filepath.Base(f.name), size: f.size, modTime: f.modTime}
+	if f.staged != nil {
+		staged, err := f.staged.Stat()
+		if err != nil {
+			return nil, err
+		}
+		info.size = staged.Size()
+		info.modTime = staged.ModTime()
+	}
+	return info, nil
+}
+
+// Sync uploads the staged file, if it changed.
+func (f *s3File) Sync() error {
+	if f.staged == nil || !f.dirty {
+		return nil
+	}
+	if err := f.upload(); err != nil {
+		return &os.PathError{Op: "sync", Path: f.name, Err: err}
+	}
+	f.dirty = false
+	return nil
+}
+
+func (f *s3File) Close() error {
+	if f.staged == nil {
+		if f.body != nil {
+			return f.body.Close()
+		}
+		return nil
+	}
+	err := f.Sync()
+	f.discard()
+	return err
+}
+
+func (f *s3File) discard() {
+	f.staged.Close()
+	os.Remove(f.staged.Name())
+}
+
+func (f *s3File) download() error {
+	body, err := f.fs.client.getObject(f.key, 0, -1)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	if _, err := io.Copy(f.staged, body); err != nil {
+		return err
+	}
+	_, err = f.staged.Seek(0, io.SeekStart)
+	return err
+}
+
+func (f *s3File) upload() error {
+	info, err := f.staged.Stat()
+	if err != nil {
+		return err
+	}
+	size := info.Size()
+	if size <= s3PartSize {
+		return f.fs.client.putObject(f.key, io.NewSectionReader(f.staged, 0, size), size)
+	}
+
+	uploadID, err := f.fs.client.createMultipartUpload(f.key)
+	if err != nil {
+		return err
+	}
+	var parts []s3CompletePart
+	for offset := int64(0); offset < size; offset += s3PartSize {
+		length := s3PartSize
+		if rest := size - offset; length > rest {
+			length = rest
+		}
+		number := len(parts) + 1
+		etag, err := f.fs.client.uploadPart(f.key, uploadID, number, io.NewSectionReader(f.staged, offset, length), length)
+		if err != nil {
+			// Otherwise the parts are kept, and paid for, indefinitely.
+			if abortErr := f.fs.client.abortMultipartUpload(f.key, uploadID); abortErr != nil {
+				l.Debugln("s3: aborting upload of", f.key+":", abortErr)
+			}
+			return err
+		}
+		parts = append(parts, s3CompletePart{PartNumber: number, ETag: etag})
+	}
+	return f.fs.client.completeMultipartUpload(f.key, uploadID, parts)
+}
+
+// s3FileInfo is the stat result.
+type s3FileInfo struct {
+	key     string
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+}
+
+func (i *s3FileInfo) Name() string {
+	return i.name
+}
+
+func (i *s3FileInfo) Mode() FileMode {
+	if i.dir {
+		return FileMode(os.ModeDir) | 0755
+	}
+	return 0644
+}
+
+func (i *s3FileInfo) Size() int64 {
+	return i.size
+}
+
+func (i *s3FileInfo) ModTime() time.Time {
+	return i.modTime
+}
+
+func (i *s3FileInfo) IsDir() bool {
+	return i.dir
+}
+
+func (i *s3FileInfo) IsRegular() bool {
+	return !i.dir
+}
+
+func (i *s3FileInfo) IsSymlink() bool {
+	return false
+}
+
+func (i *s3FileInfo) Owner() int {
+	return -1
+}
+
+func (i *s3FileInfo) Group() int {
+	return -1
+}
+
+func (i *s3FileInfo) Inode() uint64 {
+	return 0
+}
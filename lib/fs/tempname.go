@@ -47,6 +47,28 @@ func IsTemporary(name string) bool {
 }
 
 func TempNameWithPrefix(name, prefix string) string {
+	return tempName(name, prefix, ".tmp")
+}
+
+func TempName(name string) string {
+	return TempNameWithPrefix(name, TempPrefix)
+}
+
+// SnapshotName returns the name of the read only snapshot of the file that
+// requests are served from while it changes. It is a temporary name, so the
+// scanner leaves it alone, but distinct from the one used when pulling.
+func SnapshotName(name string) string {
+	return tempName(name, TempPrefix, ".snap")
+}
+
+// CompressedTempName returns the name of the temporary file that pulled
+// data is written to in compressed form, distinct from the regular one as
+// the contents can't be reused the same way.
+func CompressedTempName(name string) string {
+	return tempName(name, TempPrefix, ".lz4")
+}
+
+func tempName(name, prefix, suffix string) string {
 	tdir := filepath.Dir(name)
 	tbase := filepath.Base(name)
 	if len(tbase) > maxFilenameLength {
@@ -54,10 +76,6 @@ func TempNameWithPrefix(name, prefix string) string {
 		hash.Write([]byte(name))
 		tbase = fmt.Sprintf("%x", hash.Sum(nil))
 	}
-	tname := fmt.Sprintf("%s%s.tmp", prefix, tbase)
+	tname := fmt.Sprintf("%s%s%s", prefix, tbase, suffix)
 	return filepath.Join(tdir, tname)
 }
-
-func TempName(name string) string {
-	return TempNameWithPrefix(name, TempPrefix)
-}
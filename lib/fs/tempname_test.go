@@ -7,6 +7,7 @@
 package fs
 
 import (
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -24,3 +25,16 @@ func TestLongTempFilename(t *testing.T) {
 		t.Fatal("Invalid short filename", TempName("short"))
 	}
 }
+
+func TestSnapshotName(t *testing.T) {
+	name := SnapshotName(filepath.Join("dir", "file"))
+	if !IsTemporary(name) {
+		t.Errorf("snapshot name %q is not temporary", name)
+	}
+	if name == TempName(filepath.Join("dir", "file")) {
+		t.Error("snapshot name should differ from the temp name")
+	}
+	if filepath.Dir(name) != "dir" {
+		t.Errorf("snapshot %q should be in the same directory as the file", name)
+	}
+}
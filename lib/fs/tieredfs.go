@@ -0,0 +1,383 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+var errStubChanged = errors.New("file changed while offloading or restoring")
+
+// A TieredFilesystem keeps the contents of files that have gone cold in a
+// secondary filesystem, leaving stubs in their place. A stub is a sparse
+// file with the size, modification time, permissions and extended
+// attributes of the original, so it looks unchanged to scanning. Stubs are
+// restored when they are opened, renamed or have their modification time
+// changed through the filesystem, but not when accessed by other means.
+type TieredFilesystem interface {
+	Filesystem
+	// Offload moves the contents of the named file to the secondary
+	// filesystem if it has been neither accessed nor modified since the
+	// given time, returning whether it did.
+	Offload(name string, coldSince time.Time) (bool, error)
+	// IsOffloaded returns true if the named file is a stub.
+	IsOffloaded(name string) bool
+	// Offloaded returns the number and total size of the files kept in
+	// the secondary filesystem.
+	Offloaded() (files int, bytes int64)
+}
+
+type tieredFilesystem struct {
+	Filesystem
+	secondary Filesystem
+
+	mut   sync.Mutex           // protects stubs
+	stubs map[string]stubEntry // file name -> original; nil until loaded
+
+	// Only one file is offloaded or restored at a time.
+	moveMut sync.Mutex
+}
+
+type stubEntry struct {
+	size    int64
+	modTime time.Time
+}
+
+// NewTieredFilesystem returns a filesystem that offloads files from the
+// primary filesystem to the same path in the secondary one.
+func NewTieredFilesystem(primary, secondary Filesystem) TieredFilesystem {
+	return &tieredFilesystem{
+		Filesystem: primary,
+		secondary:  secondary,
+	}
+}
+
+func (f *tieredFilesystem) Offload(name string, coldSince time.Time) (bool, error) {
+	name = filepath.Clean(name)
+
+	f.moveMut.Lock()
+	defer f.moveMut.Unlock()
+
+	if f.IsOffloaded(name) {
+		return false, nil
+	}
+	info, err := f.Filesystem.Lstat(name)
+	if err != nil {
+		return false, err
+	}
+	if !info.IsRegular() || info.Size() == 0 || info.ModTime().After(coldSince) || f.lastAccess(name, info).After(coldSince) {
+		return false, nil
+	}
+	xattrs, _ := f.Filesystem.GetXattr(name)
+	platform, _ := f.Filesystem.GetPlatformData(name)
+
+	// Keep the contents in the secondary filesystem first...
+
+	if err := f.secondary.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return false, err
+	}
+	tmp, err := writeTemp(f.secondary, name, info, nil, protocol.PlatformData{}, func(fd File) error {
+		src, err := f.Filesystem.Open(name)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		if _, err := io.Copy(fd, src); err != nil {
+			return err
+		}
+		return fd.Sync()
+	})
+	if err != nil {
+		return false, err
+	}
+	if err := f.secondary.Rename(tmp, name); err != nil {
+		f.secondary.Remove(tmp)
+		return false, err
+	}
+
+	// ... and only then replace the file by a stub. Anyone opening the
+	// file after this is recorded will have to wait for us to finish and
+	// then restore it.
+
+	f.mut.Lock()
+	f.loadStubs()
+	f.stubs[name] = stubEntry{info.Size(), info.ModTime()}
+	f.mut.Unlock()
+
+	tmp, err = writeTemp(f.Filesystem, name, info, xattrs, platform, func(fd File) error {
+		return fd.Truncate(info.Size())
+	})
+	if err == nil {
+		err = replaceUnchanged(f.Filesystem, tmp, name, info)
+	}
+	if err != nil {
+		f.forget(name)
+		return false, err
+	}
+	return true, nil
+}
+
+func (f *tieredFilesystem) IsOffloaded(name string) bool {
+	name = filepath.Clean(name)
+	f.mut.Lock()
+	f.loadStubs()
+	stub, ok := f.stubs[name]
+	f.mut.Unlock()
+	if !ok {
+		return false
+	}
+
+	// The file may have been replaced by other means since.
+	info, err := f.Filesystem.Lstat(name)
+	return err == nil && info.IsRegular() && info.Size() == stub.size && info.ModTime().Unix() == stub.modTime.Unix()
+}
+
+func (f *tieredFilesystem) Offloaded() (int, int64) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	f.loadStubs()
+	var bytes int64
+	for _, stub := range f.stubs {
+		bytes += stub.size
+	}
+	return len(f.stubs), bytes
+}
+
+func (f *tieredFilesystem) Open(name string) (File, error) {
+	if err := f.restore(name); err != nil {
+		return nil, err
+	}
+	return f.Filesystem.Open(name)
+}
+
+func (f *tieredFilesystem) OpenFile(name string, flags int, mode FileMode) (File, error) {
+	if err := f.restore(name); err != nil {
+		return nil, err
+	}
+	return f.Filesystem.OpenFile(name, flags, mode)
+}
+
+func (f *tieredFilesystem) Create(name string) (File, error) {
+	fd, err := f.Filesystem.Create(name)
+	if err == nil {
+		f.forget(name)
+	}
+	return fd, err
+}
+
+func (f *tieredFilesystem) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	// The modification time is what tells a stub from a changed file.
+	if err := f.restore(name); err != nil {
+		return err
+	}
+	return f.Filesystem.Chtimes(name, atime, mtime)
+}
+
+func (f *tieredFilesystem) Rename(oldname, newname string) error {
+	// The contents stay where they are, so whatever is moved must be
+	// restored first.
+	if err := f.restoreAll(oldname); err != nil {
+		return err
+	}
+	if err := f.Filesystem.Rename(oldname, newname); err != nil {
+		return err
+	}
+	f.forget(newname)
+	return nil
+}
+
+func (f *tieredFilesystem) Remove(name string) error {
+	if err := f.Filesystem.Remove(name); err != nil {
+		return err
+	}
+	f.forget(name)
+	return nil
+}
+
+func (f *tieredFilesystem) RemoveAll(name string) error {
+	if err := f.Filesystem.RemoveAll(name); err != nil {
+		return err
+	}
+	for _, stub := range f.stubsIn(name) {
+		f.forget(stub)
+	}
+	return nil
+}
+
+// restore brings back the contents of the named file if it's a stub.
+func (f *tieredFilesystem) restore(name string) error {
+	name = filepath.Clean(name)
+	if !f.IsOffloaded(name) {
+		return nil
+	}
+
+	f.moveMut.Lock()
+	defer f.moveMut.Unlock()
+
+	if !f.IsOffloaded(name) {
+		// Restored while we waited.
+		return nil
+	}
+	info, err := f.Filesystem.Lstat(name)
+	if err != nil {
+		return err
+	}
+	xattrs, _ := f.Filesystem.GetXattr(name)
+	platform, _ := f.Filesystem.GetPlatformData(name)
+
+	tmp, err := writeTemp(f.Filesystem, name, info, xattrs, platform, func(fd File) error {
+		src, err := f.secondary.Open(name)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		_, err = io.Copy(fd, src)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := replaceUnchanged(f.Filesystem, tmp, name, info); err != nil {
+		return err
+	}
+	l.Debugln("Restored offloaded file", name)
+	f.forget(name)
+	return nil
+}
+
+// restoreAll restores the named file or all stubs in the named directory.
+func (f *tieredFilesystem) restoreAll(name string) error {
+	for _, stub := range f.stubsIn(name) {
+		if err := f.restore(stub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stubsIn returns the names of the stubs that are, or are in, the named
+// file or directory.
+func (f *tieredFilesystem) stubsIn(name string) []string {
+	name = filepath.Clean(name)
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	f.loadStubs()
+	var stubs []string
+	for stub := range f.stubs {
+		if stub == name || name == "." || IsParent(stub, name) {
+			stubs = append(stubs, stub)
+		}
+	}
+	return stubs
+}
+
+// forget removes the contents kept for the named file, which is no longer
+// a stub.
+func (f *tieredFilesystem) forget(name string) {
+	name = filepath.Clean(name)
+	f.mut.Lock()
+	f.loadStubs()
+	_, ok := f.stubs[name]
+	delete(f.stubs, name)
+	f.mut.Unlock()
+	if ok {
+		f.secondary.Remove(name)
+	}
+}
+
+// loadStubs reads what files are kept in the secondary filesystem, once.
+// Must be called with mut held.
+func (f *tieredFilesystem) loadStubs() {
+	if f.stubs != nil {
+		return
+	}
+	f.stubs = make(map[string]stubEntry)
+	f.secondary.Walk(".", func(path string, info FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsRegular() && !IsTemporary(path) {
+			f.stubs[path] = stubEntry{info.Size(), info.ModTime()}
+		}
+		return nil
+	})
+}
+
+// lastAccess returns when the named file was last read or written, as far
+// as we can tell.
+func (f *tieredFilesystem) lastAccess(name string, info FileInfo) time.Time {
+	if f.Filesystem.Type() != FilesystemTypeBasic {
+		return info.ModTime()
+	}
+	osInfo, err := os.Lstat(filepath.Join(f.Filesystem.URI(), name))
+	if err != nil {
+		return info.ModTime()
+	}
+	if atime, ok := accessTime(osInfo); ok && atime.After(info.ModTime()) {
+		return atime
+	}
+	return info.ModTime()
+}
+
+// writeTemp writes a temporary file for the named file, with the mode,
+// modification time, extended attributes and platform data given, and
+// returns its name.
+func writeTemp(fs Filesystem, name string, info FileInfo, xattrs []protocol.Xattr, platform protocol.PlatformData, write func(File) error) (string, error) {
+	tmp := TempName(name)
+	fd, err := fs.OpenFile(tmp, OptWriteOnly|OptCreate|OptTruncate, info.Mode()&ModePerm)
+	if err != nil {
+		return "", err
+	}
+	if err := write(fd); err != nil {
+		fd.Close()
+		fs.Remove(tmp)
+		return "", err
+	}
+	if err := fd.Close(); err != nil {
+		fs.Remove(tmp)
+		return "", err
+	}
+
+	// Best effort, like when pulling.
+	_ = fs.Chmod(tmp, info.Mode()&ModePerm)
+	_ = fs.Lchown(tmp, info.Owner(), info.Group())
+	if len(xattrs) > 0 {
+		_ = fs.SetXattr(tmp, xattrs)
+	}
+	if len(platform.Windows.Streams) > 0 {
+		_ = fs.SetPlatformData(tmp, platform)
+	}
+
+	if err := fs.Chtimes(tmp, info.ModTime(), info.ModTime()); err != nil {
+		fs.Remove(tmp)
+		return "", err
+	}
+	return tmp, nil
+}
+
+// replaceUnchanged renames tmp over name, unless name was changed since
+// info was taken.
+func replaceUnchanged(fs Filesystem, tmp, name string, info FileInfo) error {
+	cur, err := fs.Lstat(name)
+	if err == nil && (!cur.IsRegular() || cur.Size() != info.Size() || !cur.ModTime().Equal(info.ModTime())) {
+		err = errStubChanged
+	}
+	if err == nil {
+		err = fs.Rename(tmp, name)
+	}
+	if err != nil {
+		fs.Remove(tmp)
+	}
+	return err
+}
@@ -0,0 +1,19 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// +build !linux,!windows
+
+package fs
+
+import (
+	"os"
+	"time"
+)
+
+// accessTime isn't implemented here, so only the modification time counts.
+func accessTime(_ os.FileInfo) (time.Time, bool) {
+	return time.Time{}, false
+}
@@ -0,0 +1,94 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTieredFilesystem(t *testing.T) {
+	primary, primaryDir := setup(t)
+	defer os.RemoveAll(primaryDir)
+	secondary, secondaryDir := setup(t)
+	defer os.RemoveAll(secondaryDir)
+
+	contents := []byte("the contents of a cold file")
+	old := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+	for _, name := range []string{"cold", filepath.Join("dir", "cold"), "warm"} {
+		path := filepath.Join(primaryDir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := ioutil.WriteFile(path, contents, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if name != "warm" {
+			os.Chtimes(path, old, old)
+		}
+	}
+
+	tfs := NewTieredFilesystem(primary, secondary)
+	coldSince := time.Now().Add(-24 * time.Hour)
+
+	for _, name := range []string{"cold", filepath.Join("dir", "cold"), "warm"} {
+		offloaded, err := tfs.Offload(name, coldSince)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if offloaded != (name != "warm") {
+			t.Errorf("%s: offloaded %v", name, offloaded)
+		}
+	}
+	if files, bytes := tfs.Offloaded(); files != 2 || bytes != 2*int64(len(contents)) {
+		t.Errorf("expected two files offloaded, got %d of %d bytes", files, bytes)
+	}
+
+	// The stub looks like the original but has no contents.
+	info, err := primary.Lstat("cold")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != int64(len(contents)) || !info.ModTime().Equal(old) {
+		t.Errorf("stub has size %d and mtime %v", info.Size(), info.ModTime())
+	}
+	if bs, _ := ioutil.ReadFile(filepath.Join(primaryDir, "cold")); string(bs) == string(contents) {
+		t.Error("stub should not have the contents")
+	}
+	if !tfs.IsOffloaded("cold") {
+		t.Error("should be offloaded")
+	}
+
+	// Opening it restores it.
+	fd, err := tfs.Open("cold")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs, err := ioutil.ReadAll(fd)
+	fd.Close()
+	if err != nil || string(bs) != string(contents) {
+		t.Errorf("restored contents %q, %v", bs, err)
+	}
+	if tfs.IsOffloaded("cold") {
+		t.Error("should have been restored")
+	}
+	if _, err := secondary.Lstat("cold"); !IsNotExist(err) {
+		t.Error("restored contents should be removed from the secondary filesystem")
+	}
+
+	// So does moving the directory it is in.
+	if err := tfs.Rename("dir", "moved"); err != nil {
+		t.Fatal(err)
+	}
+	if bs, _ := ioutil.ReadFile(filepath.Join(primaryDir, "moved", "cold")); string(bs) != string(contents) {
+		t.Errorf("moved file has contents %q", bs)
+	}
+	if files, _ := tfs.Offloaded(); files != 0 {
+		t.Errorf("expected nothing offloaded, got %d files", files)
+	}
+}
@@ -11,6 +11,8 @@ type FilesystemType int
 const (
 	FilesystemTypeBasic FilesystemType = iota // default is basic
 	FilesystemTypeFake
+	FilesystemTypeS3
+	FilesystemTypeWebDAV
 )
 
 func (t FilesystemType) String() string {
@@ -19,6 +21,10 @@ func (t FilesystemType) String() string {
 		return "basic"
 	case FilesystemTypeFake:
 		return "fake"
+	case FilesystemTypeS3:
+		return "s3"
+	case FilesystemTypeWebDAV:
+		return "webdav"
 	default:
 		return "unknown"
 	}
@@ -34,6 +40,10 @@ func (t *FilesystemType) UnmarshalText(bs []byte) error {
 		*t = FilesystemTypeBasic
 	case "fake":
 		*t = FilesystemTypeFake
+	case "s3":
+		*t = FilesystemTypeS3
+	case "webdav":
+		*t = FilesystemTypeWebDAV
 	default:
 		*t = FilesystemTypeBasic
 	}
@@ -97,6 +97,18 @@ func IsParent(path, parent string) bool {
 	return strings.HasPrefix(path, parent)
 }
 
+// InSubtrees returns true if the path is one of the roots, is inside one,
+// or is a parent directory of one, i.e. needed to reach it. All paths are
+// relative to the same directory.
+func InSubtrees(path string, roots []string) bool {
+	for _, root := range roots {
+		if path == root || IsParent(path, root) || IsParent(root, path) {
+			return true
+		}
+	}
+	return false
+}
+
 func CommonPrefix(first, second string) string {
 	if filepath.IsAbs(first) != filepath.IsAbs(second) {
 		// Whatever
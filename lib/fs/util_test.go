@@ -7,6 +7,7 @@
 package fs
 
 import (
+	"path/filepath"
 	"runtime"
 	"testing"
 )
@@ -44,3 +45,17 @@ func TestCommonPrefix(t *testing.T) {
 	test(`Audrius`, `Audrius`, `Audrius`)
 	test(`.`, `.`, `.`)
 }
+
+func TestInSubtrees(t *testing.T) {
+	roots := []string{filepath.FromSlash("photos/2020"), "music"}
+	for _, path := range []string{"photos", "photos/2020", "photos/2020/a.jpg", "music", "music/b/c.mp3"} {
+		if !InSubtrees(filepath.FromSlash(path), roots) {
+			t.Errorf("%q should be in the subtrees", path)
+		}
+	}
+	for _, path := range []string{"photos/2019", "photos/2020x", "musical", "docs/music"} {
+		if InSubtrees(filepath.FromSlash(path), roots) {
+			t.Errorf("%q should not be in the subtrees", path)
+		}
+	}
+}
@@ -0,0 +1,322 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// WatchMode is how a filesystem is watched for changes. Notifications from
+// the operating system are unreliable on network shares, where changes
+// made by other clients of the share are usually not notified at all.
+type WatchMode int
+
+const (
+	WatchModeAuto   WatchMode = iota // hybrid on network shares, poll on object storage and WebDAV, native elsewhere
+	WatchModeNative                  // notifications only
+	WatchModeHybrid                  // notifications, and re-polling the directories that had them recently
+	WatchModePoll                    // walking the whole tree periodically
+)
+
+func (m WatchMode) String() string {
+	switch m {
+	case WatchModeAuto:
+		return "auto"
+	case WatchModeNative:
+		return "native"
+	case WatchModeHybrid:
+		return "hybrid"
+	case WatchModePoll:
+		return "poll"
+	default:
+		return "unknown"
+	}
+}
+
+func (m WatchMode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *WatchMode) UnmarshalText(bs []byte) error {
+	switch string(bs) {
+	case "native":
+		*m = WatchModeNative
+	case "hybrid":
+		*m = WatchModeHybrid
+	case "poll":
+		*m = WatchModePoll
+	default:
+		*m = WatchModeAuto
+	}
+	return nil
+}
+
+// Not meant to be changed, but must be changeable for tests
+var (
+	// Interval between walks of the whole tree in poll mode.
+	pollInterval = time.Minute
+	// Interval between re-polls of directories in hybrid mode, and for how
+	// long after the last change seen in a directory it is re-polled.
+	hybridPollInterval = 10 * time.Second
+	hybridActivePeriod = 10 * time.Minute
+)
+
+type watchModeFilesystem struct {
+	Filesystem
+	mode WatchMode
+}
+
+// NewWatchModeFilesystem returns a filesystem that is watched in the given
+// mode. Native watching is left to the filesystem itself.
+func NewWatchModeFilesystem(fs Filesystem, mode WatchMode) Filesystem {
+	if mode == WatchModeNative {
+		return fs
+	}
+	return &watchModeFilesystem{
+		Filesystem: fs,
+		mode:       mode,
+	}
+}
+
+func (f *watchModeFilesystem) Watch(name string, ignore Matcher, ctx context.Context, ignorePerms bool) (<-chan Event, <-chan error, error) {
+	mode := f.mode
+	if mode == WatchModeAuto {
+		// Checked when starting to watch rather than up front, as the
+		// share may not have been mounted yet.
+		mode = WatchModeNative
+		switch {
+		case f.Type() == FilesystemTypeBasic && isNetworkFilesystem(f.URI()):
+			l.Debugln(f.Type(), f.URI(), "Watch: Network filesystem, watching in hybrid mode")
+			mode = WatchModeHybrid
+		case f.Type() == FilesystemTypeS3 || f.Type() == FilesystemTypeWebDAV:
+			// Object stores and WebDAV have no notifications at all.
+			mode = WatchModePoll
+		}
+	}
+
+	switch mode {
+	case WatchModePoll:
+		return f.pollWatch(name, ignore, ctx, ignorePerms)
+	case WatchModeHybrid:
+		in, errChan, err := f.Filesystem.Watch(name, ignore, ctx, ignorePerms)
+		if err != nil {
+			return nil, nil, err
+		}
+		outChan := make(chan Event)
+		go f.hybridLoop(ctx, in, outChan, ignore, ignorePerms)
+		return outChan, errChan, nil
+	default:
+		return f.Filesystem.Watch(name, ignore, ctx, ignorePerms)
+	}
+}
+
+func (f *watchModeFilesystem) pollWatch(name string, ignore Matcher, ctx context.Context, ignorePerms bool) (<-chan Event, <-chan error, error) {
+	snap, err := f.walkSnapshot(ctx, name, ignore, ignorePerms)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	outChan := make(chan Event)
+	go func() {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				l.Debugln(f.Type(), f.URI(), "Watch: Stopped")
+				return
+			}
+			newSnap, err := f.walkSnapshot(ctx, name, ignore, ignorePerms)
+			if err != nil {
+				// The share may be unreachable for a while, which
+				// mustn't look like everything was removed.
+				l.Debugln(f.Type(), f.URI(), "Watch: Polling:", err)
+				continue
+			}
+			if !sendWatchEvents(ctx, outChan, diffWatchEntries(snap, newSnap)) {
+				l.Debugln(f.Type(), f.URI(), "Watch: Stopped")
+				return
+			}
+			snap = newSnap
+		}
+	}()
+
+	// Polling has no errors that would stop it.
+	return outChan, make(chan error), nil
+}
+
+// An activeDir is a directory that is re-polled in hybrid mode.
+type activeDir struct {
+	lastChange time.Time
+	entries    map[string]watchEntry
+}
+
+func (f *watchModeFilesystem) hybridLoop(ctx context.Context, in <-chan Event, outChan chan<- Event, ignore Matcher, ignorePerms bool) {
+	active := make(map[string]*activeDir)
+	ticker := time.NewTicker(hybridPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case ev := <-in:
+			// Other clients of a share tend to change the same directories
+			// as we can see being changed, so the directory is watched
+			// more closely for a while.
+			dir := filepath.Dir(ev.Name)
+			if d, ok := active[dir]; ok {
+				d.lastChange = time.Now()
+			} else if entries, err := f.listDir(dir, ignore, ignorePerms); err == nil {
+				active[dir] = &activeDir{lastChange: time.Now(), entries: entries}
+			}
+			if !sendWatchEvents(ctx, outChan, []Event{ev}) {
+				return
+			}
+
+		case <-ticker.C:
+			var evs []Event
+			for dir, d := range active {
+				if time.Since(d.lastChange) > hybridActivePeriod {
+					delete(active, dir)
+					continue
+				}
+				entries, err := f.listDir(dir, ignore, ignorePerms)
+				if err != nil {
+					// Removed, or the share is unreachable; scanning
+					// will tell which.
+					delete(active, dir)
+					evs = append(evs, Event{Name: dir, Type: NonRemove})
+					continue
+				}
+				if changed := diffWatchEntries(d.entries, entries); len(changed) > 0 {
+					l.Debugln(f.Type(), f.URI(), "Watch: Re-polling", dir, "found", len(changed), "changes")
+					evs = append(evs, changed...)
+					d.lastChange = time.Now()
+				}
+				d.entries = entries
+			}
+			if !sendWatchEvents(ctx, outChan, evs) {
+				return
+			}
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// A watchEntry is what is compared of files when polling.
+type watchEntry struct {
+	mode    FileMode
+	size    int64
+	modTime time.Time
+}
+
+func newWatchEntry(info FileInfo, ignorePerms bool) watchEntry {
+	e := watchEntry{
+		mode:    info.Mode(),
+		modTime: info.ModTime(),
+	}
+	if ignorePerms {
+		e.mode &= ModeType
+	}
+	if !info.IsDir() {
+		e.size = info.Size()
+	}
+	return e
+}
+
+func (e watchEntry) equal(other watchEntry) bool {
+	return e.mode == other.mode && e.size == other.size && e.modTime.Equal(other.modTime)
+}
+
+// walkSnapshot returns the entries of the tree below name that aren't
+// ignored.
+func (f *watchModeFilesystem) walkSnapshot(ctx context.Context, name string, ignore Matcher, ignorePerms bool) (map[string]watchEntry, error) {
+	if _, err := f.Lstat(name); err != nil {
+		return nil, err
+	}
+	snap := make(map[string]watchEntry)
+	err := f.Walk(name, func(path string, info FileInfo, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil || path == name {
+			return nil
+		}
+		if ignore.ShouldIgnore(path) {
+			if info.IsDir() && ignore.SkipIgnoredDirs() {
+				return SkipDir
+			}
+			return nil
+		}
+		snap[path] = newWatchEntry(info, ignorePerms)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// listDir returns the entries of the directory that aren't ignored.
+func (f *watchModeFilesystem) listDir(dir string, ignore Matcher, ignorePerms bool) (map[string]watchEntry, error) {
+	names, err := f.DirNames(dir)
+	if err != nil {
+		return nil, err
+	}
+	entries := make(map[string]watchEntry, len(names))
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if ignore.ShouldIgnore(path) {
+			continue
+		}
+		info, err := f.Lstat(path)
+		if err != nil {
+			// Removed since listing, which is seen next time.
+			continue
+		}
+		entries[path] = newWatchEntry(info, ignorePerms)
+	}
+	return entries, nil
+}
+
+// diffWatchEntries returns events for the entries that were added, changed
+// or removed, sorted by name.
+func diffWatchEntries(old, cur map[string]watchEntry) []Event {
+	var evs []Event
+	for name, e := range cur {
+		if oe, ok := old[name]; !ok || !oe.equal(e) {
+			evs = append(evs, Event{Name: name, Type: NonRemove})
+		}
+	}
+	for name := range old {
+		if _, ok := cur[name]; !ok {
+			evs = append(evs, Event{Name: name, Type: Remove})
+		}
+	}
+	sort.Slice(evs, func(i, j int) bool {
+		return evs[i].Name < evs[j].Name
+	})
+	return evs
+}
+
+// sendWatchEvents returns false if the context was cancelled before all
+// events were sent.
+func sendWatchEvents(ctx context.Context, outChan chan<- Event, evs []Event) bool {
+	for _, ev := range evs {
+		select {
+		case outChan <- ev:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,132 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// +build !solaris,!darwin solaris,cgo darwin,cgo
+
+package fs
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func expectWatchEvents(t *testing.T, events <-chan Event, expected []Event) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for _, exp := range expected {
+		select {
+		case ev := <-events:
+			if ev != exp {
+				t.Fatalf("Got event %v, expected %v", ev, exp)
+			}
+		case <-timeout:
+			t.Fatalf("Timed out waiting for %v", exp)
+		}
+	}
+}
+
+func TestWatchModePoll(t *testing.T) {
+	oldInterval := pollInterval
+	pollInterval = 10 * time.Millisecond
+	defer func() { pollInterval = oldInterval }()
+
+	dir, err := ioutil.TempDir("", "syncthing-watchmode-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "removed"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ffs := NewWatchModeFilesystem(NewFilesystem(FilesystemTypeBasic, dir), WatchModePoll)
+	events, _, err := ffs.Watch(".", fakeMatcher{ignore: "ignored"}, ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Changes are seen in one poll, ignoring the ignored file.
+	for _, name := range []string{"added", "ignored"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Remove(filepath.Join(dir, "removed")); err != nil {
+		t.Fatal(err)
+	}
+	expectWatchEvents(t, events, []Event{
+		{"added", NonRemove},
+		{"removed", Remove},
+	})
+
+	select {
+	case ev := <-events:
+		t.Errorf("Unexpected event %v", ev)
+	case <-time.After(5 * pollInterval):
+	}
+}
+
+func TestWatchModeHybrid(t *testing.T) {
+	oldInterval := hybridPollInterval
+	hybridPollInterval = 10 * time.Millisecond
+	defer func() { hybridPollInterval = oldInterval }()
+
+	dir, err := ioutil.TempDir("", "syncthing-watchmode-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, sub := range []string{"active", "quiet"} {
+		if err := os.Mkdir(filepath.Join(dir, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ffs := &watchModeFilesystem{Filesystem: NewFilesystem(FilesystemTypeBasic, dir), mode: WatchModeHybrid}
+	in := make(chan Event)
+	events := make(chan Event)
+	go ffs.hybridLoop(ctx, in, events, fakeMatcher{}, false)
+
+	// A notification is passed on and makes its directory be re-polled,
+	// which finds the change that wasn't notified. The directory without
+	// notifications isn't re-polled.
+	notified := Event{filepath.Join("active", "notified"), NonRemove}
+	in <- notified
+	expectWatchEvents(t, events, []Event{notified})
+
+	for _, name := range []string{filepath.Join("active", "missed"), filepath.Join("quiet", "missed")} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expectWatchEvents(t, events, []Event{
+		{filepath.Join("active", "missed"), NonRemove},
+	})
+
+	select {
+	case ev := <-events:
+		t.Errorf("Unexpected event %v", ev)
+	case <-time.After(5 * hybridPollInterval):
+	}
+}
+
+func TestWatchModeUnmarshal(t *testing.T) {
+	for _, mode := range []WatchMode{WatchModeAuto, WatchModeNative, WatchModeHybrid, WatchModePoll} {
+		bs, _ := mode.MarshalText()
+		var got WatchMode
+		if err := got.UnmarshalText(bs); err != nil || got != mode {
+			t.Errorf("%v round tripped to %v", mode, got)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// How a WebDAV server lets parts of files be written.
+type webdavPartialMode int
+
+const (
+	webdavPartialNone  webdavPartialMode = iota // whole files only
+	webdavPartialPatch                          // PATCH with X-Update-Range, as by sabre/dav (Nextcloud, ownCloud)
+	webdavPartialPut                            // PUT with Content-Range, as by Apache mod_dav
+)
+
+const webdavPropfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:"><d:prop><d:resourcetype/><d:getcontentlength/><d:getlastmodified/></d:prop></d:propfind>`
+
+// webdavClient makes WebDAV requests for paths below a base URL.
+type webdavClient struct {
+	base     url.URL // without credentials, the path without trailing slash
+	username string
+	password string
+	http     *http.Client
+}
+
+// webdavError is an unsuccessful response.
+type webdavError struct {
+	StatusCode int
+	Method     string
+}
+
+func (e *webdavError) Error() string {
+	return fmt.Sprintf("webdav: %s: %d %s", e.Method, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+func webdavStatus(err error) int {
+	if davErr, ok := err.(*webdavError); ok {
+		return davErr.StatusCode
+	}
+	return 0
+}
+
+// webdavEntry is a file or collection as returned by PROPFIND.
+type webdavEntry struct {
+	path    string // relative to the base, slash separated, empty for the base
+	dir     bool
+	size    int64
+	modTime time.Time
+}
+
+type webdavMultistatus struct {
+	Responses []struct {
+		Href     string `xml:"DAV: href"`
+		Propstat []struct {
+			Prop struct {
+				ResourceType struct {
+					Collection *struct{} `xml:"DAV: collection"`
+				} `xml:"DAV: resourcetype"`
+				ContentLength string `xml:"DAV: getcontentlength"`
+				LastModified  string `xml:"DAV: getlastmodified"`
+			} `xml:"DAV: prop"`
+			Status string `xml:"DAV: status"`
+		} `xml:"DAV: propstat"`
+	} `xml:"DAV: response"`
+}
+
+// url returns the URL of the path relative to the base, with a trailing
+// slash for collections.
+func (c *webdavClient) url(path string, collection bool) *url.URL {
+	u := c.base
+	if path != "" {
+		u.Path += "/" + path
+	}
+	if collection {
+		u.Path += "/"
+	}
+	return &u
+}
+
+// propfind returns the entry for the path, and for depth one also its
+// children.
+func (c *webdavClient) propfind(path string, depth int, collection bool) ([]webdavEntry, error) {
+	header := http.Header{
+		"Depth":        {strconv.Itoa(depth)},
+		"Content-Type": {"application/xml; charset=utf-8"},
+	}
+	resp, err := c.do("PROPFIND", c.url(path, collection), header, strings.NewReader(webdavPropfindBody), int64(len(webdavPropfindBody)))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var ms webdavMultistatus
+	if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+		return nil, err
+	}
+
+	entries := make([]webdavEntry, 0, len(ms.Responses))
+	for _, r := range ms.Responses {
+		href, err := url.Parse(r.Href)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(href.Path, c.base.Path) {
+			return nil, fmt.Errorf("webdav: response for %s outside of %s", href.Path, c.base.Path)
+		}
+		entry := webdavEntry{path: strings.Trim(strings.TrimPrefix(href.Path, c.base.Path), "/")}
+		for _, ps := range r.Propstat {
+			if !strings.Contains(ps.Status, " 200") {
+				continue
+			}
+			entry.dir = entry.dir || ps.Prop.ResourceType.Collection != nil
+			if ps.Prop.ContentLength != "" {
+				entry.size, _ = strconv.ParseInt(ps.Prop.ContentLength, 10, 64)
+			}
+			if t, err := http.ParseTime(ps.Prop.LastModified); err == nil {
+				entry.modTime = t
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// get returns the contents of the file from offset on, up to length bytes
+// or the whole rest of it when length is negative.
+func (c *webdavClient) get(path string, offset, length int64) (io.ReadCloser, error) {
+	header := make(http.Header)
+	switch {
+	case length > 0:
+		header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	case offset > 0:
+		header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := c.do(http.MethodGet, c.url(path, false), header, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 && resp.StatusCode != http.StatusPartialContent {
+		// The whole file, as the range was ignored.
+		resp.Body.Close()
+		return nil, fmt.Errorf("webdav: range requests are not supported")
+	}
+	return resp.Body, nil
+}
+
+func (c *webdavClient) put(path string, body io.Reader, size int64) error {
+	return c.simple(http.MethodPut, c.url(path, false), nil, body, size)
+}
+
+// putRange writes part of an existing file, as the partial mode allows.
+func (c *webdavClient) putRange(mode webdavPartialMode, path string, offset int64, body io.Reader, size int64) error {
+	if size == 0 {
+		return nil
+	}
+	header := make(http.Header)
+	switch mode {
+	case webdavPartialPatch:
+		header.Set("Content-Type", "application/x-sabredav-partialupdate")
+		header.Set("X-Update-Range", fmt.Sprintf("bytes=%d-%d", offset, offset+size-1))
+		return c.simple("PATCH", c.url(path, false), header, body, size)
+	case webdavPartialPut:
+		header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/*", offset, offset+size-1))
+		return c.simple(http.MethodPut, c.url(path, false), header, body, size)
+	default:
+		panic("bug: partial write without support")
+	}
+}
+
+func (c *webdavClient) mkcol(path string) error {
+	return c.simple("MKCOL", c.url(path, true), nil, nil, 0)
+}
+
+func (c *webdavClient) delete(path string, collection bool) error {
+	return c.simple(http.MethodDelete, c.url(path, collection), nil, nil, 0)
+}
+
+func (c *webdavClient) move(oldpath, newpath string, collection bool) error {
+	header := http.Header{
+		"Destination": {c.url(newpath, collection).String()},
+		"Overwrite":   {"T"},
+	}
+	return c.simple("MOVE", c.url(oldpath, collection), header, nil, 0)
+}
+
+// partialMode returns how the server lets parts of files be written, as
+// far as it tells.
+func (c *webdavClient) partialMode() (webdavPartialMode, error) {
+	resp, err := c.do(http.MethodOptions, c.url("", true), nil, nil, 0)
+	if err != nil {
+		return webdavPartialNone, err
+	}
+	resp.Body.Close()
+	for _, class := range strings.Split(strings.Join(resp.Header["Dav"], ","), ",") {
+		if strings.TrimSpace(class) == "sabredav-partialupdate" {
+			return webdavPartialPatch, nil
+		}
+	}
+	return webdavPartialNone, nil
+}
+
+func (c *webdavClient) simple(method string, u *url.URL, header http.Header, body io.Reader, size int64) error {
+	resp, err := c.do(method, u, header, body, size)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (c *webdavClient) do(method string, u *url.URL, header http.Header, body io.Reader, size int64) (*http.Response, error) {
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	for k, vs := range header {
+		req.Header[k] = vs
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		resp.Body.Close()
+		return nil, &webdavError{StatusCode: resp.StatusCode, Method: method}
+	}
+	return resp, nil
+}
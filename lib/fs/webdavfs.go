@@ -0,0 +1,900 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+var (
+	errWebdavNotDirectory = errors.New("not a directory")
+	errWebdavIsDirectory  = errors.New("is a directory")
+	errWebdavNotEmpty     = errors.New("directory not empty")
+	errWebdavReadOnlyFile = errors.New("file is not open for writing")
+	errWebdavNotSupported = errors.New("not supported on WebDAV")
+)
+
+// The webdavFilesystem keeps files on a WebDAV server, such as Nextcloud or
+// ownCloud. The URI is of the form
+//
+//	https://[user:password@]host[:port]/path[?staging=...&rangedput=true]
+//
+// where the staging directory, where files open for writing are kept until
+// uploaded, defaults to the system temporary directory.
+//
+// Files written to are uploaded whole, except on servers that can write
+// parts of files: sabre/dav based ones, like Nextcloud and ownCloud,
+// announce it and are written to with PATCH requests, while others that
+// take PUT requests with a Content-Range, like Apache mod_dav, must be
+// marked with rangedput=true. Servers that don't support those may
+// replace the whole file with the part instead.
+//
+// Files have no permissions, and their modification time is when they were
+// uploaded, which the mtime database maps to the times files should have.
+type webdavFilesystem struct {
+	client  *webdavClient
+	uri     string // without credentials
+	staging string
+
+	partialMut   sync.Mutex
+	partial      webdavPartialMode
+	partialKnown bool
+}
+
+func newWebdavFilesystem(uri string) (*webdavFilesystem, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errors.New("webdav: URI must be of the form https://host/path")
+	}
+
+	client := &webdavClient{
+		base: url.URL{Scheme: u.Scheme, Host: u.Host, Path: strings.TrimRight(u.Path, "/")},
+		http: &http.Client{
+			// Redirects would turn requests other than GET into GETs.
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+	if u.User != nil {
+		client.username = u.User.Username()
+		client.password, _ = u.User.Password()
+	}
+
+	f := &webdavFilesystem{
+		client:     client,
+		staging:    u.Query().Get("staging"),
+		partialMut: sync.NewMutex(),
+	}
+	if f.staging == "" {
+		f.staging = os.TempDir()
+	}
+	if rangedPut, _ := strconv.ParseBool(u.Query().Get("rangedput")); rangedPut {
+		f.partial = webdavPartialPut
+		f.partialKnown = true
+	}
+
+	// The credentials must not end up in logs and the GUI.
+	u.User = nil
+	f.uri = u.String()
+	return f, nil
+}
+
+// path returns the slash separated path of the named file relative to the
+// base, which is empty for the root.
+func (f *webdavFilesystem) path(name string) (string, error) {
+	name, err := Canonicalize(name)
+	if err != nil {
+		return "", err
+	}
+	if name == "." {
+		return "", nil
+	}
+	return filepath.ToSlash(name), nil
+}
+
+// partialMode returns how parts of files can be written, asking the server
+// the first time.
+func (f *webdavFilesystem) partialMode() webdavPartialMode {
+	f.partialMut.Lock()
+	defer f.partialMut.Unlock()
+	if !f.partialKnown {
+		mode, err := f.client.partialMode()
+		if err != nil {
+			// Asked again next time.
+			l.Debugln("webdav: checking for partial updates:", err)
+			return webdavPartialNone
+		}
+		f.partial, f.partialKnown = mode, true
+	}
+	return f.partial
+}
+
+func (f *webdavFilesystem) stat(op, name string) (*webdavFileInfo, error) {
+	path, err := f.path(name)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := f.client.propfind(path, 0, path == "")
+	if err != nil {
+		if webdavStatus(err) == http.StatusNotFound {
+			err = os.ErrNotExist
+		}
+		return nil, &os.PathError{Op: op, Path: name, Err: err}
+	}
+	if len(entries) != 1 {
+		return nil, &os.PathError{Op: op, Path: name, Err: errors.New("webdav: unexpected response")}
+	}
+	return newWebdavFileInfo(filepath.Base(name), entries[0]), nil
+}
+
+// readDir returns the entries of the directory, sorted by name.
+func (f *webdavFilesystem) readDir(name string) ([]*webdavFileInfo, error) {
+	path, err := f.path(name)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := f.client.propfind(path, 1, true)
+	if err != nil {
+		if webdavStatus(err) == http.StatusNotFound {
+			err = os.ErrNotExist
+		}
+		return nil, &os.PathError{Op: "readdirent", Path: name, Err: err}
+	}
+
+	var infos []*webdavFileInfo
+	for _, entry := range entries {
+		if entry.path == path {
+			if !entry.dir {
+				return nil, &os.PathError{Op: "readdirent", Path: name, Err: errWebdavNotDirectory}
+			}
+			continue
+		}
+		base := strings.TrimPrefix(entry.path, path+"/")
+		if path == "" {
+			base = entry.path
+		}
+		if base == "" || strings.Contains(base, "/") {
+			continue
+		}
+		infos = append(infos, newWebdavFileInfo(base, entry))
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].name < infos[j].name
+	})
+	return infos, nil
+}
+
+// Chmod only checks that the file exists, as files have no permissions.
+func (f *webdavFilesystem) Chmod(name string, mode FileMode) error {
+	_, err := f.stat("chmod", name)
+	return err
+}
+
+func (f *webdavFilesystem) Lchown(name string, uid, gid int) error {
+	_, err := f.stat("lchown", name)
+	return err
+}
+
+// Chtimes only checks that the file exists, as the modification time can't
+// be set in a portable way. The mtime database keeps the requested time.
+func (f *webdavFilesystem) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	_, err := f.stat("chtimes", name)
+	return err
+}
+
+func (f *webdavFilesystem) Create(name string) (File, error) {
+	return f.OpenFile(name, OptReadWrite|OptCreate|OptTruncate, 0666)
+}
+
+func (f *webdavFilesystem) CreateSymlink(target, name string) error {
+	return &os.PathError{Op: "symlink", Path: name, Err: errWebdavNotSupported}
+}
+
+func (f *webdavFilesystem) DirNames(name string) ([]string, error) {
+	infos, err := f.readDir(name)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(infos))
+	for i, info := range infos {
+		names[i] = info.name
+	}
+	return names, nil
+}
+
+func (f *webdavFilesystem) Lstat(name string) (FileInfo, error) {
+	info, err := f.stat("lstat", name)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (f *webdavFilesystem) Mkdir(name string, perm FileMode) error {
+	path, err := f.path(name)
+	if err != nil {
+		return err
+	}
+	if err := f.client.mkcol(path); err != nil {
+		switch webdavStatus(err) {
+		case http.StatusMethodNotAllowed:
+			err = os.ErrExist
+		case http.StatusConflict:
+			// The parent doesn't exist.
+			err = os.ErrNotExist
+		}
+		return &os.PathError{Op: "mkdir", Path: name, Err: err}
+	}
+	return nil
+}
+
+func (f *webdavFilesystem) MkdirAll(name string, perm FileMode) error {
+	name, err := Canonicalize(name)
+	if err != nil {
+		return err
+	}
+	if name == "." {
+		return nil
+	}
+	dir := ""
+	for _, part := range strings.Split(name, string(PathSeparator)) {
+		dir = filepath.Join(dir, part)
+		info, err := f.stat("mkdir", dir)
+		switch {
+		case os.IsNotExist(err):
+			if err := f.Mkdir(dir, perm); err != nil && !os.IsExist(err) {
+				return err
+			}
+		case err != nil:
+			return err
+		case !info.IsDir():
+			return &os.PathError{Op: "mkdir", Path: dir, Err: errWebdavNotDirectory}
+		}
+	}
+	return nil
+}
+
+func (f *webdavFilesystem) Open(name string) (File, error) {
+	return f.OpenFile(name, OptReadOnly, 0)
+}
+
+// OpenFile reads files open read only directly from the server. Files open
+// for writing are staged in a local temporary file, which is uploaded when
+// the file is synced or closed. The staged file holds the current contents,
+// unless truncated or the server can write parts of files, in which case
+// it only holds what was written.
+func (f *webdavFilesystem) OpenFile(name string, flags int, mode FileMode) (File, error) {
+	path, err := f.path(name)
+	if err != nil {
+		return nil, err
+	}
+	writing := flags&(OptWriteOnly|OptReadWrite) != 0
+	info, err := f.stat("open", name)
+	switch {
+	case err == nil && info.IsDir():
+		return nil, &os.PathError{Op: "open", Path: name, Err: errWebdavIsDirectory}
+	case err == nil && flags&OptCreate != 0 && flags&OptExclusive != 0:
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrExist}
+	case err != nil && (!os.IsNotExist(err) || flags&OptCreate == 0 || !writing):
+		return nil, err
+	}
+
+	file := &webdavFile{fs: f, name: name, path: path}
+	if !writing {
+		file.size = info.Size()
+		file.modTime = info.ModTime()
+		return file, nil
+	}
+
+	if info == nil {
+		parent, err := f.stat("open", filepath.Dir(name))
+		if err != nil {
+			return nil, err
+		}
+		if !parent.IsDir() {
+			return nil, &os.PathError{Op: "open", Path: name, Err: errWebdavNotDirectory}
+		}
+	}
+
+	staged, err := ioutil.TempFile(f.staging, "syncthing-webdav-")
+	if err != nil {
+		return nil, err
+	}
+	file.staged = staged
+	switch {
+	case info == nil || flags&OptTruncate != 0:
+		// New and truncated files are uploaded even if they aren't
+		// written to.
+		file.dirty = true
+	case info.Size() == 0:
+	case f.partialMode() != webdavPartialNone:
+		file.partial = true
+		file.remoteSize = info.Size()
+	default:
+		if err := file.download(staged); err != nil {
+			file.discard()
+			return nil, &os.PathError{Op: "open", Path: name, Err: err}
+		}
+	}
+	if flags&OptAppend != 0 {
+		if _, err := file.Seek(0, io.SeekEnd); err != nil {
+			file.discard()
+			return nil, err
+		}
+	}
+	return file, nil
+}
+
+func (f *webdavFilesystem) ReadSymlink(name string) (string, error) {
+	return "", &os.PathError{Op: "readlink", Path: name, Err: errWebdavNotSupported}
+}
+
+func (f *webdavFilesystem) Remove(name string) error {
+	info, err := f.stat("remove", name)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		// Deleting a collection deletes everything in it.
+		children, err := f.readDir(name)
+		if err != nil {
+			return err
+		}
+		if len(children) > 0 {
+			return &os.PathError{Op: "remove", Path: name, Err: errWebdavNotEmpty}
+		}
+	}
+	return f.delete("remove", name, info.IsDir())
+}
+
+func (f *webdavFilesystem) RemoveAll(name string) error {
+	info, err := f.stat("removeall", name)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return f.delete("removeall", name, info.IsDir())
+}
+
+func (f *webdavFilesystem) delete(op, name string, dir bool) error {
+	path, err := f.path(name)
+	if err != nil {
+		return err
+	}
+	if path == "" {
+		return &os.PathError{Op: op, Path: name, Err: os.ErrInvalid}
+	}
+	if err := f.client.delete(path, dir); err != nil && webdavStatus(err) != http.StatusNotFound {
+		return &os.PathError{Op: op, Path: name, Err: err}
+	}
+	return nil
+}
+
+func (f *webdavFilesystem) Rename(oldname, newname string) error {
+	info, err := f.stat("rename", oldname)
+	if err != nil {
+		return err
+	}
+	oldpath, err := f.path(oldname)
+	if err != nil {
+		return err
+	}
+	newpath, err := f.path(newname)
+	if err != nil {
+		return err
+	}
+	if oldpath == "" || newpath == "" {
+		return &os.PathError{Op: "rename", Path: oldname, Err: os.ErrInvalid}
+	}
+	if newInfo, err := f.stat("rename", newname); err == nil && newInfo.IsDir() != info.IsDir() {
+		return &os.PathError{Op: "rename", Path: newname, Err: os.ErrExist}
+	}
+	if err := f.client.move(oldpath, newpath, info.IsDir()); err != nil {
+		return &os.PathError{Op: "rename", Path: oldname, Err: err}
+	}
+	return nil
+}
+
+func (f *webdavFilesystem) Hardlink(oldname, newname string) error {
+	return &os.PathError{Op: "link", Path: newname, Err: errWebdavNotSupported}
+}
+
+func (f *webdavFilesystem) Stat(name string) (FileInfo, error) {
+	info, err := f.stat("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (f *webdavFilesystem) SymlinksSupported() bool {
+	return false
+}
+
+// Walk lists each directory with one request, rather than also requesting
+// each entry like the walkFilesystem, and walks them in lexical order.
+func (f *webdavFilesystem) Walk(root string, walkFn WalkFunc) error {
+	root, err := Canonicalize(root)
+	if err != nil {
+		return err
+	}
+	info, err := f.stat("lstat", root)
+	if err != nil {
+		return walkFn(root, nil, err)
+	}
+	if err := f.walk(root, info, walkFn); err != nil && err != SkipDir {
+		return err
+	}
+	return nil
+}
+
+func (f *webdavFilesystem) walk(path string, info *webdavFileInfo, walkFn WalkFunc) error {
+	if err := walkFn(path, info, nil); err != nil {
+		if info.IsDir() && err == SkipDir {
+			return nil
+		}
+		// SkipDir on a file skips the rest of its directory.
+		return err
+	}
+	if !info.IsDir() {
+		return nil
+	}
+
+	children, err := f.readDir(path)
+	if err != nil {
+		return walkFn(path, info, err)
+	}
+	for _, child := range children {
+		if err := f.walk(filepath.Join(path, child.name), child, walkFn); err != nil {
+			if !child.IsDir() && err == SkipDir {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *webdavFilesystem) Watch(path string, ignore Matcher, ctx context.Context, ignorePerms bool) (<-chan Event, <-chan error, error) {
+	return nil, nil, ErrWatchNotSupported
+}
+
+func (f *webdavFilesystem) Hide(name string) error {
+	return nil
+}
+
+func (f *webdavFilesystem) Unhide(name string) error {
+	return nil
+}
+
+// Glob supports patterns in the last path component only.
+func (f *webdavFilesystem) Glob(pattern string) ([]string, error) {
+	dir, base := filepath.Split(pattern)
+	names, err := f.DirNames(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var matches []string
+	for _, name := range names {
+		ok, err := filepath.Match(base, name)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matches = append(matches, filepath.Join(dir, name))
+		}
+	}
+	return matches, nil
+}
+
+func (f *webdavFilesystem) Roots() ([]string, error) {
+	return []string{"/"}, nil
+}
+
+// Usage isn't asked for, as few servers tell.
+func (f *webdavFilesystem) Usage(name string) (Usage, error) {
+	return Usage{}, errWebdavNotSupported
+}
+
+func (f *webdavFilesystem) Type() FilesystemType {
+	return FilesystemTypeWebDAV
+}
+
+func (f *webdavFilesystem) URI() string {
+	return f.uri
+}
+
+func (f *webdavFilesystem) SameFile(fi1, fi2 FileInfo) bool {
+	i1, ok1 := fi1.(*webdavFileInfo)
+	i2, ok2 := fi2.(*webdavFileInfo)
+	return ok1 && ok2 && i1.path == i2.path
+}
+
+func (f *webdavFilesystem) CloneRange(src File, srcOffset int64, dst File, dstOffset, length int64) error {
+	return ErrCloneNotSupported
+}
+
+func (f *webdavFilesystem) DedupeRange(src File, srcOffset int64, dst File, dstOffset, length int64) error {
+	return ErrCloneNotSupported
+}
+
+func (f *webdavFilesystem) GetXattr(name string) ([]protocol.Xattr, error) {
+	return nil, ErrXattrsNotSupported
+}
+
+func (f *webdavFilesystem) SetXattr(name string, xattrs []protocol.Xattr) error {
+	return ErrXattrsNotSupported
+}
+
+func (f *webdavFilesystem) GetPlatformData(name string) (protocol.PlatformData, error) {
+	return protocol.PlatformData{}, ErrPlatformDataNotSupported
+}
+
+func (f *webdavFilesystem) SetPlatformData(name string, data protocol.PlatformData) error {
+	return ErrPlatformDataNotSupported
+}
+
+// webdavRange is a written range of a partially staged file.
+type webdavRange struct {
+	start, end int64
+}
+
+// webdavFile is a file open for reading, or a staged file open for
+// writing.
+type webdavFile struct {
+	fs     *webdavFilesystem
+	name   string
+	path   string
+	offset int64
+
+	// Files open for reading.
+	size    int64
+	modTime time.Time
+
+	// Files open for writing. When partial, the staged file holds only the
+	// written ranges, at their offsets, and the rest is as on the server.
+	staged     *os.File
+	dirty      bool
+	partial    bool
+	remoteSize int64
+	written    []webdavRange // sorted and not overlapping
+}
+
+func (f *webdavFile) Name() string {
+	return f.name
+}
+
+func (f *webdavFile) Read(p []byte) (int, error) {
+	n, err := f.ReadAt(p, f.offset)
+	f.offset += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+func (f *webdavFile) ReadAt(p []byte, off int64) (int, error) {
+	if f.staged != nil && !f.partial {
+		return f.staged.ReadAt(p, off)
+	}
+
+	size := f.currentSize()
+	if off >= size {
+		return 0, io.EOF
+	}
+	length := int64(len(p))
+	if rest := size - off; length > rest {
+		length = rest
+	}
+
+	// What's on the server, and then what was written over it.
+	remote := length
+	if f.staged != nil {
+		if remote = f.remoteSize - off; remote > length {
+			remote = length
+		}
+	}
+	for i := range p[:length] {
+		p[i] = 0
+	}
+	if remote > 0 {
+		body, err := f.fs.client.get(f.path, off, remote)
+		if err != nil {
+			return 0, &os.PathError{Op: "read", Path: f.name, Err: err}
+		}
+		_, err = io.ReadFull(body, p[:remote])
+		body.Close()
+		if err != nil {
+			return 0, err
+		}
+	}
+	for _, r := range f.written {
+		start, end := r.start, r.end
+		if start < off {
+			start = off
+		}
+		if end > off+length {
+			end = off + length
+		}
+		if start >= end {
+			continue
+		}
+		if _, err := f.staged.ReadAt(p[start-off:end-off], start); err != nil {
+			return 0, err
+		}
+	}
+
+	if length < int64(len(p)) {
+		return int(length), io.EOF
+	}
+	return int(length), nil
+}
+
+func (f *webdavFile) Seek(offset int64, whence int) (int64, error) {
+	if f.staged != nil && !f.partial {
+		return f.staged.Seek(offset, whence)
+	}
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.currentSize()
+	}
+	if offset < 0 {
+		return 0, &os.PathError{Op: "seek", Path: f.name, Err: os.ErrInvalid}
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *webdavFile) Write(p []byte) (int, error) {
+	if f.staged != nil && !f.partial {
+		f.dirty = true
+		return f.staged.Write(p)
+	}
+	n, err := f.WriteAt(p, f.offset)
+	f.offset += int64(n)
+	return n, err
+}
+
+func (f *webdavFile) WriteAt(p []byte, off int64) (int, error) {
+	if f.staged == nil {
+		return 0, &os.PathError{Op: "write", Path: f.name, Err: errWebdavReadOnlyFile}
+	}
+	f.dirty = true
+	n, err := f.staged.WriteAt(p, off)
+	if f.partial && n > 0 {
+		f.addWritten(off, off+int64(n))
+	}
+	return n, err
+}
+
+// addWritten adds the range to the written ones, merging it with those it
+// overlaps or touches.
+func (f *webdavFile) addWritten(start, end int64) {
+	merged := make([]webdavRange, 0, len(f.written)+1)
+	for _, r := range f.written {
+		if r.end < start || r.start > end {
+			merged = append(merged, r)
+			continue
+		}
+		if r.start < start {
+			start = r.start
+		}
+		if r.end > end {
+			end = r.end
+		}
+	}
+	merged = append(merged, webdavRange{start, end})
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].start < merged[j].start
+	})
+	f.written = merged
+}
+
+func (f *webdavFile) Truncate(size int64) error {
+	if f.staged == nil {
+		return &os.PathError{Op: "truncate", Path: f.name, Err: errWebdavReadOnlyFile}
+	}
+	if f.partial {
+		// Parts can't be removed from the server, so the whole file is
+		// uploaded instead.
+		if err := f.stageWhole(); err != nil {
+			return &os.PathError{Op: "truncate", Path: f.name, Err: err}
+		}
+	}
+	f.dirty = true
+	return f.staged.Truncate(size)
+}
+
+// stageWhole replaces the partially staged file with one holding all of
+// it.
+func (f *webdavFile) stageWhole() error {
+	whole, err := ioutil.TempFile(f.fs.staging, "syncthing-webdav-")
+	if err != nil {
+		return err
+	}
+	size := f.currentSize()
+	if _, err := io.Copy(whole, io.NewSectionReader(f, 0, size)); err != nil {
+		whole.Close()
+		os.Remove(whole.Name())
+		return err
+	}
+	f.discard()
+	f.staged = whole
+	f.partial = false
+	f.written = nil
+	_, err = f.staged.Seek(f.offset, io.SeekStart)
+	return err
+}
+
+func (f *webdavFile) currentSize() int64 {
+	if f.staged == nil {
+		return f.size
+	}
+	size := f.remoteSize
+	if n := len(f.written); n > 0 && f.written[n-1].end > size {
+		size = f.written[n-1].end
+	}
+	return size
+}
+
+func (f *webdavFile) Stat() (FileInfo, error) {
+	info := &webdavFileInfo{path: f.path, name: filepath.Base(f.name), size: f.currentSize(), modTime: f.modTime}
+	if f.staged != nil && !f.partial {
+		staged, err := f.staged.Stat()
+		if err != nil {
+			return nil, err
+		}
+		info.size = staged.Size()
+		info.modTime = staged.ModTime()
+	}
+	return info, nil
+}
+
+// Sync uploads the staged file, or the written parts of it, if it changed.
+func (f *webdavFile) Sync() error {
+	if f.staged == nil || !f.dirty {
+		return nil
+	}
+	if err := f.upload(); err != nil {
+		return &os.PathError{Op: "sync", Path: f.name, Err: err}
+	}
+	f.dirty = false
+	return nil
+}
+
+func (f *webdavFile) upload() error {
+	if !f.partial {
+		info, err := f.staged.Stat()
+		if err != nil {
+			return err
+		}
+		return f.fs.client.put(f.path, io.NewSectionReader(f.staged, 0, info.Size()), info.Size())
+	}
+
+	mode := f.fs.partialMode()
+	for len(f.written) > 0 {
+		r := f.written[0]
+		if err := f.fs.client.putRange(mode, f.path, r.start, io.NewSectionReader(f.staged, r.start, r.end-r.start), r.end-r.start); err != nil {
+			return err
+		}
+		if r.end > f.remoteSize {
+			f.remoteSize = r.end
+		}
+		f.written = f.written[1:]
+	}
+	return nil
+}
+
+func (f *webdavFile) Close() error {
+	if f.staged == nil {
+		return nil
+	}
+	err := f.Sync()
+	f.discard()
+	return err
+}
+
+func (f *webdavFile) discard() {
+	f.staged.Close()
+	os.Remove(f.staged.Name())
+}
+
+func (f *webdavFile) download(dst *os.File) error {
+	body, err := f.fs.client.get(f.path, 0, -1)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	if _, err := io.Copy(dst, body); err != nil {
+		return err
+	}
+	_, err = dst.Seek(0, io.SeekStart)
+	return err
+}
+
+// webdavFileInfo is the stat result.
+type webdavFileInfo struct {
+	path    string
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+}
+
+func newWebdavFileInfo(name string, entry webdavEntry) *webdavFileInfo {
+	info := &webdavFileInfo{path: entry.path, name: name, modTime: entry.modTime, dir: entry.dir}
+	if !entry.dir {
+		info.size = entry.size
+	}
+	return info
+}
+
+func (i *webdavFileInfo) Name() string {
+	return i.name
+}
+
+func (i *webdavFileInfo) Mode() FileMode {
+	if i.dir {
+		return FileMode(os.ModeDir) | 0755
+	}
+	return 0644
+}
+
+func (i *webdavFileInfo) Size() int64 {
+	return i.size
+}
+
+func (i *webdavFileInfo) ModTime() time.Time {
+	return i.modTime
+}
+
+func (i *webdavFileInfo) IsDir() bool {
+	return i.dir
+}
+
+func (i *webdavFileInfo) IsRegular() bool {
+	return !i.dir
+}
+
+func (i *webdavFileInfo) IsSymlink() bool {
+	return false
+}
+
+func (i *webdavFileInfo) Owner() int {
+	return -1
+}
+
+func (i *webdavFileInfo) Group() int {
+	return -1
+}
+
+func (i *webdavFileInfo) Inode() uint64 {
+	return 0
+}
@@ -0,0 +1,265 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/webdav"
+)
+
+// fakeWebdav is a WebDAV server keeping files in memory, which can act
+// like sabre/dav in writing parts of files with PATCH requests.
+type fakeWebdav struct {
+	handler *webdav.Handler
+	patch   bool
+
+	mut      sync.Mutex
+	requests map[string]int
+}
+
+func newFakeWebdav(patch bool) *fakeWebdav {
+	return &fakeWebdav{
+		handler: &webdav.Handler{
+			Prefix:     "/dav",
+			FileSystem: webdav.NewMemFS(),
+			LockSystem: webdav.NewMemLS(),
+		},
+		patch:    patch,
+		requests: make(map[string]int),
+	}
+}
+
+func (s *fakeWebdav) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mut.Lock()
+	s.requests[r.Method]++
+	s.mut.Unlock()
+
+	if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "pass" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	switch {
+	case r.Method == http.MethodOptions && s.patch:
+		w.Header().Set("DAV", "1, 3, sabredav-partialupdate")
+		return
+	case r.Method == "PATCH" && s.patch:
+		s.servePatch(w, r)
+		return
+	case r.Method == http.MethodPut && r.Header.Get("Content-Range") != "":
+		// Like many servers, the handler would replace the whole file.
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+	s.handler.ServeHTTP(w, r)
+}
+
+func (s *fakeWebdav) servePatch(w http.ResponseWriter, r *http.Request) {
+	var start, end int64
+	if _, err := fmt.Sscanf(r.Header.Get("X-Update-Range"), "bytes=%d-%d", &start, &end); err != nil || r.Header.Get("Content-Type") != "application/x-sabredav-partialupdate" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	fd, err := s.handler.FileSystem.OpenFile(context.Background(), strings.TrimPrefix(r.URL.Path, "/dav"), os.O_RDWR, 0)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	defer fd.Close()
+	if _, err := fd.Seek(start, io.SeekStart); err != nil {
+		w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+	if n, err := io.Copy(fd, r.Body); err != nil || n != end-start+1 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *fakeWebdav) count(method string) int {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.requests[method]
+}
+
+func setupWebdavFilesystem(t *testing.T, patch bool) (*webdavFilesystem, *fakeWebdav, func()) {
+	t.Helper()
+	staging, err := ioutil.TempDir("", "syncthing-webdav-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newFakeWebdav(patch)
+	if err := server.handler.FileSystem.Mkdir(context.Background(), "/folder", 0755); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(server)
+	fs, err := newWebdavFilesystem("http://user:pass@" + srv.Listener.Addr().String() + "/dav/folder/?staging=" + url.QueryEscape(staging))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fs, server, func() {
+		srv.Close()
+		os.RemoveAll(staging)
+	}
+}
+
+func TestWebdavFilesystemFiles(t *testing.T) {
+	fs, server, cleanup := setupWebdavFilesystem(t, false)
+	defer cleanup()
+
+	if strings.Contains(fs.URI(), "pass") {
+		t.Error("Credentials should be removed from", fs.URI())
+	}
+
+	if err := fs.MkdirAll(filepath.Join("a", "b"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Mkdir("a", 0755); !os.IsExist(err) {
+		t.Error("Expected the directory to exist, got", err)
+	}
+	if err := fs.Mkdir(filepath.Join("x", "y"), 0755); !os.IsNotExist(err) {
+		t.Error("Expected the parent to not exist, got", err)
+	}
+	writeRemoteFile(t, fs, filepath.Join("a", "b", "file"), "hello")
+
+	// Without partial updates, files open for writing start with the
+	// current contents and are uploaded whole.
+	fd, err := fs.OpenFile(filepath.Join("a", "b", "file"), OptReadWrite, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd.WriteAt([]byte(" world"), 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := fd.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := readRemoteFile(t, fs, filepath.Join("a", "b", "file")); got != "hello world" {
+		t.Errorf("Got contents %q", got)
+	}
+	if server.count("PATCH") != 0 {
+		t.Error("No partial updates should have been made")
+	}
+
+	info, err := fs.Lstat(filepath.Join("a", "b", "file"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.IsRegular() || info.Size() != 11 || info.Name() != "file" {
+		t.Errorf("Unexpected file info %+v", info)
+	}
+	if _, err := fs.Lstat("nonexistent"); !os.IsNotExist(err) {
+		t.Error("Expected not to exist, got", err)
+	}
+
+	var walked []string
+	err = fs.Walk(".", func(path string, info FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		walked = append(walked, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{".", "a", filepath.Join("a", "b"), filepath.Join("a", "b", "file")}
+	if !reflect.DeepEqual(walked, expected) {
+		t.Errorf("Walked %v, expected %v", walked, expected)
+	}
+
+	if err := fs.Rename("a", "c"); err != nil {
+		t.Fatal(err)
+	}
+	if names, err := fs.DirNames("."); err != nil || !reflect.DeepEqual(names, []string{"c"}) {
+		t.Errorf("Got names %v, %v", names, err)
+	}
+	if err := fs.Remove("c"); err == nil {
+		t.Error("Removing a directory that isn't empty should fail")
+	}
+	if err := fs.RemoveAll("c"); err != nil {
+		t.Fatal(err)
+	}
+	if names, err := fs.DirNames("."); err != nil || len(names) != 0 {
+		t.Errorf("Got names %v, %v", names, err)
+	}
+}
+
+func TestWebdavFilesystemPartial(t *testing.T) {
+	fs, server, cleanup := setupWebdavFilesystem(t, true)
+	defer cleanup()
+
+	writeRemoteFile(t, fs, "file", "0123456789")
+	gets := server.count(http.MethodGet)
+
+	// Only the written parts are uploaded, and reading sees them over
+	// what's on the server.
+	fd, err := fs.OpenFile("file", OptReadWrite, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd.WriteAt([]byte("ab"), 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd.WriteAt([]byte("cd"), 4); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd.WriteAt([]byte("xyz"), 11); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 20)
+	if n, err := fd.ReadAt(buf, 0); n != 14 || err != io.EOF || string(buf[:n]) != "01abcd6789\x00xyz" {
+		t.Errorf("Read %q, %v", buf[:n], err)
+	}
+	if err := fd.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := readRemoteFile(t, fs, "file"); got != "01abcd6789\x00xyz" {
+		t.Errorf("Got contents %q", got)
+	}
+	// The adjacent writes are merged.
+	if n := server.count("PATCH"); n != 2 {
+		t.Errorf("Expected two partial updates, got %d", n)
+	}
+	if server.count(http.MethodGet) != gets+2 {
+		t.Error("The file should not have been downloaded when opened")
+	}
+
+	// Truncating uploads the whole file.
+	fd, err = fs.OpenFile("file", OptReadWrite, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd.Write([]byte("AB")); err != nil {
+		t.Fatal(err)
+	}
+	if err := fd.Truncate(4); err != nil {
+		t.Fatal(err)
+	}
+	if err := fd.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := readRemoteFile(t, fs, "file"); got != "ABab" {
+		t.Errorf("Got contents %q", got)
+	}
+	if n := server.count("PATCH"); n != 2 {
+		t.Errorf("Expected no more partial updates, got %d", n)
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package ignore
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A condition restricts a pattern to files of a certain size or age, as
+// given by a prefix like (?size>1G) or (?age<30d). Patterns with conditions
+// never match directories.
+type condition struct {
+	text  string // as written, without the parentheses, e.g. "?size>1G"
+	age   bool   // on the age rather than the size
+	less  bool   // less than rather than greater than the value
+	value int64  // bytes, or nanoseconds of age
+}
+
+var sizeUnits = map[string]int64{
+	"":  1,
+	"b": 1,
+	"k": 1 << 10,
+	"m": 1 << 20,
+	"g": 1 << 30,
+	"t": 1 << 40,
+}
+
+var ageUnits = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+	"y": 365 * 24 * time.Hour,
+}
+
+// parseCondition parses a condition prefix at the start of the line,
+// returning the condition and the rest of the line. It returns false if
+// the line doesn't start with a condition.
+func parseCondition(line string) (condition, string, bool, error) {
+	var c condition
+	switch {
+	case strings.HasPrefix(line, "(?size"):
+	case strings.HasPrefix(line, "(?age"):
+		c.age = true
+	default:
+		return c, line, false, nil
+	}
+	end := strings.IndexByte(line, ')')
+	if end < 0 {
+		return c, line, true, fmt.Errorf("unterminated condition in %q", line)
+	}
+	c.text = line[1:end]
+	expr := strings.TrimPrefix(strings.TrimPrefix(c.text, "?size"), "?age")
+
+	switch {
+	case strings.HasPrefix(expr, "<"):
+		c.less = true
+	case strings.HasPrefix(expr, ">"):
+	default:
+		return c, line, true, fmt.Errorf("condition %q needs < or >", c.text)
+	}
+	expr = strings.ToLower(strings.TrimSpace(expr[1:]))
+
+	digits := strings.IndexFunc(expr, func(r rune) bool { return r < '0' || r > '9' })
+	if digits < 0 {
+		digits = len(expr)
+	}
+	n, err := strconv.ParseInt(expr[:digits], 10, 64)
+	if err != nil {
+		return c, line, true, fmt.Errorf("invalid value in condition %q", c.text)
+	}
+	unit := strings.TrimSuffix(expr[digits:], "ib")
+	if c.age {
+		d, ok := ageUnits[unit]
+		if !ok {
+			return c, line, true, fmt.Errorf("unknown unit in condition %q, want s, m, h, d, w or y", c.text)
+		}
+		c.value = n * int64(d)
+	} else {
+		mult, ok := sizeUnits[strings.TrimSuffix(unit, "b")]
+		if !ok {
+			return c, line, true, fmt.Errorf("unknown unit in condition %q, want k, M, G or T", c.text)
+		}
+		c.value = n * mult
+	}
+
+	return c, line[end+1:], true, nil
+}
+
+func (c condition) String() string {
+	return "(" + c.text + ")"
+}
+
+// holds returns whether a file of the given size and modification time
+// meets the condition.
+func (c condition) holds(size int64, modTime time.Time) bool {
+	v := size
+	if c.age {
+		v = int64(clock.Now().Sub(modTime))
+	}
+	if c.less {
+		return v < c.value
+	}
+	return v > c.value
+}
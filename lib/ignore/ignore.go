@@ -44,10 +44,16 @@ type Pattern struct {
 	pattern string
 	match   glob.Glob
 	result  Result
+	source  string // file, preset or "shared" the pattern is from
+	line    int    // line in the source, starting at one
+	conds   []condition
 }
 
 func (p Pattern) String() string {
 	ret := p.pattern
+	for i := len(p.conds) - 1; i >= 0; i-- {
+		ret = p.conds[i].String() + ret
+	}
 	if p.result&resultInclude != resultInclude {
 		ret = "!" + ret
 	}
@@ -60,6 +66,18 @@ func (p Pattern) String() string {
 	return ret
 }
 
+// Source returns where the pattern is from: the ignore file it is in, which
+// may be an included one, "preset:" and the name of a preset, or "shared"
+// for patterns shared by another device.
+func (p Pattern) Source() string {
+	return p.source
+}
+
+// Line returns the line of the source the pattern is on, starting at one.
+func (p Pattern) Line() int {
+	return p.line
+}
+
 func (p Pattern) allowsSkippingIgnoredDirs() bool {
 	if p.result.IsIgnored() {
 		return true
@@ -108,14 +126,30 @@ type ChangeDetector interface {
 type Matcher struct {
 	fs              fs.Filesystem
 	lines           []string  // exact lines read from .stignore
-	patterns        []Pattern // patterns including those from included files
+	patterns        []Pattern // patterns including those from included files, followed by the presets and shared ones
+	presets         []string  // names of the presets that apply
+	shared          []string  // lines shared by another device
+	subtrees        []string  // everything else is ignored, when set
+	hasConds        bool      // some patterns only match files of a certain size or age
+	sharedChanged   bool
 	withCache       bool
 	matches         *cache
 	curHash         string
 	stop            chan struct{}
 	changeDetector  ChangeDetector
 	skipIgnoredDirs bool
+	foldCase        bool
 	mut             sync.Mutex
+	countMatches    bool
+	stats           []PatternStats
+}
+
+// PatternStats tells how many lookups a pattern matched, and for how many
+// of those it decided the result by being the first to match.
+type PatternStats struct {
+	Pattern Pattern
+	Matched int
+	Decided int
 }
 
 // An Option can be passed to New()
@@ -136,6 +170,36 @@ func WithChangeDetector(cd ChangeDetector) Option {
 	}
 }
 
+// WithCaseInsensitive makes patterns match regardless of case, as if they
+// all had the (?i) prefix. Patterns can opt out using the (?-i) prefix. The
+// default is case sensitive matching, except on Windows and macOS.
+func WithCaseInsensitive(v bool) Option {
+	return func(m *Matcher) {
+		m.foldCase = v
+	}
+}
+
+// WithSubtrees ignores everything that isn't in one of the given paths,
+// or needed to reach one, regardless of the patterns. The default is to
+// leave all paths to the patterns.
+func WithSubtrees(paths []string) Option {
+	return func(m *Matcher) {
+		m.subtrees = make([]string, len(paths))
+		for i, path := range paths {
+			m.subtrees[i] = filepath.Clean(filepath.FromSlash(path))
+		}
+	}
+}
+
+// WithMatchStats enables counting the matches of each pattern, as returned
+// by Stats. Every pattern is then tried on every lookup, which makes
+// matching slower. Cached lookups aren't counted. The default is disabled.
+func WithMatchStats(v bool) Option {
+	return func(m *Matcher) {
+		m.countMatches = v
+	}
+}
+
 func New(fs fs.Filesystem, opts ...Option) *Matcher {
 	m := &Matcher{
 		fs:              fs,
@@ -159,7 +223,7 @@ func (m *Matcher) Load(file string) error {
 	m.mut.Lock()
 	defer m.mut.Unlock()
 
-	if m.changeDetector.Seen(m.fs, file) && !m.changeDetector.Changed() {
+	if m.changeDetector.Seen(m.fs, file) && !m.changeDetector.Changed() && !m.sharedChanged {
 		return nil
 	}
 
@@ -188,12 +252,29 @@ func (m *Matcher) Parse(r io.Reader, file string) error {
 }
 
 func (m *Matcher) parseLocked(r io.Reader, file string) error {
-	lines, patterns, err := parseIgnoreFile(m.fs, r, file, m.changeDetector, make(map[string]struct{}))
+	defResult := defaultResult
+	if m.foldCase {
+		defResult |= resultFoldCase
+	}
+	lines, local, err := parseIgnoreFile(m.fs, r, file, m.changeDetector, make(map[string]struct{}), defResult)
 	// Error is saved and returned at the end. We process the patterns
 	// (possibly blank) anyway.
 
 	m.lines = lines
 
+	// Local patterns come first, so that they override the presets, which
+	// in turn override the shared ones. Both were checked beforehand.
+	patterns := local
+	for _, name := range m.presets {
+		_, preset, _ := parseIgnoreFile(m.fs, strings.NewReader(strings.Join(presets[name], "\n")), "preset:"+name, m.changeDetector, make(map[string]struct{}), defResult)
+		patterns = append(patterns, preset...)
+	}
+	if len(m.shared) > 0 {
+		_, shared, _ := parseIgnoreFile(m.fs, strings.NewReader(strings.Join(m.shared, "\n")), "shared", m.changeDetector, make(map[string]struct{}), defResult)
+		patterns = append(patterns, shared...)
+	}
+	m.sharedChanged = false
+
 	newHash := hashPatterns(patterns)
 	if newHash == m.curHash {
 		// We've already loaded exactly these patterns.
@@ -219,9 +300,22 @@ func (m *Matcher) parseLocked(r io.Reader, file string) error {
 
 	m.curHash = newHash
 	m.patterns = patterns
+	m.hasConds = false
+	for _, p := range patterns {
+		if len(p.conds) > 0 {
+			m.hasConds = true
+			break
+		}
+	}
 	if m.withCache {
 		m.matches = newCache(patterns)
 	}
+	if m.countMatches {
+		m.stats = make([]PatternStats, len(patterns))
+		for i, p := range patterns {
+			m.stats[i].Pattern = p
+		}
+	}
 
 	return err
 }
@@ -234,6 +328,10 @@ func (m *Matcher) Match(file string) (result Result) {
 	m.mut.Lock()
 	defer m.mut.Unlock()
 
+	if len(m.subtrees) > 0 && !fs.InSubtrees(filepath.FromSlash(file), m.subtrees) {
+		return resultInclude
+	}
+
 	if len(m.patterns) == 0 {
 		return resultNotMatched
 	}
@@ -251,26 +349,156 @@ func (m *Matcher) Match(file string) (result Result) {
 		}()
 	}
 
-	// Check all the patterns for a match.
+	if i := m.matchingPatternLocked(file, nil); i >= 0 {
+		return m.patterns[i].result
+	}
+
+	// Default to not matching.
+	return resultNotMatched
+}
+
+// MatchFile is like Match, for a file of the given size and modification
+// time. Unlike Match, which skips them, it also considers the patterns
+// that only match files of a certain size or age.
+func (m *Matcher) MatchFile(file string, size int64, modTime time.Time) Result {
+	m.mut.Lock()
+	hasConds := m.hasConds
+	m.mut.Unlock()
+	if !hasConds || file == "." {
+		return m.Match(file)
+	}
+
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	if len(m.subtrees) > 0 && !fs.InSubtrees(filepath.FromSlash(file), m.subtrees) {
+		return resultInclude
+	}
+
+	holds := func(c condition) bool {
+		return c.holds(size, modTime)
+	}
+	if i := m.matchingPatternLocked(file, holds); i >= 0 {
+		return m.patterns[i].result
+	}
+	return resultNotMatched
+}
+
+// Explain is like Match, but also returns the pattern that decided the
+// result, which is nil if none did. It doesn't use the cache.
+func (m *Matcher) Explain(file string) (Result, *Pattern) {
+	if file == "." {
+		return resultNotMatched, nil
+	}
+
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	if len(m.subtrees) > 0 && !fs.InSubtrees(filepath.FromSlash(file), m.subtrees) {
+		return resultInclude, nil
+	}
+
+	if i := m.matchingPatternLocked(file, nil); i >= 0 {
+		pattern := m.patterns[i]
+		return pattern.result, &pattern
+	}
+	return resultNotMatched, nil
+}
+
+// matchingPatternLocked returns the index of the first pattern matching
+// the file, or -1 if none does. Patterns with conditions are skipped unless
+// holds tells whether the file meets a condition.
+func (m *Matcher) matchingPatternLocked(file string, holds func(condition) bool) int {
 	file = filepath.ToSlash(file)
 	var lowercaseFile string
-	for _, pattern := range m.patterns {
+	matches := func(pattern Pattern) bool {
+		if len(pattern.conds) > 0 {
+			if holds == nil {
+				return false
+			}
+			for _, c := range pattern.conds {
+				if !holds(c) {
+					return false
+				}
+			}
+		}
 		if pattern.result.IsCaseFolded() {
 			if lowercaseFile == "" {
 				lowercaseFile = strings.ToLower(file)
 			}
-			if pattern.match.Match(lowercaseFile) {
-				return pattern.result
-			}
-		} else {
-			if pattern.match.Match(file) {
-				return pattern.result
+			return pattern.match.Match(lowercaseFile)
+		}
+		return pattern.match.Match(file)
+	}
+
+	if m.stats == nil {
+		for i, pattern := range m.patterns {
+			if matches(pattern) {
+				return i
 			}
 		}
+		return -1
 	}
 
-	// Default to not matching.
-	return resultNotMatched
+	first := -1
+	for i, pattern := range m.patterns {
+		if !matches(pattern) {
+			continue
+		}
+		m.stats[i].Matched++
+		if first < 0 {
+			m.stats[i].Decided++
+			first = i
+		}
+	}
+	return first
+}
+
+// Stats returns the match counts of the patterns, in order, since they were
+// last changed. It returns nil unless enabled using WithMatchStats.
+func (m *Matcher) Stats() []PatternStats {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	if m.stats == nil {
+		return nil
+	}
+	stats := make([]PatternStats, len(m.stats))
+	copy(stats, m.stats)
+	return stats
+}
+
+// SetShared sets the lines of ignore patterns shared by another device,
+// which apply after those in .stignore, so that local patterns take
+// precedence. Includes are not followed, as the files they refer to are
+// on the other device. The new lines take effect on the next Load.
+func (m *Matcher) SetShared(lines []string) error {
+	var shared []string
+	for _, line := range lines {
+		if !strings.HasPrefix(strings.TrimSpace(line), "#include") {
+			shared = append(shared, line)
+		}
+	}
+
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	defResult := defaultResult
+	if m.foldCase {
+		defResult |= resultFoldCase
+	}
+	if _, _, err := parseIgnoreFile(m.fs, strings.NewReader(strings.Join(shared, "\n")), "", m.changeDetector, make(map[string]struct{}), defResult); err != nil {
+		return err
+	}
+	m.shared = shared
+	m.sharedChanged = true
+	return nil
+}
+
+// Shared returns the lines of ignore patterns shared by another device.
+func (m *Matcher) Shared() []string {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	return m.shared
 }
 
 // Lines return a list of the unprocessed lines in .stignore at last load
@@ -368,7 +596,7 @@ func loadIgnoreFile(fs fs.Filesystem, file string, cd ChangeDetector) (fs.File,
 	return fd, info, err
 }
 
-func loadParseIncludeFile(filesystem fs.Filesystem, file string, cd ChangeDetector, linesSeen map[string]struct{}) ([]Pattern, error) {
+func loadParseIncludeFile(filesystem fs.Filesystem, file string, cd ChangeDetector, linesSeen map[string]struct{}, defResult Result) ([]Pattern, error) {
 	// Allow escaping the folders filesystem.
 	// TODO: Deprecate, somehow?
 	if filesystem.Type() == fs.FilesystemTypeBasic {
@@ -392,13 +620,13 @@ func loadParseIncludeFile(filesystem fs.Filesystem, file string, cd ChangeDetect
 
 	cd.Remember(filesystem, file, info.ModTime())
 
-	_, patterns, err := parseIgnoreFile(filesystem, fd, file, cd, linesSeen)
+	_, patterns, err := parseIgnoreFile(filesystem, fd, file, cd, linesSeen, defResult)
 	return patterns, err
 }
 
-func parseLine(line string) ([]Pattern, error) {
+func parseLine(line string, defResult Result) ([]Pattern, error) {
 	pattern := Pattern{
-		result: defaultResult,
+		result: defResult,
 	}
 
 	// Allow prefixes to be specified in any order, but only once.
@@ -413,10 +641,21 @@ func parseLine(line string) ([]Pattern, error) {
 			seenPrefix[1] = true
 			pattern.result |= resultFoldCase
 			line = line[4:]
+		} else if strings.HasPrefix(line, "(?-i)") && !seenPrefix[1] {
+			// Case sensitive, regardless of the default
+			seenPrefix[1] = true
+			pattern.result &^= resultFoldCase
+			line = line[5:]
 		} else if strings.HasPrefix(line, "(?d)") && !seenPrefix[2] {
 			seenPrefix[2] = true
 			pattern.result |= resultDeletable
 			line = line[4:]
+		} else if c, rest, ok, err := parseCondition(line); ok {
+			if err != nil {
+				return nil, err
+			}
+			pattern.conds = append(pattern.conds, c)
+			line = rest
 		} else {
 			break
 		}
@@ -470,15 +709,20 @@ func parseLine(line string) ([]Pattern, error) {
 	return patterns, nil
 }
 
-func parseIgnoreFile(fs fs.Filesystem, fd io.Reader, currentFile string, cd ChangeDetector, linesSeen map[string]struct{}) ([]string, []Pattern, error) {
+func parseIgnoreFile(fs fs.Filesystem, fd io.Reader, currentFile string, cd ChangeDetector, linesSeen map[string]struct{}, defResult Result) ([]string, []Pattern, error) {
 	var lines []string
 	var patterns []Pattern
 
+	lineNo := 0
 	addPattern := func(line string) error {
-		newPatterns, err := parseLine(line)
+		newPatterns, err := parseLine(line, defResult)
 		if err != nil {
 			return errors.Wrapf(err, "invalid pattern %q in ignore file", line)
 		}
+		for i := range newPatterns {
+			newPatterns[i].source = currentFile
+			newPatterns[i].line = lineNo
+		}
 		patterns = append(patterns, newPatterns...)
 		return nil
 	}
@@ -486,6 +730,7 @@ func parseIgnoreFile(fs fs.Filesystem, fd io.Reader, currentFile string, cd Chan
 	scanner := bufio.NewScanner(fd)
 	var err error
 	for scanner.Scan() {
+		lineNo++
 		line := strings.TrimSpace(scanner.Text())
 		lines = append(lines, line)
 		if _, ok := linesSeen[line]; ok {
@@ -516,7 +761,7 @@ func parseIgnoreFile(fs fs.Filesystem, fd io.Reader, currentFile string, cd Chan
 
 			includeFile := filepath.Join(filepath.Dir(currentFile), includeRel)
 			var includePatterns []Pattern
-			if includePatterns, err = loadParseIncludeFile(fs, includeFile, cd, linesSeen); err == nil {
+			if includePatterns, err = loadParseIncludeFile(fs, includeFile, cd, linesSeen, defResult); err == nil {
 				patterns = append(patterns, includePatterns...)
 			} else {
 				// Wrap the error, as if the include does not exist, we get a
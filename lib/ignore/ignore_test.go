@@ -14,6 +14,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 	"time"
 
@@ -227,6 +228,243 @@ func TestCaseSensitivity(t *testing.T) {
 	}
 }
 
+func TestCaseInsensitiveOption(t *testing.T) {
+	ign := New(fs.NewFilesystem(fs.FilesystemTypeBasic, "."), WithCaseInsensitive(true))
+	stignore := `
+	*.TMP
+	(?-i)Exact
+	`
+	if err := ign.Parse(bytes.NewBufferString(stignore), ".stignore"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []string{"a.tmp", "b.TMP", "dir/c.Tmp", "Exact", "dir/Exact"} {
+		if !ign.Match(tc).IsIgnored() {
+			t.Errorf("Incorrect match for %q: should be matched", tc)
+		}
+	}
+	for _, tc := range []string{"a.txt", "exact", "EXACT", "dir/exact"} {
+		if ign.Match(tc).IsIgnored() {
+			t.Errorf("Incorrect match for %q: should not be matched", tc)
+		}
+	}
+}
+
+func TestSharedPatterns(t *testing.T) {
+	ign := New(fs.NewFilesystem(fs.FilesystemTypeBasic, "."))
+	if err := ign.SetShared([]string{"*.tmp", "#include other", "cache/"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ign.SetShared([]string{"[bad"}); err == nil {
+		t.Error("expected an error for an invalid shared pattern")
+	}
+	if shared := ign.Shared(); len(shared) != 2 {
+		t.Errorf("expected the include to be dropped and the invalid lines not set, got %v", shared)
+	}
+
+	stignore := `
+	!keep.tmp
+	`
+	if err := ign.Parse(bytes.NewBufferString(stignore), ".stignore"); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []string{"a.tmp", "dir/b.tmp", "cache/file"} {
+		if !ign.Match(tc).IsIgnored() {
+			t.Errorf("Incorrect match for %q: should be matched", tc)
+		}
+	}
+	for _, tc := range []string{"keep.tmp", "dir/keep.tmp", "a.txt"} {
+		if ign.Match(tc).IsIgnored() {
+			t.Errorf("Incorrect match for %q: should not be matched", tc)
+		}
+	}
+	if lines := ign.Lines(); len(lines) != 3 {
+		t.Errorf("expected only the local lines, got %q", lines)
+	}
+}
+
+func TestSubtrees(t *testing.T) {
+	ign := New(fs.NewFilesystem(fs.FilesystemTypeBasic, "."), WithSubtrees([]string{"photos/2020", "music/"}))
+	stignore := `
+	*.tmp
+	`
+	if err := ign.Parse(bytes.NewBufferString(stignore), ".stignore"); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []string{"docs", "docs/a.txt", "photos/2019", "photos/2020/a.tmp", "musical"} {
+		if !ign.Match(tc).IsIgnored() {
+			t.Errorf("Incorrect match for %q: should be matched", tc)
+		}
+	}
+	for _, tc := range []string{"photos", "photos/2020", "photos/2020/a.jpg", "music/b.mp3"} {
+		if ign.Match(tc).IsIgnored() {
+			t.Errorf("Incorrect match for %q: should not be matched", tc)
+		}
+	}
+}
+
+func TestPresets(t *testing.T) {
+	for _, name := range PresetNames() {
+		lines, _ := Preset(name)
+		if _, _, err := parseIgnoreFile(nil, strings.NewReader(strings.Join(lines, "\n")), "", nil, make(map[string]struct{}), defaultResult); err != nil {
+			t.Errorf("preset %s: %v", name, err)
+		}
+	}
+
+	ign := New(fs.NewFilesystem(fs.FilesystemTypeBasic, "."), WithPresets([]string{"os-junk", "node-modules", "unknown"}))
+	stignore := `
+	!keep/node_modules
+	`
+	if err := ign.Parse(bytes.NewBufferString(stignore), ".stignore"); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []string{".DS_Store", "dir/Thumbs.db", "app/node_modules", "app/node_modules/x/index.js"} {
+		if !ign.Match(tc).IsIgnored() || !ign.Match(tc).IsDeletable() {
+			t.Errorf("Incorrect match for %q: should be matched and deletable", tc)
+		}
+	}
+	for _, tc := range []string{"a.txt", "keep/node_modules", "keep/node_modules/y.js"} {
+		if ign.Match(tc).IsIgnored() {
+			t.Errorf("Incorrect match for %q: should not be matched", tc)
+		}
+	}
+}
+
+func TestExplain(t *testing.T) {
+	ign := New(fs.NewFilesystem(fs.FilesystemTypeBasic, "."), WithPresets([]string{"os-junk"}))
+	stignore := `
+	// A comment
+	!keep.tmp
+	*.tmp
+	(?d)build/
+	`
+	if err := ign.Parse(bytes.NewBufferString(stignore), ".stignore"); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		file      string
+		ignored   bool
+		deletable bool
+		source    string
+		line      int
+	}{
+		{"a.txt", false, false, "", 0},
+		{"keep.tmp", false, false, ".stignore", 3},
+		{"dir/a.tmp", true, false, ".stignore", 4},
+		{"build/out/a.o", true, true, ".stignore", 5},
+		{"dir/Thumbs.db", true, true, "preset:os-junk", 6},
+	}
+	for _, tc := range cases {
+		res, pattern := ign.Explain(tc.file)
+		if res.IsIgnored() != tc.ignored || res.IsDeletable() != tc.deletable {
+			t.Errorf("Incorrect result for %q: %v", tc.file, res)
+		}
+		if res != ign.Match(tc.file) {
+			t.Errorf("Explain and Match disagree for %q", tc.file)
+		}
+		if tc.source == "" {
+			if pattern != nil {
+				t.Errorf("Expected no pattern for %q, got %v", tc.file, pattern)
+			}
+			continue
+		}
+		if pattern == nil || pattern.Source() != tc.source || pattern.Line() != tc.line {
+			t.Errorf("Incorrect pattern for %q: %+v", tc.file, pattern)
+		}
+	}
+}
+
+func TestMatchStats(t *testing.T) {
+	ign := New(fs.NewFilesystem(fs.FilesystemTypeBasic, "."), WithMatchStats(true))
+	if err := ign.Parse(bytes.NewBufferString("/a.tmp\n*.tmp\n/b.tmp\n"), ".stignore"); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{"a.tmp", "b.tmp", "dir/c.tmp", "c.txt"} {
+		ign.Match(file)
+	}
+
+	stats := ign.Stats()
+	expected := []struct {
+		pattern          string
+		matched, decided int
+	}{
+		{"/a.tmp", 1, 1},
+		{"/a.tmp/**", 0, 0},
+		{"*.tmp", 2, 1},
+		{"**/*.tmp", 1, 1},
+		{"*.tmp/**", 0, 0},
+		{"**/*.tmp/**", 0, 0},
+		{"/b.tmp", 1, 0},
+		{"/b.tmp/**", 0, 0},
+	}
+	if len(stats) != len(expected) {
+		t.Fatalf("Expected %d patterns, got %d", len(expected), len(stats))
+	}
+	for i, e := range expected {
+		s := stats[i]
+		if s.Pattern.String() != e.pattern || s.Matched != e.matched || s.Decided != e.decided {
+			t.Errorf("Pattern %d: expected %+v, got %v %d %d", i, e, s.Pattern, s.Matched, s.Decided)
+		}
+	}
+
+	if New(fs.NewFilesystem(fs.FilesystemTypeBasic, ".")).Stats() != nil {
+		t.Error("Expected no stats unless enabled")
+	}
+}
+
+func TestConditions(t *testing.T) {
+	ign := New(fs.NewFilesystem(fs.FilesystemTypeBasic, "."), WithCache(true))
+	stignore := `
+	!(?size<1k)keep/*
+	(?size>1G)*.iso
+	(?age>365d)(?size>10MiB)archive
+	(?d)(?age<1h)*.tmp
+	`
+	if err := ign.Parse(bytes.NewBufferString(stignore), ".stignore"); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	old := now.Add(-2 * 365 * 24 * time.Hour)
+	cases := []struct {
+		file    string
+		size    int64
+		modTime time.Time
+		result  Result
+	}{
+		{"a.iso", 2 << 30, now, resultInclude},
+		{"dir/a.iso", 100, now, resultNotMatched},
+		{"archive/big", 20 << 20, old, resultInclude},
+		{"archive/big", 20 << 20, now, resultNotMatched},
+		{"archive/small", 100, old, resultNotMatched},
+		{"a.tmp", 0, now, resultInclude | resultDeletable},
+		{"a.tmp", 0, old, resultNotMatched},
+		{"keep/a.iso", 2 << 30, now, resultInclude},
+		{"keep/b.iso", 100, now, resultNotMatched},
+	}
+	for _, tc := range cases {
+		if res := ign.MatchFile(tc.file, tc.size, tc.modTime); res&^resultFoldCase != tc.result {
+			t.Errorf("Incorrect result for %q of %d bytes modified at %v: %v", tc.file, tc.size, tc.modTime, res)
+		}
+	}
+
+	// Without size and age, patterns with conditions don't apply.
+	if ign.Match("a.iso").IsIgnored() {
+		t.Error("Incorrect match without size and age")
+	}
+
+	if !strings.Contains(ign.Patterns()[4], "(?size>1G)") {
+		t.Errorf("Conditions missing from patterns: %v", ign.Patterns())
+	}
+
+	for _, line := range []string{"(?size=1G)a", "(?size>1X)a", "(?age>1)a", "(?age>d)a", "(?size>1G"} {
+		if _, err := parseLine(line, defaultResult); err == nil {
+			t.Errorf("Expected an error parsing %q", line)
+		}
+	}
+}
+
 func TestCaching(t *testing.T) {
 	dir, err := ioutil.TempDir("", "")
 	if err != nil {
@@ -1124,7 +1362,7 @@ func TestSkipIgnoredDirs(t *testing.T) {
 	}
 
 	for _, tc := range tcs {
-		pats, err := parseLine(tc.pattern)
+		pats, err := parseLine(tc.pattern, defaultResult)
 		if err != nil {
 			t.Error(err)
 		}
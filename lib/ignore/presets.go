@@ -0,0 +1,85 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package ignore
+
+import (
+	"sort"
+)
+
+// Named sets of patterns that folders can use in addition to those in
+// .stignore, for the things people commonly want to ignore. The patterns
+// are deletable, so they don't keep directories from being removed.
+var presets = map[string][]string{
+	"os-junk": {
+		"(?d).DS_Store",
+		"(?d)._*",
+		"(?d).Spotlight-V100",
+		"(?d).Trashes",
+		"(?d).fseventsd",
+		"(?d)Thumbs.db",
+		"(?d)ehthumbs.db",
+		"(?d)desktop.ini",
+		"(?d)$RECYCLE.BIN",
+		"(?d).directory",
+		"(?d).Trash-*",
+		"(?d)*~",
+	},
+	"node-modules": {
+		"(?d)node_modules",
+		"(?d)bower_components",
+		"(?d).npm",
+		"(?d).yarn-cache",
+	},
+	"build-artifacts": {
+		"(?d)*.o",
+		"(?d)*.obj",
+		"(?d)*.pyc",
+		"(?d)__pycache__",
+		"(?d)*.class",
+		"(?d).gradle",
+		"(?d).tox",
+	},
+	"editor-temp": {
+		"(?d)*.swp",
+		"(?d)*.swo",
+		"(?d).#*",
+		"(?d)#*#",
+		"(?d)~$*",
+		"(?d).~lock.*#",
+	},
+}
+
+// PresetNames returns the names of the presets, sorted.
+func PresetNames() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Preset returns the lines of the named preset, and false if there is no
+// such preset.
+func Preset(name string) ([]string, bool) {
+	lines, ok := presets[name]
+	return append([]string(nil), lines...), ok
+}
+
+// WithPresets adds the patterns of the named presets after those in
+// .stignore, so that local patterns take precedence. Unknown names are
+// skipped. The default is no presets.
+func WithPresets(names []string) Option {
+	return func(m *Matcher) {
+		m.presets = nil
+		for _, name := range names {
+			if _, ok := presets[name]; ok {
+				m.presets = append(m.presets, name)
+			}
+		}
+	}
+}
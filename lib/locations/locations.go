@@ -30,10 +30,12 @@ const (
 	Database      LocationEnum = "database"
 	LogFile       LocationEnum = "logFile"
 	CsrfTokens    LocationEnum = "csrfTokens"
+	ShareLinks    LocationEnum = "shareLinks"
 	PanicLog      LocationEnum = "panicLog"
 	AuditLog      LocationEnum = "auditLog"
 	GUIAssets     LocationEnum = "GUIAssets"
 	DefFolder     LocationEnum = "defFolder"
+	BlockCache    LocationEnum = "blockCache"
 )
 
 type BaseDirEnum string
@@ -84,10 +86,12 @@ var locationTemplates = map[LocationEnum]string{
 	Database:      "${config}/index-v0.14.0.db",
 	LogFile:       "${config}/syncthing.log", // -logfile on Windows
 	CsrfTokens:    "${config}/csrftokens.txt",
+	ShareLinks:    "${config}/sharelinks.json",
 	PanicLog:      "${config}/panic-${timestamp}.log",
 	AuditLog:      "${config}/audit-${timestamp}.log",
 	GUIAssets:     "${config}/gui",
 	DefFolder:     "${home}/Sync",
+	BlockCache:    "${config}/blockcache",
 }
 
 var locations = make(map[LocationEnum]string)
@@ -0,0 +1,121 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/gobwas/glob"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+const (
+	skipReasonDelete      = "deletes are not accepted"
+	skipReasonPermissions = "permission changes are not accepted"
+	skipReasonPattern     = "not matched by an accepted pattern"
+)
+
+// A SkippedChange is a change from another device that the puller didn't
+// apply because the accept policy of the folder rejects it.
+type SkippedChange struct {
+	Name       string    `json:"name"`
+	Deleted    bool      `json:"deleted"`
+	Reason     string    `json:"reason"`
+	ModifiedBy string    `json:"modifiedBy"`
+	ModTime    time.Time `json:"modTime"`
+}
+
+type acceptPolicy struct {
+	rejectDeletes bool
+	rejectPerms   bool
+	patterns      []glob.Glob
+	hasPatterns   bool
+}
+
+// newAcceptPolicy returns the policy described by the configuration, or nil
+// if it accepts everything.
+func newAcceptPolicy(cfg config.AcceptPolicy) *acceptPolicy {
+	if !cfg.RejectDeletes && !cfg.RejectPermissionChanges && len(cfg.Patterns) == 0 {
+		return nil
+	}
+	p := &acceptPolicy{
+		rejectDeletes: cfg.RejectDeletes,
+		rejectPerms:   cfg.RejectPermissionChanges,
+		hasPatterns:   len(cfg.Patterns) > 0,
+	}
+	for _, pattern := range cfg.Patterns {
+		// An invalid pattern matches nothing, rather than making the
+		// policy more permissive than intended.
+		g, err := glob.Compile(pattern, '/')
+		if err != nil {
+			l.Warnf("Invalid accept pattern %q: %v", pattern, err)
+			continue
+		}
+		p.patterns = append(p.patterns, g)
+	}
+	return p
+}
+
+// needsCurrent returns true if evaluating the policy requires the current
+// local version of the file.
+func (p *acceptPolicy) needsCurrent() bool {
+	return p.rejectPerms
+}
+
+// evaluate returns the reason why the change to file is rejected, or the
+// empty string if it's accepted. Patterns only apply to files and symlinks,
+// so that directories leading to accepted files can always be created.
+func (p *acceptPolicy) evaluate(file, cur protocol.FileInfo, hasCur, ignorePerms bool) string {
+	if p.hasPatterns && !file.IsDirectory() && !p.matches(file.Name) {
+		return skipReasonPattern
+	}
+	if p.rejectDeletes && file.IsDeleted() {
+		return skipReasonDelete
+	}
+	if p.rejectPerms && hasCur && !cur.IsDeleted() && !file.IsDeleted() && !ignorePerms && !file.NoPermissions && !cur.NoPermissions && cur.Permissions&0777 != file.Permissions&0777 {
+		return skipReasonPermissions
+	}
+	return ""
+}
+
+// matches returns true if the name matches one of the patterns. Patterns
+// without a slash match the base name in any directory.
+func (p *acceptPolicy) matches(name string) bool {
+	name = filepath.ToSlash(name)
+	base := filepath.Base(name)
+	for _, g := range p.patterns {
+		if g.Match(name) || g.Match(base) {
+			return true
+		}
+	}
+	return false
+}
+
+func newSkippedChange(file protocol.FileInfo, reason string) SkippedChange {
+	return SkippedChange{
+		Name:       file.Name,
+		Deleted:    file.IsDeleted(),
+		Reason:     reason,
+		ModifiedBy: file.ModifiedBy.String(),
+		ModTime:    file.ModTime(),
+	}
+}
+
+func sortedSkippedChanges(skipped map[string]SkippedChange) []SkippedChange {
+	res := make([]SkippedChange, 0, len(skipped))
+	for _, c := range skipped {
+		res = append(res, c)
+	}
+	sort.Slice(res, func(a, b int) bool {
+		return res[a].Name < res[b].Name
+	})
+	return res
+}
@@ -0,0 +1,175 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// auditExportBatch is how many entries are read from the database at a time
+// when exporting the audit log.
+const auditExportBatch = 1000
+
+var errNoAuditLog = errors.New("folder has no audit log")
+
+// An AuditLogEntry is a change to a file, either applied from another
+// device or made locally and sent to the other devices.
+type AuditLogEntry struct {
+	ID         int64     `json:"id"`
+	Time       time.Time `json:"time"`
+	Origin     string    `json:"origin"` // remote or local
+	Action     string    `json:"action"` // added, modified or deleted
+	Type       string    `json:"type"`   // file, dir or symlink
+	Path       string    `json:"path"`
+	Device     string    `json:"device"` // who made the change; empty if it's not a device we know
+	ModifiedBy string    `json:"modifiedBy"`
+	Version    []string  `json:"version"`
+}
+
+// recordAudit adds the changes committed to the database to the audit log,
+// if the folder keeps one.
+func (f *folder) recordAudit(fs []protocol.FileInfo, remote bool) {
+	if f.audit == nil {
+		return
+	}
+	now := time.Now().UnixNano()
+	entries := make([]db.AuditEntry, 0, len(fs))
+	for _, file := range fs {
+		if file.IsInvalid() {
+			continue
+		}
+		action, _ := diskChange(file)
+		entries = append(entries, db.AuditEntry{
+			Time:       now,
+			Remote:     remote,
+			Action:     action,
+			Type:       file.Type,
+			Path:       file.Name,
+			ModifiedBy: file.ModifiedBy,
+			Version:    file.Version,
+		})
+	}
+	if len(entries) == 0 {
+		return
+	}
+	if err := f.audit.Append(entries, f.AuditLogEntries); err != nil {
+		l.Warnf("Folder %v: recording changes in the audit log: %v", f.Description(), err)
+	}
+}
+
+// AuditLog returns the entries of the audit log after the given ID, oldest
+// first, at most limit of them unless it's zero.
+func (f *folder) AuditLog(since int64, limit int) ([]db.AuditEntry, error) {
+	if f.audit == nil {
+		return nil, errNoAuditLog
+	}
+	return f.audit.Entries(since, limit)
+}
+
+// AuditLog returns the entries of the audit log of the folder after the
+// given ID, oldest first, at most limit of them unless it's zero.
+func (m *model) AuditLog(folder string, since int64, limit int) ([]AuditLogEntry, error) {
+	m.fmut.RLock()
+	runner, ok := m.folderRunners[folder]
+	m.fmut.RUnlock()
+	if !ok {
+		return nil, errFolderMissing
+	}
+
+	entries, err := runner.AuditLog(since, limit)
+	if err != nil {
+		return nil, err
+	}
+	devices := m.shortDeviceIDs()
+	res := make([]AuditLogEntry, len(entries))
+	for i, e := range entries {
+		res[i] = auditLogEntry(e, devices)
+	}
+	return res, nil
+}
+
+// ExportAuditLog writes all entries of the audit log of the folder to w,
+// oldest first, as one JSON object per line.
+func (m *model) ExportAuditLog(folder string, w io.Writer) error {
+	m.fmut.RLock()
+	runner, ok := m.folderRunners[folder]
+	m.fmut.RUnlock()
+	if !ok {
+		return errFolderMissing
+	}
+
+	devices := m.shortDeviceIDs()
+	enc := json.NewEncoder(w)
+	var since int64
+	for {
+		entries, err := runner.AuditLog(since, auditExportBatch)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if err := enc.Encode(auditLogEntry(e, devices)); err != nil {
+				return err
+			}
+			since = e.ID
+		}
+		if len(entries) < auditExportBatch {
+			return nil
+		}
+	}
+}
+
+// shortDeviceIDs returns the devices we know, including ourselves, by their
+// short ID.
+func (m *model) shortDeviceIDs() map[protocol.ShortID]protocol.DeviceID {
+	devices := make(map[protocol.ShortID]protocol.DeviceID)
+	for id := range m.cfg.Devices() {
+		devices[id.Short()] = id
+	}
+	devices[m.shortID] = m.id
+	return devices
+}
+
+func auditLogEntry(e db.AuditEntry, devices map[protocol.ShortID]protocol.DeviceID) AuditLogEntry {
+	origin := "local"
+	if e.Remote {
+		origin = "remote"
+	}
+	objType := "symlink"
+	switch e.Type {
+	case protocol.FileInfoTypeFile:
+		objType = "file"
+	case protocol.FileInfoTypeDirectory:
+		objType = "dir"
+	}
+	var device string
+	if id, ok := devices[e.ModifiedBy]; ok {
+		device = id.String()
+	}
+	version := make([]string, len(e.Version.Counters))
+	for i, c := range e.Version.Counters {
+		version[i] = fmt.Sprintf("%v:%d", c.ID, c.Value)
+	}
+	return AuditLogEntry{
+		ID:         e.ID,
+		Time:       time.Unix(0, e.Time),
+		Origin:     origin,
+		Action:     e.Action,
+		Type:       objType,
+		Path:       filepath.FromSlash(e.Path),
+		Device:     device,
+		ModifiedBy: e.ModifiedBy.String(),
+		Version:    version,
+	}
+}
@@ -0,0 +1,296 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// A change batch carries the changes of a folder since a given sequence,
+// metadata and file data, for transfer to devices that can't be reached
+// over the network. It is a tar archive holding, in this order:
+//
+//   header.json    the BatchHeader
+//   index.pb       a protocol.Index with the changed files
+//   files/<name>   the contents of each changed regular file
+//
+// On the receiving side the index is applied as an index update from the
+// exporting device and the file contents are placed as temporary files,
+// from which the puller then syncs the changes as usual.
+
+const (
+	batchVersion    = 1
+	batchHeaderName = "header.json"
+	batchIndexName  = "index.pb"
+	batchFilesDir   = "files/"
+
+	// The index is read into memory in whole, this guards against garbage.
+	maxBatchIndexSize = 1 << 30
+)
+
+var errBatchFormat = errors.New("invalid change batch")
+
+// BatchHeader describes the contents of a change batch.
+type BatchHeader struct {
+	Version      int               `json:"version"`
+	Folder       string            `json:"folder"`
+	Device       protocol.DeviceID `json:"device"`
+	FromSequence int64             `json:"fromSequence"`
+	ToSequence   int64             `json:"toSequence"`
+	Files        int               `json:"files"`
+	Created      time.Time         `json:"created"`
+}
+
+// ExportBatch writes a change batch with all changes to the folder after the
+// given sequence to w.
+func (m *model) ExportBatch(folder string, since int64, w io.Writer) (BatchHeader, error) {
+	m.fmut.RLock()
+	cfg, cfgOk := m.folderCfgs[folder]
+	fset, fsetOk := m.folderFiles[folder]
+	m.fmut.RUnlock()
+
+	if !cfgOk || !fsetOk {
+		return BatchHeader{}, errFolderMissing
+	}
+	if cfg.Paused {
+		return BatchHeader{}, ErrFolderPaused
+	}
+
+	hdr := BatchHeader{
+		Version:      batchVersion,
+		Folder:       folder,
+		Device:       m.id,
+		FromSequence: since,
+		ToSequence:   since,
+		Created:      time.Now().UTC().Truncate(time.Second),
+	}
+
+	var files []protocol.FileInfo
+	fset.WithHaveSequence(since+1, func(fi db.FileIntf) bool {
+		f := fi.(protocol.FileInfo)
+		// Same treatment as when sending the index to another device.
+		f.RawInvalid = f.IsInvalid()
+		if f.IsReceiveOnlyChanged() {
+			f.Version = protocol.Vector{}
+		}
+		f.LocalFlags = 0
+		files = append(files, f)
+		hdr.ToSequence = f.Sequence
+		return true
+	})
+	hdr.Files = len(files)
+
+	tw := tar.NewWriter(w)
+
+	bs, err := json.MarshalIndent(&hdr, "", "  ")
+	if err != nil {
+		return BatchHeader{}, err
+	}
+	if err := writeBatchEntry(tw, batchHeaderName, hdr.Created, bs); err != nil {
+		return BatchHeader{}, err
+	}
+
+	idx := protocol.Index{Folder: folder, Files: files}
+	bs, err = idx.Marshal()
+	if err != nil {
+		return BatchHeader{}, err
+	}
+	if err := writeBatchEntry(tw, batchIndexName, hdr.Created, bs); err != nil {
+		return BatchHeader{}, err
+	}
+
+	ffs := cfg.Filesystem()
+	for _, f := range files {
+		if f.Type != protocol.FileInfoTypeFile || f.IsDeleted() || f.IsInvalid() || f.Size == 0 {
+			continue
+		}
+		if err := writeBatchFile(tw, ffs, f); err != nil {
+			return BatchHeader{}, errors.Wrap(err, f.Name)
+		}
+	}
+
+	return hdr, tw.Close()
+}
+
+func writeBatchEntry(tw *tar.Writer, name string, modTime time.Time, data []byte) error {
+	err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: modTime,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = tw.Write(data)
+	return err
+}
+
+func writeBatchFile(tw *tar.Writer, ffs fs.Filesystem, f protocol.FileInfo) error {
+	fd, err := ffs.Open(f.Name)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	// The file may have changed since it was last scanned, so we write the
+	// size announced in the index. Blocks that don't match are fetched from
+	// other devices on the receiving side.
+	err = tw.WriteHeader(&tar.Header{
+		Name:    batchFilesDir + filepath.ToSlash(f.Name),
+		Mode:    0644,
+		Size:    f.Size,
+		ModTime: f.ModTime(),
+	})
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(tw, io.LimitReader(fd, f.Size))
+	if err != nil {
+		return err
+	}
+	if n < f.Size {
+		// Pad the shortened file with zeroes, the blocks will fail
+		// verification and be pulled over the network.
+		_, err = io.CopyN(tw, zeroReader{}, f.Size-n)
+	}
+	return err
+}
+
+// ImportBatch reads a change batch from r, applying its index as an index
+// update from the exporting device and placing the file contents so that
+// the changes are synced without having to request any data.
+func (m *model) ImportBatch(r io.Reader) (BatchHeader, error) {
+	tr := tar.NewReader(r)
+
+	var hdr BatchHeader
+	bs, err := readBatchEntry(tr, batchHeaderName, 1<<20)
+	if err != nil {
+		return BatchHeader{}, err
+	}
+	if err := json.Unmarshal(bs, &hdr); err != nil {
+		return BatchHeader{}, errors.Wrap(errBatchFormat, err.Error())
+	}
+	if hdr.Version != batchVersion {
+		return BatchHeader{}, fmt.Errorf("unsupported change batch version %d", hdr.Version)
+	}
+	if hdr.Device == m.id {
+		return BatchHeader{}, errors.New("change batch was exported by this device")
+	}
+
+	cfg, ok := m.cfg.Folder(hdr.Folder)
+	if !ok || !cfg.SharedWith(hdr.Device) {
+		return BatchHeader{}, errors.Wrapf(errFolderMissing, "folder %q shared with %v", hdr.Folder, hdr.Device)
+	}
+	if cfg.Paused {
+		return BatchHeader{}, ErrFolderPaused
+	}
+
+	bs, err = readBatchEntry(tr, batchIndexName, maxBatchIndexSize)
+	if err != nil {
+		return BatchHeader{}, err
+	}
+	var idx protocol.Index
+	if err := idx.Unmarshal(bs); err != nil {
+		return BatchHeader{}, errors.Wrap(errBatchFormat, err.Error())
+	}
+	if idx.Folder != hdr.Folder {
+		return BatchHeader{}, errors.Wrap(errBatchFormat, "index for wrong folder")
+	}
+
+	sizes := make(map[string]int64, len(idx.Files))
+	for _, f := range idx.Files {
+		if f.Type == protocol.FileInfoTypeFile && !f.IsDeleted() {
+			sizes[f.Name] = f.Size
+		}
+	}
+
+	// Place the file contents as temporary files before the index is
+	// applied, so that they are there when the puller gets going.
+	tempFs := cfg.Filesystem()
+	if cfg.TempPath != "" {
+		tempFs = cfg.TempFilesystem()
+	}
+	for {
+		th, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return BatchHeader{}, errors.Wrap(errBatchFormat, err.Error())
+		}
+		name := strings.TrimPrefix(th.Name, batchFilesDir)
+		if name == th.Name || path.Clean(name) != name {
+			return BatchHeader{}, errors.Wrapf(errBatchFormat, "unexpected entry %q", th.Name)
+		}
+		name = filepath.FromSlash(name)
+		if size, ok := sizes[name]; !ok || size != th.Size {
+			return BatchHeader{}, errors.Wrapf(errBatchFormat, "unexpected entry %q", th.Name)
+		}
+		if err := writeBatchTempFile(tempFs, name, tr); err != nil {
+			return BatchHeader{}, errors.Wrap(err, name)
+		}
+	}
+
+	if err := m.handleIndex(hdr.Device, hdr.Folder, idx.Files, true); err != nil {
+		return BatchHeader{}, err
+	}
+	l.Infof("Imported change batch for folder %v from device %v with %d files (sequence %d to %d)", cfg.Description(), hdr.Device, hdr.Files, hdr.FromSequence, hdr.ToSequence)
+	return hdr, nil
+}
+
+func readBatchEntry(tr *tar.Reader, name string, maxSize int64) ([]byte, error) {
+	th, err := tr.Next()
+	if err != nil {
+		return nil, errors.Wrap(errBatchFormat, err.Error())
+	}
+	if th.Name != name {
+		return nil, errors.Wrapf(errBatchFormat, "expected %q, got %q", name, th.Name)
+	}
+	if th.Size > maxSize {
+		return nil, errors.Wrapf(errBatchFormat, "%q too large", name)
+	}
+	return ioutil.ReadAll(tr)
+}
+
+func writeBatchTempFile(tempFs fs.Filesystem, name string, r io.Reader) error {
+	tempName := fs.TempName(name)
+	if err := tempFs.MkdirAll(filepath.Dir(tempName), 0755); err != nil {
+		return err
+	}
+	fd, err := tempFs.Create(tempName)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(fd, r); err != nil {
+		fd.Close()
+		return err
+	}
+	return fd.Close()
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(bs []byte) (int, error) {
+	for i := range bs {
+		bs[i] = 0
+	}
+	return len(bs), nil
+}
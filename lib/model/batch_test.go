@@ -0,0 +1,88 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/db/backend"
+	"github.com/syncthing/syncthing/lib/fs"
+)
+
+func TestExportImportBatch(t *testing.T) {
+	// The exporting side is device1, sharing the folder with us.
+
+	w := createTmpWrapper(config.New(device1))
+	_, _ = w.SetDevice(config.NewDeviceConfiguration(myID, "myID"))
+	dir := createTmpDir()
+	fcfg := config.NewFolderConfiguration(device1, "default", "default", fs.FilesystemTypeBasic, dir)
+	fcfg.FSWatcherEnabled = false
+	fcfg.Devices = append(fcfg.Devices, config.FolderDeviceConfiguration{DeviceID: myID})
+	_, _ = w.SetFolder(fcfg)
+
+	ffs := fcfg.Filesystem()
+	must(t, ffs.MkdirAll("dir", 0755))
+	files := map[string][]byte{
+		"file":                      []byte("some contents\n"),
+		filepath.Join("dir", "sub"): []byte("other contents\n"),
+	}
+	for name, contents := range files {
+		must(t, ioutil.WriteFile(filepath.Join(dir, name), contents, 0644))
+	}
+
+	exporter := newModel(w, device1, "syncthing", "dev", db.NewLowlevel(backend.OpenMemory()), nil)
+	exporter.ServeBackground()
+	exporter.ScanFolders()
+	defer cleanupModelAndRemoveDir(exporter, dir)
+
+	buf := new(bytes.Buffer)
+	hdr, err := exporter.ExportBatch("default", 0, buf)
+	must(t, err)
+	if hdr.Device != device1 || hdr.FromSequence != 0 || hdr.ToSequence != 3 || hdr.Files != 3 {
+		t.Fatalf("unexpected batch header %+v", hdr)
+	}
+
+	// We import it without ever being connected to device1.
+
+	w2, fcfg2 := tmpDefaultWrapper()
+	m2 := setupModel(w2)
+	defer cleanupModelAndRemoveDir(m2, fcfg2.Filesystem().URI())
+
+	if _, err := m2.ImportBatch(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if seq, _ := m2.RemoteSequence("default"); seq != 3 {
+		t.Errorf("remote sequence is %d after import, expected 3", seq)
+	}
+
+	timeout := time.After(10 * time.Second)
+	for name, contents := range files {
+		for {
+			if err := equalContents(filepath.Join(fcfg2.Filesystem().URI(), name), contents); err == nil {
+				break
+			}
+			select {
+			case <-timeout:
+				t.Fatalf("%v was not synced from the batch", name)
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}
+
+	// The batch can't be imported where the folder isn't shared with the
+	// exporting device.
+
+	if _, err := exporter.ImportBatch(bytes.NewReader(buf.Bytes())); err == nil {
+		t.Error("expected error importing on the exporting device")
+	}
+}
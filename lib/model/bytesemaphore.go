@@ -13,6 +13,7 @@ import (
 type byteSemaphore struct {
 	max       int
 	available int
+	first     int // number of takeFirst calls waiting
 	mut       sync.Mutex
 	cond      *sync.Cond
 }
@@ -31,13 +32,35 @@ func (s *byteSemaphore) take(bytes int) {
 	if bytes > s.max {
 		bytes = s.max
 	}
+	for bytes > s.available || s.first > 0 {
+		s.cond.Wait()
+		if bytes > s.max {
+			bytes = s.max
+		}
+	}
+	s.available -= bytes
+	s.mut.Unlock()
+}
+
+// takeFirst is like take, but goes before any take calls that are waiting.
+func (s *byteSemaphore) takeFirst(bytes int) {
+	s.mut.Lock()
+	if bytes > s.max {
+		bytes = s.max
+	}
+	s.first++
 	for bytes > s.available {
 		s.cond.Wait()
 		if bytes > s.max {
 			bytes = s.max
 		}
 	}
+	s.first--
 	s.available -= bytes
+	if s.first == 0 {
+		// Those waiting in take may go ahead, if there is room left.
+		s.cond.Broadcast()
+	}
 	s.mut.Unlock()
 }
 
@@ -111,3 +111,37 @@ func TestByteSempahoreGiveMore(t *testing.T) {
 		t.Errorf("bad state after large take + give with adjustment")
 	}
 }
+
+func TestByteSempahoreTakeFirst(t *testing.T) {
+	// A waiting takeFirst should get in before a waiting take
+
+	s := newByteSemaphore(100)
+
+	s.take(100)
+
+	order := make(chan string, 2)
+	go func() {
+		s.take(100)
+		order <- "take"
+	}()
+	go func() {
+		s.takeFirst(100)
+		order <- "takeFirst"
+	}()
+	for {
+		// Let takeFirst start waiting
+		s.mut.Lock()
+		first := s.first
+		s.mut.Unlock()
+		if first == 1 {
+			break
+		}
+	}
+
+	s.give(100)
+	if first := <-order; first != "takeFirst" {
+		t.Fatal("take got in before takeFirst")
+	}
+	s.give(100)
+	<-order
+}
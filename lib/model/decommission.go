@@ -0,0 +1,146 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"sort"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// PendingDecommission is the suggestion of a device to decommission another
+// one, waiting for the user to accept or reject it.
+type PendingDecommission struct {
+	Device      protocol.DeviceID `json:"device"`
+	Name        string            `json:"name"`
+	SuggestedBy protocol.DeviceID `json:"suggestedBy"`
+	Received    time.Time         `json:"received"`
+}
+
+// DecommissionDevice removes the device from the configuration and all
+// folders, remembers it as decommissioned and drops what we know about its
+// files. The other devices learn about it with the next cluster config, so
+// we reconnect to them, and act on it according to their decommission
+// policy for us.
+func (m *model) DecommissionDevice(device protocol.DeviceID) error {
+	if device == m.id {
+		return errDecommissionSelf
+	}
+
+	waiter, err := m.cfg.DecommissionDevice(device)
+	if err != nil {
+		return err
+	}
+	waiter.Wait()
+	if err := m.cfg.Save(); err != nil {
+		l.Warnln("Failed to save config", err)
+	}
+
+	m.closeConn(device, errDecommissioned).Wait()
+
+	m.fmut.Lock()
+	delete(m.pendingRemovals, device)
+	fsets := make(map[string]*db.FileSet, len(m.folderFiles))
+	for folder, fset := range m.folderFiles {
+		fsets[folder] = fset
+	}
+	m.fmut.Unlock()
+
+	for folder, fset := range fsets {
+		fset.Drop(device)
+		fset.SetIndexID(device, 0)
+		l.Debugf("%v dropped index of decommissioned device %s for folder %s", m, device, folder)
+	}
+	l.Infof("Decommissioned device %s", device)
+
+	m.pmut.RLock()
+	others := make([]protocol.DeviceID, 0, len(m.conn))
+	for id := range m.conn {
+		others = append(others, id)
+	}
+	m.pmut.RUnlock()
+	m.closeConns(others, errDecommissioned)
+
+	return nil
+}
+
+// handleDecommission acts on the suggestion of the device to decommission
+// another one according to our decommission policy for it. It must not be
+// called with m.fmut held, as decommissioning causes CommitConfiguration.
+func (m *model) handleDecommission(deviceCfg config.DeviceConfiguration, device protocol.DeviceID) {
+	if device == m.id || device == deviceCfg.DeviceID || m.cfg.DecommissionedDevice(device) {
+		return
+	}
+
+	switch deviceCfg.DecommissionPolicy {
+	case config.SettingsPolicyApply:
+		// Decommissioning closes this very connection, which waits for the
+		// cluster config to be handled.
+		go func() {
+			if err := m.DecommissionDevice(device); err != nil {
+				l.Warnf("Decommissioning device %s as suggested by %s: %v", device, deviceCfg.DeviceID, err)
+			}
+		}()
+
+	case config.SettingsPolicyAsk:
+		devCfg, _ := m.cfg.Device(device)
+		m.fmut.Lock()
+		_, ok := m.pendingRemovals[device]
+		m.pendingRemovals[device] = PendingDecommission{
+			Device:      device,
+			Name:        devCfg.Name,
+			SuggestedBy: deviceCfg.DeviceID,
+			Received:    time.Now(),
+		}
+		m.fmut.Unlock()
+		if ok {
+			return
+		}
+		l.Infof("Device %s suggests decommissioning device %s, waiting to be accepted", deviceCfg.DeviceID, device)
+		m.evLogger.Log(events.DeviceDecommissionPending, map[string]string{
+			"device":      device.String(),
+			"suggestedBy": deviceCfg.DeviceID.String(),
+		})
+	}
+}
+
+// PendingDecommissions returns the suggestions to decommission devices that
+// wait to be accepted or rejected, oldest first.
+func (m *model) PendingDecommissions() []PendingDecommission {
+	m.fmut.RLock()
+	pending := make([]PendingDecommission, 0, len(m.pendingRemovals))
+	for _, p := range m.pendingRemovals {
+		pending = append(pending, p)
+	}
+	m.fmut.RUnlock()
+
+	sort.Slice(pending, func(a, b int) bool {
+		return pending[a].Received.Before(pending[b].Received)
+	})
+	return pending
+}
+
+// ResolveDecommission decommissions the device if accepted, and forgets
+// about the suggestion either way.
+func (m *model) ResolveDecommission(device protocol.DeviceID, accept bool) error {
+	m.fmut.Lock()
+	_, ok := m.pendingRemovals[device]
+	delete(m.pendingRemovals, device)
+	m.fmut.Unlock()
+
+	if !ok {
+		return errNoPendingRemoval
+	}
+	if !accept {
+		return nil
+	}
+	return m.DecommissionDevice(device)
+}
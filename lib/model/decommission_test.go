@@ -0,0 +1,90 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+func TestDecommissionDevice(t *testing.T) {
+	m, fc, fcfg := setupModelWithConnection()
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+
+	devCfg := config.NewDeviceConfiguration(device2, "device2")
+	devCfg.DecommissionPolicy = config.SettingsPolicyAsk
+	waiter, _ := m.cfg.SetDevice(devCfg)
+	waiter.Wait()
+
+	m.fmut.RLock()
+	fset := m.folderFiles["default"]
+	m.fmut.RUnlock()
+	fset.Update(device1, []protocol.FileInfo{{
+		Name:     "remote",
+		Sequence: 1,
+		Version:  protocol.Vector{}.Update(device1.Short()),
+	}})
+	fset.SetIndexID(device1, 1)
+
+	if err := m.DecommissionDevice(myID); err != errDecommissionSelf {
+		t.Error("expected an error decommissioning ourselves, got", err)
+	}
+	if err := m.DecommissionDevice(device1); err != nil {
+		t.Fatal(err)
+	}
+	if !fc.Closed() {
+		t.Error("expected the connection to be closed")
+	}
+	if _, ok := m.cfg.Device(device1); ok {
+		t.Error("expected the device to be removed")
+	}
+	if !m.cfg.DecommissionedDevice(device1) || !m.cfg.IgnoredDevice(device1) {
+		t.Error("expected the device to be decommissioned and ignored")
+	}
+	if cfg, _ := m.cfg.Folder("default"); cfg.SharedWith(device1) {
+		t.Error("expected the folder to be no longer shared with the device")
+	}
+	// The folder was restarted, so with a new file set.
+	m.fmut.RLock()
+	fset = m.folderFiles["default"]
+	m.fmut.RUnlock()
+	if seq := fset.Sequence(device1); seq != 0 {
+		t.Errorf("expected the index to be dropped, got sequence %d", seq)
+	}
+	if id := fset.IndexID(device1); id != 0 {
+		t.Errorf("expected the index ID to be reset, got %v", id)
+	}
+
+	cm := m.generateClusterConfig(device2)
+	if len(cm.Decommissioned) != 1 || cm.Decommissioned[0] != device1 {
+		t.Errorf("expected the decommissioned device to be announced, got %v", cm.Decommissioned)
+	}
+
+	// Suggestions from others wait for us to accept them, unless we
+	// already decommissioned the device.
+	device3 := protocol.NewDeviceID([]byte("device3"))
+	m.handleDecommission(devCfg, device1)
+	m.handleDecommission(devCfg, device3)
+	pending := m.PendingDecommissions()
+	if len(pending) != 1 || pending[0].Device != device3 || pending[0].SuggestedBy != device2 {
+		t.Fatalf("expected a pending decommission, got %+v", pending)
+	}
+	if err := m.ResolveDecommission(device3, false); err != nil {
+		t.Fatal(err)
+	}
+	if pending := m.PendingDecommissions(); len(pending) != 0 {
+		t.Errorf("expected nothing pending, got %+v", pending)
+	}
+	if err := m.ResolveDecommission(device3, true); err != errNoPendingRemoval {
+		t.Error("expected an error for nothing pending, got", err)
+	}
+	if m.cfg.DecommissionedDevice(device3) {
+		t.Error("expected a rejected suggestion to change nothing")
+	}
+}
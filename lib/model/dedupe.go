@@ -0,0 +1,174 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/thejerf/suture"
+
+	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/util"
+)
+
+// How long to wait for local changes to settle before deduplicating them.
+const dedupeDelay = 10 * time.Second
+
+// The blockDeduper makes files that have changed locally share the storage
+// of identical blocks with the other files in any folder, when the
+// filesystem supports that (e.g. Btrfs and XFS). The filesystem verifies
+// the data and counts the references to the shared extents, so a block is
+// stored once no matter how many files hold it, and writing to one of them
+// doesn't affect the others.
+type blockDeduper struct {
+	suture.Service
+	model *model
+	delay time.Duration
+
+	pending     map[string]map[string]struct{} // folder -> file names
+	unsupported map[dedupePair]struct{}
+}
+
+// A dedupePair is a combination of folders that can't share storage,
+// typically because they are on different filesystems.
+type dedupePair struct {
+	src, dst string
+}
+
+func newBlockDeduper(m *model) *blockDeduper {
+	d := &blockDeduper{
+		model:       m,
+		delay:       dedupeDelay,
+		pending:     make(map[string]map[string]struct{}),
+		unsupported: make(map[dedupePair]struct{}),
+	}
+	d.Service = util.AsService(d.serve, d.String())
+	return d
+}
+
+func (d *blockDeduper) serve(ctx context.Context) {
+	sub := d.model.evLogger.Subscribe(events.LocalIndexUpdated)
+	defer sub.Unsubscribe()
+
+	timer := time.NewTimer(d.delay)
+	timer.Stop()
+
+	for {
+		select {
+		case ev := <-sub.C():
+			data := ev.Data.(map[string]interface{})
+			folder := data["folder"].(string)
+			if len(d.pending) == 0 {
+				timer.Reset(d.delay)
+			}
+			names, ok := d.pending[folder]
+			if !ok {
+				names = make(map[string]struct{})
+				d.pending[folder] = names
+			}
+			for _, name := range data["filenames"].([]string) {
+				names[name] = struct{}{}
+			}
+		case <-timer.C:
+			d.dedupePending(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (d *blockDeduper) dedupePending(ctx context.Context) {
+	filesystems := make(map[string]fs.Filesystem)
+	var folders []string
+	for folder, cfg := range d.model.cfg.Folders() {
+		filesystems[folder] = cfg.Filesystem()
+		folders = append(folders, folder)
+	}
+
+	var shared int64
+	for folder, names := range d.pending {
+		delete(d.pending, folder)
+		if _, ok := filesystems[folder]; !ok {
+			continue
+		}
+		for name := range names {
+			if ctx.Err() != nil {
+				return
+			}
+			shared += d.dedupeFile(folder, name, folders, filesystems)
+		}
+	}
+	if shared > 0 {
+		l.Infof("Shared storage of %d bytes in identical blocks across folders", shared)
+	}
+}
+
+// dedupeFile makes the blocks of the file share storage with identical
+// blocks elsewhere and returns the number of bytes for which that worked.
+func (d *blockDeduper) dedupeFile(folder, name string, folders []string, filesystems map[string]fs.Filesystem) int64 {
+	d.model.fmut.RLock()
+	fset := d.model.folderFiles[folder]
+	d.model.fmut.RUnlock()
+	if fset == nil {
+		return 0
+	}
+	file, ok := fset.Get(protocol.LocalDeviceID, name)
+	if !ok || file.IsDeleted() || file.IsInvalid() || file.IsDirectory() || file.IsSymlink() || file.IsPlaceholder() {
+		return 0
+	}
+
+	dstFs := filesystems[folder]
+	dstFd, err := dstFs.OpenFile(file.Name, fs.OptReadWrite, 0)
+	if err != nil {
+		l.Debugln("dedupe open", folder, file.Name, err)
+		return 0
+	}
+	defer dstFd.Close()
+
+	var shared int64
+	for _, block := range file.Blocks {
+		if block.Size == 0 {
+			continue
+		}
+		d.model.finder.IterateOffsets(folders, block.Hash, file.BlockSize(), func(srcFolder, path string, srcOffset int64) bool {
+			if srcFolder == folder && path == file.Name {
+				return false
+			}
+			pair := dedupePair{srcFolder, folder}
+			if _, ok := d.unsupported[pair]; ok {
+				return false
+			}
+			srcFd, err := filesystems[srcFolder].Open(path)
+			if err != nil {
+				return false
+			}
+			defer srcFd.Close()
+
+			switch err := dstFs.DedupeRange(srcFd, srcOffset, dstFd, block.Offset, int64(block.Size)); err {
+			case nil:
+				shared += int64(block.Size)
+				return true
+			case fs.ErrCloneNotSupported:
+				d.unsupported[pair] = struct{}{}
+			case fs.ErrRangesDiffer:
+				// The database is behind on one of the files.
+			default:
+				l.Debugln("dedupe", srcFolder, path, folder, file.Name, err)
+			}
+			return false
+		})
+	}
+	return shared
+}
+
+func (d *blockDeduper) String() string {
+	return fmt.Sprintf("blockDeduper@%p", d)
+}
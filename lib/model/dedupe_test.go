@@ -0,0 +1,54 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/fs"
+)
+
+func TestBlockDeduper(t *testing.T) {
+	w, fcfg := tmpDefaultWrapper()
+	dir := fcfg.Filesystem().URI()
+	data := make([]byte, 256<<10)
+	io.ReadFull(rand.Reader, data)
+	for _, name := range []string{"a", "b"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := setupModel(w)
+	defer cleanupModelAndRemoveDir(m, dir)
+
+	d := newBlockDeduper(m)
+	d.pending[fcfg.ID] = map[string]struct{}{"b": {}}
+	shared := d.dedupeFile(fcfg.ID, "b", []string{fcfg.ID}, map[string]fs.Filesystem{fcfg.ID: fcfg.Filesystem()})
+	if shared == 0 {
+		if _, ok := d.unsupported[dedupePair{fcfg.ID, fcfg.ID}]; !ok {
+			t.Fatal("expected the folder to be remembered as unsupported when nothing was shared")
+		}
+		t.Skip("deduplication not supported on", dir)
+	}
+	if shared != int64(len(data)) {
+		t.Errorf("expected %d bytes shared, got %d", len(data), shared)
+	}
+
+	d.dedupePending(context.Background())
+	if len(d.pending) != 0 {
+		t.Error("expected nothing pending after deduplicating")
+	}
+	if bs, err := ioutil.ReadFile(filepath.Join(dir, "b")); err != nil || !bytes.Equal(bs, data) {
+		t.Error("deduplication changed the data", err)
+	}
+}
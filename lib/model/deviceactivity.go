@@ -7,13 +7,16 @@
 package model
 
 import (
+	"time"
+
+	"github.com/syncthing/syncthing/lib/connections"
 	"github.com/syncthing/syncthing/lib/protocol"
 	"github.com/syncthing/syncthing/lib/sync"
 )
 
 // deviceActivity tracks the number of outstanding requests per device and can
-// answer which device is least busy. It is safe for use from multiple
-// goroutines.
+// answer which device is fastest to request from. It is safe for use from
+// multiple goroutines.
 type deviceActivity struct {
 	act map[protocol.DeviceID]int
 	mut sync.Mutex
@@ -26,19 +29,42 @@ func newDeviceActivity() *deviceActivity {
 	}
 }
 
-func (m *deviceActivity) leastBusy(availability []Availability) (Availability, bool) {
+// fastest returns the device expected to deliver the given number of bytes
+// the soonest, going by the quality of its connection and the requests
+// already outstanding to it. Devices nothing is known about yet count as
+// fast, so that they get tried; among equals the least busy one wins. A nil
+// quality function means nothing is known about any device.
+func (m *deviceActivity) fastest(availability []Availability, bytes int, quality func(protocol.DeviceID) connections.Quality) (Availability, bool) {
+	var estimates []time.Duration
+	if quality != nil {
+		// Looked up before taking the lock, as it takes other locks.
+		estimates = make([]time.Duration, len(availability))
+		for i, info := range availability {
+			estimates[i] = quality(info.ID).Estimate(bytes)
+		}
+	}
+
 	m.mut.Lock()
-	low := 2<<30 - 1
+	defer m.mut.Unlock()
 	found := false
 	var selected Availability
-	for _, info := range availability {
-		if usage := m.act[info.ID]; usage < low {
-			low = usage
+	var lowCost time.Duration
+	lowUsage := 0
+	for i, info := range availability {
+		usage := m.act[info.ID]
+		var cost time.Duration
+		if estimates != nil {
+			// Outstanding requests are assumed to be served one after
+			// another.
+			cost = estimates[i] * time.Duration(usage+1)
+		}
+		if !found || cost < lowCost || cost == lowCost && usage < lowUsage {
 			selected = info
+			lowCost = cost
+			lowUsage = usage
 			found = true
 		}
 	}
-	m.mut.Unlock()
 	return selected, found
 }
 
@@ -8,7 +8,9 @@ package model
 
 import (
 	"testing"
+	"time"
 
+	"github.com/syncthing/syncthing/lib/connections"
 	"github.com/syncthing/syncthing/lib/protocol"
 )
 
@@ -19,42 +21,80 @@ func TestDeviceActivity(t *testing.T) {
 	devices := []Availability{n0, n1, n2}
 	na := newDeviceActivity()
 
-	if lb, ok := na.leastBusy(devices); !ok || lb != n0 {
+	if lb, ok := na.fastest(devices, 0, nil); !ok || lb != n0 {
 		t.Errorf("Least busy device should be n0 (%v) not %v", n0, lb)
 	}
-	if lb, ok := na.leastBusy(devices); !ok || lb != n0 {
+	if lb, ok := na.fastest(devices, 0, nil); !ok || lb != n0 {
 		t.Errorf("Least busy device should still be n0 (%v) not %v", n0, lb)
 	}
 
-	lb, _ := na.leastBusy(devices)
+	lb, _ := na.fastest(devices, 0, nil)
 	na.using(lb)
-	if lb, ok := na.leastBusy(devices); !ok || lb != n1 {
+	if lb, ok := na.fastest(devices, 0, nil); !ok || lb != n1 {
 		t.Errorf("Least busy device should be n1 (%v) not %v", n1, lb)
 	}
-	lb, _ = na.leastBusy(devices)
+	lb, _ = na.fastest(devices, 0, nil)
 	na.using(lb)
-	if lb, ok := na.leastBusy(devices); !ok || lb != n2 {
+	if lb, ok := na.fastest(devices, 0, nil); !ok || lb != n2 {
 		t.Errorf("Least busy device should be n2 (%v) not %v", n2, lb)
 	}
 
-	lb, _ = na.leastBusy(devices)
+	lb, _ = na.fastest(devices, 0, nil)
 	na.using(lb)
-	if lb, ok := na.leastBusy(devices); !ok || lb != n0 {
+	if lb, ok := na.fastest(devices, 0, nil); !ok || lb != n0 {
 		t.Errorf("Least busy device should be n0 (%v) not %v", n0, lb)
 	}
 
 	na.done(n1)
-	if lb, ok := na.leastBusy(devices); !ok || lb != n1 {
+	if lb, ok := na.fastest(devices, 0, nil); !ok || lb != n1 {
 		t.Errorf("Least busy device should be n1 (%v) not %v", n1, lb)
 	}
 
 	na.done(n2)
-	if lb, ok := na.leastBusy(devices); !ok || lb != n1 {
+	if lb, ok := na.fastest(devices, 0, nil); !ok || lb != n1 {
 		t.Errorf("Least busy device should still be n1 (%v) not %v", n1, lb)
 	}
 
 	na.done(n0)
-	if lb, ok := na.leastBusy(devices); !ok || lb != n0 {
+	if lb, ok := na.fastest(devices, 0, nil); !ok || lb != n0 {
 		t.Errorf("Least busy device should be n0 (%v) not %v", n0, lb)
 	}
 }
+
+func TestDeviceActivityQuality(t *testing.T) {
+	slow := Availability{protocol.DeviceID([32]byte{1, 2, 3, 4}), false}
+	fast := Availability{protocol.DeviceID([32]byte{5, 6, 7, 8}), false}
+	unknown := Availability{protocol.DeviceID([32]byte{9, 10, 11, 12}), false}
+	quality := func(id protocol.DeviceID) connections.Quality {
+		switch id {
+		case slow.ID:
+			return connections.Quality{Requests: 10, Latency: time.Second, Throughput: 1e3}
+		case fast.ID:
+			return connections.Quality{Requests: 10, Latency: 10 * time.Millisecond, Throughput: 1e5}
+		}
+		return connections.Quality{}
+	}
+	na := newDeviceActivity()
+
+	// Devices nothing is known about are tried first.
+	if sel, _ := na.fastest([]Availability{slow, fast, unknown}, 1e4, quality); sel != unknown {
+		t.Errorf("expected the unknown device, got %v", sel)
+	}
+	if sel, _ := na.fastest([]Availability{slow, fast}, 1e4, quality); sel != fast {
+		t.Errorf("expected the fast device, got %v", sel)
+	}
+
+	// The fast device stays preferred while busy, until it has so many
+	// requests outstanding that the slow one would be done sooner.
+	for i := 0; i < 98; i++ {
+		na.using(fast)
+	}
+	if sel, _ := na.fastest([]Availability{slow, fast}, 1e4, quality); sel != fast {
+		t.Errorf("expected the fast device, got %v", sel)
+	}
+	na.using(fast)
+	na.using(fast)
+	if sel, _ := na.fastest([]Availability{slow, fast}, 1e4, quality); sel != slow {
+		t.Errorf("expected the slow device, got %v", sel)
+	}
+}
@@ -34,6 +34,8 @@ type fakeConnection struct {
 	model                    *model
 	indexFn                  func(context.Context, string, []protocol.FileInfo)
 	requestFn                func(ctx context.Context, folder, name string, offset int64, size int, hash []byte, fromTemporary bool) ([]byte, error)
+	batchRequests            int
+	indexSnapshots           int
 	closeFn                  func(error)
 	mut                      sync.Mutex
 }
@@ -82,6 +84,16 @@ func (f *fakeConnection) IndexUpdate(ctx context.Context, folder string, fs []pr
 	return nil
 }
 
+func (f *fakeConnection) IndexSnapshot(ctx context.Context, folder string, fs []protocol.FileInfo, first bool) error {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	f.indexSnapshots++
+	if f.indexFn != nil {
+		f.indexFn(ctx, folder, fs)
+	}
+	return nil
+}
+
 func (f *fakeConnection) Request(ctx context.Context, folder, name string, offset int64, size int, hash []byte, weakHash uint32, fromTemporary bool) ([]byte, error) {
 	f.mut.Lock()
 	defer f.mut.Unlock()
@@ -91,6 +103,23 @@ func (f *fakeConnection) Request(ctx context.Context, folder, name string, offse
 	return f.fileData[name], nil
 }
 
+func (f *fakeConnection) BatchRequest(ctx context.Context, folder string, files []protocol.BatchRequestFile) ([][]byte, []error, error) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	f.batchRequests++
+	datas := make([][]byte, len(files))
+	errs := make([]error, len(files))
+	for i, file := range files {
+		data, ok := f.fileData[file.Name]
+		if !ok {
+			errs[i] = protocol.ErrNoSuchFile
+			continue
+		}
+		datas[i] = data
+	}
+	return datas, errs, nil
+}
+
 func (f *fakeConnection) ClusterConfig(protocol.ClusterConfig) {}
 
 func (f *fakeConnection) Ping() bool {
@@ -116,6 +145,14 @@ func (f *fakeConnection) DownloadProgress(_ context.Context, folder string, upda
 	})
 }
 
+func (f *fakeConnection) ApplicationMessage(_ context.Context, msgType string, data []byte) error {
+	return nil
+}
+
+func (f *fakeConnection) ScanRequest(_ context.Context, folder string, subdirs []string) error {
+	return nil
+}
+
 func (f *fakeConnection) addFileLocked(name string, flags uint32, ftype protocol.FileInfoType, data []byte, version protocol.Vector) {
 	blockSize := protocol.BlockSize(int64(len(data)))
 	blocks, _ := scanner.Blocks(context.TODO(), bytes.NewReader(data), blockSize, int64(len(data)), nil, true)
@@ -195,8 +232,12 @@ func (f *fakeConnection) sendIndexUpdate() {
 }
 
 func addFakeConn(m *model, dev protocol.DeviceID) *fakeConnection {
+	return addFakeConnWithHello(m, dev, protocol.HelloResult{})
+}
+
+func addFakeConnWithHello(m *model, dev protocol.DeviceID, hello protocol.HelloResult) *fakeConnection {
 	fc := &fakeConnection{id: dev, model: m}
-	m.AddConnection(fc, protocol.HelloResult{})
+	m.AddConnection(fc, hello)
 
 	m.ClusterConfig(dev, protocol.ClusterConfig{
 		Folders: []protocol.Folder{
@@ -224,7 +265,9 @@ func newFakeProtoConn(protoConn protocol.Connection) connections.Connection {
 
 // fakeUnderlyingConn implements the methods of connections.Connection that are
 // not implemented by protocol.Connection
-type fakeUnderlyingConn struct{}
+type fakeUnderlyingConn struct {
+	local bool
+}
 
 func (f *fakeUnderlyingConn) RemoteAddr() net.Addr {
 	return &fakeAddr{}
@@ -246,6 +289,10 @@ func (f *fakeUnderlyingConn) Priority() int {
 	return 9000
 }
 
+func (f *fakeUnderlyingConn) IsLocal() bool {
+	return f.local
+}
+
 func (f *fakeUnderlyingConn) String() string {
 	return ""
 }
@@ -0,0 +1,166 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"errors"
+	"path/filepath"
+
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/ignore"
+	"github.com/syncthing/syncthing/lib/osutil"
+	"github.com/syncthing/syncthing/lib/versioner"
+)
+
+var (
+	errPathInvalid  = errors.New("invalid path")
+	errPathIgnored  = errors.New("path is ignored")
+	errPathExists   = errors.New("path already exists")
+	errPathNotFound = errors.New("path does not exist")
+)
+
+// A fileManagerOp is a change made to the contents of a folder on behalf of
+// the user, after which the affected paths are scanned so that the change
+// is announced like any other.
+type fileManagerOp struct {
+	ffs      fs.Filesystem
+	ignores  *ignore.Matcher
+	ver      versioner.Versioner
+	runner   service
+	scanSubs []string
+}
+
+func (m *model) fileManagerOp(folder string) (*fileManagerOp, error) {
+	m.fmut.RLock()
+	defer m.fmut.RUnlock()
+
+	if err := m.checkFolderRunningLocked(folder); err != nil {
+		return nil, err
+	}
+	return &fileManagerOp{
+		ffs:     m.folderCfgs[folder].Filesystem(),
+		ignores: m.folderIgnores[folder],
+		ver:     m.folderVersioners[folder],
+		runner:  m.folderRunners[folder],
+	}, nil
+}
+
+// path returns the name in canonical form, if it is something the user may
+// change, and remembers it to be scanned. Names below a symlink are refused,
+// as changing them would change something outside the folder.
+func (op *fileManagerOp) path(name string) (string, error) {
+	name, err := fs.Canonicalize(osutil.NativeFilename(name))
+	if err != nil || name == "." || fs.IsInternal(name) || fs.IsTemporary(name) {
+		return "", errPathInvalid
+	}
+	if op.ignores.Match(name).IsIgnored() {
+		return "", errPathIgnored
+	}
+	if err := osutil.TraversesSymlink(op.ffs, filepath.Dir(name)); err != nil {
+		return "", err
+	}
+	op.scanSubs = append(op.scanSubs, name)
+	return name, nil
+}
+
+// scan scans the paths that were changed, so that the changes are in the
+// index when the operation returns.
+func (op *fileManagerOp) scan() error {
+	return op.runner.Scan(op.scanSubs)
+}
+
+// MakeDir creates a directory in the folder, along with any missing parent
+// directories.
+func (m *model) MakeDir(folder, name string) error {
+	op, err := m.fileManagerOp(folder)
+	if err != nil {
+		return err
+	}
+	if name, err = op.path(name); err != nil {
+		return err
+	}
+
+	if _, err := op.ffs.Lstat(name); err == nil {
+		return errPathExists
+	}
+	if err := op.ffs.MkdirAll(name, 0755); err != nil {
+		return err
+	}
+	return op.scan()
+}
+
+// RenamePath renames a file or directory in the folder. The new name must
+// not exist already.
+func (m *model) RenamePath(folder, from, to string) error {
+	op, err := m.fileManagerOp(folder)
+	if err != nil {
+		return err
+	}
+	if from, err = op.path(from); err != nil {
+		return err
+	}
+	if to, err = op.path(to); err != nil {
+		return err
+	}
+
+	if _, err := op.ffs.Lstat(from); fs.IsNotExist(err) {
+		return errPathNotFound
+	} else if err != nil {
+		return err
+	}
+	if _, err := op.ffs.Lstat(to); err == nil {
+		return errPathExists
+	}
+	if dir := filepath.Dir(to); dir != "." {
+		if err := op.ffs.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	if err := op.ffs.Rename(from, to); err != nil {
+		return err
+	}
+	return op.scan()
+}
+
+// DeletePath removes a file, or a directory with everything in it, from
+// the folder. Files are archived by the folder's versioner, if it has one.
+func (m *model) DeletePath(folder, name string) error {
+	op, err := m.fileManagerOp(folder)
+	if err != nil {
+		return err
+	}
+	if name, err = op.path(name); err != nil {
+		return err
+	}
+
+	info, err := op.ffs.Lstat(name)
+	if fs.IsNotExist(err) {
+		return errPathNotFound
+	} else if err != nil {
+		return err
+	}
+
+	if op.ver != nil {
+		if info.IsRegular() {
+			err = op.ver.Archive(name)
+		} else if info.IsDir() {
+			err = op.ffs.Walk(name, func(path string, info fs.FileInfo, err error) error {
+				if err != nil || !info.IsRegular() {
+					return err
+				}
+				return op.ver.Archive(path)
+			})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := op.ffs.RemoveAll(name); err != nil {
+		return err
+	}
+	return op.scan()
+}
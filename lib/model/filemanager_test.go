@@ -0,0 +1,92 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestFileManager(t *testing.T) {
+	m, _, fcfg := setupModelWithConnection()
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+
+	exists := func(name string) bool {
+		t.Helper()
+		f, ok := m.CurrentFolderFile("default", filepath.FromSlash(name))
+		return ok && !f.IsDeleted()
+	}
+
+	if err := m.MakeDir("default", "a/b"); err != nil {
+		t.Fatal(err)
+	}
+	if !exists("a") || !exists("a/b") {
+		t.Fatal("expected the new directories to be in the index")
+	}
+	if err := m.MakeDir("default", "a/b"); err != errPathExists {
+		t.Error("expected an error for an existing directory, got", err)
+	}
+
+	if err := m.RenamePath("default", "a/b", "c/d"); err != nil {
+		t.Fatal(err)
+	}
+	if exists("a/b") || !exists("c/d") {
+		t.Error("expected the rename to be in the index")
+	}
+
+	if err := m.DeletePath("default", "c"); err != nil {
+		t.Fatal(err)
+	}
+	if exists("c") || exists("c/d") {
+		t.Error("expected the deletion to be in the index")
+	}
+	if err := m.DeletePath("default", "c"); err != errPathNotFound {
+		t.Error("expected an error for a missing path, got", err)
+	}
+
+	for _, name := range []string{"", "/", "../outside", ".stfolder", "a/.syncthing.b.tmp"} {
+		if err := m.MakeDir("default", name); err != errPathInvalid {
+			t.Errorf("expected %q to be refused, got %v", name, err)
+		}
+	}
+	if err := m.SetIgnores("default", []string{"ignored"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.MakeDir("default", "ignored"); err != errPathIgnored {
+		t.Error("expected an ignored path to be refused, got", err)
+	}
+	if runtime.GOOS != "windows" {
+		outside := createTmpDir()
+		defer os.RemoveAll(outside)
+		if err := os.Mkdir(filepath.Join(outside, "x"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := fcfg.Filesystem().CreateSymlink(outside, "link"); err != nil {
+			t.Fatal(err)
+		}
+		if err := m.MakeDir("default", "link/a"); err == nil {
+			t.Error("expected a path below a symlink to be refused")
+		}
+		if err := m.RenamePath("default", "link/x", "y"); err == nil {
+			t.Error("expected a path below a symlink to be refused")
+		}
+		if err := m.DeletePath("default", "link/x"); err == nil {
+			t.Error("expected a path below a symlink to be refused")
+		}
+		if _, err := os.Lstat(filepath.Join(outside, "a")); !os.IsNotExist(err) {
+			t.Error("expected nothing to be created outside the folder")
+		}
+		if _, err := os.Lstat(filepath.Join(outside, "x")); err != nil {
+			t.Error("expected nothing to be changed outside the folder:", err)
+		}
+	}
+	if err := m.MakeDir("missing", "a"); err != errFolderMissing {
+		t.Error("expected an error for a missing folder, got", err)
+	}
+}
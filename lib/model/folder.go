@@ -36,6 +36,12 @@ import (
 // scanLimiter limits the number of concurrent scans. A limit of zero means no limit.
 var scanLimiter = newByteSemaphore(0)
 
+var errNotPlaceholder = errors.New("file is not a placeholder")
+
+// After waking from sleep a scan is due, as the watcher may have missed
+// changes. It's deferred a little so that reconnecting goes first.
+const wakeScanDelay = 10 * time.Second
+
 type folder struct {
 	suture.Service
 	stateTracker
@@ -52,11 +58,21 @@ type folder struct {
 
 	scanInterval        time.Duration
 	scanTimer           *time.Timer
+	rescanSequence      int64 // local sequence at the last timed scan, for adaptive rescans
 	scanNow             chan rescanRequest
 	scanDelay           chan time.Duration
 	initialScanFinished chan struct{}
+	scanDeferred        bool // the initial full scan was deferred by quick start
+	lowPriorityScan     bool // the running scan uses a single hasher
 	scanErrors          []FileError
+	scrubErrors         map[string]string
 	scanErrorsMut       sync.Mutex
+	hashCache           *hashCache
+	undo                *undoBuffer
+	audit               *db.AuditLog
+	unstable            map[string]struct{} // files to scan once they stop changing
+	unstableTimer       *time.Timer         // nil unless a scan of them is scheduled
+	unstableMut         sync.Mutex
 
 	pullScheduled chan struct{}
 
@@ -64,9 +80,13 @@ type folder struct {
 	watchChan        chan []string
 	restartWatchChan chan struct{}
 	watchErr         error
+	watchAggrMode    func() watchaggregator.Mode // nil when not watching
 	watchMut         sync.Mutex
 
 	puller puller
+
+	// afterScan, if set, is called at the end of every successful scan.
+	afterScan func()
 }
 
 type rescanRequest struct {
@@ -79,6 +99,18 @@ type puller interface {
 }
 
 func newFolder(model *model, fset *db.FileSet, ignores *ignore.Matcher, cfg config.FolderConfiguration, evLogger events.Logger) folder {
+	var undo *undoBuffer
+	if cfg.UndoBufferSize > 0 {
+		undo = newUndoBuffer(cfg.Filesystem(), cfg.MarkerName, cfg.UndoBufferSize)
+	}
+	var audit *db.AuditLog
+	if cfg.AuditLogEntries > 0 {
+		var err error
+		if audit, err = db.NewAuditLog(model.db, cfg.ID); err != nil {
+			l.Warnf("Folder %v: loading audit log: %v", cfg.Description(), err)
+		}
+	}
+
 	return folder{
 		stateTracker:              newStateTracker(cfg.ID, evLogger),
 		FolderConfiguration:       cfg,
@@ -95,12 +127,17 @@ func newFolder(model *model, fset *db.FileSet, ignores *ignore.Matcher, cfg conf
 		scanDelay:           make(chan time.Duration),
 		initialScanFinished: make(chan struct{}),
 		scanErrorsMut:       sync.NewMutex(),
+		unstable:            make(map[string]struct{}),
+		unstableMut:         sync.NewMutex(),
 
 		pullScheduled: make(chan struct{}, 1), // This needs to be 1-buffered so that we queue a pull if we're busy when it comes.
 
 		watchCancel:      func() {},
 		restartWatchChan: make(chan struct{}, 1),
 		watchMut:         sync.NewMutex(),
+
+		undo:  undo,
+		audit: audit,
 	}
 }
 
@@ -115,7 +152,12 @@ func (f *folder) serve(ctx context.Context) {
 
 	defer func() {
 		f.scanTimer.Stop()
+		f.stopUnstableTimer()
 		f.setState(FolderIdle)
+		if f.hashCache != nil {
+			f.hashCache.Close()
+			f.hashCache = nil
+		}
 	}()
 
 	pause := f.basePause()
@@ -126,10 +168,26 @@ func (f *folder) serve(ctx context.Context) {
 		f.startWatch()
 	}
 
+	if f.ScrubIntervalS > 0 {
+		go f.scrubber()
+	}
+
+	if f.TieringColdDays > 0 {
+		go f.tierer()
+	}
+
+	if f.TombstoneRetentionDays > 0 {
+		go f.tombstoneCollector()
+	}
+
 	initialCompleted := f.initialScanFinished
 
+	wakeSub := f.evLogger.Subscribe(events.SystemWoke)
+	defer wakeSub.Unsubscribe()
+
 	pull := func() {
 		startTime := time.Now()
+		f.reconcileRecovery()
 		if f.puller.pull() {
 			// We're good. Don't schedule another pull and reset
 			// the pause interval.
@@ -164,6 +222,7 @@ func (f *folder) serve(ctx context.Context) {
 		case <-initialCompleted:
 			// Initial scan has completed, we should do a pull
 			initialCompleted = nil // never hit this case again
+			f.reconcileRecovery()
 			if !f.puller.pull() {
 				// Pulling failed, try again later.
 				pullFailTimer.Reset(pause)
@@ -181,6 +240,10 @@ func (f *folder) serve(ctx context.Context) {
 			l.Debugln(f, "Delaying scan")
 			f.scanTimer.Reset(next)
 
+		case <-wakeSub.C():
+			l.Debugln(f, "Scheduling scan after wake")
+			f.scanTimer.Reset(wakeScanDelay)
+
 		case fsEvents := <-f.watchChan:
 			l.Debugln(f, "Scan due to watcher", fsEvents)
 			f.scanSubdirs(fsEvents)
@@ -194,6 +257,8 @@ func (f *folder) serve(ctx context.Context) {
 
 func (f *folder) BringToFront(string) {}
 
+func (f *folder) Prioritize(string, bool) {}
+
 func (f *folder) Override() {}
 
 func (f *folder) Revert() {}
@@ -208,6 +273,16 @@ func (f *folder) ignoresUpdated() {
 	}
 }
 
+// ignoredFile returns whether the ignore patterns match the file, taking
+// its size and age into account unless it's a directory, symlink or
+// deleted.
+func ignoredFile(ignores *ignore.Matcher, file db.FileIntf) bool {
+	if file.IsDirectory() || file.IsSymlink() || file.IsDeleted() {
+		return ignores.Match(file.FileName()).IsIgnored()
+	}
+	return ignores.MatchFile(file.FileName(), file.FileSize(), file.ModTime()).IsIgnored()
+}
+
 func (f *folder) SchedulePull() {
 	select {
 	case f.pullScheduled <- struct{}{}:
@@ -338,6 +413,22 @@ func (f *folder) scanSubdirs(subDirs []string) error {
 
 	f.setState(FolderScanning)
 
+	// Full scans are journaled, resuming one that was interrupted.
+	var journal *scanJournal
+	var walkJournal scanner.ScanJournal
+	if len(subDirs) == 0 {
+		journal = newScanJournal(db.NewScanJournal(f.model.db, f.ID))
+		if resumed, err := journal.Start(f.ignores.Hash()); err != nil {
+			l.Debugln(f, "starting scan journal:", err)
+			journal = nil
+		} else {
+			if resumed > 0 {
+				l.Infof("Resuming interrupted scan of folder %s, skipping %d completed directories", f.Description(), resumed)
+			}
+			walkJournal = journal
+		}
+	}
+
 	mtimefs := f.fset.MtimeFS()
 	fchan := scanner.Walk(f.ctx, scanner.Config{
 		Folder:                f.ID,
@@ -348,12 +439,25 @@ func (f *folder) scanSubdirs(subDirs []string) error {
 		Filesystem:            mtimefs,
 		IgnorePerms:           f.IgnorePerms,
 		AutoNormalize:         f.AutoNormalize,
-		Hashers:               f.model.numHashers(f.ID),
+		Normalization:         f.Normalization,
+		Hashers:               f.scanHashers(),
+		DiskLimiter:           f.model.diskLimiter,
+		Disk:                  f.model.hashingDisk(f.ID),
 		ShortID:               f.shortID,
 		ProgressTickIntervalS: f.ScanProgressIntervalS,
 		LocalFlags:            f.localFlags,
 		ModTimeWindow:         f.ModTimeWindow(),
 		EventLogger:           f.evLogger,
+		HashCache:             f.scanHashCache(),
+		SyncXattrs:            f.SyncXattrs,
+		SyncPlatformData:      f.SyncPlatformData,
+		SyncOwnership:         f.SyncOwnership,
+		ProgressFn:            f.markProgress,
+		ProgressStatusFn:      f.setScanProgress,
+		BlockScheme:           f.BlockScheme(),
+		HashAlgorithm:         f.model.folderHashAlgorithm(f.FolderConfiguration),
+		Journal:               walkJournal,
+		StabilityWindow:       time.Duration(f.StabilityWindowS) * time.Second,
 	})
 
 	batchFn := func(fs []protocol.FileInfo) error {
@@ -362,6 +466,11 @@ func (f *folder) scanSubdirs(subDirs []string) error {
 			return err
 		}
 		f.updateLocalsFromScanning(fs)
+		if journal != nil {
+			if err := journal.record(); err != nil {
+				l.Debugln(f, "recording scan journal:", err)
+			}
+		}
 		return nil
 	}
 	// Resolve items which are identical with the global state.
@@ -389,6 +498,8 @@ func (f *folder) scanSubdirs(subDirs []string) error {
 	}
 	batch := newFileInfoBatch(batchFn)
 
+	summary := newScanSummary(f.ScanSummaryPaths)
+
 	// Schedule a pull after scanning, but only if we actually detected any
 	// changes.
 	changes := 0
@@ -399,7 +510,21 @@ func (f *folder) scanSubdirs(subDirs []string) error {
 	}()
 
 	f.clearScanErrors(subDirs)
+	var unstable []string
+	defer func() {
+		f.rescanWhenStable(unstable)
+	}()
 	for res := range fchan {
+		if res.Dir != "" {
+			journal.result(res)
+			continue
+		}
+		if res.Unstable {
+			// Not counted by the journal either, so its directory isn't
+			// completed.
+			unstable = append(unstable, res.Path)
+			continue
+		}
 		if res.Err != nil {
 			f.newScanError(res.Path, res.Err)
 			continue
@@ -408,8 +533,14 @@ func (f *folder) scanSubdirs(subDirs []string) error {
 			return err
 		}
 
+		cur, hasCur := f.fset.Get(protocol.LocalDeviceID, res.File.Name)
+		summary.scanned(cur, hasCur, res.File)
+
 		batch.append(res.File)
 		changes++
+		if journal != nil {
+			journal.result(res)
+		}
 	}
 
 	if err := batch.flush(); err != nil {
@@ -426,6 +557,7 @@ func (f *folder) scanSubdirs(subDirs []string) error {
 	// ignored files.
 	var toIgnore []db.FileInfoTruncated
 	ignoredParent := ""
+	cases := fs.NewCachingCaseChecker(mtimefs)
 	for _, sub := range subDirs {
 		var iterError error
 
@@ -458,7 +590,7 @@ func (f *folder) scanSubdirs(subDirs []string) error {
 				ignoredParent = ""
 			}
 
-			switch ignored := f.ignores.Match(file.Name).IsIgnored(); {
+			switch ignored := ignoredFile(f.ignores, file); {
 			case !file.IsIgnored() && ignored:
 				// File was not ignored at last pass but has been ignored.
 				if file.IsDirectory() {
@@ -485,8 +617,10 @@ func (f *folder) scanSubdirs(subDirs []string) error {
 				// The file is not ignored, deleted or unsupported. Lets check if
 				// it's still here. Simply stat:ing it wont do as there are
 				// tons of corner cases (e.g. parent dir->symlink, missing
-				// permissions)
-				if !osutil.IsDeleted(mtimefs, file.Name) {
+				// permissions). On case insensitive filesystems it is also
+				// found after a case only rename, having been scanned
+				// under the new name.
+				if !osutil.IsDeleted(mtimefs, file.Name) && cases.IsRealCase(file.Name) {
 					if ignoredParent != "" {
 						// Don't ignore parents of this not ignored item
 						toIgnore = toIgnore[:0]
@@ -517,6 +651,7 @@ func (f *folder) scanSubdirs(subDirs []string) error {
 				}
 
 				batch.append(nf)
+				summary.deleted(nf.Name)
 				changes++
 			}
 			return true
@@ -550,13 +685,88 @@ func (f *folder) scanSubdirs(subDirs []string) error {
 		return err
 	}
 
+	if len(subDirs) == 0 && f.hashCache != nil {
+		f.hashCache.maybeGC()
+	}
+	if journal != nil {
+		if err := journal.Finish(); err != nil {
+			l.Debugln(f, "finishing scan journal:", err)
+		}
+	}
+
+	f.evLogger.Log(events.FolderScanSummary, summary.eventData(f.ID))
 	f.ScanCompleted()
 	f.setState(FolderIdle)
+
+	if f.afterScan != nil {
+		f.afterScan()
+	}
 	return nil
 }
 
+// scanHashCache returns the hash cache to use when scanning, opening it if
+// necessary, or nil if the folder doesn't have one. The cache is closed when
+// the folder stops. A new one is only created once the initial scan is
+// done, so that folders that are just being set up or removed again, e.g.
+// after being auto accepted, don't get one.
+func (f *folder) scanHashCache() scanner.HashCache {
+	if f.hashCache == nil {
+		if f.ctx.Err() != nil {
+			return nil
+		}
+		create := false
+		select {
+		case <-f.initialScanFinished:
+			create = true
+		default:
+		}
+		hc, err := openHashCache(f.FolderConfiguration, create)
+		if err != nil {
+			l.Debugln(f, "opening hash cache:", err)
+		}
+		if hc == nil {
+			return nil
+		}
+		f.hashCache = hc
+	}
+	return f.hashCache
+}
+
+func (f *folder) scanHashers() int {
+	if f.lowPriorityScan {
+		return 1
+	}
+	return f.model.numHashers(f.ID)
+}
+
 func (f *folder) scanTimerFired() {
+	select {
+	case <-f.initialScanFinished:
+	default:
+		if f.QuickStart && f.quickStartCheck() {
+			l.Infoln("Completed quick start check of", f.Type.String(), "folder", f.Description())
+			close(f.initialScanFinished)
+			f.scanDeferred = true
+			f.scanTimer.Reset(quickStartScanDelay)
+			return
+		}
+	}
+
+	// The first full scan tells nothing about how often the folder
+	// changes.
+	firstScan := f.scanDeferred
+	select {
+	case <-f.initialScanFinished:
+	default:
+		firstScan = true
+	}
+
+	// The deferred initial scan runs in the background while the folder
+	// is already in use, so it shouldn't hog the CPU.
+	f.lowPriorityScan = f.scanDeferred
 	err := f.scanSubdirs(nil)
+	f.lowPriorityScan = false
+	f.scanDeferred = false
 
 	select {
 	case <-f.initialScanFinished:
@@ -569,6 +779,7 @@ func (f *folder) scanTimerFired() {
 		close(f.initialScanFinished)
 	}
 
+	f.adaptRescanInterval(firstScan)
 	f.Reschedule()
 }
 
@@ -578,10 +789,20 @@ func (f *folder) WatchError() error {
 	return f.watchErr
 }
 
+func (f *folder) WatchAggregationMode() watchaggregator.Mode {
+	f.watchMut.Lock()
+	defer f.watchMut.Unlock()
+	if f.watchAggrMode == nil {
+		return watchaggregator.ModeNormal
+	}
+	return f.watchAggrMode()
+}
+
 // stopWatch immediately aborts watching and may be called asynchronously
 func (f *folder) stopWatch() {
 	f.watchMut.Lock()
 	f.watchCancel()
+	f.watchAggrMode = nil
 	f.watchMut.Unlock()
 	f.setWatchError(nil)
 }
@@ -638,7 +859,10 @@ func (f *folder) monitorWatch(ctx context.Context) {
 				failTimer.Reset(time.Minute)
 				continue
 			}
-			watchaggregator.Aggregate(aggrCtx, eventChan, f.watchChan, f.FolderConfiguration, f.model.cfg, f.evLogger)
+			aggrMode := watchaggregator.Aggregate(aggrCtx, eventChan, f.watchChan, f.FolderConfiguration, f.model.cfg, f.evLogger)
+			f.watchMut.Lock()
+			f.watchAggrMode = aggrMode
+			f.watchMut.Unlock()
 			l.Debugln("Started filesystem watcher for folder", f.Description())
 		case err = <-errChan:
 			f.setWatchError(err)
@@ -715,6 +939,10 @@ func (f *folder) setError(err error) {
 	if err != nil {
 		if oldErr == nil {
 			l.Warnf("Error on folder %s: %v", f.Description(), err)
+			f.evLogger.Log(events.FolderStopped, map[string]interface{}{
+				"folder": f.folderID,
+				"error":  err.Error(),
+			})
 		} else {
 			l.Infof("Error on folder %s changed: %q -> %q", f.Description(), oldErr, err)
 		}
@@ -776,7 +1004,73 @@ outer:
 func (f *folder) Errors() []FileError {
 	f.scanErrorsMut.Lock()
 	defer f.scanErrorsMut.Unlock()
-	return append([]FileError{}, f.scanErrors...)
+	errors := make([]FileError, 0, len(f.scanErrors)+len(f.scrubErrors))
+	for path, err := range f.scrubErrors {
+		errors = append(errors, FileError{path, err})
+	}
+	sort.Sort(fileErrorList(errors))
+	return append(append([]FileError{}, f.scanErrors...), errors...)
+}
+
+// UndoEntries returns the files that can be restored from the undo buffer,
+// most recent first.
+func (f *folder) UndoEntries() ([]UndoEntry, error) {
+	if f.undo == nil {
+		return nil, errNoUndo
+	}
+	return f.undo.Entries(), nil
+}
+
+// Undo restores the given entry from the undo buffer, or the most recent
+// one if id is negative, and scans the restored file.
+func (f *folder) Undo(id int64) (UndoEntry, error) {
+	if f.undo == nil {
+		return UndoEntry{}, errNoUndo
+	}
+	entry, err := f.undo.restore(id, f.IgnorePerms)
+	if err != nil {
+		return UndoEntry{}, err
+	}
+	l.Infof("Folder %s: restored %q from the undo buffer", f.Description(), entry.Name)
+	return entry, f.Scan([]string{entry.Name})
+}
+
+// Hydrate requests the contents of the given placeholder to be pulled. The
+// version of the placeholder is cleared, which makes the global file needed
+// again, and the puller replaces the placeholder by the real file.
+func (f *folder) Hydrate(name string) error {
+	cur, ok := f.fset.Get(protocol.LocalDeviceID, name)
+	if !ok || !cur.IsPlaceholder() {
+		return errNotPlaceholder
+	}
+	if len(cur.Version.Counters) > 0 {
+		cur.Version = protocol.Vector{}
+		f.updateLocals([]protocol.FileInfo{cur})
+	}
+	f.SchedulePull()
+	return nil
+}
+
+// SkippedChanges returns the changes from other devices that weren't applied
+// due to the accept policy. Only folders that pull have any.
+func (f *folder) SkippedChanges() ([]SkippedChange, error) {
+	return nil, nil
+}
+
+// PullerState is empty for folders that don't pull.
+func (f *folder) PullerState(_ int) PullerState {
+	return PullerState{Pulling: []PullingFile{}, Queued: []QueuedFile{}}
+}
+
+// recordUndo keeps a copy of the local file in the undo buffer, if the
+// folder has one, before it's deleted or replaced by a remote change.
+func (f *folder) recordUndo(cur, file protocol.FileInfo, action string) {
+	if f.undo == nil || cur.IsPlaceholder() {
+		return
+	}
+	if err := f.undo.record(cur, action, file.ModifiedBy); err != nil {
+		l.Infof("Folder %s: failed to keep a copy of %q for undo: %v", f.Description(), cur.Name, err)
+	}
 }
 
 // ForceRescan marks the file such that it gets rehashed on next scan and then
@@ -792,12 +1086,14 @@ func (f *folder) updateLocalsFromScanning(fs []protocol.FileInfo) {
 	f.updateLocals(fs)
 
 	f.emitDiskChangeEvents(fs, events.LocalChangeDetected)
+	f.recordAudit(fs, false)
 }
 
 func (f *folder) updateLocalsFromPulling(fs []protocol.FileInfo) {
 	f.updateLocals(fs)
 
 	f.emitDiskChangeEvents(fs, events.RemoteChangeDetected)
+	f.recordAudit(fs, true)
 }
 
 func (f *folder) updateLocals(fs []protocol.FileInfo) {
@@ -816,35 +1112,42 @@ func (f *folder) updateLocals(fs []protocol.FileInfo) {
 	})
 }
 
+// diskChange returns whether the file was added, modified or deleted, and
+// whether it's a file, dir or symlink.
+func diskChange(file protocol.FileInfo) (action, objType string) {
+	objType = "file"
+	action = "modified"
+
+	switch {
+	case file.IsDeleted():
+		action = "deleted"
+
+	// If our local vector is version 1 AND it is the only version
+	// vector so far seen for this file then it is a new file.  Else if
+	// it is > 1 it's not new, and if it is 1 but another shortId
+	// version vector exists then it is new for us but created elsewhere
+	// so the file is still not new but modified by us. Only if it is
+	// truly new do we change this to 'added', else we leave it as
+	// 'modified'.
+	case len(file.Version.Counters) == 1 && file.Version.Counters[0].Value == 1:
+		action = "added"
+	}
+
+	if file.IsSymlink() {
+		objType = "symlink"
+	} else if file.IsDirectory() {
+		objType = "dir"
+	}
+	return action, objType
+}
+
 func (f *folder) emitDiskChangeEvents(fs []protocol.FileInfo, typeOfEvent events.EventType) {
 	for _, file := range fs {
 		if file.IsInvalid() {
 			continue
 		}
 
-		objType := "file"
-		action := "modified"
-
-		switch {
-		case file.IsDeleted():
-			action = "deleted"
-
-		// If our local vector is version 1 AND it is the only version
-		// vector so far seen for this file then it is a new file.  Else if
-		// it is > 1 it's not new, and if it is 1 but another shortId
-		// version vector exists then it is new for us but created elsewhere
-		// so the file is still not new but modified by us. Only if it is
-		// truly new do we change this to 'added', else we leave it as
-		// 'modified'.
-		case len(file.Version.Counters) == 1 && file.Version.Counters[0].Value == 1:
-			action = "added"
-		}
-
-		if file.IsSymlink() {
-			objType = "symlink"
-		} else if file.IsDirectory() {
-			objType = "dir"
-		}
+		action, objType := diskChange(file)
 
 		// Two different events can be fired here based on what EventType is passed into function
 		f.evLogger.Log(typeOfEvent, map[string]string{
@@ -0,0 +1,308 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/osutil"
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/versioner"
+)
+
+// The archive formats ExportFolder can write.
+const (
+	ExportFormatTar = "tar"
+	ExportFormatZip = "zip"
+)
+
+var errExportFormat = errors.New("unknown archive format")
+
+// ExportFolder writes an archive of the local files in the folder under
+// the given prefix to w. If at is non-zero, files are as they were at that
+// time, as far as the versioner kept them: versions replaced after it stand
+// in for the current files, and files created after it are left out. The
+// arguments are checked before anything is written.
+func (m *model) ExportFolder(folder, prefix, format string, at time.Time, w io.Writer) error {
+	m.fmut.RLock()
+	cfg, cfgOk := m.folderCfgs[folder]
+	fset, fsetOk := m.folderFiles[folder]
+	ver := m.folderVersioners[folder]
+	m.fmut.RUnlock()
+	if !cfgOk || !fsetOk {
+		return errFolderMissing
+	}
+	if format != ExportFormatTar && format != ExportFormatZip {
+		return errExportFormat
+	}
+	prefix = osutil.NativeFilename(prefix)
+	if prefix == "." {
+		prefix = ""
+	}
+
+	var versions map[string][]versioner.FileVersion
+	if !at.IsZero() {
+		if ver == nil {
+			return errNoVersioner
+		}
+		var err error
+		if versions, err = ver.GetVersions(); err != nil {
+			return err
+		}
+	}
+
+	var aw archiveWriter
+	if format == ExportFormatZip {
+		aw = &zipArchiveWriter{zip.NewWriter(w)}
+	} else {
+		aw = &tarArchiveWriter{tar.NewWriter(w)}
+	}
+
+	e := &exporter{
+		fs:       cfg.Filesystem(),
+		ver:      ver,
+		versions: make(map[string][]versioner.FileVersion, len(versions)),
+		at:       at,
+		aw:       aw,
+	}
+	for name, vs := range versions {
+		name = osutil.NativeFilename(name)
+		if prefix == "" || name == prefix || fs.IsParent(name, prefix) {
+			e.versions[name] = vs
+		}
+	}
+
+	var err error
+	fset.WithPrefixedHaveTruncated(protocol.LocalDeviceID, prefix, func(fi db.FileIntf) bool {
+		err = e.exportCurrent(fi.(db.FileInfoTruncated))
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Whatever remains was deleted since, but existed back then.
+	names := make([]string, 0, len(e.versions))
+	for name := range e.versions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := e.exportVersion(name); err != nil && err != errNoVersion {
+			return err
+		}
+	}
+
+	return aw.Close()
+}
+
+type exporter struct {
+	fs       fs.Filesystem
+	ver      versioner.Versioner
+	versions map[string][]versioner.FileVersion // not yet exported
+	at       time.Time
+	aw       archiveWriter
+}
+
+func (e *exporter) exportCurrent(file db.FileInfoTruncated) error {
+	if file.IsDeleted() || file.IsInvalid() {
+		return nil
+	}
+	if e.at.IsZero() {
+		return e.exportFile(file)
+	}
+
+	// Directories come implicitly with the files in them, what they
+	// looked like back then isn't recorded.
+	if file.IsDirectory() {
+		return nil
+	}
+	switch err := e.exportVersion(file.Name); {
+	case err != errNoVersion:
+		return err
+	case file.ModTime().After(e.at):
+		// Created or changed since, and nothing kept of before.
+		return nil
+	default:
+		return e.exportFile(file)
+	}
+}
+
+var errNoVersion = errors.New("no version at the given time")
+
+// exportVersion exports the version of the file that was current at the
+// time asked for, returning errNoVersion if the versioner doesn't have it.
+func (e *exporter) exportVersion(name string) error {
+	vs := e.versions[name]
+	delete(e.versions, name)
+
+	// The version current at the time is the first one replaced after it,
+	// if it existed back then.
+	var found *versioner.FileVersion
+	for i := range vs {
+		v := &vs[i]
+		if !v.VersionTime.After(e.at) || v.ModTime.After(e.at) {
+			continue
+		}
+		if found == nil || v.VersionTime.Before(found.VersionTime) {
+			found = v
+		}
+	}
+	if found == nil {
+		return errNoVersion
+	}
+
+	fd, err := e.ver.Open(name, found.VersionTime)
+	if err != nil {
+		return errors.Wrapf(err, "opening version of %s", name)
+	}
+	defer fd.Close()
+	info, err := fd.Stat()
+	if err != nil {
+		return err
+	}
+	return e.aw.addFile(name, info.Mode(), found.ModTime, info.Size(), fd)
+}
+
+// exportFile exports the current state of the item on disk.
+func (e *exporter) exportFile(file db.FileInfoTruncated) error {
+	switch {
+	case file.IsDirectory():
+		return e.aw.addDir(file.Name, fs.FileMode(file.Permissions&0777), file.ModTime())
+
+	case file.IsSymlink():
+		target, err := e.fs.ReadSymlink(file.Name)
+		if err != nil {
+			return errors.Wrapf(err, "reading %s", file.Name)
+		}
+		return e.aw.addSymlink(file.Name, target, file.ModTime())
+
+	default:
+		fd, err := e.fs.Open(file.Name)
+		if fs.IsNotExist(err) {
+			// Removed since the last scan.
+			return nil
+		} else if err != nil {
+			return errors.Wrapf(err, "opening %s", file.Name)
+		}
+		defer fd.Close()
+		info, err := fd.Stat()
+		if err != nil {
+			return err
+		}
+		return e.aw.addFile(file.Name, info.Mode(), info.ModTime(), info.Size(), fd)
+	}
+}
+
+// An archiveWriter adds items to an archive. Names are native paths.
+type archiveWriter interface {
+	addDir(name string, mode fs.FileMode, modTime time.Time) error
+	addSymlink(name, target string, modTime time.Time) error
+	// addFile adds a file of the given size, read from r, which must have
+	// at least that much.
+	addFile(name string, mode fs.FileMode, modTime time.Time, size int64, r io.Reader) error
+	Close() error
+}
+
+type tarArchiveWriter struct {
+	tw *tar.Writer
+}
+
+func (a *tarArchiveWriter) addDir(name string, mode fs.FileMode, modTime time.Time) error {
+	return a.tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeDir,
+		Name:     filepath.ToSlash(name) + "/",
+		Mode:     int64(mode & fs.ModePerm),
+		ModTime:  modTime,
+	})
+}
+
+func (a *tarArchiveWriter) addSymlink(name, target string, modTime time.Time) error {
+	return a.tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeSymlink,
+		Name:     filepath.ToSlash(name),
+		Linkname: filepath.ToSlash(target),
+		Mode:     0777,
+		ModTime:  modTime,
+	})
+}
+
+func (a *tarArchiveWriter) addFile(name string, mode fs.FileMode, modTime time.Time, size int64, r io.Reader) error {
+	err := a.tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     filepath.ToSlash(name),
+		Mode:     int64(mode & fs.ModePerm),
+		ModTime:  modTime,
+		Size:     size,
+	})
+	if err != nil {
+		return err
+	}
+	// The header promised this many bytes, no more and no less.
+	if n, err := io.Copy(a.tw, io.LimitReader(r, size)); err != nil {
+		return errors.Wrapf(err, "reading %s", name)
+	} else if n != size {
+		return fmt.Errorf("%s: changed while exporting", name)
+	}
+	return nil
+}
+
+func (a *tarArchiveWriter) Close() error {
+	return a.tw.Close()
+}
+
+type zipArchiveWriter struct {
+	zw *zip.Writer
+}
+
+func (a *zipArchiveWriter) addDir(name string, mode fs.FileMode, modTime time.Time) error {
+	hdr := &zip.FileHeader{Name: filepath.ToSlash(name) + "/", Modified: modTime}
+	hdr.SetMode(os.ModeDir | os.FileMode(mode&fs.ModePerm))
+	_, err := a.zw.CreateHeader(hdr)
+	return err
+}
+
+func (a *zipArchiveWriter) addSymlink(name, target string, modTime time.Time) error {
+	// Zip has no symlinks as such; by convention they're files with the
+	// target as contents and the symlink mode bit set.
+	hdr := &zip.FileHeader{Name: filepath.ToSlash(name), Modified: modTime}
+	hdr.SetMode(os.ModeSymlink | 0777)
+	w, err := a.zw.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, filepath.ToSlash(target))
+	return err
+}
+
+func (a *zipArchiveWriter) addFile(name string, mode fs.FileMode, modTime time.Time, size int64, r io.Reader) error {
+	hdr := &zip.FileHeader{Name: filepath.ToSlash(name), Modified: modTime, Method: zip.Deflate}
+	hdr.SetMode(os.FileMode(mode & fs.ModePerm))
+	w, err := a.zw.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, io.LimitReader(r, size)); err != nil {
+		return errors.Wrapf(err, "reading %s", name)
+	}
+	return nil
+}
+
+func (a *zipArchiveWriter) Close() error {
+	return a.zw.Close()
+}
@@ -0,0 +1,142 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/config"
+)
+
+func TestExportFolder(t *testing.T) {
+	dir := createTmpDir()
+	defer os.RemoveAll(dir)
+
+	fcfg := testFolderConfig(dir)
+	fcfg.Versioning.Type = "simple"
+	filesystem := fcfg.Filesystem()
+
+	old := time.Date(2019, 6, 1, 0, 0, 0, 0, time.Local)
+	for name, mtime := range map[string]time.Time{
+		"a.txt":                                time.Now(),
+		"b.txt":                                time.Now(),
+		"sub/c.txt":                            old,
+		".stversions/a~20200101-000000.txt":    old,
+		".stversions/gone~20200101-000000.txt": old,
+	} {
+		name = filepath.FromSlash(name)
+		must(t, filesystem.MkdirAll(filepath.Dir(name), 0755))
+		fd, err := filesystem.Create(name)
+		must(t, err)
+		_, err = fd.Write([]byte(name))
+		must(t, err)
+		must(t, fd.Close())
+		must(t, filesystem.Chtimes(name, mtime, mtime))
+	}
+
+	m := setupModel(createTmpWrapper(config.Configuration{Folders: []config.FolderConfiguration{fcfg}}))
+	defer cleanupModel(m)
+	must(t, m.ScanFolder(fcfg.ID))
+
+	// As things are now, as a tar archive.
+	buf := new(bytes.Buffer)
+	must(t, m.ExportFolder(fcfg.ID, "", ExportFormatTar, time.Time{}, buf))
+	got := make(map[string]string)
+	tr := tar.NewReader(buf)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		must(t, err)
+		bs, err := ioutil.ReadAll(tr)
+		must(t, err)
+		got[hdr.Name] = string(bs)
+	}
+	expectExported(t, got, map[string]string{
+		"a.txt":     "a.txt",
+		"b.txt":     "b.txt",
+		"sub/":      "",
+		"sub/c.txt": filepath.FromSlash("sub/c.txt"),
+	})
+
+	// As things were at the end of 2019, only what's under sub, as a zip
+	// archive.
+	buf.Reset()
+	must(t, m.ExportFolder(fcfg.ID, "sub", ExportFormatZip, time.Date(2019, 12, 1, 0, 0, 0, 0, time.Local), buf))
+	got = readZip(t, buf.Bytes())
+	expectExported(t, got, map[string]string{
+		"sub/c.txt": filepath.FromSlash("sub/c.txt"),
+	})
+
+	// The whole folder back then, with the old version of a and the file
+	// that has been deleted since.
+	buf.Reset()
+	must(t, m.ExportFolder(fcfg.ID, "", ExportFormatZip, time.Date(2019, 12, 1, 0, 0, 0, 0, time.Local), buf))
+	got = readZip(t, buf.Bytes())
+	expectExported(t, got, map[string]string{
+		"a.txt":     filepath.FromSlash(".stversions/a~20200101-000000.txt"),
+		"gone.txt":  filepath.FromSlash(".stversions/gone~20200101-000000.txt"),
+		"sub/c.txt": filepath.FromSlash("sub/c.txt"),
+	})
+
+	if err := m.ExportFolder(fcfg.ID, "", "rar", time.Time{}, buf); err != errExportFormat {
+		t.Error("expected errExportFormat, got", err)
+	}
+	if err := m.ExportFolder("nonexistent", "", ExportFormatTar, time.Time{}, buf); err != errFolderMissing {
+		t.Error("expected errFolderMissing, got", err)
+	}
+}
+
+func TestExportFolderNoVersioner(t *testing.T) {
+	w, fcfg := tmpDefaultWrapper()
+	m := setupModel(w)
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+
+	if err := m.ExportFolder(fcfg.ID, "", ExportFormatTar, time.Now(), ioutil.Discard); err != errNoVersioner {
+		t.Error("expected errNoVersioner, got", err)
+	}
+}
+
+func readZip(t *testing.T, bs []byte) map[string]string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(bs), int64(len(bs)))
+	must(t, err)
+	got := make(map[string]string)
+	for _, f := range zr.File {
+		fd, err := f.Open()
+		must(t, err)
+		content, err := ioutil.ReadAll(fd)
+		fd.Close()
+		must(t, err)
+		got[f.Name] = string(content)
+	}
+	return got
+}
+
+func expectExported(t *testing.T, got, expected map[string]string) {
+	t.Helper()
+	for name, content := range expected {
+		if c, ok := got[name]; !ok {
+			t.Errorf("%s is missing", name)
+		} else if c != content {
+			t.Errorf("%s: got %q, expected %q", name, c, content)
+		}
+		delete(got, name)
+	}
+	for name := range got {
+		t.Errorf("unexpected %s", name)
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"path/filepath"
+
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/scanner"
+)
+
+// A scanJournal follows the results of a full scan to find the directories
+// it completes: those that were walked, with the results for all items in
+// them and their subdirectories committed to the database. These are
+// recorded in the database, so that an interrupted scan can resume without
+// walking them again.
+type scanJournal struct {
+	*db.ScanJournal
+	dirs map[string]*journalDir
+	done []string // completed, but not yet recorded
+}
+
+type journalDir struct {
+	items    int  // results received for the items directly in it
+	expected int  // results to expect, once walked
+	walked   bool // the walk has left it
+	children int  // walked subdirectories not yet completed
+}
+
+func newScanJournal(j *db.ScanJournal) *scanJournal {
+	return &scanJournal{
+		ScanJournal: j,
+		dirs:        make(map[string]*journalDir),
+	}
+}
+
+func (j *scanJournal) dir(name string) *journalDir {
+	d, ok := j.dirs[name]
+	if !ok {
+		d = &journalDir{}
+		j.dirs[name] = d
+	}
+	return d
+}
+
+// result accounts for a result of the walk, once it has been added to the
+// batch of files to commit.
+func (j *scanJournal) result(res scanner.ScanResult) {
+	if res.Dir == "" {
+		// Errors are not counted, keeping the directory from completing.
+		if res.Err == nil {
+			name := filepath.Dir(res.File.Name)
+			j.dir(name).items++
+			j.check(name)
+		}
+		return
+	}
+	d := j.dir(res.Dir)
+	d.walked = true
+	d.expected = res.Items
+	if res.Dir != "." {
+		j.dir(filepath.Dir(res.Dir)).children++
+	}
+	j.check(res.Dir)
+}
+
+// check marks the directory completed if it is, and then its parent if
+// that completes with it.
+func (j *scanJournal) check(name string) {
+	for {
+		d := j.dirs[name]
+		if !d.walked || d.items != d.expected || d.children > 0 {
+			return
+		}
+		delete(j.dirs, name)
+		j.done = append(j.done, name)
+		if name == "." {
+			return
+		}
+		name = filepath.Dir(name)
+		j.dirs[name].children--
+	}
+}
+
+// record records the completed directories, once the results for them
+// have been committed.
+func (j *scanJournal) record() error {
+	if err := j.Complete(j.done); err != nil {
+		return err
+	}
+	j.done = j.done[:0]
+	return nil
+}
@@ -0,0 +1,42 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/scanner"
+)
+
+func TestScanJournalCompletion(t *testing.T) {
+	j := newScanJournal(nil)
+	file := func(name string) scanner.ScanResult {
+		return scanner.ScanResult{File: protocol.FileInfo{Name: name}}
+	}
+	ab := filepath.Join("a", "b")
+
+	j.result(file("a"))
+	j.result(file(ab))
+	j.result(scanner.ScanResult{Dir: ab})
+	// Two files in a are being hashed when the walk leaves it.
+	j.result(scanner.ScanResult{Dir: "a", Items: 3})
+	j.result(file(filepath.Join("a", "1")))
+	j.result(scanner.ScanResult{Dir: ".", Items: 1})
+	if expected := []string{ab}; !reflect.DeepEqual(j.done, expected) {
+		t.Fatalf("completed %v, expected %v", j.done, expected)
+	}
+
+	// Once the last one is hashed, a and then the root complete.
+
+	j.result(file(filepath.Join("a", "2")))
+	if expected := []string{ab, "a", "."}; !reflect.DeepEqual(j.done, expected) {
+		t.Errorf("completed %v, expected %v", j.done, expected)
+	}
+}
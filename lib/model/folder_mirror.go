@@ -0,0 +1,45 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/ignore"
+	"github.com/syncthing/syncthing/lib/versioner"
+)
+
+func init() {
+	folderFactories[config.FolderTypeMirror] = newMirrorFolder
+}
+
+// A mirrorFolder is a receive only folder that doesn't wait for the user to
+// revert local changes: any that a scan finds are reverted right away, so
+// that locally modified or deleted files are restored from the global
+// version and locally added ones are removed. It's intended for
+// distributing things like configuration, where the local copy must always
+// match what the cluster has.
+type mirrorFolder struct {
+	*receiveOnlyFolder
+}
+
+func newMirrorFolder(model *model, fset *db.FileSet, ignores *ignore.Matcher, cfg config.FolderConfiguration, ver versioner.Versioner, fs fs.Filesystem, evLogger events.Logger) service {
+	f := &mirrorFolder{newReceiveOnlyFolder(model, fset, ignores, cfg, ver, fs, evLogger).(*receiveOnlyFolder)}
+	f.afterScan = f.revertLocalChanges
+	return f
+}
+
+func (f *mirrorFolder) revertLocalChanges() {
+	changed := f.fset.ReceiveOnlyChangedSize()
+	if changed.TotalItems() == 0 {
+		return
+	}
+	l.Infof("Folder %v is a mirror; reverting %d locally changed items", f.Description(), changed.TotalItems())
+	f.Revert()
+}
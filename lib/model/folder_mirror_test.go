@@ -0,0 +1,91 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+func TestMirrorRevertsAfterScan(t *testing.T) {
+	m, f := setupROFolderOfType(config.FolderTypeMirror)
+	ffs := f.Filesystem()
+	defer cleanupModelAndRemoveDir(m, ffs.URI())
+
+	must(t, ffs.MkdirAll(".stfolder", 0755))
+	must(t, ffs.MkdirAll("unknownDir", 0755))
+	must(t, ioutil.WriteFile(filepath.Join(ffs.URI(), "unknownDir/unknownFile"), []byte("hello\n"), 0644))
+
+	knownFiles := setupKnownFiles(t, ffs, []byte("hello\n"))
+	m.Index(device1, "ro", knownFiles)
+	f.updateLocalsFromScanning(knownFiles)
+
+	// The scan finds the local additions, which are reverted right away
+	// without anyone asking for it.
+
+	m.startFolder("ro")
+	m.ScanFolder("ro")
+
+	for _, p := range []string{"unknownDir", "unknownDir/unknownFile"} {
+		if _, err := ffs.Stat(p); !fs.IsNotExist(err) {
+			t.Error("Unexpected existing thing:", p)
+		}
+	}
+	if _, err := ffs.Stat("knownDir/knownFile"); err != nil {
+		t.Error("Unexpected error:", err)
+	}
+
+	if size := m.ReceiveOnlyChangedSize("ro"); size.TotalItems() != 0 {
+		t.Errorf("ROChanged: expected nothing: %+v", size)
+	}
+	size := m.LocalSize("ro")
+	if size.Files != 1 || size.Directories != 1 {
+		t.Errorf("Local: expected 1 file and 1 directory: %+v", size)
+	}
+}
+
+func TestMirrorReportsLocalChanges(t *testing.T) {
+	m, f := setupROFolderOfType(config.FolderTypeMirror)
+	defer cleanupModelAndRemoveDir(m, f.Filesystem().URI())
+
+	// A local change that hasn't been reverted yet, as between a scan and
+	// the revert following it.
+	f.updateLocalsFromScanning([]protocol.FileInfo{{
+		Name:       "changed",
+		Type:       protocol.FileInfoTypeFile,
+		Size:       5,
+		Version:    protocol.Vector{}.Update(myID.Short()),
+		LocalFlags: protocol.FlagLocalReceiveOnly,
+	}})
+
+	if files := m.LocalChangedFiles("ro", 1, 10); len(files) != 1 || files[0].Name != "changed" {
+		t.Errorf("expected the changed file, got %v", files)
+	}
+
+	// The folder isn't running, lest it revert the change, which the
+	// summary would otherwise complain about.
+	summary, err := NewFolderSummaryService(m.cfg, notRunningModel{m}, myID, m.evLogger).Summary("ro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed := summary["receiveOnlyChangedFiles"]; changed != int32(1) {
+		t.Errorf("expected one changed file in the summary, got %v", changed)
+	}
+}
+
+type notRunningModel struct {
+	*model
+}
+
+func (notRunningModel) FolderErrors(string) ([]FileError, error) {
+	return nil, nil
+}
@@ -0,0 +1,82 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"time"
+
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/rand"
+)
+
+const (
+	quickStartRecent = 1000 // number of most recently changed files checked
+	quickStartSample = 1000 // number of randomly chosen files checked
+	// The full scan is deferred by this long, to let the cluster catch up
+	// with us first.
+	quickStartScanDelay = time.Minute
+)
+
+// quickStartCheck scans the most recently changed files, which are the most
+// likely to have changed again while we were down, and a random sample of
+// the rest instead of the whole folder. It returns false if a full scan is
+// needed before the index can be trusted, either because there is no index
+// yet or because the random sample turned up changes.
+func (f *folder) quickStartCheck() bool {
+	seq := f.fset.Sequence(protocol.LocalDeviceID)
+	if seq == 0 {
+		return false
+	}
+
+	var recent []string
+	start := seq - quickStartRecent + 1
+	if start < 1 {
+		start = 1
+	}
+	f.fset.WithHaveSequence(start, func(fi db.FileIntf) bool {
+		recent = append(recent, fi.FileName())
+		return true
+	})
+	if len(recent) > 0 {
+		if err := f.scanSubdirs(recent); err != nil {
+			return false
+		}
+	}
+
+	sample := f.randomSample(seq)
+	if len(sample) == 0 {
+		return true
+	}
+	before := f.fset.Sequence(protocol.LocalDeviceID)
+	if err := f.scanSubdirs(sample); err != nil {
+		return false
+	}
+	if f.fset.Sequence(protocol.LocalDeviceID) != before {
+		l.Infof("Quick start check of folder %v found changes in a random sample of files; doing a full scan", f.Description())
+		return false
+	}
+	return true
+}
+
+// randomSample returns the names of up to quickStartSample random files out
+// of those with sequence numbers up to seq. It seeks to random sequence
+// numbers in the index, so it doesn't need to go through the whole of it.
+func (f *folder) randomSample(seq int64) []string {
+	seen := make(map[string]struct{}, quickStartSample)
+	sample := make([]string, 0, quickStartSample)
+	for i := 0; i < quickStartSample && int64(i) < seq; i++ {
+		f.fset.WithHaveSequence(rand.Int63()%seq+1, func(fi db.FileIntf) bool {
+			if _, ok := seen[fi.FileName()]; !ok {
+				seen[fi.FileName()] = struct{}{}
+				sample = append(sample, fi.FileName())
+			}
+			return false
+		})
+	}
+	return sample
+}
@@ -311,10 +311,14 @@ func setupKnownFiles(t *testing.T, ffs fs.Filesystem, data []byte) []protocol.Fi
 }
 
 func setupROFolder() (*model, *sendOnlyFolder) {
+	return setupROFolderOfType(config.FolderTypeReceiveOnly)
+}
+
+func setupROFolderOfType(folderType config.FolderType) (*model, *sendOnlyFolder) {
 	w := createTmpWrapper(defaultCfg)
 	fcfg := testFolderConfigTmp()
 	fcfg.ID = "ro"
-	fcfg.Type = config.FolderTypeReceiveOnly
+	fcfg.Type = folderType
 	w.SetFolder(fcfg)
 
 	m := newModel(w, myID, "syncthing", "dev", db.NewLowlevel(backend.OpenMemory()), nil)
@@ -0,0 +1,70 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"time"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// The bounds of adaptive rescan intervals, unless configured otherwise.
+const (
+	defaultRescanIntervalMin = time.Minute
+	defaultRescanIntervalMax = 24 * time.Hour
+)
+
+// adaptRescanInterval lengthens the rescan interval if nothing changed in
+// the folder since the last timed scan, be it by scanning, watching or
+// pulling, and shortens it otherwise. It does nothing unless adaptive
+// rescans are enabled.
+func (f *folder) adaptRescanInterval(firstScan bool) {
+	if !f.AdaptiveRescan || f.RescanIntervalS == 0 {
+		return
+	}
+
+	seq := f.fset.Sequence(protocol.LocalDeviceID)
+	changed := seq != f.rescanSequence
+	f.rescanSequence = seq
+	if firstScan {
+		return
+	}
+
+	min := time.Duration(f.RescanIntervalMinS) * time.Second
+	if min <= 0 {
+		min = defaultRescanIntervalMin
+	}
+	max := time.Duration(f.RescanIntervalMaxS) * time.Second
+	if max <= 0 {
+		max = defaultRescanIntervalMax
+	}
+
+	interval := nextRescanInterval(f.scanInterval, min, max, changed)
+	if interval != f.scanInterval {
+		l.Debugf("%v adapting rescan interval from %v to %v (changed: %v)", f, f.scanInterval, interval, changed)
+		f.scanInterval = interval
+	}
+}
+
+// nextRescanInterval returns the interval halved if the folder changed and
+// doubled otherwise, within the bounds.
+func nextRescanInterval(cur, min, max time.Duration, changed bool) time.Duration {
+	if max < min {
+		max = min
+	}
+	next := cur * 2
+	if changed {
+		next = cur / 2
+	}
+	if next < min {
+		return min
+	}
+	if next > max {
+		return max
+	}
+	return next
+}
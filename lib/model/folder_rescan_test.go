@@ -0,0 +1,70 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+func TestNextRescanInterval(t *testing.T) {
+	cases := []struct {
+		cur      time.Duration
+		changed  bool
+		expected time.Duration
+	}{
+		{time.Hour, false, 2 * time.Hour},
+		{time.Hour, true, 30 * time.Minute},
+		{20 * time.Hour, false, 24 * time.Hour},
+		{90 * time.Second, true, time.Minute},
+	}
+	for _, tc := range cases {
+		if next := nextRescanInterval(tc.cur, time.Minute, 24*time.Hour, tc.changed); next != tc.expected {
+			t.Errorf("nextRescanInterval(%v, %v) = %v, expected %v", tc.cur, tc.changed, next, tc.expected)
+		}
+	}
+}
+
+func TestAdaptRescanInterval(t *testing.T) {
+	m, f := setupSendReceiveFolder()
+	defer cleanupSRFolder(f, m)
+
+	f.AdaptiveRescan = true
+	f.RescanIntervalS = 3600
+	f.RescanIntervalMinS = 600
+	f.scanInterval = time.Hour
+
+	// The first scan only sets the baseline.
+	file := protocol.FileInfo{Name: "a", Version: protocol.Vector{}.Update(myID.Short())}
+	f.updateLocalsFromScanning([]protocol.FileInfo{file})
+	f.adaptRescanInterval(true)
+	if f.scanInterval != time.Hour {
+		t.Errorf("expected the interval to remain, got %v", f.scanInterval)
+	}
+
+	f.adaptRescanInterval(false)
+	if f.scanInterval != 2*time.Hour {
+		t.Errorf("expected the interval to double without changes, got %v", f.scanInterval)
+	}
+
+	for _, expected := range []time.Duration{time.Hour, 30 * time.Minute, 15 * time.Minute, 10 * time.Minute} {
+		file.Version = file.Version.Update(myID.Short())
+		f.updateLocalsFromScanning([]protocol.FileInfo{file})
+		f.adaptRescanInterval(false)
+		if f.scanInterval != expected {
+			t.Errorf("expected the interval to become %v after a change, got %v", expected, f.scanInterval)
+		}
+	}
+
+	f.AdaptiveRescan = false
+	f.adaptRescanInterval(false)
+	if f.scanInterval != 10*time.Minute {
+		t.Errorf("expected no change when not adaptive, got %v", f.scanInterval)
+	}
+}
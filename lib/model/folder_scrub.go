@@ -0,0 +1,275 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/scanner"
+)
+
+// The rate at which files are read when scrubbing, unless configured
+// otherwise. Low enough to not get in the way of anything else.
+const defaultScrubRateKiBs = 1024
+
+var errScrubVersionMismatch = errors.New("local file is not the global version")
+
+// scrubber periodically reads back all files in the folder and compares
+// their contents to the block hashes in the database, to find corruption
+// that happened without the modification time or size changing and hence
+// goes unnoticed by scanning.
+func (f *folder) scrubber() {
+	interval := time.Duration(f.ScrubIntervalS) * time.Second
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+		case <-f.ctx.Done():
+			return
+		}
+
+		if err := f.CheckHealth(); err != nil {
+			l.Debugln(f, "skipping scrub:", err)
+		} else {
+			f.scrub(f.ctx)
+		}
+
+		timer.Reset(interval)
+	}
+}
+
+// scrub makes one rate limited pass over all files in the folder.
+func (f *folder) scrub(ctx context.Context) {
+	kibs := f.ScrubRateKiBs
+	if kibs <= 0 {
+		kibs = defaultScrubRateKiBs
+	}
+	limiter := rate.NewLimiter(rate.Limit(kibs*1024), protocol.MaxBlockSize)
+
+	// Collect the names first to not keep a database snapshot open for
+	// the duration of the whole pass.
+	var names []string
+	f.fset.WithHaveTruncated(protocol.LocalDeviceID, func(fi db.FileIntf) bool {
+		if !fi.IsDirectory() && !fi.IsSymlink() && !fi.IsDeleted() && !fi.IsInvalid() {
+			names = append(names, fi.FileName())
+		}
+		return true
+	})
+
+	l.Debugf("%v scrubbing %d files at %d KiB/s", f, len(names), kibs)
+	start := time.Now()
+
+	for _, name := range names {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		f.scrubFile(ctx, name, limiter)
+	}
+
+	l.Debugf("%v scrub of %d files done in %v", f, len(names), time.Since(start))
+}
+
+func (f *folder) scrubFile(ctx context.Context, name string, limiter *rate.Limiter) {
+	file, ok := f.fset.Get(protocol.LocalDeviceID, name)
+	if !ok || file.IsDirectory() || file.IsSymlink() || file.IsDeleted() || file.IsInvalid() {
+		f.clearScrubError(name)
+		return
+	}
+
+	if tfs, ok := f.Filesystem().(fs.TieredFilesystem); ok && tfs.IsOffloaded(name) {
+		// Reading it would bring it back, defeating the point.
+		f.clearScrubError(name)
+		return
+	}
+
+	mtimefs := f.fset.MtimeFS()
+	corrupt, err := f.scrubBlocks(ctx, mtimefs, file, limiter)
+	if err != nil {
+		// The file changed or disappeared, which is for the scanner to
+		// deal with, or we're shutting down.
+		l.Debugln(f, "scrub", name, err)
+		f.clearScrubError(name)
+		return
+	}
+	if len(corrupt) == 0 {
+		f.clearScrubError(name)
+		return
+	}
+
+	l.Warnf("Scrubbing folder %s: %d of %d blocks of %q do not match their expected hash", f.Description(), len(corrupt), len(file.Blocks), name)
+
+	repaired := false
+	if f.ScrubAutoRepair {
+		if err := f.repairBlocks(ctx, mtimefs, file, corrupt); err != nil {
+			l.Infof("Scrubbing folder %s: failed to repair %q: %v", f.Description(), name, err)
+		} else {
+			l.Infof("Scrubbing folder %s: repaired %q", f.Description(), name)
+			repaired = true
+		}
+	}
+
+	f.evLogger.Log(events.LocalCorruptionDetected, map[string]interface{}{
+		"folder":   f.ID,
+		"item":     name,
+		"blocks":   len(corrupt),
+		"repaired": repaired,
+	})
+
+	if repaired {
+		f.clearScrubError(name)
+	} else {
+		f.setScrubError(name, fmt.Errorf("data corruption: %d blocks do not match their expected hash", len(corrupt)))
+	}
+}
+
+// scrubBlocks returns the indexes of the blocks of file whose contents on
+// disk don't match their hash. An error is returned if the file isn't the
+// one in the database, before or after reading it.
+func (f *folder) scrubBlocks(ctx context.Context, ffs fs.Filesystem, file protocol.FileInfo, limiter *rate.Limiter) ([]int, error) {
+	if err := f.checkScrubbedFile(ffs, file); err != nil {
+		return nil, err
+	}
+
+	fd, err := ffs.Open(file.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	buf := protocol.BufferPool.Get(file.BlockSize())
+	defer func() {
+		protocol.BufferPool.Put(buf)
+	}()
+
+	var corrupt []int
+	for i, block := range file.Blocks {
+		if err := limiter.WaitN(ctx, int(block.Size)); err != nil {
+			return nil, err
+		}
+		buf = protocol.BufferPool.Upgrade(buf, int(block.Size))
+		if _, err := fd.ReadAt(buf, block.Offset); err != nil && err != io.EOF {
+			// Failing to read is as bad as reading garbage.
+			l.Debugln(f, "scrub read", file.Name, block.Offset, err)
+			corrupt = append(corrupt, i)
+			continue
+		}
+		if !scanner.Validate(buf, block.Hash, block.WeakHash, file.HashAlgorithm) {
+			corrupt = append(corrupt, i)
+		}
+	}
+
+	if err := f.checkScrubbedFile(ffs, file); err != nil {
+		return nil, err
+	}
+	return corrupt, nil
+}
+
+// checkScrubbedFile returns an error if the file on disk is not the one
+// described by file, going by size and modification time.
+func (f *folder) checkScrubbedFile(ffs fs.Filesystem, file protocol.FileInfo) error {
+	stat, err := ffs.Lstat(file.Name)
+	if err != nil {
+		return err
+	}
+	statItem, err := scanner.CreateFileInfo(stat, file.Name, ffs)
+	if err != nil {
+		return err
+	}
+	if !statItem.IsEquivalentOptional(file, f.ModTimeWindow(), f.IgnorePerms, true, protocol.LocalAllFlags) {
+		return errModified
+	}
+	return nil
+}
+
+// repairBlocks fetches the given blocks from other devices and writes them
+// in place, restoring the modification time afterwards. This is only
+// possible when the local file is the global version, as otherwise the
+// other devices don't have the blocks we need.
+func (f *folder) repairBlocks(ctx context.Context, ffs fs.Filesystem, file protocol.FileInfo, corrupt []int) error {
+	global, ok := f.fset.GetGlobal(file.Name)
+	if !ok || !global.Version.Equal(file.Version) {
+		return errScrubVersionMismatch
+	}
+
+	bufs := make([][]byte, len(corrupt))
+	for i, idx := range corrupt {
+		buf, err := f.fetchBlock(ctx, file, file.Blocks[idx])
+		if err != nil {
+			return err
+		}
+		bufs[i] = buf
+	}
+
+	// Make sure nothing changed while we were fetching.
+	if err := f.checkScrubbedFile(ffs, file); err != nil {
+		return err
+	}
+
+	fd, err := ffs.OpenFile(file.Name, fs.OptReadWrite, 0)
+	if err != nil {
+		return err
+	}
+	for i, idx := range corrupt {
+		if _, err := fd.WriteAt(bufs[i], file.Blocks[idx].Offset); err != nil {
+			fd.Close()
+			return err
+		}
+	}
+	if err := fd.Close(); err != nil {
+		return err
+	}
+
+	return ffs.Chtimes(file.Name, file.ModTime(), file.ModTime())
+}
+
+// fetchBlock requests the block from each device that has it until one
+// returns the expected data.
+func (f *folder) fetchBlock(ctx context.Context, file protocol.FileInfo, block protocol.BlockInfo) ([]byte, error) {
+	lastError := errNoDevice
+	for _, avail := range f.model.Availability(f.ID, file, block) {
+		buf, err := f.model.requestGlobal(ctx, avail.ID, f.ID, file.Name, block.Offset, int(block.Size), block.Hash, block.WeakHash, avail.FromTemporary)
+		if err == nil {
+			err = verifyBuffer(buf, block, file.HashAlgorithm)
+		}
+		if err != nil {
+			l.Debugln(f, "scrub repair request", file.Name, block.Offset, avail.ID, err)
+			lastError = err
+			continue
+		}
+		return buf, nil
+	}
+	return nil, lastError
+}
+
+func (f *folder) setScrubError(path string, err error) {
+	f.scanErrorsMut.Lock()
+	if f.scrubErrors == nil {
+		f.scrubErrors = make(map[string]string)
+	}
+	f.scrubErrors[path] = err.Error()
+	f.scanErrorsMut.Unlock()
+}
+
+func (f *folder) clearScrubError(path string) {
+	f.scanErrorsMut.Lock()
+	delete(f.scrubErrors, path)
+	f.scanErrorsMut.Unlock()
+}
@@ -0,0 +1,83 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/scanner"
+)
+
+func TestScrubDetectsCorruption(t *testing.T) {
+	m, f := setupSendReceiveFolder()
+	defer cleanupSRFolder(f, m)
+	f.ScrubRateKiBs = 1 << 20
+
+	s := m.evLogger.Subscribe(events.LocalCorruptionDetected)
+	defer s.Unsubscribe()
+
+	name := "scrubbed"
+	data := bytes.Repeat([]byte("abcdefgh"), protocol.MinBlockSize/4) // two blocks
+	writeScrubFile := func(data []byte, mtime time.Time) {
+		t.Helper()
+		fd, err := f.fs.OpenFile(name, fs.OptReadWrite|fs.OptCreate, 0644)
+		must(t, err)
+		_, err = fd.WriteAt(data, 0)
+		must(t, err)
+		must(t, fd.Close())
+		if !mtime.IsZero() {
+			must(t, f.fs.Chtimes(name, mtime, mtime))
+		}
+	}
+	writeScrubFile(data, time.Time{})
+
+	info, err := f.fs.Lstat(name)
+	must(t, err)
+	file, err := scanner.CreateFileInfo(info, name, f.fs)
+	must(t, err)
+	file.Blocks, err = scanner.HashFile(context.Background(), f.fs, name, protocol.MinBlockSize, nil, true)
+	must(t, err)
+	file.Version = file.Version.Update(myID.Short())
+	f.updateLocalsFromScanning([]protocol.FileInfo{file})
+
+	// An intact file is fine.
+
+	f.scrub(context.Background())
+	if errs := f.Errors(); len(errs) != 0 {
+		t.Fatal("unexpected errors", errs)
+	}
+
+	// Corrupt the second block behind the scanner's back.
+
+	corrupted := append([]byte{}, data...)
+	corrupted[protocol.MinBlockSize+1] ^= 0xff
+	writeScrubFile(corrupted, info.ModTime())
+
+	f.scrub(context.Background())
+	if errs := f.Errors(); len(errs) != 1 || errs[0].Path != name {
+		t.Fatal("expected a single error for the corrupted file, got", errs)
+	}
+	ev, err := s.Poll(time.Minute)
+	must(t, err)
+	if data := ev.Data.(map[string]interface{}); data["item"] != name || data["blocks"] != 1 || data["repaired"] != false {
+		t.Error("unexpected event data", data)
+	}
+
+	// Once the data is restored the error goes away.
+
+	writeScrubFile(data, info.ModTime())
+	f.scrub(context.Background())
+	if errs := f.Errors(); len(errs) != 0 {
+		t.Fatal("unexpected errors", errs)
+	}
+}
@@ -26,7 +26,6 @@ import (
 	"github.com/syncthing/syncthing/lib/protocol"
 	"github.com/syncthing/syncthing/lib/rand"
 	"github.com/syncthing/syncthing/lib/scanner"
-	"github.com/syncthing/syncthing/lib/sha256"
 	"github.com/syncthing/syncthing/lib/sync"
 	"github.com/syncthing/syncthing/lib/util"
 	"github.com/syncthing/syncthing/lib/versioner"
@@ -70,6 +69,8 @@ var (
 	errModified               = errors.New("file modified but not rescanned; will try again later")
 	errUnexpectedDirOnFileDel = errors.New("encountered directory when trying to remove file/symlink")
 	errIncompatibleSymlink    = errors.New("incompatible symlink entry; rescan with newer Syncthing on source")
+	errMaxFolderSize          = errors.New("pulling the file would make the folder larger than its maximum size")
+	errCaseConflict           = errors.New("an item with the same name in another case is in the way; will try again after it is removed")
 	contextRemovingOldItem    = "removing item to be replaced"
 )
 
@@ -87,6 +88,7 @@ const (
 	defaultCopiers          = 2
 	defaultPullerPause      = 60 * time.Second
 	defaultPullerPendingKiB = 2 * protocol.MaxBlockSize / 1024
+	urgentPullerPendingKiB  = protocol.MaxBlockSize / 1024
 
 	maxPullerIterations = 3
 )
@@ -100,24 +102,50 @@ type sendReceiveFolder struct {
 	folder
 
 	fs        fs.Filesystem
+	tempFs    fs.Filesystem // where temporary files are created; usually the same as fs
 	versioner versioner.Versioner
 
 	queue *jobQueue
 
+	pullers    map[string]*sharedPullerState // files between handleFile and the finisher
+	pullersMut sync.Mutex
+
 	pullErrors    map[string]string // errors for most recent/current iteration
 	oldPullErrors map[string]string // errors from previous iterations for log filtering only
 	pullErrorsMut sync.Mutex
+
+	compressTemp bool // write pulled data to compressed temp files
+
+	cases *fs.CaseChecker // finds items that exist only in another case
+
+	maxFolderBytes int64 // zero if there is no limit, for the current iteration
+	folderBytes    int64 // the size of the folder with the files pulled so far
+
+	acceptPolicy *acceptPolicy            // nil if all changes are accepted
+	skipped      map[string]SkippedChange // changes rejected by the accept policy in the most recent iteration
+	skippedMut   sync.Mutex
 }
 
-func newSendReceiveFolder(model *model, fset *db.FileSet, ignores *ignore.Matcher, cfg config.FolderConfiguration, ver versioner.Versioner, fs fs.Filesystem, evLogger events.Logger) service {
+func newSendReceiveFolder(model *model, fset *db.FileSet, ignores *ignore.Matcher, cfg config.FolderConfiguration, ver versioner.Versioner, ffs fs.Filesystem, evLogger events.Logger) service {
 	f := &sendReceiveFolder{
 		folder:        newFolder(model, fset, ignores, cfg, evLogger),
-		fs:            fs,
+		fs:            ffs,
+		tempFs:        ffs,
 		versioner:     ver,
 		queue:         newJobQueue(),
+		pullers:       make(map[string]*sharedPullerState),
+		pullersMut:    sync.NewMutex(),
 		pullErrorsMut: sync.NewMutex(),
+		acceptPolicy:  newAcceptPolicy(cfg.AcceptPolicy),
+		skippedMut:    sync.NewMutex(),
 	}
 	f.folder.puller = f
+
+	if cfg.TempPath != "" {
+		f.tempFs = cfg.TempFilesystem()
+	}
+	f.compressTemp = compressTempFiles(cfg, f.tempFs)
+	f.cases = fs.NewCaseChecker(f.fs)
 	f.folder.Service = util.AsService(f.serve, f.String())
 
 	if f.Copiers == 0 {
@@ -234,6 +262,11 @@ func (f *sendReceiveFolder) pullerIteration(scanChan chan<- string) int {
 	f.pullErrors = make(map[string]string)
 	f.pullErrorsMut.Unlock()
 
+	f.maxFolderBytes = f.MaxFolderBytes()
+	if f.maxFolderBytes > 0 {
+		f.folderBytes = f.fset.LocalSize().Bytes
+	}
+
 	pullChan := make(chan pullBlockState)
 	copyChan := make(chan copyBlocksState)
 	finisherChan := make(chan *sharedPullerState)
@@ -312,6 +345,12 @@ func (f *sendReceiveFolder) processNeeded(dbUpdateChan chan<- dbUpdateJob, copyC
 	var dirDeletions []protocol.FileInfo
 	fileDeletions := map[string]protocol.FileInfo{}
 	buckets := map[string][]protocol.FileInfo{}
+	skipped := map[string]SkippedChange{}
+	defer func() {
+		f.skippedMut.Lock()
+		f.skipped = skipped
+		f.skippedMut.Unlock()
+	}()
 
 	// Iterate the list of items that we need and sort them into piles.
 	// Regular files to pull goes into the file queue, everything else
@@ -329,12 +368,18 @@ func (f *sendReceiveFolder) processNeeded(dbUpdateChan chan<- dbUpdateJob, copyC
 			return true
 		}
 
+		if reason := f.rejectedByPolicy(intf.(protocol.FileInfo)); reason != "" {
+			l.Debugln(f, "skipping change (accept policy)", intf.FileName(), reason)
+			skipped[intf.FileName()] = newSkippedChange(intf.(protocol.FileInfo), reason)
+			return true
+		}
+
 		changed++
 
-		file := intf.(protocol.FileInfo)
+		file := f.localOwnership(intf.(protocol.FileInfo))
 
 		switch {
-		case f.ignores.ShouldIgnore(file.Name):
+		case f.ignores.ShouldIgnore(file.Name), ignoredFile(f.ignores, file):
 			file.SetIgnored(f.shortID)
 			l.Debugln(f, "Handling ignored file", file)
 			dbUpdateChan <- dbUpdateJob{file, dbUpdateInvalidate}
@@ -378,7 +423,12 @@ func (f *sendReceiveFolder) processNeeded(dbUpdateChan chan<- dbUpdateJob, copyC
 
 		case file.Type == protocol.FileInfoTypeFile:
 			curFile, hasCurFile := f.fset.Get(protocol.LocalDeviceID, file.Name)
-			if _, need := blockDiff(curFile.Blocks, file.Blocks); hasCurFile && len(need) == 0 {
+			if f.wantsPlaceholder(curFile, hasCurFile) {
+				l.Debugln(f, "Handling placeholder", file.Name)
+				if f.checkParent(file.Name, scanChan) {
+					f.handlePlaceholder(file, curFile, hasCurFile, dbUpdateChan, scanChan)
+				}
+			} else if _, need := blockDiff(curFile.Blocks, file.Blocks); hasCurFile && !curFile.IsPlaceholder() && len(need) == 0 {
 				// We are supposed to copy the entire file, and then fetch nothing. We
 				// are only updating metadata, so we don't actually *need* to make the
 				// copy.
@@ -388,7 +438,7 @@ func (f *sendReceiveFolder) processNeeded(dbUpdateChan chan<- dbUpdateJob, copyC
 				f.queue.Push(file.Name, file.Size, file.ModTime())
 			}
 
-		case runtime.GOOS == "windows" && file.IsSymlink():
+		case runtime.GOOS == "windows" && file.IsSymlink() && !(f.SyncPlatformData && file.Platform.Windows.Junction):
 			file.SetUnsupported(f.shortID)
 			l.Debugln(f, "Invalidating symlink (unsupported)", file.Name)
 			dbUpdateChan <- dbUpdateJob{file, dbUpdateInvalidate}
@@ -457,6 +507,7 @@ nextFile:
 			f.queue.Done(fileName)
 			continue
 		}
+		fi = f.localOwnership(fi)
 
 		if fi.IsDeleted() || fi.IsInvalid() || fi.Type != protocol.FileInfoTypeFile {
 			// The item has changed type or status in the index while we
@@ -506,6 +557,12 @@ nextFile:
 				continue nextFile
 			}
 		}
+		if f.hasTempFile(fi) {
+			// The data is already here, e.g. from an imported change
+			// batch, and doesn't need to be requested from anyone.
+			f.handleFile(fi, copyChan, dbUpdateChan)
+			continue nextFile
+		}
 		f.newPullError(fileName, errNotAvailable)
 		f.queue.Done(fileName)
 	}
@@ -513,6 +570,29 @@ nextFile:
 	return changed, fileDeletions, dirDeletions, nil
 }
 
+// rejectedByPolicy returns the reason why the accept policy rejects the
+// change to file, or the empty string if it doesn't. Ignored files are
+// left to be handled as such.
+func (f *sendReceiveFolder) rejectedByPolicy(file protocol.FileInfo) string {
+	if f.acceptPolicy == nil || f.ignores.ShouldIgnore(file.Name) {
+		return ""
+	}
+	var cur protocol.FileInfo
+	var hasCur bool
+	if f.acceptPolicy.needsCurrent() {
+		cur, hasCur = f.fset.Get(protocol.LocalDeviceID, file.Name)
+	}
+	return f.acceptPolicy.evaluate(file, cur, hasCur, f.IgnorePerms)
+}
+
+// SkippedChanges returns the changes that the accept policy rejected in the
+// most recent pull.
+func (f *sendReceiveFolder) SkippedChanges() ([]SkippedChange, error) {
+	f.skippedMut.Lock()
+	defer f.skippedMut.Unlock()
+	return sortedSkippedChanges(f.skipped), nil
+}
+
 func (f *sendReceiveFolder) processDeletions(fileDeletions map[string]protocol.FileInfo, dirDeletions []protocol.FileInfo, dbUpdateChan chan<- dbUpdateJob, scanChan chan<- string) {
 	for _, file := range fileDeletions {
 		select {
@@ -571,6 +651,11 @@ func (f *sendReceiveFolder) handleDir(file protocol.FileInfo, dbUpdateChan chan<
 		l.Debugf("need dir\n\t%v\n\t%v", file, curFile)
 	}
 
+	if err = f.checkCase(file.Name); err != nil {
+		f.newPullError(file.Name, errors.Wrap(err, "handling dir"))
+		return
+	}
+
 	info, err := f.fs.Lstat(file.Name)
 	switch {
 	// There is already something under that name, we need to handle that.
@@ -598,6 +683,9 @@ func (f *sendReceiveFolder) handleDir(file protocol.FileInfo, dbUpdateChan chan<
 				return f.moveForConflict(name, file.ModifiedBy.String(), scanChan)
 			}, curFile.Name)
 		} else {
+			if hasCurFile && !curFile.IsDirectory() && !curFile.IsSymlink() && !curFile.IsDeleted() {
+				f.recordUndo(curFile, file, undoActionReplace)
+			}
 			err = f.deleteItemOnDisk(curFile, scanChan)
 		}
 		if err != nil {
@@ -618,7 +706,7 @@ func (f *sendReceiveFolder) handleDir(file protocol.FileInfo, dbUpdateChan chan<
 			}
 
 			// Copy the parent owner and group, if we are supposed to do that.
-			if err := f.maybeCopyOwner(path); err != nil {
+			if err := f.maybeCopyOwner(f.fs, path); err != nil {
 				return err
 			}
 
@@ -638,6 +726,8 @@ func (f *sendReceiveFolder) handleDir(file protocol.FileInfo, dbUpdateChan chan<
 		}
 
 		if err = f.inWritableDir(mkdir, file.Name); err == nil {
+			f.setXattrs(f.fs, file.Name, file)
+			f.setPlatformData(f.fs, file.Name, file)
 			dbUpdateChan <- dbUpdateJob{file, dbUpdateHandleDir}
 		} else {
 			f.newPullError(file.Name, errors.Wrap(err, "creating directory"))
@@ -663,9 +753,68 @@ func (f *sendReceiveFolder) handleDir(file protocol.FileInfo, dbUpdateChan chan<
 			return
 		}
 	}
+	f.setXattrs(f.fs, file.Name, file)
+	f.setPlatformData(f.fs, file.Name, file)
 	dbUpdateChan <- dbUpdateJob{file, dbUpdateHandleDir}
 }
 
+// localOwnership returns file with its owner and group translated to the
+// local user and group of the same name, or of the name they are mapped to,
+// if the folder syncs ownership. That is then what gets applied and
+// recorded as our version of the file, matching what a scan would find.
+// Names that don't exist here leave the numeric ID as it is, with whatever
+// name that has here.
+func (f *sendReceiveFolder) localOwnership(file protocol.FileInfo) protocol.FileInfo {
+	if !f.SyncOwnership {
+		return file
+	}
+	if file.OwnerName != "" {
+		name := f.LocalUserName(file.OwnerName)
+		if uid, ok := osutil.UserID(name); ok {
+			file.OwnerName = name
+			file.Uid = int32(uid)
+		} else {
+			l.Debugf("%v no local user %q for %s", f, name, file.Name)
+			file.OwnerName, _ = osutil.UserName(int(file.Uid))
+		}
+	}
+	if file.GroupName != "" {
+		name := f.LocalGroupName(file.GroupName)
+		if gid, ok := osutil.GroupID(name); ok {
+			file.GroupName = name
+			file.Gid = int32(gid)
+		} else {
+			l.Debugf("%v no local group %q for %s", f, name, file.Name)
+			file.GroupName, _ = osutil.GroupName(int(file.Gid))
+		}
+	}
+	return file
+}
+
+// setXattrs applies the extended attributes of file to name, if the folder
+// syncs them. Failing to do so doesn't fail the item, as the attributes are
+// secondary to the contents and may well be unsupported here.
+func (f *sendReceiveFolder) setXattrs(ffs fs.Filesystem, name string, file protocol.FileInfo) {
+	if !f.SyncXattrs {
+		return
+	}
+	if err := ffs.SetXattr(name, file.Xattrs); err != nil && err != fs.ErrXattrsNotSupported {
+		l.Infof("Puller (folder %s, item %q): setting extended attributes: %v", f.Description(), file.Name, err)
+	}
+}
+
+// setPlatformData applies the platform data of file to name, if the folder
+// syncs it. Like the extended attributes, failing to do so doesn't fail the
+// item.
+func (f *sendReceiveFolder) setPlatformData(ffs fs.Filesystem, name string, file protocol.FileInfo) {
+	if !f.SyncPlatformData {
+		return
+	}
+	if err := ffs.SetPlatformData(name, file.Platform); err != nil && err != fs.ErrPlatformDataNotSupported {
+		l.Infof("Puller (folder %s, item %q): setting platform data: %v", f.Description(), file.Name, err)
+	}
+}
+
 // checkParent verifies that the thing we are handling lives inside a directory,
 // and not a symlink or regular file. It also resurrects missing parent dirs.
 func (f *sendReceiveFolder) checkParent(file string, scanChan chan<- string) bool {
@@ -735,6 +884,11 @@ func (f *sendReceiveFolder) handleSymlink(file protocol.FileInfo, dbUpdateChan c
 		return
 	}
 
+	if err = f.checkCase(file.Name); err != nil {
+		f.newPullError(file.Name, errors.Wrap(err, "handling symlink"))
+		return
+	}
+
 	// There is already something under that name, we need to handle that.
 	if info, err := f.fs.Lstat(file.Name); err == nil {
 		// Check that it is what we have in the database.
@@ -758,6 +912,9 @@ func (f *sendReceiveFolder) handleSymlink(file protocol.FileInfo, dbUpdateChan c
 				return f.moveForConflict(name, file.ModifiedBy.String(), scanChan)
 			}, curFile.Name)
 		} else {
+			if hasCurFile && !curFile.IsDirectory() && !curFile.IsSymlink() && !curFile.IsDeleted() {
+				f.recordUndo(curFile, file, undoActionReplace)
+			}
 			err = f.deleteItemOnDisk(curFile, scanChan)
 		}
 		if err != nil {
@@ -772,7 +929,7 @@ func (f *sendReceiveFolder) handleSymlink(file protocol.FileInfo, dbUpdateChan c
 		if err := f.fs.CreateSymlink(file.SymlinkTarget, path); err != nil {
 			return err
 		}
-		return f.maybeCopyOwner(path)
+		return f.maybeCopyOwner(f.fs, path)
 	}
 
 	if err = f.inWritableDir(createLink, file.Name); err == nil {
@@ -805,6 +962,12 @@ func (f *sendReceiveFolder) deleteDir(file protocol.FileInfo, dbUpdateChan chan<
 		})
 	}()
 
+	if !f.cases.IsRealCase(file.Name) {
+		l.Debugln(f, "not deleting dir only found in another case, but update db", file.Name)
+		dbUpdateChan <- dbUpdateJob{file, dbUpdateDeleteDir}
+		return
+	}
+
 	if err = f.deleteDirOnDisk(file.Name, scanChan); err != nil {
 		f.newPullError(file.Name, errors.Wrap(err, "delete dir"))
 		return
@@ -859,6 +1022,14 @@ func (f *sendReceiveFolder) deleteFileWithCurrent(file, cur protocol.FileInfo, h
 		return
 	}
 
+	if !f.cases.IsRealCase(file.Name) {
+		// On a case insensitive filesystem, what's found is what it was
+		// renamed to, e.g. by a case only rename.
+		l.Debugln(f, "not deleting file only found in another case, but update db", file.Name)
+		dbUpdateChan <- dbUpdateJob{file, dbUpdateDeleteFile}
+		return
+	}
+
 	if err = f.checkToBeDeleted(cur, scanChan); err != nil {
 		return
 	}
@@ -879,7 +1050,11 @@ func (f *sendReceiveFolder) deleteFileWithCurrent(file, cur protocol.FileInfo, h
 		return
 	}
 
-	if f.versioner != nil && !cur.IsSymlink() {
+	if !cur.IsSymlink() {
+		f.recordUndo(cur, file, undoActionDelete)
+	}
+
+	if f.versioner != nil && !cur.IsSymlink() && !cur.IsPlaceholder() {
 		err = f.inWritableDir(f.versioner.Archive, file.Name)
 	} else {
 		err = f.inWritableDir(f.fs.Remove, file.Name)
@@ -946,8 +1121,19 @@ func (f *sendReceiveFolder) renameFile(cur, source, target protocol.FileInfo, db
 	}
 	// Check that the target corresponds to what we have in the DB
 	curTarget, ok := f.fset.Get(protocol.LocalDeviceID, target.Name)
-	switch stat, serr := f.fs.Lstat(target.Name); {
-	case serr != nil && fs.IsNotExist(serr):
+	stat, serr := f.fs.Lstat(target.Name)
+	caseOnly := false
+	if serr == nil && !f.cases.IsRealCase(target.Name) {
+		if !fs.IsCaseOnlyRename(source.Name, target.Name) {
+			err = errCaseConflict
+			return err
+		}
+		// What's found is the source, which is moved away below, so the
+		// target doesn't exist as such.
+		caseOnly = true
+	}
+	switch {
+	case caseOnly, serr != nil && fs.IsNotExist(serr):
 		if !ok || curTarget.IsDeleted() {
 			break
 		}
@@ -976,17 +1162,20 @@ func (f *sendReceiveFolder) renameFile(cur, source, target protocol.FileInfo, db
 	}
 
 	tempName := fs.TempName(target.Name)
+	if err = f.ensureTempDir(tempName); err != nil {
+		return err
+	}
 
 	if f.versioner != nil {
 		err = f.CheckAvailableSpace(source.Size)
 		if err == nil {
-			err = osutil.Copy(f.fs, f.fs, source.Name, tempName)
+			err = osutil.Copy(f.fs, f.tempFs, source.Name, tempName)
 			if err == nil {
 				err = f.inWritableDir(f.versioner.Archive, source.Name)
 			}
 		}
 	} else {
-		err = osutil.RenameOrCopy(f.fs, f.fs, source.Name, tempName)
+		err = osutil.RenameOrCopy(f.fs, f.tempFs, source.Name, tempName)
 	}
 	if err != nil {
 		return err
@@ -1050,18 +1239,56 @@ func (f *sendReceiveFolder) renameFile(cur, source, target protocol.FileInfo, db
 func (f *sendReceiveFolder) handleFile(file protocol.FileInfo, copyChan chan<- copyBlocksState, dbUpdateChan chan<- dbUpdateJob) {
 	curFile, hasCurFile := f.fset.Get(protocol.LocalDeviceID, file.Name)
 
+	if err := f.checkCase(file.Name); err != nil {
+		f.newPullError(file.Name, errors.Wrap(err, "handling file"))
+		f.queue.Done(file.Name)
+		return
+	}
+
+	if f.maxFolderBytes > 0 {
+		// Space freed by deletions later in the iteration is counted in
+		// the next one.
+		growth := file.Size
+		if hasCurFile && !curFile.IsDeleted() && !curFile.IsDirectory() {
+			growth -= curFile.Size
+		}
+		if growth > 0 && f.folderBytes+growth > f.maxFolderBytes {
+			l.Debugf("%v: %s would make the folder %d bytes, above the maximum of %d", f, file.Name, f.folderBytes+growth, f.maxFolderBytes)
+			f.newPullError(file.Name, errMaxFolderSize)
+			f.queue.Done(file.Name)
+			return
+		}
+		f.folderBytes += growth
+	}
+
 	have, _ := blockDiff(curFile.Blocks, file.Blocks)
 
 	tempName := fs.TempName(file.Name)
+	var compressor *tempCompressor
+	if f.compressTemp {
+		tempName = fs.CompressedTempName(file.Name)
+		compressor = newTempCompressor()
+	}
+	if err := f.ensureTempDir(tempName); err != nil {
+		f.newPullError(file.Name, errors.Wrap(err, "creating temp dir"))
+		f.queue.Done(file.Name)
+		return
+	}
 
 	populateOffsets(file.Blocks)
 
 	blocks := make([]protocol.BlockInfo, 0, len(file.Blocks))
 	reused := make([]int32, 0, len(file.Blocks))
 
+	if compressor != nil {
+		// An old compressed temp file can't be reused, as the compressed
+		// sizes of its blocks are gone.
+		inWritableDir(f.tempFs.Remove, f.tempFs, tempName, f.IgnorePerms)
+	}
+
 	// Check for an old temporary file which might have some blocks we could
 	// reuse.
-	tempBlocks, err := scanner.HashFile(f.ctx, f.fs, tempName, file.BlockSize(), nil, false)
+	tempBlocks, err := scanner.HashFileScheme(f.ctx, f.tempFs, tempName, file.BlockScheme, file.HashAlgorithm, file.BlockSize(), nil, false)
 	if err == nil {
 		// Check for any reusable blocks in the temp file
 		tempCopyBlocks, _ := blockDiff(tempBlocks, file.Blocks)
@@ -1088,15 +1315,19 @@ func (f *sendReceiveFolder) handleFile(file protocol.FileInfo, copyChan chan<- c
 			// Otherwise, discard the file ourselves in order for the
 			// sharedpuller not to panic when it fails to exclusively create a
 			// file which already exists
-			f.inWritableDir(f.fs.Remove, tempName)
+			inWritableDir(f.tempFs.Remove, f.tempFs, tempName, f.IgnorePerms)
 		}
 	} else {
 		// Copy the blocks, as we don't want to shuffle them on the FileInfo
 		blocks = append(blocks, file.Blocks...)
 	}
 
-	// Shuffle the blocks
+	// Shuffle the blocks, then move the ones available from the fewest
+	// devices to the front. When several devices are pulling the same file
+	// this makes them fetch different blocks from the source and then from
+	// each other, instead of all asking the source for the same blocks.
 	rand.Shuffle(blocks)
+	rarestFirst(blocks, file, f.model.blockAvailabilityCounts(f.folderID, file))
 
 	f.evLogger.Log(events.ItemStarted, map[string]string{
 		"folder": f.folderID,
@@ -1107,7 +1338,7 @@ func (f *sendReceiveFolder) handleFile(file protocol.FileInfo, copyChan chan<- c
 
 	s := sharedPullerState{
 		file:             file,
-		fs:               f.fs,
+		fs:               f.tempFs,
 		folder:           f.folderID,
 		tempName:         tempName,
 		realName:         file.Name,
@@ -1122,11 +1353,17 @@ func (f *sendReceiveFolder) handleFile(file protocol.FileInfo, copyChan chan<- c
 		curFile:          curFile,
 		mut:              sync.NewRWMutex(),
 		sparse:           !f.DisableSparseFiles,
+		fsync:            f.Durability.SyncFiles(),
 		created:          time.Now(),
+		compressor:       compressor,
 	}
 
 	l.Debugf("%v need file %s; copy %d, reused %v", f, file.Name, len(blocks), len(reused))
 
+	f.pullersMut.Lock()
+	f.pullers[file.Name] = &s
+	f.pullersMut.Unlock()
+
 	cs := copyBlocksState{
 		sharedPullerState: &s,
 		blocks:            blocks,
@@ -1135,6 +1372,25 @@ func (f *sendReceiveFolder) handleFile(file protocol.FileInfo, copyChan chan<- c
 	copyChan <- cs
 }
 
+// rarestFirst sorts the blocks by ascending availability, given the number
+// of devices that have each block index. The sort is stable, so blocks that
+// are equally rare keep their current relative order.
+func rarestFirst(blocks []protocol.BlockInfo, file protocol.FileInfo, counts []int) {
+	if len(counts) == 0 {
+		return
+	}
+	count := func(b protocol.BlockInfo) int {
+		idx := file.BlockIndex(b.Offset)
+		if idx >= len(counts) {
+			return 0
+		}
+		return counts[idx]
+	}
+	sort.SliceStable(blocks, func(a, b int) bool {
+		return count(blocks[a]) < count(blocks[b])
+	})
+}
+
 // blockDiff returns lists of common and missing (to transform src into tgt)
 // blocks. Both block lists must have been created with the same block size.
 func blockDiff(src, tgt []protocol.BlockInfo) ([]protocol.BlockInfo, []protocol.BlockInfo) {
@@ -1174,6 +1430,81 @@ func populateOffsets(blocks []protocol.BlockInfo) {
 	}
 }
 
+// wantsPlaceholder returns true if a needed file should be represented by a
+// placeholder instead of being pulled. Files that we have the contents of
+// are kept up to date as usual, and placeholders are replaced by the real
+// thing once hydration has been requested, i.e. their version cleared.
+func (f *sendReceiveFolder) wantsPlaceholder(curFile protocol.FileInfo, hasCurFile bool) bool {
+	if !f.Placeholders {
+		return false
+	}
+	if !hasCurFile || curFile.IsDeleted() {
+		return true
+	}
+	return curFile.IsPlaceholder() && len(curFile.Version.Counters) > 0
+}
+
+// handlePlaceholder creates or updates the zero-sized placeholder for the
+// given file, recording it in the index as a placeholder.
+func (f *sendReceiveFolder) handlePlaceholder(file, curFile protocol.FileInfo, hasCurFile bool, dbUpdateChan chan<- dbUpdateJob, scanChan chan<- string) {
+	// Used in the defer closure below, updated by the function body. Take
+	// care not declare another err.
+	var err error
+
+	f.evLogger.Log(events.ItemStarted, map[string]string{
+		"folder": f.folderID,
+		"item":   file.Name,
+		"type":   "file",
+		"action": "placeholder",
+	})
+
+	defer func() {
+		if err != nil {
+			f.newPullError(file.Name, errors.Wrap(err, "handling placeholder"))
+		}
+		f.evLogger.Log(events.ItemFinished, map[string]interface{}{
+			"folder": f.folderID,
+			"item":   file.Name,
+			"error":  events.Error(err),
+			"type":   "file",
+			"action": "placeholder",
+		})
+	}()
+
+	if stat, serr := f.fs.Lstat(file.Name); serr == nil {
+		// Whatever is there must be the placeholder we put there before,
+		// anything else is a local change that must be scanned first.
+		if err = f.scanIfItemChanged(stat, curFile, hasCurFile, scanChan); err != nil {
+			return
+		}
+	} else if !fs.IsNotExist(serr) {
+		err = serr
+		return
+	} else {
+		var fd fs.File
+		fd, err = f.fs.Create(file.Name)
+		if err != nil {
+			return
+		}
+		if err = fd.Close(); err != nil {
+			return
+		}
+	}
+
+	if !f.IgnorePerms && !file.NoPermissions {
+		if err = f.fs.Chmod(file.Name, fs.FileMode(file.Permissions&0777)); err != nil {
+			return
+		}
+		if err = f.fs.Lchown(file.Name, int(file.Uid), int(file.Gid)); err != nil {
+			return
+		}
+	}
+	f.fs.Chtimes(file.Name, file.ModTime(), file.ModTime()) // never fails
+
+	file.LocalFlags = protocol.FlagLocalPlaceholder
+	dbUpdateChan <- dbUpdateJob{file, dbUpdateHandleFile}
+}
+
 // shortcutFile sets file mode and modification time, when that's the only
 // thing that has changed.
 func (f *sendReceiveFolder) shortcutFile(file, curFile protocol.FileInfo, dbUpdateChan chan<- dbUpdateJob) {
@@ -1208,8 +1539,13 @@ func (f *sendReceiveFolder) shortcutFile(file, curFile protocol.FileInfo, dbUpda
 		}
 	}
 
+	// Writing streams changes the modification time.
+	f.setPlatformData(f.fs, file.Name, file)
+
 	f.fs.Chtimes(file.Name, file.ModTime(), file.ModTime()) // never fails
 
+	f.setXattrs(f.fs, file.Name, file)
+
 	// This may have been a conflict. We should merge the version vectors so
 	// that our clock doesn't move backwards.
 	file.Version = file.Version.Merge(curFile.Version)
@@ -1249,6 +1585,10 @@ func (f *sendReceiveFolder) copierRoutine(in <-chan copyBlocksState, pullChan ch
 			folders = append(folders, folder)
 		}
 
+		// Folders whose filesystem can't clone into our temp file, so
+		// that we don't keep trying for every block.
+		noClone := make(map[string]bool)
+
 		var file fs.File
 		var weakHashFinder *weakhash.Finder
 
@@ -1257,7 +1597,9 @@ func (f *sendReceiveFolder) copierRoutine(in <-chan copyBlocksState, pullChan ch
 			blocksPercentChanged = (tot - state.have) * 100 / tot
 		}
 
-		if blocksPercentChanged >= f.WeakHashThresholdPct {
+		// The weak hash finder looks for blocks of the block size, which
+		// content defined blocks rarely are.
+		if blocksPercentChanged >= f.WeakHashThresholdPct && state.file.BlockScheme == protocol.BlockSchemeFixed {
 			hashesToFind := make([]uint32, 0, len(state.blocks))
 			for _, block := range state.blocks {
 				if block.WeakHash != 0 {
@@ -1288,12 +1630,14 @@ func (f *sendReceiveFolder) copierRoutine(in <-chan copyBlocksState, pullChan ch
 				break blocks
 			default:
 			}
-			if !f.DisableSparseFiles && state.reused == 0 && block.IsEmpty() {
+			if state.reused == 0 && block.IsEmpty() && (!f.DisableSparseFiles || state.inHole(block)) {
 				// The block is a block of all zeroes, and we are not reusing
 				// a temp file, so there is no need to do anything with it.
 				// If we were reusing a temp file and had this block to copy,
 				// it would be because the block in the temp file was *not* a
-				// block of all zeroes, so then we should not skip it.
+				// block of all zeroes, so then we should not skip it. Blocks
+				// in holes of the source file are skipped regardless of
+				// sparse files being disabled, to keep them holes.
 
 				// Pretend we copied it.
 				state.copiedFromOrigin()
@@ -1304,7 +1648,7 @@ func (f *sendReceiveFolder) copierRoutine(in <-chan copyBlocksState, pullChan ch
 			buf = protocol.BufferPool.Upgrade(buf, int(block.Size))
 
 			found, err := weakHashFinder.Iterate(block.WeakHash, buf, func(offset int64) bool {
-				if verifyBuffer(buf, block) != nil {
+				if verifyBuffer(buf, block, state.file.HashAlgorithm) != nil {
 					return true
 				}
 				_, err = dstFd.WriteAt(buf, block.Offset)
@@ -1325,26 +1669,39 @@ func (f *sendReceiveFolder) copierRoutine(in <-chan copyBlocksState, pullChan ch
 			}
 
 			if !found {
-				found = f.model.finder.Iterate(folders, block.Hash, func(folder, path string, index int32) bool {
-					fs := folderFilesystems[folder]
-					fd, err := fs.Open(path)
+				found = f.model.finder.IterateOffsets(folders, block.Hash, state.file.BlockSize(), func(folder, path string, srcOffset int64) bool {
+					fd, err := folderFilesystems[folder].Open(path)
 					if err != nil {
 						return false
 					}
 
-					_, err = fd.ReadAt(buf, int64(state.file.BlockSize())*int64(index))
-					fd.Close()
+					defer fd.Close()
+
+					_, err = fd.ReadAt(buf, srcOffset)
 					if err != nil {
 						return false
 					}
 
-					if err := verifyBuffer(buf, block); err != nil {
+					if err := verifyBuffer(buf, block, state.file.HashAlgorithm); err != nil {
 						l.Debugln("Finder failed to verify buffer", err)
 						return false
 					}
-					_, err = dstFd.WriteAt(buf, block.Offset)
-					if err != nil {
-						state.fail(errors.Wrap(err, "dst write"))
+
+					cloned := false
+					if !noClone[folder] && state.compressor == nil {
+						err = dstFd.CloneRangeFrom(f.tempFs, fd, srcOffset, block.Offset, int64(block.Size))
+						if err == fs.ErrCloneNotSupported {
+							noClone[folder] = true
+						} else if err != nil {
+							l.Debugln("Clone failed, falling back to copying", err)
+						}
+						cloned = err == nil
+					}
+					if !cloned {
+						_, err = dstFd.WriteAt(buf, block.Offset)
+						if err != nil {
+							state.fail(errors.Wrap(err, "dst write"))
+						}
 					}
 					if path == state.file.Name {
 						state.copiedFromOrigin()
@@ -1353,6 +1710,18 @@ func (f *sendReceiveFolder) copierRoutine(in <-chan copyBlocksState, pullChan ch
 				})
 			}
 
+			if !found && f.model.blockStore != nil && state.file.HashAlgorithm == protocol.HashAlgorithmSHA256 {
+				// Blocks we have pulled before, for any file in any folder,
+				// may still be in the block cache, which only holds those
+				// hashed with SHA-256.
+				if data, err := f.model.blockStore.Get(block.Hash); err == nil && verifyBuffer(data, block, state.file.HashAlgorithm) == nil {
+					if _, err := dstFd.WriteAt(data, block.Offset); err != nil {
+						state.fail(errors.Wrap(err, "dst write"))
+					}
+					found = true
+				}
+			}
+
 			if state.failed() != nil {
 				break
 			}
@@ -1366,6 +1735,7 @@ func (f *sendReceiveFolder) copierRoutine(in <-chan copyBlocksState, pullChan ch
 				pullChan <- ps
 			} else {
 				state.copyDone(block)
+				f.markProgress()
 			}
 		}
 		if file != nil {
@@ -1378,11 +1748,11 @@ func (f *sendReceiveFolder) copierRoutine(in <-chan copyBlocksState, pullChan ch
 	}
 }
 
-func verifyBuffer(buf []byte, block protocol.BlockInfo) error {
+func verifyBuffer(buf []byte, block protocol.BlockInfo, algo protocol.HashAlgorithm) error {
 	if len(buf) != int(block.Size) {
 		return fmt.Errorf("length mismatch %d != %d", len(buf), block.Size)
 	}
-	hf := sha256.New()
+	hf := algo.NewHash()
 	_, err := hf.Write(buf)
 	if err != nil {
 		return err
@@ -1398,6 +1768,7 @@ func verifyBuffer(buf []byte, block protocol.BlockInfo) error {
 
 func (f *sendReceiveFolder) pullerRoutine(in <-chan pullBlockState, out chan<- *sharedPullerState) {
 	requestLimiter := newByteSemaphore(f.PullerMaxPendingKiB * 1024)
+	urgentLimiter := newByteSemaphore(urgentPullerPendingKiB * 1024)
 	wg := sync.NewWaitGroup()
 
 	for state := range in {
@@ -1415,12 +1786,20 @@ func (f *sendReceiveFolder) pullerRoutine(in <-chan pullBlockState, out chan<- *
 		state := state
 		bytes := int(state.block.Size)
 
-		requestLimiter.take(bytes)
+		limiter := requestLimiter
+		if f.queue.IsUrgent(state.file.Name) {
+			// The user asked for this file to be synced right now, so we
+			// don't make it wait behind the rest of the backlog. It has a
+			// limiter of its own, to still not flood the other devices.
+			limiter = urgentLimiter
+		}
+
+		limiter.take(bytes)
 		wg.Add(1)
 
 		go func() {
 			defer wg.Done()
-			defer requestLimiter.give(bytes)
+			defer limiter.give(bytes)
 
 			f.pullBlock(state, out)
 		}()
@@ -1438,7 +1817,7 @@ func (f *sendReceiveFolder) pullBlock(state pullBlockState, out chan<- *sharedPu
 		return
 	}
 
-	if !f.DisableSparseFiles && state.reused == 0 && state.block.IsEmpty() {
+	if state.reused == 0 && state.block.IsEmpty() && (!f.DisableSparseFiles || state.inHole(state.block)) {
 		// There is no need to request a block of all zeroes. Pretend we
 		// requested it and handled it correctly.
 		state.pullDone(state.block)
@@ -1456,10 +1835,10 @@ func (f *sendReceiveFolder) pullBlock(state pullBlockState, out chan<- *sharedPu
 		default:
 		}
 
-		// Select the least busy device to pull the block from. If we found no
+		// Select the fastest device to pull the block from. If we found no
 		// feasible device at all, fail the block (and in the long run, the
 		// file).
-		selected, found := activity.leastBusy(candidates)
+		selected, found := activity.fastest(candidates, int(state.block.Size), f.model.connectionQuality)
 		if !found {
 			if lastError != nil {
 				state.fail(errors.Wrap(lastError, "pull"))
@@ -1472,11 +1851,23 @@ func (f *sendReceiveFolder) pullBlock(state pullBlockState, out chan<- *sharedPu
 		candidates = removeAvailability(candidates, selected)
 
 		// Fetch the block, while marking the selected device as in use so that
-		// leastBusy can select another device when someone else asks.
+		// fastest can account for it when someone else asks.
 		activity.using(selected)
+		state.requesting(selected.ID)
+		ctx := f.ctx
+		if f.queue.IsInteractive(state.file.Name) {
+			ctx = protocol.WithRequestPriority(ctx, protocol.RequestPriorityInteractive)
+		}
 		var buf []byte
-		buf, lastError = f.model.requestGlobal(f.ctx, selected.ID, f.folderID, state.file.Name, state.block.Offset, int(state.block.Size), state.block.Hash, state.block.WeakHash, selected.FromTemporary)
+		if len(state.file.Blocks) == 1 && state.file.Size <= maxBatchedFileSize && !selected.FromTemporary {
+			// Small files are requested as a whole, possibly together
+			// with many others in a single round trip.
+			buf, lastError = f.model.requestGlobalBatched(ctx, selected.ID, f.folderID, state.file.Name, int(state.block.Size), state.block.Hash, state.block.WeakHash)
+		} else {
+			buf, lastError = f.model.requestGlobal(ctx, selected.ID, f.folderID, state.file.Name, state.block.Offset, int(state.block.Size), state.block.Hash, state.block.WeakHash, selected.FromTemporary)
+		}
 		activity.done(selected)
+		state.requested(selected.ID)
 		if lastError != nil {
 			l.Debugln("request:", f.folderID, state.file.Name, state.block.Offset, state.block.Size, "returned error:", lastError)
 			continue
@@ -1484,7 +1875,7 @@ func (f *sendReceiveFolder) pullBlock(state pullBlockState, out chan<- *sharedPu
 
 		// Verify that the received block matches the desired hash, if not
 		// try pulling it from another device.
-		lastError = verifyBuffer(buf, state.block)
+		lastError = verifyBuffer(buf, state.block, state.file.HashAlgorithm)
 		if lastError != nil {
 			l.Debugln("request:", f.folderID, state.file.Name, state.block.Offset, state.block.Size, "hash mismatch")
 			continue
@@ -1497,6 +1888,11 @@ func (f *sendReceiveFolder) pullBlock(state pullBlockState, out chan<- *sharedPu
 		} else {
 			state.pullDone(state.block)
 		}
+		if f.model.blockStore != nil && state.file.HashAlgorithm == protocol.HashAlgorithmSHA256 {
+			if err := f.model.blockStore.Put(state.block.Hash, buf); err != nil {
+				l.Debugln("block cache:", f.folderID, state.file.Name, state.block.Offset, err)
+			}
+		}
 		break
 	}
 	out <- state.sharedPullerState
@@ -1505,19 +1901,34 @@ func (f *sendReceiveFolder) pullBlock(state pullBlockState, out chan<- *sharedPu
 func (f *sendReceiveFolder) performFinish(file, curFile protocol.FileInfo, hasCurFile bool, tempName string, dbUpdateChan chan<- dbUpdateJob, scanChan chan<- string) error {
 	// Set the correct permission bits on the new file
 	if !f.IgnorePerms && !file.NoPermissions {
-		if err := f.fs.Chmod(tempName, fs.FileMode(file.Permissions&0777)); err != nil {
+		if err := f.tempFs.Chmod(tempName, fs.FileMode(file.Permissions&0777)); err != nil {
 			return err
 		}
-		if err := f.fs.Lchown(tempName, int(file.Uid), int(file.Gid)); err != nil {
+		if err := f.tempFs.Lchown(tempName, int(file.Uid), int(file.Gid)); err != nil {
 			return err
 		}
 	}
 
 	// Copy the parent owner and group, if we are supposed to do that.
-	if err := f.maybeCopyOwner(tempName); err != nil {
+	if err := f.maybeCopyOwner(f.tempFs, tempName); err != nil {
 		return err
 	}
 
+	f.setXattrs(f.tempFs, tempName, file)
+	f.setPlatformData(f.tempFs, tempName, file)
+
+	if f.VerifyAfterWrite {
+		err := f.verifyTempFile(file, tempName)
+		f.evLogger.Log(events.ItemVerified, map[string]interface{}{
+			"folder": f.folderID,
+			"item":   file.Name,
+			"error":  events.Error(err),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	if stat, err := f.fs.Lstat(file.Name); err == nil {
 		// There is an old file or directory already in place. We need to
 		// handle that.
@@ -1540,6 +1951,9 @@ func (f *sendReceiveFolder) performFinish(file, curFile protocol.FileInfo, hasCu
 				return f.moveForConflict(name, file.ModifiedBy.String(), scanChan)
 			}, curFile.Name)
 		} else {
+			if hasCurFile && !curFile.IsDirectory() && !curFile.IsSymlink() && !curFile.IsDeleted() {
+				f.recordUndo(curFile, file, undoActionReplace)
+			}
 			err = f.deleteItemOnDisk(curFile, scanChan)
 		}
 		if err != nil {
@@ -1548,11 +1962,18 @@ func (f *sendReceiveFolder) performFinish(file, curFile protocol.FileInfo, hasCu
 	}
 
 	// Replace the original content with the new one. If it didn't work,
-	// leave the temp file in place for reuse.
-	if err := osutil.RenameOrCopy(f.fs, f.fs, tempName, file.Name); err != nil {
+	// leave the temp file in place for reuse. When the temp file lives on
+	// another filesystem this becomes a copy and delete.
+	if err := osutil.RenameOrCopy(f.tempFs, f.fs, tempName, file.Name); err != nil {
 		return err
 	}
 
+	// With full durability the new directory entry is on disk before the
+	// file is recorded, not just by the time the batch is committed.
+	if f.Durability == config.DurabilityFull {
+		syncDir(f.fs, filepath.Dir(file.Name))
+	}
+
 	// chown
 	if err := f.fs.Lchown(file.Name, int(file.Uid) /*uid*/, int(file.Gid) /*gid*/); err != nil {
 		l.Infof("failed to chown %d:%d %s. error: %v", file.Gid, file.Uid, file.Name, err)
@@ -1566,14 +1987,61 @@ func (f *sendReceiveFolder) performFinish(file, curFile protocol.FileInfo, hasCu
 	return nil
 }
 
+// syncDir fsyncs the given directory. Failing to do so isn't worth failing
+// anything over, so errors are only logged.
+func syncDir(ffs fs.Filesystem, dir string) {
+	fd, err := ffs.Open(dir)
+	if err != nil {
+		l.Debugf("fsync %q failed: %v", dir, err)
+		return
+	}
+	if err := fd.Sync(); err != nil {
+		l.Debugf("fsync %q failed: %v", dir, err)
+	}
+	fd.Close()
+}
+
+// verifyTempFile reads back the finished temporary file and checks every
+// block against its expected hash, to catch data that was corrupted by the
+// storage on the way to disk. A failure leaves the temp file in place, and
+// only the blocks that still verify are reused on the next attempt.
+func (f *sendReceiveFolder) verifyTempFile(file protocol.FileInfo, tempName string) error {
+	fd, err := f.tempFs.Open(tempName)
+	if err != nil {
+		return errors.Wrap(err, "verifying")
+	}
+	defer fd.Close()
+
+	buf := protocol.BufferPool.Get(file.BlockSize())
+	defer func() {
+		protocol.BufferPool.Put(buf)
+	}()
+
+	for i, block := range file.Blocks {
+		buf = protocol.BufferPool.Upgrade(buf, int(block.Size))
+		if _, err := fd.ReadAt(buf, block.Offset); err != nil {
+			return errors.Wrap(err, "verifying")
+		}
+		if !scanner.Validate(buf, block.Hash, block.WeakHash, file.HashAlgorithm) {
+			return fmt.Errorf("verifying: block %d (offset %d) does not match the expected hash", i, block.Offset)
+		}
+	}
+	return nil
+}
+
 func (f *sendReceiveFolder) finisherRoutine(in <-chan *sharedPullerState, dbUpdateChan chan<- dbUpdateJob, scanChan chan<- string) {
 	for state := range in {
+		f.markProgress()
 		if closed, err := state.finalClose(); closed {
 			l.Debugln(f, "closing", state.file.Name)
 			f.queue.Done(state.file.Name)
 
+			tempName := state.tempName
+			if err == nil && state.compressor != nil {
+				tempName, err = state.decompressTemp()
+			}
 			if err == nil {
-				err = f.performFinish(state.file, state.curFile, state.hasCurFile, state.tempName, dbUpdateChan, scanChan)
+				err = f.performFinish(state.file, state.curFile, state.hasCurFile, tempName, dbUpdateChan, scanChan)
 			}
 
 			if err != nil {
@@ -1593,6 +2061,9 @@ func (f *sendReceiveFolder) finisherRoutine(in <-chan *sharedPullerState, dbUpda
 			}
 
 			f.model.progressEmitter.Deregister(state)
+			f.pullersMut.Lock()
+			delete(f.pullers, state.file.Name)
+			f.pullersMut.Unlock()
 
 			f.evLogger.Log(events.ItemFinished, map[string]interface{}{
 				"folder": f.folderID,
@@ -1610,10 +2081,50 @@ func (f *sendReceiveFolder) BringToFront(filename string) {
 	f.queue.BringToFront(filename)
 }
 
+// Prioritize moves the given filename to the front of the job queue and, if
+// bumpRequests is set, lets its block requests skip the wait for the pending
+// request limit.
+func (f *sendReceiveFolder) Prioritize(filename string, bumpRequests bool) {
+	if bumpRequests {
+		f.queue.SetUrgent(filename)
+	}
+	f.queue.BringToFront(filename)
+}
+
 func (f *sendReceiveFolder) Jobs(page, perpage int) ([]string, []string, int) {
 	return f.queue.Jobs(page, perpage)
 }
 
+// PullerState describes what the puller of a folder is working on, for
+// understanding transfers that are stuck.
+type PullerState struct {
+	Pulling     []PullingFile `json:"pulling"`
+	Queued      []QueuedFile  `json:"queued"`
+	QueuedTotal int           `json:"queuedTotal"`
+}
+
+// PullerState returns the files being pulled, oldest first, and up to
+// queued of the files waiting in the job queue.
+func (f *sendReceiveFolder) PullerState(queued int) PullerState {
+	now := time.Now()
+	f.pullersMut.Lock()
+	pulling := make([]PullingFile, 0, len(f.pullers))
+	for _, s := range f.pullers {
+		pulling = append(pulling, s.pullingFile(now))
+	}
+	f.pullersMut.Unlock()
+	sort.Slice(pulling, func(a, b int) bool {
+		return pulling[a].Started.Before(pulling[b].Started)
+	})
+
+	files, total := f.queue.queuedFiles(queued)
+	return PullerState{
+		Pulling:     pulling,
+		Queued:      files,
+		QueuedTotal: total,
+	}
+}
+
 // dbUpdaterRoutine aggregates db updates and commits them in batches no
 // larger than 1000 items, and no more delayed than 2 seconds.
 func (f *sendReceiveFolder) dbUpdaterRoutine(dbUpdateChan <-chan dbUpdateJob) {
@@ -1631,15 +2142,9 @@ func (f *sendReceiveFolder) dbUpdaterRoutine(dbUpdateChan <-chan dbUpdateJob) {
 		// sync directories
 		for dir := range changedDirs {
 			delete(changedDirs, dir)
-			fd, err := f.fs.Open(dir)
-			if err != nil {
-				l.Debugf("fsync %q failed: %v", dir, err)
-				continue
+			if f.Durability.SyncDirs() {
+				syncDir(f.fs, dir)
 			}
-			if err := fd.Sync(); err != nil {
-				l.Debugf("fsync %q failed: %v", dir, err)
-			}
-			fd.Close()
 		}
 
 		// All updates to file/folder objects that originated remotely
@@ -1682,6 +2187,7 @@ loop:
 			job.file.Sequence = 0
 
 			batch.append(job.file)
+			f.markProgress()
 
 			batch.flushIfFull()
 
@@ -1836,7 +2342,7 @@ func (f *sendReceiveFolder) deleteItemOnDisk(item protocol.FileInfo, scanChan ch
 		// to potential children.
 		return f.deleteDirOnDisk(item.Name, scanChan)
 
-	case !item.IsSymlink() && f.versioner != nil:
+	case !item.IsSymlink() && !item.IsPlaceholder() && f.versioner != nil:
 		// If we should use versioning, let the versioner archive the
 		// file before we replace it. Archiving a non-existent file is not
 		// an error.
@@ -1940,6 +2446,11 @@ func (f *sendReceiveFolder) scanIfItemChanged(stat fs.FileInfo, item protocol.Fi
 		return errors.Wrap(err, "comparing item on disk to db")
 	}
 
+	if item.IsPlaceholder() {
+		// What's on disk is the placeholder, not the file.
+		item.Size = 0
+	}
+
 	if !statItem.IsEquivalentOptional(item, f.ModTimeWindow(), f.IgnorePerms, true, protocol.LocalAllFlags) {
 		return errModified
 	}
@@ -1964,7 +2475,19 @@ func (f *sendReceiveFolder) checkToBeDeleted(cur protocol.FileInfo, scanChan cha
 	return f.scanIfItemChanged(stat, cur, true, scanChan)
 }
 
-func (f *sendReceiveFolder) maybeCopyOwner(path string) error {
+// checkCase returns errCaseConflict if there is an item under name in
+// another case, which on a case insensitive filesystem would be taken for
+// the one we want to handle.
+func (f *sendReceiveFolder) checkCase(name string) error {
+	if !f.cases.IsRealCase(name) {
+		return errCaseConflict
+	}
+	return nil
+}
+
+// maybeCopyOwner sets the owner of path in the given filesystem to that of
+// its parent directory in the folder, if we are configured to do so.
+func (f *sendReceiveFolder) maybeCopyOwner(filesystem fs.Filesystem, path string) error {
 	if !f.CopyOwnershipFromParent {
 		// Not supposed to do anything.
 		return nil
@@ -1978,12 +2501,30 @@ func (f *sendReceiveFolder) maybeCopyOwner(path string) error {
 	if err != nil {
 		return errors.Wrap(err, "copy owner from parent")
 	}
-	if err := f.fs.Lchown(path, info.Owner(), info.Group()); err != nil {
+	if err := filesystem.Lchown(path, info.Owner(), info.Group()); err != nil {
 		return errors.Wrap(err, "copy owner from parent")
 	}
 	return nil
 }
 
+// hasTempFile returns true if there is a complete temporary file for the
+// given file.
+func (f *sendReceiveFolder) hasTempFile(file protocol.FileInfo) bool {
+	info, err := f.tempFs.Lstat(fs.TempName(file.Name))
+	return err == nil && info.IsRegular() && info.Size() == file.Size
+}
+
+// ensureTempDir makes sure the parent directory of the given temp file
+// exists when temp files are kept outside of the folder. Inside the folder
+// the parent directory is the same as that of the final file and is
+// handled by the regular directory syncing.
+func (f *sendReceiveFolder) ensureTempDir(tempName string) error {
+	if f.TempPath == "" {
+		return nil
+	}
+	return f.tempFs.MkdirAll(filepath.Dir(tempName), 0755)
+}
+
 func (f *sendReceiveFolder) inWritableDir(fn func(string) error, path string) error {
 	return inWritableDir(fn, f.fs, path, f.IgnorePerms)
 }
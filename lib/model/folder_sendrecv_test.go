@@ -13,11 +13,17 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"os/user"
 	"path/filepath"
+	"reflect"
 	"runtime"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/syncthing/syncthing/lib/blockstore"
 	"github.com/syncthing/syncthing/lib/config"
 	"github.com/syncthing/syncthing/lib/db"
 	"github.com/syncthing/syncthing/lib/db/backend"
@@ -27,6 +33,7 @@ import (
 	"github.com/syncthing/syncthing/lib/osutil"
 	"github.com/syncthing/syncthing/lib/protocol"
 	"github.com/syncthing/syncthing/lib/scanner"
+	"github.com/syncthing/syncthing/lib/sha256"
 	"github.com/syncthing/syncthing/lib/sync"
 )
 
@@ -104,13 +111,19 @@ func setupSendReceiveFolder(files ...protocol.FileInfo) (*model, *sendReceiveFol
 			initialScanFinished: make(chan struct{}),
 			ctx:                 context.TODO(),
 			FolderConfiguration: fcfg,
+			scanErrorsMut:       sync.NewMutex(),
 		},
 
 		queue:         newJobQueue(),
+		pullers:       make(map[string]*sharedPullerState),
+		pullersMut:    sync.NewMutex(),
 		pullErrors:    make(map[string]string),
 		pullErrorsMut: sync.NewMutex(),
+		skippedMut:    sync.NewMutex(),
 	}
 	f.fs = fs.NewMtimeFS(f.Filesystem(), db.NewNamespacedKV(model.db, "mtime"))
+	f.tempFs = f.fs
+	f.cases = fs.NewCaseChecker(f.fs)
 
 	// Update index
 	if files != nil {
@@ -173,6 +186,42 @@ func TestHandleFile(t *testing.T) {
 	}
 }
 
+func TestHandleFileMaxFolderSize(t *testing.T) {
+	existingFile := setupFile("filex", []int{0, 2, 0})
+	existingFile.Size = 3 * protocol.MinBlockSize
+
+	m, f := setupSendReceiveFolder(existingFile)
+	defer cleanupSRFolder(f, m)
+	f.pullErrors = make(map[string]string)
+
+	copyChan := make(chan copyBlocksState, 1)
+	dbUpdateChan := make(chan dbUpdateJob, 1)
+
+	// Growing the existing file by two blocks is within the limit, after
+	// which no other file fits.
+	f.maxFolderBytes = 5 * protocol.MinBlockSize
+	f.folderBytes = existingFile.Size
+
+	requiredFile := setupFile("filex", []int{1, 2, 3, 4, 5})
+	requiredFile.Size = 5 * protocol.MinBlockSize
+	f.handleFile(requiredFile, copyChan, dbUpdateChan)
+	<-copyChan
+
+	newFile := setupFile("filey", []int{6})
+	newFile.Size = protocol.MinBlockSize
+	f.handleFile(newFile, copyChan, dbUpdateChan)
+	select {
+	case <-copyChan:
+		t.Error("The file should not have been pulled")
+	default:
+	}
+
+	errs := f.Errors()
+	if len(errs) != 1 || errs[0].Path != "filey" || !strings.Contains(errs[0].Err, errMaxFolderSize.Error()) {
+		t.Errorf("Unexpected errors %v", errs)
+	}
+}
+
 func TestHandleFileWithTemp(t *testing.T) {
 	// After diff between required and existing we should:
 	// Copy: 2, 5, 8
@@ -221,6 +270,107 @@ func TestHandleFileWithTemp(t *testing.T) {
 	}
 }
 
+func TestFinishFromTempPath(t *testing.T) {
+	m, f := setupSendReceiveFolder()
+	defer cleanupSRFolder(f, m)
+
+	tempDir := createTmpDir()
+	defer os.RemoveAll(tempDir)
+	f.TempPath = tempDir
+	f.tempFs = f.TempFilesystem()
+
+	name := filepath.Join("dir", "file")
+	must(t, f.fs.MkdirAll("dir", 0755))
+	tempName := fs.TempName(name)
+	must(t, f.ensureTempDir(tempName))
+	fd, err := f.tempFs.Create(tempName)
+	must(t, err)
+	_, err = fd.Write([]byte("hello"))
+	must(t, err)
+	fd.Close()
+
+	file := protocol.FileInfo{
+		Name:        name,
+		Type:        protocol.FileInfoTypeFile,
+		Size:        5,
+		Permissions: 0644,
+		ModifiedS:   time.Now().Unix(),
+	}
+	dbUpdateChan := make(chan dbUpdateJob, 1)
+	scanChan := make(chan string, 1)
+	must(t, f.performFinish(file, protocol.FileInfo{}, false, tempName, dbUpdateChan, scanChan))
+
+	if _, err := f.tempFs.Lstat(tempName); !fs.IsNotExist(err) {
+		t.Errorf("temp file should be gone from the temp path, got %v", err)
+	}
+	bs, err := ioutil.ReadFile(filepath.Join(f.Filesystem().URI(), name))
+	must(t, err)
+	if string(bs) != "hello" {
+		t.Errorf("unexpected file contents %q", bs)
+	}
+}
+
+func TestVerifyAfterWrite(t *testing.T) {
+	m, f := setupSendReceiveFolder()
+	defer cleanupSRFolder(f, m)
+	f.VerifyAfterWrite = true
+
+	s := m.evLogger.Subscribe(events.ItemVerified)
+	defer s.Unsubscribe()
+
+	hash := sha256.Sum256([]byte("hello"))
+	file := protocol.FileInfo{
+		Name:        "verified",
+		Type:        protocol.FileInfoTypeFile,
+		Size:        5,
+		Permissions: 0644,
+		ModifiedS:   time.Now().Unix(),
+		Blocks:      []protocol.BlockInfo{{Size: 5, Hash: hash[:]}},
+	}
+	tempName := fs.TempName(file.Name)
+	dbUpdateChan := make(chan dbUpdateJob, 1)
+	scanChan := make(chan string, 1)
+
+	// A temp file with the wrong contents is not moved into place.
+
+	writeTemp := func(data string) {
+		t.Helper()
+		fd, err := f.tempFs.Create(tempName)
+		must(t, err)
+		_, err = fd.Write([]byte(data))
+		must(t, err)
+		fd.Close()
+	}
+	writeTemp("hellp")
+	if err := f.performFinish(file, protocol.FileInfo{}, false, tempName, dbUpdateChan, scanChan); err == nil {
+		t.Fatal("expected verification to fail")
+	}
+	if ev, err := s.Poll(time.Minute); err != nil {
+		t.Fatal(err)
+	} else if ev.Data.(map[string]interface{})["error"] == nil {
+		t.Error("expected event with error")
+	}
+	if _, err := f.fs.Lstat(file.Name); !fs.IsNotExist(err) {
+		t.Errorf("file should not have been moved into place, got %v", err)
+	}
+	if _, err := f.tempFs.Lstat(tempName); err != nil {
+		t.Errorf("temp file should have been kept, got %v", err)
+	}
+
+	// With the right contents it is.
+
+	writeTemp("hello")
+	must(t, f.performFinish(file, protocol.FileInfo{}, false, tempName, dbUpdateChan, scanChan))
+	if ev, err := s.Poll(time.Minute); err != nil {
+		t.Fatal(err)
+	} else if err := ev.Data.(map[string]interface{})["error"]; err != (*string)(nil) {
+		t.Error("unexpected error", err)
+	}
+	if _, err := f.fs.Lstat(file.Name); err != nil {
+		t.Error(err)
+	}
+}
+
 func TestCopierFinder(t *testing.T) {
 	// After diff between required and existing we should:
 	// Copy: 1, 2, 3, 4, 6, 7, 8
@@ -313,6 +463,166 @@ func TestCopierFinder(t *testing.T) {
 	}
 }
 
+func TestCopierContentDefined(t *testing.T) {
+	// The required file is the existing one with a few bytes inserted at
+	// the start. As its blocks are content defined, all but those around
+	// the insertion are found in the existing file, at other offsets.
+
+	data := make([]byte, 1<<20)
+	rand.Read(data)
+	changed := append([]byte("inserted"), data...)
+
+	chunked := func(name string, data []byte) protocol.FileInfo {
+		t.Helper()
+		blocks, err := scanner.Chunks(context.TODO(), bytes.NewReader(data), protocol.MinBlockSize, int64(len(data)), nil, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return protocol.FileInfo{
+			Name:         name,
+			Type:         protocol.FileInfoTypeFile,
+			Size:         int64(len(data)),
+			Version:      protocol.Vector{}.Update(myID.Short()),
+			RawBlockSize: protocol.MinBlockSize,
+			BlockScheme:  protocol.BlockSchemeFastCDC,
+			Blocks:       blocks,
+		}
+	}
+	existingFile := chunked("file", data)
+	requiredFile := chunked("file2", changed)
+
+	m, f := setupSendReceiveFolder(existingFile)
+	defer cleanupSRFolder(f, m)
+
+	// The copier looks for blocks in the configured folders.
+	_, err := m.cfg.SetFolder(f.FolderConfiguration)
+	must(t, err)
+	must(t, ioutil.WriteFile(filepath.Join(f.Filesystem().URI(), "file"), data, 0644))
+
+	copyChan := make(chan copyBlocksState)
+	pullChan := make(chan pullBlockState, len(requiredFile.Blocks))
+	finisherChan := make(chan *sharedPullerState, 1)
+	dbUpdateChan := make(chan dbUpdateJob, 1)
+
+	go f.copierRoutine(copyChan, pullChan, finisherChan)
+	defer close(copyChan)
+
+	f.handleFile(requiredFile, copyChan, dbUpdateChan)
+
+	var finish *sharedPullerState
+	select {
+	case finish = <-finisherChan:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Timed out waiting for the copier")
+	}
+	defer cleanupSharedPullerState(finish)
+
+	if pulls := len(pullChan); pulls == 0 || pulls > 2 {
+		t.Errorf("expected one or two of %d blocks to be pulled, got %d", len(requiredFile.Blocks), pulls)
+	}
+	pulled := make(map[int64]bool)
+	for len(pullChan) > 0 {
+		pulled[(<-pullChan).block.Offset] = true
+	}
+
+	// Everything else was copied into place.
+	fd, err := f.fs.Open(fs.TempName("file2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+	for _, block := range requiredFile.Blocks {
+		if pulled[block.Offset] {
+			continue
+		}
+		buf := make([]byte, block.Size)
+		if _, err := fd.ReadAt(buf, block.Offset); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf, changed[block.Offset:block.Offset+int64(block.Size)]) {
+			t.Errorf("block at offset %d was not copied correctly", block.Offset)
+		}
+	}
+}
+
+func TestCopierBlockStore(t *testing.T) {
+	// Block 2 is in the temp file at index 1, but not announced as being
+	// there, so it can only come from the block cache:
+	// Pull: 1, 5, 6, 8
+
+	tempFile := fs.TempName("file2")
+
+	existingBlocks := []int{0, 0, 3, 4, 0, 0, 7, 0}
+	existingFile := setupFile(fs.TempName("file"), existingBlocks)
+	requiredFile := existingFile
+	requiredFile.Blocks = blocks[1:]
+	requiredFile.Name = "file2"
+
+	m, f := setupSendReceiveFolder(existingFile)
+	defer cleanupSRFolder(f, m)
+
+	tmpName, err := prepareTmpFile(f.Filesystem())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd, err := f.fs.Open(tmpName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, blocks[2].Size)
+	_, err = fd.ReadAt(data, int64(blocks[2].Size))
+	fd.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "syncthing-blockcache-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	m.blockStore, err = blockstore.Open(dir, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	must(t, m.blockStore.Put(blocks[2].Hash, data))
+
+	copyChan := make(chan copyBlocksState)
+	pullChan := make(chan pullBlockState, 5)
+	finisherChan := make(chan *sharedPullerState, 1)
+	dbUpdateChan := make(chan dbUpdateJob, 1)
+
+	go f.copierRoutine(copyChan, pullChan, finisherChan)
+	defer close(copyChan)
+
+	f.handleFile(requiredFile, copyChan, dbUpdateChan)
+
+	var finish *sharedPullerState
+	select {
+	case finish = <-finisherChan:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Timed out before the copier finished")
+	}
+	defer cleanupSharedPullerState(finish)
+
+	if len(pullChan) != 4 {
+		t.Errorf("expected 4 blocks to be pulled, got %d", len(pullChan))
+	}
+	for len(pullChan) > 0 {
+		if ps := <-pullChan; string(ps.block.Hash) == string(blocks[2].Hash) {
+			t.Error("block from the cache should not be pulled")
+		}
+	}
+
+	blks, err := scanner.HashFile(context.TODO(), f.Filesystem(), tempFile, protocol.MinBlockSize, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(blks[1].Hash) != string(blocks[2].Hash) {
+		t.Errorf("Block 2 mismatch: %s != %s", blks[1].String(), blocks[2].String())
+	}
+}
+
 func TestWeakHash(t *testing.T) {
 	// Setup the model/pull environment
 	model, fo := setupSendReceiveFolder()
@@ -700,6 +1010,63 @@ func TestIssue3164(t *testing.T) {
 	}
 }
 
+func TestAcceptPolicy(t *testing.T) {
+	m, f := setupSendReceiveFolder()
+	defer cleanupSRFolder(f, m)
+	f.ignores = ignore.New(f.Filesystem())
+	f.acceptPolicy = newAcceptPolicy(config.AcceptPolicy{
+		RejectDeletes:           true,
+		RejectPermissionChanges: true,
+		Patterns:                []string{"*.txt"},
+	})
+
+	newFile := func(name string, perms uint32) protocol.FileInfo {
+		return protocol.FileInfo{
+			Name:        name,
+			Type:        protocol.FileInfoTypeFile,
+			Permissions: perms,
+			Version:     protocol.Vector{}.Update(myID.Short()),
+			Size:        int64(blocks[1].Size),
+			Blocks:      blocks[1:2],
+		}
+	}
+
+	deleted := newFile("deleted.txt", 0644)
+	perms := newFile("perms.txt", 0644)
+	f.updateLocalsFromScanning([]protocol.FileInfo{deleted, perms})
+
+	deleted.Deleted = true
+	deleted.Blocks = nil
+	deleted.Size = 0
+	deleted.Version = deleted.Version.Update(device1.Short())
+	perms.Permissions = 0755
+	perms.Version = perms.Version.Update(device1.Short())
+	f.fset.Update(device1, []protocol.FileInfo{deleted, perms, newFile("new.txt", 0644), newFile("new.jpg", 0644)})
+
+	dbUpdateChan := make(chan dbUpdateJob, 10)
+	changed, _, _, err := f.processNeeded(dbUpdateChan, make(chan copyBlocksState), make(chan string, 10))
+	must(t, err)
+	if changed != 1 {
+		t.Errorf("expected only new.txt to be accepted, got %d changes", changed)
+	}
+
+	skipped, err := f.SkippedChanges()
+	must(t, err)
+	expected := map[string]string{
+		"deleted.txt": skipReasonDelete,
+		"new.jpg":     skipReasonPattern,
+		"perms.txt":   skipReasonPermissions,
+	}
+	if len(skipped) != len(expected) {
+		t.Fatalf("expected %d skipped changes, got %v", len(expected), skipped)
+	}
+	for _, c := range skipped {
+		if reason := expected[c.Name]; c.Reason != reason {
+			t.Errorf("%s: expected reason %q, got %q", c.Name, reason, c.Reason)
+		}
+	}
+}
+
 func TestDiff(t *testing.T) {
 	for i, test := range diffTestData {
 		a, _ := scanner.Blocks(context.TODO(), bytes.NewBufferString(test.a), test.s, -1, nil, false)
@@ -736,6 +1103,26 @@ func BenchmarkDiff(b *testing.B) {
 	}
 }
 
+func TestRarestFirst(t *testing.T) {
+	bs := []protocol.BlockInfo{
+		{Offset: 0, Size: protocol.MinBlockSize},
+		{Offset: protocol.MinBlockSize, Size: protocol.MinBlockSize},
+		{Offset: 2 * protocol.MinBlockSize, Size: protocol.MinBlockSize},
+		{Offset: 3 * protocol.MinBlockSize, Size: protocol.MinBlockSize},
+	}
+
+	// Block 2 is the rarest, blocks 0 and 3 are equally rare and keep
+	// their relative order.
+	rarestFirst(bs, protocol.FileInfo{RawBlockSize: protocol.MinBlockSize}, []int{2, 3, 1, 2})
+
+	expected := []int64{2, 0, 3, 1}
+	for i, b := range bs {
+		if idx := b.Offset / protocol.MinBlockSize; idx != expected[i] {
+			t.Errorf("position %d: expected block %d, got %d", i, expected[i], idx)
+		}
+	}
+}
+
 func TestDiffEmpty(t *testing.T) {
 	emptyCases := []struct {
 		a    []protocol.BlockInfo
@@ -815,6 +1202,7 @@ func TestCopyOwner(t *testing.T) {
 	f.folder.FolderConfiguration.CopyOwnershipFromParent = true
 
 	f.fs = f.Filesystem()
+	f.tempFs = f.fs
 
 	// Create a parent dir with a certain owner/group.
 
@@ -1026,6 +1414,55 @@ func cleanupSharedPullerState(s *sharedPullerState) {
 	s.writer.mut.Unlock()
 }
 
+func TestPullXattrs(t *testing.T) {
+	m, f := setupSendReceiveFolder()
+	defer cleanupSRFolder(f, m)
+	f.SyncXattrs = true
+
+	if err := f.fs.SetXattr(".", nil); err == fs.ErrXattrsNotSupported {
+		t.Skip("extended attributes not supported")
+	}
+
+	xattrs := []protocol.Xattr{{Name: "user.tag", Value: []byte("red")}}
+	dir := protocol.FileInfo{
+		Name:        "dir",
+		Type:        protocol.FileInfoTypeDirectory,
+		Permissions: 0755,
+		Xattrs:      xattrs,
+	}
+	file := protocol.FileInfo{
+		Name:        "dir/file",
+		Type:        protocol.FileInfoTypeFile,
+		Permissions: 0644,
+		Xattrs:      xattrs,
+	}
+
+	dbUpdateChan := make(chan dbUpdateJob, 1)
+	defer close(dbUpdateChan)
+	f.handleDir(dir, dbUpdateChan, nil)
+	<-dbUpdateChan
+
+	finisherChan := make(chan *sharedPullerState)
+	copierChan, copyWg := startCopier(f, nil, finisherChan)
+	go f.finisherRoutine(finisherChan, dbUpdateChan, nil)
+	defer func() {
+		close(copierChan)
+		copyWg.Wait()
+		close(finisherChan)
+	}()
+
+	f.handleFile(file, copierChan, nil)
+	<-dbUpdateChan
+
+	for _, name := range []string{"dir", "dir/file"} {
+		got, err := f.fs.GetXattr(name)
+		must(t, err)
+		if !reflect.DeepEqual(got, xattrs) {
+			t.Errorf("%s: got xattrs %v, expected %v", name, got, xattrs)
+		}
+	}
+}
+
 func startCopier(f *sendReceiveFolder, pullChan chan<- pullBlockState, finisherChan chan<- *sharedPullerState) (chan copyBlocksState, sync.WaitGroup) {
 	copyChan := make(chan copyBlocksState)
 	wg := sync.NewWaitGroup()
@@ -1036,3 +1473,104 @@ func startCopier(f *sendReceiveFolder, pullChan chan<- pullBlockState, finisherC
 	}()
 	return copyChan, wg
 }
+
+func TestLocalOwnership(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no numeric user IDs on Windows")
+	}
+	cur, err := user.Current()
+	if err != nil {
+		t.Skip("no current user:", err)
+	}
+
+	m, f := setupSendReceiveFolder()
+	defer cleanupSRFolder(f, m)
+	f.UserMappings = []config.NameMapping{{Remote: "remoteuser", Local: cur.Username}}
+
+	file := protocol.FileInfo{
+		Name:      "file",
+		Uid:       12345,
+		Gid:       54321,
+		OwnerName: "remoteuser",
+		GroupName: "syncthing-no-such-group",
+	}
+
+	if got := f.localOwnership(file); !reflect.DeepEqual(got, file) {
+		t.Error("ownership should be left alone when not syncing it, got", got)
+	}
+
+	f.SyncOwnership = true
+	got := f.localOwnership(file)
+	if got.OwnerName != cur.Username || strconv.Itoa(int(got.Uid)) != cur.Uid {
+		t.Errorf("expected owner %s (%s), got %s (%d)", cur.Username, cur.Uid, got.OwnerName, got.Uid)
+	}
+	expGroup, _ := osutil.GroupName(54321)
+	if got.GroupName != expGroup || got.Gid != 54321 {
+		t.Errorf("expected unknown group to keep its ID, got %q (%d)", got.GroupName, got.Gid)
+	}
+}
+
+func TestPullCaseOnlyRename(t *testing.T) {
+	m, f := setupSendReceiveFolder()
+	defer cleanupSRFolder(f, m)
+	f.folder.FolderConfiguration = config.NewFolderConfiguration(m.id, f.ID, f.Label, fs.FilesystemTypeFake, "/TestPullCaseOnlyRename?insens=true")
+	f.fs = f.Filesystem()
+	f.tempFs = f.fs
+	f.cases = fs.NewCaseChecker(f.fs)
+	f.pullErrors = make(map[string]string)
+
+	cur := createFile(t, "Foo.txt", f.fs)
+	f.updateLocalsFromScanning([]protocol.FileInfo{cur})
+
+	source := cur
+	source.Deleted = true
+	source.Version = source.Version.Update(device1.Short())
+	target := cur
+	target.Name = "foo.txt"
+	target.Version = protocol.Vector{}.Update(device1.Short())
+
+	// Something else in another case of the name is in the way of a new
+	// file.
+	copyChan := make(chan copyBlocksState, 1)
+	dbUpdateChan := make(chan dbUpdateJob, 2)
+	other := target
+	other.Name = "FOO.TXT"
+	f.handleFile(other, copyChan, dbUpdateChan)
+	if errs := f.Errors(); len(errs) != 1 || !strings.Contains(errs[0].Err, errCaseConflict.Error()) {
+		t.Errorf("Expected a case conflict, got %v", errs)
+	}
+
+	// The rename to just another case goes ahead, leaving only the new
+	// name.
+	scanChan := make(chan string, 1)
+	if err := f.renameFile(cur, source, target, dbUpdateChan, scanChan); err != nil {
+		t.Fatal(err)
+	}
+	names, err := f.fs.DirNames(".")
+	must(t, err)
+	sort.Strings(names)
+	if !reflect.DeepEqual(names, []string{config.DefaultMarkerName, "foo.txt"}) {
+		t.Errorf("Expected only foo.txt, got %v", names)
+	}
+	for _, name := range []string{"foo.txt", "Foo.txt"} {
+		if u := <-dbUpdateChan; u.file.Name != name {
+			t.Errorf("Expected update for %v, got %v", name, u.file.Name)
+		}
+	}
+
+	// Deleting the old name must not remove the new one.
+	f.deleteFile(source, dbUpdateChan, scanChan)
+	select {
+	case name := <-scanChan:
+		t.Fatalf("Received %v on scanChan", name)
+	case u := <-dbUpdateChan:
+		if u.jobType != dbUpdateDeleteFile || u.file.Name != "Foo.txt" {
+			t.Errorf("Unexpected update %v for %v", u.jobType, u.file.Name)
+		}
+	default:
+		t.Fatal("No db update received")
+	}
+	if _, err := f.fs.Lstat("foo.txt"); err != nil {
+		t.Error("Expected foo.txt to still exist, got", err)
+	}
+}
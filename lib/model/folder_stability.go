@@ -0,0 +1,57 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"sort"
+	"time"
+)
+
+// rescanWhenStable schedules a scan of the files left out of a scan for
+// having been modified within the stability window, once they have been
+// left alone for that long. Files that are still changing by then are left
+// out again.
+func (f *folder) rescanWhenStable(paths []string) {
+	if len(paths) == 0 {
+		return
+	}
+	l.Debugf("%v: scanning %d unstable files in %ds", f, len(paths), f.StabilityWindowS)
+
+	f.unstableMut.Lock()
+	defer f.unstableMut.Unlock()
+	for _, path := range paths {
+		f.unstable[path] = struct{}{}
+	}
+	if f.unstableTimer == nil {
+		f.unstableTimer = time.AfterFunc(time.Duration(f.StabilityWindowS)*time.Second, f.scanUnstable)
+	}
+}
+
+func (f *folder) stopUnstableTimer() {
+	f.unstableMut.Lock()
+	defer f.unstableMut.Unlock()
+	if f.unstableTimer != nil {
+		f.unstableTimer.Stop()
+		f.unstableTimer = nil
+	}
+}
+
+func (f *folder) scanUnstable() {
+	f.unstableMut.Lock()
+	paths := make([]string, 0, len(f.unstable))
+	for path := range f.unstable {
+		paths = append(paths, path)
+	}
+	f.unstable = make(map[string]struct{})
+	f.unstableTimer = nil
+	f.unstableMut.Unlock()
+
+	sort.Strings(paths)
+	if err := f.Scan(paths); err != nil {
+		l.Debugf("%v: scanning unstable files: %v", f, err)
+	}
+}
@@ -16,6 +16,7 @@ import (
 
 	"github.com/syncthing/syncthing/lib/config"
 	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/fs"
 	"github.com/syncthing/syncthing/lib/protocol"
 	"github.com/syncthing/syncthing/lib/sync"
 	"github.com/syncthing/syncthing/lib/util"
@@ -96,9 +97,9 @@ func (c *folderSummaryService) Summary(folder string) (map[string]interface{}, e
 	need := c.model.NeedSize(folder)
 	res["needFiles"], res["needDirectories"], res["needSymlinks"], res["needDeletes"], res["needBytes"], res["needTotalItems"] = need.Files, need.Directories, need.Symlinks, need.Deleted, need.Bytes, need.TotalItems()
 
-	if c.cfg.Folders()[folder].Type == config.FolderTypeReceiveOnly {
+	if t := c.cfg.Folders()[folder].Type; t == config.FolderTypeReceiveOnly || t == config.FolderTypeMirror {
 		// Add statistics for things that have changed locally in a receive
-		// only folder.
+		// only or mirror folder.
 		ro := c.model.ReceiveOnlyChangedSize(folder)
 		res["receiveOnlyChangedFiles"] = ro.Files
 		res["receiveOnlyChangedDirectories"] = ro.Directories
@@ -114,6 +115,9 @@ func (c *folderSummaryService) Summary(folder string) (map[string]interface{}, e
 	if err != nil {
 		res["error"] = err.Error()
 	}
+	if progress, ok := c.model.ScanProgress(folder); ok {
+		res["scanProgress"] = progress
+	}
 
 	ourSeq, _ := c.model.CurrentSequence(folder)
 	remoteSeq, _ := c.model.RemoteSequence(folder)
@@ -134,6 +138,23 @@ func (c *folderSummaryService) Summary(folder string) (map[string]interface{}, e
 	if err != nil {
 		res["watchError"] = err.Error()
 	}
+	res["watchAggregationMode"] = c.model.WatchAggregationMode(folder).String()
+
+	res["maxFolderBytes"] = int64(0)
+	res["maxFolderSizeExceeded"] = false
+	for _, fe := range errors {
+		if strings.Contains(fe.Err, errMaxFolderSize.Error()) {
+			res["maxFolderSizeExceeded"] = true
+			break
+		}
+	}
+
+	if fcfg, ok := c.cfg.Folder(folder); ok {
+		res["maxFolderBytes"] = fcfg.MaxFolderBytes()
+		if tfs, ok := fcfg.Filesystem().(fs.TieredFilesystem); ok {
+			res["offloadedFiles"], res["offloadedBytes"] = tfs.Offloaded()
+		}
+	}
 
 	return res, nil
 }
@@ -13,6 +13,8 @@ import (
 
 	"github.com/d4l3k/messagediff"
 	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/scanner"
 )
 
 type unifySubsCase struct {
@@ -148,3 +150,23 @@ func BenchmarkUnifySubs(b *testing.B) {
 		}
 	}
 }
+
+func TestScanProgressState(t *testing.T) {
+	st := newStateTracker("default", events.NoopLogger)
+
+	st.setScanProgress(scanner.ScanProgress{File: "a"})
+	if _, ok := st.getScanProgress(); ok {
+		t.Error("expected no progress when not scanning")
+	}
+
+	st.setState(FolderScanning)
+	st.setScanProgress(scanner.ScanProgress{File: "a", Dir: "."})
+	if p, ok := st.getScanProgress(); !ok || p.File != "a" {
+		t.Errorf("expected progress on a, got %v, %v", p, ok)
+	}
+
+	st.setState(FolderIdle)
+	if _, ok := st.getScanProgress(); ok {
+		t.Error("expected progress to be cleared when done scanning")
+	}
+}
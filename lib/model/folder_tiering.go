@@ -0,0 +1,85 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"context"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// How often to look for files to offload. Coldness is counted in days, so
+// there is no point in doing it much more often.
+const tieringInterval = 6 * time.Hour
+
+// tierer periodically offloads files that have gone cold to the tiering
+// path of the folder.
+func (f *folder) tierer() {
+	tfs, ok := f.Filesystem().(fs.TieredFilesystem)
+	if !ok {
+		return
+	}
+
+	timer := time.NewTimer(tieringInterval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+		case <-f.ctx.Done():
+			return
+		}
+
+		if err := f.CheckHealth(); err != nil {
+			l.Debugln(f, "skipping offloading:", err)
+		} else {
+			f.offloadCold(f.ctx, tfs)
+		}
+
+		timer.Reset(tieringInterval)
+	}
+}
+
+// offloadCold makes one pass over the files in the folder, offloading
+// those that haven't been accessed or modified for the configured number
+// of days.
+func (f *folder) offloadCold(ctx context.Context, tfs fs.TieredFilesystem) {
+	coldSince := time.Now().Add(-time.Duration(f.TieringColdDays) * 24 * time.Hour)
+
+	// Files modified since are not going to be cold by access time either.
+	var names []string
+	f.fset.WithHaveTruncated(protocol.LocalDeviceID, func(fi db.FileIntf) bool {
+		if !fi.IsDirectory() && !fi.IsSymlink() && !fi.IsDeleted() && !fi.IsInvalid() && fi.FileSize() > 0 && fi.ModTime().Before(coldSince) {
+			names = append(names, fi.FileName())
+		}
+		return true
+	})
+
+	offloaded := 0
+	for _, name := range names {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		ok, err := tfs.Offload(name, coldSince)
+		if err != nil {
+			l.Debugf("%v offloading %s: %v", f, name, err)
+			continue
+		}
+		if ok {
+			offloaded++
+		}
+	}
+
+	if offloaded > 0 {
+		l.Infof("Offloaded %d cold files of folder %s", offloaded, f.Description())
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// FolderSettings are the settings of a folder that a device may push to the
+// others sharing it.
+type FolderSettings struct {
+	IgnorePatterns []string                       `json:"ignorePatterns"`
+	Versioning     config.VersioningConfiguration `json:"versioning"`
+}
+
+// PendingFolderSettings are pushed settings waiting for the user to accept
+// or reject them.
+type PendingFolderSettings struct {
+	Folder   string            `json:"folder"`
+	Device   protocol.DeviceID `json:"device"`
+	Received time.Time         `json:"received"`
+	FolderSettings
+}
+
+// adoptableVersioningParams are the versioning types we adopt from other
+// devices, with the parameters of each that we take along. Those running
+// commands or pointing at arbitrary places on disk, like the external type
+// or the fsPath and versionsPath parameters, are never adopted.
+var adoptableVersioningParams = map[string][]string{
+	"":          nil,
+	"simple":    {"keep"},
+	"trashcan":  {"cleanoutDays"},
+	"staggered": {"maxAge", "cleanInterval"},
+	"tiered":    {"keepAllHours", "hourlyDays", "dailyDays", "weeklyDays", "cleanInterval"},
+}
+
+// adoptableVersioning returns the pushed versioning without the parameters
+// we don't take along, or false if we don't adopt its type at all.
+func adoptableVersioning(v config.VersioningConfiguration) (config.VersioningConfiguration, bool) {
+	keys, ok := adoptableVersioningParams[v.Type]
+	if !ok {
+		return config.VersioningConfiguration{}, false
+	}
+	adopted := config.VersioningConfiguration{Type: v.Type}
+	for _, key := range keys {
+		if val, ok := v.Params[key]; ok {
+			if adopted.Params == nil {
+				adopted.Params = make(map[string]string)
+			}
+			adopted.Params[key] = val
+		}
+	}
+	return adopted, true
+}
+
+func folderSettingsFromProtocol(s *protocol.FolderSettings) FolderSettings {
+	return FolderSettings{
+		IgnorePatterns: s.IgnorePatterns,
+		Versioning: config.VersioningConfiguration{
+			Type:   s.VersioningType,
+			Params: s.VersioningParams,
+		},
+	}
+}
+
+func (s FolderSettings) toProtocol() *protocol.FolderSettings {
+	return &protocol.FolderSettings{
+		IgnorePatterns:   s.IgnorePatterns,
+		VersioningType:   s.Versioning.Type,
+		VersioningParams: s.Versioning.Params,
+	}
+}
+
+func (s FolderSettings) ignoresEqual(other FolderSettings) bool {
+	if len(s.IgnorePatterns) == 0 && len(other.IgnorePatterns) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(s.IgnorePatterns, other.IgnorePatterns)
+}
+
+func (s FolderSettings) versioningEqual(other FolderSettings) bool {
+	if s.Versioning.Type != other.Versioning.Type {
+		return false
+	}
+	if len(s.Versioning.Params) == 0 && len(other.Versioning.Params) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(s.Versioning.Params, other.Versioning.Params)
+}
+
+// pushedSettingsLocked returns the settings to push for the folder, or nil
+// if it doesn't push them. Need to hold (read) lock on m.fmut when calling
+// this.
+func (m *model) pushedSettingsLocked(cfg config.FolderConfiguration) *protocol.FolderSettings {
+	if !cfg.PushSettings {
+		return nil
+	}
+	settings := FolderSettings{Versioning: cfg.Versioning}
+	if ignores, ok := m.folderIgnores[cfg.ID]; ok {
+		settings.IgnorePatterns = ignores.Lines()
+	}
+	return settings.toProtocol()
+}
+
+// handlePushedSettings acts on the folder settings pushed by the device
+// according to our policy for it. It must not be called with m.fmut held,
+// as applying settings can cause CommitConfiguration.
+func (m *model) handlePushedSettings(deviceCfg config.DeviceConfiguration, folder protocol.Folder) {
+	cfg, ok := m.cfg.Folder(folder.ID)
+	if !ok || !cfg.SharedWith(deviceCfg.DeviceID) || cfg.PushSettings {
+		// We don't take settings for folders we push settings for
+		// ourselves, lest two devices keep overriding each other.
+		return
+	}
+
+	pushed := folderSettingsFromProtocol(folder.Settings)
+	current, err := m.currentFolderSettings(cfg)
+	if err != nil {
+		l.Infof("Not adopting settings for folder %s from %s: %v", cfg.Description(), deviceCfg.DeviceID, err)
+		return
+	}
+	if versioning, ok := adoptableVersioning(pushed.Versioning); ok {
+		pushed.Versioning = versioning
+	} else {
+		l.Infof("Not adopting %q versioning for folder %s from %s", pushed.Versioning.Type, cfg.Description(), deviceCfg.DeviceID)
+		pushed.Versioning = current.Versioning
+	}
+	if pushed.ignoresEqual(current) && pushed.versioningEqual(current) {
+		m.fmut.Lock()
+		delete(m.pendingSettings, folder.ID)
+		m.fmut.Unlock()
+		return
+	}
+
+	switch deviceCfg.SettingsPolicy {
+	case config.SettingsPolicyApply:
+		// Applying settings may restart the folder, which waits for this
+		// very connection to close.
+		go func() {
+			if err := m.applyFolderSettings(folder.ID, deviceCfg.DeviceID, pushed); err != nil {
+				l.Warnf("Adopting settings for folder %s from %s: %v", cfg.Description(), deviceCfg.DeviceID, err)
+			}
+		}()
+
+	case config.SettingsPolicyAsk:
+		m.fmut.Lock()
+		m.pendingSettings[folder.ID] = PendingFolderSettings{
+			Folder:         folder.ID,
+			Device:         deviceCfg.DeviceID,
+			Received:       time.Now(),
+			FolderSettings: pushed,
+		}
+		m.fmut.Unlock()
+		l.Infof("Device %s pushed new settings for folder %s, waiting to be accepted", deviceCfg.DeviceID, cfg.Description())
+		m.evLogger.Log(events.FolderSettingsPending, map[string]string{
+			"folder":      folder.ID,
+			"folderLabel": cfg.Label,
+			"device":      deviceCfg.DeviceID.String(),
+		})
+	}
+}
+
+func (m *model) currentFolderSettings(cfg config.FolderConfiguration) (FolderSettings, error) {
+	lines, _, err := m.GetIgnores(cfg.ID)
+	if err != nil {
+		return FolderSettings{}, err
+	}
+	return FolderSettings{
+		IgnorePatterns: lines,
+		Versioning:     cfg.Versioning,
+	}, nil
+}
+
+func (m *model) applyFolderSettings(folder string, device protocol.DeviceID, settings FolderSettings) error {
+	cfg, ok := m.cfg.Folder(folder)
+	if !ok {
+		return errFolderMissing
+	}
+	current, err := m.currentFolderSettings(cfg)
+	if err != nil {
+		return err
+	}
+
+	if !settings.ignoresEqual(current) {
+		if err := m.SetIgnores(folder, settings.IgnorePatterns); err != nil {
+			return err
+		}
+	}
+	if !settings.versioningEqual(current) {
+		cfg.Versioning = settings.Versioning
+		w, err := m.cfg.SetFolder(cfg)
+		if err != nil {
+			return err
+		}
+		w.Wait()
+		if err := m.cfg.Save(); err != nil {
+			l.Warnln("Failed to save config", err)
+		}
+	}
+
+	l.Infof("Adopted settings for folder %s from %s", cfg.Description(), device)
+	return nil
+}
+
+// PendingFolderSettings returns the pushed settings waiting to be accepted
+// or rejected, ordered by folder.
+func (m *model) PendingFolderSettings() []PendingFolderSettings {
+	m.fmut.RLock()
+	pending := make([]PendingFolderSettings, 0, len(m.pendingSettings))
+	for _, p := range m.pendingSettings {
+		pending = append(pending, p)
+	}
+	m.fmut.RUnlock()
+
+	sort.Slice(pending, func(a, b int) bool {
+		return pending[a].Folder < pending[b].Folder
+	})
+	return pending
+}
+
+// ResolveFolderSettings applies the pending settings of the folder if
+// accepted, and forgets about them either way.
+func (m *model) ResolveFolderSettings(folder string, accept bool) error {
+	m.fmut.Lock()
+	pending, ok := m.pendingSettings[folder]
+	delete(m.pendingSettings, folder)
+	m.fmut.Unlock()
+
+	if !ok {
+		return errNoPendingSettings
+	}
+	if !accept {
+		return nil
+	}
+	return m.applyFolderSettings(folder, pending.Device, pending.FolderSettings)
+}
@@ -0,0 +1,109 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+func TestPushedFolderSettings(t *testing.T) {
+	w, fcfg := tmpDefaultWrapper()
+	m, _ := setupModelWithConnectionFromWrapper(w)
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+
+	devCfg, _ := w.Device(device1)
+	devCfg.SettingsPolicy = config.SettingsPolicyAsk
+	folder := protocol.Folder{
+		ID: "default",
+		Settings: &protocol.FolderSettings{
+			IgnorePatterns:   []string{"*.tmp"},
+			VersioningType:   "trashcan",
+			VersioningParams: map[string]string{"cleanoutDays": "7"},
+		},
+	}
+
+	m.handlePushedSettings(devCfg, folder)
+	pending := m.PendingFolderSettings()
+	if len(pending) != 1 || pending[0].Folder != "default" || pending[0].Device != device1 {
+		t.Fatalf("expected pending settings for the folder, got %+v", pending)
+	}
+
+	if err := m.ResolveFolderSettings("default", true); err != nil {
+		t.Fatal(err)
+	}
+	if lines, _, err := m.GetIgnores("default"); err != nil || !reflect.DeepEqual(lines, []string{"*.tmp"}) {
+		t.Errorf("expected the pushed ignore patterns, got %v, %v", lines, err)
+	}
+	if cfg, _ := m.cfg.Folder("default"); cfg.Versioning.Type != "trashcan" || cfg.Versioning.Params["cleanoutDays"] != "7" {
+		t.Errorf("expected the pushed versioning, got %+v", cfg.Versioning)
+	}
+
+	// Settings we already have don't need accepting.
+	m.handlePushedSettings(devCfg, folder)
+	if pending := m.PendingFolderSettings(); len(pending) != 0 {
+		t.Errorf("expected nothing pending, got %+v", pending)
+	}
+	if err := m.ResolveFolderSettings("default", true); err != errNoPendingSettings {
+		t.Error("expected an error for nothing pending, got", err)
+	}
+
+	// Nor do we take settings for a folder we push settings for.
+	fcfg, _ = m.cfg.Folder("default")
+	fcfg.PushSettings = true
+	waiter, _ := m.cfg.SetFolder(fcfg)
+	waiter.Wait()
+	folder.Settings.IgnorePatterns = []string{"*.bak"}
+	m.handlePushedSettings(devCfg, folder)
+	if pending := m.PendingFolderSettings(); len(pending) != 0 {
+		t.Errorf("expected nothing pending, got %+v", pending)
+	}
+}
+
+func TestPushedVersioningRestricted(t *testing.T) {
+	w, fcfg := tmpDefaultWrapper()
+	m, _ := setupModelWithConnectionFromWrapper(w)
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+
+	devCfg, _ := w.Device(device1)
+	devCfg.SettingsPolicy = config.SettingsPolicyApply
+
+	// Commands aren't taken from other devices.
+	m.handlePushedSettings(devCfg, protocol.Folder{
+		ID: "default",
+		Settings: &protocol.FolderSettings{
+			VersioningType:   "external",
+			VersioningParams: map[string]string{"command": "rm -rf /"},
+		},
+	})
+	if pending := m.PendingFolderSettings(); len(pending) != 0 {
+		t.Errorf("expected nothing pending, got %+v", pending)
+	}
+	if cfg, _ := m.cfg.Folder("default"); cfg.Versioning.Type != "" || len(cfg.Versioning.Params) != 0 {
+		t.Errorf("expected no versioning, got %+v", cfg.Versioning)
+	}
+
+	// Nor are paths.
+	devCfg.SettingsPolicy = config.SettingsPolicyAsk
+	m.handlePushedSettings(devCfg, protocol.Folder{
+		ID: "default",
+		Settings: &protocol.FolderSettings{
+			VersioningType:   "staggered",
+			VersioningParams: map[string]string{"maxAge": "3600", "versionsPath": "/etc", "fsPath": "/etc"},
+		},
+	})
+	if err := m.ResolveFolderSettings("default", true); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"maxAge": "3600"}
+	if cfg, _ := m.cfg.Folder("default"); cfg.Versioning.Type != "staggered" || !reflect.DeepEqual(cfg.Versioning.Params, expected) {
+		t.Errorf("expected staggered versioning with only maxAge, got %+v", cfg.Versioning)
+	}
+}
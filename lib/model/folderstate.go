@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/scanner"
 	"github.com/syncthing/syncthing/lib/sync"
 )
 
@@ -47,10 +48,14 @@ type stateTracker struct {
 	folderID string
 	evLogger events.Logger
 
-	mut     sync.Mutex
-	current folderState
-	err     error
-	changed time.Time
+	mut      sync.Mutex
+	current  folderState
+	err      error
+	changed  time.Time
+	progress time.Time // last progress made in the current state
+
+	// The progress of hashing, while scanning
+	scanning *scanner.ScanProgress
 }
 
 func newStateTracker(id string, evLogger events.Logger) stateTracker {
@@ -92,6 +97,7 @@ func (s *stateTracker) setState(newState folderState) {
 
 	s.current = newState
 	s.changed = time.Now()
+	s.scanning = nil
 
 	s.evLogger.Log(events.StateChanged, eventData)
 }
@@ -105,6 +111,45 @@ func (s *stateTracker) getState() (current folderState, changed time.Time, err e
 	return
 }
 
+// markProgress records that the folder is getting somewhere in its current
+// state, e.g. an item was scanned or a block was pulled.
+func (s *stateTracker) markProgress() {
+	s.mut.Lock()
+	s.progress = time.Now()
+	s.mut.Unlock()
+}
+
+// getProgress returns the last time the folder made progress, or changed
+// state if that happened later.
+func (s *stateTracker) getProgress() time.Time {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if s.progress.After(s.changed) {
+		return s.progress
+	}
+	return s.changed
+}
+
+// setScanProgress records the progress of hashing in the current scan.
+func (s *stateTracker) setScanProgress(p scanner.ScanProgress) {
+	s.mut.Lock()
+	if s.current == FolderScanning {
+		s.scanning = &p
+	}
+	s.mut.Unlock()
+}
+
+// getScanProgress returns the progress of hashing, if the folder is
+// scanning and has got to hashing.
+func (s *stateTracker) getScanProgress() (scanner.ScanProgress, bool) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if s.scanning == nil {
+		return scanner.ScanProgress{}, false
+	}
+	return *s.scanning, true
+}
+
 // setError sets the folder state to FolderError with the specified error or
 // to FolderIdle if the error is nil
 func (s *stateTracker) setError(err error) {
@@ -131,6 +176,7 @@ func (s *stateTracker) setError(err error) {
 
 	s.err = err
 	s.changed = time.Now()
+	s.scanning = nil
 
 	s.evLogger.Log(events.StateChanged, eventData)
 }
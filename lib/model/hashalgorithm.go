@@ -0,0 +1,62 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// Whether a device understands files of a folder hashed with BLAKE3, as we
+// keep it in the database so that it is known before the device connects.
+func blake3SupportKey(folder string, device protocol.DeviceID) string {
+	return "blake3Support-" + folder + "-" + device.String()
+}
+
+// handleHashAlgorithms remembers whether the device understands files of
+// the folder hashed with BLAKE3, as it announced in its cluster config.
+func (m *model) handleHashAlgorithms(device protocol.DeviceID, folder protocol.Folder) {
+	misc := db.NewMiscDataNamespace(m.db)
+	if err := misc.PutBool(blake3SupportKey(folder.ID, device), folder.SupportsHashAlgorithm(protocol.HashAlgorithmBLAKE3)); err != nil {
+		l.Warnln("Storing supported hash algorithms:", err)
+	}
+}
+
+// folderHashAlgorithm returns the algorithm to hash the blocks of new and
+// changed files of the folder with. That is BLAKE3 if the folder is set to
+// use it and every device sharing it has announced that it understands it,
+// and SHA-256 otherwise.
+func (m *model) folderHashAlgorithm(cfg config.FolderConfiguration) protocol.HashAlgorithm {
+	if !cfg.BLAKE3Hashing {
+		return protocol.HashAlgorithmSHA256
+	}
+	misc := db.NewMiscDataNamespace(m.db)
+	for _, dev := range cfg.Devices {
+		if dev.DeviceID == m.id {
+			continue
+		}
+		if ok, _, _ := misc.Bool(blake3SupportKey(cfg.ID, dev.DeviceID)); !ok {
+			return protocol.HashAlgorithmSHA256
+		}
+	}
+	return protocol.HashAlgorithmBLAKE3
+}
+
+// requestHashAlgorithm returns the algorithm by which the blocks requested
+// from the file were hashed. Temporary files are of the global version,
+// others of ours.
+func (m *model) requestHashAlgorithm(folder, name string, fromTemporary bool) protocol.HashAlgorithm {
+	current := m.CurrentFolderFile
+	if fromTemporary {
+		current = m.CurrentGlobalFile
+	}
+	if f, ok := current(folder, name); ok {
+		return f.HashAlgorithm
+	}
+	return protocol.HashAlgorithmSHA256
+}
@@ -0,0 +1,48 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+func TestFolderHashAlgorithm(t *testing.T) {
+	w, fcfg := tmpDefaultWrapper()
+	m, _ := setupModelWithConnectionFromWrapper(w)
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+
+	cm := m.generateClusterConfig(device1)
+	if len(cm.Folders) != 1 || !reflect.DeepEqual(cm.Folders[0].HashAlgorithms, protocol.SupportedHashAlgorithms) {
+		t.Errorf("expected the supported hash algorithms to be announced, got %+v", cm.Folders)
+	}
+
+	// BLAKE3 isn't used unless asked for, nor until the other device has
+	// announced that it understands it.
+	m.handleHashAlgorithms(device1, protocol.Folder{ID: "default", HashAlgorithms: protocol.SupportedHashAlgorithms})
+	if algo := m.folderHashAlgorithm(fcfg); algo != protocol.HashAlgorithmSHA256 {
+		t.Errorf("expected SHA-256 when BLAKE3 isn't enabled, got %v", algo)
+	}
+	fcfg.BLAKE3Hashing = true
+	m.handleHashAlgorithms(device1, protocol.Folder{ID: "default"})
+	if algo := m.folderHashAlgorithm(fcfg); algo != protocol.HashAlgorithmSHA256 {
+		t.Errorf("expected SHA-256 for a device that doesn't understand BLAKE3, got %v", algo)
+	}
+	m.handleHashAlgorithms(device1, protocol.Folder{ID: "default", HashAlgorithms: protocol.SupportedHashAlgorithms})
+	if algo := m.folderHashAlgorithm(fcfg); algo != protocol.HashAlgorithmBLAKE3 {
+		t.Errorf("expected BLAKE3, got %v", algo)
+	}
+
+	// Every device sharing the folder must understand it.
+	fcfg.Devices = append(fcfg.Devices, config.FolderDeviceConfiguration{DeviceID: device2})
+	if algo := m.folderHashAlgorithm(fcfg); algo != protocol.HashAlgorithmSHA256 {
+		t.Errorf("expected SHA-256 for a device that hasn't announced anything, got %v", algo)
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"encoding/binary"
+	"path/filepath"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/db/backend"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+const (
+	hashCacheName = "hashcache"
+
+	// Entries are rewritten when older than this, and removed by the
+	// garbage collection when not rewritten for twice as long.
+	hashCacheMaxAge      = 30 * 24 * time.Hour
+	hashCacheGCInterval  = 24 * time.Hour
+	hashCacheKeyLength   = 8 + 8 + 8 + 4 // inode, size, mtime, block size
+	hashCacheStampLength = 8
+)
+
+// The hashCache is a scanner.HashCache persisted in the folder marker
+// directory. As it is keyed on inode, size and modification time rather
+// than the file name, and lives with the data rather than in the database,
+// it survives the database being moved or recreated and the folder being
+// removed and added again, saving a full rehash of unchanged files.
+type hashCache struct {
+	db     backend.Backend
+	lastGC time.Time
+}
+
+// openHashCache opens the hash cache for the given folder. Only folders on
+// the basic filesystem with the default marker directory have one. Unless
+// create is true, a cache that doesn't exist yet is not created. The marker
+// directory itself is never created, as a folder without one is either
+// broken or being removed.
+func openHashCache(cfg config.FolderConfiguration, create bool) (*hashCache, error) {
+	if cfg.FilesystemType != fs.FilesystemTypeBasic || cfg.MarkerName != config.DefaultMarkerName {
+		return nil, nil
+	}
+	if cfg.EncryptionPassphrase != "" {
+		// The cache would keep the hashes of the plaintext unencrypted.
+		return nil, nil
+	}
+	ffs := cfg.Filesystem()
+	name := filepath.Join(config.DefaultMarkerName, hashCacheName)
+	if info, err := ffs.Stat(name); fs.IsNotExist(err) && create {
+		// Mkdir rather than MkdirAll, to fail if the marker is gone.
+		if err := ffs.Mkdir(name, 0700); err != nil {
+			return nil, nil
+		}
+	} else if err != nil || !info.IsDir() {
+		return nil, nil
+	}
+	db, err := backend.Open(filepath.Join(ffs.URI(), name), backend.TuningSmall)
+	if err != nil {
+		return nil, err
+	}
+	return newHashCache(db), nil
+}
+
+func newHashCache(db backend.Backend) *hashCache {
+	return &hashCache{
+		db:     db,
+		lastGC: time.Now(),
+	}
+}
+
+func (c *hashCache) Get(info fs.FileInfo, blockSize int) ([]protocol.BlockInfo, bool) {
+	key, ok := hashCacheKey(info, blockSize)
+	if !ok {
+		return nil, false
+	}
+	val, err := c.db.Get(key)
+	if err != nil || len(val) < hashCacheStampLength {
+		return nil, false
+	}
+	var f protocol.FileInfo
+	if err := f.Unmarshal(val[hashCacheStampLength:]); err != nil {
+		l.Debugln("hash cache: unmarshal:", err)
+		return nil, false
+	}
+	return f.Blocks, true
+}
+
+func (c *hashCache) Put(info fs.FileInfo, blockSize int, blocks []protocol.BlockInfo) {
+	key, ok := hashCacheKey(info, blockSize)
+	if !ok {
+		return
+	}
+
+	// Most files are unchanged since the last scan, so avoid rewriting
+	// their entries unless they are getting old.
+	now := time.Now()
+	if val, err := c.db.Get(key); err == nil && len(val) >= hashCacheStampLength {
+		stamp := time.Unix(int64(binary.BigEndian.Uint64(val)), 0)
+		if now.Sub(stamp) < hashCacheMaxAge {
+			return
+		}
+	}
+
+	f := protocol.FileInfo{RawBlockSize: int32(blockSize), Blocks: blocks}
+	bs, err := f.Marshal()
+	if err != nil {
+		return
+	}
+	val := make([]byte, hashCacheStampLength+len(bs))
+	binary.BigEndian.PutUint64(val, uint64(now.Unix()))
+	copy(val[hashCacheStampLength:], bs)
+	if err := c.db.Put(key, val); err != nil {
+		l.Debugln("hash cache: put:", err)
+	}
+}
+
+// maybeGC removes entries that haven't been rewritten for a long time,
+// i.e. that belong to files no longer present. It does nothing if it
+// has run recently.
+func (c *hashCache) maybeGC() {
+	if time.Since(c.lastGC) < hashCacheGCInterval {
+		return
+	}
+	c.lastGC = time.Now()
+	if err := c.gc(c.lastGC.Add(-2 * hashCacheMaxAge)); err != nil {
+		l.Debugln("hash cache: gc:", err)
+	}
+}
+
+func (c *hashCache) gc(before time.Time) error {
+	t, err := c.db.NewWriteTransaction()
+	if err != nil {
+		return err
+	}
+	defer t.Release()
+
+	it, err := t.NewPrefixIterator(nil)
+	if err != nil {
+		return err
+	}
+	defer it.Release()
+
+	removed := 0
+	for it.Next() {
+		val := it.Value()
+		if len(val) >= hashCacheStampLength && int64(binary.BigEndian.Uint64(val)) >= before.Unix() {
+			continue
+		}
+		if err := t.Delete(it.Key()); err != nil {
+			return err
+		}
+		removed++
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	it.Release()
+
+	l.Debugf("hash cache: removed %d stale entries", removed)
+	return t.Commit()
+}
+
+func (c *hashCache) Close() error {
+	return c.db.Close()
+}
+
+// hashCacheKey returns the key identifying the given file and block size,
+// or false if the file can't be identified by inode.
+func hashCacheKey(info fs.FileInfo, blockSize int) ([]byte, bool) {
+	inode := info.Inode()
+	if inode == 0 {
+		return nil, false
+	}
+	key := make([]byte, hashCacheKeyLength)
+	binary.BigEndian.PutUint64(key, inode)
+	binary.BigEndian.PutUint64(key[8:], uint64(info.Size()))
+	binary.BigEndian.PutUint64(key[16:], uint64(info.ModTime().UnixNano()))
+	binary.BigEndian.PutUint32(key[24:], uint32(blockSize))
+	return key, true
+}
@@ -0,0 +1,117 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/db/backend"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+func TestHashCache(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no inodes on windows")
+	}
+
+	tmpDir := createTmpDir()
+	defer os.RemoveAll(tmpDir)
+	ffs := fs.NewFilesystem(fs.FilesystemTypeBasic, tmpDir)
+	if err := ioutil.WriteFile(filepath.Join(tmpDir, "file"), []byte("some data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := ffs.Lstat("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := newHashCache(backend.OpenMemory())
+	defer c.Close()
+
+	if _, ok := c.Get(info, protocol.MinBlockSize); ok {
+		t.Fatal("unexpected hit in empty cache")
+	}
+
+	blocks := []protocol.BlockInfo{{Size: 9, Hash: []byte("hash")}}
+	c.Put(info, protocol.MinBlockSize, blocks)
+
+	if cached, ok := c.Get(info, protocol.MinBlockSize); !ok || !protocol.BlocksEqual(cached, blocks) {
+		t.Fatal("expected cached blocks, got", cached, ok)
+	}
+	if _, ok := c.Get(info, 2*protocol.MinBlockSize); ok {
+		t.Fatal("unexpected hit for other block size")
+	}
+
+	// A changed modification time is a different file.
+	if err := ffs.Chtimes("file", time.Now(), info.ModTime().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	changed, err := ffs.Lstat("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Get(changed, protocol.MinBlockSize); ok {
+		t.Fatal("unexpected hit for changed file")
+	}
+
+	// Recently written entries survive garbage collection, old ones don't.
+	if err := c.gc(time.Now().Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Get(info, protocol.MinBlockSize); !ok {
+		t.Fatal("recent entry should survive gc")
+	}
+	if err := c.gc(time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Get(info, protocol.MinBlockSize); ok {
+		t.Fatal("old entry should be removed by gc")
+	}
+}
+
+func TestOpenHashCache(t *testing.T) {
+	tmpDir := createTmpDir()
+	defer os.RemoveAll(tmpDir)
+	cfg := config.NewFolderConfiguration(protocol.LocalDeviceID, "default", "default", fs.FilesystemTypeBasic, tmpDir)
+
+	// Without a marker there is no cache, and the marker isn't created.
+	if c, err := openHashCache(cfg, true); c != nil || err != nil {
+		t.Fatal("expected no cache without marker, got", c, err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, config.DefaultMarkerName)); !os.IsNotExist(err) {
+		t.Fatal("marker should not have been created:", err)
+	}
+
+	if err := cfg.CreateMarker(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A cache that doesn't exist is only created when asked to.
+	if c, err := openHashCache(cfg, false); c != nil || err != nil {
+		t.Fatal("expected no new cache, got", c, err)
+	}
+	c, err := openHashCache(cfg, true)
+	if err != nil || c == nil {
+		t.Fatal("expected new cache, got", c, err)
+	}
+	c.Close()
+
+	// An existing one is opened either way.
+	c, err = openHashCache(cfg, false)
+	if err != nil || c == nil {
+		t.Fatal("expected existing cache, got", c, err)
+	}
+	c.Close()
+}
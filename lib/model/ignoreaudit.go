@@ -0,0 +1,125 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"strings"
+
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/ignore"
+)
+
+// The problems an ignore audit reports.
+const (
+	IgnoreUnreachable = "unreachable" // the same line comes earlier in .stignore, so this one has no effect
+	IgnoreShadowed    = "shadowed"    // matches files, but an earlier pattern always matches them first
+	IgnoreUnused      = "unused"      // matches nothing on disk
+)
+
+// An IgnoreAuditEntry is an ignore pattern that never decides whether a
+// file is ignored.
+type IgnoreAuditEntry struct {
+	Pattern string `json:"pattern"`
+	Source  string `json:"source"`
+	Line    int    `json:"line"`
+	Problem string `json:"problem"`
+}
+
+// AuditIgnores walks the folder on disk, matching everything against its
+// ignore patterns like a scan would, and reports the patterns that didn't
+// decide whether any file is ignored. Repeated lines are reported first,
+// followed by the other patterns in the order they apply.
+func (m *model) AuditIgnores(folder string) ([]IgnoreAuditEntry, error) {
+	m.fmut.RLock()
+	cfg, ok := m.folderCfgs[folder]
+	current := m.folderIgnores[folder]
+	m.fmut.RUnlock()
+	if !ok {
+		return nil, errFolderMissing
+	}
+
+	ignores, err := m.trialIgnores(cfg, current, nil, ignore.WithMatchStats(true))
+	if err != nil {
+		return nil, err
+	}
+
+	err = cfg.Filesystem().Walk(".", func(path string, info fs.FileInfo, err error) error {
+		switch {
+		case err != nil && path == ".":
+			return err
+		case err != nil || path == ".":
+			return nil
+		case fs.IsInternal(path):
+			if info.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		var res ignore.Result
+		if info.IsRegular() {
+			res = ignores.MatchFile(path, info.Size(), info.ModTime())
+		} else {
+			res = ignores.Match(path)
+		}
+		if info.IsDir() && res.IsIgnored() && ignores.SkipIgnoredDirs() {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []IgnoreAuditEntry
+
+	// Repeated lines are skipped when parsing, so they have no patterns.
+	seen := make(map[string]struct{})
+	for i, line := range ignores.Lines() {
+		if line == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+		if _, ok := seen[line]; ok {
+			entries = append(entries, IgnoreAuditEntry{
+				Pattern: line,
+				Source:  ".stignore",
+				Line:    i + 1,
+				Problem: IgnoreUnreachable,
+			})
+		}
+		seen[line] = struct{}{}
+	}
+
+	// A line may expand to several patterns, which are next to each other
+	// and share the source and line.
+	stats := ignores.Stats()
+	for i := 0; i < len(stats); {
+		first := stats[i].Pattern
+		matched, decided := 0, 0
+		for ; i < len(stats) && stats[i].Pattern.Source() == first.Source() && stats[i].Pattern.Line() == first.Line(); i++ {
+			matched += stats[i].Matched
+			decided += stats[i].Decided
+		}
+
+		entry := IgnoreAuditEntry{
+			Pattern: first.String(),
+			Source:  first.Source(),
+			Line:    first.Line(),
+		}
+		switch {
+		case matched == 0:
+			entry.Problem = IgnoreUnused
+		case decided == 0:
+			entry.Problem = IgnoreShadowed
+		default:
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
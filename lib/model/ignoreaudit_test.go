@@ -0,0 +1,51 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"testing"
+)
+
+func TestAuditIgnores(t *testing.T) {
+	m, _, fcfg := setupModelWithConnection()
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+
+	ffs := fcfg.Filesystem()
+	for _, name := range []string{"a.tmp", "b.tmp", "c.txt"} {
+		fd, err := ffs.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fd.Close()
+	}
+
+	if err := m.SetIgnores("default", []string{"*.tmp", "b.tmp", "*.bak", "*.tmp"}); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := m.AuditIgnores("default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []IgnoreAuditEntry{
+		{Pattern: "*.tmp", Source: ".stignore", Line: 4, Problem: IgnoreUnreachable},
+		{Pattern: "b.tmp", Source: ".stignore", Line: 2, Problem: IgnoreShadowed},
+		{Pattern: "*.bak", Source: ".stignore", Line: 3, Problem: IgnoreUnused},
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("expected %d entries, got %+v", len(expected), entries)
+	}
+	for i := range expected {
+		if entries[i] != expected[i] {
+			t.Errorf("expected %+v, got %+v", expected[i], entries[i])
+		}
+	}
+
+	if _, err := m.AuditIgnores("missing"); err != errFolderMissing {
+		t.Error("expected an error for a missing folder, got", err)
+	}
+}
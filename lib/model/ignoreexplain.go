@@ -0,0 +1,103 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"strings"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/ignore"
+	"github.com/syncthing/syncthing/lib/osutil"
+)
+
+// An IgnoreExplanation tells whether a path is ignored, and by which
+// pattern.
+type IgnoreExplanation struct {
+	Path      string `json:"path"`
+	Ignored   bool   `json:"ignored"`
+	Deletable bool   `json:"deletable"`
+	Pattern   string `json:"pattern,omitempty"`
+	Source    string `json:"source,omitempty"`
+	Line      int    `json:"line,omitempty"`
+}
+
+// ExplainIgnores matches the paths against the given ignore patterns, or
+// the current ones of the folder if nil, without changing anything. The
+// presets and shared patterns of the folder apply as usual. Without paths,
+// those in the global index that would be ignored are returned.
+func (m *model) ExplainIgnores(folder string, lines []string, paths []string) ([]IgnoreExplanation, error) {
+	m.fmut.RLock()
+	cfg, ok := m.folderCfgs[folder]
+	current := m.folderIgnores[folder]
+	fset := m.folderFiles[folder]
+	m.fmut.RUnlock()
+	if !ok {
+		return nil, errFolderMissing
+	}
+
+	ignores, err := m.trialIgnores(cfg, current, lines)
+	if err != nil {
+		return nil, err
+	}
+
+	explain := func(path string) IgnoreExplanation {
+		res, pattern := ignores.Explain(osutil.NativeFilename(path))
+		exp := IgnoreExplanation{
+			Path:      path,
+			Ignored:   res.IsIgnored(),
+			Deletable: res.IsDeletable(),
+		}
+		if pattern != nil {
+			exp.Pattern = pattern.String()
+			exp.Source = pattern.Source()
+			exp.Line = pattern.Line()
+		}
+		return exp
+	}
+
+	explanations := make([]IgnoreExplanation, 0, len(paths))
+	if len(paths) > 0 {
+		for _, path := range paths {
+			explanations = append(explanations, explain(path))
+		}
+		return explanations, nil
+	}
+
+	if fset == nil {
+		return nil, errFolderNotRunning
+	}
+	fset.WithGlobalTruncated(func(f db.FileIntf) bool {
+		if exp := explain(f.FileName()); exp.Ignored {
+			explanations = append(explanations, exp)
+		}
+		return true
+	})
+	return explanations, nil
+}
+
+// trialIgnores returns a matcher for the given ignore patterns of the
+// folder, or the current ones if nil, with the same presets and shared
+// patterns as the current matcher, which may be nil.
+func (m *model) trialIgnores(cfg config.FolderConfiguration, current *ignore.Matcher, lines []string, opts ...ignore.Option) (*ignore.Matcher, error) {
+	if lines == nil {
+		var err error
+		if lines, _, err = m.GetIgnores(cfg.ID); err != nil {
+			return nil, err
+		}
+	}
+	ignores := ignore.New(cfg.Filesystem(), append(folderIgnoreOptions(cfg), opts...)...)
+	if current != nil {
+		if err := ignores.SetShared(current.Shared()); err != nil {
+			return nil, err
+		}
+	}
+	if err := ignores.Parse(strings.NewReader(strings.Join(lines, "\n")), ".stignore"); err != nil {
+		return nil, err
+	}
+	return ignores, nil
+}
@@ -0,0 +1,59 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"testing"
+)
+
+func TestExplainIgnores(t *testing.T) {
+	m, _, fcfg := setupModelWithConnection()
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+
+	ffs := fcfg.Filesystem()
+	for _, name := range []string{"a.tmp", "b.txt"} {
+		fd, err := ffs.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fd.Close()
+	}
+	if err := m.ScanFolder("default"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Candidate patterns against the paths in the database.
+	exps, err := m.ExplainIgnores("default", []string{"// temporary", "(?d)*.tmp"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exps) != 1 || exps[0].Path != "a.tmp" || !exps[0].Deletable || exps[0].Line != 2 || exps[0].Pattern != "(?d)*.tmp" {
+		t.Errorf("expected a.tmp to be ignored by the second line, got %+v", exps)
+	}
+	if lines, _, _ := m.GetIgnores("default"); len(lines) != 0 {
+		t.Errorf("expected the current patterns to be unchanged, got %v", lines)
+	}
+
+	// The current patterns against the given paths.
+	if err := m.SetIgnores("default", []string{"*.txt"}); err != nil {
+		t.Fatal(err)
+	}
+	exps, err = m.ExplainIgnores("default", nil, []string{"b.txt", "dir/c.txt", "a.tmp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exps) != 3 || !exps[0].Ignored || !exps[1].Ignored || exps[2].Ignored || exps[0].Source != ".stignore" {
+		t.Errorf("expected the .txt files to be ignored, got %+v", exps)
+	}
+
+	if _, err := m.ExplainIgnores("default", []string{"[invalid"}, nil); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+	if _, err := m.ExplainIgnores("missing", nil, nil); err != errFolderMissing {
+		t.Error("expected an error for a missing folder, got", err)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// RollbackIndexSnapshot puts the files that changed since the snapshot back
+// into the local index as they were in it, returning how many there were.
+// Files added since are left alone. Only the index is rolled back, so the
+// files are rescanned afterwards for the index to catch up with what's on
+// disk, and whatever is needed then gets pulled.
+func (f *folder) RollbackIndexSnapshot(name string) (int, error) {
+	changes, err := f.fset.DiffIndexSnapshot(name)
+	if err != nil {
+		return 0, err
+	}
+
+	var names []string
+	batch := newFileInfoBatch(func(fs []protocol.FileInfo) error {
+		f.updateLocals(fs)
+		return nil
+	})
+	for _, c := range changes {
+		if c.Snapshot.Name == "" {
+			continue
+		}
+		batch.append(c.Snapshot)
+		names = append(names, c.Snapshot.Name)
+		if err := batch.flushIfFull(); err != nil {
+			return 0, err
+		}
+	}
+	if err := batch.flush(); err != nil {
+		return 0, err
+	}
+	if len(names) == 0 {
+		return 0, nil
+	}
+
+	l.Infof("Folder %v: rolled back %d files to index snapshot %q", f.Description(), len(names), name)
+	f.SchedulePull()
+	return len(names), f.Scan(names)
+}
+
+// CreateIndexSnapshot keeps a copy of the local index of the folder under
+// the given name, for comparing against and rolling back to later.
+func (m *model) CreateIndexSnapshot(folder, name string) (db.IndexSnapshot, error) {
+	m.fmut.RLock()
+	fs, ok := m.folderFiles[folder]
+	m.fmut.RUnlock()
+	if !ok {
+		return db.IndexSnapshot{}, errFolderMissing
+	}
+	return fs.CreateIndexSnapshot(name)
+}
+
+// IndexSnapshots returns the index snapshots of the folder.
+func (m *model) IndexSnapshots(folder string) ([]db.IndexSnapshot, error) {
+	m.fmut.RLock()
+	fs, ok := m.folderFiles[folder]
+	m.fmut.RUnlock()
+	if !ok {
+		return nil, errFolderMissing
+	}
+	return fs.IndexSnapshots()
+}
+
+// DiffIndexSnapshot returns the files of the folder that changed since the
+// snapshot.
+func (m *model) DiffIndexSnapshot(folder, name string) ([]db.IndexSnapshotChange, error) {
+	m.fmut.RLock()
+	fs, ok := m.folderFiles[folder]
+	m.fmut.RUnlock()
+	if !ok {
+		return nil, errFolderMissing
+	}
+	return fs.DiffIndexSnapshot(name)
+}
+
+// RollbackIndexSnapshot rolls the local index of the folder back to the
+// snapshot, returning how many files were.
+func (m *model) RollbackIndexSnapshot(folder, name string) (int, error) {
+	m.fmut.RLock()
+	runner, ok := m.folderRunners[folder]
+	m.fmut.RUnlock()
+	if !ok {
+		return 0, errFolderMissing
+	}
+	return runner.RollbackIndexSnapshot(name)
+}
+
+// DropIndexSnapshot removes the snapshot of the folder.
+func (m *model) DropIndexSnapshot(folder, name string) error {
+	m.fmut.RLock()
+	fs, ok := m.folderFiles[folder]
+	m.fmut.RUnlock()
+	if !ok {
+		return errFolderMissing
+	}
+	return fs.DropIndexSnapshot(name)
+}
@@ -0,0 +1,184 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+// IndexDivergence tells how the index of a folder a device summarized on
+// connecting differs from ours.
+type IndexDivergence struct {
+	// Known is whether the device sent a summary.
+	Known bool `json:"known"`
+	// InSync is whether its index is the same as ours.
+	InSync bool `json:"inSync"`
+	// Current is whether our copy of its index is up to date.
+	Current bool `json:"current"`
+	// Paths are the top level files and directories in either index
+	// under which the indexes may differ.
+	Paths []string `json:"paths"`
+}
+
+// IndexDivergence tells how the index of the folder that the device
+// summarized on connecting differs from ours.
+func (m *model) IndexDivergence(folder string, device protocol.DeviceID) (IndexDivergence, error) {
+	m.fmut.RLock()
+	fset, ok := m.folderFiles[folder]
+	m.fmut.RUnlock()
+	if !ok {
+		return IndexDivergence{}, errFolderMissing
+	}
+	return m.indexSummaries.divergence(fset, folder, device), nil
+}
+
+// indexSummaries caches the summaries of the indexes in the database, until
+// the sequence of an index changes, and keeps the summaries the devices
+// sent of their own.
+type indexSummaries struct {
+	cached map[summaryKey]cachedSummary
+	remote map[summaryKey]protocol.IndexSummary
+	mut    sync.Mutex
+}
+
+type summaryKey struct {
+	folder string
+	device protocol.DeviceID
+}
+
+type cachedSummary struct {
+	indexID  protocol.IndexID
+	sequence int64
+	summary  protocol.IndexSummary
+}
+
+func newIndexSummaries() *indexSummaries {
+	return &indexSummaries{
+		cached: make(map[summaryKey]cachedSummary),
+		remote: make(map[summaryKey]protocol.IndexSummary),
+		mut:    sync.NewMutex(),
+	}
+}
+
+// get returns the summary of the index of the device in the file set.
+func (s *indexSummaries) get(fset *db.FileSet, folder string, device protocol.DeviceID) protocol.IndexSummary {
+	key := summaryKey{folder, device}
+	indexID, sequence := fset.IndexID(device), fset.Sequence(device)
+
+	s.mut.Lock()
+	c, ok := s.cached[key]
+	s.mut.Unlock()
+	if ok && c.indexID == indexID && c.sequence == sequence {
+		return c.summary
+	}
+
+	c = cachedSummary{indexID, sequence, fset.IndexSummary(device)}
+	s.mut.Lock()
+	s.cached[key] = c
+	s.mut.Unlock()
+	return c.summary
+}
+
+// setRemote remembers the summary the device sent, or forgets it if nil.
+func (s *indexSummaries) setRemote(folder string, device protocol.DeviceID, summary *protocol.IndexSummary) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if summary == nil {
+		delete(s.remote, summaryKey{folder, device})
+		return
+	}
+	s.remote[summaryKey{folder, device}] = *summary
+}
+
+func (s *indexSummaries) getRemote(folder string, device protocol.DeviceID) (protocol.IndexSummary, bool) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	summary, ok := s.remote[summaryKey{folder, device}]
+	return summary, ok
+}
+
+// forgetDevice forgets the summaries the device sent, as it disconnected.
+func (s *indexSummaries) forgetDevice(device protocol.DeviceID) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	for key := range s.remote {
+		if key.device == device {
+			delete(s.remote, key)
+		}
+	}
+}
+
+// forgetFolder forgets everything about the folder, as it was removed.
+func (s *indexSummaries) forgetFolder(folder string) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	for key := range s.cached {
+		if key.folder == folder {
+			delete(s.cached, key)
+		}
+	}
+	for key := range s.remote {
+		if key.folder == folder {
+			delete(s.remote, key)
+		}
+	}
+}
+
+// inSync returns whether the device has the same index of the folder as we
+// do, and our copy of its index is up to date. It then needs exactly what
+// we need.
+func (s *indexSummaries) inSync(fset *db.FileSet, folder string, device protocol.DeviceID) bool {
+	remote, ok := s.getRemote(folder, device)
+	return ok && remote.Equal(s.get(fset, folder, protocol.LocalDeviceID)) && remote.Equal(s.get(fset, folder, device))
+}
+
+// divergence compares the summary the device sent to our index and to our
+// copy of its index.
+func (s *indexSummaries) divergence(fset *db.FileSet, folder string, device protocol.DeviceID) IndexDivergence {
+	remote, ok := s.getRemote(folder, device)
+	if !ok {
+		return IndexDivergence{}
+	}
+	local := s.get(fset, folder, protocol.LocalDeviceID)
+	div := IndexDivergence{
+		Known:   true,
+		InSync:  remote.Equal(local),
+		Current: remote.Equal(s.get(fset, folder, device)),
+		Paths:   []string{},
+	}
+	if div.InSync {
+		return div
+	}
+
+	buckets := make(map[int]struct{})
+	for _, b := range remote.DivergentBuckets(local) {
+		buckets[b] = struct{}{}
+	}
+	paths := make(map[string]struct{})
+	for _, dev := range []protocol.DeviceID{protocol.LocalDeviceID, device} {
+		fset.WithHaveTruncated(dev, func(f db.FileIntf) bool {
+			name := f.FileName()
+			if _, ok := buckets[protocol.IndexSummaryBucket(name)]; ok {
+				if i := strings.IndexAny(name, `/\`); i >= 0 {
+					name = name[:i]
+				}
+				paths[name] = struct{}{}
+			}
+			return true
+		})
+	}
+	for path := range paths {
+		div.Paths = append(div.Paths, path)
+	}
+	sort.Strings(div.Paths)
+	return div
+}
@@ -0,0 +1,72 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"time"
+
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// DatabaseUsage returns how much of the database each folder and the
+// index of each device take up.
+func (m *model) DatabaseUsage() (db.DatabaseUsage, error) {
+	usage, err := m.db.Usage()
+	if err != nil {
+		return usage, err
+	}
+
+	// The local index is under a placeholder ID in the database.
+	localToID := func(devs map[protocol.DeviceID]db.Usage) {
+		if u, ok := devs[protocol.LocalDeviceID]; ok {
+			delete(devs, protocol.LocalDeviceID)
+			devs[m.id] = u
+		}
+	}
+	localToID(usage.Devices)
+	for _, fu := range usage.Folders {
+		localToID(fu.Devices)
+	}
+	return usage, nil
+}
+
+// CompactDatabase makes the database reclaim the space of deleted and
+// overwritten entries now, which may take a while.
+func (m *model) CompactDatabase() error {
+	l.Infoln("Compacting database")
+	t0 := time.Now()
+	if err := m.db.Compact(); err != nil {
+		l.Warnln("Compacting database:", err)
+		return err
+	}
+	l.Infof("Compacted database in %v", time.Since(t0).Truncate(time.Millisecond))
+	return nil
+}
+
+// CheckDatabase checks the database entries of the folder against each
+// other and against the folder metadata, and with repair set fixes what
+// was found to be wrong.
+func (m *model) CheckDatabase(folder string, repair bool) (db.FolderCheck, error) {
+	m.fmut.RLock()
+	fset, ok := m.folderFiles[folder]
+	m.fmut.RUnlock()
+	if !ok {
+		return db.FolderCheck{}, errFolderMissing
+	}
+
+	check, err := fset.Check(repair)
+	if err != nil {
+		return check, err
+	}
+	if check.Repaired {
+		l.Infof("Repaired database entries of folder %v: %+v", folder, check)
+	} else if !check.OK() {
+		l.Infof("Database entries of folder %v are inconsistent: %+v", folder, check)
+	}
+	return check, nil
+}
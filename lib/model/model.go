@@ -11,6 +11,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net"
 	"path/filepath"
 	"reflect"
@@ -23,12 +24,14 @@ import (
 	"github.com/pkg/errors"
 	"github.com/thejerf/suture"
 
+	"github.com/syncthing/syncthing/lib/blockstore"
 	"github.com/syncthing/syncthing/lib/config"
 	"github.com/syncthing/syncthing/lib/connections"
 	"github.com/syncthing/syncthing/lib/db"
 	"github.com/syncthing/syncthing/lib/events"
 	"github.com/syncthing/syncthing/lib/fs"
 	"github.com/syncthing/syncthing/lib/ignore"
+	"github.com/syncthing/syncthing/lib/locations"
 	"github.com/syncthing/syncthing/lib/osutil"
 	"github.com/syncthing/syncthing/lib/protocol"
 	"github.com/syncthing/syncthing/lib/scanner"
@@ -37,6 +40,7 @@ import (
 	"github.com/syncthing/syncthing/lib/upgrade"
 	"github.com/syncthing/syncthing/lib/util"
 	"github.com/syncthing/syncthing/lib/versioner"
+	"github.com/syncthing/syncthing/lib/watchaggregator"
 )
 
 // How many files to send in each Index/IndexUpdate message.
@@ -45,8 +49,13 @@ const (
 	maxBatchSizeFiles = 1000       // Either way, don't include more files than this
 )
 
+// Folders with at least this many files have their initial index sent as a
+// snapshot, to devices that accept them.
+var indexSnapshotMinFiles = 10000
+
 type service interface {
 	BringToFront(string)
+	Prioritize(file string, bumpRequests bool)
 	Override()
 	Revert()
 	DelayScan(d time.Duration)
@@ -58,10 +67,20 @@ type service interface {
 	CheckHealth() error
 	Errors() []FileError
 	WatchError() error
+	WatchAggregationMode() watchaggregator.Mode
 	ForceRescan(file protocol.FileInfo) error
 	GetStatistics() (stats.FolderStatistics, error)
+	UndoEntries() ([]UndoEntry, error)
+	Undo(id int64) (UndoEntry, error)
+	Hydrate(file string) error
+	SkippedChanges() ([]SkippedChange, error)
+	PullerState(queued int) PullerState
+	AuditLog(since int64, limit int) ([]db.AuditEntry, error)
+	RollbackIndexSnapshot(name string) (int, error)
 
 	getState() (folderState, time.Time, error)
+	getProgress() time.Time
+	getScanProgress() (scanner.ScanProgress, bool)
 }
 
 type Availability struct {
@@ -82,14 +101,55 @@ type Model interface {
 	State(folder string) (string, time.Time, error)
 	FolderErrors(folder string) ([]FileError, error)
 	WatchError(folder string) error
+	WatchAggregationMode(folder string) watchaggregator.Mode
 	Override(folder string)
 	Revert(folder string)
 	BringToFront(folder, file string)
+	Prioritize(folder, file string, bumpRequests bool) error
 	GetIgnores(folder string) ([]string, []string, error)
+	SharedIgnores(folder string) []string
+	MakeDir(folder, name string) error
+	RenamePath(folder, from, to string) error
+	DeletePath(folder, name string) error
 	SetIgnores(folder string, content []string) error
 
 	GetFolderVersions(folder string) (map[string][]versioner.FileVersion, error)
 	RestoreFolderVersions(folder string, versions map[string]time.Time) (map[string]string, error)
+	ExportFolder(folder, prefix, format string, at time.Time, w io.Writer) error
+
+	UndoEntries(folder string) ([]UndoEntry, error)
+	Undo(folder string, id int64) (UndoEntry, error)
+	Hydrate(folder, file string) error
+	SkippedChanges(folder string) ([]SkippedChange, error)
+	PullerState(folder string, queued int) (PullerState, error)
+	SendApplicationMessage(device protocol.DeviceID, msgType string, data []byte) error
+	RequestRemoteScan(device protocol.DeviceID, folder string, subdirs []string) error
+	PendingFolderSettings() []PendingFolderSettings
+	ResolveFolderSettings(folder string, accept bool) error
+	DecommissionDevice(device protocol.DeviceID) error
+	PendingDecommissions() []PendingDecommission
+	ResolveDecommission(device protocol.DeviceID, accept bool) error
+	ExplainIgnores(folder string, lines []string, paths []string) ([]IgnoreExplanation, error)
+	AuditIgnores(folder string) ([]IgnoreAuditEntry, error)
+	IndexDivergence(folder string, device protocol.DeviceID) (IndexDivergence, error)
+	DatabaseUsage() (db.DatabaseUsage, error)
+	CompactDatabase() error
+	CheckDatabase(folder string, repair bool) (db.FolderCheck, error)
+	FileHistory(folder, file string) ([]db.FileHistoryEntry, error)
+	AuditLog(folder string, since int64, limit int) ([]AuditLogEntry, error)
+	ExportAuditLog(folder string, w io.Writer) error
+	CreateIndexSnapshot(folder, name string) (db.IndexSnapshot, error)
+	IndexSnapshots(folder string) ([]db.IndexSnapshot, error)
+	DiffIndexSnapshot(folder, name string) ([]db.IndexSnapshotChange, error)
+	RollbackIndexSnapshot(folder, name string) (int, error)
+	DropIndexSnapshot(folder, name string) error
+	ScanProgress(folder string) (scanner.ScanProgress, bool)
+
+	ExportBatch(folder string, since int64, w io.Writer) (BatchHeader, error)
+	ImportBatch(r io.Reader) (BatchHeader, error)
+	StreamFile(ctx context.Context, folder string, file protocol.FileInfo, w io.Writer) error
+	ReadGlobalAt(ctx context.Context, folder string, file protocol.FileInfo, buf []byte, offset int64) (int, error)
+	GlobalDirectoryEntries(folder, dir string) ([]db.FileInfoTruncated, error)
 
 	LocalChangedFiles(folder string, page, perpage int) []db.FileInfoTruncated
 	NeedFolderFiles(folder string, page, perpage int) ([]db.FileInfoTruncated, []db.FileInfoTruncated, []db.FileInfoTruncated)
@@ -128,6 +188,8 @@ type model struct {
 	cacheIgnoredFiles bool
 	protectedFiles    []string
 	evLogger          events.Logger
+	snapshots         *requestSnapshots
+	blockStore        *blockstore.Store // nil unless the block cache is enabled
 
 	clientName    string
 	clientVersion string
@@ -135,18 +197,28 @@ type model struct {
 	fmut               sync.RWMutex                                           // protects the below
 	folderCfgs         map[string]config.FolderConfiguration                  // folder -> cfg
 	folderFiles        map[string]*db.FileSet                                 // folder -> files
+	folderTempFs       map[string]fs.Filesystem                               // folder -> filesystem for temporary files
 	deviceStatRefs     map[protocol.DeviceID]*stats.DeviceStatisticsReference // deviceID -> statsRef
 	folderIgnores      map[string]*ignore.Matcher                             // folder -> matcher object
 	folderRunners      map[string]service                                     // folder -> puller or scanner
 	folderRunnerTokens map[string][]suture.ServiceToken                       // folder -> tokens for puller or scanner
 	folderRestartMuts  syncMutexMap                                           // folder -> restart mutex
 	folderVersioners   map[string]versioner.Versioner                         // folder -> versioner (may be nil)
+	folderRecoveries   map[string]*folderRecovery                             // folder -> recovery state, while recovering
+	pendingSettings    map[string]PendingFolderSettings                       // folder -> settings pushed by a device, to be accepted
+	pendingRemovals    map[protocol.DeviceID]PendingDecommission              // device -> suggestion to decommission it, to be accepted
+	folderDisks        map[string]scanner.Disk                                // folder -> disk it is on
+	requestReads       *requestReadLimiter
+	remoteScans        *remoteScanQueue
+	diskLimiter        *scanner.DiskLimiter
+	indexSummaries     *indexSummaries
 
 	pmut                sync.RWMutex // protects the below
-	conn                map[protocol.DeviceID]connections.Connection
+	conn                map[protocol.DeviceID]*connections.ConnectionSet
 	connRequestLimiters map[protocol.DeviceID]*byteSemaphore
 	closed              map[protocol.DeviceID]chan struct{}
 	helloMessages       map[protocol.DeviceID]protocol.HelloResult
+	requestBatchers     map[protocol.DeviceID]*requestBatcher
 	deviceDownloads     map[protocol.DeviceID]*deviceDownloadState
 	remotePausedFolders map[protocol.DeviceID][]string // deviceID -> folders
 
@@ -160,18 +232,28 @@ var (
 )
 
 var (
-	errDeviceUnknown     = errors.New("unknown device")
-	errDevicePaused      = errors.New("device is paused")
-	errDeviceIgnored     = errors.New("device is ignored")
-	ErrFolderPaused      = errors.New("folder is paused")
-	errFolderNotRunning  = errors.New("folder is not running")
-	errFolderMissing     = errors.New("no such folder")
-	errNetworkNotAllowed = errors.New("network not allowed")
-	errNoVersioner       = errors.New("folder has no versioner")
+	errDeviceUnknown      = errors.New("unknown device")
+	errDevicePaused       = errors.New("device is paused")
+	errDeviceIgnored      = errors.New("device is ignored")
+	ErrFolderPaused       = errors.New("folder is paused")
+	errFolderNotRunning   = errors.New("folder is not running")
+	errFolderMissing      = errors.New("no such folder")
+	errNetworkNotAllowed  = errors.New("network not allowed")
+	errNoVersioner        = errors.New("folder has no versioner")
+	errDeviceNotConnected = errors.New("device is not connected")
+	errNoAppMessages      = errors.New("device does not support application messages")
+	errNoScanRequests     = errors.New("device does not accept scan requests")
+	errFolderNotShared    = errors.New("folder is not shared with device")
+	errNoPendingSettings  = errors.New("no pending settings for folder")
+	errNoPendingRemoval   = errors.New("no pending decommission for device")
+	errDecommissionSelf   = errors.New("cannot decommission this device")
+	errFileNotNeeded      = errors.New("file is not needed")
 	// errors about why a connection is closed
 	errIgnoredFolderRemoved = errors.New("folder no longer ignored")
 	errReplacingConnection  = errors.New("replacing connection")
 	errStopped              = errors.New("Syncthing is being stopped")
+	errSettingsChanged      = errors.New("pushed folder settings changed")
+	errDecommissioned       = errors.New("device was decommissioned")
 )
 
 // NewModel creates and starts a new model. The model starts in read-only mode,
@@ -194,19 +276,30 @@ func NewModel(cfg config.Wrapper, id protocol.DeviceID, clientName, clientVersio
 		cacheIgnoredFiles:   cfg.Options().CacheIgnoredFiles,
 		protectedFiles:      protectedFiles,
 		evLogger:            evLogger,
+		snapshots:           newRequestSnapshots(),
 		clientName:          clientName,
 		clientVersion:       clientVersion,
 		folderCfgs:          make(map[string]config.FolderConfiguration),
 		folderFiles:         make(map[string]*db.FileSet),
+		folderTempFs:        make(map[string]fs.Filesystem),
 		deviceStatRefs:      make(map[protocol.DeviceID]*stats.DeviceStatisticsReference),
 		folderIgnores:       make(map[string]*ignore.Matcher),
 		folderRunners:       make(map[string]service),
 		folderRunnerTokens:  make(map[string][]suture.ServiceToken),
 		folderVersioners:    make(map[string]versioner.Versioner),
-		conn:                make(map[protocol.DeviceID]connections.Connection),
+		folderRecoveries:    make(map[string]*folderRecovery),
+		pendingSettings:     make(map[string]PendingFolderSettings),
+		pendingRemovals:     make(map[protocol.DeviceID]PendingDecommission),
+		folderDisks:         make(map[string]scanner.Disk),
+		requestReads:        newRequestReadLimiter(cfg.Options()),
+		remoteScans:         newRemoteScanQueue(),
+		diskLimiter:         scanner.NewDiskLimiter(),
+		indexSummaries:      newIndexSummaries(),
+		conn:                make(map[protocol.DeviceID]*connections.ConnectionSet),
 		connRequestLimiters: make(map[protocol.DeviceID]*byteSemaphore),
 		closed:              make(map[protocol.DeviceID]chan struct{}),
 		helloMessages:       make(map[protocol.DeviceID]protocol.HelloResult),
+		requestBatchers:     make(map[protocol.DeviceID]*requestBatcher),
 		deviceDownloads:     make(map[protocol.DeviceID]*deviceDownloadState),
 		remotePausedFolders: make(map[protocol.DeviceID][]string),
 		fmut:                sync.NewRWMutex(),
@@ -215,7 +308,19 @@ func NewModel(cfg config.Wrapper, id protocol.DeviceID, clientName, clientVersio
 	for devID := range cfg.Devices() {
 		m.deviceStatRefs[devID] = stats.NewDeviceStatisticsReference(m.db, devID.String())
 	}
+	if mib := cfg.Options().BlockCacheMiB; mib > 0 {
+		store, err := blockstore.Open(locations.Get(locations.BlockCache), int64(mib)<<20)
+		if err != nil {
+			l.Warnln("Opening block cache:", err)
+		} else {
+			m.blockStore = store
+		}
+	}
 	m.Add(m.progressEmitter)
+	m.Add(newFolderWatchdog(m))
+	if cfg.Options().SharedBlockStorage {
+		m.Add(newBlockDeduper(m))
+	}
 	scanLimiter.setCapacity(cfg.Options().MaxConcurrentScans)
 
 	return m
@@ -296,6 +401,9 @@ func (m *model) startFolderLocked(cfg config.FolderConfiguration) {
 
 	fset := m.folderFiles[folder]
 
+	m.folderDisks[folder] = folderDisk(cfg)
+	m.updateDiskLimitLocked(m.folderDisks[folder].ID)
+
 	// Find any devices for which we hold the index in the db, but the folder
 	// is not shared, and drop it.
 	expected := mapDevices(cfg.DeviceIDs())
@@ -308,6 +416,7 @@ func (m *model) startFolderLocked(cfg config.FolderConfiguration) {
 
 	v, ok := fset.Sequence(protocol.LocalDeviceID), true
 	indexHasFiles := ok && v > 0
+	m.startRecoveryLocked(cfg, indexHasFiles)
 	if !indexHasFiles {
 		// It's a blank folder, so this may the first time we're looking at
 		// it. Attempt to create and tag with our marker as appropriate. We
@@ -414,14 +523,32 @@ func (m *model) addFolder(cfg config.FolderConfiguration) {
 func (m *model) addFolderLocked(cfg config.FolderConfiguration, fset *db.FileSet) {
 	m.folderCfgs[cfg.ID] = cfg
 	m.folderFiles[cfg.ID] = fset
+	m.folderTempFs[cfg.ID] = cfg.TempFilesystem()
+	fset.SetFileHistory(cfg.FileHistory)
 
-	ignores := ignore.New(cfg.Filesystem(), ignore.WithCache(m.cacheIgnoredFiles))
+	for _, name := range cfg.IgnorePresets {
+		if _, ok := ignore.Preset(name); !ok {
+			l.Warnf("Folder %v uses unknown ignore preset %q", cfg.Description(), name)
+		}
+	}
+	ignores := ignore.New(cfg.Filesystem(), append(folderIgnoreOptions(cfg), ignore.WithCache(m.cacheIgnoredFiles))...)
+	m.setSharedIgnoresLocked(cfg, ignores)
 	if err := ignores.Load(".stignore"); err != nil && !fs.IsNotExist(err) {
 		l.Warnln("Loading ignores:", err)
 	}
 	m.folderIgnores[cfg.ID] = ignores
 }
 
+// folderIgnoreOptions returns the options for the ignore matcher of the
+// folder that follow from its configuration.
+func folderIgnoreOptions(cfg config.FolderConfiguration) []ignore.Option {
+	return []ignore.Option{
+		ignore.WithCaseInsensitive(cfg.CaseInsensitiveIgnores),
+		ignore.WithSubtrees(cfg.Subtrees),
+		ignore.WithPresets(cfg.IgnorePresets),
+	}
+}
+
 func (m *model) removeFolder(cfg config.FolderConfiguration) {
 	m.stopFolder(cfg, fmt.Errorf("removing folder %v", cfg.Description()))
 
@@ -445,6 +572,7 @@ func (m *model) removeFolder(cfg config.FolderConfiguration) {
 
 	// Remove it from the database
 	db.DropFolder(m.db, cfg.ID)
+	m.indexSummaries.forgetFolder(cfg.ID)
 }
 
 func (m *model) stopFolder(cfg config.FolderConfiguration, err error) {
@@ -468,10 +596,16 @@ func (m *model) removeFolderLocked(cfg config.FolderConfiguration) {
 	// Clean up our config maps
 	delete(m.folderCfgs, cfg.ID)
 	delete(m.folderFiles, cfg.ID)
+	delete(m.folderTempFs, cfg.ID)
 	delete(m.folderIgnores, cfg.ID)
 	delete(m.folderRunners, cfg.ID)
 	delete(m.folderRunnerTokens, cfg.ID)
 	delete(m.folderVersioners, cfg.ID)
+	delete(m.folderRecoveries, cfg.ID)
+	if disk, ok := m.folderDisks[cfg.ID]; ok {
+		delete(m.folderDisks, cfg.ID)
+		m.updateDiskLimitLocked(disk.ID)
+	}
 }
 
 func (m *model) restartFolder(from, to config.FolderConfiguration) {
@@ -516,6 +650,17 @@ func (m *model) restartFolder(from, to config.FolderConfiguration) {
 
 	m.stopFolder(from, fmt.Errorf("%v folder %v", errMsg, to.Description()))
 
+	if fset != nil && !reflect.DeepEqual(from.Subtrees, to.Subtrees) && (len(from.Subtrees) > 0 || len(to.Subtrees) > 0) {
+		// What the other devices sent us was for the old subtrees. Forget
+		// it, so that they send their full indexes for the new ones.
+		for _, device := range to.DeviceIDs() {
+			if device != m.id {
+				fset.Drop(device)
+				fset.SetIndexID(device, 0)
+			}
+		}
+	}
+
 	m.fmut.Lock()
 	defer m.fmut.Unlock()
 
@@ -560,7 +705,9 @@ func (m *model) UsageReportingStats(version int, preview bool) map[string]interf
 		m.pmut.RLock()
 		transportStats := make(map[string]int)
 		for _, conn := range m.conn {
-			transportStats[conn.Transport()]++
+			for _, c := range conn.Connections() {
+				transportStats[c.Transport()]++
+			}
 		}
 		m.pmut.RUnlock()
 		stats["transportStats"] = transportStats
@@ -645,6 +792,8 @@ type ConnectionInfo struct {
 	ClientVersion string
 	Type          string
 	Crypto        string
+	Transports    []string
+	Quality       connections.Quality
 }
 
 func (info ConnectionInfo) MarshalJSON() ([]byte, error) {
@@ -658,9 +807,23 @@ func (info ConnectionInfo) MarshalJSON() ([]byte, error) {
 		"clientVersion": info.ClientVersion,
 		"type":          info.Type,
 		"crypto":        info.Crypto,
+		"transports":    info.Transports,
+		"quality":       info.Quality,
 	})
 }
 
+// connectionQuality returns how requests to the device have been going, as
+// far as the current connection to it can tell.
+func (m *model) connectionQuality(device protocol.DeviceID) connections.Quality {
+	m.pmut.RLock()
+	conn, ok := m.conn[device]
+	m.pmut.RUnlock()
+	if !ok {
+		return connections.Quality{}
+	}
+	return conn.Quality()
+}
+
 // ConnectionStats returns a map with connection statistics for each device.
 func (m *model) ConnectionStats() map[string]interface{} {
 	m.pmut.RLock()
@@ -684,6 +847,10 @@ func (m *model) ConnectionStats() map[string]interface{} {
 			ci.Crypto = conn.Crypto()
 			ci.Connected = ok
 			ci.Statistics = conn.Statistics()
+			ci.Quality = conn.Quality()
+			for _, c := range conn.Connections() {
+				ci.Transports = append(ci.Transports, c.Transport())
+			}
 			if addr := conn.RemoteAddr(); addr != nil {
 				ci.Address = addr.String()
 			}
@@ -777,8 +944,15 @@ func (m *model) Completion(device protocol.DeviceID, folder string) FolderComple
 	counts := m.deviceDownloads[device].GetBlockCounts(folder)
 	m.pmut.RUnlock()
 
+	// A device with the same index as ours needs what we need, which is
+	// quicker to find.
+	needDevice := device
+	if device != protocol.LocalDeviceID && m.indexSummaries.inSync(rf, folder, device) {
+		needDevice = protocol.LocalDeviceID
+	}
+
 	var need, items, fileNeed, downloaded, deletes int64
-	rf.WithNeedTruncated(device, func(f db.FileIntf) bool {
+	rf.WithNeedTruncated(needDevice, func(f db.FileIntf) bool {
 		ft := f.(db.FileInfoTruncated)
 
 		// If the file is deleted, we account it only in the deleted column.
@@ -978,7 +1152,7 @@ func (m *model) LocalChangedFiles(folder string, page, perpage int) []db.FileInf
 	if !ok {
 		return nil
 	}
-	if fcfg.Type != config.FolderTypeReceiveOnly {
+	if fcfg.Type != config.FolderTypeReceiveOnly && fcfg.Type != config.FolderTypeMirror {
 		return nil
 	}
 	if rf.ReceiveOnlyChangedSize().TotalItems() == 0 {
@@ -1057,7 +1231,8 @@ func (m *model) handleIndex(deviceID protocol.DeviceID, folder string, fs []prot
 
 	l.Debugf("%v (in): %s / %q: %d files", op, deviceID, folder, len(fs))
 
-	if cfg, ok := m.cfg.Folder(folder); !ok || !cfg.SharedWith(deviceID) {
+	cfg, ok := m.cfg.Folder(folder)
+	if !ok || !cfg.SharedWith(deviceID) {
 		l.Infof("%v for unexpected folder ID %q sent from device %q; ensure that the folder exists and that this device is selected under \"Share With\" in the folder configuration.", op, folder, deviceID)
 		return errors.Wrap(errFolderMissing, folder)
 	} else if cfg.Paused {
@@ -1079,6 +1254,14 @@ func (m *model) handleIndex(deviceID protocol.DeviceID, folder string, fs []prot
 		defer runner.SchedulePull()
 	}
 
+	for i := range fs {
+		// The local flags should never be transmitted over the wire. Make
+		// sure they look like they weren't.
+		fs[i].LocalFlags = 0
+		// Names are compared with ours in the form we keep them in.
+		fs[i].Name = cfg.Normalization.Normalize(fs[i].Name)
+	}
+
 	m.pmut.RLock()
 	downloads := m.deviceDownloads[deviceID]
 	m.pmut.RUnlock()
@@ -1087,11 +1270,6 @@ func (m *model) handleIndex(deviceID protocol.DeviceID, folder string, fs []prot
 	if !update {
 		files.Drop(deviceID)
 	}
-	for i := range fs {
-		// The local flags should never be transmitted over the wire. Make
-		// sure they look like they weren't.
-		fs[i].LocalFlags = 0
-	}
 	files.Update(deviceID, fs)
 
 	m.evLogger.Log(events.RemoteIndexUpdated, map[string]interface{}{
@@ -1110,6 +1288,11 @@ func (m *model) ClusterConfig(deviceID protocol.DeviceID, cm protocol.ClusterCon
 	// Also, collect a list of folders we do share, and if he's interested in
 	// temporary indexes, subscribe the connection.
 
+	if cm.Secondary {
+		// Sent on an additional connection, which only carries requests.
+		return nil
+	}
+
 	tempIndexFolders := make([]string, 0, len(cm.Folders))
 
 	m.pmut.RLock()
@@ -1148,7 +1331,12 @@ func (m *model) ClusterConfig(deviceID protocol.DeviceID, cm protocol.ClusterCon
 				l.Infof("Ignoring folder %s from device %s since we are configured to", folder.Description(), deviceID)
 				continue
 			}
-			m.cfg.AddOrUpdatePendingFolder(folder.ID, folder.Label, deviceID)
+			m.cfg.AddOrUpdatePendingFolder(config.ObservedFolder{
+				ID:          folder.ID,
+				Label:       folder.Label,
+				GlobalBytes: folder.GlobalBytes,
+				GlobalFiles: folder.GlobalFiles,
+			}, deviceID)
 			changed = true
 			m.evLogger.Log(events.FolderRejected, map[string]string{
 				"folder":      folder.ID,
@@ -1243,17 +1431,29 @@ func (m *model) ClusterConfig(deviceID protocol.DeviceID, cm protocol.ClusterCon
 						defer runner.SchedulePull()
 					}
 				}
+
+				if r := m.folderRecoveries[folder.ID]; r != nil {
+					r.announce(deviceID, dev.MaxSequence)
+				}
 			}
 		}
 
+		batchFiles, batchBytes, snapshotBytes := indexBatchSizes(m.cfg.Options(), hello.IndexWindow)
 		is := &indexSender{
-			conn:         conn,
-			connClosed:   closed,
-			folder:       folder.ID,
-			fset:         fs,
-			prevSequence: startSequence,
-			dropSymlinks: dropSymlinks,
-			evLogger:     m.evLogger,
+			conn:          conn,
+			connClosed:    closed,
+			folder:        folder.ID,
+			fset:          fs,
+			prevSequence:  startSequence,
+			dropSymlinks:  dropSymlinks,
+			fixedBlocks:   !hello.SupportsBlockScheme(protocol.BlockSchemeFastCDC),
+			snapshots:     hello.SupportsIndexSnapshots,
+			sha256Only:    !folder.SupportsHashAlgorithm(protocol.HashAlgorithmBLAKE3),
+			subtrees:      nativeSubtrees(folder.Subtrees),
+			batchFiles:    batchFiles,
+			batchBytes:    batchBytes,
+			snapshotBytes: snapshotBytes,
+			evLogger:      m.evLogger,
 		}
 		is.Service = util.AsService(is.serve, is.String())
 		// The token isn't tracked as the service stops when the connection
@@ -1279,6 +1479,26 @@ func (m *model) ClusterConfig(deviceID protocol.DeviceID, cm protocol.ClusterCon
 		}
 	}
 
+	// Needs to happen outside of the fmut, as can cause CommitConfiguration
+	if deviceCfg.SettingsPolicy != config.SettingsPolicyIgnore {
+		for _, folder := range cm.Folders {
+			if folder.Settings != nil {
+				m.handlePushedSettings(deviceCfg, folder)
+			}
+		}
+	}
+	for _, folder := range cm.Folders {
+		m.handleSharedIgnores(deviceID, folder)
+		m.handleHashAlgorithms(deviceID, folder)
+		m.handleTombstoneRetention(deviceID, folder)
+		m.indexSummaries.setRemote(folder.ID, deviceID, folder.IndexSummary)
+	}
+	if deviceCfg.DecommissionPolicy != config.SettingsPolicyIgnore {
+		for _, dev := range cm.Decommissioned {
+			m.handleDecommission(deviceCfg, dev)
+		}
+	}
+
 	if deviceCfg.Introducer {
 		folders, devices, foldersDevices, introduced := m.handleIntroductions(deviceCfg, cm)
 		folders, devices, deintroduced := m.handleDeintroductions(deviceCfg, foldersDevices, folders, devices)
@@ -1333,6 +1553,10 @@ func (m *model) handleIntroductions(introducerCfg config.DeviceConfiguration, cm
 			if device.ID == m.id {
 				continue
 			}
+			// Nor to bring back a device we decommissioned.
+			if m.cfg.DecommissionedDevice(device.ID) {
+				continue
+			}
 
 			foldersDevices.set(device.ID, folder.ID)
 
@@ -1496,21 +1720,40 @@ func (m *model) Closed(conn protocol.Connection, err error) {
 	device := conn.ID()
 
 	m.pmut.Lock()
-	conn, ok := m.conn[device]
+	set, ok := m.conn[device]
 	if !ok {
 		m.pmut.Unlock()
 		return
 	}
+	if set.Remove(conn) {
+		m.pmut.Unlock()
+		l.Infof("Connection to %s at %s closed: %v", device, conn.Name(), err)
+		return
+	}
+	if set.Name() != conn.Name() {
+		// An additional connection of an earlier set, closed along with
+		// its primary connection.
+		m.pmut.Unlock()
+		return
+	}
 	delete(m.conn, device)
 	delete(m.connRequestLimiters, device)
 	delete(m.helloMessages, device)
+	delete(m.requestBatchers, device)
 	delete(m.deviceDownloads, device)
 	delete(m.remotePausedFolders, device)
 	closed := m.closed[device]
 	delete(m.closed, device)
 	m.pmut.Unlock()
 
-	m.progressEmitter.temporaryIndexUnsubscribe(conn)
+	m.indexSummaries.forgetDevice(device)
+
+	// The additional connections are of no use without the primary one.
+	for _, c := range set.Connections()[1:] {
+		c.Close(err)
+	}
+
+	m.progressEmitter.temporaryIndexUnsubscribe(set)
 
 	l.Infof("Connection to %s at %s closed: %v", device, conn.Name(), err)
 	m.evLogger.Log(events.DeviceDisconnected, map[string]string{
@@ -1586,7 +1829,7 @@ func (r *requestResponse) Wait() {
 
 // Request returns the specified data segment by reading it from local disk.
 // Implements the protocol.Model interface.
-func (m *model) Request(deviceID protocol.DeviceID, folder, name string, size int32, offset int64, hash []byte, weakHash uint32, fromTemporary bool) (out protocol.RequestResponse, err error) {
+func (m *model) Request(deviceID protocol.DeviceID, folder, name string, size int32, offset int64, hash []byte, weakHash uint32, fromTemporary bool, priority protocol.RequestPriority) (out protocol.RequestResponse, err error) {
 	if size < 0 || offset < 0 {
 		return nil, protocol.ErrInvalid
 	}
@@ -1594,6 +1837,7 @@ func (m *model) Request(deviceID protocol.DeviceID, folder, name string, size in
 	m.fmut.RLock()
 	folderCfg, ok := m.folderCfgs[folder]
 	folderIgnores := m.folderIgnores[folder]
+	tempFs := m.folderTempFs[folder]
 	m.fmut.RUnlock()
 	if !ok {
 		// The folder might be already unpaused in the config, but not yet
@@ -1616,6 +1860,7 @@ func (m *model) Request(deviceID protocol.DeviceID, folder, name string, size in
 		l.Debugf("Request from %s in folder %q for invalid filename %s", deviceID, folder, name)
 		return nil, protocol.ErrGeneric
 	}
+	name = folderCfg.Normalization.Normalize(name)
 
 	if deviceID != protocol.LocalDeviceID {
 		l.Debugf("%v REQ(in): %s: %q / %q o=%d s=%d t=%v", m, deviceID, folder, name, offset, size, fromTemporary)
@@ -1645,7 +1890,11 @@ func (m *model) Request(deviceID protocol.DeviceID, folder, name string, size in
 	m.pmut.RUnlock()
 
 	if limiter != nil {
-		limiter.take(int(size))
+		if priority == protocol.RequestPriorityInteractive {
+			limiter.takeFirst(int(size))
+		} else {
+			limiter.take(int(size))
+		}
 	}
 
 	// The requestResponse releases the bytes to the limiter when its Close method is called.
@@ -1664,33 +1913,46 @@ func (m *model) Request(deviceID protocol.DeviceID, folder, name string, size in
 		}()
 	}
 
+	// Reads for other devices are subject to the limits on those, local
+	// ones aren't.
+	read := readOffsetIntoBuf
+	if deviceID != protocol.LocalDeviceID {
+		read = m.requestReads.read
+	}
+
 	// Only check temp files if the flag is set, and if we are set to advertise
 	// the temp indexes.
 	if fromTemporary && !folderCfg.DisableTempIndexes {
 		tempFn := fs.TempName(name)
-
-		if info, err := folderFs.Lstat(tempFn); err != nil || !info.IsRegular() {
+		if info, err := tempFs.Lstat(tempFn); err != nil || !info.IsRegular() {
 			// Reject reads for anything that doesn't exist or is something
 			// other than a regular file.
 			l.Debugf("%v REQ(in) failed stating temp file (%v): %s: %q / %q o=%d s=%d", m, err, deviceID, folder, name, offset, size)
 			return nil, protocol.ErrNoSuchFile
 		}
-		err := readOffsetIntoBuf(folderFs, tempFn, offset, res.data)
-		if err == nil && scanner.Validate(res.data, hash, weakHash) {
+		err := read(tempFs, tempFn, offset, res.data)
+		if err == nil && scanner.Validate(res.data, hash, weakHash, m.requestHashAlgorithm(folder, name, true)) {
 			return res, nil
 		}
 		// Fall through to reading from a non-temp file, just incase the temp
 		// file has finished downloading.
 	}
 
-	if info, err := folderFs.Lstat(name); err != nil || !info.IsRegular() {
+	algo := m.requestHashAlgorithm(folder, name, false)
+	info, err := folderFs.Lstat(name)
+	if err != nil || !info.IsRegular() {
+		// The file may have been moved away to be replaced by a new
+		// version that hasn't been scanned yet.
+		if m.snapshots.read(folder, name, offset, res.data) && scanner.Validate(res.data, hash, weakHash, algo) {
+			return res, nil
+		}
 		// Reject reads for anything that doesn't exist or is something
 		// other than a regular file.
 		l.Debugf("%v REQ(in) failed stating file (%v): %s: %q / %q o=%d s=%d", m, err, deviceID, folder, name, offset, size)
 		return nil, protocol.ErrNoSuchFile
 	}
 
-	if err := readOffsetIntoBuf(folderFs, name, offset, res.data); fs.IsNotExist(err) {
+	if err := read(folderFs, name, offset, res.data); fs.IsNotExist(err) {
 		l.Debugf("%v REQ(in) file doesn't exist: %s: %q / %q o=%d s=%d", m, deviceID, folder, name, offset, size)
 		return nil, protocol.ErrNoSuchFile
 	} else if err != nil {
@@ -1698,12 +1960,25 @@ func (m *model) Request(deviceID protocol.DeviceID, folder, name string, size in
 		return nil, protocol.ErrGeneric
 	}
 
-	if !scanner.Validate(res.data, hash, weakHash) {
+	if !scanner.Validate(res.data, hash, weakHash, algo) {
+		// The file changed since it was scanned, but the snapshot may
+		// still hold the version the other device wants.
+		if m.snapshots.read(folder, name, offset, res.data) && scanner.Validate(res.data, hash, weakHash, algo) {
+			l.Debugf("%v REQ(in) served from snapshot: %s: %q / %q o=%d s=%d", m, deviceID, folder, name, offset, size)
+			return res, nil
+		}
+		m.snapshots.drop(folder, name)
 		m.recheckFile(deviceID, folderFs, folder, name, size, offset, hash)
 		l.Debugf("%v REQ(in) failed validating data (%v): %s: %q / %q o=%d s=%d", m, err, deviceID, folder, name, offset, size)
 		return nil, protocol.ErrNoSuchFile
 	}
 
+	// Files that changed recently are likely to change again before the
+	// other device has all of it.
+	if time.Since(info.ModTime()) < snapshotHotWindow {
+		m.snapshots.take(folderFs, folder, name)
+	}
+
 	return res, nil
 }
 
@@ -1719,7 +1994,7 @@ func (m *model) recheckFile(deviceID protocol.DeviceID, folderFs fs.Filesystem,
 		return
 	}
 
-	blockIndex := int(offset / int64(cf.BlockSize()))
+	blockIndex := cf.BlockIndex(offset)
 	if blockIndex >= len(cf.Blocks) {
 		l.Debugf("%v recheckFile: %s: %q / %q i=%d: block index too far", m, deviceID, folder, name, blockIndex)
 		return
@@ -1771,8 +2046,30 @@ func (m *model) CurrentGlobalFile(folder string, file string) (protocol.FileInfo
 	return fs.GetGlobal(file)
 }
 
-// Connection returns the current connection for device, and a boolean whether a connection was found.
-func (m *model) Connection(deviceID protocol.DeviceID) (connections.Connection, bool) {
+// FileHistory returns the global versions of the file recorded in the
+// database, oldest first, if the folder keeps a file history.
+func (m *model) FileHistory(folder, file string) ([]db.FileHistoryEntry, error) {
+	m.fmut.RLock()
+	fs, ok := m.folderFiles[folder]
+	m.fmut.RUnlock()
+	if !ok {
+		return nil, errFolderMissing
+	}
+	entries, err := fs.FileHistory(file)
+	if err != nil {
+		return nil, err
+	}
+	// Our own changes are under a placeholder ID in the database.
+	for i := range entries {
+		if bytes.Equal(entries[i].Device, protocol.LocalDeviceID[:]) {
+			entries[i].Device = m.id[:]
+		}
+	}
+	return entries, nil
+}
+
+// Connection returns the current connections for device, and a boolean whether a connection was found.
+func (m *model) Connection(deviceID protocol.DeviceID) (*connections.ConnectionSet, bool) {
 	m.pmut.RLock()
 	cn, ok := m.conn[deviceID]
 	m.pmut.RUnlock()
@@ -1801,7 +2098,7 @@ func (m *model) GetIgnores(folder string) ([]string, []string, error) {
 	}
 
 	if !ignoresOk {
-		ignores = ignore.New(fs.NewFilesystem(cfg.FilesystemType, cfg.Path))
+		ignores = ignore.New(fs.NewEncryptedFilesystem(fs.NewFilesystem(cfg.FilesystemType, cfg.Path), cfg.EncryptionPassphrase), folderIgnoreOptions(cfg)...)
 	}
 
 	if err := ignores.Load(".stignore"); err != nil && !fs.IsNotExist(err) {
@@ -1833,6 +2130,11 @@ func (m *model) SetIgnores(folder string, content []string) error {
 		return err
 	}
 
+	if cfg.PushSettings || cfg.ShareIgnores {
+		// Reconnect to push the new patterns with the cluster config.
+		m.closeConns(cfg.DeviceIDs(), errSettingsChanged)
+	}
+
 	m.fmut.RLock()
 	runner, ok := m.folderRunners[folder]
 	m.fmut.RUnlock()
@@ -1878,19 +2180,35 @@ func (m *model) OnHello(remoteID protocol.DeviceID, addr net.Addr, hello protoco
 // GetHello is called when we are about to connect to some remote device.
 func (m *model) GetHello(id protocol.DeviceID) protocol.HelloIntf {
 	name := ""
-	if _, ok := m.cfg.Device(id); ok {
+	multipath, padding, scanRequests := false, false, false
+	if cfg, ok := m.cfg.Device(id); ok {
 		name = m.cfg.MyName()
+		multipath = cfg.Multipath
+		padding = cfg.PadRelayed
+		scanRequests = cfg.AllowRemoteScan
 	}
 	return &protocol.Hello{
-		DeviceName:    name,
-		ClientName:    m.clientName,
-		ClientVersion: m.clientVersion,
+		DeviceName:            name,
+		ClientName:            m.clientName,
+		ClientVersion:         m.clientVersion,
+		SupportsBatchRequests: true,
+		CompressionAlgorithms: protocol.SupportedCompressionAlgorithms,
+		SupportsMultipath:     multipath,
+		SupportsPadding:       padding,
+
+		SupportsApplicationMessages: true,
+		SupportsScanRequests:        scanRequests,
+		BlockSchemes:                protocol.SupportedBlockSchemes,
+		SupportsIndexSnapshots:      true,
+		IndexWindow:                 int64(m.cfg.Options().IndexReceiveWindowKiB) << 10,
 	}
 }
 
 // AddConnection adds a new peer connection to the model. An initial index will
 // be sent to the connected peer, thereafter index updates whenever the local
-// folder changes.
+// folder changes. If both sides want multipath connections and there is no
+// connection over the same transport yet, the connection is added to those
+// used for requests to the device instead.
 func (m *model) AddConnection(conn connections.Connection, hello protocol.HelloResult) {
 	deviceID := conn.ID()
 	device, ok := m.cfg.Device(deviceID)
@@ -1900,6 +2218,14 @@ func (m *model) AddConnection(conn connections.Connection, hello protocol.HelloR
 	}
 
 	m.pmut.Lock()
+	if set, ok := m.conn[deviceID]; ok && set.CanAdd(conn.Transport()) {
+		set.Add(conn)
+		l.Infof("Additional connection to %s at %s", deviceID, conn)
+		conn.Start()
+		m.pmut.Unlock()
+		conn.ClusterConfig(protocol.ClusterConfig{Secondary: true})
+		return
+	}
 	if oldConn, ok := m.conn[deviceID]; ok {
 		l.Infoln("Replacing old connection", oldConn, "with", conn, "for", deviceID)
 		// There is an existing connection to this device that we are
@@ -1914,7 +2240,8 @@ func (m *model) AddConnection(conn connections.Connection, hello protocol.HelloR
 		m.pmut.Lock()
 	}
 
-	m.conn[deviceID] = conn
+	set := connections.NewConnectionSet(conn, device.Multipath && hello.SupportsMultipath)
+	m.conn[deviceID] = set
 	m.closed[deviceID] = make(chan struct{})
 	m.deviceDownloads[deviceID] = newDeviceDownloadState()
 	// 0: default, <0: no limiting
@@ -1926,6 +2253,9 @@ func (m *model) AddConnection(conn connections.Connection, hello protocol.HelloR
 	}
 
 	m.helloMessages[deviceID] = hello
+	if hello.SupportsBatchRequests {
+		m.requestBatchers[deviceID] = newRequestBatcher(set, m.closed[deviceID])
+	}
 
 	event := map[string]string{
 		"id":            deviceID.String(),
@@ -1968,6 +2298,9 @@ func (m *model) DownloadProgress(device protocol.DeviceID, folder string, update
 	if !ok || cfg.DisableTempIndexes || !cfg.SharedWith(device) {
 		return nil
 	}
+	for i := range updates {
+		updates[i].Name = cfg.Normalization.Normalize(updates[i].Name)
+	}
 
 	m.pmut.RLock()
 	downloads := m.deviceDownloads[device]
@@ -1984,6 +2317,35 @@ func (m *model) DownloadProgress(device protocol.DeviceID, folder string, update
 	return nil
 }
 
+// ApplicationMessage passes on a message from the device to applications
+// listening for events. Implements the protocol.Model interface.
+func (m *model) ApplicationMessage(device protocol.DeviceID, msgType string, data []byte) error {
+	l.Debugf("%v application message from %s: %q (%d bytes)", m, device, msgType, len(data))
+	m.evLogger.Log(events.ApplicationMessageReceived, map[string]interface{}{
+		"device": device.String(),
+		"type":   msgType,
+		"data":   data,
+	})
+	return nil
+}
+
+// SendApplicationMessage sends a small opaque message to the connected
+// device, where it becomes an ApplicationMessageReceived event.
+func (m *model) SendApplicationMessage(device protocol.DeviceID, msgType string, data []byte) error {
+	m.pmut.RLock()
+	conn, ok := m.conn[device]
+	hello := m.helloMessages[device]
+	m.pmut.RUnlock()
+
+	if !ok {
+		return errDeviceNotConnected
+	}
+	if !hello.SupportsApplicationMessages {
+		return errNoAppMessages
+	}
+	return conn.ApplicationMessage(context.TODO(), msgType, data)
+}
+
 func (m *model) deviceWasSeen(deviceID protocol.DeviceID) {
 	m.fmut.RLock()
 	sr, ok := m.deviceStatRefs[deviceID]
@@ -2001,8 +2363,18 @@ type indexSender struct {
 	fset         *db.FileSet
 	prevSequence int64
 	dropSymlinks bool
+	fixedBlocks  bool     // the other device only understands fixed size blocks
+	snapshots    bool     // the other device accepts index snapshots
+	sha256Only   bool     // the other device only understands SHA-256 hashes
+	subtrees     []string // the only paths to send, when set
 	evLogger     events.Logger
 	connClosed   chan struct{}
+
+	// The most files and bytes per index message, and the most bytes per
+	// index snapshot message.
+	batchFiles    int
+	batchBytes    int
+	snapshotBytes int
 }
 
 func (s *indexSender) serve(ctx context.Context) {
@@ -2070,9 +2442,18 @@ func (s *indexSender) Complete() bool { return true }
 // returns the highest sent sequence number.
 func (s *indexSender) sendIndexTo(ctx context.Context) error {
 	initial := s.prevSequence == 0
+	// The initial index of a large folder goes in a few large compressed
+	// snapshot messages rather than many index messages. What changes
+	// meanwhile follows as index updates.
+	snapshot := initial && s.snapshots && int(s.fset.LocalSize().TotalItems()) >= indexSnapshotMinFiles
 	batch := newFileInfoBatch(nil)
 	batch.flushFn = func(fs []protocol.FileInfo) error {
-		l.Debugf("%v: Sending %d files (<%d bytes)", s, len(batch.infos), batch.size)
+		l.Debugf("%v: Sending %d files (<%d bytes, snapshot=%v)", s, len(batch.infos), batch.size, snapshot)
+		if snapshot {
+			first := initial
+			initial = false
+			return s.conn.IndexSnapshot(ctx, s.folder, fs, first)
+		}
 		if initial {
 			initial = false
 			return s.conn.Index(ctx, s.folder, fs)
@@ -2083,8 +2464,10 @@ func (s *indexSender) sendIndexTo(ctx context.Context) error {
 	var err error
 	var f protocol.FileInfo
 	s.fset.WithHaveSequence(s.prevSequence+1, func(fi db.FileIntf) bool {
-		if err = batch.flushIfFull(); err != nil {
-			return false
+		if snapshot && batch.size >= s.snapshotBytes || !snapshot && (len(batch.infos) >= s.batchFiles || batch.size >= s.batchBytes) {
+			if err = batch.flush(); err != nil {
+				return false
+			}
 		}
 
 		if shouldDebug() {
@@ -2108,6 +2491,14 @@ func (s *indexSender) sendIndexTo(ctx context.Context) error {
 		}
 		f.LocalFlags = 0 // never sent externally
 
+		if !f.IsDeleted() && (s.fixedBlocks && f.BlockScheme != protocol.BlockSchemeFixed || s.sha256Only && f.HashAlgorithm != protocol.HashAlgorithmSHA256) {
+			// The other device would misinterpret the blocks, so it
+			// mustn't try to pull the file. Once it's upgraded, a change
+			// to the file is required for it to sync, due to delta
+			// indexes.
+			f.RawInvalid = true
+		}
+
 		if s.dropSymlinks && f.IsSymlink() {
 			// Do not send index entries with symlinks to clients that can't
 			// handle it. Fixes issue #3802. Once both sides are upgraded, a
@@ -2116,6 +2507,12 @@ func (s *indexSender) sendIndexTo(ctx context.Context) error {
 			return true
 		}
 
+		if len(s.subtrees) > 0 && !fs.InSubtrees(f.Name, s.subtrees) {
+			// The other device only syncs some paths in the folder, and
+			// asked us not to bother it with the rest.
+			return true
+		}
+
 		batch.append(f)
 		return true
 	})
@@ -2134,11 +2531,50 @@ func (s *indexSender) sendIndexTo(ctx context.Context) error {
 	return err
 }
 
+// nativeSubtrees returns the subtrees a device sent us as native paths.
+func nativeSubtrees(subtrees []string) []string {
+	if len(subtrees) == 0 {
+		return nil
+	}
+	native := make([]string, len(subtrees))
+	for i, subtree := range subtrees {
+		native[i] = filepath.Clean(osutil.NativeFilename(subtree))
+	}
+	return native
+}
+
+// indexBatchSizes returns the most files and bytes to put in an index
+// message and the most bytes to put in an index snapshot message, as
+// configured. None are larger than the index window of the other device,
+// when it has one, for a message to not wait on the previous one being
+// handled in full.
+func indexBatchSizes(opts config.OptionsConfiguration, window int64) (files, bytes, snapshotBytes int) {
+	files, bytes, snapshotBytes = maxBatchSizeFiles, maxBatchSizeBytes, protocol.MaxIndexSnapshotSize
+	if opts.IndexBatchFiles > 0 {
+		files = opts.IndexBatchFiles
+	}
+	if opts.IndexBatchKiB > 0 {
+		bytes = opts.IndexBatchKiB << 10
+	}
+	if window > 0 {
+		if int64(bytes) > window {
+			bytes = int(window)
+		}
+		if int64(snapshotBytes) > window {
+			snapshotBytes = int(window)
+		}
+	}
+	return files, bytes, snapshotBytes
+}
+
 func (s *indexSender) String() string {
 	return fmt.Sprintf("indexSender@%p for %s to %s at %s", s, s.folder, s.dev, s.conn)
 }
 
 func (m *model) requestGlobal(ctx context.Context, deviceID protocol.DeviceID, folder, name string, offset int64, size int, hash []byte, weakHash uint32, fromTemporary bool) ([]byte, error) {
+	m.fmut.RLock()
+	transports := m.folderCfgs[folder].DataTransports
+	m.fmut.RUnlock()
 	m.pmut.RLock()
 	nc, ok := m.conn[deviceID]
 	m.pmut.RUnlock()
@@ -2146,12 +2582,36 @@ func (m *model) requestGlobal(ctx context.Context, deviceID protocol.DeviceID, f
 	if !ok {
 		return nil, fmt.Errorf("requestGlobal: no such device: %s", deviceID)
 	}
+	if len(transports) > 0 {
+		ctx = connections.WithTransports(ctx, transports)
+	}
 
 	l.Debugf("%v REQ(out): %s: %q / %q o=%d s=%d h=%x wh=%x ft=%t", m, deviceID, folder, name, offset, size, hash, weakHash, fromTemporary)
 
 	return nc.Request(ctx, folder, name, offset, size, hash, weakHash, fromTemporary)
 }
 
+// requestGlobalBatched requests the complete contents of a small file,
+// batched together with other such requests if the device supports it.
+func (m *model) requestGlobalBatched(ctx context.Context, deviceID protocol.DeviceID, folder, name string, size int, hash []byte, weakHash uint32) ([]byte, error) {
+	m.fmut.RLock()
+	restricted := len(m.folderCfgs[folder].DataTransports) > 0
+	m.fmut.RUnlock()
+	m.pmut.RLock()
+	batcher, ok := m.requestBatchers[deviceID]
+	m.pmut.RUnlock()
+
+	// Batches may mix folders, so requests for folders restricted to some
+	// transports aren't batched.
+	if !ok || restricted {
+		return m.requestGlobal(ctx, deviceID, folder, name, 0, size, hash, weakHash, false)
+	}
+
+	l.Debugf("%v REQ(out, batched): %s: %q / %q s=%d h=%x wh=%x", m, deviceID, folder, name, size, hash, weakHash)
+
+	return batcher.Request(ctx, folder, name, size, hash, weakHash)
+}
+
 func (m *model) ScanFolders() map[string]error {
 	m.fmut.RLock()
 	folders := make([]string, 0, len(m.folderCfgs))
@@ -2237,6 +2697,46 @@ func (m *model) numHashers(folder string) int {
 	return 1
 }
 
+// folderDisk returns the disk the folder is on, as configured or detected.
+func folderDisk(cfg config.FolderConfiguration) scanner.Disk {
+	var disk scanner.Disk
+	if cfg.FilesystemType == fs.FilesystemTypeBasic {
+		disk = scanner.DetectDisk(cfg.Filesystem().URI())
+	}
+	if cfg.Disk != "" {
+		disk.ID = cfg.Disk
+	}
+	return disk
+}
+
+// updateDiskLimitLocked sets how many files may be hashed at the same time
+// on the disk, according to the folders on it.
+func (m *model) updateDiskLimitLocked(disk string) {
+	limit := 0
+	rotational := false
+	for folder, d := range m.folderDisks {
+		if d.ID != disk {
+			continue
+		}
+		if n := m.folderCfgs[folder].DiskHashers; n > 0 && (limit == 0 || n < limit) {
+			limit = n
+		}
+		rotational = rotational || d.Rotational
+	}
+	if limit == 0 && rotational {
+		limit = 1
+	}
+	l.Debugf("Limiting hashing on disk %q to %d files at a time", disk, limit)
+	m.diskLimiter.SetLimit(disk, limit)
+}
+
+// hashingDisk returns the ID of the disk the folder is on, for hashing.
+func (m *model) hashingDisk(folder string) string {
+	m.fmut.RLock()
+	defer m.fmut.RUnlock()
+	return m.folderDisks[folder].ID
+}
+
 // generateClusterConfig returns a ClusterConfigMessage that is correct for
 // the given peer device
 func (m *model) generateClusterConfig(device protocol.DeviceID) protocol.ClusterConfig {
@@ -2251,19 +2751,35 @@ func (m *model) generateClusterConfig(device protocol.DeviceID) protocol.Cluster
 		}
 
 		protocolFolder := protocol.Folder{
-			ID:                 folderCfg.ID,
-			Label:              folderCfg.Label,
-			ReadOnly:           folderCfg.Type == config.FolderTypeSendOnly,
-			IgnorePermissions:  folderCfg.IgnorePerms,
-			IgnoreDelete:       folderCfg.IgnoreDelete,
-			DisableTempIndexes: folderCfg.DisableTempIndexes,
-			Paused:             folderCfg.Paused,
+			ID:                     folderCfg.ID,
+			Label:                  folderCfg.Label,
+			ReadOnly:               folderCfg.Type == config.FolderTypeSendOnly,
+			IgnorePermissions:      folderCfg.IgnorePerms,
+			IgnoreDelete:           folderCfg.IgnoreDelete,
+			DisableTempIndexes:     folderCfg.DisableTempIndexes,
+			Paused:                 folderCfg.Paused,
+			Settings:               m.pushedSettingsLocked(folderCfg),
+			SharedIgnores:          m.sharedIgnoresLocked(folderCfg),
+			Subtrees:               folderCfg.Subtrees,
+			HashAlgorithms:         protocol.SupportedHashAlgorithms,
+			TombstoneRetentionDays: int32(folderCfg.TombstoneRetentionDays),
 		}
 
 		var fs *db.FileSet
 		if !folderCfg.Paused {
 			fs = m.folderFiles[folderCfg.ID]
 		}
+		if fs != nil {
+			// Lets the other side show what it's in for before accepting
+			// the folder.
+			global := fs.GlobalSize()
+			protocolFolder.GlobalBytes = global.Bytes
+			protocolFolder.GlobalFiles = int64(global.Files)
+			// Lets the other side tell whether and where our indexes
+			// diverge.
+			summary := m.indexSummaries.get(fs, folderCfg.ID, protocol.LocalDeviceID)
+			protocolFolder.IndexSummary = &summary
+		}
 
 		for _, device := range folderCfg.Devices {
 			deviceCfg, _ := m.cfg.Device(device.DeviceID)
@@ -2293,6 +2809,10 @@ func (m *model) generateClusterConfig(device protocol.DeviceID) protocol.Cluster
 		message.Folders = append(message.Folders, protocolFolder)
 	}
 
+	for _, dev := range m.cfg.RawCopy().Decommissioned {
+		message.Decommissioned = append(message.Decommissioned, dev.ID)
+	}
+
 	return message
 }
 
@@ -2310,6 +2830,18 @@ func (m *model) State(folder string) (string, time.Time, error) {
 	return state.String(), changed, err
 }
 
+// ScanProgress returns the progress of hashing in the folder, if it is
+// being scanned.
+func (m *model) ScanProgress(folder string) (scanner.ScanProgress, bool) {
+	m.fmut.RLock()
+	runner, ok := m.folderRunners[folder]
+	m.fmut.RUnlock()
+	if !ok {
+		return scanner.ScanProgress{}, false
+	}
+	return runner.getScanProgress()
+}
+
 func (m *model) FolderErrors(folder string) ([]FileError, error) {
 	m.fmut.RLock()
 	err := m.checkFolderRunningLocked(folder)
@@ -2332,6 +2864,19 @@ func (m *model) WatchError(folder string) error {
 	return runner.WatchError()
 }
 
+// WatchAggregationMode returns how the folder's watcher currently aggregates
+// events, which is normal when it isn't watching.
+func (m *model) WatchAggregationMode(folder string) watchaggregator.Mode {
+	m.fmut.RLock()
+	err := m.checkFolderRunningLocked(folder)
+	runner := m.folderRunners[folder]
+	m.fmut.RUnlock()
+	if err != nil {
+		return watchaggregator.ModeNormal
+	}
+	return runner.WatchAggregationMode()
+}
+
 func (m *model) Override(folder string) {
 	// Grab the runner and the file set.
 
@@ -2536,14 +3081,16 @@ next:
 				continue next
 			}
 		}
-		_, ok := m.conn[device]
-		if ok {
+		if m.dataAllowedLocked(device, cfg) {
 			availabilities = append(availabilities, Availability{ID: device, FromTemporary: false})
 		}
 	}
 
 	for _, device := range cfg.Devices {
-		if m.deviceDownloads[device.DeviceID].Has(folder, file.Name, file.Version, int32(block.Offset/int64(file.BlockSize()))) {
+		if !m.dataAllowedLocked(device.DeviceID, cfg) {
+			continue
+		}
+		if m.deviceDownloads[device.DeviceID].Has(folder, file.Name, file.Version, int32(file.BlockIndex(block.Offset))) {
 			availabilities = append(availabilities, Availability{ID: device.DeviceID, FromTemporary: true})
 		}
 	}
@@ -2551,6 +3098,62 @@ next:
 	return availabilities
 }
 
+// dataAllowedLocked returns true if the device is connected over a
+// transport that file data of the folder may be transferred over.
+func (m *model) dataAllowedLocked(device protocol.DeviceID, cfg config.FolderConfiguration) bool {
+	conn, ok := m.conn[device]
+	return ok && (len(cfg.DataTransports) == 0 || conn.Allows(cfg.DataTransports))
+}
+
+// blockAvailabilityCounts returns, for each block of the given file, the
+// number of connected devices that the block can currently be pulled from.
+// Devices that only have part of the file in a temporary file, according
+// to their download progress updates, are counted for the blocks they have.
+func (m *model) blockAvailabilityCounts(folder string, file protocol.FileInfo) []int {
+	m.fmut.RLock()
+	m.pmut.RLock()
+	defer m.pmut.RUnlock()
+
+	fs, ok := m.folderFiles[folder]
+	cfg := m.folderCfgs[folder]
+	m.fmut.RUnlock()
+
+	if !ok {
+		return nil
+	}
+
+	full := 0
+next:
+	for _, device := range fs.Availability(file.Name) {
+		for _, pausedFolder := range m.remotePausedFolders[device] {
+			if pausedFolder == folder {
+				continue next
+			}
+		}
+		if m.dataAllowedLocked(device, cfg) {
+			full++
+		}
+	}
+
+	counts := make([]int, len(file.Blocks))
+	for i := range counts {
+		counts[i] = full
+	}
+	for _, device := range cfg.Devices {
+		downloads, ok := m.deviceDownloads[device.DeviceID]
+		if !ok || !m.dataAllowedLocked(device.DeviceID, cfg) {
+			continue
+		}
+		for i := range counts {
+			if downloads.Has(folder, file.Name, file.Version, int32(i)) {
+				counts[i]++
+			}
+		}
+	}
+
+	return counts
+}
+
 // BringToFront bumps the given files priority in the job queue.
 func (m *model) BringToFront(folder, file string) {
 	m.fmut.RLock()
@@ -2562,6 +3165,89 @@ func (m *model) BringToFront(folder, file string) {
 	}
 }
 
+// Prioritize bumps the given file to the front of the job queue and
+// optionally lets its block requests bypass the pending request limit.
+func (m *model) Prioritize(folder, file string, bumpRequests bool) error {
+	m.fmut.RLock()
+	runner, ok := m.folderRunners[folder]
+	fset := m.folderFiles[folder]
+	m.fmut.RUnlock()
+
+	if !ok {
+		return errFolderMissing
+	}
+	if !fset.NeedsFile(file) {
+		return errFileNotNeeded
+	}
+	runner.Prioritize(file, bumpRequests)
+	return nil
+}
+
+// UndoEntries returns the files that were deleted or replaced by remote
+// changes and can be restored, most recent first.
+func (m *model) UndoEntries(folder string) ([]UndoEntry, error) {
+	m.fmut.RLock()
+	runner, ok := m.folderRunners[folder]
+	m.fmut.RUnlock()
+
+	if !ok {
+		return nil, errFolderMissing
+	}
+	return runner.UndoEntries()
+}
+
+// Undo restores the file of the given undo entry, or of the most recent one
+// if id is negative.
+func (m *model) Undo(folder string, id int64) (UndoEntry, error) {
+	m.fmut.RLock()
+	runner, ok := m.folderRunners[folder]
+	m.fmut.RUnlock()
+
+	if !ok {
+		return UndoEntry{}, errFolderMissing
+	}
+	return runner.Undo(id)
+}
+
+// Hydrate pulls the contents of a file that is represented by a
+// placeholder.
+func (m *model) Hydrate(folder, file string) error {
+	m.fmut.RLock()
+	runner, ok := m.folderRunners[folder]
+	m.fmut.RUnlock()
+
+	if !ok {
+		return errFolderMissing
+	}
+	return runner.Hydrate(file)
+}
+
+// SkippedChanges returns the changes from other devices that the accept
+// policy of the folder kept from being applied.
+func (m *model) SkippedChanges(folder string) ([]SkippedChange, error) {
+	m.fmut.RLock()
+	runner, ok := m.folderRunners[folder]
+	m.fmut.RUnlock()
+
+	if !ok {
+		return nil, errFolderMissing
+	}
+	return runner.SkippedChanges()
+}
+
+// PullerState returns the files the folder is pulling and the first queued
+// ones, up to the given number.
+func (m *model) PullerState(folder string, queued int) (PullerState, error) {
+	m.fmut.RLock()
+	runner, ok := m.folderRunners[folder]
+	m.fmut.RUnlock()
+
+	if !ok {
+		return PullerState{}, errFolderMissing
+	}
+	return runner.PullerState(queued), nil
+}
+
 func (m *model) ResetFolder(folder string) {
 	l.Infof("Cleaning data for folder %q", folder)
 	db.DropFolder(m.db, folder)
@@ -2666,6 +3352,7 @@ func (m *model) CommitConfiguration(from, to config.Configuration) bool {
 	m.fmut.Unlock()
 
 	scanLimiter.setCapacity(to.Options.MaxConcurrentScans)
+	m.requestReads.setOptions(to.Options)
 
 	// Some options don't require restart as those components handle it fine
 	// by themselves. Compare the options structs containing only the
@@ -16,6 +16,7 @@ import (
 	"math/rand"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"runtime/pprof"
 	"strconv"
@@ -26,6 +27,7 @@ import (
 	"time"
 
 	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/connections"
 	"github.com/syncthing/syncthing/lib/db"
 	"github.com/syncthing/syncthing/lib/db/backend"
 	"github.com/syncthing/syncthing/lib/events"
@@ -134,7 +136,7 @@ func TestRequest(t *testing.T) {
 	defer cleanupModel(m)
 
 	// Existing, shared file
-	res, err := m.Request(device1, "default", "foo", 6, 0, nil, 0, false)
+	res, err := m.Request(device1, "default", "foo", 6, 0, nil, 0, false, protocol.RequestPriorityNormal)
 	if err != nil {
 		t.Error(err)
 	}
@@ -144,31 +146,31 @@ func TestRequest(t *testing.T) {
 	}
 
 	// Existing, nonshared file
-	_, err = m.Request(device2, "default", "foo", 6, 0, nil, 0, false)
+	_, err = m.Request(device2, "default", "foo", 6, 0, nil, 0, false, protocol.RequestPriorityNormal)
 	if err == nil {
 		t.Error("Unexpected nil error on insecure file read")
 	}
 
 	// Nonexistent file
-	_, err = m.Request(device1, "default", "nonexistent", 6, 0, nil, 0, false)
+	_, err = m.Request(device1, "default", "nonexistent", 6, 0, nil, 0, false, protocol.RequestPriorityNormal)
 	if err == nil {
 		t.Error("Unexpected nil error on insecure file read")
 	}
 
 	// Shared folder, but disallowed file name
-	_, err = m.Request(device1, "default", "../walk.go", 6, 0, nil, 0, false)
+	_, err = m.Request(device1, "default", "../walk.go", 6, 0, nil, 0, false, protocol.RequestPriorityNormal)
 	if err == nil {
 		t.Error("Unexpected nil error on insecure file read")
 	}
 
 	// Negative offset
-	_, err = m.Request(device1, "default", "foo", -4, 0, nil, 0, false)
+	_, err = m.Request(device1, "default", "foo", -4, 0, nil, 0, false, protocol.RequestPriorityNormal)
 	if err == nil {
 		t.Error("Unexpected nil error on insecure file read")
 	}
 
 	// Larger block than available
-	_, err = m.Request(device1, "default", "foo", 42, 0, nil, 0, false)
+	_, err = m.Request(device1, "default", "foo", 42, 0, nil, 0, false, protocol.RequestPriorityNormal)
 	if err == nil {
 		t.Error("Unexpected nil error on insecure file read")
 	}
@@ -282,7 +284,7 @@ func BenchmarkRequestInSingleFile(b *testing.B) {
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
-		if _, err := m.Request(device1, "default", "request/for/a/file/in/a/couple/of/dirs/128k", 128<<10, 0, nil, 0, false); err != nil {
+		if _, err := m.Request(device1, "default", "request/for/a/file/in/a/couple/of/dirs/128k", 128<<10, 0, nil, 0, false, protocol.RequestPriorityNormal); err != nil {
 			b.Error(err)
 		}
 	}
@@ -461,6 +463,41 @@ func TestClusterConfig(t *testing.T) {
 	}
 }
 
+func TestClusterConfigFolderSize(t *testing.T) {
+	w := createTmpWrapper(defaultCfg)
+	m := setupModel(w)
+	defer cleanupModel(m)
+
+	m.fmut.RLock()
+	fset := m.folderFiles["default"]
+	m.fmut.RUnlock()
+	fset.Update(device1, []protocol.FileInfo{{Name: "a", Size: 1234, Version: protocol.Vector{}.Update(device1.Short())}})
+
+	cm := m.generateClusterConfig(device1)
+	if len(cm.Folders) != 1 || cm.Folders[0].GlobalFiles < 1 || cm.Folders[0].GlobalBytes < 1234 {
+		t.Fatal("expected the global size to be announced, got", cm.Folders)
+	}
+
+	// The size announced for a folder we don't have is kept with the
+	// pending folder.
+
+	addFakeConn(m, device1)
+	m.ClusterConfig(device1, protocol.ClusterConfig{
+		Folders: []protocol.Folder{
+			{
+				ID:          "pending",
+				GlobalBytes: 2 << 40,
+				GlobalFiles: 240000,
+				Devices:     []protocol.Device{{ID: myID}, {ID: device1}},
+			},
+		},
+	})
+	dev, _ := w.Device(device1)
+	if len(dev.PendingFolders) != 1 || dev.PendingFolders[0].GlobalBytes != 2<<40 || dev.PendingFolders[0].GlobalFiles != 240000 {
+		t.Fatal("expected a pending folder with the announced size, got", dev.PendingFolders)
+	}
+}
+
 func TestIntroducer(t *testing.T) {
 	var introducedByAnyone protocol.DeviceID
 
@@ -869,7 +906,7 @@ func TestIssue5063(t *testing.T) {
 
 	m.pmut.Lock()
 	for _, c := range m.conn {
-		conn := c.(*fakeConnection)
+		conn := c.Connection.(*fakeConnection)
 		conn.mut.Lock()
 		conn.closeFn = func(_ error) {}
 		conn.mut.Unlock()
@@ -3251,14 +3288,14 @@ func TestRequestLimit(t *testing.T) {
 
 	file := "tmpfile"
 	befReq := time.Now()
-	first, err := m.Request(device1, "default", file, 2000, 0, nil, 0, false)
+	first, err := m.Request(device1, "default", file, 2000, 0, nil, 0, false, protocol.RequestPriorityNormal)
 	if err != nil {
 		t.Fatalf("First request failed: %v", err)
 	}
 	reqDur := time.Since(befReq)
 	returned := make(chan struct{})
 	go func() {
-		second, err := m.Request(device1, "default", file, 2000, 0, nil, 0, false)
+		second, err := m.Request(device1, "default", file, 2000, 0, nil, 0, false, protocol.RequestPriorityNormal)
 		if err != nil {
 			t.Errorf("Second request failed: %v", err)
 		}
@@ -3436,3 +3473,411 @@ func TestDeviceWasSeen(t *testing.T) {
 		t.Error("device should have been seen now")
 	}
 }
+
+func TestQuickStart(t *testing.T) {
+	w, fcfg := tmpDefaultWrapper()
+	fcfg.QuickStart = true
+	w.SetFolder(fcfg)
+	ffs := fcfg.Filesystem()
+	defer os.RemoveAll(ffs.URI())
+	defer os.Remove(w.ConfigPath())
+
+	for _, name := range []string{"a", "b"} {
+		must(t, ioutil.WriteFile(filepath.Join(ffs.URI(), name), []byte(name), 0644))
+	}
+
+	// Without an index the initial scan is a full one.
+	ldb := db.NewLowlevel(backend.OpenMemory())
+	m := newModel(w, myID, "syncthing", "dev", ldb, nil)
+	m.ServeBackground()
+	m.ScanFolders()
+	m.Stop()
+	m.evLogger.Stop()
+
+	// Files that are neither recently changed nor in the sample are only
+	// found by the deferred full scan.
+	must(t, ioutil.WriteFile(filepath.Join(ffs.URI(), "c"), []byte("c"), 0644))
+
+	m = newModel(w, myID, "syncthing", "dev", ldb, nil)
+	defer cleanupModel(m)
+	m.ServeBackground()
+
+	m.fmut.RLock()
+	runner := m.folderRunners["default"].(*sendReceiveFolder)
+	m.fmut.RUnlock()
+	select {
+	case <-runner.initialScanFinished:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out before the initial scan finished")
+	}
+
+	if _, ok := m.CurrentFolderFile("default", "a"); !ok {
+		t.Error("a should be in the index")
+	}
+	if _, ok := m.CurrentFolderFile("default", "c"); ok {
+		t.Error("c should not be in the index before the full scan")
+	}
+
+	must(t, m.ScanFolder("default"))
+	if _, ok := m.CurrentFolderFile("default", "c"); !ok {
+		t.Error("c should be in the index after the full scan")
+	}
+}
+
+func TestIndexSenderSubtrees(t *testing.T) {
+	ldb := db.NewLowlevel(backend.OpenMemory())
+	defer ldb.Close()
+	fset := db.NewFileSet("default", fs.NewFilesystem(fs.FilesystemTypeFake, ""), ldb)
+	var files []protocol.FileInfo
+	for i, name := range []string{"photos", "photos/2019", "photos/2020", "photos/2020/a.jpg", "docs", "docs/b.txt"} {
+		files = append(files, protocol.FileInfo{Name: filepath.FromSlash(name), Type: protocol.FileInfoTypeDirectory, Version: protocol.Vector{}.Update(myID.Short()), Sequence: int64(i + 1)})
+	}
+	fset.Update(protocol.LocalDeviceID, files)
+
+	var sent []string
+	fc := &fakeConnection{id: device1}
+	fc.indexFn = func(_ context.Context, _ string, fs []protocol.FileInfo) {
+		for _, f := range fs {
+			sent = append(sent, filepath.ToSlash(f.Name))
+		}
+	}
+	s := &indexSender{conn: fc, folder: "default", fset: fset, subtrees: nativeSubtrees([]string{"photos/2020"})}
+	if err := s.sendIndexTo(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"photos", "photos/2020", "photos/2020/a.jpg"}
+	if strings.Join(sent, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected %v to be sent, got %v", expected, sent)
+	}
+	if s.prevSequence != int64(len(files)) {
+		t.Errorf("expected to have gone through all files, got sequence %d", s.prevSequence)
+	}
+}
+
+func TestIndexSenderSnapshot(t *testing.T) {
+	defer func(min int) { indexSnapshotMinFiles = min }(indexSnapshotMinFiles)
+	indexSnapshotMinFiles = 3
+
+	ldb := db.NewLowlevel(backend.OpenMemory())
+	defer ldb.Close()
+	fset := db.NewFileSet("default", fs.NewFilesystem(fs.FilesystemTypeFake, ""), ldb)
+	var files []protocol.FileInfo
+	for i, name := range []string{"a", "b", "c"} {
+		files = append(files, protocol.FileInfo{Name: name, Type: protocol.FileInfoTypeDirectory, Version: protocol.Vector{}.Update(myID.Short()), Sequence: int64(i + 1)})
+	}
+	fset.Update(protocol.LocalDeviceID, files)
+
+	sent := 0
+	fc := &fakeConnection{id: device1}
+	fc.indexFn = func(_ context.Context, _ string, fs []protocol.FileInfo) {
+		sent += len(fs)
+	}
+	s := &indexSender{conn: fc, folder: "default", fset: fset, snapshots: true}
+	s.batchFiles, s.batchBytes, s.snapshotBytes = indexBatchSizes(config.OptionsConfiguration{}, 0)
+	if err := s.sendIndexTo(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if fc.indexSnapshots != 1 || sent != 3 {
+		t.Errorf("expected all files in one snapshot, got %d files in %d snapshots", sent, fc.indexSnapshots)
+	}
+
+	// What changed after the snapshot is sent as an index update.
+	fset.Update(protocol.LocalDeviceID, []protocol.FileInfo{{Name: "d", Type: protocol.FileInfoTypeDirectory, Version: protocol.Vector{}.Update(myID.Short()), Sequence: 4}})
+	if err := s.sendIndexTo(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if fc.indexSnapshots != 1 || sent != 4 {
+		t.Errorf("expected the change as an update, got %d files in %d snapshots", sent, fc.indexSnapshots)
+	}
+}
+
+func TestIgnoreConditions(t *testing.T) {
+	m, _, fcfg := setupModelWithConnection()
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+
+	ffs := fcfg.Filesystem()
+	for name, size := range map[string]int{"big": 2048, "small": 10} {
+		fd, err := ffs.Create(name)
+		must(t, err)
+		_, err = fd.Write(make([]byte, size))
+		must(t, err)
+		must(t, fd.Close())
+	}
+	must(t, m.ScanFolder("default"))
+
+	// Files already in the index become ignored, as do new ones.
+	must(t, m.SetIgnores("default", []string{"(?size>1k)*"}))
+	fd, err := ffs.Create("bigger")
+	must(t, err)
+	_, err = fd.Write(make([]byte, 4096))
+	must(t, err)
+	must(t, fd.Close())
+	must(t, m.ScanFolder("default"))
+
+	for name, ignored := range map[string]bool{"big": true, "bigger": true, "small": false} {
+		f, ok := m.CurrentFolderFile("default", name)
+		if ignored && ok && !f.IsIgnored() {
+			t.Errorf("expected %s to be ignored", name)
+		} else if !ignored && (!ok || f.IsIgnored()) {
+			t.Errorf("expected %s not to be ignored", name)
+		}
+	}
+}
+
+func TestDataTransports(t *testing.T) {
+	w, fcfg := tmpDefaultWrapper()
+	fcfg.DataTransports = []string{connections.TransportLAN}
+	waiter, err := w.SetFolder(fcfg)
+	must(t, err)
+	waiter.Wait()
+	m, fc := setupModelWithConnectionFromWrapper(w)
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+
+	file := protocol.FileInfo{
+		Name:    "file",
+		Size:    1,
+		Version: protocol.Vector{}.Update(device1.Short()),
+		Blocks:  []protocol.BlockInfo{{Size: 1}},
+	}
+	must(t, m.Index(device1, "default", []protocol.FileInfo{file}))
+
+	// File data isn't requested over the connection, as it isn't to the
+	// LAN, while the index was still received.
+	if avail := m.Availability("default", file, file.Blocks[0]); len(avail) != 0 {
+		t.Errorf("expected no availability over a WAN connection, got %v", avail)
+	}
+	if _, err := m.requestGlobal(context.Background(), device1, "default", "file", 0, 1, nil, 0, false); err != connections.ErrNoAllowedConnection {
+		t.Error("expected the request to be refused, got", err)
+	}
+
+	fc.local = true
+	if avail := m.Availability("default", file, file.Blocks[0]); len(avail) != 1 || avail[0].ID != device1 {
+		t.Errorf("expected availability over a LAN connection, got %v", avail)
+	}
+}
+
+func TestFolderDisk(t *testing.T) {
+	w, fcfg := tmpDefaultWrapper()
+	fcfg.Disk = "testdisk"
+	fcfg.DiskHashers = 1
+	waiter, err := w.SetFolder(fcfg)
+	must(t, err)
+	waiter.Wait()
+	m := setupModel(w)
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+
+	if disk := m.hashingDisk("default"); disk != "testdisk" {
+		t.Errorf("expected the configured disk, got %q", disk)
+	}
+	if disk := m.hashingDisk("missing"); disk != "" {
+		t.Errorf("expected no disk for a missing folder, got %q", disk)
+	}
+}
+
+func TestScanJournalResume(t *testing.T) {
+	w, fcfg := tmpDefaultWrapper()
+	m := setupModel(w)
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+
+	ffs := fcfg.Filesystem()
+	for _, dir := range []string{"a", "b"} {
+		must(t, ffs.Mkdir(dir, 0755))
+		fd, err := ffs.Create(filepath.Join(dir, "file"))
+		must(t, err)
+		fd.Close()
+	}
+
+	// An interrupted scan left b completed, so resuming it doesn't walk b.
+
+	m.fmut.RLock()
+	hash := m.folderIgnores["default"].Hash()
+	m.fmut.RUnlock()
+	journal := db.NewScanJournal(m.db, "default")
+	if _, err := journal.Start(hash); err != nil {
+		t.Fatal(err)
+	}
+	must(t, journal.Complete([]string{"b"}))
+
+	must(t, m.ScanFolder("default"))
+	if _, ok := m.CurrentFolderFile("default", filepath.Join("a", "file")); !ok {
+		t.Error("expected a/file to be scanned")
+	}
+	if _, ok := m.CurrentFolderFile("default", filepath.Join("b", "file")); ok {
+		t.Error("expected b/file to be skipped")
+	}
+
+	// The finished scan removed the journal, so the next one is complete.
+
+	must(t, m.ScanFolder("default"))
+	if _, ok := m.CurrentFolderFile("default", filepath.Join("b", "file")); !ok {
+		t.Error("expected b/file to be scanned")
+	}
+}
+
+func TestIndexDivergence(t *testing.T) {
+	w := createTmpWrapper(defaultCfg)
+	m := setupModel(w)
+	defer cleanupModel(m)
+
+	m.fmut.RLock()
+	fset := m.folderFiles["default"]
+	m.fmut.RUnlock()
+	addFakeConn(m, device1)
+	must(t, m.ScanFolder("default"))
+	var files []protocol.FileInfo
+	fset.WithHave(protocol.LocalDeviceID, func(f db.FileIntf) bool {
+		files = append(files, f.(protocol.FileInfo))
+		return true
+	})
+	fset.Update(device1, files)
+
+	if cm := m.generateClusterConfig(device1); cm.Folders[0].IndexSummary == nil {
+		t.Fatal("expected an index summary to be announced")
+	}
+
+	sendSummary := func() {
+		summary := fset.IndexSummary(device1)
+		m.ClusterConfig(device1, protocol.ClusterConfig{
+			Folders: []protocol.Folder{{ID: "default", IndexSummary: &summary}},
+		})
+	}
+	divergence := func() IndexDivergence {
+		t.Helper()
+		div, err := m.IndexDivergence("default", device1)
+		must(t, err)
+		return div
+	}
+
+	if div := divergence(); div.Known {
+		t.Error("expected nothing to be known before the device sent a summary")
+	}
+
+	sendSummary()
+	if div := divergence(); !div.Known || !div.InSync || !div.Current || len(div.Paths) != 0 {
+		t.Errorf("expected the indexes to be in sync, got %+v", div)
+	}
+	if !m.indexSummaries.inSync(fset, "default", device1) {
+		t.Error("expected the need of the device to be taken from ours")
+	}
+
+	// Once the device changed a file, our copy of its index is outdated
+	// until it tells us where they diverge.
+
+	file, _ := fset.Get(device1, filepath.Join("baz", "quux"))
+	file.Version = file.Version.Update(device1.Short())
+	file.Sequence = fset.Sequence(device1) + 1
+	fset.Update(device1, []protocol.FileInfo{file})
+	if div := divergence(); !div.InSync || div.Current {
+		t.Errorf("expected our copy of the index to be outdated, got %+v", div)
+	}
+	sendSummary()
+	if div := divergence(); div.InSync || !div.Current || !reflect.DeepEqual(div.Paths, []string{"baz"}) {
+		t.Errorf("expected the indexes to diverge in baz, got %+v", div)
+	}
+	if m.indexSummaries.inSync(fset, "default", device1) {
+		t.Error("expected the need of the device to be calculated")
+	}
+}
+
+func TestStabilityWindow(t *testing.T) {
+	w, fcfg := tmpDefaultWrapper()
+	fcfg.StabilityWindowS = 60
+	w.SetFolder(fcfg)
+	ffs := fcfg.Filesystem()
+	defer os.RemoveAll(ffs.URI())
+	defer os.Remove(w.ConfigPath())
+
+	must(t, ioutil.WriteFile(filepath.Join(ffs.URI(), "writing"), []byte("partial"), 0644))
+
+	m := setupModel(w)
+	defer cleanupModel(m)
+
+	// The file that was just modified is left out, to be scanned once it
+	// has been left alone for the stability window.
+	must(t, m.ScanFolder("default"))
+	if _, ok := m.CurrentFolderFile("default", "writing"); ok {
+		t.Error("the file being written should not be in the index")
+	}
+	m.fmut.RLock()
+	runner := m.folderRunners["default"].(*sendReceiveFolder)
+	m.fmut.RUnlock()
+	runner.unstableMut.Lock()
+	_, ok := runner.unstable["writing"]
+	scheduled := runner.unstableTimer != nil
+	runner.unstableMut.Unlock()
+	if !ok || !scheduled {
+		t.Fatal("expected a scan of the file being written to be scheduled")
+	}
+
+	old := time.Now().Add(-time.Minute)
+	must(t, os.Chtimes(filepath.Join(ffs.URI(), "writing"), old, old))
+	runner.stopUnstableTimer()
+	runner.scanUnstable()
+	if _, ok := m.CurrentFolderFile("default", "writing"); !ok {
+		t.Error("the file should be in the index once it has been left alone")
+	}
+}
+
+// TestScanCaseOnlyRename checks that renaming a file to another case of its
+// name on a case insensitive filesystem is scanned as a deletion of the old
+// name, although that is still found.
+func TestScanCaseOnlyRename(t *testing.T) {
+	wrapper := createTmpWrapper(defaultCfg.Copy())
+	folderCfg, _ := wrapper.Folder("default")
+	folderCfg.FilesystemType = fs.FilesystemTypeFake
+	folderCfg.Path = "/TestScanCaseOnlyRename?insens=true"
+	wrapper.SetFolder(folderCfg)
+	ffs := fs.NewFilesystem(fs.FilesystemTypeFake, folderCfg.Path)
+
+	m := setupModel(wrapper)
+	defer cleanupModel(m)
+
+	fd, err := ffs.Create("Foo.txt")
+	must(t, err)
+	fd.Close()
+	must(t, m.ScanFolder("default"))
+	if _, ok := m.CurrentFolderFile("default", "Foo.txt"); !ok {
+		t.Fatal("Foo.txt missing in db")
+	}
+
+	must(t, ffs.Rename("Foo.txt", "foo.txt"))
+	must(t, m.ScanFolder("default"))
+	if fi, ok := m.CurrentFolderFile("default", "Foo.txt"); !ok || !fi.IsDeleted() {
+		t.Error("Expected Foo.txt to be deleted")
+	}
+	if fi, ok := m.CurrentFolderFile("default", "foo.txt"); !ok || fi.IsDeleted() {
+		t.Error("Expected foo.txt to exist")
+	}
+}
+
+func TestIndexBatchSizes(t *testing.T) {
+	cases := []struct {
+		opts                        config.OptionsConfiguration
+		window                      int64
+		files, bytes, snapshotBytes int
+	}{
+		{config.OptionsConfiguration{}, 0, maxBatchSizeFiles, maxBatchSizeBytes, protocol.MaxIndexSnapshotSize},
+		{config.OptionsConfiguration{IndexBatchFiles: 10, IndexBatchKiB: 64}, 0, 10, 64 << 10, protocol.MaxIndexSnapshotSize},
+		{config.OptionsConfiguration{IndexBatchKiB: 64}, 32 << 10, maxBatchSizeFiles, 32 << 10, 32 << 10},
+		{config.OptionsConfiguration{}, 1 << 30, maxBatchSizeFiles, maxBatchSizeBytes, protocol.MaxIndexSnapshotSize},
+	}
+	for i, tc := range cases {
+		files, bytes, snapshotBytes := indexBatchSizes(tc.opts, tc.window)
+		if files != tc.files || bytes != tc.bytes || snapshotBytes != tc.snapshotBytes {
+			t.Errorf("case %d: got %d files, %d bytes, %d snapshot bytes; expected %d, %d, %d", i, files, bytes, snapshotBytes, tc.files, tc.bytes, tc.snapshotBytes)
+		}
+	}
+}
+
+func TestPrioritizeNotNeeded(t *testing.T) {
+	m, _, fcfg := setupModelWithConnection()
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+
+	if err := m.Prioritize("default", "nonexistent", true); err != errFileNotNeeded {
+		t.Errorf("expected %v, got %v", errFileNotNeeded, err)
+	}
+	if err := m.Prioritize("nonexistent", "file", true); err != errFolderMissing {
+		t.Errorf("expected %v, got %v", errFolderMissing, err)
+	}
+}
@@ -267,6 +267,19 @@ func (t *ProgressEmitter) BytesCompleted(folder string) (bytes int64) {
 	return
 }
 
+// pullerProgress returns the progress of the files currently being pulled
+// in the given folder.
+func (t *ProgressEmitter) pullerProgress(folder string) map[string]*pullerProgress {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	progress := make(map[string]*pullerProgress, len(t.registry[folder]))
+	for name, s := range t.registry[folder] {
+		progress[name] = s.Progress()
+	}
+	return progress
+}
+
 func (t *ProgressEmitter) String() string {
 	return fmt.Sprintf("ProgressEmitter@%p", t)
 }
@@ -17,6 +17,8 @@ import (
 type jobQueue struct {
 	progress []string
 	queued   []jobQueueEntry
+	urgent   map[string]struct{}
+	front    map[string]struct{} // brought to the front by the user
 	mut      sync.Mutex
 }
 
@@ -26,9 +28,20 @@ type jobQueueEntry struct {
 	modified time.Time
 }
 
+// A QueuedFile is a file waiting in the job queue.
+type QueuedFile struct {
+	Name     string    `json:"name"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+	Front    bool      `json:"front"`  // brought to the front by the user
+	Urgent   bool      `json:"urgent"` // not subject to the pending request limit
+}
+
 func newJobQueue() *jobQueue {
 	return &jobQueue{
-		mut: sync.NewMutex(),
+		urgent: make(map[string]struct{}),
+		front:  make(map[string]struct{}),
+		mut:    sync.NewMutex(),
 	}
 }
 
@@ -59,6 +72,7 @@ func (q *jobQueue) BringToFront(filename string) {
 
 	for i, cur := range q.queued {
 		if cur.name == filename {
+			q.front[filename] = struct{}{}
 			if i > 0 {
 				// Shift the elements before the selected element one step to
 				// the right, overwriting the selected element
@@ -71,10 +85,52 @@ func (q *jobQueue) BringToFront(filename string) {
 	}
 }
 
+// SetUrgent marks the given file as urgent until it is done, if it is queued
+// or being pulled. Block requests for urgent files are not subject to the
+// puller's pending request limit.
+func (q *jobQueue) SetUrgent(filename string) {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+
+	for _, cur := range q.progress {
+		if cur == filename {
+			q.urgent[filename] = struct{}{}
+			return
+		}
+	}
+	for _, cur := range q.queued {
+		if cur.name == filename {
+			q.urgent[filename] = struct{}{}
+			return
+		}
+	}
+}
+
+func (q *jobQueue) IsUrgent(filename string) bool {
+	q.mut.Lock()
+	_, ok := q.urgent[filename]
+	q.mut.Unlock()
+	return ok
+}
+
+// IsInteractive returns true if the user is waiting for the given file,
+// having brought it to the front or marked it urgent. Its blocks are
+// requested with interactive priority.
+func (q *jobQueue) IsInteractive(filename string) bool {
+	q.mut.Lock()
+	_, front := q.front[filename]
+	_, urgent := q.urgent[filename]
+	q.mut.Unlock()
+	return front || urgent
+}
+
 func (q *jobQueue) Done(file string) {
 	q.mut.Lock()
 	defer q.mut.Unlock()
 
+	delete(q.urgent, file)
+	delete(q.front, file)
+
 	for i := range q.progress {
 		if q.progress[i] == file {
 			copy(q.progress[i:], q.progress[i+1:])
@@ -126,6 +182,31 @@ func (q *jobQueue) Jobs(page, perpage int) ([]string, []string, int) {
 	return progress, queued, (page - 1) * perpage
 }
 
+// queuedFiles returns the first files of the queue, up to limit, and how
+// many are queued in total.
+func (q *jobQueue) queuedFiles(limit int) ([]QueuedFile, int) {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+
+	n := len(q.queued)
+	if n > limit {
+		n = limit
+	}
+	files := make([]QueuedFile, n)
+	for i, e := range q.queued[:n] {
+		_, front := q.front[e.name]
+		_, urgent := q.urgent[e.name]
+		files[i] = QueuedFile{
+			Name:     e.name,
+			Size:     e.size,
+			Modified: e.modified,
+			Front:    front,
+			Urgent:   urgent,
+		}
+	}
+	return files, len(q.queued)
+}
+
 func (q *jobQueue) Shuffle() {
 	q.mut.Lock()
 	defer q.mut.Unlock()
@@ -138,6 +219,8 @@ func (q *jobQueue) Reset() {
 	defer q.mut.Unlock()
 	q.progress = nil
 	q.queued = nil
+	q.urgent = make(map[string]struct{})
+	q.front = make(map[string]struct{})
 }
 
 func (q *jobQueue) lenQueued() int {
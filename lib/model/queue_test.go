@@ -161,6 +161,34 @@ func TestBringToFront(t *testing.T) {
 	}
 }
 
+func TestUrgent(t *testing.T) {
+	q := newJobQueue()
+	q.Push("f1", 0, time.Time{})
+	q.Push("f2", 0, time.Time{})
+
+	if q.IsUrgent("f2") {
+		t.Fatal("f2 should not be urgent yet")
+	}
+
+	q.SetUrgent("f2")
+	if !q.IsUrgent("f2") {
+		t.Fatal("f2 should be urgent")
+	}
+	if q.IsUrgent("f1") {
+		t.Fatal("f1 should not be urgent")
+	}
+
+	q.SetUrgent("f3")
+	if q.IsUrgent("f3") {
+		t.Fatal("f3 should not be urgent, as it isn't queued")
+	}
+
+	q.Done("f2")
+	if q.IsUrgent("f2") {
+		t.Fatal("f2 should no longer be urgent when done")
+	}
+}
+
 func TestShuffle(t *testing.T) {
 	q := newJobQueue()
 	q.Push("f1", 0, time.Time{})
@@ -413,3 +441,30 @@ func equalStrings(first, second []string) bool {
 	}
 	return true
 }
+
+func TestQueuedFiles(t *testing.T) {
+	q := newJobQueue()
+	q.Push("f1", 1, time.Time{})
+	q.Push("f2", 2, time.Time{})
+	q.Push("f3", 3, time.Time{})
+	q.BringToFront("f2")
+	q.SetUrgent("f3")
+
+	files, total := q.queuedFiles(2)
+	if total != 3 {
+		t.Errorf("expected 3 queued in total, got %d", total)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files, got %d", len(files))
+	}
+	if files[0].Name != "f2" || !files[0].Front || files[0].Urgent || files[0].Size != 2 {
+		t.Errorf("unexpected first file %+v", files[0])
+	}
+	if files[1].Name != "f1" || files[1].Front || files[1].Urgent {
+		t.Errorf("unexpected second file %+v", files[1])
+	}
+
+	if files, _ := q.queuedFiles(10); len(files) != 3 || !files[2].Urgent {
+		t.Errorf("unexpected files %+v", files)
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+// When the database is lost while the files of a folder remain, the
+// rescan gives every file a version of our own, concurrent with the ones
+// the other devices have. Left alone, every file then has to be sorted
+// out by the puller, with the metadata of one side or the other rewritten
+// everywhere. While a folder is recovering, files that are the same as
+// what another device has take on that device's version instead, before
+// pulling, so that only what really differs is synced. Recovery is over
+// once every device has sent the index it announced.
+
+type folderRecovery struct {
+	announced  map[protocol.DeviceID]int64 // the sequence each device says it's at
+	reconciled map[protocol.DeviceID]int64 // the sequence we've reconciled up to
+	matched    int
+	done       bool
+	mut        sync.Mutex
+}
+
+func newFolderRecovery() *folderRecovery {
+	return &folderRecovery{
+		announced:  make(map[protocol.DeviceID]int64),
+		reconciled: make(map[protocol.DeviceID]int64),
+		mut:        sync.NewMutex(),
+	}
+}
+
+func (r *folderRecovery) announce(device protocol.DeviceID, sequence int64) {
+	r.mut.Lock()
+	r.announced[device] = sequence
+	r.mut.Unlock()
+}
+
+func recoveryKey(folder string) string {
+	return "folderRecovery-" + folder
+}
+
+// startRecoveryLocked puts the folder in recovery if the database has
+// nothing on a folder we've synced before, or it was recovering when we
+// last stopped. Must be called with fmut held, before the marker is
+// created.
+func (m *model) startRecoveryLocked(cfg config.FolderConfiguration, indexHasFiles bool) {
+	misc := db.NewMiscDataNamespace(m.db)
+	recovering, _, _ := misc.Bool(recoveryKey(cfg.ID))
+	if !recovering && !indexHasFiles && cfg.CheckPath() == nil {
+		// The marker is only there if the folder has been synced before.
+		l.Infof("Folder %v has no database entries, but has been synced before; files that are the same on other devices will be matched up with them", cfg.Description())
+		recovering = true
+		if err := misc.PutBool(recoveryKey(cfg.ID), true); err != nil {
+			l.Warnln("Recording folder recovery:", err)
+		}
+	}
+	if recovering {
+		m.folderRecoveries[cfg.ID] = newFolderRecovery()
+	} else {
+		delete(m.folderRecoveries, cfg.ID)
+	}
+}
+
+// reconcileRecovery matches up local files with what the other devices
+// have, if the folder is recovering. It runs before pulling.
+func (f *folder) reconcileRecovery() {
+	f.model.fmut.RLock()
+	r := f.model.folderRecoveries[f.ID]
+	f.model.fmut.RUnlock()
+	if r == nil {
+		return
+	}
+
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	if r.done {
+		return
+	}
+
+	complete := true
+	for _, device := range f.DeviceIDs() {
+		if device == f.model.id {
+			continue
+		}
+		sequence := f.fset.Sequence(device)
+		if sequence > r.reconciled[device] {
+			n, err := f.reconcileWith(device)
+			if err != nil {
+				l.Infof("Recovering folder %v: %v", f.Description(), err)
+				return
+			}
+			r.matched += n
+			r.reconciled[device] = sequence
+		}
+		if announced, ok := r.announced[device]; !ok || sequence < announced {
+			complete = false
+		}
+	}
+	if !complete {
+		return
+	}
+
+	r.done = true
+	if err := db.NewMiscDataNamespace(f.model.db).Delete(recoveryKey(f.ID)); err != nil {
+		l.Warnln("Recording folder recovery:", err)
+	}
+	l.Infof("Recovery of folder %v complete; %d items matched up with other devices", f.Description(), r.matched)
+}
+
+// reconcileWith gives local files the version the device has of them,
+// where they are the same, and returns how many it changed.
+func (f *folder) reconcileWith(device protocol.DeviceID) (int, error) {
+	matched := 0
+	batch := newFileInfoBatch(func(fs []protocol.FileInfo) error {
+		if err := f.CheckHealth(); err != nil {
+			return err
+		}
+		f.updateLocals(fs)
+		matched += len(fs)
+		return nil
+	})
+
+	var err error
+	f.fset.WithHave(device, func(fi db.FileIntf) bool {
+		remote := fi.(protocol.FileInfo)
+		local, ok := f.fset.Get(protocol.LocalDeviceID, remote.Name)
+		if !ok || !recoveryMatch(local, remote) {
+			return true
+		}
+		l.Debugln(f, "recovery: matched", remote.Name, "with", device)
+		local.Version = remote.Version
+		batch.append(local)
+		err = batch.flushIfFull()
+		return err == nil
+	})
+	if err != nil {
+		return matched, err
+	}
+	return matched, batch.flush()
+}
+
+// recoveryMatch returns true if the local file has the same contents as
+// the remote one and would otherwise be synced.
+func recoveryMatch(local, remote protocol.FileInfo) bool {
+	switch {
+	case local.IsDeleted() || local.IsInvalid() || local.IsPlaceholder():
+		return false
+	case remote.IsDeleted() || remote.IsInvalid():
+		return false
+	case local.Type != remote.Type:
+		return false
+	case local.Version.GreaterEqual(remote.Version):
+		// Either the same already, or ours wins anyway.
+		return false
+	case local.IsDirectory():
+		return true
+	case local.IsSymlink():
+		return local.SymlinkTarget == remote.SymlinkTarget
+	default:
+		return local.Size == remote.Size && protocol.BlocksEqual(local.Blocks, remote.Blocks)
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"os"
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+func TestRecoveryAfterDatabaseLoss(t *testing.T) {
+	dir := createTmpDir()
+	defer os.RemoveAll(dir)
+
+	// A folder that has been synced before, but that the database doesn't
+	// know anything about.
+	fcfg := testFolderConfig(dir)
+	must(t, fcfg.CreateMarker())
+	ffs := fcfg.Filesystem()
+	for _, name := range []string{"same", "differs"} {
+		fd, err := ffs.Create(name)
+		must(t, err)
+		_, err = fd.Write([]byte("local contents of " + name))
+		must(t, err)
+		must(t, fd.Close())
+	}
+
+	m := setupModel(createTmpWrapper(config.Configuration{Folders: []config.FolderConfiguration{fcfg}}))
+	defer cleanupModel(m)
+
+	m.fmut.RLock()
+	r := m.folderRecoveries[fcfg.ID]
+	fset := m.folderFiles[fcfg.ID]
+	runner := m.folderRunners[fcfg.ID].(*sendReceiveFolder)
+	m.fmut.RUnlock()
+	if r == nil {
+		t.Fatal("expected the folder to be recovering")
+	}
+	if recovering, _, _ := db.NewMiscDataNamespace(m.db).Bool(recoveryKey(fcfg.ID)); !recovering {
+		t.Error("expected recovery to be recorded in the database")
+	}
+
+	// The other device has the same contents for one file, and different
+	// ones for the other, both with its own versions.
+	remoteVersion := protocol.Vector{}.Update(device1.Short()).Update(device1.Short())
+	var remote []protocol.FileInfo
+	for i, name := range []string{"same", "differs"} {
+		fi, ok := fset.Get(protocol.LocalDeviceID, name)
+		if !ok {
+			t.Fatal("missing", name)
+		}
+		fi.Version = remoteVersion
+		fi.Sequence = int64(i + 1)
+		fi.LocalFlags = 0
+		if name == "differs" {
+			fi.Blocks = []protocol.BlockInfo{{Size: int32(fi.Size), Hash: make([]byte, 32)}}
+		}
+		remote = append(remote, fi)
+	}
+	fset.Update(device1, remote)
+
+	runner.reconcileRecovery()
+
+	if fi, _ := fset.Get(protocol.LocalDeviceID, "same"); !fi.Version.Equal(remoteVersion) {
+		t.Errorf("expected the identical file to take the remote version, got %v", fi.Version)
+	}
+	if fi, _ := fset.Get(protocol.LocalDeviceID, "differs"); !fi.Version.Concurrent(remoteVersion) {
+		t.Errorf("expected the differing file to keep its own version, got %v", fi.Version)
+	}
+	if r.done {
+		t.Error("recovery shouldn't be done before the device announced its index")
+	}
+
+	// Once the device's index is known to be complete, so is recovery.
+	r.announce(device1, 2)
+	runner.reconcileRecovery()
+	if !r.done {
+		t.Error("expected recovery to be done")
+	}
+	if _, ok, _ := db.NewMiscDataNamespace(m.db).Bool(recoveryKey(fcfg.ID)); ok {
+		t.Error("expected recovery to be cleared from the database")
+	}
+}
+
+func TestNoRecoveryForNewFolder(t *testing.T) {
+	w, fcfg := tmpDefaultWrapper()
+	m := setupModel(w)
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+
+	m.fmut.RLock()
+	r := m.folderRecoveries[fcfg.ID]
+	m.fmut.RUnlock()
+	if r != nil {
+		t.Error("a new folder shouldn't be recovering")
+	}
+}
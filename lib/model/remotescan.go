@@ -0,0 +1,135 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"context"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+// ScanRequest rescans a folder on behalf of the device, if it is allowed to
+// ask for that. Implements the protocol.Model interface.
+func (m *model) ScanRequest(device protocol.DeviceID, folder string, subdirs []string) error {
+	if devCfg, ok := m.cfg.Device(device); !ok || !devCfg.AllowRemoteScan {
+		l.Infof("Ignoring request from %s to scan folder %q: not allowed", device, folder)
+		return nil
+	}
+	if cfg, ok := m.cfg.Folder(folder); !ok || !cfg.SharedWith(device) {
+		l.Infof("Ignoring request from %s to scan folder %q: not shared", device, folder)
+		return nil
+	}
+
+	l.Debugf("%v scan request from %s for %s %v", m, device, folder, subdirs)
+	if m.remoteScans.add(folder, subdirs) {
+		go m.serveRemoteScans(folder)
+	}
+	return nil
+}
+
+// serveRemoteScans runs the scans queued for the folder until there are no
+// more.
+func (m *model) serveRemoteScans(folder string) {
+	for {
+		subdirs, ok := m.remoteScans.next(folder)
+		if !ok {
+			return
+		}
+		if err := m.ScanFolderSubdirs(folder, subdirs); err != nil {
+			l.Infof("Scanning folder %q on request: %v", folder, err)
+		}
+	}
+}
+
+// RequestRemoteScan asks the connected device to rescan the given
+// subdirectories of a folder, or all of it if there are none.
+func (m *model) RequestRemoteScan(device protocol.DeviceID, folder string, subdirs []string) error {
+	cfg, ok := m.cfg.Folder(folder)
+	if !ok {
+		return errFolderMissing
+	}
+	if !cfg.SharedWith(device) {
+		return errFolderNotShared
+	}
+
+	m.pmut.RLock()
+	conn, ok := m.conn[device]
+	hello := m.helloMessages[device]
+	m.pmut.RUnlock()
+
+	if !ok {
+		return errDeviceNotConnected
+	}
+	if !hello.SupportsScanRequests {
+		return errNoScanRequests
+	}
+	return conn.ScanRequest(context.TODO(), folder, subdirs)
+}
+
+// remoteScanQueue coalesces the scan requests for each folder, so that a
+// device asking over and over results in at most one running and one
+// pending scan.
+type remoteScanQueue struct {
+	pending map[string]*remoteScan // folder -> what to scan next
+	running map[string]bool
+	mut     sync.Mutex
+}
+
+type remoteScan struct {
+	all     bool
+	subdirs []string
+}
+
+func newRemoteScanQueue() *remoteScanQueue {
+	return &remoteScanQueue{
+		pending: make(map[string]*remoteScan),
+		running: make(map[string]bool),
+		mut:     sync.NewMutex(),
+	}
+}
+
+// add queues a scan of the subdirectories, or of the whole folder if there
+// are none. It returns true when the caller should start serving the
+// folder's scans.
+func (q *remoteScanQueue) add(folder string, subdirs []string) bool {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+
+	scan, ok := q.pending[folder]
+	if !ok {
+		scan = new(remoteScan)
+		q.pending[folder] = scan
+	}
+	if len(subdirs) == 0 {
+		scan.all = true
+		scan.subdirs = nil
+	} else if !scan.all {
+		scan.subdirs = append(scan.subdirs, subdirs...)
+	}
+
+	if q.running[folder] {
+		return false
+	}
+	q.running[folder] = true
+	return true
+}
+
+// next returns what to scan next in the folder, or false when there is
+// nothing left, in which case the caller must stop serving it.
+func (q *remoteScanQueue) next(folder string) ([]string, bool) {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+
+	scan, ok := q.pending[folder]
+	if !ok {
+		delete(q.running, folder)
+		return nil, false
+	}
+	delete(q.pending, folder)
+	return scan.subdirs, true
+}
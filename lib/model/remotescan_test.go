@@ -0,0 +1,45 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRemoteScanQueue(t *testing.T) {
+	q := newRemoteScanQueue()
+
+	if !q.add("default", []string{"a"}) {
+		t.Fatal("the first request should start serving")
+	}
+	if q.add("default", []string{"b"}) {
+		t.Error("a request while serving shouldn't start serving again")
+	}
+	if !q.add("other", nil) {
+		t.Error("another folder should be served separately")
+	}
+
+	if subs, ok := q.next("default"); !ok || !reflect.DeepEqual(subs, []string{"a", "b"}) {
+		t.Errorf("expected the subdirs to be merged, got %v, %v", subs, ok)
+	}
+
+	// A request for the whole folder supersedes any subdirs.
+	q.add("default", []string{"c"})
+	q.add("default", nil)
+	q.add("default", []string{"d"})
+	if subs, ok := q.next("default"); !ok || subs != nil {
+		t.Errorf("expected a scan of the whole folder, got %v, %v", subs, ok)
+	}
+
+	if _, ok := q.next("default"); ok {
+		t.Error("expected nothing left to scan")
+	}
+	if !q.add("default", []string{"e"}) {
+		t.Error("a request after serving stopped should start serving again")
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"context"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+const (
+	// Files up to this size are requested in batches from devices that
+	// support it.
+	maxBatchedFileSize = 32 << 10
+
+	// A batch is sent when it reaches either limit, or when no more
+	// requests have arrived within batchDelay of the first one.
+	maxBatchFiles = 256
+	maxBatchBytes = 4 << 20
+	batchDelay    = 5 * time.Millisecond
+)
+
+// The requestBatcher collects requests for small files to a device and
+// sends them as batch requests, to avoid paying a round trip per file when
+// syncing many small files over a high latency link.
+type requestBatcher struct {
+	conn     protocol.Connection
+	requests chan *batchedRequest
+	stop     <-chan struct{}
+}
+
+type batchedRequest struct {
+	folder string
+	file   protocol.BatchRequestFile
+	data   []byte
+	err    error
+	done   chan struct{}
+}
+
+func newRequestBatcher(conn protocol.Connection, stop <-chan struct{}) *requestBatcher {
+	b := &requestBatcher{
+		conn:     conn,
+		requests: make(chan *batchedRequest),
+		stop:     stop,
+	}
+	go b.serve()
+	return b
+}
+
+// Request returns the contents of the given file, which must consist of a
+// single block, once the batch it was added to has been answered.
+func (b *requestBatcher) Request(ctx context.Context, folder, name string, size int, hash []byte, weakHash uint32) ([]byte, error) {
+	req := &batchedRequest{
+		folder: folder,
+		file: protocol.BatchRequestFile{
+			Name:     name,
+			Size:     int32(size),
+			Hash:     hash,
+			WeakHash: weakHash,
+		},
+		done: make(chan struct{}),
+	}
+
+	select {
+	case b.requests <- req:
+	case <-b.stop:
+		return nil, protocol.ErrClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	select {
+	case <-req.done:
+		return req.data, req.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (b *requestBatcher) serve() {
+	for {
+		var req *batchedRequest
+		select {
+		case req = <-b.requests:
+		case <-b.stop:
+			return
+		}
+
+		batch := []*batchedRequest{req}
+		bytes := int(req.file.Size)
+		timer := time.NewTimer(batchDelay)
+
+	collect:
+		for len(batch) < maxBatchFiles && bytes < maxBatchBytes {
+			select {
+			case req = <-b.requests:
+				batch = append(batch, req)
+				bytes += int(req.file.Size)
+			case <-timer.C:
+				break collect
+			case <-b.stop:
+				timer.Stop()
+				for _, req := range batch {
+					req.err = protocol.ErrClosed
+					close(req.done)
+				}
+				return
+			}
+		}
+		timer.Stop()
+
+		go b.send(batch)
+	}
+}
+
+// send sends one batch request per folder in the batch and hands the
+// results to the waiting requesters.
+func (b *requestBatcher) send(batch []*batchedRequest) {
+	var folders []string
+	byFolder := make(map[string][]*batchedRequest)
+	for _, req := range batch {
+		if _, ok := byFolder[req.folder]; !ok {
+			folders = append(folders, req.folder)
+		}
+		byFolder[req.folder] = append(byFolder[req.folder], req)
+	}
+
+	for _, folder := range folders {
+		reqs := byFolder[folder]
+		files := make([]protocol.BatchRequestFile, len(reqs))
+		for i, req := range reqs {
+			files[i] = req.file
+		}
+
+		l.Debugf("REQ(out, batch): %s: %q / %d files", b.conn.ID(), folder, len(files))
+		datas, errs, err := b.conn.BatchRequest(context.Background(), folder, files)
+		for i, req := range reqs {
+			if err != nil {
+				req.err = err
+			} else {
+				req.data, req.err = datas[i], errs[i]
+			}
+			close(req.done)
+		}
+	}
+}
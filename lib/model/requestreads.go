@@ -0,0 +1,55 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"context"
+
+	"golang.org/x/time/rate"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// A requestReadLimiter limits the disk reads serving requests from other
+// devices, in how many at a time and how fast, so that a slow disk stays
+// usable locally while other devices pull from it. This is apart from the
+// bandwidth limits, which apply to the network.
+type requestReadLimiter struct {
+	reads *byteSemaphore // zero capacity for no limit
+	rate  *rate.Limiter
+}
+
+func newRequestReadLimiter(opts config.OptionsConfiguration) *requestReadLimiter {
+	r := &requestReadLimiter{
+		reads: newByteSemaphore(0),
+		rate:  rate.NewLimiter(rate.Inf, protocol.MaxBlockSize),
+	}
+	r.setOptions(opts)
+	return r
+}
+
+func (r *requestReadLimiter) setOptions(opts config.OptionsConfiguration) {
+	r.reads.setCapacity(opts.MaxRequestReads)
+	if opts.MaxRequestReadKiBs > 0 {
+		r.rate.SetLimit(rate.Limit(opts.MaxRequestReadKiBs * 1024))
+	} else {
+		r.rate.SetLimit(rate.Inf)
+	}
+}
+
+// read is readOffsetIntoBuf within the limits.
+func (r *requestReadLimiter) read(ffs fs.Filesystem, file string, offset int64, buf []byte) error {
+	// Waiting for the rate first keeps the reads free for those that may
+	// go ahead. Blocks are never larger than the burst, so this doesn't
+	// fail.
+	_ = r.rate.WaitN(context.Background(), len(buf))
+	r.reads.take(1)
+	defer r.reads.give(1)
+	return readOffsetIntoBuf(ffs, file, offset, buf)
+}
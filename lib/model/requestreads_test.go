@@ -0,0 +1,68 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+func TestRequestReadLimiter(t *testing.T) {
+	ffs := fs.NewFilesystem(fs.FilesystemTypeFake, "requestreads")
+	fd, err := ffs.Create("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd.Truncate(64 << 10)
+	fd.Close()
+	data := make([]byte, 64<<10)
+	if err := readOffsetIntoBuf(ffs, "file", 0, data); err != nil {
+		t.Fatal(err)
+	}
+
+	r := newRequestReadLimiter(config.OptionsConfiguration{})
+	if r.rate.Limit() != rate.Inf || r.reads.max != 0 {
+		t.Fatal("expected no limits by default")
+	}
+
+	r.setOptions(config.OptionsConfiguration{MaxRequestReads: 2, MaxRequestReadKiBs: 1024})
+	if r.reads.max != 2 {
+		t.Errorf("expected 2 concurrent reads, got %d", r.reads.max)
+	}
+	if r.rate.Limit() != 1024*1024 {
+		t.Errorf("expected 1 MiB/s, got %v", r.rate.Limit())
+	}
+
+	// Use up the burst so that the read has to wait for the rate.
+	r.rate.AllowN(time.Now(), protocol.MaxBlockSize)
+	buf := make([]byte, len(data))
+	t0 := time.Now()
+	if err := r.read(ffs, "file", 0, buf); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(t0); d < 40*time.Millisecond {
+		t.Errorf("reading 64 KiB at 1 MiB/s took only %v", d)
+	}
+	if !bytes.Equal(buf, data) {
+		t.Error("read the wrong data")
+	}
+	if r.reads.available != 2 {
+		t.Error("the read wasn't given back")
+	}
+
+	r.setOptions(config.OptionsConfiguration{})
+	if r.rate.Limit() != rate.Inf || r.reads.max != 0 {
+		t.Error("expected the limits to be lifted")
+	}
+}
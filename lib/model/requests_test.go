@@ -10,6 +10,7 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -24,6 +25,7 @@ import (
 	"github.com/syncthing/syncthing/lib/fs"
 	"github.com/syncthing/syncthing/lib/ignore"
 	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/sha256"
 )
 
 func TestRequestSimple(t *testing.T) {
@@ -65,6 +67,61 @@ func TestRequestSimple(t *testing.T) {
 	}
 }
 
+func TestRequestBatched(t *testing.T) {
+	// Verify that small files are pulled using batch requests when the
+	// other device supports them.
+
+	w, fcfg := tmpDefaultWrapper()
+	tfs := fcfg.Filesystem()
+	m := setupModel(w)
+	defer cleanupModelAndRemoveDir(m, tfs.URI())
+	fc := addFakeConnWithHello(m, device1, protocol.HelloResult{SupportsBatchRequests: true})
+	fc.folder = "default"
+
+	const numFiles = 20
+	done := make(chan struct{})
+	seen := make(map[string]struct{})
+	fc.mut.Lock()
+	fc.indexFn = func(_ context.Context, folder string, fs []protocol.FileInfo) {
+		for _, f := range fs {
+			if strings.HasPrefix(f.Name, "small") {
+				seen[f.Name] = struct{}{}
+			}
+		}
+		select {
+		case <-done:
+		default:
+			if len(seen) == numFiles {
+				close(done)
+			}
+		}
+	}
+	for i := 0; i < numFiles; i++ {
+		fc.addFileLocked("small"+strconv.Itoa(i), 0644, protocol.FileInfoTypeFile, []byte("contents "+strconv.Itoa(i)), protocol.Vector{}.Update(device1.Short()))
+	}
+	fc.mut.Unlock()
+
+	fc.sendIndexUpdate()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for files to sync")
+	}
+
+	for i := 0; i < numFiles; i++ {
+		name := "small" + strconv.Itoa(i)
+		if err := equalContents(filepath.Join(tfs.URI(), name), []byte("contents "+strconv.Itoa(i))); err != nil {
+			t.Error("File did not sync correctly:", err)
+		}
+	}
+
+	fc.mut.Lock()
+	defer fc.mut.Unlock()
+	if fc.batchRequests == 0 {
+		t.Error("expected batch requests to be used")
+	}
+}
+
 func TestSymlinkTraversalRead(t *testing.T) {
 	// Verify that a symlink can not be traversed for reading.
 
@@ -102,7 +159,7 @@ func TestSymlinkTraversalRead(t *testing.T) {
 	<-done
 
 	// Request a file by traversing the symlink
-	res, err := m.Request(device1, "default", "symlink/requests_test.go", 10, 0, nil, 0, false)
+	res, err := m.Request(device1, "default", "symlink/requests_test.go", 10, 0, nil, 0, false, protocol.RequestPriorityNormal)
 	if err == nil || res != nil {
 		t.Error("Managed to traverse symlink")
 	}
@@ -508,7 +565,7 @@ func TestRescanIfHaveInvalidContent(t *testing.T) {
 		t.Fatalf("unexpected weak hash: %d != 103547413", f.Blocks[0].WeakHash)
 	}
 
-	res, err := m.Request(device1, "default", "foo", int32(len(payload)), 0, f.Blocks[0].Hash, f.Blocks[0].WeakHash, false)
+	res, err := m.Request(device1, "default", "foo", int32(len(payload)), 0, f.Blocks[0].Hash, f.Blocks[0].WeakHash, false, protocol.RequestPriorityNormal)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -522,7 +579,7 @@ func TestRescanIfHaveInvalidContent(t *testing.T) {
 
 	must(t, ioutil.WriteFile(filepath.Join(tmpDir, "foo"), payload, 0777))
 
-	_, err = m.Request(device1, "default", "foo", int32(len(payload)), 0, f.Blocks[0].Hash, f.Blocks[0].WeakHash, false)
+	_, err = m.Request(device1, "default", "foo", int32(len(payload)), 0, f.Blocks[0].Hash, f.Blocks[0].WeakHash, false, protocol.RequestPriorityNormal)
 	if err == nil {
 		t.Fatalf("expected failure")
 	}
@@ -1137,3 +1194,181 @@ func TestIgnoreDeleteUnignore(t *testing.T) {
 	case <-done:
 	}
 }
+
+func TestRequestPlaceholder(t *testing.T) {
+	// Verify that files in a folder with placeholders are only pulled once
+	// hydration is requested.
+
+	w, fcfg := tmpDefaultWrapper()
+	fcfg.Placeholders = true
+	_, _ = w.SetFolder(fcfg)
+	m, fc := setupModelWithConnectionFromWrapper(w)
+	tfs := fcfg.Filesystem()
+	defer cleanupModelAndRemoveDir(m, tfs.URI())
+
+	indexed := make(chan protocol.FileInfo, 1)
+	fc.mut.Lock()
+	fc.indexFn = func(_ context.Context, folder string, fs []protocol.FileInfo) {
+		for _, f := range fs {
+			if f.Name == "testfile" {
+				indexed <- f
+			}
+		}
+	}
+	fc.mut.Unlock()
+
+	waitIndexed := func() protocol.FileInfo {
+		select {
+		case f := <-indexed:
+			return f
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out before index update was received")
+		}
+		return protocol.FileInfo{}
+	}
+
+	contents := []byte("test file contents\n")
+	fc.addFile("testfile", 0644, protocol.FileInfoTypeFile, contents)
+	fc.sendIndexUpdate()
+
+	// We announce the placeholder as invalid, so that nobody asks us for
+	// its contents.
+
+	if f := waitIndexed(); !f.IsInvalid() {
+		t.Error("placeholder should be announced as invalid")
+	}
+	if info, err := tfs.Lstat("testfile"); err != nil {
+		t.Fatal(err)
+	} else if info.Size() != 0 {
+		t.Errorf("placeholder has size %d, expected 0", info.Size())
+	}
+	if cur, _ := m.CurrentFolderFile("default", "testfile"); !cur.IsPlaceholder() {
+		t.Errorf("expected placeholder in db, got %v", cur)
+	}
+
+	// Scanning the placeholder doesn't make it a truncated file.
+
+	must(t, m.ScanFolder("default"))
+	if cur, _ := m.CurrentFolderFile("default", "testfile"); !cur.IsPlaceholder() {
+		t.Errorf("expected placeholder in db after scan, got %v", cur)
+	}
+
+	// Hydrating pulls the contents.
+
+	must(t, m.Hydrate("default", "testfile"))
+	for f := waitIndexed(); f.IsInvalid(); f = waitIndexed() {
+	}
+	if err := equalContents(filepath.Join(tfs.URI(), "testfile"), contents); err != nil {
+		t.Error("File did not sync correctly:", err)
+	}
+	if err := m.Hydrate("default", "testfile"); err != errNotPlaceholder {
+		t.Errorf("expected errNotPlaceholder hydrating a regular file, got %v", err)
+	}
+}
+
+func TestRequestStreamFile(t *testing.T) {
+	// Verify that an ignored remote file can be streamed without ending up
+	// in the folder.
+
+	m, fc, fcfg := setupModelWithConnection()
+	tfs := fcfg.Filesystem()
+	defer cleanupModelAndRemoveDir(m, tfs.URI())
+
+	if err := m.SetIgnores("default", []string{"streamed"}); err != nil {
+		t.Fatal(err)
+	}
+
+	contents := []byte("test file contents\n")
+	fc.addFile("streamed", 0644, protocol.FileInfoTypeFile, contents)
+	fc.sendIndexUpdate()
+
+	var gf protocol.FileInfo
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		var ok bool
+		if gf, ok = m.CurrentGlobalFile("default", "streamed"); ok {
+			break
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("timed out before index update was processed")
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	must(t, m.StreamFile(context.Background(), "default", gf, buf))
+	if !bytes.Equal(buf.Bytes(), contents) {
+		t.Errorf("streamed %q, expected %q", buf.Bytes(), contents)
+	}
+	if _, err := tfs.Lstat("streamed"); !fs.IsNotExist(err) {
+		t.Error("streamed file should not exist in the folder, got", err)
+	}
+
+	part := make([]byte, 8)
+	if n, err := m.ReadGlobalAt(context.Background(), "default", gf, part, 5); err != nil || n != len(part) {
+		t.Fatalf("read %d bytes, %v", n, err)
+	} else if !bytes.Equal(part, contents[5:13]) {
+		t.Errorf("read %q, expected %q", part, contents[5:13])
+	}
+	if n, err := m.ReadGlobalAt(context.Background(), "default", gf, part, gf.Size-4); err != io.EOF || n != 4 {
+		t.Errorf("read %d bytes at end of file, %v", n, err)
+	}
+
+	if entries, err := m.GlobalDirectoryEntries("default", ""); err != nil {
+		t.Fatal(err)
+	} else if len(entries) != 1 || entries[0].Name != "streamed" {
+		t.Errorf("unexpected directory entries %v", entries)
+	}
+
+	gf.Type = protocol.FileInfoTypeDirectory
+	if err := m.StreamFile(context.Background(), "default", gf, buf); err != errNotStreamable {
+		t.Errorf("expected errNotStreamable streaming a directory, got %v", err)
+	}
+}
+
+func TestRequestFromSnapshot(t *testing.T) {
+	// Verify that requests for a recently changed file are served from a
+	// snapshot when the file is replaced before the transfer is done.
+
+	m, _, fcfg := setupModelWithConnection()
+	tfs := fcfg.Filesystem()
+	defer cleanupModelAndRemoveDir(m, tfs.URI())
+
+	contents := []byte("first version\n")
+	if err := ioutil.WriteFile(filepath.Join(tfs.URI(), "hot"), contents, 0644); err != nil {
+		t.Fatal(err)
+	}
+	hash := sha256.Sum256(contents)
+
+	res, err := m.Request(device1, "default", "hot", int32(len(contents)), 0, hash[:], 0, false, protocol.RequestPriorityNormal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Close()
+	defer m.snapshots.drop("default", "hot")
+
+	// Replace the file the way editors usually do, by renaming a new
+	// version into place.
+
+	if err := ioutil.WriteFile(filepath.Join(tfs.URI(), "hot.new"), []byte("second version\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	must(t, tfs.Rename("hot.new", "hot"))
+
+	res, err = m.Request(device1, "default", "hot", int32(len(contents)), 0, hash[:], 0, false, protocol.RequestPriorityNormal)
+	if err != nil {
+		t.Fatal("request for the old version failed:", err)
+	}
+	if !bytes.Equal(res.Data(), contents) {
+		t.Errorf("got %q, expected %q", res.Data(), contents)
+	}
+	res.Close()
+
+	// The snapshot is removed once it's useless.
+
+	wrongHash := sha256.Sum256([]byte("third version\n"))
+	if _, err := m.Request(device1, "default", "hot", int32(len(contents)), 0, wrongHash[:], 0, false, protocol.RequestPriorityNormal); err != protocol.ErrNoSuchFile {
+		t.Errorf("expected ErrNoSuchFile for unknown data, got %v", err)
+	}
+	if _, err := tfs.Lstat(fs.SnapshotName("hot")); !fs.IsNotExist(err) {
+		t.Error("snapshot should have been removed, got", err)
+	}
+}
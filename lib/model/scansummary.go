@@ -0,0 +1,87 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// The kinds of change told apart in FolderScanSummary events.
+const (
+	scanChangeAdded    = "added"
+	scanChangeModified = "modified"
+	scanChangeDeleted  = "deleted"
+	scanChangeMetadata = "metadata"
+)
+
+// scanSummary collects the changes found by a scan, for the
+// FolderScanSummary event sent when it's done. Items newly ignored aren't
+// counted, as nothing changed about them on disk.
+type scanSummary struct {
+	maxPaths int
+	counts   map[string]int
+	paths    map[string][]string
+}
+
+func newScanSummary(maxPaths int) *scanSummary {
+	return &scanSummary{
+		maxPaths: maxPaths,
+		counts:   make(map[string]int),
+		paths:    make(map[string][]string),
+	}
+}
+
+// scanned counts a changed item found by scanning, given what we had for
+// it before, if anything.
+func (s *scanSummary) scanned(cur protocol.FileInfo, hasCur bool, file protocol.FileInfo) {
+	switch {
+	case !hasCur || cur.IsDeleted() || cur.IsInvalid():
+		s.add(scanChangeAdded, file.Name)
+	case cur.Type != file.Type:
+		s.add(scanChangeModified, file.Name)
+	case file.IsDirectory(),
+		file.IsSymlink() && file.SymlinkTarget == cur.SymlinkTarget,
+		file.Type == protocol.FileInfoTypeFile && protocol.BlocksEqual(cur.Blocks, file.Blocks):
+		s.add(scanChangeMetadata, file.Name)
+	default:
+		s.add(scanChangeModified, file.Name)
+	}
+}
+
+// deleted counts an item found to be deleted.
+func (s *scanSummary) deleted(name string) {
+	s.add(scanChangeDeleted, name)
+}
+
+func (s *scanSummary) add(kind, name string) {
+	s.counts[kind]++
+	if len(s.paths[kind]) < s.maxPaths {
+		s.paths[kind] = append(s.paths[kind], name)
+	}
+}
+
+// eventData returns the data of the FolderScanSummary event. The paths are
+// only included if asked for, and truncated says whether all of them fit.
+func (s *scanSummary) eventData(folder string) map[string]interface{} {
+	data := map[string]interface{}{
+		"folder": folder,
+	}
+	truncated := false
+	for _, kind := range []string{scanChangeAdded, scanChangeModified, scanChangeDeleted, scanChangeMetadata} {
+		data[kind] = s.counts[kind]
+		truncated = truncated || s.counts[kind] > len(s.paths[kind])
+	}
+	if s.maxPaths > 0 {
+		paths := make(map[string][]string, len(s.paths))
+		for kind, names := range s.paths {
+			paths[kind] = names
+		}
+		data["paths"] = paths
+		data["truncated"] = truncated
+	}
+	return data
+}
@@ -0,0 +1,56 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+func TestScanSummary(t *testing.T) {
+	blocks := []protocol.BlockInfo{{Hash: []byte("a"), Size: 1}}
+	otherBlocks := []protocol.BlockInfo{{Hash: []byte("b"), Size: 1}}
+	file := func(name string, blocks []protocol.BlockInfo, perms uint32) protocol.FileInfo {
+		return protocol.FileInfo{Name: name, Type: protocol.FileInfoTypeFile, Blocks: blocks, Permissions: perms}
+	}
+
+	s := newScanSummary(1)
+	s.scanned(protocol.FileInfo{}, false, file("new", blocks, 0644))
+	s.scanned(protocol.FileInfo{Name: "undeleted", Deleted: true}, true, file("undeleted", blocks, 0644))
+	s.scanned(file("changed", blocks, 0644), true, file("changed", otherBlocks, 0644))
+	s.scanned(file("chmod", blocks, 0644), true, file("chmod", blocks, 0600))
+	s.scanned(file("dir", blocks, 0644), true, protocol.FileInfo{Name: "dir", Type: protocol.FileInfoTypeDirectory})
+	s.deleted("gone")
+
+	data := s.eventData("default")
+	expected := map[string]interface{}{
+		"folder":   "default",
+		"added":    2,
+		"modified": 2,
+		"deleted":  1,
+		"metadata": 1,
+		"paths": map[string][]string{
+			"added":    {"new"},
+			"modified": {"changed"},
+			"deleted":  {"gone"},
+			"metadata": {"chmod"},
+		},
+		"truncated": true,
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("got %v, expected %v", data, expected)
+	}
+
+	// No paths unless asked for.
+	s = newScanSummary(0)
+	s.deleted("gone")
+	if _, ok := s.eventData("default")["paths"]; ok {
+		t.Error("paths should not be included")
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/ignore"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// sharedIgnores are the ignore patterns a device recommends for a folder,
+// as we keep them in the database so that they apply from the start.
+type sharedIgnores struct {
+	Device protocol.DeviceID `json:"device"`
+	Lines  []string          `json:"lines"`
+}
+
+func sharedIgnoresKey(folder string) string {
+	return "sharedIgnores-" + folder
+}
+
+func loadSharedIgnores(misc *db.NamespacedKV, folder string) sharedIgnores {
+	var shared sharedIgnores
+	if bs, ok, _ := misc.Bytes(sharedIgnoresKey(folder)); ok {
+		_ = json.Unmarshal(bs, &shared)
+	}
+	return shared
+}
+
+// setSharedIgnoresLocked gives the matcher the patterns shared for the
+// folder, before it is first loaded. Must be called with fmut held.
+func (m *model) setSharedIgnoresLocked(cfg config.FolderConfiguration, ignores *ignore.Matcher) {
+	if cfg.ShareIgnores {
+		return
+	}
+	shared := loadSharedIgnores(db.NewMiscDataNamespace(m.db), cfg.ID)
+	if len(shared.Lines) == 0 {
+		return
+	}
+	if err := ignores.SetShared(shared.Lines); err != nil {
+		l.Infof("Not using ignore patterns shared by %s for folder %s: %v", shared.Device, cfg.Description(), err)
+	}
+}
+
+// sharedIgnoresLocked returns the ignore patterns to recommend for the
+// folder, or nil if it doesn't share them. Need to hold (read) lock on
+// m.fmut when calling this.
+func (m *model) sharedIgnoresLocked(cfg config.FolderConfiguration) []string {
+	if !cfg.ShareIgnores {
+		return nil
+	}
+	if ignores, ok := m.folderIgnores[cfg.ID]; ok {
+		return ignores.Lines()
+	}
+	return nil
+}
+
+// handleSharedIgnores takes up the ignore patterns the device recommends
+// for the folder, or drops those it recommended before if it no longer
+// does. When several devices recommend patterns for a folder, the last one
+// to connect wins.
+func (m *model) handleSharedIgnores(device protocol.DeviceID, folder protocol.Folder) {
+	cfg, ok := m.cfg.Folder(folder.ID)
+	if !ok || !cfg.SharedWith(device) || cfg.ShareIgnores {
+		// We don't take patterns for folders we share patterns for
+		// ourselves, lest two devices keep overriding each other.
+		return
+	}
+
+	misc := db.NewMiscDataNamespace(m.db)
+	current := loadSharedIgnores(misc, folder.ID)
+	if len(folder.SharedIgnores) == 0 {
+		if current.Device != device || len(current.Lines) == 0 {
+			return
+		}
+	} else if current.Device == device && reflect.DeepEqual(current.Lines, folder.SharedIgnores) {
+		return
+	}
+
+	m.fmut.RLock()
+	ignores := m.folderIgnores[folder.ID]
+	runner := m.folderRunners[folder.ID]
+	m.fmut.RUnlock()
+	if ignores == nil {
+		return
+	}
+	if err := ignores.SetShared(folder.SharedIgnores); err != nil {
+		l.Infof("Not using ignore patterns shared by %s for folder %s: %v", device, cfg.Description(), err)
+		return
+	}
+
+	var err error
+	if len(folder.SharedIgnores) == 0 {
+		l.Infof("Device %s no longer shares ignore patterns for folder %s", device, cfg.Description())
+		err = misc.Delete(sharedIgnoresKey(folder.ID))
+	} else {
+		l.Infof("Using %d ignore patterns shared by %s for folder %s", len(folder.SharedIgnores), device, cfg.Description())
+		bs, _ := json.Marshal(&sharedIgnores{Device: device, Lines: folder.SharedIgnores})
+		err = misc.PutBytes(sharedIgnoresKey(folder.ID), bs)
+	}
+	if err != nil {
+		l.Warnln("Storing shared ignore patterns:", err)
+	}
+
+	if runner != nil {
+		// The new patterns are loaded when scanning.
+		go runner.Scan(nil)
+	}
+}
+
+// SharedIgnores returns the ignore patterns another device shares for the
+// folder, which apply after the local ones.
+func (m *model) SharedIgnores(folder string) []string {
+	m.fmut.RLock()
+	ignores, ok := m.folderIgnores[folder]
+	m.fmut.RUnlock()
+	if !ok {
+		return nil
+	}
+	return ignores.Shared()
+}
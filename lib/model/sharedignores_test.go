@@ -0,0 +1,60 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+func TestSharedIgnores(t *testing.T) {
+	w, fcfg := tmpDefaultWrapper()
+	m, _ := setupModelWithConnectionFromWrapper(w)
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+
+	patterns := []string{"*.tmp"}
+	m.handleSharedIgnores(device1, protocol.Folder{ID: "default", SharedIgnores: patterns})
+	if shared := m.SharedIgnores("default"); !reflect.DeepEqual(shared, patterns) {
+		t.Errorf("expected the shared patterns, got %v", shared)
+	}
+	if stored := loadSharedIgnores(db.NewMiscDataNamespace(m.db), "default"); stored.Device != device1 || !reflect.DeepEqual(stored.Lines, patterns) {
+		t.Errorf("expected the shared patterns to be stored, got %+v", stored)
+	}
+
+	// Another device not sharing patterns leaves them be, while the device
+	// that shared them can take them back.
+	m.handleSharedIgnores(device2, protocol.Folder{ID: "default"})
+	if shared := m.SharedIgnores("default"); len(shared) != 1 {
+		t.Errorf("expected the shared patterns to remain, got %v", shared)
+	}
+	m.handleSharedIgnores(device1, protocol.Folder{ID: "default"})
+	if shared := m.SharedIgnores("default"); len(shared) != 0 {
+		t.Errorf("expected no shared patterns, got %v", shared)
+	}
+	if stored := loadSharedIgnores(db.NewMiscDataNamespace(m.db), "default"); len(stored.Lines) != 0 {
+		t.Errorf("expected nothing stored, got %+v", stored)
+	}
+
+	// A folder sharing its patterns sends them, and doesn't take others.
+	fcfg.ShareIgnores = true
+	waiter, _ := m.cfg.SetFolder(fcfg)
+	waiter.Wait()
+	if err := m.SetIgnores("default", []string{"*.bak"}); err != nil {
+		t.Fatal(err)
+	}
+	cm := m.generateClusterConfig(device1)
+	if len(cm.Folders) != 1 || !reflect.DeepEqual(cm.Folders[0].SharedIgnores, []string{"*.bak"}) {
+		t.Errorf("expected the patterns to be sent, got %+v", cm.Folders)
+	}
+	m.handleSharedIgnores(device1, protocol.Folder{ID: "default", SharedIgnores: patterns})
+	if shared := m.SharedIgnores("default"); len(shared) != 0 {
+		t.Errorf("expected no shared patterns, got %v", shared)
+	}
+}
@@ -31,42 +31,65 @@ type sharedPullerState struct {
 	hasCurFile  bool              // Whether curFile is set
 	curFile     protocol.FileInfo // The file as it exists now in our database
 	sparse      bool
+	fsync       bool // whether to fsync the temp file when done writing it
 	created     time.Time
+	compressor  *tempCompressor // set if the temp file is written compressed
 
 	// Mutable, must be locked for access
-	err               error           // The first error we hit
-	writer            *lockedWriterAt // Wraps fd to prevent fd closing at the same time as writing
-	copyTotal         int             // Total number of copy actions for the whole job
-	pullTotal         int             // Total number of pull actions for the whole job
-	copyOrigin        int             // Number of blocks copied from the original file
-	copyOriginShifted int             // Number of blocks copied from the original file but shifted
-	copyNeeded        int             // Number of copy actions still pending
-	pullNeeded        int             // Number of block pulls still pending
-	updated           time.Time       // Time when any of the counters above were last updated
-	closed            bool            // True if the file has been finalClosed.
-	available         []int32         // Indexes of the blocks that are available in the temporary file
-	availableUpdated  time.Time       // Time when list of available blocks was last updated
-	mut               sync.RWMutex    // Protects the above
+	err               error                     // The first error we hit
+	writer            *lockedWriterAt           // Wraps fd to prevent fd closing at the same time as writing
+	copyTotal         int                       // Total number of copy actions for the whole job
+	pullTotal         int                       // Total number of pull actions for the whole job
+	copyOrigin        int                       // Number of blocks copied from the original file
+	copyOriginShifted int                       // Number of blocks copied from the original file but shifted
+	copyNeeded        int                       // Number of copy actions still pending
+	pullNeeded        int                       // Number of block pulls still pending
+	updated           time.Time                 // Time when any of the counters above were last updated
+	closed            bool                      // True if the file has been finalClosed.
+	available         []int32                   // Indexes of the blocks that are available in the temporary file
+	availableUpdated  time.Time                 // Time when list of available blocks was last updated
+	requests          map[protocol.DeviceID]int // Number of block requests in flight per device
+	mut               sync.RWMutex              // Protects the above
 }
 
 // A momentary state representing the progress of the puller
 type pullerProgress struct {
-	Total                   int   `json:"total"`
-	Reused                  int   `json:"reused"`
-	CopiedFromOrigin        int   `json:"copiedFromOrigin"`
-	CopiedFromOriginShifted int   `json:"copiedFromOriginShifted"`
-	CopiedFromElsewhere     int   `json:"copiedFromElsewhere"`
-	Pulled                  int   `json:"pulled"`
-	Pulling                 int   `json:"pulling"`
-	BytesDone               int64 `json:"bytesDone"`
-	BytesTotal              int64 `json:"bytesTotal"`
+	Total                   int     `json:"total"`
+	Reused                  int     `json:"reused"`
+	CopiedFromOrigin        int     `json:"copiedFromOrigin"`
+	CopiedFromOriginShifted int     `json:"copiedFromOriginShifted"`
+	CopiedFromElsewhere     int     `json:"copiedFromElsewhere"`
+	Pulled                  int     `json:"pulled"`
+	Pulling                 int     `json:"pulling"`
+	BytesDone               int64   `json:"bytesDone"`
+	BytesTotal              int64   `json:"bytesTotal"`
+	SparseHoles             int     `json:"sparseHoles"` // Number of contiguous ranges of blocks not yet written
+	Completion              float64 `json:"completion"`  // Percentage of bytes written, 0-100
+}
+
+// A PullingFile describes a file being pulled, for debugging transfers
+// that don't make progress.
+type PullingFile struct {
+	Name            string         `json:"name"`
+	Started         time.Time      `json:"started"`
+	ElapsedS        float64        `json:"elapsedS"`
+	Updated         time.Time      `json:"updated"` // when any of the block counts last changed
+	Blocks          int            `json:"blocks"`
+	BlocksRemaining int            `json:"blocksRemaining"`
+	CopyNeeded      int            `json:"copyNeeded"`
+	PullNeeded      int            `json:"pullNeeded"`
+	Requests        map[string]int `json:"requests"` // block requests in flight per device
+	Error           string         `json:"error,omitempty"`
 }
 
 // lockedWriterAt adds a lock to protect from closing the fd at the same time as writing.
 // WriteAt() is goroutine safe by itself, but not against for example Close().
 type lockedWriterAt struct {
-	mut sync.RWMutex
-	fd  fs.File
+	mut        sync.RWMutex
+	fd         fs.File
+	compressor *tempCompressor
+	holes      []protocol.FileHole // holes of the file left unwritten, if the temp file is new and sparse
+	fsync      bool
 }
 
 // WriteAt itself is goroutine safe, thus just needs to acquire a read-lock to
@@ -74,14 +97,82 @@ type lockedWriterAt struct {
 func (w *lockedWriterAt) WriteAt(p []byte, off int64) (n int, err error) {
 	w.mut.RLock()
 	defer w.mut.RUnlock()
+	if w.compressor != nil {
+		return w.compressor.writeAt(w.fd, p, off)
+	}
+	if len(w.holes) > 0 {
+		return writeSparse(w.fd, p, off, w.holes)
+	}
 	return w.fd.WriteAt(p, off)
 }
 
+// writeSparse writes p at off, except for the parts of it that are in the
+// given holes and all zeroes. In a file that has been truncated to its
+// size, and not written there before, those stay holes.
+func writeSparse(w io.WriterAt, p []byte, off int64, holes []protocol.FileHole) (int, error) {
+	end := off + int64(len(p))
+	pos := off
+	for _, h := range holes {
+		start, stop := h.Offset, h.Offset+h.Length
+		if stop <= pos || start >= end {
+			continue
+		}
+		if start < pos {
+			start = pos
+		}
+		if stop > end {
+			stop = end
+		}
+		if !isZeroes(p[start-off : stop-off]) {
+			// The file has changed since its holes were found. This part
+			// is written along with what follows.
+			continue
+		}
+		if start > pos {
+			if _, err := w.WriteAt(p[pos-off:start-off], pos); err != nil {
+				return int(pos - off), err
+			}
+		}
+		pos = stop
+	}
+	if pos < end {
+		if _, err := w.WriteAt(p[pos-off:], pos); err != nil {
+			return int(pos - off), err
+		}
+	}
+	return len(p), nil
+}
+
+func isZeroes(p []byte) bool {
+	for _, b := range p {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// CloneRangeFrom makes the given range of the file share storage with the
+// range at srcOffset in src, if the filesystem supports it. Like WriteAt it
+// only needs a read-lock.
+func (w *lockedWriterAt) CloneRangeFrom(filesystem fs.Filesystem, src fs.File, srcOffset, off, length int64) error {
+	w.mut.RLock()
+	defer w.mut.RUnlock()
+	if w.compressor != nil {
+		return errCompressedClone
+	}
+	return filesystem.CloneRange(src, srcOffset, w.fd, off, length)
+}
+
 // SyncClose ensures that no more writes are happening before going ahead and
-// syncing and closing the fd, thus needs to acquire a write-lock.
+// syncing, unless the folder's durability says otherwise, and closing the fd,
+// thus needs to acquire a write-lock.
 func (w *lockedWriterAt) SyncClose() error {
 	w.mut.Lock()
 	defer w.mut.Unlock()
+	if !w.fsync {
+		return w.fd.Close()
+	}
 	if err := w.fd.Sync(); err != nil {
 		// Sync() is nice if it works but not worth failing the
 		// operation over if it fails.
@@ -92,7 +183,7 @@ func (w *lockedWriterAt) SyncClose() error {
 
 // tempFile returns the fd for the temporary file, reusing an open fd
 // or creating the file as necessary.
-func (s *sharedPullerState) tempFile() (io.WriterAt, error) {
+func (s *sharedPullerState) tempFile() (*lockedWriterAt, error) {
 	s.mut.Lock()
 	defer s.mut.Unlock()
 
@@ -158,8 +249,10 @@ func (s *sharedPullerState) tempFileInWritableDir(_ string) error {
 	s.fs.Hide(s.tempName)
 
 	// Don't truncate symlink files, as that will mean that the path will
-	// contain a bunch of nulls.
-	if s.sparse && !s.file.IsSymlink() {
+	// contain a bunch of nulls. Files with holes are truncated even when
+	// sparse files are disabled, to keep the holes as they are.
+	var holes []protocol.FileHole
+	if (s.sparse || len(s.file.Holes) > 0) && !s.file.IsSymlink() {
 		// Truncate sets the size of the file. This creates a sparse file or a
 		// space reservation, depending on the underlying filesystem.
 		if err := fd.Truncate(s.file.Size); err != nil {
@@ -183,14 +276,25 @@ func (s *sharedPullerState) tempFileInWritableDir(_ string) error {
 
 				return err
 			}
+		} else if s.reused == 0 {
+			// Nothing has been written to the file, so it's all a hole.
+			holes = s.file.Holes
 		}
 	}
 
 	// Same fd will be used by all writers
-	s.writer = &lockedWriterAt{sync.NewRWMutex(), fd}
+	s.writer = &lockedWriterAt{sync.NewRWMutex(), fd, s.compressor, holes, s.fsync}
 	return nil
 }
 
+// inHole returns whether the block is all in a hole of the file that is
+// kept as such in the temp file, so it doesn't need to be written.
+func (s *sharedPullerState) inHole(block protocol.BlockInfo) bool {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+	return s.writer != nil && len(s.writer.holes) > 0 && s.file.InHole(block.Offset, int64(block.Size))
+}
+
 // fail sets the error on the puller state compose of error, and marks the
 // sharedPullerState as failed. Is a no-op when called on an already failed state.
 func (s *sharedPullerState) fail(err error) {
@@ -220,7 +324,7 @@ func (s *sharedPullerState) copyDone(block protocol.BlockInfo) {
 	s.mut.Lock()
 	s.copyNeeded--
 	s.updated = time.Now()
-	s.available = append(s.available, int32(block.Offset/int64(s.file.BlockSize())))
+	s.available = append(s.available, int32(s.file.BlockIndex(block.Offset)))
 	s.availableUpdated = time.Now()
 	l.Debugln("sharedPullerState", s.folder, s.file.Name, "copyNeeded ->", s.copyNeeded)
 	s.mut.Unlock()
@@ -256,12 +360,31 @@ func (s *sharedPullerState) pullDone(block protocol.BlockInfo) {
 	s.mut.Lock()
 	s.pullNeeded--
 	s.updated = time.Now()
-	s.available = append(s.available, int32(block.Offset/int64(s.file.BlockSize())))
+	s.available = append(s.available, int32(s.file.BlockIndex(block.Offset)))
 	s.availableUpdated = time.Now()
 	l.Debugln("sharedPullerState", s.folder, s.file.Name, "pullNeeded done ->", s.pullNeeded)
 	s.mut.Unlock()
 }
 
+// requesting records that a block is being requested from the device.
+func (s *sharedPullerState) requesting(device protocol.DeviceID) {
+	s.mut.Lock()
+	if s.requests == nil {
+		s.requests = make(map[protocol.DeviceID]int)
+	}
+	s.requests[device]++
+	s.mut.Unlock()
+}
+
+// requested records that a request to the device has returned.
+func (s *sharedPullerState) requested(device protocol.DeviceID) {
+	s.mut.Lock()
+	if s.requests[device]--; s.requests[device] <= 0 {
+		delete(s.requests, device)
+	}
+	s.mut.Unlock()
+}
+
 // finalClose atomically closes and returns closed status of a file. A true
 // first return value means the file was closed and should be finished, with
 // the error indicating the success or failure of the close. A false first
@@ -305,17 +428,72 @@ func (s *sharedPullerState) Progress() *pullerProgress {
 	s.mut.RLock()
 	defer s.mut.RUnlock()
 	total := s.reused + s.copyTotal + s.pullTotal
-	done := total - s.copyNeeded - s.pullNeeded
-	return &pullerProgress{
+	bytesDone, holes := s.writtenLocked()
+	p := &pullerProgress{
 		Total:               total,
 		Reused:              s.reused,
 		CopiedFromOrigin:    s.copyOrigin,
 		CopiedFromElsewhere: s.copyTotal - s.copyNeeded - s.copyOrigin,
 		Pulled:              s.pullTotal - s.pullNeeded,
 		Pulling:             s.pullNeeded,
-		BytesTotal:          blocksToSize(s.file.BlockSize(), total),
-		BytesDone:           blocksToSize(s.file.BlockSize(), done),
+		BytesTotal:          s.file.Size,
+		BytesDone:           bytesDone,
+		SparseHoles:         holes,
+	}
+	if p.BytesTotal > 0 {
+		p.Completion = 100 * float64(p.BytesDone) / float64(p.BytesTotal)
+	} else if s.copyNeeded+s.pullNeeded == 0 {
+		p.Completion = 100
 	}
+	return p
+}
+
+// pullingFile returns the state of the file for debugging.
+func (s *sharedPullerState) pullingFile(now time.Time) PullingFile {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+	p := PullingFile{
+		Name:            s.file.Name,
+		Started:         s.created,
+		ElapsedS:        now.Sub(s.created).Seconds(),
+		Updated:         s.updated,
+		Blocks:          len(s.file.Blocks),
+		BlocksRemaining: s.copyNeeded + s.pullNeeded,
+		CopyNeeded:      s.copyNeeded,
+		PullNeeded:      s.pullNeeded,
+		Requests:        make(map[string]int, len(s.requests)),
+	}
+	for device, n := range s.requests {
+		p.Requests[device.String()] = n
+	}
+	if s.err != nil {
+		p.Error = s.err.Error()
+	}
+	return p
+}
+
+// writtenLocked returns the number of bytes that are available in the
+// temporary file, using the actual size of each block, and the number of
+// contiguous ranges of blocks that are still missing. Blocks are written out
+// of order, so the missing ranges are holes in the temporary file.
+func (s *sharedPullerState) writtenLocked() (int64, int) {
+	written := make([]bool, len(s.file.Blocks))
+	var bytes int64
+	for _, idx := range s.available {
+		if idx < 0 || int(idx) >= len(written) || written[idx] {
+			continue
+		}
+		written[idx] = true
+		bytes += int64(s.file.Blocks[idx].Size)
+	}
+
+	holes := 0
+	for i, ok := range written {
+		if !ok && (i == 0 || written[i-1]) {
+			holes++
+		}
+	}
+	return bytes, holes
 }
 
 // Updated returns the time when any of the progress related counters was last updated.
@@ -336,15 +514,12 @@ func (s *sharedPullerState) AvailableUpdated() time.Time {
 
 // Available returns blocks available in the current temporary file
 func (s *sharedPullerState) Available() []int32 {
+	if s.compressor != nil {
+		// Other devices can't be served from a compressed temp file.
+		return nil
+	}
 	s.mut.RLock()
 	blocks := s.available
 	s.mut.RUnlock()
 	return blocks
 }
-
-func blocksToSize(size int, num int) int64 {
-	if num < 2 {
-		return int64(size / 2)
-	}
-	return int64(num-1)*int64(size) + int64(size/2)
-}
@@ -7,10 +7,16 @@
 package model
 
 import (
+	"bytes"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/protocol"
 	"github.com/syncthing/syncthing/lib/sync"
 )
 
@@ -41,3 +47,177 @@ func TestReadOnlyDir(t *testing.T) {
 	s.fail(nil)
 	s.finalClose()
 }
+
+func TestProgressHolesAndSizes(t *testing.T) {
+	// Three full blocks and a small last one.
+	blocks := []protocol.BlockInfo{
+		{Offset: 0, Size: protocol.MinBlockSize},
+		{Offset: protocol.MinBlockSize, Size: protocol.MinBlockSize},
+		{Offset: 2 * protocol.MinBlockSize, Size: protocol.MinBlockSize},
+		{Offset: 3 * protocol.MinBlockSize, Size: 100},
+	}
+	s := sharedPullerState{
+		file: protocol.FileInfo{
+			Size:         3*protocol.MinBlockSize + 100,
+			Blocks:       blocks,
+			RawBlockSize: protocol.MinBlockSize,
+		},
+		copyTotal:  4,
+		copyNeeded: 4,
+		mut:        sync.NewRWMutex(),
+	}
+
+	p := s.Progress()
+	if p.SparseHoles != 1 || p.BytesDone != 0 || p.Completion != 0 {
+		t.Fatalf("unexpected initial progress %+v", p)
+	}
+
+	// Writing a block in the middle splits the file into two holes.
+	s.copyDone(blocks[1])
+	p = s.Progress()
+	if p.SparseHoles != 2 {
+		t.Errorf("expected two holes, got %d", p.SparseHoles)
+	}
+	if p.BytesDone != protocol.MinBlockSize {
+		t.Errorf("expected %d bytes done, got %d", protocol.MinBlockSize, p.BytesDone)
+	}
+
+	// The small last block only accounts for its actual size.
+	s.copyDone(blocks[3])
+	p = s.Progress()
+	if p.SparseHoles != 2 {
+		t.Errorf("expected two holes, got %d", p.SparseHoles)
+	}
+	if exp := int64(protocol.MinBlockSize + 100); p.BytesDone != exp {
+		t.Errorf("expected %d bytes done, got %d", exp, p.BytesDone)
+	}
+
+	s.copyDone(blocks[0])
+	s.copyDone(blocks[2])
+	p = s.Progress()
+	if p.SparseHoles != 0 || p.BytesDone != p.BytesTotal || p.Completion != 100 {
+		t.Errorf("unexpected final progress %+v", p)
+	}
+}
+
+func TestPullingFileRequests(t *testing.T) {
+	s := sharedPullerState{
+		file:       protocol.FileInfo{Name: "foo", Blocks: make([]protocol.BlockInfo, 3)},
+		copyNeeded: 1,
+		pullNeeded: 1,
+		created:    time.Now().Add(-time.Minute),
+		mut:        sync.NewRWMutex(),
+	}
+
+	s.requesting(device1)
+	s.requesting(device1)
+	s.requesting(device2)
+	s.requested(device2)
+
+	p := s.pullingFile(time.Now())
+	if p.Name != "foo" || p.Blocks != 3 || p.BlocksRemaining != 2 {
+		t.Errorf("unexpected state %+v", p)
+	}
+	if p.ElapsedS < 60 {
+		t.Errorf("expected at least a minute elapsed, got %vs", p.ElapsedS)
+	}
+	if len(p.Requests) != 1 || p.Requests[device1.String()] != 2 {
+		t.Errorf("expected two requests to device1 only, got %v", p.Requests)
+	}
+}
+
+func TestSparseTempFile(t *testing.T) {
+	tmpDir := createTmpDir()
+	defer os.RemoveAll(tmpDir)
+	ffs := fs.NewFilesystem(fs.FilesystemTypeBasic, tmpDir)
+
+	// A hole in the middle of the second block and one covering the third,
+	// with sparse files otherwise disabled.
+	const size = 3 * protocol.MinBlockSize
+	holes := []protocol.FileHole{
+		{Offset: protocol.MinBlockSize + 4096, Length: 8192},
+		{Offset: 2 * protocol.MinBlockSize, Length: protocol.MinBlockSize},
+	}
+	s := sharedPullerState{
+		file: protocol.FileInfo{
+			Size:  size,
+			Holes: holes,
+		},
+		fs:       ffs,
+		tempName: ".temp_name",
+		mut:      sync.NewRWMutex(),
+	}
+
+	fd, err := s.tempFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.inHole(protocol.BlockInfo{Offset: 0, Size: protocol.MinBlockSize}) {
+		t.Error("First block isn't in a hole")
+	}
+	if !s.inHole(protocol.BlockInfo{Offset: 2 * protocol.MinBlockSize, Size: protocol.MinBlockSize}) {
+		t.Error("Last block is in a hole")
+	}
+
+	data := make([]byte, protocol.MinBlockSize)
+	for i := range data {
+		data[i] = 1
+	}
+	if _, err := fd.WriteAt(data, 0); err != nil {
+		t.Fatal(err)
+	}
+	copy(data[4096:], make([]byte, 8192))
+	if _, err := fd.WriteAt(data, protocol.MinBlockSize); err != nil {
+		t.Fatal(err)
+	}
+	s.fail(nil)
+	s.finalClose()
+
+	bs, err := ioutil.ReadFile(filepath.Join(tmpDir, ".temp_name"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bs) != size {
+		t.Fatalf("Temp file has size %d, expected %d", len(bs), size)
+	}
+	if !bytes.Equal(bs[protocol.MinBlockSize:2*protocol.MinBlockSize], data) {
+		t.Error("Second block differs from what was written")
+	}
+
+	// Where the filesystem supports it, the holes are still there.
+	check, err := ffs.Open(".temp_name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer check.Close()
+	got, err := fs.Holes(check)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) > 0 && !reflect.DeepEqual(got, holes) {
+		t.Errorf("Temp file has holes %v, expected %v", got, holes)
+	}
+}
+
+func TestWriteSparseChangedHole(t *testing.T) {
+	// Data where the source had a hole is written all the same.
+	tmpDir := createTmpDir()
+	defer os.RemoveAll(tmpDir)
+	fd, err := os.Create(filepath.Join(tmpDir, "file"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	data := []byte("abcdefgh")
+	if _, err := writeSparse(fd, data, 0, []protocol.FileHole{{Offset: 2, Length: 4}}); err != nil {
+		t.Fatal(err)
+	}
+	bs, err := ioutil.ReadFile(fd.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(bs, data) {
+		t.Errorf("Got %q, expected %q", bs, data)
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"time"
+
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+const (
+	// Files modified more recently than this are likely to change again
+	// while other devices pull them, and get a snapshot when requested.
+	snapshotHotWindow = time.Minute
+	// Snapshots are removed when they haven't been used for this long.
+	snapshotLifetime = time.Minute
+)
+
+// requestSnapshots keeps read only snapshots of files that are requested by
+// other devices while they are being modified locally. When the file has
+// changed by the time a block is requested, the block of the announced
+// version can still be served from the snapshot instead of failing the
+// request, and with it the whole transfer, until the next scan.
+//
+// Snapshots are copy-on-write clones where the filesystem supports it and
+// hard links otherwise. The latter only help against files that are
+// replaced by renaming a new version into place, which is what most
+// applications that save often do. They live next to the file under a
+// temporary name, so the scanner ignores them and cleans up leftovers.
+type requestSnapshots struct {
+	snapshots map[snapshotKey]*requestSnapshot
+	mut       sync.Mutex
+}
+
+type snapshotKey struct {
+	folder, name string
+}
+
+type requestSnapshot struct {
+	fs    fs.Filesystem
+	name  string
+	timer *time.Timer
+}
+
+func newRequestSnapshots() *requestSnapshots {
+	return &requestSnapshots{
+		snapshots: make(map[snapshotKey]*requestSnapshot),
+		mut:       sync.NewMutex(),
+	}
+}
+
+// take makes a snapshot of the file unless there already is one, in which
+// case its lifetime is extended.
+func (s *requestSnapshots) take(ffs fs.Filesystem, folder, name string) {
+	key := snapshotKey{folder, name}
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if snap, ok := s.snapshots[key]; ok {
+		snap.timer.Reset(snapshotLifetime)
+		return
+	}
+
+	snapName := fs.SnapshotName(name)
+	if err := snapshotFile(ffs, name, snapName); err != nil {
+		l.Debugf("Taking snapshot of %s in folder %q: %v", name, folder, err)
+		return
+	}
+
+	snap := &requestSnapshot{
+		fs:   ffs,
+		name: snapName,
+	}
+	snap.timer = time.AfterFunc(snapshotLifetime, func() {
+		s.remove(key, snap)
+	})
+	s.snapshots[key] = snap
+}
+
+// read reads from the snapshot of the file, if there is one.
+func (s *requestSnapshots) read(folder, name string, offset int64, buf []byte) bool {
+	s.mut.Lock()
+	snap, ok := s.snapshots[snapshotKey{folder, name}]
+	if ok {
+		snap.timer.Reset(snapshotLifetime)
+	}
+	s.mut.Unlock()
+
+	return ok && readOffsetIntoBuf(snap.fs, snap.name, offset, buf) == nil
+}
+
+// drop removes the snapshot of the file, if there is one, as it doesn't
+// hold what other devices want anymore.
+func (s *requestSnapshots) drop(folder, name string) {
+	key := snapshotKey{folder, name}
+	s.mut.Lock()
+	snap, ok := s.snapshots[key]
+	s.mut.Unlock()
+	if ok {
+		snap.timer.Stop()
+		s.remove(key, snap)
+	}
+}
+
+func (s *requestSnapshots) remove(key snapshotKey, snap *requestSnapshot) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	// The timer may fire after the snapshot was already dropped and
+	// another one taken in its place.
+	if s.snapshots[key] != snap {
+		return
+	}
+	delete(s.snapshots, key)
+
+	if err := snap.fs.Remove(snap.name); err != nil && !fs.IsNotExist(err) {
+		l.Debugf("Removing snapshot %s: %v", snap.name, err)
+	}
+}
+
+// snapshotFile makes snapName a clone of name if possible, or a hard link
+// to it otherwise.
+func snapshotFile(ffs fs.Filesystem, name, snapName string) error {
+	// A snapshot left behind by an unclean shutdown is of no use.
+	if err := ffs.Remove(snapName); err != nil && !fs.IsNotExist(err) {
+		return err
+	}
+
+	src, err := ffs.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	dst, err := ffs.OpenFile(snapName, fs.OptReadWrite|fs.OptCreate|fs.OptExclusive, 0600)
+	if err != nil {
+		return err
+	}
+	err = ffs.CloneRange(src, 0, dst, 0, info.Size())
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		return nil
+	}
+
+	ffs.Remove(snapName)
+	if err != fs.ErrCloneNotSupported {
+		return err
+	}
+	return ffs.Hardlink(name, snapName)
+}
@@ -0,0 +1,165 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/osutil"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+var errNotStreamable = errors.New("not a regular file")
+
+// StreamFile writes the contents of the given global file to w, requesting
+// the blocks from connected devices one by one as they are written. Nothing
+// is stored in the folder, so this works for files that are not available
+// locally, e.g. because they are ignored.
+func (m *model) StreamFile(ctx context.Context, folder string, file protocol.FileInfo, w io.Writer) error {
+	m.fmut.RLock()
+	_, ok := m.folderFiles[folder]
+	m.fmut.RUnlock()
+	if !ok {
+		return errFolderMissing
+	}
+
+	if file.IsDeleted() || file.IsInvalid() || file.Type != protocol.FileInfoTypeFile {
+		return errNotStreamable
+	}
+
+	for _, block := range file.Blocks {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		buf, err := m.globalBlock(ctx, folder, file, block)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadGlobalAt reads from the given global file into buf, starting at
+// offset, requesting the covering blocks from connected devices. Like
+// io.ReaderAt it returns io.EOF when fewer than len(buf) bytes were read
+// because the end of the file was reached.
+func (m *model) ReadGlobalAt(ctx context.Context, folder string, file protocol.FileInfo, buf []byte, offset int64) (int, error) {
+	m.fmut.RLock()
+	_, ok := m.folderFiles[folder]
+	m.fmut.RUnlock()
+	if !ok {
+		return 0, errFolderMissing
+	}
+
+	if file.IsDeleted() || file.IsInvalid() || file.Type != protocol.FileInfoTypeFile {
+		return 0, errNotStreamable
+	}
+	if offset < 0 {
+		return 0, errors.New("negative offset")
+	}
+
+	n := 0
+	for i := file.BlockIndex(offset); i < len(file.Blocks) && n < len(buf); i++ {
+		block := file.Blocks[i]
+		data, err := m.globalBlock(ctx, folder, file, block)
+		if err != nil {
+			return n, err
+		}
+		start := offset + int64(n) - block.Offset
+		n += copy(buf[n:], data[start:])
+	}
+	if n < len(buf) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// GlobalDirectoryEntries returns the global files that are immediate
+// children of the given directory, which is the empty string for the root
+// of the folder. Deleted and invalid files are not included.
+func (m *model) GlobalDirectoryEntries(folder, dir string) ([]db.FileInfoTruncated, error) {
+	m.fmut.RLock()
+	files, ok := m.folderFiles[folder]
+	m.fmut.RUnlock()
+	if !ok {
+		return nil, errFolderMissing
+	}
+
+	prefix := osutil.NativeFilename(dir)
+	if prefix != "" && !strings.HasSuffix(prefix, string(filepath.Separator)) {
+		prefix += string(filepath.Separator)
+	}
+
+	var entries []db.FileInfoTruncated
+	files.WithPrefixedGlobalTruncated(prefix, func(fi db.FileIntf) bool {
+		f := fi.(db.FileInfoTruncated)
+		if f.IsInvalid() || f.IsDeleted() || !strings.HasPrefix(f.Name, prefix) {
+			return true
+		}
+		rel := f.Name[len(prefix):]
+		if rel == "" || strings.ContainsRune(rel, filepath.Separator) {
+			return true
+		}
+		entries = append(entries, f)
+		return true
+	})
+	return entries, nil
+}
+
+// globalBlock returns the data of the block, requested from a connected
+// device unless it's all zeroes.
+func (m *model) globalBlock(ctx context.Context, folder string, file protocol.FileInfo, block protocol.BlockInfo) ([]byte, error) {
+	if block.IsEmpty() {
+		return make([]byte, block.Size), nil
+	}
+	buf, err := m.streamBlock(ctx, folder, file, block)
+	if err != nil {
+		return nil, errors.Wrapf(err, "request %s at offset %d", file.Name, block.Offset)
+	}
+	return buf, nil
+}
+
+// streamBlock requests the block from the fastest device that has it,
+// trying the others in turn until one returns the expected data.
+func (m *model) streamBlock(ctx context.Context, folder string, file protocol.FileInfo, block protocol.BlockInfo) ([]byte, error) {
+	lastError := errNoDevice
+	candidates := m.Availability(folder, file, block)
+	for {
+		selected, found := activity.fastest(candidates, int(block.Size), m.connectionQuality)
+		if !found {
+			return nil, lastError
+		}
+		candidates = removeAvailability(candidates, selected)
+
+		activity.using(selected)
+		// Someone is waiting for the data, so it's served before bulk
+		// requests for other files.
+		buf, err := m.requestGlobal(protocol.WithRequestPriority(ctx, protocol.RequestPriorityInteractive), selected.ID, folder, file.Name, block.Offset, int(block.Size), block.Hash, block.WeakHash, selected.FromTemporary)
+		activity.done(selected)
+		if err == nil {
+			err = verifyBuffer(buf, block, file.HashAlgorithm)
+		}
+		if err != nil {
+			l.Debugln("stream request:", folder, file.Name, block.Offset, selected.ID, err)
+			lastError = err
+			continue
+		}
+		return buf, nil
+	}
+}
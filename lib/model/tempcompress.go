@@ -0,0 +1,164 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"fmt"
+
+	lz4 "github.com/bkaradzic/go-lz4"
+	"github.com/pkg/errors"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+var errCompressedClone = errors.New("cannot clone into a compressed temp file")
+
+// A tempCompressor writes the blocks of a temp file lz4 compressed, each at
+// the start of the place it will have in the final file. The rest of that
+// place is never written, so on filesystems with sparse files only the
+// compressed data hits the disk. Blocks that don't compress are written as
+// they are.
+//
+// The compressed sizes are only kept in memory, so compressed temp files
+// can't be reused after a restart.
+type tempCompressor struct {
+	sizes map[int64]int // compressed size of the block at each offset, zero if stored as is
+	mut   sync.Mutex
+}
+
+func newTempCompressor() *tempCompressor {
+	return &tempCompressor{
+		sizes: make(map[int64]int),
+		mut:   sync.NewMutex(),
+	}
+}
+
+func (c *tempCompressor) writeAt(fd fs.File, data []byte, off int64) (int, error) {
+	buf := protocol.BufferPool.Get(lz4.CompressBound(len(data)))
+	defer protocol.BufferPool.Put(buf)
+
+	compressed, err := lz4.Encode(buf, data)
+	if err != nil || len(compressed) >= len(data) {
+		compressed = nil
+	}
+
+	if compressed != nil {
+		_, err = fd.WriteAt(compressed, off)
+	} else {
+		_, err = fd.WriteAt(data, off)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	c.mut.Lock()
+	c.sizes[off] = len(compressed)
+	c.mut.Unlock()
+	return len(data), nil
+}
+
+// compressTempFiles returns true if pulled data should be written to
+// compressed temp files. That's only worth it where the filesystem wouldn't
+// compress it anyway.
+func compressTempFiles(cfg config.FolderConfiguration, tempFs fs.Filesystem) bool {
+	return cfg.CompressTempFiles && !fs.TransparentCompression(tempFs)
+}
+
+// decompressTemp writes the contents of the compressed temp file to the
+// regular temp file, removes the former and returns the name of the latter.
+func (s *sharedPullerState) decompressTemp() (string, error) {
+	tempName := fs.TempName(s.file.Name)
+	err := inWritableDir(func(_ string) error {
+		return s.decompressTempInWritableDir(tempName)
+	}, s.fs, tempName, s.ignorePerms)
+	if err != nil {
+		s.fs.Remove(tempName)
+		return "", errors.Wrap(err, "decompressing temp file")
+	}
+	if err := s.fs.Remove(s.tempName); err != nil {
+		l.Debugln("failed to remove compressed temp file:", err)
+	}
+	return tempName, nil
+}
+
+func (s *sharedPullerState) decompressTempInWritableDir(tempName string) error {
+	src, err := s.fs.Open(s.tempName)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	mode := fs.FileMode(s.file.Permissions) | 0600
+	if s.ignorePerms {
+		mode = 0666
+	}
+	dst, err := s.fs.OpenFile(tempName, fs.OptWriteOnly|fs.OptCreate|fs.OptTruncate, mode)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+	if s.sparse || len(s.file.Holes) > 0 {
+		if err := dst.Truncate(s.file.Size); err != nil {
+			return err
+		}
+	}
+
+	c := s.compressor
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	buf := protocol.BufferPool.Get(s.file.BlockSize())
+	defer func() {
+		protocol.BufferPool.Put(buf)
+	}()
+	out := protocol.BufferPool.Get(s.file.BlockSize())
+	defer func() {
+		protocol.BufferPool.Put(out)
+	}()
+
+	for _, block := range s.file.Blocks {
+		size, ok := c.sizes[block.Offset]
+		if !ok {
+			if (s.sparse || s.file.InHole(block.Offset, int64(block.Size))) && block.IsEmpty() {
+				// Never written, a hole in the final file as well.
+				continue
+			}
+			return fmt.Errorf("block at offset %d was not written", block.Offset)
+		}
+
+		compressed := size != 0
+		if !compressed {
+			size = int(block.Size)
+		}
+		buf = protocol.BufferPool.Upgrade(buf, size)
+		if _, err := src.ReadAt(buf, block.Offset); err != nil {
+			return err
+		}
+
+		data := buf
+		if compressed {
+			out = protocol.BufferPool.Upgrade(out, int(block.Size))
+			if data, err = lz4.Decode(out, buf); err != nil {
+				return err
+			}
+			if len(data) != int(block.Size) {
+				return fmt.Errorf("block at offset %d decompressed to %d bytes, expected %d", block.Offset, len(data), block.Size)
+			}
+		}
+		if _, err := writeSparse(dst, data, block.Offset, s.file.Holes); err != nil {
+			return err
+		}
+	}
+
+	if !s.fsync {
+		return nil
+	}
+	return dst.Sync()
+}
@@ -0,0 +1,103 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"time"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// How often to look for deleted files to forget. Retention is counted in
+// days, so there is no point in doing it much more often.
+const tombstoneInterval = 6 * time.Hour
+
+// How many days a device keeps deleted files of a folder, as we keep it in
+// the database so that it is known before the device connects.
+func tombstoneRetentionKey(folder string, device protocol.DeviceID) string {
+	return "tombstoneRetention-" + folder + "-" + device.String()
+}
+
+// handleTombstoneRetention remembers how many days the device keeps
+// deleted files of the folder, as it announced in its cluster config.
+func (m *model) handleTombstoneRetention(device protocol.DeviceID, folder protocol.Folder) {
+	misc := db.NewMiscDataNamespace(m.db)
+	if err := misc.PutInt64(tombstoneRetentionKey(folder.ID, device), int64(folder.TombstoneRetentionDays)); err != nil {
+		l.Warnln("Storing tombstone retention:", err)
+	}
+}
+
+// folderTombstoneRetention returns how long to keep deleted files of the
+// folder after every device has the deletion, or zero to keep them for
+// good. That is the longest retention of the devices sharing the folder,
+// and zero unless every one of them has announced one, as older devices
+// and those keeping deleted files for good have no say otherwise.
+func (m *model) folderTombstoneRetention(cfg config.FolderConfiguration) time.Duration {
+	days := int64(cfg.TombstoneRetentionDays)
+	if days <= 0 {
+		return 0
+	}
+	misc := db.NewMiscDataNamespace(m.db)
+	for _, dev := range cfg.Devices {
+		if dev.DeviceID == m.id {
+			continue
+		}
+		theirs, ok, _ := misc.Int64(tombstoneRetentionKey(cfg.ID, dev.DeviceID))
+		if !ok || theirs <= 0 {
+			return 0
+		}
+		if theirs > days {
+			days = theirs
+		}
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// tombstoneCollector periodically forgets deleted files of the folder
+// that every device has had the deletion of for long enough.
+func (f *folder) tombstoneCollector() {
+	timer := time.NewTimer(tombstoneInterval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+		case <-f.ctx.Done():
+			return
+		}
+
+		f.dropTombstones()
+
+		timer.Reset(tombstoneInterval)
+	}
+}
+
+// dropTombstones makes one pass over the deleted files in the folder.
+func (f *folder) dropTombstones() {
+	retention := f.model.folderTombstoneRetention(f.FolderConfiguration)
+	if retention == 0 {
+		l.Debugln(f, "not dropping tombstones, as not every device is set to")
+		return
+	}
+
+	devices := make([]protocol.DeviceID, 0, len(f.Devices))
+	for _, dev := range f.Devices {
+		if dev.DeviceID != f.model.id {
+			devices = append(devices, dev.DeviceID)
+		}
+	}
+	dropped, err := f.fset.DropTombstones(devices, retention)
+	if err != nil {
+		l.Warnf("Folder %v: forgetting deleted files: %v", f.Description(), err)
+		return
+	}
+	if dropped > 0 {
+		l.Infof("Folder %v: forgot %d deleted files that every device has had for %v", f.Description(), dropped, retention)
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+func TestFolderTombstoneRetention(t *testing.T) {
+	w, fcfg := tmpDefaultWrapper()
+	m, _ := setupModelWithConnectionFromWrapper(w)
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+
+	fcfg.TombstoneRetentionDays = 30
+	if r := m.folderTombstoneRetention(fcfg); r != 0 {
+		t.Errorf("expected no retention before the other device announced one, got %v", r)
+	}
+	m.handleTombstoneRetention(device1, protocol.Folder{ID: "default"})
+	if r := m.folderTombstoneRetention(fcfg); r != 0 {
+		t.Errorf("expected no retention for a device keeping deleted files, got %v", r)
+	}
+
+	// The longest retention applies.
+	m.handleTombstoneRetention(device1, protocol.Folder{ID: "default", TombstoneRetentionDays: 60})
+	if r := m.folderTombstoneRetention(fcfg); r != 60*24*time.Hour {
+		t.Errorf("expected 60 days, got %v", r)
+	}
+	m.handleTombstoneRetention(device1, protocol.Folder{ID: "default", TombstoneRetentionDays: 10})
+	if r := m.folderTombstoneRetention(fcfg); r != 30*24*time.Hour {
+		t.Errorf("expected 30 days, got %v", r)
+	}
+
+	// Every device sharing the folder must have announced one.
+	fcfg.Devices = append(fcfg.Devices, config.FolderDeviceConfiguration{DeviceID: device2})
+	if r := m.folderTombstoneRetention(fcfg); r != 0 {
+		t.Errorf("expected no retention for a device that hasn't announced anything, got %v", r)
+	}
+}
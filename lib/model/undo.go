@@ -0,0 +1,231 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/osutil"
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+const (
+	undoDirName   = "undo"
+	undoIndexName = "index.json"
+
+	undoActionDelete  = "delete"
+	undoActionReplace = "replace"
+)
+
+var (
+	errNoUndo        = errors.New("folder has no undo buffer")
+	errNoSuchUndo    = errors.New("no such undo entry")
+	errUndoDirectory = errors.New("a directory is in the way")
+)
+
+// An UndoEntry describes a file that was deleted or replaced when applying
+// a change from another device.
+type UndoEntry struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Action      string    `json:"action"`
+	Time        time.Time `json:"time"`
+	ModifiedBy  string    `json:"modifiedBy"`
+	Size        int64     `json:"size"`
+	ModTime     time.Time `json:"modTime"`
+	Permissions uint32    `json:"permissions"`
+}
+
+// The undoBuffer keeps copies of the most recent files that were deleted or
+// replaced due to changes from other devices, so that they can be put back
+// regardless of whether the folder has a versioner. The copies live in the
+// folder marker directory, together with an index of the entries.
+type undoBuffer struct {
+	fs      fs.Filesystem
+	dir     string
+	size    int
+	entries []UndoEntry // oldest first
+	nextID  int64
+	loaded  bool
+	mut     sync.Mutex
+}
+
+type undoIndex struct {
+	NextID  int64       `json:"nextID"`
+	Entries []UndoEntry `json:"entries"`
+}
+
+func newUndoBuffer(ffs fs.Filesystem, markerName string, size int) *undoBuffer {
+	return &undoBuffer{
+		fs:   ffs,
+		dir:  filepath.Join(markerName, undoDirName),
+		size: size,
+		mut:  sync.NewMutex(),
+	}
+}
+
+// record keeps a copy of the file as it currently is on disk, evicting the
+// oldest entries if the buffer is full.
+func (b *undoBuffer) record(cur protocol.FileInfo, action string, modifiedBy protocol.ShortID) error {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	b.loadLocked()
+
+	if err := b.fs.MkdirAll(b.dir, 0700); err != nil {
+		return err
+	}
+	id := b.nextID
+	if err := osutil.Copy(b.fs, b.fs, cur.Name, b.path(id)); err != nil {
+		_ = b.fs.Remove(b.path(id))
+		return err
+	}
+	b.nextID++
+
+	b.entries = append(b.entries, UndoEntry{
+		ID:          id,
+		Name:        cur.Name,
+		Action:      action,
+		Time:        time.Now().Truncate(time.Second),
+		ModifiedBy:  modifiedBy.String(),
+		Size:        cur.Size,
+		ModTime:     cur.ModTime(),
+		Permissions: cur.Permissions,
+	})
+	for len(b.entries) > b.size {
+		_ = b.fs.Remove(b.path(b.entries[0].ID))
+		b.entries = b.entries[1:]
+	}
+
+	return b.saveLocked()
+}
+
+// Entries returns the entries in the buffer, most recent first.
+func (b *undoBuffer) Entries() []UndoEntry {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	b.loadLocked()
+
+	entries := make([]UndoEntry, len(b.entries))
+	for i, e := range b.entries {
+		entries[len(entries)-1-i] = e
+	}
+	return entries
+}
+
+// restore puts the kept copy of the given entry, or of the most recent one
+// if id is negative, back in place and removes the entry from the buffer.
+// Whatever is at that path now is overwritten.
+func (b *undoBuffer) restore(id int64, ignorePerms bool) (UndoEntry, error) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	b.loadLocked()
+
+	idx := -1
+	if id < 0 {
+		idx = len(b.entries) - 1
+	}
+	for i, e := range b.entries {
+		if e.ID == id {
+			idx = i
+		}
+	}
+	if idx < 0 {
+		return UndoEntry{}, errNoSuchUndo
+	}
+	entry := b.entries[idx]
+
+	if info, err := b.fs.Lstat(entry.Name); err == nil && info.IsDir() {
+		return UndoEntry{}, errUndoDirectory
+	}
+	if err := b.fs.MkdirAll(filepath.Dir(entry.Name), 0755); err != nil {
+		return UndoEntry{}, err
+	}
+
+	tempName := fs.TempName(entry.Name)
+	if err := osutil.Copy(b.fs, b.fs, b.path(entry.ID), tempName); err != nil {
+		_ = b.fs.Remove(tempName)
+		return UndoEntry{}, err
+	}
+	if !ignorePerms {
+		if err := b.fs.Chmod(tempName, fs.FileMode(entry.Permissions&0777)); err != nil {
+			_ = b.fs.Remove(tempName)
+			return UndoEntry{}, err
+		}
+	}
+	b.fs.Chtimes(tempName, entry.ModTime, entry.ModTime) // never fails
+	if err := osutil.RenameOrCopy(b.fs, b.fs, tempName, entry.Name); err != nil {
+		_ = b.fs.Remove(tempName)
+		return UndoEntry{}, err
+	}
+
+	_ = b.fs.Remove(b.path(entry.ID))
+	b.entries = append(b.entries[:idx], b.entries[idx+1:]...)
+
+	return entry, b.saveLocked()
+}
+
+func (b *undoBuffer) loadLocked() {
+	if b.loaded {
+		return
+	}
+	b.loaded = true
+
+	fd, err := b.fs.Open(b.indexPath())
+	if err != nil {
+		return
+	}
+	defer fd.Close()
+	bs, err := ioutil.ReadAll(fd)
+	if err != nil {
+		return
+	}
+	var idx undoIndex
+	if err := json.Unmarshal(bs, &idx); err != nil {
+		l.Debugln("undo: loading index:", err)
+		return
+	}
+	b.entries = idx.Entries
+	b.nextID = idx.NextID
+}
+
+func (b *undoBuffer) saveLocked() error {
+	bs, err := json.Marshal(undoIndex{
+		NextID:  b.nextID,
+		Entries: b.entries,
+	})
+	if err != nil {
+		return err
+	}
+	fd, err := b.fs.Create(b.indexPath())
+	if err != nil {
+		return err
+	}
+	if _, err := fd.Write(bs); err != nil {
+		fd.Close()
+		return err
+	}
+	return fd.Close()
+}
+
+func (b *undoBuffer) path(id int64) string {
+	return filepath.Join(b.dir, strconv.FormatInt(id, 10))
+}
+
+func (b *undoBuffer) indexPath() string {
+	return filepath.Join(b.dir, undoIndexName)
+}
@@ -0,0 +1,112 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/scanner"
+)
+
+func TestUndoRemoteDelete(t *testing.T) {
+	m, f := setupSendReceiveFolder()
+	defer cleanupSRFolder(f, m)
+	f.undo = newUndoBuffer(f.fs, config.DefaultMarkerName, 2)
+
+	name := "undone"
+	contents := []byte("precious contents")
+	must(t, ioutil.WriteFile(filepath.Join(f.fs.URI(), name), contents, 0644))
+	info, err := f.fs.Lstat(name)
+	must(t, err)
+	fi, err := scanner.CreateFileInfo(info, name, f.fs)
+	must(t, err)
+	fi.Version = fi.Version.Update(myID.Short())
+	f.updateLocalsFromScanning([]protocol.FileInfo{fi})
+
+	// A remote device deletes the file.
+
+	fi.Deleted = true
+	fi.Version = fi.Version.Update(device1.Short())
+	fi.ModifiedBy = device1.Short()
+	scanChan := make(chan string, 1)
+	dbUpdateChan := make(chan dbUpdateJob, 1)
+	f.deleteFile(fi, dbUpdateChan, scanChan)
+	if _, err := f.fs.Lstat(name); !fs.IsNotExist(err) {
+		t.Fatal("file should have been deleted, got", err)
+	}
+
+	entries, err := f.UndoEntries()
+	must(t, err)
+	if len(entries) != 1 || entries[0].Name != name || entries[0].Action != undoActionDelete || entries[0].ModifiedBy != device1.Short().String() {
+		t.Fatal("unexpected undo entries", entries)
+	}
+
+	// The entries survive a restart.
+
+	f.undo = newUndoBuffer(f.fs, config.DefaultMarkerName, 2)
+	if entries := f.undo.Entries(); len(entries) != 1 {
+		t.Fatal("expected the entry to be loaded, got", entries)
+	}
+
+	restored, err := f.undo.restore(-1, false)
+	must(t, err)
+	if restored.Name != name {
+		t.Error("restored the wrong entry", restored)
+	}
+	if err := equalContents(filepath.Join(f.fs.URI(), name), contents); err != nil {
+		t.Error("file was not restored:", err)
+	}
+	if entries := f.undo.Entries(); len(entries) != 0 {
+		t.Error("restored entry should be gone, got", entries)
+	}
+	if _, err := f.undo.restore(-1, false); err != errNoSuchUndo {
+		t.Error("expected errNoSuchUndo, got", err)
+	}
+}
+
+func TestUndoEviction(t *testing.T) {
+	m, f := setupSendReceiveFolder()
+	defer cleanupSRFolder(f, m)
+	f.undo = newUndoBuffer(f.fs, config.DefaultMarkerName, 2)
+
+	fi := createFile(t, "file", f.fs)
+	for i := 0; i < 3; i++ {
+		must(t, f.undo.record(fi, undoActionReplace, device1.Short()))
+	}
+
+	entries := f.undo.Entries()
+	if len(entries) != 2 || entries[0].ID != 2 || entries[1].ID != 1 {
+		t.Fatal("unexpected undo entries", entries)
+	}
+	if _, err := f.fs.Lstat(f.undo.path(0)); !fs.IsNotExist(err) {
+		t.Error("evicted copy should have been removed, got", err)
+	}
+	if _, err := f.undo.restore(0, false); err != errNoSuchUndo {
+		t.Error("expected errNoSuchUndo for evicted entry, got", err)
+	}
+}
+
+func TestUndoMarkerName(t *testing.T) {
+	m, f := setupSendReceiveFolder()
+	defer cleanupSRFolder(f, m)
+	f.undo = newUndoBuffer(f.fs, "custommarker", 2)
+
+	fi := createFile(t, "file", f.fs)
+	must(t, f.undo.record(fi, undoActionReplace, device1.Short()))
+
+	if _, err := f.fs.Lstat(filepath.Join("custommarker", undoDirName, "0")); err != nil {
+		t.Error("expected the copy in the folder's marker, got", err)
+	}
+	if _, err := f.fs.Lstat(filepath.Join(config.DefaultMarkerName, undoDirName)); !fs.IsNotExist(err) {
+		t.Error("expected nothing in the default marker, got", err)
+	}
+}
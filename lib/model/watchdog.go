@@ -0,0 +1,185 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"runtime/pprof"
+	"time"
+
+	"github.com/thejerf/suture"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/locations"
+	"github.com/syncthing/syncthing/lib/sync"
+	"github.com/syncthing/syncthing/lib/util"
+)
+
+const (
+	folderWatchdogInterval = time.Minute
+
+	// Number of queued items to include in the diagnostics.
+	stuckDiagnosticsQueued = 100
+)
+
+// The folderWatchdog looks for folders that have been scanning or syncing
+// for longer than the configured timeouts without making any progress. It
+// saves diagnostics about such a folder and restarts it, which is usually
+// enough to get things going again without restarting the whole process.
+type folderWatchdog struct {
+	suture.Service
+	model    *model
+	interval time.Duration
+	diagDir  string
+
+	restarting map[string]struct{}
+	mut        sync.Mutex
+}
+
+type stuckFolder struct {
+	cfg      config.FolderConfiguration
+	runner   service
+	state    folderState
+	duration time.Duration
+}
+
+func newFolderWatchdog(m *model) *folderWatchdog {
+	w := &folderWatchdog{
+		model:      m,
+		interval:   folderWatchdogInterval,
+		diagDir:    locations.GetBaseDir(locations.ConfigBaseDir),
+		restarting: make(map[string]struct{}),
+		mut:        sync.NewMutex(),
+	}
+	w.Service = util.AsService(w.serve, w.String())
+	return w
+}
+
+func (w *folderWatchdog) serve(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			for _, stuck := range w.stuckFolders() {
+				w.restart(stuck)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// stuckFolders returns the folders that have gone without progress for
+// longer than the timeout of their current state.
+func (w *folderWatchdog) stuckFolders() []stuckFolder {
+	opts := w.model.cfg.Options()
+
+	w.model.fmut.RLock()
+	defer w.model.fmut.RUnlock()
+
+	var stuck []stuckFolder
+	for id, runner := range w.model.folderRunners {
+		state, _, _ := runner.getState()
+		var timeout time.Duration
+		switch state {
+		case FolderScanning:
+			timeout = time.Duration(opts.StuckScanTimeoutS) * time.Second
+		case FolderSyncing:
+			timeout = time.Duration(opts.StuckSyncTimeoutS) * time.Second
+		default:
+			continue
+		}
+		if timeout <= 0 {
+			continue
+		}
+		if d := time.Since(runner.getProgress()); d >= timeout {
+			stuck = append(stuck, stuckFolder{
+				cfg:      w.model.folderCfgs[id],
+				runner:   runner,
+				state:    state,
+				duration: d,
+			})
+		}
+	}
+	return stuck
+}
+
+// restart saves diagnostics for the stuck folder and restarts it in the
+// background, unless a restart is already in progress.
+func (w *folderWatchdog) restart(stuck stuckFolder) {
+	w.mut.Lock()
+	if _, ok := w.restarting[stuck.cfg.ID]; ok {
+		w.mut.Unlock()
+		return
+	}
+	w.restarting[stuck.cfg.ID] = struct{}{}
+	w.mut.Unlock()
+
+	path, err := w.saveDiagnostics(stuck)
+	if err != nil {
+		l.Warnln("Saving diagnostics for stuck folder:", err)
+	}
+	l.Warnf("Folder %v has been %v for %v without making progress; restarting it (diagnostics in %v)", stuck.cfg.Description(), stuck.state, stuck.duration.Truncate(time.Second), path)
+	w.model.evLogger.Log(events.FolderStuck, map[string]interface{}{
+		"folder":      stuck.cfg.ID,
+		"state":       stuck.state.String(),
+		"duration":    stuck.duration.Seconds(),
+		"diagnostics": path,
+	})
+
+	go func() {
+		// The folder might not stop if it is truly wedged, in which case
+		// this never returns and we don't try again.
+		w.model.restartFolder(stuck.cfg, stuck.cfg)
+		w.mut.Lock()
+		delete(w.restarting, stuck.cfg.ID)
+		w.mut.Unlock()
+	}()
+}
+
+// saveDiagnostics writes what the folder was doing and the stacks of all
+// goroutines to a file in the config directory, returning its path.
+func (w *folderWatchdog) saveDiagnostics(stuck stuckFolder) (string, error) {
+	now := time.Now()
+	buf := new(bytes.Buffer)
+
+	fmt.Fprintf(buf, "Folder: %s\n", stuck.cfg.Description())
+	fmt.Fprintf(buf, "State: %s without progress since %s\n", stuck.state, now.Add(-stuck.duration).Format(time.RFC3339))
+	fmt.Fprintf(buf, "Time: %s\n\n", now.Format(time.RFC3339))
+
+	progress, queued, _ := stuck.runner.Jobs(1, stuckDiagnosticsQueued)
+	fmt.Fprintf(buf, "In progress: %q\n", progress)
+	fmt.Fprintf(buf, "Queued (first %d): %q\n\n", stuckDiagnosticsQueued, queued)
+
+	if pulling := w.model.progressEmitter.pullerProgress(stuck.cfg.ID); len(pulling) > 0 {
+		bs, _ := json.MarshalIndent(pulling, "", "  ")
+		fmt.Fprintf(buf, "Pullers: %s\n\n", bs)
+	}
+	for _, ferr := range stuck.runner.Errors() {
+		fmt.Fprintf(buf, "Error: %s: %s\n", ferr.Path, ferr.Err)
+	}
+
+	fmt.Fprintf(buf, "\nGoroutines:\n")
+	if err := pprof.Lookup("goroutine").WriteTo(buf, 2); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(w.diagDir, fmt.Sprintf("stuck-%s-%s.log", sanitizePath(stuck.cfg.ID), now.Format("20060102-150405")))
+	return path, ioutil.WriteFile(path, buf.Bytes(), 0600)
+}
+
+func (w *folderWatchdog) String() string {
+	return fmt.Sprintf("folderWatchdog@%p", w)
+}
@@ -0,0 +1,145 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/db/backend"
+)
+
+// stateOnlyService is a folder service that does nothing but report the
+// state of its tracker.
+type stateOnlyService struct {
+	service
+	st *stateTracker
+}
+
+func (s stateOnlyService) getState() (folderState, time.Time, error) {
+	return s.st.getState()
+}
+
+func (s stateOnlyService) getProgress() time.Time {
+	return s.st.getProgress()
+}
+
+func (s stateOnlyService) Jobs(page, perpage int) ([]string, []string, int) {
+	return []string{"pulling"}, nil, 0
+}
+
+func (s stateOnlyService) Errors() []FileError {
+	return nil
+}
+
+func TestFolderWatchdogStuck(t *testing.T) {
+	cfg := defaultCfg.Copy()
+	cfg.Options.StuckScanTimeoutS = 3600
+	cfg.Options.StuckSyncTimeoutS = 3600
+	w := createTmpWrapper(cfg)
+	m := newModel(w, myID, "syncthing", "dev", db.NewLowlevel(backend.OpenMemory()), nil)
+	defer cleanupModel(m)
+
+	st := newStateTracker("default", m.evLogger)
+	m.fmut.Lock()
+	m.folderCfgs["default"] = defaultFolderConfig
+	m.folderRunners["default"] = stateOnlyService{st: &st}
+	m.fmut.Unlock()
+
+	wd := newFolderWatchdog(m)
+
+	// Freshly started syncing isn't stuck.
+
+	st.setState(FolderSyncing)
+	if stuck := wd.stuckFolders(); len(stuck) != 0 {
+		t.Fatal("expected nothing to be stuck, got", stuck)
+	}
+
+	// Syncing without progress beyond the timeout is.
+
+	st.mut.Lock()
+	st.changed = time.Now().Add(-2 * time.Hour)
+	st.mut.Unlock()
+	stuck := wd.stuckFolders()
+	if len(stuck) != 1 || stuck[0].cfg.ID != "default" || stuck[0].state != FolderSyncing {
+		t.Fatal("expected the folder to be stuck syncing, got", stuck)
+	}
+
+	// Progress resets the clock.
+
+	st.markProgress()
+	if stuck := wd.stuckFolders(); len(stuck) != 0 {
+		t.Fatal("expected nothing to be stuck after progress, got", stuck)
+	}
+
+	// Idle folders are never stuck.
+
+	st.setState(FolderIdle)
+	st.mut.Lock()
+	st.changed = time.Now().Add(-2 * time.Hour)
+	st.progress = time.Time{}
+	st.mut.Unlock()
+	if stuck := wd.stuckFolders(); len(stuck) != 0 {
+		t.Fatal("expected idle folder not to be stuck, got", stuck)
+	}
+}
+
+func TestFolderWatchdogDisabledByDefault(t *testing.T) {
+	w := createTmpWrapper(defaultCfg)
+	m := newModel(w, myID, "syncthing", "dev", db.NewLowlevel(backend.OpenMemory()), nil)
+	defer cleanupModel(m)
+
+	st := newStateTracker("default", m.evLogger)
+	m.fmut.Lock()
+	m.folderCfgs["default"] = defaultFolderConfig
+	m.folderRunners["default"] = stateOnlyService{st: &st}
+	m.fmut.Unlock()
+
+	wd := newFolderWatchdog(m)
+
+	st.setState(FolderSyncing)
+	st.mut.Lock()
+	st.changed = time.Now().Add(-24 * time.Hour)
+	st.mut.Unlock()
+	if stuck := wd.stuckFolders(); len(stuck) != 0 {
+		t.Fatal("expected nothing to be stuck without timeouts, got", stuck)
+	}
+}
+
+func TestFolderWatchdogDiagnostics(t *testing.T) {
+	w := createTmpWrapper(defaultCfg)
+	m := newModel(w, myID, "syncthing", "dev", db.NewLowlevel(backend.OpenMemory()), nil)
+	defer cleanupModel(m)
+
+	dir, err := ioutil.TempDir("", "syncthing-watchdog-")
+	must(t, err)
+	defer os.RemoveAll(dir)
+
+	wd := newFolderWatchdog(m)
+	wd.diagDir = dir
+
+	st := newStateTracker("default", m.evLogger)
+	path, err := wd.saveDiagnostics(stuckFolder{
+		cfg:      defaultFolderConfig,
+		runner:   stateOnlyService{st: &st},
+		state:    FolderSyncing,
+		duration: time.Hour,
+	})
+	must(t, err)
+
+	bs, err := ioutil.ReadFile(path)
+	must(t, err)
+	for _, want := range []string{"syncing", `["pulling"]`, "Goroutines:", "TestFolderWatchdogDiagnostics"} {
+		if !strings.Contains(string(bs), want) {
+			t.Errorf("diagnostics should contain %q", want)
+		}
+	}
+}
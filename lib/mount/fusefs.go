@@ -0,0 +1,184 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// +build linux darwin freebsd
+
+package mount
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+
+	"bazil.org/fuse"
+	fusefs "bazil.org/fuse/fs"
+
+	"github.com/syncthing/syncthing/lib/model"
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+// mountFolder serves the global tree of the folder at dir until the
+// context is cancelled or the filesystem is unmounted from the outside.
+func mountFolder(ctx context.Context, m model.Model, folder, dir string) error {
+	c, err := fuse.Mount(dir, fuse.ReadOnly(), fuse.FSName("syncthing:"+folder), fuse.Subtype("syncthing"))
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- fusefs.Serve(c, &globalFS{model: m, folder: folder})
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		if err := fuse.Unmount(dir); err != nil {
+			// Most likely busy, in which case Serve keeps going until
+			// the mount goes away.
+			return err
+		}
+		return <-done
+	}
+}
+
+type globalFS struct {
+	model  model.Model
+	folder string
+}
+
+func (f *globalFS) Root() (fusefs.Node, error) {
+	return &dirNode{fs: f}, nil
+}
+
+// node returns the node for the global file with the given name, or
+// ENOENT if there is no such file.
+func (f *globalFS) node(name string) (fusefs.Node, error) {
+	gf, ok := f.model.CurrentGlobalFile(f.folder, name)
+	if !ok || gf.IsDeleted() || gf.IsInvalid() {
+		return nil, fuse.ENOENT
+	}
+	switch {
+	case gf.IsDirectory():
+		return &dirNode{fs: f, file: gf}, nil
+	case gf.IsSymlink():
+		return &symlinkNode{file: gf}, nil
+	default:
+		return &fileNode{fs: f, file: gf}, nil
+	}
+}
+
+// dirNode is a directory, where the zero file is the root of the folder.
+type dirNode struct {
+	fs   *globalFS
+	file protocol.FileInfo
+}
+
+func (d *dirNode) Attr(ctx context.Context, a *fuse.Attr) error {
+	a.Mode = os.ModeDir | 0555
+	a.Mtime = d.file.ModTime()
+	return nil
+}
+
+func (d *dirNode) Lookup(ctx context.Context, name string) (fusefs.Node, error) {
+	return d.fs.node(filepath.Join(d.file.Name, name))
+}
+
+func (d *dirNode) ReadDirAll(ctx context.Context) ([]fuse.Dirent, error) {
+	entries, err := d.fs.model.GlobalDirectoryEntries(d.fs.folder, d.file.Name)
+	if err != nil {
+		return nil, err
+	}
+	dirents := make([]fuse.Dirent, 0, len(entries))
+	for _, f := range entries {
+		dirent := fuse.Dirent{
+			Name: filepath.Base(f.Name),
+			Type: fuse.DT_File,
+		}
+		switch {
+		case f.IsDirectory():
+			dirent.Type = fuse.DT_Dir
+		case f.IsSymlink():
+			dirent.Type = fuse.DT_Link
+		}
+		dirents = append(dirents, dirent)
+	}
+	return dirents, nil
+}
+
+type symlinkNode struct {
+	file protocol.FileInfo
+}
+
+func (s *symlinkNode) Attr(ctx context.Context, a *fuse.Attr) error {
+	a.Mode = os.ModeSymlink | 0777
+	a.Mtime = s.file.ModTime()
+	return nil
+}
+
+func (s *symlinkNode) Readlink(ctx context.Context, req *fuse.ReadlinkRequest) (string, error) {
+	return s.file.SymlinkTarget, nil
+}
+
+type fileNode struct {
+	fs   *globalFS
+	file protocol.FileInfo
+}
+
+func (n *fileNode) Attr(ctx context.Context, a *fuse.Attr) error {
+	a.Mode = 0444
+	if !n.file.NoPermissions {
+		a.Mode = os.FileMode(n.file.Permissions & 0555)
+	}
+	a.Size = uint64(n.file.Size)
+	a.Mtime = n.file.ModTime()
+	a.BlockSize = uint32(n.file.BlockSize())
+	return nil
+}
+
+func (n *fileNode) Open(ctx context.Context, req *fuse.OpenRequest, resp *fuse.OpenResponse) (fusefs.Handle, error) {
+	return &fileHandle{
+		fileNode:  n,
+		cachedIdx: -1,
+		mut:       sync.NewMutex(),
+	}, nil
+}
+
+// fileHandle keeps the most recently read block, as the kernel reads in
+// chunks much smaller than blocks of large files.
+type fileHandle struct {
+	*fileNode
+	cached    []byte
+	cachedIdx int
+	mut       sync.Mutex
+}
+
+func (h *fileHandle) Read(ctx context.Context, req *fuse.ReadRequest, resp *fuse.ReadResponse) error {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+
+	buf := make([]byte, req.Size)
+	n := 0
+	for off := req.Offset; n < len(buf) && off < h.file.Size; off = req.Offset + int64(n) {
+		idx := h.file.BlockIndex(off)
+		if idx != h.cachedIdx {
+			block := h.file.Blocks[idx]
+			data := make([]byte, block.Size)
+			if _, err := h.fs.model.ReadGlobalAt(ctx, h.fs.folder, h.file, data, block.Offset); err != nil && err != io.EOF {
+				l.Debugf("Reading %s in folder %s: %v", h.file.Name, h.fs.folder, err)
+				return fuse.EIO
+			}
+			h.cached, h.cachedIdx = data, idx
+		}
+		n += copy(buf[n:], h.cached[off-h.file.Blocks[idx].Offset:])
+	}
+	resp.Data = buf[:n]
+	return nil
+}
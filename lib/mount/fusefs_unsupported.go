@@ -0,0 +1,19 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// +build !linux,!darwin,!freebsd
+
+package mount
+
+import (
+	"context"
+
+	"github.com/syncthing/syncthing/lib/model"
+)
+
+func mountFolder(ctx context.Context, m model.Model, folder, dir string) error {
+	return errUnsupported
+}
@@ -0,0 +1,102 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// Package mount exposes the global tree of folders as read only FUSE
+// filesystems. File contents are requested from connected devices as they
+// are read, so the folder itself doesn't need to hold them.
+package mount
+
+import (
+	"context"
+	"errors"
+
+	"github.com/thejerf/suture"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/model"
+	"github.com/syncthing/syncthing/lib/sync"
+	"github.com/syncthing/syncthing/lib/util"
+)
+
+var errUnsupported = errors.New("FUSE mounts are not supported on this platform")
+
+// The Service mounts each folder that has a mount path configured for as
+// long as it runs.
+type Service struct {
+	suture.Service
+	cfg   config.Wrapper
+	model model.Model
+}
+
+func New(cfg config.Wrapper, m model.Model) *Service {
+	s := &Service{
+		cfg:   cfg,
+		model: m,
+	}
+	s.Service = util.AsService(s.serve, s.String())
+	return s
+}
+
+func (s *Service) serve(ctx context.Context) {
+	s.cfg.Subscribe(s)
+	defer s.cfg.Unsubscribe(s)
+
+	wg := sync.NewWaitGroup()
+	for _, fcfg := range s.cfg.Folders() {
+		if fcfg.MountPath == "" || fcfg.Paused {
+			continue
+		}
+		dir, err := fs.ExpandTilde(fcfg.MountPath)
+		if err != nil {
+			l.Warnf("Mounting folder %s: %v", fcfg.Description(), err)
+			continue
+		}
+
+		wg.Add(1)
+		go func(fcfg config.FolderConfiguration, dir string) {
+			defer wg.Done()
+			l.Infof("Mounting folder %s at %s", fcfg.Description(), dir)
+			if err := mountFolder(ctx, s.model, fcfg.ID, dir); err != nil {
+				l.Warnf("Mounting folder %s at %s: %v", fcfg.Description(), dir, err)
+			}
+		}(fcfg, dir)
+	}
+	wg.Wait()
+}
+
+func (s *Service) VerifyConfiguration(from, to config.Configuration) error {
+	return nil
+}
+
+// CommitConfiguration requires a restart when anything changes about which
+// folders are mounted where.
+func (s *Service) CommitConfiguration(from, to config.Configuration) bool {
+	mounts := func(cfg config.Configuration) map[string]string {
+		res := make(map[string]string)
+		for _, fcfg := range cfg.Folders {
+			if fcfg.MountPath != "" && !fcfg.Paused {
+				res[fcfg.ID] = fcfg.MountPath
+			}
+		}
+		return res
+	}
+
+	fromMounts, toMounts := mounts(from), mounts(to)
+	if len(fromMounts) != len(toMounts) {
+		return false
+	}
+	for id, path := range fromMounts {
+		if toMounts[id] != path {
+			return false
+		}
+	}
+	return true
+}
+
+func (*Service) String() string {
+	return "mount.Service"
+}
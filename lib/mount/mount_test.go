@@ -0,0 +1,44 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package mount
+
+import (
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/config"
+)
+
+func TestCommitConfiguration(t *testing.T) {
+	s := &Service{}
+
+	from := config.Configuration{
+		Folders: []config.FolderConfiguration{
+			{ID: "a", MountPath: "/mnt/a"},
+			{ID: "b"},
+		},
+	}
+
+	cases := []struct {
+		name   string
+		modify func(*config.Configuration)
+		ok     bool
+	}{
+		{"unchanged", func(*config.Configuration) {}, true},
+		{"other option", func(c *config.Configuration) { c.Folders[1].Label = "b" }, true},
+		{"mount added", func(c *config.Configuration) { c.Folders[1].MountPath = "/mnt/b" }, false},
+		{"mount moved", func(c *config.Configuration) { c.Folders[0].MountPath = "/mnt/c" }, false},
+		{"mounted folder paused", func(c *config.Configuration) { c.Folders[0].Paused = true }, false},
+	}
+
+	for _, tc := range cases {
+		to := from.Copy()
+		tc.modify(&to)
+		if ok := s.CommitConfiguration(from, to); ok != tc.ok {
+			t.Errorf("%s: got %v, expected %v", tc.name, ok, tc.ok)
+		}
+	}
+}
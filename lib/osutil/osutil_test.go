@@ -9,8 +9,10 @@ package osutil_test
 import (
 	"io/ioutil"
 	"os"
+	"os/user"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -155,3 +157,30 @@ func TestRenameOrCopy(t *testing.T) {
 		}
 	}
 }
+
+func TestOwnerNames(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no numeric user IDs on Windows")
+	}
+	cur, err := user.Current()
+	if err != nil {
+		t.Skip("no current user:", err)
+	}
+	uid, err := strconv.Atoi(cur.Uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if name, ok := osutil.UserName(uid); !ok || name != cur.Username {
+		t.Errorf("UserName(%d) = %q, %v; expected %q", uid, name, ok, cur.Username)
+	}
+	if id, ok := osutil.UserID(cur.Username); !ok || id != uid {
+		t.Errorf("UserID(%q) = %d, %v; expected %d", cur.Username, id, ok, uid)
+	}
+	if _, ok := osutil.UserID("syncthing-no-such-user"); ok {
+		t.Error("unexpected user found")
+	}
+	if _, ok := osutil.GroupID("syncthing-no-such-group"); ok {
+		t.Error("unexpected group found")
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package osutil
+
+import (
+	"os/user"
+	"strconv"
+
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+// Looking up users and groups may mean reading and parsing /etc/passwd or
+// asking a directory service, which we don't want to do for every file
+// scanned or pulled. Lookups are cached, failed ones included, for the
+// lifetime of the process.
+var (
+	users = newOwnerCache(func(id int) (string, error) {
+		u, err := user.LookupId(strconv.Itoa(id))
+		if err != nil {
+			return "", err
+		}
+		return u.Username, nil
+	}, func(name string) (int, error) {
+		u, err := user.Lookup(name)
+		if err != nil {
+			return 0, err
+		}
+		return strconv.Atoi(u.Uid)
+	})
+
+	groups = newOwnerCache(func(id int) (string, error) {
+		g, err := user.LookupGroupId(strconv.Itoa(id))
+		if err != nil {
+			return "", err
+		}
+		return g.Name, nil
+	}, func(name string) (int, error) {
+		g, err := user.LookupGroup(name)
+		if err != nil {
+			return 0, err
+		}
+		return strconv.Atoi(g.Gid)
+	})
+)
+
+// UserName returns the name of the user with the given numeric ID.
+func UserName(uid int) (string, bool) {
+	return users.name(uid)
+}
+
+// UserID returns the numeric ID of the user with the given name.
+func UserID(name string) (int, bool) {
+	return users.id(name)
+}
+
+// GroupName returns the name of the group with the given numeric ID.
+func GroupName(gid int) (string, bool) {
+	return groups.name(gid)
+}
+
+// GroupID returns the numeric ID of the group with the given name.
+func GroupID(name string) (int, bool) {
+	return groups.id(name)
+}
+
+type ownerCache struct {
+	lookupName func(id int) (string, error)
+	lookupID   func(name string) (int, error)
+	names      map[int]string // "" when not found
+	ids        map[string]int // -1 when not found
+	mut        sync.Mutex
+}
+
+func newOwnerCache(lookupName func(int) (string, error), lookupID func(string) (int, error)) *ownerCache {
+	return &ownerCache{
+		lookupName: lookupName,
+		lookupID:   lookupID,
+		names:      make(map[int]string),
+		ids:        make(map[string]int),
+		mut:        sync.NewMutex(),
+	}
+}
+
+func (c *ownerCache) name(id int) (string, bool) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	name, ok := c.names[id]
+	if !ok {
+		var err error
+		if name, err = c.lookupName(id); err != nil {
+			name = ""
+		}
+		c.names[id] = name
+	}
+	return name, name != ""
+}
+
+func (c *ownerCache) id(name string) (int, bool) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	id, ok := c.ids[name]
+	if !ok {
+		var err error
+		if id, err = c.lookupID(name); err != nil || id < 0 {
+			id = -1
+		}
+		c.ids[name] = id
+	}
+	return id, id >= 0
+}
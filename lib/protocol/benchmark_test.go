@@ -174,7 +174,7 @@ func (m *fakeModel) IndexUpdate(deviceID DeviceID, folder string, files []FileIn
 	return nil
 }
 
-func (m *fakeModel) Request(deviceID DeviceID, folder, name string, size int32, offset int64, hash []byte, weakHash uint32, fromTemporary bool) (RequestResponse, error) {
+func (m *fakeModel) Request(deviceID DeviceID, folder, name string, size int32, offset int64, hash []byte, weakHash uint32, fromTemporary bool, priority RequestPriority) (RequestResponse, error) {
 	// We write the offset to the end of the buffer, so the receiver
 	// can verify that it did in fact get some data back over the
 	// connection.
@@ -193,3 +193,11 @@ func (m *fakeModel) Closed(conn Connection, err error) {
 func (m *fakeModel) DownloadProgress(deviceID DeviceID, folder string, updates []FileDownloadProgressUpdate) error {
 	return nil
 }
+
+func (m *fakeModel) ApplicationMessage(deviceID DeviceID, msgType string, data []byte) error {
+	return nil
+}
+
+func (m *fakeModel) ScanRequest(deviceID DeviceID, folder string, subdirs []string) error {
+	return nil
+}
@@ -26,36 +26,54 @@ const _ = proto.GoGoProtoPackageIsVersion3 // please upgrade the proto package
 type MessageType int32
 
 const (
-	messageTypeClusterConfig    MessageType = 0
-	messageTypeIndex            MessageType = 1
-	messageTypeIndexUpdate      MessageType = 2
-	messageTypeRequest          MessageType = 3
-	messageTypeResponse         MessageType = 4
-	messageTypeDownloadProgress MessageType = 5
-	messageTypePing             MessageType = 6
-	messageTypeClose            MessageType = 7
+	messageTypeClusterConfig      MessageType = 0
+	messageTypeIndex              MessageType = 1
+	messageTypeIndexUpdate        MessageType = 2
+	messageTypeRequest            MessageType = 3
+	messageTypeResponse           MessageType = 4
+	messageTypeDownloadProgress   MessageType = 5
+	messageTypePing               MessageType = 6
+	messageTypeClose              MessageType = 7
+	messageTypeBatchRequest       MessageType = 8
+	messageTypeBatchResponse      MessageType = 9
+	messageTypeApplicationMessage MessageType = 10
+	messageTypeScanRequest        MessageType = 11
+	messageTypeIndexSnapshot      MessageType = 12
+	messageTypeIndexAck           MessageType = 13
 )
 
 var MessageType_name = map[int32]string{
-	0: "CLUSTER_CONFIG",
-	1: "INDEX",
-	2: "INDEX_UPDATE",
-	3: "REQUEST",
-	4: "RESPONSE",
-	5: "DOWNLOAD_PROGRESS",
-	6: "PING",
-	7: "CLOSE",
+	0:  "CLUSTER_CONFIG",
+	1:  "INDEX",
+	2:  "INDEX_UPDATE",
+	3:  "REQUEST",
+	4:  "RESPONSE",
+	5:  "DOWNLOAD_PROGRESS",
+	6:  "PING",
+	7:  "CLOSE",
+	8:  "BATCH_REQUEST",
+	9:  "BATCH_RESPONSE",
+	10: "APPLICATION_MESSAGE",
+	11: "SCAN_REQUEST",
+	12: "INDEX_SNAPSHOT",
+	13: "INDEX_ACK",
 }
 
 var MessageType_value = map[string]int32{
-	"CLUSTER_CONFIG":    0,
-	"INDEX":             1,
-	"INDEX_UPDATE":      2,
-	"REQUEST":           3,
-	"RESPONSE":          4,
-	"DOWNLOAD_PROGRESS": 5,
-	"PING":              6,
-	"CLOSE":             7,
+	"CLUSTER_CONFIG":      0,
+	"INDEX":               1,
+	"INDEX_UPDATE":        2,
+	"REQUEST":             3,
+	"RESPONSE":            4,
+	"DOWNLOAD_PROGRESS":   5,
+	"PING":                6,
+	"CLOSE":               7,
+	"BATCH_REQUEST":       8,
+	"BATCH_RESPONSE":      9,
+	"APPLICATION_MESSAGE": 10,
+	"SCAN_REQUEST":        11,
+	"INDEX_SNAPSHOT":      12,
+	"INDEX_ACK":           13,
 }
 
 func (x MessageType) String() string {
@@ -71,16 +89,19 @@ type MessageCompression int32
 const (
 	MessageCompressionNone MessageCompression = 0
 	MessageCompressionLZ4  MessageCompression = 1
+	MessageCompressionZstd MessageCompression = 2
 )
 
 var MessageCompression_name = map[int32]string{
 	0: "NONE",
 	1: "LZ4",
+	2: "ZSTD",
 }
 
 var MessageCompression_value = map[string]int32{
 	"NONE": 0,
 	"LZ4":  1,
+	"ZSTD": 2,
 }
 
 func (x MessageCompression) String() string {
@@ -119,6 +140,85 @@ func (Compression) EnumDescriptor() ([]byte, []int) {
 	return fileDescriptor_e3f59eb60afbbc6e, []int{2}
 }
 
+type CompressionAlgorithm int32
+
+const (
+	CompressionAlgorithmLZ4  CompressionAlgorithm = 0
+	CompressionAlgorithmZstd CompressionAlgorithm = 1
+)
+
+var CompressionAlgorithm_name = map[int32]string{
+	0: "COMPRESSION_ALGORITHM_LZ4",
+	1: "COMPRESSION_ALGORITHM_ZSTD",
+}
+
+var CompressionAlgorithm_value = map[string]int32{
+	"COMPRESSION_ALGORITHM_LZ4":  0,
+	"COMPRESSION_ALGORITHM_ZSTD": 1,
+}
+
+func (x CompressionAlgorithm) String() string {
+	return proto.EnumName(CompressionAlgorithm_name, int32(x))
+}
+
+func (CompressionAlgorithm) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_e3f59eb60afbbc6e, []int{3}
+}
+
+// How a file is divided into blocks: at fixed intervals of the block size,
+// or at content defined boundaries, so that data inserted into a file only
+// changes the blocks around it.
+type BlockScheme int32
+
+const (
+	BlockSchemeFixed   BlockScheme = 0
+	BlockSchemeFastCDC BlockScheme = 1
+)
+
+var BlockScheme_name = map[int32]string{
+	0: "BLOCK_SCHEME_FIXED",
+	1: "BLOCK_SCHEME_FASTCDC",
+}
+
+var BlockScheme_value = map[string]int32{
+	"BLOCK_SCHEME_FIXED":   0,
+	"BLOCK_SCHEME_FASTCDC": 1,
+}
+
+func (x BlockScheme) String() string {
+	return proto.EnumName(BlockScheme_name, int32(x))
+}
+
+func (BlockScheme) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_e3f59eb60afbbc6e, []int{4}
+}
+
+// The algorithm by which the blocks of a file are hashed.
+type HashAlgorithm int32
+
+const (
+	HashAlgorithmSHA256 HashAlgorithm = 0
+	HashAlgorithmBLAKE3 HashAlgorithm = 1
+)
+
+var HashAlgorithm_name = map[int32]string{
+	0: "HASH_ALGORITHM_SHA256",
+	1: "HASH_ALGORITHM_BLAKE3",
+}
+
+var HashAlgorithm_value = map[string]int32{
+	"HASH_ALGORITHM_SHA256": 0,
+	"HASH_ALGORITHM_BLAKE3": 1,
+}
+
+func (x HashAlgorithm) String() string {
+	return proto.EnumName(HashAlgorithm_name, int32(x))
+}
+
+func (HashAlgorithm) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_e3f59eb60afbbc6e, []int{5}
+}
+
 type FileInfoType int32
 
 const (
@@ -150,7 +250,32 @@ func (x FileInfoType) String() string {
 }
 
 func (FileInfoType) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_e3f59eb60afbbc6e, []int{3}
+	return fileDescriptor_e3f59eb60afbbc6e, []int{6}
+}
+
+type RequestPriority int32
+
+const (
+	RequestPriorityNormal      RequestPriority = 0
+	RequestPriorityInteractive RequestPriority = 1
+)
+
+var RequestPriority_name = map[int32]string{
+	0: "REQUEST_PRIORITY_NORMAL",
+	1: "REQUEST_PRIORITY_INTERACTIVE",
+}
+
+var RequestPriority_value = map[string]int32{
+	"REQUEST_PRIORITY_NORMAL":      0,
+	"REQUEST_PRIORITY_INTERACTIVE": 1,
+}
+
+func (x RequestPriority) String() string {
+	return proto.EnumName(RequestPriority_name, int32(x))
+}
+
+func (RequestPriority) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_e3f59eb60afbbc6e, []int{7}
 }
 
 type ErrorCode int32
@@ -181,7 +306,7 @@ func (x ErrorCode) String() string {
 }
 
 func (ErrorCode) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_e3f59eb60afbbc6e, []int{4}
+	return fileDescriptor_e3f59eb60afbbc6e, []int{8}
 }
 
 type FileDownloadProgressUpdateType int32
@@ -206,13 +331,22 @@ func (x FileDownloadProgressUpdateType) String() string {
 }
 
 func (FileDownloadProgressUpdateType) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_e3f59eb60afbbc6e, []int{5}
+	return fileDescriptor_e3f59eb60afbbc6e, []int{9}
 }
 
 type Hello struct {
-	DeviceName    string `protobuf:"bytes,1,opt,name=device_name,json=deviceName,proto3" json:"device_name,omitempty"`
-	ClientName    string `protobuf:"bytes,2,opt,name=client_name,json=clientName,proto3" json:"client_name,omitempty"`
-	ClientVersion string `protobuf:"bytes,3,opt,name=client_version,json=clientVersion,proto3" json:"client_version,omitempty"`
+	DeviceName                  string                 `protobuf:"bytes,1,opt,name=device_name,json=deviceName,proto3" json:"device_name,omitempty"`
+	ClientName                  string                 `protobuf:"bytes,2,opt,name=client_name,json=clientName,proto3" json:"client_name,omitempty"`
+	ClientVersion               string                 `protobuf:"bytes,3,opt,name=client_version,json=clientVersion,proto3" json:"client_version,omitempty"`
+	SupportsBatchRequests       bool                   `protobuf:"varint,4,opt,name=supports_batch_requests,json=supportsBatchRequests,proto3" json:"supports_batch_requests,omitempty"`
+	CompressionAlgorithms       []CompressionAlgorithm `protobuf:"varint,5,rep,packed,name=compression_algorithms,json=compressionAlgorithms,proto3,enum=protocol.CompressionAlgorithm" json:"compression_algorithms,omitempty"`
+	SupportsMultipath           bool                   `protobuf:"varint,6,opt,name=supports_multipath,json=supportsMultipath,proto3" json:"supports_multipath,omitempty"`
+	SupportsPadding             bool                   `protobuf:"varint,7,opt,name=supports_padding,json=supportsPadding,proto3" json:"supports_padding,omitempty"`
+	SupportsApplicationMessages bool                   `protobuf:"varint,8,opt,name=supports_application_messages,json=supportsApplicationMessages,proto3" json:"supports_application_messages,omitempty"`
+	SupportsScanRequests        bool                   `protobuf:"varint,9,opt,name=supports_scan_requests,json=supportsScanRequests,proto3" json:"supports_scan_requests,omitempty"`
+	BlockSchemes                []BlockScheme          `protobuf:"varint,10,rep,packed,name=block_schemes,json=blockSchemes,proto3,enum=protocol.BlockScheme" json:"block_schemes,omitempty"`
+	SupportsIndexSnapshots      bool                   `protobuf:"varint,11,opt,name=supports_index_snapshots,json=supportsIndexSnapshots,proto3" json:"supports_index_snapshots,omitempty"`
+	IndexWindow                 int64                  `protobuf:"varint,12,opt,name=index_window,json=indexWindow,proto3" json:"index_window,omitempty"`
 }
 
 func (m *Hello) Reset()         { *m = Hello{} }
@@ -287,7 +421,9 @@ func (m *Header) XXX_DiscardUnknown() {
 var xxx_messageInfo_Header proto.InternalMessageInfo
 
 type ClusterConfig struct {
-	Folders []Folder `protobuf:"bytes,1,rep,name=folders,proto3" json:"folders"`
+	Folders        []Folder   `protobuf:"bytes,1,rep,name=folders,proto3" json:"folders"`
+	Secondary      bool       `protobuf:"varint,2,opt,name=secondary,proto3" json:"secondary,omitempty"`
+	Decommissioned []DeviceID `protobuf:"bytes,3,rep,name=decommissioned,proto3,customtype=DeviceID" json:"decommissioned"`
 }
 
 func (m *ClusterConfig) Reset()         { *m = ClusterConfig{} }
@@ -324,14 +460,25 @@ func (m *ClusterConfig) XXX_DiscardUnknown() {
 var xxx_messageInfo_ClusterConfig proto.InternalMessageInfo
 
 type Folder struct {
-	ID                 string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	Label              string   `protobuf:"bytes,2,opt,name=label,proto3" json:"label,omitempty"`
-	ReadOnly           bool     `protobuf:"varint,3,opt,name=read_only,json=readOnly,proto3" json:"read_only,omitempty"`
-	IgnorePermissions  bool     `protobuf:"varint,4,opt,name=ignore_permissions,json=ignorePermissions,proto3" json:"ignore_permissions,omitempty"`
-	IgnoreDelete       bool     `protobuf:"varint,5,opt,name=ignore_delete,json=ignoreDelete,proto3" json:"ignore_delete,omitempty"`
-	DisableTempIndexes bool     `protobuf:"varint,6,opt,name=disable_temp_indexes,json=disableTempIndexes,proto3" json:"disable_temp_indexes,omitempty"`
-	Paused             bool     `protobuf:"varint,7,opt,name=paused,proto3" json:"paused,omitempty"`
-	Devices            []Device `protobuf:"bytes,16,rep,name=devices,proto3" json:"devices"`
+	ID                 string          `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Label              string          `protobuf:"bytes,2,opt,name=label,proto3" json:"label,omitempty"`
+	GlobalBytes        int64           `protobuf:"varint,8,opt,name=global_bytes,json=globalBytes,proto3" json:"global_bytes,omitempty"`
+	GlobalFiles        int64           `protobuf:"varint,9,opt,name=global_files,json=globalFiles,proto3" json:"global_files,omitempty"`
+	ReadOnly           bool            `protobuf:"varint,3,opt,name=read_only,json=readOnly,proto3" json:"read_only,omitempty"`
+	IgnorePermissions  bool            `protobuf:"varint,4,opt,name=ignore_permissions,json=ignorePermissions,proto3" json:"ignore_permissions,omitempty"`
+	IgnoreDelete       bool            `protobuf:"varint,5,opt,name=ignore_delete,json=ignoreDelete,proto3" json:"ignore_delete,omitempty"`
+	DisableTempIndexes bool            `protobuf:"varint,6,opt,name=disable_temp_indexes,json=disableTempIndexes,proto3" json:"disable_temp_indexes,omitempty"`
+	Paused             bool            `protobuf:"varint,7,opt,name=paused,proto3" json:"paused,omitempty"`
+	Devices            []Device        `protobuf:"bytes,16,rep,name=devices,proto3" json:"devices"`
+	Settings           *FolderSettings `protobuf:"bytes,17,opt,name=settings,proto3" json:"settings,omitempty"`
+	SharedIgnores      []string        `protobuf:"bytes,18,rep,name=shared_ignores,json=sharedIgnores,proto3" json:"shared_ignores,omitempty"`
+	Subtrees           []string        `protobuf:"bytes,19,rep,name=subtrees,proto3" json:"subtrees,omitempty"`
+	IndexSummary       *IndexSummary   `protobuf:"bytes,20,opt,name=index_summary,json=indexSummary,proto3" json:"index_summary,omitempty"`
+	// The hash algorithms the device understands in files of the folder.
+	HashAlgorithms []HashAlgorithm `protobuf:"varint,21,rep,packed,name=hash_algorithms,json=hashAlgorithms,proto3,enum=protocol.HashAlgorithm" json:"hash_algorithms,omitempty"`
+	// How many days the device keeps deleted files of the folder after
+	// every device has the deletion; zero if it keeps them for good.
+	TombstoneRetentionDays int32 `protobuf:"varint,22,opt,name=tombstone_retention_days,json=tombstoneRetentionDays,proto3" json:"tombstone_retention_days,omitempty"`
 }
 
 func (m *Folder) Reset()         { *m = Folder{} }
@@ -367,6 +514,87 @@ func (m *Folder) XXX_DiscardUnknown() {
 
 var xxx_messageInfo_Folder proto.InternalMessageInfo
 
+// A summary of a device's index of a folder, to tell whether and roughly
+// where it differs from another.
+type IndexSummary struct {
+	Root    []byte   `protobuf:"bytes,1,opt,name=root,proto3" json:"root,omitempty"`
+	Buckets [][]byte `protobuf:"bytes,2,rep,name=buckets,proto3" json:"buckets,omitempty"`
+}
+
+func (m *IndexSummary) Reset()         { *m = IndexSummary{} }
+func (m *IndexSummary) String() string { return proto.CompactTextString(m) }
+func (*IndexSummary) ProtoMessage()    {}
+func (*IndexSummary) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e3f59eb60afbbc6e, []int{4}
+}
+func (m *IndexSummary) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *IndexSummary) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_IndexSummary.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *IndexSummary) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_IndexSummary.Merge(m, src)
+}
+func (m *IndexSummary) XXX_Size() int {
+	return m.ProtoSize()
+}
+func (m *IndexSummary) XXX_DiscardUnknown() {
+	xxx_messageInfo_IndexSummary.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_IndexSummary proto.InternalMessageInfo
+
+// The settings of a folder that a device pushes to the others, which may
+// adopt them.
+type FolderSettings struct {
+	IgnorePatterns   []string          `protobuf:"bytes,1,rep,name=ignore_patterns,json=ignorePatterns,proto3" json:"ignore_patterns,omitempty"`
+	VersioningType   string            `protobuf:"bytes,2,opt,name=versioning_type,json=versioningType,proto3" json:"versioning_type,omitempty"`
+	VersioningParams map[string]string `protobuf:"bytes,3,rep,name=versioning_params,json=versioningParams,proto3" json:"versioning_params,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (m *FolderSettings) Reset()         { *m = FolderSettings{} }
+func (m *FolderSettings) String() string { return proto.CompactTextString(m) }
+func (*FolderSettings) ProtoMessage()    {}
+func (*FolderSettings) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e3f59eb60afbbc6e, []int{5}
+}
+func (m *FolderSettings) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *FolderSettings) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_FolderSettings.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *FolderSettings) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FolderSettings.Merge(m, src)
+}
+func (m *FolderSettings) XXX_Size() int {
+	return m.ProtoSize()
+}
+func (m *FolderSettings) XXX_DiscardUnknown() {
+	xxx_messageInfo_FolderSettings.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FolderSettings proto.InternalMessageInfo
+
 type Device struct {
 	ID                       DeviceID    `protobuf:"bytes,1,opt,name=id,proto3,customtype=DeviceID" json:"id"`
 	Name                     string      `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
@@ -383,7 +611,7 @@ func (m *Device) Reset()         { *m = Device{} }
 func (m *Device) String() string { return proto.CompactTextString(m) }
 func (*Device) ProtoMessage()    {}
 func (*Device) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e3f59eb60afbbc6e, []int{4}
+	return fileDescriptor_e3f59eb60afbbc6e, []int{6}
 }
 func (m *Device) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -421,7 +649,7 @@ func (m *Index) Reset()         { *m = Index{} }
 func (m *Index) String() string { return proto.CompactTextString(m) }
 func (*Index) ProtoMessage()    {}
 func (*Index) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e3f59eb60afbbc6e, []int{5}
+	return fileDescriptor_e3f59eb60afbbc6e, []int{7}
 }
 func (m *Index) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -459,7 +687,7 @@ func (m *IndexUpdate) Reset()         { *m = IndexUpdate{} }
 func (m *IndexUpdate) String() string { return proto.CompactTextString(m) }
 func (*IndexUpdate) ProtoMessage()    {}
 func (*IndexUpdate) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e3f59eb60afbbc6e, []int{6}
+	return fileDescriptor_e3f59eb60afbbc6e, []int{8}
 }
 func (m *IndexUpdate) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -489,20 +717,27 @@ func (m *IndexUpdate) XXX_DiscardUnknown() {
 var xxx_messageInfo_IndexUpdate proto.InternalMessageInfo
 
 type FileInfo struct {
-	Name          string       `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	Size          int64        `protobuf:"varint,3,opt,name=size,proto3" json:"size,omitempty"`
-	ModifiedS     int64        `protobuf:"varint,5,opt,name=modified_s,json=modifiedS,proto3" json:"modified_s,omitempty"`
-	ModifiedBy    ShortID      `protobuf:"varint,12,opt,name=modified_by,json=modifiedBy,proto3,customtype=ShortID" json:"modified_by"`
-	Version       Vector       `protobuf:"bytes,9,opt,name=version,proto3" json:"version"`
-	Sequence      int64        `protobuf:"varint,10,opt,name=sequence,proto3" json:"sequence,omitempty"`
-	Blocks        []BlockInfo  `protobuf:"bytes,16,rep,name=Blocks,proto3" json:"Blocks"`
-	SymlinkTarget string       `protobuf:"bytes,17,opt,name=symlink_target,json=symlinkTarget,proto3" json:"symlink_target,omitempty"`
-	Type          FileInfoType `protobuf:"varint,2,opt,name=type,proto3,enum=protocol.FileInfoType" json:"type,omitempty"`
-	Permissions   uint32       `protobuf:"varint,4,opt,name=permissions,proto3" json:"permissions,omitempty"`
-	ModifiedNs    int32        `protobuf:"varint,11,opt,name=modified_ns,json=modifiedNs,proto3" json:"modified_ns,omitempty"`
-	RawBlockSize  int32        `protobuf:"varint,13,opt,name=block_size,json=blockSize,proto3" json:"block_size,omitempty"`
-	Gid           int32        `protobuf:"varint,18,opt,name=gid,proto3" json:"gid,omitempty"`
-	Uid           int32        `protobuf:"varint,19,opt,name=uid,proto3" json:"uid,omitempty"`
+	Name          string        `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Size          int64         `protobuf:"varint,3,opt,name=size,proto3" json:"size,omitempty"`
+	ModifiedS     int64         `protobuf:"varint,5,opt,name=modified_s,json=modifiedS,proto3" json:"modified_s,omitempty"`
+	ModifiedBy    ShortID       `protobuf:"varint,12,opt,name=modified_by,json=modifiedBy,proto3,customtype=ShortID" json:"modified_by"`
+	Version       Vector        `protobuf:"bytes,9,opt,name=version,proto3" json:"version"`
+	Sequence      int64         `protobuf:"varint,10,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	Blocks        []BlockInfo   `protobuf:"bytes,16,rep,name=Blocks,proto3" json:"Blocks"`
+	SymlinkTarget string        `protobuf:"bytes,17,opt,name=symlink_target,json=symlinkTarget,proto3" json:"symlink_target,omitempty"`
+	Xattrs        []Xattr       `protobuf:"bytes,20,rep,name=xattrs,proto3" json:"xattrs"`
+	OwnerName     string        `protobuf:"bytes,21,opt,name=owner_name,json=ownerName,proto3" json:"owner_name,omitempty"`
+	GroupName     string        `protobuf:"bytes,22,opt,name=group_name,json=groupName,proto3" json:"group_name,omitempty"`
+	Platform      PlatformData  `protobuf:"bytes,25,opt,name=platform,proto3" json:"platform"`
+	Holes         []FileHole    `protobuf:"bytes,26,rep,name=holes,proto3" json:"holes"`
+	Type          FileInfoType  `protobuf:"varint,2,opt,name=type,proto3,enum=protocol.FileInfoType" json:"type,omitempty"`
+	Permissions   uint32        `protobuf:"varint,4,opt,name=permissions,proto3" json:"permissions,omitempty"`
+	ModifiedNs    int32         `protobuf:"varint,11,opt,name=modified_ns,json=modifiedNs,proto3" json:"modified_ns,omitempty"`
+	RawBlockSize  int32         `protobuf:"varint,13,opt,name=block_size,json=blockSize,proto3" json:"block_size,omitempty"`
+	Gid           int32         `protobuf:"varint,18,opt,name=gid,proto3" json:"gid,omitempty"`
+	Uid           int32         `protobuf:"varint,19,opt,name=uid,proto3" json:"uid,omitempty"`
+	BlockScheme   BlockScheme   `protobuf:"varint,23,opt,name=block_scheme,json=blockScheme,proto3,enum=protocol.BlockScheme" json:"block_scheme,omitempty"`
+	HashAlgorithm HashAlgorithm `protobuf:"varint,24,opt,name=hash_algorithm,json=hashAlgorithm,proto3,enum=protocol.HashAlgorithm" json:"hash_algorithm,omitempty"`
 	// The local_flags fields stores flags that are relevant to the local
 	// host only. It is not part of the protocol, doesn't get sent or
 	// received (we make sure to zero it), nonetheless we need it on our
@@ -516,7 +751,7 @@ type FileInfo struct {
 func (m *FileInfo) Reset()      { *m = FileInfo{} }
 func (*FileInfo) ProtoMessage() {}
 func (*FileInfo) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e3f59eb60afbbc6e, []int{7}
+	return fileDescriptor_e3f59eb60afbbc6e, []int{9}
 }
 func (m *FileInfo) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -555,7 +790,7 @@ type BlockInfo struct {
 func (m *BlockInfo) Reset()      { *m = BlockInfo{} }
 func (*BlockInfo) ProtoMessage() {}
 func (*BlockInfo) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e3f59eb60afbbc6e, []int{8}
+	return fileDescriptor_e3f59eb60afbbc6e, []int{10}
 }
 func (m *BlockInfo) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -584,6 +819,198 @@ func (m *BlockInfo) XXX_DiscardUnknown() {
 
 var xxx_messageInfo_BlockInfo proto.InternalMessageInfo
 
+type Xattr struct {
+	Name  string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Value []byte `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+func (m *Xattr) Reset()         { *m = Xattr{} }
+func (m *Xattr) String() string { return proto.CompactTextString(m) }
+func (*Xattr) ProtoMessage()    {}
+func (*Xattr) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e3f59eb60afbbc6e, []int{11}
+}
+func (m *Xattr) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Xattr) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Xattr.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *Xattr) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Xattr.Merge(m, src)
+}
+func (m *Xattr) XXX_Size() int {
+	return m.ProtoSize()
+}
+func (m *Xattr) XXX_DiscardUnknown() {
+	xxx_messageInfo_Xattr.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Xattr proto.InternalMessageInfo
+
+// Metadata of a file that only some platforms have.
+type PlatformData struct {
+	Windows WindowsData `protobuf:"bytes,1,opt,name=windows,proto3" json:"windows"`
+}
+
+func (m *PlatformData) Reset()         { *m = PlatformData{} }
+func (m *PlatformData) String() string { return proto.CompactTextString(m) }
+func (*PlatformData) ProtoMessage()    {}
+func (*PlatformData) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e3f59eb60afbbc6e, []int{12}
+}
+func (m *PlatformData) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *PlatformData) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_PlatformData.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *PlatformData) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PlatformData.Merge(m, src)
+}
+func (m *PlatformData) XXX_Size() int {
+	return m.ProtoSize()
+}
+func (m *PlatformData) XXX_DiscardUnknown() {
+	xxx_messageInfo_PlatformData.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PlatformData proto.InternalMessageInfo
+
+type WindowsData struct {
+	Streams  []DataStream `protobuf:"bytes,1,rep,name=streams,proto3" json:"streams"`
+	Junction bool         `protobuf:"varint,2,opt,name=junction,proto3" json:"junction,omitempty"`
+}
+
+func (m *WindowsData) Reset()         { *m = WindowsData{} }
+func (m *WindowsData) String() string { return proto.CompactTextString(m) }
+func (*WindowsData) ProtoMessage()    {}
+func (*WindowsData) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e3f59eb60afbbc6e, []int{13}
+}
+func (m *WindowsData) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *WindowsData) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_WindowsData.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *WindowsData) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WindowsData.Merge(m, src)
+}
+func (m *WindowsData) XXX_Size() int {
+	return m.ProtoSize()
+}
+func (m *WindowsData) XXX_DiscardUnknown() {
+	xxx_messageInfo_WindowsData.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WindowsData proto.InternalMessageInfo
+
+// An alternate data stream of a file or directory on NTFS.
+type DataStream struct {
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Data []byte `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+}
+
+func (m *DataStream) Reset()         { *m = DataStream{} }
+func (m *DataStream) String() string { return proto.CompactTextString(m) }
+func (*DataStream) ProtoMessage()    {}
+func (*DataStream) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e3f59eb60afbbc6e, []int{14}
+}
+func (m *DataStream) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *DataStream) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_DataStream.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *DataStream) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DataStream.Merge(m, src)
+}
+func (m *DataStream) XXX_Size() int {
+	return m.ProtoSize()
+}
+func (m *DataStream) XXX_DiscardUnknown() {
+	xxx_messageInfo_DataStream.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DataStream proto.InternalMessageInfo
+
+// A range of a sparse file that takes no space on disk and reads as zeroes.
+type FileHole struct {
+	Offset int64 `protobuf:"varint,1,opt,name=offset,proto3" json:"offset,omitempty"`
+	Length int64 `protobuf:"varint,2,opt,name=length,proto3" json:"length,omitempty"`
+}
+
+func (m *FileHole) Reset()         { *m = FileHole{} }
+func (m *FileHole) String() string { return proto.CompactTextString(m) }
+func (*FileHole) ProtoMessage()    {}
+func (*FileHole) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e3f59eb60afbbc6e, []int{15}
+}
+func (m *FileHole) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *FileHole) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_FileHole.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *FileHole) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FileHole.Merge(m, src)
+}
+func (m *FileHole) XXX_Size() int {
+	return m.ProtoSize()
+}
+func (m *FileHole) XXX_DiscardUnknown() {
+	xxx_messageInfo_FileHole.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FileHole proto.InternalMessageInfo
+
 type Vector struct {
 	Counters []Counter `protobuf:"bytes,1,rep,name=counters,proto3" json:"counters"`
 }
@@ -592,7 +1019,7 @@ func (m *Vector) Reset()         { *m = Vector{} }
 func (m *Vector) String() string { return proto.CompactTextString(m) }
 func (*Vector) ProtoMessage()    {}
 func (*Vector) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e3f59eb60afbbc6e, []int{9}
+	return fileDescriptor_e3f59eb60afbbc6e, []int{16}
 }
 func (m *Vector) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -630,7 +1057,7 @@ func (m *Counter) Reset()         { *m = Counter{} }
 func (m *Counter) String() string { return proto.CompactTextString(m) }
 func (*Counter) ProtoMessage()    {}
 func (*Counter) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e3f59eb60afbbc6e, []int{10}
+	return fileDescriptor_e3f59eb60afbbc6e, []int{17}
 }
 func (m *Counter) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -660,21 +1087,22 @@ func (m *Counter) XXX_DiscardUnknown() {
 var xxx_messageInfo_Counter proto.InternalMessageInfo
 
 type Request struct {
-	ID            int32  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
-	Folder        string `protobuf:"bytes,2,opt,name=folder,proto3" json:"folder,omitempty"`
-	Name          string `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
-	Offset        int64  `protobuf:"varint,4,opt,name=offset,proto3" json:"offset,omitempty"`
-	Size          int32  `protobuf:"varint,5,opt,name=size,proto3" json:"size,omitempty"`
-	Hash          []byte `protobuf:"bytes,6,opt,name=hash,proto3" json:"hash,omitempty"`
-	FromTemporary bool   `protobuf:"varint,7,opt,name=from_temporary,json=fromTemporary,proto3" json:"from_temporary,omitempty"`
-	WeakHash      uint32 `protobuf:"varint,8,opt,name=weak_hash,json=weakHash,proto3" json:"weak_hash,omitempty"`
+	ID            int32           `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Folder        string          `protobuf:"bytes,2,opt,name=folder,proto3" json:"folder,omitempty"`
+	Name          string          `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	Offset        int64           `protobuf:"varint,4,opt,name=offset,proto3" json:"offset,omitempty"`
+	Size          int32           `protobuf:"varint,5,opt,name=size,proto3" json:"size,omitempty"`
+	Hash          []byte          `protobuf:"bytes,6,opt,name=hash,proto3" json:"hash,omitempty"`
+	FromTemporary bool            `protobuf:"varint,7,opt,name=from_temporary,json=fromTemporary,proto3" json:"from_temporary,omitempty"`
+	WeakHash      uint32          `protobuf:"varint,8,opt,name=weak_hash,json=weakHash,proto3" json:"weak_hash,omitempty"`
+	Priority      RequestPriority `protobuf:"varint,9,opt,name=priority,proto3,enum=protocol.RequestPriority" json:"priority,omitempty"`
 }
 
 func (m *Request) Reset()         { *m = Request{} }
 func (m *Request) String() string { return proto.CompactTextString(m) }
 func (*Request) ProtoMessage()    {}
 func (*Request) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e3f59eb60afbbc6e, []int{11}
+	return fileDescriptor_e3f59eb60afbbc6e, []int{18}
 }
 func (m *Request) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -713,7 +1141,7 @@ func (m *Response) Reset()         { *m = Response{} }
 func (m *Response) String() string { return proto.CompactTextString(m) }
 func (*Response) ProtoMessage()    {}
 func (*Response) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e3f59eb60afbbc6e, []int{12}
+	return fileDescriptor_e3f59eb60afbbc6e, []int{19}
 }
 func (m *Response) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -742,23 +1170,24 @@ func (m *Response) XXX_DiscardUnknown() {
 
 var xxx_messageInfo_Response proto.InternalMessageInfo
 
-type DownloadProgress struct {
-	Folder  string                       `protobuf:"bytes,1,opt,name=folder,proto3" json:"folder,omitempty"`
-	Updates []FileDownloadProgressUpdate `protobuf:"bytes,2,rep,name=updates,proto3" json:"updates"`
+type BatchRequest struct {
+	ID     int32              `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Folder string             `protobuf:"bytes,2,opt,name=folder,proto3" json:"folder,omitempty"`
+	Files  []BatchRequestFile `protobuf:"bytes,3,rep,name=files,proto3" json:"files"`
 }
 
-func (m *DownloadProgress) Reset()         { *m = DownloadProgress{} }
-func (m *DownloadProgress) String() string { return proto.CompactTextString(m) }
-func (*DownloadProgress) ProtoMessage()    {}
-func (*DownloadProgress) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e3f59eb60afbbc6e, []int{13}
+func (m *BatchRequest) Reset()         { *m = BatchRequest{} }
+func (m *BatchRequest) String() string { return proto.CompactTextString(m) }
+func (*BatchRequest) ProtoMessage()    {}
+func (*BatchRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e3f59eb60afbbc6e, []int{20}
 }
-func (m *DownloadProgress) XXX_Unmarshal(b []byte) error {
+func (m *BatchRequest) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
 }
-func (m *DownloadProgress) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+func (m *BatchRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
 	if deterministic {
-		return xxx_messageInfo_DownloadProgress.Marshal(b, m, deterministic)
+		return xxx_messageInfo_BatchRequest.Marshal(b, m, deterministic)
 	} else {
 		b = b[:cap(b)]
 		n, err := m.MarshalToSizedBuffer(b)
@@ -768,30 +1197,147 @@ func (m *DownloadProgress) XXX_Marshal(b []byte, deterministic bool) ([]byte, er
 		return b[:n], nil
 	}
 }
-func (m *DownloadProgress) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_DownloadProgress.Merge(m, src)
+func (m *BatchRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BatchRequest.Merge(m, src)
 }
-func (m *DownloadProgress) XXX_Size() int {
+func (m *BatchRequest) XXX_Size() int {
 	return m.ProtoSize()
 }
-func (m *DownloadProgress) XXX_DiscardUnknown() {
-	xxx_messageInfo_DownloadProgress.DiscardUnknown(m)
+func (m *BatchRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_BatchRequest.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_DownloadProgress proto.InternalMessageInfo
+var xxx_messageInfo_BatchRequest proto.InternalMessageInfo
 
-type FileDownloadProgressUpdate struct {
-	UpdateType   FileDownloadProgressUpdateType `protobuf:"varint,1,opt,name=update_type,json=updateType,proto3,enum=protocol.FileDownloadProgressUpdateType" json:"update_type,omitempty"`
-	Name         string                         `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
-	Version      Vector                         `protobuf:"bytes,3,opt,name=version,proto3" json:"version"`
-	BlockIndexes []int32                        `protobuf:"varint,4,rep,name=block_indexes,json=blockIndexes,proto3" json:"block_indexes,omitempty"`
+type BatchRequestFile struct {
+	Name     string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Size     int32  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	Hash     []byte `protobuf:"bytes,3,opt,name=hash,proto3" json:"hash,omitempty"`
+	WeakHash uint32 `protobuf:"varint,4,opt,name=weak_hash,json=weakHash,proto3" json:"weak_hash,omitempty"`
 }
 
-func (m *FileDownloadProgressUpdate) Reset()         { *m = FileDownloadProgressUpdate{} }
-func (m *FileDownloadProgressUpdate) String() string { return proto.CompactTextString(m) }
-func (*FileDownloadProgressUpdate) ProtoMessage()    {}
-func (*FileDownloadProgressUpdate) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e3f59eb60afbbc6e, []int{14}
+func (m *BatchRequestFile) Reset()         { *m = BatchRequestFile{} }
+func (m *BatchRequestFile) String() string { return proto.CompactTextString(m) }
+func (*BatchRequestFile) ProtoMessage()    {}
+func (*BatchRequestFile) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e3f59eb60afbbc6e, []int{21}
+}
+func (m *BatchRequestFile) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *BatchRequestFile) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_BatchRequestFile.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *BatchRequestFile) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BatchRequestFile.Merge(m, src)
+}
+func (m *BatchRequestFile) XXX_Size() int {
+	return m.ProtoSize()
+}
+func (m *BatchRequestFile) XXX_DiscardUnknown() {
+	xxx_messageInfo_BatchRequestFile.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BatchRequestFile proto.InternalMessageInfo
+
+type BatchResponse struct {
+	ID    int32       `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Data  []byte      `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	Codes []ErrorCode `protobuf:"varint,3,rep,packed,name=codes,proto3,enum=protocol.ErrorCode" json:"codes,omitempty"`
+}
+
+func (m *BatchResponse) Reset()         { *m = BatchResponse{} }
+func (m *BatchResponse) String() string { return proto.CompactTextString(m) }
+func (*BatchResponse) ProtoMessage()    {}
+func (*BatchResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e3f59eb60afbbc6e, []int{22}
+}
+func (m *BatchResponse) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *BatchResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_BatchResponse.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *BatchResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BatchResponse.Merge(m, src)
+}
+func (m *BatchResponse) XXX_Size() int {
+	return m.ProtoSize()
+}
+func (m *BatchResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_BatchResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BatchResponse proto.InternalMessageInfo
+
+type DownloadProgress struct {
+	Folder  string                       `protobuf:"bytes,1,opt,name=folder,proto3" json:"folder,omitempty"`
+	Updates []FileDownloadProgressUpdate `protobuf:"bytes,2,rep,name=updates,proto3" json:"updates"`
+}
+
+func (m *DownloadProgress) Reset()         { *m = DownloadProgress{} }
+func (m *DownloadProgress) String() string { return proto.CompactTextString(m) }
+func (*DownloadProgress) ProtoMessage()    {}
+func (*DownloadProgress) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e3f59eb60afbbc6e, []int{23}
+}
+func (m *DownloadProgress) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *DownloadProgress) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_DownloadProgress.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *DownloadProgress) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DownloadProgress.Merge(m, src)
+}
+func (m *DownloadProgress) XXX_Size() int {
+	return m.ProtoSize()
+}
+func (m *DownloadProgress) XXX_DiscardUnknown() {
+	xxx_messageInfo_DownloadProgress.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DownloadProgress proto.InternalMessageInfo
+
+type FileDownloadProgressUpdate struct {
+	UpdateType   FileDownloadProgressUpdateType `protobuf:"varint,1,opt,name=update_type,json=updateType,proto3,enum=protocol.FileDownloadProgressUpdateType" json:"update_type,omitempty"`
+	Name         string                         `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Version      Vector                         `protobuf:"bytes,3,opt,name=version,proto3" json:"version"`
+	BlockIndexes []int32                        `protobuf:"varint,4,rep,name=block_indexes,json=blockIndexes,proto3" json:"block_indexes,omitempty"`
+}
+
+func (m *FileDownloadProgressUpdate) Reset()         { *m = FileDownloadProgressUpdate{} }
+func (m *FileDownloadProgressUpdate) String() string { return proto.CompactTextString(m) }
+func (*FileDownloadProgressUpdate) ProtoMessage()    {}
+func (*FileDownloadProgressUpdate) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e3f59eb60afbbc6e, []int{24}
 }
 func (m *FileDownloadProgressUpdate) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -827,7 +1373,7 @@ func (m *Ping) Reset()         { *m = Ping{} }
 func (m *Ping) String() string { return proto.CompactTextString(m) }
 func (*Ping) ProtoMessage()    {}
 func (*Ping) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e3f59eb60afbbc6e, []int{15}
+	return fileDescriptor_e3f59eb60afbbc6e, []int{25}
 }
 func (m *Ping) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -864,7 +1410,7 @@ func (m *Close) Reset()         { *m = Close{} }
 func (m *Close) String() string { return proto.CompactTextString(m) }
 func (*Close) ProtoMessage()    {}
 func (*Close) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e3f59eb60afbbc6e, []int{16}
+	return fileDescriptor_e3f59eb60afbbc6e, []int{26}
 }
 func (m *Close) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -893,151 +1439,401 @@ func (m *Close) XXX_DiscardUnknown() {
 
 var xxx_messageInfo_Close proto.InternalMessageInfo
 
+type ApplicationMessage struct {
+	Type string `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Data []byte `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+}
+
+func (m *ApplicationMessage) Reset()         { *m = ApplicationMessage{} }
+func (m *ApplicationMessage) String() string { return proto.CompactTextString(m) }
+func (*ApplicationMessage) ProtoMessage()    {}
+func (*ApplicationMessage) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e3f59eb60afbbc6e, []int{27}
+}
+func (m *ApplicationMessage) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *ApplicationMessage) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_ApplicationMessage.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *ApplicationMessage) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ApplicationMessage.Merge(m, src)
+}
+func (m *ApplicationMessage) XXX_Size() int {
+	return m.ProtoSize()
+}
+func (m *ApplicationMessage) XXX_DiscardUnknown() {
+	xxx_messageInfo_ApplicationMessage.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ApplicationMessage proto.InternalMessageInfo
+
+type ScanRequest struct {
+	Folder  string   `protobuf:"bytes,1,opt,name=folder,proto3" json:"folder,omitempty"`
+	Subdirs []string `protobuf:"bytes,2,rep,name=subdirs,proto3" json:"subdirs,omitempty"`
+}
+
+func (m *ScanRequest) Reset()         { *m = ScanRequest{} }
+func (m *ScanRequest) String() string { return proto.CompactTextString(m) }
+func (*ScanRequest) ProtoMessage()    {}
+func (*ScanRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e3f59eb60afbbc6e, []int{28}
+}
+func (m *ScanRequest) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *ScanRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_ScanRequest.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *ScanRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ScanRequest.Merge(m, src)
+}
+func (m *ScanRequest) XXX_Size() int {
+	return m.ProtoSize()
+}
+func (m *ScanRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ScanRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ScanRequest proto.InternalMessageInfo
+
+type IndexSnapshot struct {
+	Folder string `protobuf:"bytes,1,opt,name=folder,proto3" json:"folder,omitempty"`
+	Data   []byte `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	First  bool   `protobuf:"varint,3,opt,name=first,proto3" json:"first,omitempty"`
+}
+
+func (m *IndexSnapshot) Reset()         { *m = IndexSnapshot{} }
+func (m *IndexSnapshot) String() string { return proto.CompactTextString(m) }
+func (*IndexSnapshot) ProtoMessage()    {}
+func (*IndexSnapshot) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e3f59eb60afbbc6e, []int{29}
+}
+func (m *IndexSnapshot) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *IndexSnapshot) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_IndexSnapshot.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *IndexSnapshot) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_IndexSnapshot.Merge(m, src)
+}
+func (m *IndexSnapshot) XXX_Size() int {
+	return m.ProtoSize()
+}
+func (m *IndexSnapshot) XXX_DiscardUnknown() {
+	xxx_messageInfo_IndexSnapshot.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_IndexSnapshot proto.InternalMessageInfo
+
+type IndexAck struct {
+	Size int64 `protobuf:"varint,1,opt,name=size,proto3" json:"size,omitempty"`
+}
+
+func (m *IndexAck) Reset()         { *m = IndexAck{} }
+func (m *IndexAck) String() string { return proto.CompactTextString(m) }
+func (*IndexAck) ProtoMessage()    {}
+func (*IndexAck) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e3f59eb60afbbc6e, []int{30}
+}
+func (m *IndexAck) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *IndexAck) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_IndexAck.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *IndexAck) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_IndexAck.Merge(m, src)
+}
+func (m *IndexAck) XXX_Size() int {
+	return m.ProtoSize()
+}
+func (m *IndexAck) XXX_DiscardUnknown() {
+	xxx_messageInfo_IndexAck.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_IndexAck proto.InternalMessageInfo
+
 func init() {
 	proto.RegisterEnum("protocol.MessageType", MessageType_name, MessageType_value)
 	proto.RegisterEnum("protocol.MessageCompression", MessageCompression_name, MessageCompression_value)
 	proto.RegisterEnum("protocol.Compression", Compression_name, Compression_value)
+	proto.RegisterEnum("protocol.CompressionAlgorithm", CompressionAlgorithm_name, CompressionAlgorithm_value)
+	proto.RegisterEnum("protocol.BlockScheme", BlockScheme_name, BlockScheme_value)
+	proto.RegisterEnum("protocol.HashAlgorithm", HashAlgorithm_name, HashAlgorithm_value)
 	proto.RegisterEnum("protocol.FileInfoType", FileInfoType_name, FileInfoType_value)
+	proto.RegisterEnum("protocol.RequestPriority", RequestPriority_name, RequestPriority_value)
 	proto.RegisterEnum("protocol.ErrorCode", ErrorCode_name, ErrorCode_value)
 	proto.RegisterEnum("protocol.FileDownloadProgressUpdateType", FileDownloadProgressUpdateType_name, FileDownloadProgressUpdateType_value)
 	proto.RegisterType((*Hello)(nil), "protocol.Hello")
 	proto.RegisterType((*Header)(nil), "protocol.Header")
 	proto.RegisterType((*ClusterConfig)(nil), "protocol.ClusterConfig")
 	proto.RegisterType((*Folder)(nil), "protocol.Folder")
+	proto.RegisterType((*IndexSummary)(nil), "protocol.IndexSummary")
+	proto.RegisterType((*FolderSettings)(nil), "protocol.FolderSettings")
+	proto.RegisterMapType((map[string]string)(nil), "protocol.FolderSettings.VersioningParamsEntry")
 	proto.RegisterType((*Device)(nil), "protocol.Device")
 	proto.RegisterType((*Index)(nil), "protocol.Index")
 	proto.RegisterType((*IndexUpdate)(nil), "protocol.IndexUpdate")
 	proto.RegisterType((*FileInfo)(nil), "protocol.FileInfo")
 	proto.RegisterType((*BlockInfo)(nil), "protocol.BlockInfo")
+	proto.RegisterType((*Xattr)(nil), "protocol.Xattr")
+	proto.RegisterType((*PlatformData)(nil), "protocol.PlatformData")
+	proto.RegisterType((*WindowsData)(nil), "protocol.WindowsData")
+	proto.RegisterType((*DataStream)(nil), "protocol.DataStream")
+	proto.RegisterType((*FileHole)(nil), "protocol.FileHole")
 	proto.RegisterType((*Vector)(nil), "protocol.Vector")
 	proto.RegisterType((*Counter)(nil), "protocol.Counter")
 	proto.RegisterType((*Request)(nil), "protocol.Request")
 	proto.RegisterType((*Response)(nil), "protocol.Response")
+	proto.RegisterType((*BatchRequest)(nil), "protocol.BatchRequest")
+	proto.RegisterType((*BatchRequestFile)(nil), "protocol.BatchRequestFile")
+	proto.RegisterType((*BatchResponse)(nil), "protocol.BatchResponse")
 	proto.RegisterType((*DownloadProgress)(nil), "protocol.DownloadProgress")
 	proto.RegisterType((*FileDownloadProgressUpdate)(nil), "protocol.FileDownloadProgressUpdate")
 	proto.RegisterType((*Ping)(nil), "protocol.Ping")
 	proto.RegisterType((*Close)(nil), "protocol.Close")
+	proto.RegisterType((*ApplicationMessage)(nil), "protocol.ApplicationMessage")
+	proto.RegisterType((*ScanRequest)(nil), "protocol.ScanRequest")
+	proto.RegisterType((*IndexSnapshot)(nil), "protocol.IndexSnapshot")
+	proto.RegisterType((*IndexAck)(nil), "protocol.IndexAck")
 }
 
 func init() { proto.RegisterFile("bep.proto", fileDescriptor_e3f59eb60afbbc6e) }
 
 var fileDescriptor_e3f59eb60afbbc6e = []byte{
-	// 1825 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x56, 0xcf, 0x6f, 0xdb, 0xc8,
-	0x15, 0x16, 0x25, 0xea, 0xd7, 0x93, 0xec, 0xa5, 0x27, 0x89, 0xcb, 0x32, 0x59, 0x89, 0x51, 0x92,
-	0x8d, 0xd6, 0xd8, 0x26, 0xe9, 0xee, 0xb6, 0x45, 0x8b, 0xb6, 0x80, 0x7e, 0xd0, 0x8e, 0x50, 0x47,
-	0x72, 0x47, 0x72, 0xb6, 0xd9, 0x43, 0x09, 0x5a, 0x1c, 0xc9, 0x44, 0x28, 0x8e, 0x4a, 0x52, 0x76,
-	0xb4, 0xc7, 0x1e, 0x75, 0xea, 0xb1, 0x17, 0x01, 0x0b, 0xf4, 0xd4, 0xff, 0x24, 0xc7, 0xb4, 0x87,
-	0xa2, 0xe8, 0xc1, 0xe8, 0x3a, 0x97, 0x3d, 0xf6, 0x2f, 0x28, 0x8a, 0x99, 0x21, 0x25, 0xca, 0xde,
-	0x2c, 0x72, 0xe8, 0x89, 0x33, 0xef, 0x7d, 0xf3, 0x66, 0xe6, 0x7b, 0xef, 0x7d, 0x43, 0x28, 0x9e,
-	0x90, 0xe9, 0xa3, 0xa9, 0x4f, 0x43, 0x8a, 0x0a, 0xfc, 0x33, 0xa4, 0xae, 0x76, 0xcf, 0x27, 0x53,
-	0x1a, 0x3c, 0xe6, 0xf3, 0x93, 0xd9, 0xe8, 0xf1, 0x98, 0x8e, 0x29, 0x9f, 0xf0, 0x91, 0x80, 0xd7,
-	0xa6, 0x90, 0x7d, 0x4a, 0x5c, 0x97, 0xa2, 0x2a, 0x94, 0x6c, 0x72, 0xe6, 0x0c, 0x89, 0xe9, 0x59,
-	0x13, 0xa2, 0x4a, 0xba, 0x54, 0x2f, 0x62, 0x10, 0xa6, 0xae, 0x35, 0x21, 0x0c, 0x30, 0x74, 0x1d,
-	0xe2, 0x85, 0x02, 0x90, 0x16, 0x00, 0x61, 0xe2, 0x80, 0x07, 0xb0, 0x1d, 0x01, 0xce, 0x88, 0x1f,
-	0x38, 0xd4, 0x53, 0x33, 0x1c, 0xb3, 0x25, 0xac, 0xcf, 0x85, 0xb1, 0x16, 0x40, 0xee, 0x29, 0xb1,
-	0x6c, 0xe2, 0xa3, 0x8f, 0x41, 0x0e, 0xe7, 0x53, 0xb1, 0xd7, 0xf6, 0xa7, 0xb7, 0x1e, 0xc5, 0x27,
-	0x7f, 0xf4, 0x8c, 0x04, 0x81, 0x35, 0x26, 0x83, 0xf9, 0x94, 0x60, 0x0e, 0x41, 0xbf, 0x86, 0xd2,
-	0x90, 0x4e, 0xa6, 0x3e, 0x09, 0x78, 0xe0, 0x34, 0x5f, 0x71, 0xe7, 0xda, 0x8a, 0xd6, 0x1a, 0x83,
-	0x93, 0x0b, 0x6a, 0x0d, 0xd8, 0x6a, 0xb9, 0xb3, 0x20, 0x24, 0x7e, 0x8b, 0x7a, 0x23, 0x67, 0x8c,
-	0x9e, 0x40, 0x7e, 0x44, 0x5d, 0x9b, 0xf8, 0x81, 0x2a, 0xe9, 0x99, 0x7a, 0xe9, 0x53, 0x65, 0x1d,
-	0x6c, 0x9f, 0x3b, 0x9a, 0xf2, 0xeb, 0x8b, 0x6a, 0x0a, 0xc7, 0xb0, 0xda, 0x5f, 0xd2, 0x90, 0x13,
-	0x1e, 0xb4, 0x0b, 0x69, 0xc7, 0x16, 0x14, 0x35, 0x73, 0x97, 0x17, 0xd5, 0x74, 0xa7, 0x8d, 0xd3,
-	0x8e, 0x8d, 0x6e, 0x42, 0xd6, 0xb5, 0x4e, 0x88, 0x1b, 0x91, 0x23, 0x26, 0xe8, 0x36, 0x14, 0x7d,
-	0x62, 0xd9, 0x26, 0xf5, 0xdc, 0x39, 0xa7, 0xa4, 0x80, 0x0b, 0xcc, 0xd0, 0xf3, 0xdc, 0x39, 0xfa,
-	0x11, 0x20, 0x67, 0xec, 0x51, 0x9f, 0x98, 0x53, 0xe2, 0x4f, 0x1c, 0x7e, 0xda, 0x40, 0x95, 0x39,
-	0x6a, 0x47, 0x78, 0x8e, 0xd6, 0x0e, 0x74, 0x0f, 0xb6, 0x22, 0xb8, 0x4d, 0x5c, 0x12, 0x12, 0x35,
-	0xcb, 0x91, 0x65, 0x61, 0x6c, 0x73, 0x1b, 0x7a, 0x02, 0x37, 0x6d, 0x27, 0xb0, 0x4e, 0x5c, 0x62,
-	0x86, 0x64, 0x32, 0x35, 0x1d, 0xcf, 0x26, 0xaf, 0x48, 0xa0, 0xe6, 0x38, 0x16, 0x45, 0xbe, 0x01,
-	0x99, 0x4c, 0x3b, 0xc2, 0x83, 0x76, 0x21, 0x37, 0xb5, 0x66, 0x01, 0xb1, 0xd5, 0x3c, 0xc7, 0x44,
-	0x33, 0xc6, 0x92, 0xa8, 0x80, 0x40, 0x55, 0xae, 0xb2, 0xd4, 0xe6, 0x8e, 0x98, 0xa5, 0x08, 0x56,
-	0xfb, 0x4f, 0x1a, 0x72, 0xc2, 0x83, 0x3e, 0x5a, 0xb1, 0x54, 0x6e, 0xee, 0x32, 0xd4, 0xbf, 0x2e,
-	0xaa, 0x05, 0xe1, 0xeb, 0xb4, 0x13, 0xac, 0x21, 0x90, 0x13, 0x15, 0xc5, 0xc7, 0xe8, 0x0e, 0x14,
-	0x2d, 0xdb, 0x66, 0xd9, 0x23, 0x81, 0x9a, 0xd1, 0x33, 0xf5, 0x22, 0x5e, 0x1b, 0xd0, 0xcf, 0x36,
-	0xab, 0x41, 0xbe, 0x5a, 0x3f, 0xef, 0x2a, 0x03, 0x96, 0x8a, 0x21, 0xf1, 0xa3, 0x0a, 0xce, 0xf2,
-	0xfd, 0x0a, 0xcc, 0xc0, 0xeb, 0xf7, 0x2e, 0x94, 0x27, 0xd6, 0x2b, 0x33, 0x20, 0x7f, 0x98, 0x11,
-	0x6f, 0x48, 0x38, 0x5d, 0x19, 0x5c, 0x9a, 0x58, 0xaf, 0xfa, 0x91, 0x09, 0x55, 0x00, 0x1c, 0x2f,
-	0xf4, 0xa9, 0x3d, 0x1b, 0x12, 0x3f, 0xe2, 0x2a, 0x61, 0x41, 0x3f, 0x81, 0x02, 0x27, 0xdb, 0x74,
-	0x6c, 0xb5, 0xa0, 0x4b, 0x75, 0xb9, 0xa9, 0x45, 0x17, 0xcf, 0x73, 0xaa, 0xf9, 0xbd, 0xe3, 0x21,
-	0xce, 0x73, 0x6c, 0xc7, 0x46, 0xbf, 0x04, 0x2d, 0x78, 0xe9, 0xb0, 0x44, 0x89, 0x48, 0xa1, 0x43,
-	0x3d, 0xd3, 0x27, 0x13, 0x7a, 0x66, 0xb9, 0x81, 0x5a, 0xe4, 0xdb, 0xa8, 0x0c, 0xd1, 0x49, 0x00,
-	0x70, 0xe4, 0xaf, 0xf5, 0x20, 0xcb, 0x23, 0xb2, 0x2c, 0x8a, 0x62, 0x8d, 0xba, 0x37, 0x9a, 0xa1,
-	0x47, 0x90, 0x1d, 0x39, 0x2e, 0x09, 0xd4, 0x34, 0xcf, 0x21, 0x4a, 0x54, 0xba, 0xe3, 0x92, 0x8e,
-	0x37, 0xa2, 0x51, 0x16, 0x05, 0xac, 0x76, 0x0c, 0x25, 0x1e, 0xf0, 0x78, 0x6a, 0x5b, 0x21, 0xf9,
-	0xbf, 0x85, 0xfd, 0x63, 0x16, 0x0a, 0xb1, 0x67, 0x95, 0x74, 0x29, 0x91, 0x74, 0x04, 0x72, 0xe0,
-	0x7c, 0x45, 0x78, 0x8f, 0x64, 0x30, 0x1f, 0xa3, 0x0f, 0x01, 0x26, 0xd4, 0x76, 0x46, 0x0e, 0xb1,
-	0xcd, 0x80, 0xa7, 0x2c, 0x83, 0x8b, 0xb1, 0xa5, 0x8f, 0x9e, 0x40, 0x69, 0xe5, 0x3e, 0x99, 0xab,
-	0x65, 0xce, 0xf9, 0x07, 0x31, 0xe7, 0xfd, 0x53, 0xea, 0x87, 0x9d, 0x36, 0x5e, 0x85, 0x68, 0xce,
-	0x59, 0x49, 0xc7, 0xf2, 0xc4, 0x88, 0xdd, 0x28, 0xe9, 0xe7, 0x64, 0x18, 0xd2, 0x55, 0xe3, 0x47,
-	0x30, 0xa4, 0x41, 0x61, 0x55, 0x13, 0xc0, 0x0f, 0xb0, 0x9a, 0xa3, 0x1f, 0x43, 0xae, 0xe9, 0xd2,
-	0xe1, 0xcb, 0xb8, 0x3f, 0x6e, 0xac, 0x83, 0x71, 0x7b, 0x82, 0x85, 0x08, 0xc8, 0x64, 0x32, 0x98,
-	0x4f, 0x5c, 0xc7, 0x7b, 0x69, 0x86, 0x96, 0x3f, 0x26, 0xa1, 0xba, 0x23, 0x64, 0x32, 0xb2, 0x0e,
-	0xb8, 0x11, 0xed, 0x45, 0xe2, 0x28, 0xa4, 0x6e, 0xf7, 0x3a, 0xb9, 0x09, 0x75, 0xd4, 0xa1, 0x74,
-	0x55, 0x3d, 0xb6, 0x70, 0xd2, 0xc4, 0xc4, 0x7b, 0xc5, 0x93, 0x17, 0xa8, 0x25, 0x5d, 0xaa, 0x67,
-	0xd7, 0xb4, 0x74, 0x03, 0xf4, 0x18, 0xe0, 0x84, 0x9d, 0xcf, 0xe4, 0x19, 0xd8, 0x62, 0xfe, 0xa6,
-	0x72, 0x79, 0x51, 0x2d, 0x63, 0xeb, 0x9c, 0x1f, 0xbc, 0xef, 0x7c, 0x45, 0x70, 0xf1, 0x24, 0x1e,
-	0x22, 0x05, 0x32, 0x63, 0xc7, 0x56, 0x11, 0x8f, 0xc4, 0x86, 0xcc, 0x32, 0x73, 0x6c, 0xf5, 0x86,
-	0xb0, 0xcc, 0x1c, 0x9b, 0x9d, 0xcb, 0xa5, 0x43, 0xcb, 0x35, 0x47, 0xae, 0x35, 0x0e, 0xd4, 0x6f,
-	0xf3, 0xfc, 0x60, 0xc0, 0x6d, 0xfb, 0xcc, 0x84, 0x54, 0x26, 0x30, 0x4c, 0xb4, 0xec, 0x48, 0x9d,
-	0xe2, 0x29, 0xaa, 0x43, 0xde, 0xf1, 0xce, 0x2c, 0xd7, 0x89, 0x34, 0xa9, 0xb9, 0x7d, 0x79, 0x51,
-	0x05, 0x6c, 0x9d, 0x77, 0x84, 0x15, 0xc7, 0x6e, 0x46, 0xa8, 0x47, 0x37, 0xe4, 0xb3, 0xc0, 0x43,
-	0x6d, 0x79, 0x34, 0x21, 0x9d, 0xbf, 0x90, 0xff, 0xfc, 0x75, 0x35, 0x55, 0xf3, 0xa0, 0xb8, 0x4a,
-	0x0c, 0x2b, 0xb8, 0x53, 0x2b, 0x38, 0xe5, 0x05, 0x57, 0xc6, 0x7c, 0xcc, 0xaa, 0x9d, 0x8e, 0x46,
-	0x01, 0x09, 0x79, 0x69, 0x66, 0x70, 0x34, 0x5b, 0x15, 0x67, 0x9a, 0x5f, 0x4f, 0x14, 0xe7, 0x6d,
-	0x28, 0x9e, 0x13, 0xeb, 0xa5, 0xc9, 0x83, 0x08, 0xd6, 0x0b, 0xcc, 0xf0, 0xd4, 0x0a, 0x4e, 0xa3,
-	0xfd, 0x7e, 0x05, 0x39, 0x51, 0x55, 0xe8, 0x33, 0x28, 0x0c, 0xe9, 0xcc, 0x0b, 0xd7, 0x4f, 0xce,
-	0x4e, 0x52, 0xb1, 0xb8, 0x27, 0x2a, 0x95, 0x15, 0xb0, 0xb6, 0x0f, 0xf9, 0xc8, 0x85, 0x1e, 0xac,
-	0xe4, 0x54, 0x6e, 0xde, 0xba, 0x52, 0xe1, 0x9b, 0x6f, 0xd0, 0x99, 0xe5, 0xce, 0xc4, 0x41, 0x65,
-	0x2c, 0x26, 0xb5, 0xbf, 0x49, 0x90, 0xc7, 0xac, 0x68, 0x83, 0x30, 0xf1, 0x7a, 0x65, 0x37, 0x5e,
-	0xaf, 0x75, 0x9f, 0xa7, 0x37, 0xfa, 0x3c, 0x6e, 0xd5, 0x4c, 0xa2, 0x55, 0xd7, 0x2c, 0xc9, 0xdf,
-	0xc9, 0x52, 0x36, 0xc1, 0x52, 0xcc, 0x72, 0x2e, 0xc1, 0xf2, 0x03, 0xd8, 0x1e, 0xf9, 0x74, 0xc2,
-	0xdf, 0x27, 0xea, 0x5b, 0xfe, 0x3c, 0x12, 0xd3, 0x2d, 0x66, 0x1d, 0xc4, 0xc6, 0x4d, 0x82, 0x0b,
-	0x9b, 0x04, 0xd7, 0x4c, 0x28, 0x60, 0x12, 0x4c, 0xa9, 0x17, 0x90, 0x77, 0xde, 0x09, 0x81, 0x6c,
-	0x5b, 0xa1, 0xc5, 0x6f, 0x54, 0xc6, 0x7c, 0x8c, 0x1e, 0x82, 0x3c, 0xa4, 0xb6, 0xb8, 0xcf, 0x76,
-	0xb2, 0x63, 0x0d, 0xdf, 0xa7, 0x7e, 0x8b, 0xda, 0x04, 0x73, 0x40, 0x6d, 0x0a, 0x4a, 0x9b, 0x9e,
-	0x7b, 0x2e, 0xb5, 0xec, 0x23, 0x9f, 0x8e, 0xd9, 0x23, 0xf2, 0x4e, 0x31, 0x6c, 0x43, 0x7e, 0xc6,
-	0xe5, 0x32, 0x96, 0xc3, 0xfb, 0x9b, 0x1d, 0x7b, 0x35, 0x90, 0xd0, 0xd6, 0x58, 0x6a, 0xa2, 0xa5,
-	0xb5, 0x7f, 0x48, 0xa0, 0xbd, 0x1b, 0x8d, 0x3a, 0x50, 0x12, 0x48, 0x33, 0xf1, 0xdf, 0x54, 0x7f,
-	0x9f, 0x8d, 0xb8, 0x58, 0xc0, 0x6c, 0x35, 0xfe, 0xce, 0x47, 0x37, 0x21, 0x8d, 0x99, 0xf7, 0x93,
-	0xc6, 0x87, 0xb0, 0x25, 0x54, 0x23, 0xfe, 0xc5, 0x90, 0xf5, 0x4c, 0x3d, 0xdb, 0x4c, 0x2b, 0x29,
-	0x5c, 0x3e, 0x11, 0x6d, 0xc6, 0xed, 0xb5, 0x1c, 0xc8, 0x47, 0x8e, 0x37, 0xae, 0x55, 0x21, 0xdb,
-	0x72, 0x29, 0x4f, 0x58, 0xce, 0x27, 0x56, 0x40, 0xbd, 0x98, 0x47, 0x31, 0xdb, 0xfb, 0x7b, 0x1a,
-	0x4a, 0x89, 0xdf, 0x3f, 0xf4, 0x04, 0xb6, 0x5b, 0x87, 0xc7, 0xfd, 0x81, 0x81, 0xcd, 0x56, 0xaf,
-	0xbb, 0xdf, 0x39, 0x50, 0x52, 0xda, 0x9d, 0xc5, 0x52, 0x57, 0x27, 0x6b, 0xd0, 0xe6, 0x9f, 0x5d,
-	0x15, 0xb2, 0x9d, 0x6e, 0xdb, 0xf8, 0x9d, 0x22, 0x69, 0x37, 0x17, 0x4b, 0x5d, 0x49, 0x00, 0xc5,
-	0x33, 0xf9, 0x09, 0x94, 0x39, 0xc0, 0x3c, 0x3e, 0x6a, 0x37, 0x06, 0x86, 0x92, 0xd6, 0xb4, 0xc5,
-	0x52, 0xdf, 0xbd, 0x8a, 0x8b, 0x38, 0xbf, 0x07, 0x79, 0x6c, 0xfc, 0xf6, 0xd8, 0xe8, 0x0f, 0x94,
-	0x8c, 0xb6, 0xbb, 0x58, 0xea, 0x28, 0x01, 0x8c, 0x5b, 0xea, 0x01, 0x14, 0xb0, 0xd1, 0x3f, 0xea,
-	0x75, 0xfb, 0x86, 0x22, 0x6b, 0x3f, 0x58, 0x2c, 0xf5, 0x1b, 0x1b, 0xa8, 0xa8, 0x4a, 0x7f, 0x0a,
-	0x3b, 0xed, 0xde, 0x17, 0xdd, 0xc3, 0x5e, 0xa3, 0x6d, 0x1e, 0xe1, 0xde, 0x01, 0x36, 0xfa, 0x7d,
-	0x25, 0xab, 0x55, 0x17, 0x4b, 0xfd, 0x76, 0x02, 0x7f, 0xad, 0xe8, 0x3e, 0x04, 0xf9, 0xa8, 0xd3,
-	0x3d, 0x50, 0x72, 0xda, 0x8d, 0xc5, 0x52, 0xff, 0x20, 0x01, 0x65, 0xa4, 0xb2, 0x1b, 0xb7, 0x0e,
-	0x7b, 0x7d, 0x43, 0xc9, 0x5f, 0xbb, 0x31, 0x27, 0x7b, 0xef, 0xf7, 0x80, 0xae, 0xff, 0x20, 0xa3,
-	0xfb, 0x20, 0x77, 0x7b, 0x5d, 0x43, 0x49, 0x89, 0xfb, 0x5f, 0x47, 0x74, 0xa9, 0x47, 0x50, 0x0d,
-	0x32, 0x87, 0x5f, 0x7e, 0xae, 0x48, 0xda, 0x0f, 0x17, 0x4b, 0xfd, 0xd6, 0x75, 0xd0, 0xe1, 0x97,
-	0x9f, 0xef, 0x51, 0x28, 0x25, 0x03, 0xd7, 0xa0, 0xf0, 0xcc, 0x18, 0x34, 0xda, 0x8d, 0x41, 0x43,
-	0x49, 0x89, 0x23, 0xc5, 0xee, 0x67, 0x24, 0xb4, 0x78, 0x13, 0xde, 0x81, 0x6c, 0xd7, 0x78, 0x6e,
-	0x60, 0x45, 0xd2, 0x76, 0x16, 0x4b, 0x7d, 0x2b, 0x06, 0x74, 0xc9, 0x19, 0xf1, 0x51, 0x05, 0x72,
-	0x8d, 0xc3, 0x2f, 0x1a, 0x2f, 0xfa, 0x4a, 0x5a, 0x43, 0x8b, 0xa5, 0xbe, 0x1d, 0xbb, 0x1b, 0xee,
-	0xb9, 0x35, 0x0f, 0xf6, 0xfe, 0x2b, 0x41, 0x39, 0xf9, 0x0e, 0xa2, 0x0a, 0xc8, 0xfb, 0x9d, 0x43,
-	0x23, 0xde, 0x2e, 0xe9, 0x63, 0x63, 0x54, 0x87, 0x62, 0xbb, 0x83, 0x8d, 0xd6, 0xa0, 0x87, 0x5f,
-	0xc4, 0x77, 0x49, 0x82, 0xda, 0x8e, 0xcf, 0x0b, 0x7c, 0x8e, 0x7e, 0x0e, 0xe5, 0xfe, 0x8b, 0x67,
-	0x87, 0x9d, 0xee, 0x6f, 0x4c, 0x1e, 0x31, 0xad, 0x3d, 0x5c, 0x2c, 0xf5, 0xbb, 0x1b, 0x60, 0x32,
-	0xf5, 0xc9, 0xd0, 0x0a, 0x89, 0xdd, 0x17, 0x4f, 0x36, 0x73, 0x16, 0x24, 0xd4, 0x82, 0x9d, 0x78,
-	0xe9, 0x7a, 0xb3, 0x8c, 0xf6, 0xc9, 0x62, 0xa9, 0x7f, 0xf4, 0xbd, 0xeb, 0x57, 0xbb, 0x17, 0x24,
-	0x74, 0x1f, 0xf2, 0x51, 0x90, 0xb8, 0x92, 0x92, 0x4b, 0xa3, 0x05, 0x7b, 0x7f, 0x95, 0xa0, 0xb8,
-	0x92, 0x2b, 0x46, 0x78, 0xb7, 0x67, 0x1a, 0x18, 0xf7, 0x70, 0xcc, 0xc0, 0xca, 0xd9, 0xa5, 0x7c,
-	0x88, 0xee, 0x42, 0xfe, 0xc0, 0xe8, 0x1a, 0xb8, 0xd3, 0x8a, 0x1b, 0x63, 0x05, 0x39, 0x20, 0x1e,
-	0xf1, 0x9d, 0x21, 0xfa, 0x18, 0xca, 0xdd, 0x9e, 0xd9, 0x3f, 0x6e, 0x3d, 0x8d, 0xaf, 0xce, 0xf7,
-	0x4f, 0x84, 0xea, 0xcf, 0x86, 0xa7, 0x9c, 0xcf, 0x3d, 0xd6, 0x43, 0xcf, 0x1b, 0x87, 0x9d, 0xb6,
-	0x80, 0x66, 0x34, 0x75, 0xb1, 0xd4, 0x6f, 0xae, 0xa0, 0xd1, 0x23, 0xcd, 0xb0, 0x7b, 0x36, 0x54,
-	0xbe, 0x5f, 0x98, 0x90, 0x0e, 0xb9, 0xc6, 0xd1, 0x91, 0xd1, 0x6d, 0xc7, 0xa7, 0x5f, 0xfb, 0x1a,
-	0xd3, 0x29, 0xf1, 0xd8, 0x9f, 0x44, 0x6e, 0xbf, 0x87, 0x0f, 0x8c, 0x41, 0x7c, 0xf8, 0x35, 0x62,
-	0x9f, 0xb2, 0xff, 0xa5, 0x66, 0xfd, 0xf5, 0x37, 0x95, 0xd4, 0x9b, 0x6f, 0x2a, 0xa9, 0xd7, 0x97,
-	0x15, 0xe9, 0xcd, 0x65, 0x45, 0xfa, 0xf7, 0x65, 0x25, 0xf5, 0xed, 0x65, 0x45, 0xfa, 0xd3, 0xdb,
-	0x4a, 0xea, 0xeb, 0xb7, 0x15, 0xe9, 0xcd, 0xdb, 0x4a, 0xea, 0x9f, 0x6f, 0x2b, 0xa9, 0x93, 0x1c,
-	0x17, 0xb5, 0xcf, 0xfe, 0x17, 0x00, 0x00, 0xff, 0xff, 0x24, 0x1a, 0xcb, 0xca, 0x35, 0x0f, 0x00,
-	0x00,
+	// 3101 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x59, 0xcb, 0x6f, 0x1b, 0xd7,
+	0xb9, 0xd7, 0xf0, 0xcd, 0x8f, 0x0f, 0x8f, 0x8e, 0x25, 0x79, 0x4c, 0xdb, 0x14, 0xcd, 0xc4, 0xb1,
+	0x2c, 0x38, 0x8e, 0xa3, 0x38, 0xbe, 0xbe, 0xbe, 0xbe, 0xb9, 0xe1, 0x4b, 0x12, 0x61, 0x89, 0x64,
+	0x86, 0xb4, 0x13, 0xfb, 0x2e, 0x06, 0x43, 0xce, 0x11, 0x35, 0xd5, 0x70, 0x86, 0x9d, 0x19, 0x4a,
+	0x66, 0xd6, 0x59, 0x14, 0xec, 0x26, 0xcb, 0x16, 0x05, 0x81, 0x6c, 0xfb, 0x9f, 0x64, 0x19, 0xa0,
+	0x40, 0x51, 0x74, 0x61, 0x34, 0xf6, 0x26, 0x8b, 0x2e, 0xba, 0xec, 0xaa, 0x28, 0xce, 0x39, 0x73,
+	0x86, 0x43, 0x8a, 0x32, 0xd2, 0xa2, 0x2b, 0x9e, 0xf3, 0x7d, 0xbf, 0xef, 0x3c, 0xbe, 0xf7, 0x1c,
+	0x42, 0xb2, 0x8b, 0x87, 0xf7, 0x86, 0xb6, 0xe5, 0x5a, 0x28, 0x41, 0x7f, 0x7a, 0x96, 0x91, 0x7b,
+	0xcf, 0xc6, 0x43, 0xcb, 0xf9, 0x88, 0xce, 0xbb, 0xa3, 0xa3, 0x8f, 0xfa, 0x56, 0xdf, 0xa2, 0x13,
+	0x3a, 0x62, 0xf0, 0xe2, 0x5f, 0x23, 0x10, 0xdd, 0xc7, 0x86, 0x61, 0xa1, 0x4d, 0x48, 0x69, 0xf8,
+	0x54, 0xef, 0x61, 0xc5, 0x54, 0x07, 0x58, 0x12, 0x0a, 0xc2, 0x56, 0x52, 0x06, 0x46, 0x6a, 0xa8,
+	0x03, 0x4c, 0x00, 0x3d, 0x43, 0xc7, 0xa6, 0xcb, 0x00, 0x21, 0x06, 0x60, 0x24, 0x0a, 0xb8, 0x05,
+	0x59, 0x0f, 0x70, 0x8a, 0x6d, 0x47, 0xb7, 0x4c, 0x29, 0x4c, 0x31, 0x19, 0x46, 0x7d, 0xce, 0x88,
+	0xe8, 0x21, 0x5c, 0x71, 0x46, 0xc3, 0xa1, 0x65, 0xbb, 0x8e, 0xd2, 0x55, 0xdd, 0xde, 0xb1, 0x62,
+	0xe3, 0x5f, 0x8e, 0xb0, 0xe3, 0x3a, 0x52, 0xa4, 0x20, 0x6c, 0x25, 0xe4, 0x75, 0xce, 0x2e, 0x13,
+	0xae, 0xec, 0x31, 0xd1, 0x33, 0xd8, 0xe8, 0x59, 0x83, 0xa1, 0x8d, 0x1d, 0xb2, 0x8c, 0xa2, 0x1a,
+	0x7d, 0xcb, 0xd6, 0xdd, 0xe3, 0x81, 0x23, 0x45, 0x0b, 0xe1, 0xad, 0xec, 0x4e, 0xfe, 0x1e, 0xbf,
+	0xfa, 0xbd, 0xca, 0x0c, 0x57, 0xe2, 0x30, 0x79, 0xbd, 0xb7, 0x84, 0xea, 0xa0, 0x0f, 0x01, 0xf9,
+	0xc7, 0x19, 0x8c, 0x0c, 0x57, 0x1f, 0xaa, 0xee, 0xb1, 0x14, 0xa3, 0x27, 0x59, 0xe5, 0x9c, 0x43,
+	0xce, 0x40, 0x77, 0x40, 0xf4, 0xe1, 0x43, 0x55, 0xd3, 0x74, 0xb3, 0x2f, 0xc5, 0x29, 0xf8, 0x12,
+	0xa7, 0xb7, 0x18, 0x19, 0x95, 0xe1, 0x86, 0x0f, 0x55, 0x87, 0x43, 0x43, 0xef, 0xa9, 0x2e, 0x39,
+	0xf9, 0x00, 0x3b, 0x8e, 0xda, 0xc7, 0x8e, 0x94, 0xa0, 0x72, 0xd7, 0x38, 0xa8, 0x34, 0xc3, 0x1c,
+	0x7a, 0x10, 0xf4, 0x00, 0x36, 0xfc, 0x35, 0x9c, 0x9e, 0x6a, 0xce, 0x74, 0x95, 0xa4, 0xc2, 0x6b,
+	0x9c, 0xdb, 0xee, 0xa9, 0xa6, 0xaf, 0xaa, 0xc7, 0x90, 0xe9, 0x1a, 0x56, 0xef, 0x44, 0x71, 0x7a,
+	0xc7, 0x78, 0x80, 0x1d, 0x09, 0xa8, 0x86, 0xd6, 0x67, 0x1a, 0x2a, 0x13, 0x76, 0x9b, 0x72, 0xe5,
+	0x74, 0x77, 0x36, 0x71, 0xd0, 0x23, 0x90, 0xfc, 0x1d, 0x75, 0x53, 0xc3, 0xaf, 0x14, 0xc7, 0x54,
+	0x87, 0xce, 0xb1, 0xe5, 0x3a, 0x52, 0x8a, 0xee, 0xe9, 0x9f, 0xa8, 0x4e, 0xd8, 0x6d, 0xce, 0x45,
+	0x37, 0x21, 0xcd, 0x04, 0xce, 0x74, 0x53, 0xb3, 0xce, 0xa4, 0x74, 0x41, 0xd8, 0x0a, 0xcb, 0x29,
+	0x4a, 0xfb, 0x92, 0x92, 0x8a, 0x0e, 0xc4, 0xf6, 0xb1, 0xaa, 0x61, 0x1b, 0xdd, 0x81, 0x88, 0x3b,
+	0x1e, 0x32, 0x3f, 0x9b, 0x3b, 0x99, 0x77, 0xf5, 0xce, 0x78, 0x88, 0x65, 0x0a, 0x41, 0x9f, 0x41,
+	0x2a, 0x60, 0x3a, 0xea, 0x78, 0xd9, 0x9d, 0xeb, 0xe7, 0x24, 0x02, 0x46, 0x97, 0x83, 0x02, 0xc5,
+	0xdf, 0x0a, 0x90, 0xa9, 0x18, 0x23, 0xc7, 0xc5, 0x76, 0xc5, 0x32, 0x8f, 0xf4, 0x3e, 0xba, 0x0f,
+	0xf1, 0x23, 0xcb, 0xd0, 0xb0, 0xed, 0x48, 0x42, 0x21, 0xbc, 0x95, 0xda, 0x11, 0x67, 0xab, 0xed,
+	0x52, 0x46, 0x39, 0xf2, 0xfd, 0xeb, 0xcd, 0x15, 0x99, 0xc3, 0xd0, 0x75, 0x48, 0x3a, 0xb8, 0x67,
+	0x99, 0x9a, 0x6a, 0x8f, 0xe9, 0x09, 0x12, 0xf2, 0x8c, 0x80, 0x1e, 0x41, 0x56, 0xc3, 0x3d, 0x6b,
+	0x30, 0xd0, 0xe9, 0x8e, 0x58, 0x93, 0xc2, 0x85, 0xf0, 0x56, 0xba, 0x2c, 0x92, 0x45, 0xfe, 0xfc,
+	0x7a, 0x33, 0x51, 0xa5, 0x61, 0x54, 0xaf, 0xca, 0x0b, 0xb8, 0xe2, 0xef, 0xa2, 0x10, 0x63, 0x3b,
+	0xa2, 0x0d, 0x08, 0xe9, 0x1a, 0x8b, 0xbb, 0x72, 0xec, 0xcd, 0xeb, 0xcd, 0x50, 0xbd, 0x2a, 0x87,
+	0x74, 0x0d, 0xad, 0x41, 0xd4, 0x50, 0xbb, 0xd8, 0xf0, 0x22, 0x8e, 0x4d, 0x88, 0xb2, 0xfb, 0x86,
+	0xd5, 0x55, 0x0d, 0xa5, 0x3b, 0x76, 0x3d, 0x5f, 0x0a, 0xcb, 0x29, 0x46, 0x2b, 0x13, 0x52, 0x00,
+	0x72, 0xa4, 0x1b, 0x98, 0x79, 0x8c, 0x0f, 0xd9, 0x25, 0x24, 0x74, 0x0d, 0x92, 0x36, 0x56, 0x35,
+	0xc5, 0x32, 0x8d, 0x31, 0x8d, 0xd6, 0x84, 0x9c, 0x20, 0x84, 0xa6, 0x69, 0x8c, 0x49, 0x64, 0xe8,
+	0x7d, 0xd3, 0xb2, 0xb1, 0x32, 0xc4, 0xb6, 0x77, 0x64, 0x1e, 0xa3, 0xab, 0x8c, 0xd3, 0x9a, 0x31,
+	0xd0, 0x7b, 0x90, 0xf1, 0xe0, 0x1a, 0x36, 0xb0, 0x8b, 0xa5, 0x28, 0x45, 0xa6, 0x19, 0xb1, 0x4a,
+	0x69, 0xe8, 0x3e, 0xac, 0x69, 0xba, 0xa3, 0x76, 0x0d, 0xac, 0xb8, 0x78, 0x30, 0x64, 0x1e, 0x86,
+	0x1d, 0x2f, 0xde, 0x90, 0xc7, 0xeb, 0xe0, 0xc1, 0xb0, 0xce, 0x38, 0x68, 0x03, 0x62, 0x43, 0x75,
+	0xe4, 0x60, 0xcd, 0x0b, 0x33, 0x6f, 0x46, 0x6c, 0xc8, 0x92, 0x93, 0x23, 0x89, 0x8b, 0x36, 0x64,
+	0xea, 0xe6, 0x36, 0xf4, 0x60, 0xe8, 0x01, 0x24, 0x1c, 0xec, 0xba, 0xba, 0xd9, 0x77, 0xa4, 0xd5,
+	0x82, 0xb0, 0x95, 0xda, 0x91, 0x16, 0xcd, 0xde, 0xf6, 0xf8, 0xb2, 0x8f, 0x24, 0x59, 0xcd, 0x39,
+	0x56, 0x6d, 0xac, 0x29, 0xec, 0x22, 0x8e, 0x84, 0x0a, 0x61, 0x92, 0xd5, 0x18, 0xb5, 0xce, 0x88,
+	0x28, 0x07, 0x09, 0x67, 0xd4, 0x75, 0x6d, 0x8c, 0x1d, 0xe9, 0x32, 0x05, 0xf8, 0x73, 0xf4, 0x3f,
+	0x90, 0xf1, 0x22, 0x69, 0x34, 0x18, 0x10, 0x07, 0x5a, 0xa3, 0xbb, 0x6f, 0xcc, 0x76, 0x67, 0x91,
+	0xc4, 0xb8, 0x72, 0x5a, 0x0f, 0xcc, 0xd0, 0xe7, 0x70, 0xe9, 0x58, 0x75, 0x8e, 0x83, 0xf9, 0x6e,
+	0x9d, 0x46, 0xf3, 0x95, 0x99, 0xf8, 0xbe, 0xea, 0x1c, 0xcf, 0x12, 0x5d, 0xf6, 0x38, 0x38, 0xa5,
+	0x11, 0xed, 0x5a, 0x83, 0xae, 0xe3, 0x5a, 0x26, 0x56, 0x6c, 0xec, 0x62, 0x93, 0xa6, 0x21, 0x4d,
+	0x1d, 0x3b, 0xd2, 0x46, 0x41, 0xd8, 0x8a, 0xca, 0x1b, 0x3e, 0x5f, 0xe6, 0xec, 0xaa, 0x3a, 0x76,
+	0x8a, 0x4f, 0x20, 0x1d, 0x3c, 0x19, 0x42, 0x10, 0xb1, 0x2d, 0xcb, 0xa5, 0x4e, 0x9a, 0x96, 0xe9,
+	0x18, 0x49, 0x10, 0xef, 0x8e, 0x7a, 0x27, 0xd8, 0x75, 0xa4, 0x10, 0x71, 0x7a, 0x99, 0x4f, 0x8b,
+	0xdf, 0x84, 0x20, 0x3b, 0xaf, 0x56, 0x74, 0x1b, 0x2e, 0x71, 0x97, 0x52, 0x5d, 0x17, 0xdb, 0x26,
+	0x0b, 0xc0, 0xa4, 0x9c, 0xf5, 0xfc, 0xc9, 0xa3, 0x12, 0xa0, 0x57, 0x44, 0x74, 0xb3, 0xaf, 0xd0,
+	0x4c, 0xc1, 0xdc, 0x3f, 0x3b, 0x23, 0x93, 0x14, 0x81, 0xfe, 0x1f, 0x56, 0x03, 0xc0, 0xa1, 0x6a,
+	0xab, 0x03, 0x87, 0x46, 0x5f, 0x6a, 0xe7, 0xde, 0x45, 0xd6, 0xbd, 0xf7, 0xdc, 0x97, 0x68, 0x51,
+	0x81, 0x9a, 0xe9, 0xda, 0x63, 0x59, 0x3c, 0x5d, 0x20, 0xe7, 0x2a, 0xb0, 0xbe, 0x14, 0x8a, 0x44,
+	0x08, 0x9f, 0xe0, 0xb1, 0x57, 0x24, 0xc9, 0x90, 0x44, 0xe9, 0xa9, 0x6a, 0x8c, 0xf8, 0x31, 0xd9,
+	0xe4, 0x71, 0xe8, 0x91, 0x50, 0xfc, 0x5b, 0x08, 0x62, 0xcc, 0x21, 0xd1, 0x07, 0x7e, 0x88, 0xa7,
+	0xcb, 0x1b, 0x8b, 0xb9, 0x21, 0x10, 0xf2, 0x08, 0x22, 0x81, 0x1a, 0x4b, 0xc7, 0x24, 0x03, 0xa9,
+	0x9a, 0x46, 0x72, 0x1a, 0x66, 0x17, 0x4c, 0xca, 0x33, 0x02, 0xfa, 0xaf, 0xf9, 0x1c, 0x19, 0x59,
+	0xcc, 0xaa, 0x17, 0x25, 0x47, 0x92, 0x01, 0x7a, 0xd8, 0xf6, 0x6a, 0x7a, 0x94, 0xee, 0x97, 0x20,
+	0x04, 0x5a, 0xd1, 0x6f, 0x42, 0x7a, 0xa0, 0xbe, 0x52, 0x1c, 0x52, 0x57, 0xcc, 0x1e, 0xa6, 0x51,
+	0x1a, 0x96, 0x53, 0x03, 0xf5, 0x55, 0xdb, 0x23, 0xa1, 0x3c, 0x80, 0x6e, 0xba, 0xb6, 0xa5, 0x8d,
+	0x7a, 0xd8, 0xf6, 0x42, 0x34, 0x40, 0x41, 0x9f, 0x42, 0x82, 0xf9, 0xbe, 0xae, 0xd1, 0x1c, 0x15,
+	0x29, 0xe7, 0xbc, 0x8b, 0xc7, 0xa9, 0x6b, 0xd1, 0x7b, 0xf3, 0xa1, 0x1c, 0xa7, 0xd8, 0xba, 0x86,
+	0x9e, 0x40, 0xce, 0x39, 0xd1, 0x87, 0x0a, 0x5f, 0x89, 0x7a, 0xac, 0x8d, 0x07, 0xd6, 0xa9, 0x6a,
+	0xf0, 0xda, 0x27, 0x11, 0x44, 0x3d, 0x00, 0x90, 0x3d, 0x7e, 0xb1, 0x09, 0x51, 0xba, 0x22, 0x49,
+	0x1e, 0x2c, 0x83, 0x7b, 0xa6, 0xf2, 0x66, 0xe8, 0x1e, 0x44, 0x59, 0x4e, 0x0c, 0x51, 0x4f, 0x41,
+	0x01, 0x4f, 0xd1, 0x0d, 0x5c, 0x37, 0x8f, 0x2c, 0x2f, 0x79, 0x30, 0x58, 0xf1, 0x19, 0xa4, 0xe8,
+	0x82, 0xcf, 0x86, 0x9a, 0xea, 0xe2, 0xff, 0xd8, 0xb2, 0x7f, 0x88, 0x43, 0x82, 0x73, 0x7c, 0xa3,
+	0x0b, 0x01, 0xa3, 0x23, 0x88, 0x38, 0xfa, 0xd7, 0x98, 0xa6, 0xe6, 0xb0, 0x4c, 0xc7, 0xe8, 0x06,
+	0xc0, 0xc0, 0xd2, 0xf4, 0x23, 0x1d, 0x6b, 0x8a, 0x43, 0x4d, 0x16, 0x96, 0x93, 0x9c, 0xd2, 0x46,
+	0xf7, 0x21, 0xe5, 0xb3, 0xbb, 0x63, 0x5a, 0x84, 0x23, 0xe5, 0x4b, 0x5c, 0xe7, 0xed, 0x63, 0xcb,
+	0x76, 0xeb, 0x55, 0xd9, 0x5f, 0xa2, 0x3c, 0x26, 0x99, 0x94, 0x37, 0x6c, 0xc9, 0x82, 0x30, 0x9f,
+	0x49, 0x9f, 0xe3, 0x9e, 0x6b, 0xf9, 0xd5, 0xd0, 0x83, 0xd1, 0x64, 0xc7, 0x7d, 0x02, 0xe8, 0x01,
+	0xfc, 0x39, 0xfa, 0x18, 0x62, 0xb4, 0xb9, 0xe0, 0x69, 0xf9, 0xf2, 0x42, 0xd3, 0x11, 0xd0, 0x82,
+	0x07, 0xa4, 0x29, 0x76, 0x3c, 0x30, 0x74, 0xf3, 0x44, 0x71, 0x55, 0xbb, 0x8f, 0x5d, 0x9a, 0x9e,
+	0x49, 0x8a, 0x65, 0xd4, 0x0e, 0x25, 0xa2, 0x0f, 0x21, 0xf6, 0x4a, 0x75, 0x5d, 0xdb, 0x91, 0xd6,
+	0xe8, 0xca, 0x97, 0x66, 0x2b, 0x7f, 0x45, 0xe8, 0x7c, 0x55, 0x06, 0x22, 0x7a, 0xb2, 0xce, 0x4c,
+	0x6c, 0x33, 0xd7, 0x5e, 0xa7, 0x2b, 0x26, 0x29, 0x85, 0xfa, 0xf6, 0x0d, 0x80, 0xbe, 0x6d, 0x8d,
+	0x86, 0x8c, 0xbd, 0xc1, 0xd8, 0x94, 0x42, 0xd9, 0x8f, 0x20, 0x31, 0x34, 0x54, 0xf7, 0xc8, 0xb2,
+	0x07, 0xd2, 0xd5, 0xc5, 0x74, 0xdd, 0xf2, 0x38, 0x55, 0xd5, 0x55, 0xbd, 0x5d, 0x7d, 0x34, 0x71,
+	0x82, 0x63, 0x8b, 0x38, 0x41, 0x6e, 0x99, 0x13, 0xec, 0x5b, 0x06, 0x2f, 0x4c, 0x0c, 0x86, 0xb6,
+	0xbd, 0x4e, 0x88, 0xf5, 0x35, 0x1b, 0xe7, 0x7d, 0x26, 0xd0, 0x0a, 0x15, 0x20, 0xb5, 0x58, 0x8b,
+	0x33, 0x72, 0x90, 0x44, 0xba, 0x74, 0xdf, 0xfc, 0x26, 0xeb, 0xd8, 0xa2, 0x33, 0x6b, 0x37, 0x1c,
+	0xf4, 0x11, 0x80, 0xd7, 0x1b, 0x12, 0xc7, 0xca, 0x10, 0x7e, 0x59, 0x7c, 0xf3, 0x7a, 0x33, 0x2d,
+	0xab, 0x67, 0xac, 0x2b, 0xd4, 0xbf, 0xc6, 0x72, 0xb2, 0xcb, 0x87, 0x24, 0xd7, 0xf5, 0x75, 0x4d,
+	0x42, 0x74, 0x25, 0x32, 0x24, 0x94, 0x91, 0xae, 0x49, 0x97, 0x19, 0x65, 0xa4, 0x6b, 0xe8, 0x11,
+	0xa4, 0x83, 0x0d, 0xa7, 0x74, 0x65, 0x31, 0xff, 0x04, 0xfb, 0xcd, 0x54, 0xa0, 0xdf, 0x44, 0x9f,
+	0x41, 0x76, 0xbe, 0xbc, 0x49, 0x52, 0x41, 0x78, 0x57, 0x75, 0xcb, 0xcc, 0x55, 0x37, 0xa2, 0x11,
+	0xc3, 0xea, 0x91, 0x1e, 0xc7, 0x50, 0xfb, 0x8e, 0xf4, 0x53, 0x9c, 0xaa, 0x04, 0x28, 0x6d, 0x97,
+	0x90, 0x48, 0x81, 0x62, 0x0d, 0x89, 0xe6, 0x75, 0x19, 0x7c, 0x8a, 0xb6, 0x20, 0xae, 0x9b, 0xa7,
+	0xaa, 0xa1, 0x7b, 0xbd, 0x45, 0x39, 0xfb, 0xe6, 0xf5, 0x26, 0xc8, 0xea, 0x59, 0x9d, 0x51, 0x65,
+	0xce, 0x26, 0x1e, 0x6a, 0x5a, 0x73, 0x6d, 0x10, 0xeb, 0xdd, 0x33, 0xa6, 0x15, 0x68, 0x81, 0x1e,
+	0x47, 0x7e, 0xf3, 0xdd, 0xe6, 0x4a, 0xd1, 0x84, 0xa4, 0xef, 0xe9, 0x24, 0x82, 0xc9, 0x81, 0x69,
+	0x04, 0xa7, 0x65, 0x3a, 0x26, 0xe9, 0xc3, 0x3a, 0x3a, 0x72, 0x30, 0x2b, 0xa4, 0x61, 0xd9, 0x9b,
+	0xf9, 0xd1, 0x1e, 0xa2, 0x8a, 0xa5, 0x63, 0x92, 0x9f, 0xcf, 0xb0, 0x7a, 0xa2, 0xd0, 0x45, 0x98,
+	0xbd, 0x13, 0x84, 0x40, 0x94, 0xe2, 0xed, 0xf7, 0x31, 0x44, 0xa9, 0xff, 0x2f, 0xcd, 0x20, 0x73,
+	0x75, 0x29, 0xed, 0xd5, 0xa5, 0x62, 0x0d, 0xd2, 0x41, 0x1f, 0x46, 0x9f, 0x42, 0x9c, 0x35, 0xed,
+	0x0e, 0x15, 0x4e, 0x05, 0x4d, 0xc7, 0x5a, 0x77, 0x27, 0xe0, 0xeb, 0x1c, 0x5b, 0x54, 0x20, 0x15,
+	0xe0, 0xa2, 0x07, 0x10, 0x77, 0x5c, 0x1b, 0xab, 0x03, 0x56, 0xd5, 0x53, 0x3b, 0x6b, 0x81, 0x96,
+	0x4c, 0x75, 0xd5, 0x36, 0x65, 0xf2, 0x45, 0x3c, 0x28, 0x49, 0x26, 0xbf, 0x18, 0x99, 0x34, 0x81,
+	0x7b, 0x9d, 0xb5, 0x3f, 0x2f, 0x3e, 0x00, 0x98, 0x09, 0x5e, 0x94, 0x21, 0x35, 0xd5, 0x55, 0xbd,
+	0xeb, 0xd1, 0x71, 0xf1, 0x31, 0x24, 0x78, 0xa8, 0x5d, 0xa8, 0xeb, 0x0d, 0x88, 0x19, 0xd8, 0xec,
+	0xbb, 0xc7, 0x54, 0x32, 0x2c, 0x7b, 0xb3, 0xe2, 0xff, 0x42, 0x8c, 0xe5, 0x3c, 0xf4, 0x09, 0x24,
+	0x7a, 0xd6, 0xc8, 0x74, 0x67, 0x5f, 0x09, 0xab, 0xc1, 0x7a, 0x4a, 0x39, 0x3c, 0xf8, 0x39, 0xb0,
+	0xb8, 0x0b, 0x71, 0x8f, 0x85, 0x6e, 0xf9, 0xc5, 0x3e, 0x52, 0x5e, 0x5f, 0xc8, 0xbf, 0xf3, 0xed,
+	0xfd, 0xcc, 0x40, 0x11, 0x6e, 0xa0, 0x5f, 0x85, 0x20, 0xee, 0x7d, 0xce, 0x05, 0x3e, 0x0c, 0xa2,
+	0x73, 0x1f, 0x06, 0xb3, 0x2a, 0x14, 0x9a, 0xab, 0x42, 0x5c, 0x4d, 0xe1, 0x80, 0x9a, 0x66, 0x6a,
+	0x88, 0x2c, 0x75, 0xb9, 0x68, 0xc0, 0xe5, 0xb8, 0xcb, 0xc6, 0x02, 0x2e, 0x7b, 0x0b, 0xb2, 0x47,
+	0xb6, 0x35, 0xa0, 0x4d, 0xbb, 0x65, 0x93, 0x1e, 0x96, 0x95, 0xfa, 0x0c, 0xa1, 0x76, 0x38, 0x71,
+	0xde, 0x5b, 0x13, 0xf3, 0xde, 0x4a, 0x5a, 0x81, 0xa1, 0xad, 0x93, 0xb8, 0x1d, 0xd3, 0x42, 0x93,
+	0xdd, 0xb9, 0x3a, 0x53, 0xa8, 0x77, 0xd9, 0x96, 0x07, 0x90, 0x7d, 0x68, 0x51, 0x81, 0x84, 0x8c,
+	0x9d, 0xa1, 0x65, 0x3a, 0xf8, 0x42, 0x55, 0x2c, 0xf1, 0x02, 0x74, 0x1b, 0x22, 0x3d, 0x4b, 0x63,
+	0x6a, 0xc8, 0x06, 0xcb, 0x50, 0xcd, 0xb6, 0x2d, 0xbb, 0x62, 0x69, 0x58, 0xa6, 0x80, 0xe2, 0x29,
+	0xa4, 0x83, 0x2f, 0x0d, 0xff, 0xb2, 0xbe, 0x1f, 0xf2, 0xaa, 0xcf, 0xda, 0xce, 0x5c, 0x20, 0xeb,
+	0x05, 0x96, 0x25, 0x1e, 0x39, 0x5f, 0xfd, 0x4f, 0x40, 0x5c, 0x04, 0xbc, 0xb3, 0x09, 0x08, 0x2d,
+	0xb1, 0x51, 0x30, 0xad, 0xbc, 0x2b, 0x55, 0x14, 0x8f, 0x20, 0xe3, 0x6d, 0xf6, 0x6f, 0xa8, 0xf2,
+	0x0e, 0x44, 0x89, 0xa6, 0xd8, 0x0d, 0x2f, 0xd0, 0x25, 0x43, 0x14, 0x87, 0x20, 0x56, 0xad, 0x33,
+	0xd3, 0xb0, 0x54, 0xad, 0x65, 0x5b, 0x7d, 0x1b, 0x3b, 0xce, 0x85, 0xed, 0x52, 0x15, 0xe2, 0x23,
+	0xda, 0x50, 0xf1, 0x86, 0xe9, 0xfd, 0xf9, 0xe2, 0xb7, 0xb8, 0x10, 0xeb, 0xbe, 0x78, 0xfe, 0xf0,
+	0x44, 0x8b, 0x7f, 0x14, 0x20, 0x77, 0x31, 0x1a, 0xd5, 0x21, 0xc5, 0x90, 0x4a, 0xe0, 0xbd, 0x61,
+	0xeb, 0xe7, 0x6c, 0x44, 0xeb, 0x2e, 0x8c, 0xfc, 0xf1, 0xd2, 0xb6, 0x3c, 0xd0, 0x3c, 0x85, 0x7f,
+	0x5e, 0xf3, 0x74, 0x9b, 0x3f, 0xce, 0xf0, 0x6f, 0xdf, 0x48, 0x21, 0xbc, 0x15, 0x2d, 0x87, 0xc4,
+	0x15, 0xef, 0x25, 0xc6, 0xfb, 0xf2, 0x2d, 0xc6, 0x20, 0xd2, 0xd2, 0xcd, 0x7e, 0x71, 0x13, 0xa2,
+	0x15, 0xc3, 0xa2, 0x26, 0x8b, 0xd9, 0x58, 0x75, 0x2c, 0x93, 0xeb, 0x91, 0xcd, 0x8a, 0x4f, 0x00,
+	0x9d, 0x7f, 0x3b, 0x22, 0xa7, 0xf5, 0x6f, 0x9c, 0xf4, 0xfa, 0x87, 0x65, 0xd9, 0xf2, 0xff, 0x20,
+	0x15, 0x78, 0x3c, 0xba, 0xd0, 0x58, 0x12, 0xc4, 0x9d, 0x51, 0x57, 0xd3, 0x6d, 0x66, 0xac, 0xa4,
+	0xcc, 0xa7, 0xc5, 0x2f, 0x20, 0x33, 0xf7, 0x12, 0x74, 0xe1, 0x12, 0xcb, 0x5c, 0x6b, 0x8d, 0x04,
+	0x8f, 0xed, 0xb8, 0xde, 0xeb, 0x03, 0x9b, 0x14, 0xf3, 0x90, 0xa0, 0x4b, 0x96, 0x7a, 0x27, 0xbe,
+	0xfb, 0x0b, 0xb3, 0x1e, 0x78, 0xfb, 0xef, 0x11, 0x48, 0x05, 0x1e, 0x8a, 0xd0, 0x7d, 0xc8, 0x56,
+	0x0e, 0x9e, 0xb5, 0x3b, 0x35, 0x59, 0xa9, 0x34, 0x1b, 0xbb, 0xf5, 0x3d, 0x71, 0x25, 0x77, 0x7d,
+	0x32, 0x2d, 0x48, 0x83, 0x19, 0x68, 0xfe, 0x09, 0x68, 0x13, 0xa2, 0xf5, 0x46, 0xb5, 0xf6, 0x95,
+	0x28, 0xe4, 0xd6, 0x26, 0xd3, 0x82, 0x18, 0x00, 0xd2, 0x9d, 0xd1, 0x5d, 0x48, 0x53, 0x80, 0xf2,
+	0xac, 0x55, 0x2d, 0x75, 0x6a, 0x62, 0x28, 0x97, 0x9b, 0x4c, 0x0b, 0x1b, 0x8b, 0x38, 0xcf, 0xcb,
+	0xde, 0x83, 0xb8, 0x5c, 0xfb, 0xe2, 0x59, 0xad, 0xdd, 0x11, 0xc3, 0xb9, 0x8d, 0xc9, 0xb4, 0x80,
+	0x02, 0x40, 0xae, 0xda, 0x5b, 0x90, 0x90, 0x6b, 0xed, 0x56, 0xb3, 0xd1, 0xae, 0x89, 0x91, 0xdc,
+	0x95, 0xc9, 0xb4, 0x70, 0x79, 0x0e, 0xe5, 0x45, 0xe6, 0x43, 0x58, 0xad, 0x36, 0xbf, 0x6c, 0x1c,
+	0x34, 0x4b, 0x55, 0xa5, 0x25, 0x37, 0xf7, 0xe4, 0x5a, 0xbb, 0x2d, 0x46, 0x73, 0x9b, 0x93, 0x69,
+	0xe1, 0x5a, 0x00, 0x7f, 0x2e, 0xcc, 0x6e, 0x40, 0xa4, 0x55, 0x6f, 0xec, 0x89, 0xb1, 0xdc, 0xe5,
+	0xc9, 0xb4, 0x70, 0x29, 0x00, 0x25, 0x6e, 0x44, 0x6e, 0x5c, 0x39, 0x68, 0xb6, 0x6b, 0x62, 0xfc,
+	0xdc, 0x8d, 0x99, 0x7b, 0xdd, 0x83, 0x4c, 0xb9, 0xd4, 0xa9, 0xec, 0x2b, 0xfc, 0x26, 0x89, 0xdc,
+	0xb5, 0xc9, 0xb4, 0x70, 0x25, 0x00, 0x9c, 0xcb, 0x93, 0xf7, 0x21, 0xcb, 0xf1, 0xde, 0xa5, 0x92,
+	0xe7, 0x94, 0x3e, 0x9f, 0x73, 0x1e, 0xc3, 0xe5, 0x52, 0xab, 0x75, 0x50, 0xaf, 0x94, 0x3a, 0xf5,
+	0x66, 0x43, 0x39, 0xac, 0xb5, 0xdb, 0xa5, 0xbd, 0x9a, 0x08, 0xb9, 0x9b, 0x93, 0x69, 0xe1, 0x46,
+	0x40, 0x6c, 0x89, 0x3b, 0xdf, 0x85, 0x74, 0xbb, 0x52, 0x6a, 0xf8, 0x87, 0x4b, 0x9d, 0xb3, 0x47,
+	0xd0, 0x8b, 0xef, 0x43, 0x96, 0x59, 0xaf, 0xdd, 0x28, 0xb5, 0xda, 0xfb, 0xcd, 0x8e, 0x98, 0x3e,
+	0x77, 0xb6, 0x79, 0xa7, 0xfd, 0x00, 0x92, 0x4c, 0xa2, 0x54, 0x79, 0x2a, 0x66, 0xce, 0x59, 0x87,
+	0xbb, 0xe3, 0xf6, 0x37, 0x02, 0xa0, 0xf3, 0x2f, 0x8e, 0xe8, 0x7d, 0x88, 0x34, 0x9a, 0x8d, 0x9a,
+	0xb8, 0xc2, 0x8e, 0x75, 0x1e, 0xd1, 0xb0, 0x4c, 0x8c, 0x8a, 0x10, 0x3e, 0x78, 0xf9, 0x40, 0x14,
+	0x72, 0x57, 0x27, 0xd3, 0xc2, 0xfa, 0x79, 0xd0, 0xc1, 0xcb, 0x07, 0x64, 0xa5, 0x97, 0xed, 0x4e,
+	0x95, 0x3b, 0xdc, 0x79, 0xd0, 0x4b, 0xc7, 0xd5, 0xb6, 0x2d, 0x48, 0x05, 0xb7, 0x2f, 0x42, 0xe2,
+	0xb0, 0xd6, 0x29, 0x55, 0x4b, 0x9d, 0x92, 0xb8, 0xc2, 0xec, 0xcb, 0xd9, 0x87, 0xd8, 0x55, 0x69,
+	0xa8, 0x5d, 0x87, 0x68, 0xa3, 0xf6, 0xbc, 0x26, 0x8b, 0x42, 0x6e, 0x75, 0x32, 0x2d, 0x64, 0x38,
+	0xa0, 0x81, 0x4f, 0xb1, 0x8d, 0xf2, 0x10, 0x2b, 0x1d, 0x7c, 0x59, 0x7a, 0xd1, 0x16, 0x43, 0x39,
+	0x34, 0x99, 0x16, 0xb2, 0x9c, 0x5d, 0x32, 0xce, 0xd4, 0xb1, 0xb3, 0xfd, 0xad, 0x00, 0x6b, 0xcb,
+	0xde, 0xd5, 0xd1, 0x63, 0xb8, 0x5a, 0x69, 0x1e, 0xb6, 0x88, 0x97, 0x12, 0xa3, 0x96, 0x0e, 0xf6,
+	0x9a, 0x72, 0xbd, 0xb3, 0x7f, 0xa8, 0x90, 0x9b, 0xae, 0x30, 0x17, 0x5a, 0x26, 0x48, 0xee, 0xfa,
+	0x04, 0x72, 0xcb, 0x65, 0xa9, 0x06, 0x04, 0x66, 0xb2, 0x65, 0xc2, 0x54, 0x07, 0x03, 0x48, 0x05,
+	0xbe, 0x2b, 0xd0, 0x5d, 0x40, 0xe5, 0x83, 0x66, 0xe5, 0xa9, 0xd2, 0xae, 0xec, 0xd7, 0x0e, 0x6b,
+	0xca, 0x6e, 0xfd, 0xab, 0x5a, 0x95, 0x6b, 0x23, 0x00, 0xdc, 0xd5, 0x5f, 0xd1, 0xf7, 0xc3, 0xb5,
+	0x79, 0x74, 0xa9, 0xdd, 0xa9, 0x54, 0x2b, 0xa2, 0xc0, 0xc2, 0x37, 0x88, 0x57, 0x1d, 0xb7, 0x52,
+	0xad, 0x6c, 0x9f, 0x41, 0x66, 0xee, 0x53, 0x04, 0xed, 0xc0, 0xfa, 0x7e, 0xa9, 0xbd, 0x1f, 0x38,
+	0x76, 0x7b, 0xbf, 0xb4, 0xf3, 0xe9, 0x43, 0x71, 0x85, 0xb9, 0xcf, 0x1c, 0x9a, 0xb1, 0x96, 0xc8,
+	0x94, 0x0f, 0x4a, 0x4f, 0x6b, 0x9f, 0x88, 0xc2, 0x12, 0x19, 0xc6, 0xda, 0xfe, 0x87, 0x00, 0xe9,
+	0xe0, 0xc7, 0x20, 0xca, 0x43, 0x64, 0xb7, 0x7e, 0x50, 0xe3, 0x77, 0x0b, 0xf2, 0xc8, 0x18, 0x6d,
+	0x41, 0xb2, 0x5a, 0x97, 0x6b, 0x95, 0x4e, 0x53, 0x7e, 0xc1, 0x9d, 0x2d, 0x08, 0xaa, 0xea, 0x36,
+	0x2d, 0x4d, 0x63, 0xf4, 0xdf, 0x90, 0x6e, 0xbf, 0x38, 0x3c, 0xa8, 0x37, 0x9e, 0x2a, 0x74, 0xc5,
+	0x50, 0xee, 0xf6, 0x64, 0x5a, 0xb8, 0x39, 0x07, 0xc6, 0x43, 0x1b, 0xf7, 0x54, 0x17, 0x6b, 0x6d,
+	0xf6, 0x39, 0x4e, 0x98, 0x09, 0x01, 0x55, 0x60, 0x95, 0x8b, 0xce, 0x36, 0x0b, 0xe7, 0xee, 0x4e,
+	0xa6, 0x85, 0x0f, 0xde, 0x29, 0xef, 0xef, 0x9e, 0x10, 0xd0, 0xfb, 0x10, 0xf7, 0x16, 0xe1, 0x19,
+	0x31, 0x28, 0xea, 0x09, 0x6c, 0xff, 0x5a, 0x80, 0x4b, 0x0b, 0x0d, 0x22, 0xf9, 0x1b, 0xc9, 0x4b,
+	0x05, 0x4a, 0x4b, 0xae, 0x13, 0x5d, 0xbe, 0x50, 0x1a, 0x4d, 0xf9, 0xb0, 0x74, 0x20, 0xae, 0xb0,
+	0x1b, 0x2f, 0x48, 0x34, 0x2c, 0x7b, 0xa0, 0x1a, 0xe8, 0x73, 0xb8, 0x7e, 0x4e, 0xae, 0xde, 0xe8,
+	0xd4, 0xe4, 0x52, 0xa5, 0x53, 0x7f, 0x5e, 0x13, 0x85, 0x5c, 0x7e, 0x32, 0x2d, 0xe4, 0x16, 0x84,
+	0xeb, 0xa4, 0xa5, 0x57, 0x7b, 0xae, 0x7e, 0x8a, 0xb7, 0x7f, 0x2f, 0x40, 0xd2, 0xef, 0x7b, 0x48,
+	0xe4, 0x35, 0x9a, 0x4a, 0x4d, 0x96, 0x9b, 0x32, 0xb7, 0x87, 0xcf, 0x6c, 0x58, 0x74, 0x88, 0x6e,
+	0x42, 0x7c, 0xaf, 0xd6, 0xa8, 0xc9, 0xf5, 0x0a, 0x2f, 0x37, 0x3e, 0x64, 0x0f, 0x9b, 0xd8, 0xd6,
+	0x7b, 0xe8, 0x0e, 0xa4, 0x1b, 0x4d, 0xa5, 0xfd, 0xac, 0xb2, 0xcf, 0x0d, 0x41, 0xb5, 0x11, 0x58,
+	0xaa, 0x3d, 0xea, 0x1d, 0x53, 0xeb, 0x6e, 0x93, 0xca, 0xf4, 0xbc, 0x74, 0x50, 0xaf, 0x32, 0x68,
+	0x38, 0x27, 0x4d, 0xa6, 0x85, 0x35, 0x1f, 0xea, 0x7d, 0xbd, 0x12, 0xec, 0xb6, 0x06, 0xf9, 0x77,
+	0x37, 0x38, 0xa8, 0x00, 0xb1, 0x52, 0xab, 0x55, 0x6b, 0xf8, 0x91, 0x32, 0xe3, 0x95, 0x86, 0x43,
+	0x6c, 0x6a, 0x04, 0xb1, 0xdb, 0x94, 0xf7, 0x6a, 0x1d, 0x51, 0x58, 0x44, 0xec, 0x5a, 0xe4, 0x65,
+	0xa6, 0xbc, 0xf5, 0xfd, 0x8f, 0xf9, 0x95, 0x1f, 0x7e, 0xcc, 0xaf, 0x7c, 0xff, 0x26, 0x2f, 0xfc,
+	0xf0, 0x26, 0x2f, 0xfc, 0xe5, 0x4d, 0x7e, 0xe5, 0xa7, 0x37, 0x79, 0xe1, 0xdb, 0xb7, 0xf9, 0x95,
+	0xef, 0xde, 0xe6, 0x85, 0x1f, 0xde, 0xe6, 0x57, 0xfe, 0xf4, 0x36, 0xbf, 0xd2, 0x8d, 0xd1, 0xe6,
+	0xe8, 0x93, 0x7f, 0x0e, 0x00, 0x8e, 0x8f, 0x1c, 0xf6, 0xb2, 0x1c, 0x00, 0x00,
 }
 
 func (m *Hello) Marshal() (dAtA []byte, err error) {
@@ -1060,6 +1856,107 @@ func (m *Hello) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if m.IndexWindow != 0 {
+		i = encodeVarintBep(dAtA, i, uint64(m.IndexWindow))
+		i--
+		dAtA[i] = 0x60
+	}
+	if m.SupportsIndexSnapshots {
+		i--
+		if m.SupportsIndexSnapshots {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x58
+	}
+	if len(m.BlockSchemes) > 0 {
+		dAtA2 := make([]byte, len(m.BlockSchemes)*10)
+		var j1 int
+		for _, num := range m.BlockSchemes {
+			for num >= 1<<7 {
+				dAtA2[j1] = uint8(uint64(num)&0x7f | 0x80)
+				num >>= 7
+				j1++
+			}
+			dAtA2[j1] = uint8(num)
+			j1++
+		}
+		i -= j1
+		copy(dAtA[i:], dAtA2[:j1])
+		i = encodeVarintBep(dAtA, i, uint64(j1))
+		i--
+		dAtA[i] = 0x52
+	}
+	if m.SupportsScanRequests {
+		i--
+		if m.SupportsScanRequests {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x48
+	}
+	if m.SupportsApplicationMessages {
+		i--
+		if m.SupportsApplicationMessages {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x40
+	}
+	if m.SupportsPadding {
+		i--
+		if m.SupportsPadding {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x38
+	}
+	if m.SupportsMultipath {
+		i--
+		if m.SupportsMultipath {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x30
+	}
+	if len(m.CompressionAlgorithms) > 0 {
+		dAtA4 := make([]byte, len(m.CompressionAlgorithms)*10)
+		var j3 int
+		for _, num := range m.CompressionAlgorithms {
+			for num >= 1<<7 {
+				dAtA4[j3] = uint8(uint64(num)&0x7f | 0x80)
+				num >>= 7
+				j3++
+			}
+			dAtA4[j3] = uint8(num)
+			j3++
+		}
+		i -= j3
+		copy(dAtA[i:], dAtA4[:j3])
+		i = encodeVarintBep(dAtA, i, uint64(j3))
+		i--
+		dAtA[i] = 0x2a
+	}
+	if m.SupportsBatchRequests {
+		i--
+		if m.SupportsBatchRequests {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x20
+	}
 	if len(m.ClientVersion) > 0 {
 		i -= len(m.ClientVersion)
 		copy(dAtA[i:], m.ClientVersion)
@@ -1137,6 +2034,30 @@ func (m *ClusterConfig) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if len(m.Decommissioned) > 0 {
+		for iNdEx := len(m.Decommissioned) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size := m.Decommissioned[iNdEx].ProtoSize()
+				i -= size
+				if _, err := m.Decommissioned[iNdEx].MarshalTo(dAtA[i:]); err != nil {
+					return 0, err
+				}
+				i = encodeVarintBep(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x1a
+		}
+	}
+	if m.Secondary {
+		i--
+		if m.Secondary {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x10
+	}
 	if len(m.Folders) > 0 {
 		for iNdEx := len(m.Folders) - 1; iNdEx >= 0; iNdEx-- {
 			{
@@ -1174,11 +2095,88 @@ func (m *Folder) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
-	if len(m.Devices) > 0 {
-		for iNdEx := len(m.Devices) - 1; iNdEx >= 0; iNdEx-- {
-			{
-				size, err := m.Devices[iNdEx].MarshalToSizedBuffer(dAtA[:i])
-				if err != nil {
+	if m.TombstoneRetentionDays != 0 {
+		i = encodeVarintBep(dAtA, i, uint64(m.TombstoneRetentionDays))
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0xb0
+	}
+	if len(m.HashAlgorithms) > 0 {
+		dAtA6 := make([]byte, len(m.HashAlgorithms)*10)
+		var j5 int
+		for _, num := range m.HashAlgorithms {
+			for num >= 1<<7 {
+				dAtA6[j5] = uint8(uint64(num)&0x7f | 0x80)
+				num >>= 7
+				j5++
+			}
+			dAtA6[j5] = uint8(num)
+			j5++
+		}
+		i -= j5
+		copy(dAtA[i:], dAtA6[:j5])
+		i = encodeVarintBep(dAtA, i, uint64(j5))
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0xaa
+	}
+	if m.IndexSummary != nil {
+		{
+			size, err := m.IndexSummary.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintBep(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0xa2
+	}
+	if len(m.Subtrees) > 0 {
+		for iNdEx := len(m.Subtrees) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Subtrees[iNdEx])
+			copy(dAtA[i:], m.Subtrees[iNdEx])
+			i = encodeVarintBep(dAtA, i, uint64(len(m.Subtrees[iNdEx])))
+			i--
+			dAtA[i] = 0x1
+			i--
+			dAtA[i] = 0x9a
+		}
+	}
+	if len(m.SharedIgnores) > 0 {
+		for iNdEx := len(m.SharedIgnores) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.SharedIgnores[iNdEx])
+			copy(dAtA[i:], m.SharedIgnores[iNdEx])
+			i = encodeVarintBep(dAtA, i, uint64(len(m.SharedIgnores[iNdEx])))
+			i--
+			dAtA[i] = 0x1
+			i--
+			dAtA[i] = 0x92
+		}
+	}
+	if m.Settings != nil {
+		{
+			size, err := m.Settings.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintBep(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0x8a
+	}
+	if len(m.Devices) > 0 {
+		for iNdEx := len(m.Devices) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Devices[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
 					return 0, err
 				}
 				i -= size
@@ -1190,6 +2188,16 @@ func (m *Folder) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 			dAtA[i] = 0x82
 		}
 	}
+	if m.GlobalFiles != 0 {
+		i = encodeVarintBep(dAtA, i, uint64(m.GlobalFiles))
+		i--
+		dAtA[i] = 0x48
+	}
+	if m.GlobalBytes != 0 {
+		i = encodeVarintBep(dAtA, i, uint64(m.GlobalBytes))
+		i--
+		dAtA[i] = 0x40
+	}
 	if m.Paused {
 		i--
 		if m.Paused {
@@ -1257,6 +2265,103 @@ func (m *Folder) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	return len(dAtA) - i, nil
 }
 
+func (m *IndexSummary) Marshal() (dAtA []byte, err error) {
+	size := m.ProtoSize()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *IndexSummary) MarshalTo(dAtA []byte) (int, error) {
+	size := m.ProtoSize()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *IndexSummary) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Buckets) > 0 {
+		for iNdEx := len(m.Buckets) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Buckets[iNdEx])
+			copy(dAtA[i:], m.Buckets[iNdEx])
+			i = encodeVarintBep(dAtA, i, uint64(len(m.Buckets[iNdEx])))
+			i--
+			dAtA[i] = 0x12
+		}
+	}
+	if len(m.Root) > 0 {
+		i -= len(m.Root)
+		copy(dAtA[i:], m.Root)
+		i = encodeVarintBep(dAtA, i, uint64(len(m.Root)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *FolderSettings) Marshal() (dAtA []byte, err error) {
+	size := m.ProtoSize()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *FolderSettings) MarshalTo(dAtA []byte) (int, error) {
+	size := m.ProtoSize()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *FolderSettings) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.VersioningParams) > 0 {
+		for k := range m.VersioningParams {
+			v := m.VersioningParams[k]
+			baseI := i
+			i -= len(v)
+			copy(dAtA[i:], v)
+			i = encodeVarintBep(dAtA, i, uint64(len(v)))
+			i--
+			dAtA[i] = 0x12
+			i -= len(k)
+			copy(dAtA[i:], k)
+			i = encodeVarintBep(dAtA, i, uint64(len(k)))
+			i--
+			dAtA[i] = 0xa
+			i = encodeVarintBep(dAtA, i, uint64(baseI-i))
+			i--
+			dAtA[i] = 0x1a
+		}
+	}
+	if len(m.VersioningType) > 0 {
+		i -= len(m.VersioningType)
+		copy(dAtA[i:], m.VersioningType)
+		i = encodeVarintBep(dAtA, i, uint64(len(m.VersioningType)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if len(m.IgnorePatterns) > 0 {
+		for iNdEx := len(m.IgnorePatterns) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.IgnorePatterns[iNdEx])
+			copy(dAtA[i:], m.IgnorePatterns[iNdEx])
+			i = encodeVarintBep(dAtA, i, uint64(len(m.IgnorePatterns[iNdEx])))
+			i--
+			dAtA[i] = 0xa
+		}
+	}
+	return len(dAtA) - i, nil
+}
+
 func (m *Device) Marshal() (dAtA []byte, err error) {
 	size := m.ProtoSize()
 	dAtA = make([]byte, size)
@@ -1463,6 +2568,82 @@ func (m *FileInfo) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i--
 		dAtA[i] = 0xc0
 	}
+	if len(m.Holes) > 0 {
+		for iNdEx := len(m.Holes) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Holes[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintBep(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x1
+			i--
+			dAtA[i] = 0xd2
+		}
+	}
+	{
+		size, err := m.Platform.MarshalToSizedBuffer(dAtA[:i])
+		if err != nil {
+			return 0, err
+		}
+		i -= size
+		i = encodeVarintBep(dAtA, i, uint64(size))
+	}
+	i--
+	dAtA[i] = 0x1
+	i--
+	dAtA[i] = 0xca
+	if m.HashAlgorithm != 0 {
+		i = encodeVarintBep(dAtA, i, uint64(m.HashAlgorithm))
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0xc0
+	}
+	if m.BlockScheme != 0 {
+		i = encodeVarintBep(dAtA, i, uint64(m.BlockScheme))
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0xb8
+	}
+	if len(m.GroupName) > 0 {
+		i -= len(m.GroupName)
+		copy(dAtA[i:], m.GroupName)
+		i = encodeVarintBep(dAtA, i, uint64(len(m.GroupName)))
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0xb2
+	}
+	if len(m.OwnerName) > 0 {
+		i -= len(m.OwnerName)
+		copy(dAtA[i:], m.OwnerName)
+		i = encodeVarintBep(dAtA, i, uint64(len(m.OwnerName)))
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0xaa
+	}
+	if len(m.Xattrs) > 0 {
+		for iNdEx := len(m.Xattrs) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Xattrs[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintBep(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x1
+			i--
+			dAtA[i] = 0xa2
+		}
+	}
 	if m.Uid != 0 {
 		i = encodeVarintBep(dAtA, i, uint64(m.Uid))
 		i--
@@ -1637,7 +2818,7 @@ func (m *BlockInfo) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	return len(dAtA) - i, nil
 }
 
-func (m *Vector) Marshal() (dAtA []byte, err error) {
+func (m *Xattr) Marshal() (dAtA []byte, err error) {
 	size := m.ProtoSize()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -1647,20 +2828,100 @@ func (m *Vector) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *Vector) MarshalTo(dAtA []byte) (int, error) {
+func (m *Xattr) MarshalTo(dAtA []byte) (int, error) {
 	size := m.ProtoSize()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *Vector) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *Xattr) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
 	_ = l
-	if len(m.Counters) > 0 {
-		for iNdEx := len(m.Counters) - 1; iNdEx >= 0; iNdEx-- {
+	if len(m.Value) > 0 {
+		i -= len(m.Value)
+		copy(dAtA[i:], m.Value)
+		i = encodeVarintBep(dAtA, i, uint64(len(m.Value)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if len(m.Name) > 0 {
+		i -= len(m.Name)
+		copy(dAtA[i:], m.Name)
+		i = encodeVarintBep(dAtA, i, uint64(len(m.Name)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *PlatformData) Marshal() (dAtA []byte, err error) {
+	size := m.ProtoSize()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *PlatformData) MarshalTo(dAtA []byte) (int, error) {
+	size := m.ProtoSize()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *PlatformData) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	{
+		size, err := m.Windows.MarshalToSizedBuffer(dAtA[:i])
+		if err != nil {
+			return 0, err
+		}
+		i -= size
+		i = encodeVarintBep(dAtA, i, uint64(size))
+	}
+	i--
+	dAtA[i] = 0xa
+	return len(dAtA) - i, nil
+}
+
+func (m *WindowsData) Marshal() (dAtA []byte, err error) {
+	size := m.ProtoSize()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *WindowsData) MarshalTo(dAtA []byte) (int, error) {
+	size := m.ProtoSize()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *WindowsData) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.Junction {
+		i--
+		if m.Junction {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x10
+	}
+	if len(m.Streams) > 0 {
+		for iNdEx := len(m.Streams) - 1; iNdEx >= 0; iNdEx-- {
 			{
-				size, err := m.Counters[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				size, err := m.Streams[iNdEx].MarshalToSizedBuffer(dAtA[:i])
 				if err != nil {
 					return 0, err
 				}
@@ -1674,7 +2935,7 @@ func (m *Vector) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	return len(dAtA) - i, nil
 }
 
-func (m *Counter) Marshal() (dAtA []byte, err error) {
+func (m *DataStream) Marshal() (dAtA []byte, err error) {
 	size := m.ProtoSize()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -1684,30 +2945,34 @@ func (m *Counter) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *Counter) MarshalTo(dAtA []byte) (int, error) {
+func (m *DataStream) MarshalTo(dAtA []byte) (int, error) {
 	size := m.ProtoSize()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *Counter) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *DataStream) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
 	_ = l
-	if m.Value != 0 {
-		i = encodeVarintBep(dAtA, i, uint64(m.Value))
+	if len(m.Data) > 0 {
+		i -= len(m.Data)
+		copy(dAtA[i:], m.Data)
+		i = encodeVarintBep(dAtA, i, uint64(len(m.Data)))
 		i--
-		dAtA[i] = 0x10
+		dAtA[i] = 0x12
 	}
-	if m.ID != 0 {
-		i = encodeVarintBep(dAtA, i, uint64(m.ID))
+	if len(m.Name) > 0 {
+		i -= len(m.Name)
+		copy(dAtA[i:], m.Name)
+		i = encodeVarintBep(dAtA, i, uint64(len(m.Name)))
 		i--
-		dAtA[i] = 0x8
+		dAtA[i] = 0xa
 	}
 	return len(dAtA) - i, nil
 }
 
-func (m *Request) Marshal() (dAtA []byte, err error) {
+func (m *FileHole) Marshal() (dAtA []byte, err error) {
 	size := m.ProtoSize()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -1717,22 +2982,130 @@ func (m *Request) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *Request) MarshalTo(dAtA []byte) (int, error) {
+func (m *FileHole) MarshalTo(dAtA []byte) (int, error) {
 	size := m.ProtoSize()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *Request) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *FileHole) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
 	_ = l
-	if m.WeakHash != 0 {
-		i = encodeVarintBep(dAtA, i, uint64(m.WeakHash))
+	if m.Length != 0 {
+		i = encodeVarintBep(dAtA, i, uint64(m.Length))
 		i--
-		dAtA[i] = 0x40
+		dAtA[i] = 0x10
 	}
-	if m.FromTemporary {
+	if m.Offset != 0 {
+		i = encodeVarintBep(dAtA, i, uint64(m.Offset))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *Vector) Marshal() (dAtA []byte, err error) {
+	size := m.ProtoSize()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Vector) MarshalTo(dAtA []byte) (int, error) {
+	size := m.ProtoSize()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Vector) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Counters) > 0 {
+		for iNdEx := len(m.Counters) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Counters[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintBep(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0xa
+		}
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *Counter) Marshal() (dAtA []byte, err error) {
+	size := m.ProtoSize()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Counter) MarshalTo(dAtA []byte) (int, error) {
+	size := m.ProtoSize()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Counter) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.Value != 0 {
+		i = encodeVarintBep(dAtA, i, uint64(m.Value))
+		i--
+		dAtA[i] = 0x10
+	}
+	if m.ID != 0 {
+		i = encodeVarintBep(dAtA, i, uint64(m.ID))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *Request) Marshal() (dAtA []byte, err error) {
+	size := m.ProtoSize()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Request) MarshalTo(dAtA []byte) (int, error) {
+	size := m.ProtoSize()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Request) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.Priority != 0 {
+		i = encodeVarintBep(dAtA, i, uint64(m.Priority))
+		i--
+		dAtA[i] = 0x48
+	}
+	if m.WeakHash != 0 {
+		i = encodeVarintBep(dAtA, i, uint64(m.WeakHash))
+		i--
+		dAtA[i] = 0x40
+	}
+	if m.FromTemporary {
 		i--
 		if m.FromTemporary {
 			dAtA[i] = 1
@@ -1821,6 +3194,155 @@ func (m *Response) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	return len(dAtA) - i, nil
 }
 
+func (m *BatchRequest) Marshal() (dAtA []byte, err error) {
+	size := m.ProtoSize()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *BatchRequest) MarshalTo(dAtA []byte) (int, error) {
+	size := m.ProtoSize()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *BatchRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Files) > 0 {
+		for iNdEx := len(m.Files) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Files[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintBep(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x1a
+		}
+	}
+	if len(m.Folder) > 0 {
+		i -= len(m.Folder)
+		copy(dAtA[i:], m.Folder)
+		i = encodeVarintBep(dAtA, i, uint64(len(m.Folder)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if m.ID != 0 {
+		i = encodeVarintBep(dAtA, i, uint64(m.ID))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *BatchRequestFile) Marshal() (dAtA []byte, err error) {
+	size := m.ProtoSize()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *BatchRequestFile) MarshalTo(dAtA []byte) (int, error) {
+	size := m.ProtoSize()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *BatchRequestFile) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.WeakHash != 0 {
+		i = encodeVarintBep(dAtA, i, uint64(m.WeakHash))
+		i--
+		dAtA[i] = 0x20
+	}
+	if len(m.Hash) > 0 {
+		i -= len(m.Hash)
+		copy(dAtA[i:], m.Hash)
+		i = encodeVarintBep(dAtA, i, uint64(len(m.Hash)))
+		i--
+		dAtA[i] = 0x1a
+	}
+	if m.Size != 0 {
+		i = encodeVarintBep(dAtA, i, uint64(m.Size))
+		i--
+		dAtA[i] = 0x10
+	}
+	if len(m.Name) > 0 {
+		i -= len(m.Name)
+		copy(dAtA[i:], m.Name)
+		i = encodeVarintBep(dAtA, i, uint64(len(m.Name)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *BatchResponse) Marshal() (dAtA []byte, err error) {
+	size := m.ProtoSize()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *BatchResponse) MarshalTo(dAtA []byte) (int, error) {
+	size := m.ProtoSize()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *BatchResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Codes) > 0 {
+		dAtA13 := make([]byte, len(m.Codes)*10)
+		var j12 int
+		for _, num := range m.Codes {
+			for num >= 1<<7 {
+				dAtA13[j12] = uint8(uint64(num)&0x7f | 0x80)
+				num >>= 7
+				j12++
+			}
+			dAtA13[j12] = uint8(num)
+			j12++
+		}
+		i -= j12
+		copy(dAtA[i:], dAtA13[:j12])
+		i = encodeVarintBep(dAtA, i, uint64(j12))
+		i--
+		dAtA[i] = 0x1a
+	}
+	if len(m.Data) > 0 {
+		i -= len(m.Data)
+		copy(dAtA[i:], m.Data)
+		i = encodeVarintBep(dAtA, i, uint64(len(m.Data)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if m.ID != 0 {
+		i = encodeVarintBep(dAtA, i, uint64(m.ID))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
 func (m *DownloadProgress) Marshal() (dAtA []byte, err error) {
 	size := m.ProtoSize()
 	dAtA = make([]byte, size)
@@ -1970,25 +3492,176 @@ func (m *Close) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	return len(dAtA) - i, nil
 }
 
-func encodeVarintBep(dAtA []byte, offset int, v uint64) int {
-	offset -= sovBep(v)
-	base := offset
-	for v >= 1<<7 {
-		dAtA[offset] = uint8(v&0x7f | 0x80)
-		v >>= 7
-		offset++
+func (m *ApplicationMessage) Marshal() (dAtA []byte, err error) {
+	size := m.ProtoSize()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
-	dAtA[offset] = uint8(v)
-	return base
+	return dAtA[:n], nil
 }
-func (m *Hello) ProtoSize() (n int) {
-	if m == nil {
-		return 0
-	}
-	var l int
-	_ = l
-	l = len(m.DeviceName)
-	if l > 0 {
+
+func (m *ApplicationMessage) MarshalTo(dAtA []byte) (int, error) {
+	size := m.ProtoSize()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *ApplicationMessage) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Data) > 0 {
+		i -= len(m.Data)
+		copy(dAtA[i:], m.Data)
+		i = encodeVarintBep(dAtA, i, uint64(len(m.Data)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if len(m.Type) > 0 {
+		i -= len(m.Type)
+		copy(dAtA[i:], m.Type)
+		i = encodeVarintBep(dAtA, i, uint64(len(m.Type)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *ScanRequest) Marshal() (dAtA []byte, err error) {
+	size := m.ProtoSize()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *ScanRequest) MarshalTo(dAtA []byte) (int, error) {
+	size := m.ProtoSize()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *ScanRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Subdirs) > 0 {
+		for iNdEx := len(m.Subdirs) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Subdirs[iNdEx])
+			copy(dAtA[i:], m.Subdirs[iNdEx])
+			i = encodeVarintBep(dAtA, i, uint64(len(m.Subdirs[iNdEx])))
+			i--
+			dAtA[i] = 0x12
+		}
+	}
+	if len(m.Folder) > 0 {
+		i -= len(m.Folder)
+		copy(dAtA[i:], m.Folder)
+		i = encodeVarintBep(dAtA, i, uint64(len(m.Folder)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *IndexSnapshot) Marshal() (dAtA []byte, err error) {
+	size := m.ProtoSize()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *IndexSnapshot) MarshalTo(dAtA []byte) (int, error) {
+	size := m.ProtoSize()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *IndexSnapshot) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.First {
+		i--
+		if m.First {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x18
+	}
+	if len(m.Data) > 0 {
+		i -= len(m.Data)
+		copy(dAtA[i:], m.Data)
+		i = encodeVarintBep(dAtA, i, uint64(len(m.Data)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if len(m.Folder) > 0 {
+		i -= len(m.Folder)
+		copy(dAtA[i:], m.Folder)
+		i = encodeVarintBep(dAtA, i, uint64(len(m.Folder)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *IndexAck) Marshal() (dAtA []byte, err error) {
+	size := m.ProtoSize()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *IndexAck) MarshalTo(dAtA []byte) (int, error) {
+	size := m.ProtoSize()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *IndexAck) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.Size != 0 {
+		i = encodeVarintBep(dAtA, i, uint64(m.Size))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func encodeVarintBep(dAtA []byte, offset int, v uint64) int {
+	offset -= sovBep(v)
+	base := offset
+	for v >= 1<<7 {
+		dAtA[offset] = uint8(v&0x7f | 0x80)
+		v >>= 7
+		offset++
+	}
+	dAtA[offset] = uint8(v)
+	return base
+}
+func (m *Hello) ProtoSize() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.DeviceName)
+	if l > 0 {
 		n += 1 + l + sovBep(uint64(l))
 	}
 	l = len(m.ClientName)
@@ -1999,6 +3672,41 @@ func (m *Hello) ProtoSize() (n int) {
 	if l > 0 {
 		n += 1 + l + sovBep(uint64(l))
 	}
+	if m.SupportsBatchRequests {
+		n += 2
+	}
+	if len(m.CompressionAlgorithms) > 0 {
+		l = 0
+		for _, e := range m.CompressionAlgorithms {
+			l += sovBep(uint64(e))
+		}
+		n += 1 + sovBep(uint64(l)) + l
+	}
+	if m.SupportsMultipath {
+		n += 2
+	}
+	if m.SupportsPadding {
+		n += 2
+	}
+	if m.SupportsApplicationMessages {
+		n += 2
+	}
+	if m.SupportsScanRequests {
+		n += 2
+	}
+	if len(m.BlockSchemes) > 0 {
+		l = 0
+		for _, e := range m.BlockSchemes {
+			l += sovBep(uint64(e))
+		}
+		n += 1 + sovBep(uint64(l)) + l
+	}
+	if m.SupportsIndexSnapshots {
+		n += 2
+	}
+	if m.IndexWindow != 0 {
+		n += 1 + sovBep(uint64(m.IndexWindow))
+	}
 	return n
 }
 
@@ -2029,6 +3737,15 @@ func (m *ClusterConfig) ProtoSize() (n int) {
 			n += 1 + l + sovBep(uint64(l))
 		}
 	}
+	if m.Secondary {
+		n += 2
+	}
+	if len(m.Decommissioned) > 0 {
+		for _, e := range m.Decommissioned {
+			l = e.ProtoSize()
+			n += 1 + l + sovBep(uint64(l))
+		}
+	}
 	return n
 }
 
@@ -2061,12 +3778,94 @@ func (m *Folder) ProtoSize() (n int) {
 	if m.Paused {
 		n += 2
 	}
+	if m.GlobalBytes != 0 {
+		n += 1 + sovBep(uint64(m.GlobalBytes))
+	}
+	if m.GlobalFiles != 0 {
+		n += 1 + sovBep(uint64(m.GlobalFiles))
+	}
 	if len(m.Devices) > 0 {
 		for _, e := range m.Devices {
 			l = e.ProtoSize()
 			n += 2 + l + sovBep(uint64(l))
 		}
 	}
+	if m.Settings != nil {
+		l = m.Settings.ProtoSize()
+		n += 2 + l + sovBep(uint64(l))
+	}
+	if len(m.SharedIgnores) > 0 {
+		for _, s := range m.SharedIgnores {
+			l = len(s)
+			n += 2 + l + sovBep(uint64(l))
+		}
+	}
+	if len(m.Subtrees) > 0 {
+		for _, s := range m.Subtrees {
+			l = len(s)
+			n += 2 + l + sovBep(uint64(l))
+		}
+	}
+	if m.IndexSummary != nil {
+		l = m.IndexSummary.ProtoSize()
+		n += 2 + l + sovBep(uint64(l))
+	}
+	if len(m.HashAlgorithms) > 0 {
+		l = 0
+		for _, e := range m.HashAlgorithms {
+			l += sovBep(uint64(e))
+		}
+		n += 2 + sovBep(uint64(l)) + l
+	}
+	if m.TombstoneRetentionDays != 0 {
+		n += 2 + sovBep(uint64(m.TombstoneRetentionDays))
+	}
+	return n
+}
+
+func (m *IndexSummary) ProtoSize() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Root)
+	if l > 0 {
+		n += 1 + l + sovBep(uint64(l))
+	}
+	if len(m.Buckets) > 0 {
+		for _, b := range m.Buckets {
+			l = len(b)
+			n += 1 + l + sovBep(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *FolderSettings) ProtoSize() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.IgnorePatterns) > 0 {
+		for _, s := range m.IgnorePatterns {
+			l = len(s)
+			n += 1 + l + sovBep(uint64(l))
+		}
+	}
+	l = len(m.VersioningType)
+	if l > 0 {
+		n += 1 + l + sovBep(uint64(l))
+	}
+	if len(m.VersioningParams) > 0 {
+		for k, v := range m.VersioningParams {
+			_ = k
+			_ = v
+			mapEntrySize := 1 + len(k) + sovBep(uint64(len(k))) + 1 + len(v) + sovBep(uint64(len(v)))
+			n += mapEntrySize + 1 + sovBep(uint64(mapEntrySize))
+		}
+	}
 	return n
 }
 
@@ -2209,6 +4008,34 @@ func (m *FileInfo) ProtoSize() (n int) {
 	if m.Uid != 0 {
 		n += 2 + sovBep(uint64(m.Uid))
 	}
+	if len(m.Xattrs) > 0 {
+		for _, e := range m.Xattrs {
+			l = e.ProtoSize()
+			n += 2 + l + sovBep(uint64(l))
+		}
+	}
+	l = len(m.OwnerName)
+	if l > 0 {
+		n += 2 + l + sovBep(uint64(l))
+	}
+	l = len(m.GroupName)
+	if l > 0 {
+		n += 2 + l + sovBep(uint64(l))
+	}
+	if m.BlockScheme != 0 {
+		n += 2 + sovBep(uint64(m.BlockScheme))
+	}
+	if m.HashAlgorithm != 0 {
+		n += 2 + sovBep(uint64(m.HashAlgorithm))
+	}
+	l = m.Platform.ProtoSize()
+	n += 2 + l + sovBep(uint64(l))
+	if len(m.Holes) > 0 {
+		for _, e := range m.Holes {
+			l = e.ProtoSize()
+			n += 2 + l + sovBep(uint64(l))
+		}
+	}
 	if m.LocalFlags != 0 {
 		n += 2 + sovBep(uint64(m.LocalFlags))
 	}
@@ -2237,48 +4064,126 @@ func (m *BlockInfo) ProtoSize() (n int) {
 	return n
 }
 
-func (m *Vector) ProtoSize() (n int) {
+func (m *Xattr) ProtoSize() (n int) {
 	if m == nil {
 		return 0
 	}
 	var l int
 	_ = l
-	if len(m.Counters) > 0 {
-		for _, e := range m.Counters {
-			l = e.ProtoSize()
-			n += 1 + l + sovBep(uint64(l))
-		}
+	l = len(m.Name)
+	if l > 0 {
+		n += 1 + l + sovBep(uint64(l))
+	}
+	l = len(m.Value)
+	if l > 0 {
+		n += 1 + l + sovBep(uint64(l))
 	}
 	return n
 }
 
-func (m *Counter) ProtoSize() (n int) {
+func (m *PlatformData) ProtoSize() (n int) {
 	if m == nil {
 		return 0
 	}
 	var l int
 	_ = l
-	if m.ID != 0 {
-		n += 1 + sovBep(uint64(m.ID))
-	}
-	if m.Value != 0 {
-		n += 1 + sovBep(uint64(m.Value))
-	}
+	l = m.Windows.ProtoSize()
+	n += 1 + l + sovBep(uint64(l))
 	return n
 }
 
-func (m *Request) ProtoSize() (n int) {
+func (m *WindowsData) ProtoSize() (n int) {
 	if m == nil {
 		return 0
 	}
 	var l int
 	_ = l
-	if m.ID != 0 {
-		n += 1 + sovBep(uint64(m.ID))
+	if len(m.Streams) > 0 {
+		for _, e := range m.Streams {
+			l = e.ProtoSize()
+			n += 1 + l + sovBep(uint64(l))
+		}
 	}
-	l = len(m.Folder)
-	if l > 0 {
-		n += 1 + l + sovBep(uint64(l))
+	if m.Junction {
+		n += 2
+	}
+	return n
+}
+
+func (m *DataStream) ProtoSize() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Name)
+	if l > 0 {
+		n += 1 + l + sovBep(uint64(l))
+	}
+	l = len(m.Data)
+	if l > 0 {
+		n += 1 + l + sovBep(uint64(l))
+	}
+	return n
+}
+
+func (m *FileHole) ProtoSize() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Offset != 0 {
+		n += 1 + sovBep(uint64(m.Offset))
+	}
+	if m.Length != 0 {
+		n += 1 + sovBep(uint64(m.Length))
+	}
+	return n
+}
+
+func (m *Vector) ProtoSize() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Counters) > 0 {
+		for _, e := range m.Counters {
+			l = e.ProtoSize()
+			n += 1 + l + sovBep(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *Counter) ProtoSize() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.ID != 0 {
+		n += 1 + sovBep(uint64(m.ID))
+	}
+	if m.Value != 0 {
+		n += 1 + sovBep(uint64(m.Value))
+	}
+	return n
+}
+
+func (m *Request) ProtoSize() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.ID != 0 {
+		n += 1 + sovBep(uint64(m.ID))
+	}
+	l = len(m.Folder)
+	if l > 0 {
+		n += 1 + l + sovBep(uint64(l))
 	}
 	l = len(m.Name)
 	if l > 0 {
@@ -2300,6 +4205,9 @@ func (m *Request) ProtoSize() (n int) {
 	if m.WeakHash != 0 {
 		n += 1 + sovBep(uint64(m.WeakHash))
 	}
+	if m.Priority != 0 {
+		n += 1 + sovBep(uint64(m.Priority))
+	}
 	return n
 }
 
@@ -2322,6 +4230,74 @@ func (m *Response) ProtoSize() (n int) {
 	return n
 }
 
+func (m *BatchRequest) ProtoSize() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.ID != 0 {
+		n += 1 + sovBep(uint64(m.ID))
+	}
+	l = len(m.Folder)
+	if l > 0 {
+		n += 1 + l + sovBep(uint64(l))
+	}
+	if len(m.Files) > 0 {
+		for _, e := range m.Files {
+			l = e.ProtoSize()
+			n += 1 + l + sovBep(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *BatchRequestFile) ProtoSize() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Name)
+	if l > 0 {
+		n += 1 + l + sovBep(uint64(l))
+	}
+	if m.Size != 0 {
+		n += 1 + sovBep(uint64(m.Size))
+	}
+	l = len(m.Hash)
+	if l > 0 {
+		n += 1 + l + sovBep(uint64(l))
+	}
+	if m.WeakHash != 0 {
+		n += 1 + sovBep(uint64(m.WeakHash))
+	}
+	return n
+}
+
+func (m *BatchResponse) ProtoSize() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.ID != 0 {
+		n += 1 + sovBep(uint64(m.ID))
+	}
+	l = len(m.Data)
+	if l > 0 {
+		n += 1 + l + sovBep(uint64(l))
+	}
+	if len(m.Codes) > 0 {
+		l = 0
+		for _, e := range m.Codes {
+			l += sovBep(uint64(e))
+		}
+		n += 1 + sovBep(uint64(l)) + l
+	}
+	return n
+}
+
 func (m *DownloadProgress) ProtoSize() (n int) {
 	if m == nil {
 		return 0
@@ -2386,6 +4362,74 @@ func (m *Close) ProtoSize() (n int) {
 	return n
 }
 
+func (m *ApplicationMessage) ProtoSize() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Type)
+	if l > 0 {
+		n += 1 + l + sovBep(uint64(l))
+	}
+	l = len(m.Data)
+	if l > 0 {
+		n += 1 + l + sovBep(uint64(l))
+	}
+	return n
+}
+
+func (m *ScanRequest) ProtoSize() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Folder)
+	if l > 0 {
+		n += 1 + l + sovBep(uint64(l))
+	}
+	if len(m.Subdirs) > 0 {
+		for _, s := range m.Subdirs {
+			l = len(s)
+			n += 1 + l + sovBep(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *IndexSnapshot) ProtoSize() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Folder)
+	if l > 0 {
+		n += 1 + l + sovBep(uint64(l))
+	}
+	l = len(m.Data)
+	if l > 0 {
+		n += 1 + l + sovBep(uint64(l))
+	}
+	if m.First {
+		n += 2
+	}
+	return n
+}
+
+func (m *IndexAck) ProtoSize() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Size != 0 {
+		n += 1 + sovBep(uint64(m.Size))
+	}
+	return n
+}
+
 func sovBep(x uint64) (n int) {
 	return (math_bits.Len64(x|1) + 6) / 7
 }
@@ -2517,64 +4561,11 @@ func (m *Hello) Unmarshal(dAtA []byte) error {
 			}
 			m.ClientVersion = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		default:
-			iNdEx = preIndex
-			skippy, err := skipBep(dAtA[iNdEx:])
-			if err != nil {
-				return err
-			}
-			if skippy < 0 {
-				return ErrInvalidLengthBep
-			}
-			if (iNdEx + skippy) < 0 {
-				return ErrInvalidLengthBep
-			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
-			}
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *Header) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowBep
-			}
-			if iNdEx >= l {
-				return io.ErrUnexpectedEOF
-			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= uint64(b&0x7F) << shift
-			if b < 0x80 {
-				break
-			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: Header: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Header: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
+		case 4:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Type", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field SupportsBatchRequests", wireType)
 			}
-			m.Type = 0
+			var v int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowBep
@@ -2584,16 +4575,2992 @@ func (m *Header) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Type |= MessageType(b&0x7F) << shift
+				v |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 2:
+			m.SupportsBatchRequests = bool(v != 0)
+		case 5:
+			if wireType == 0 {
+				var v CompressionAlgorithm
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowBep
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					v |= CompressionAlgorithm(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				m.CompressionAlgorithms = append(m.CompressionAlgorithms, v)
+			} else if wireType == 2 {
+				var packedLen int
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowBep
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					packedLen |= int(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				if packedLen < 0 {
+					return ErrInvalidLengthBep
+				}
+				postIndex := iNdEx + packedLen
+				if postIndex < 0 {
+					return ErrInvalidLengthBep
+				}
+				if postIndex > l {
+					return io.ErrUnexpectedEOF
+				}
+				var elementCount int
+				if elementCount != 0 && len(m.CompressionAlgorithms) == 0 {
+					m.CompressionAlgorithms = make([]CompressionAlgorithm, 0, elementCount)
+				}
+				for iNdEx < postIndex {
+					var v CompressionAlgorithm
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowBep
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						v |= CompressionAlgorithm(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					m.CompressionAlgorithms = append(m.CompressionAlgorithms, v)
+				}
+			} else {
+				return fmt.Errorf("proto: wrong wireType = %d for field CompressionAlgorithms", wireType)
+			}
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SupportsMultipath", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.SupportsMultipath = bool(v != 0)
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SupportsPadding", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.SupportsPadding = bool(v != 0)
+		case 8:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SupportsApplicationMessages", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.SupportsApplicationMessages = bool(v != 0)
+		case 9:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SupportsScanRequests", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.SupportsScanRequests = bool(v != 0)
+		case 10:
+			if wireType == 0 {
+				var v BlockScheme
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowBep
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					v |= BlockScheme(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				m.BlockSchemes = append(m.BlockSchemes, v)
+			} else if wireType == 2 {
+				var packedLen int
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowBep
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					packedLen |= int(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				if packedLen < 0 {
+					return ErrInvalidLengthBep
+				}
+				postIndex := iNdEx + packedLen
+				if postIndex < 0 {
+					return ErrInvalidLengthBep
+				}
+				if postIndex > l {
+					return io.ErrUnexpectedEOF
+				}
+				var elementCount int
+				if elementCount != 0 && len(m.BlockSchemes) == 0 {
+					m.BlockSchemes = make([]BlockScheme, 0, elementCount)
+				}
+				for iNdEx < postIndex {
+					var v BlockScheme
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowBep
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						v |= BlockScheme(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					m.BlockSchemes = append(m.BlockSchemes, v)
+				}
+			} else {
+				return fmt.Errorf("proto: wrong wireType = %d for field BlockSchemes", wireType)
+			}
+		case 11:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SupportsIndexSnapshots", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.SupportsIndexSnapshots = bool(v != 0)
+		case 12:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field IndexWindow", wireType)
+			}
+			m.IndexWindow = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.IndexWindow |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipBep(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthBep
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthBep
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Header) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowBep
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Header: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Header: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Type", wireType)
+			}
+			m.Type = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Type |= MessageType(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Compression", wireType)
+			}
+			m.Compression = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Compression |= MessageCompression(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipBep(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthBep
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthBep
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *ClusterConfig) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowBep
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: ClusterConfig: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: ClusterConfig: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Folders", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Folders = append(m.Folders, Folder{})
+			if err := m.Folders[len(m.Folders)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Secondary", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Secondary = bool(v != 0)
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Decommissioned", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			var v DeviceID
+			m.Decommissioned = append(m.Decommissioned, v)
+			if err := m.Decommissioned[len(m.Decommissioned)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipBep(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthBep
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthBep
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Folder) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowBep
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Folder: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Folder: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ID", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.ID = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Label", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Label = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ReadOnly", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.ReadOnly = bool(v != 0)
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field IgnorePermissions", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.IgnorePermissions = bool(v != 0)
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field IgnoreDelete", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.IgnoreDelete = bool(v != 0)
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field DisableTempIndexes", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.DisableTempIndexes = bool(v != 0)
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Paused", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Paused = bool(v != 0)
+		case 8:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field GlobalBytes", wireType)
+			}
+			m.GlobalBytes = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.GlobalBytes |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 9:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field GlobalFiles", wireType)
+			}
+			m.GlobalFiles = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.GlobalFiles |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 16:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Devices", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Devices = append(m.Devices, Device{})
+			if err := m.Devices[len(m.Devices)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 17:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Settings", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Settings == nil {
+				m.Settings = &FolderSettings{}
+			}
+			if err := m.Settings.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 18:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SharedIgnores", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.SharedIgnores = append(m.SharedIgnores, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		case 19:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Subtrees", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Subtrees = append(m.Subtrees, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		case 20:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field IndexSummary", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.IndexSummary == nil {
+				m.IndexSummary = &IndexSummary{}
+			}
+			if err := m.IndexSummary.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 21:
+			if wireType == 0 {
+				var v HashAlgorithm
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowBep
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					v |= HashAlgorithm(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				m.HashAlgorithms = append(m.HashAlgorithms, v)
+			} else if wireType == 2 {
+				var packedLen int
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowBep
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					packedLen |= int(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				if packedLen < 0 {
+					return ErrInvalidLengthBep
+				}
+				postIndex := iNdEx + packedLen
+				if postIndex < 0 {
+					return ErrInvalidLengthBep
+				}
+				if postIndex > l {
+					return io.ErrUnexpectedEOF
+				}
+				var elementCount int
+				if elementCount != 0 && len(m.HashAlgorithms) == 0 {
+					m.HashAlgorithms = make([]HashAlgorithm, 0, elementCount)
+				}
+				for iNdEx < postIndex {
+					var v HashAlgorithm
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowBep
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						v |= HashAlgorithm(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					m.HashAlgorithms = append(m.HashAlgorithms, v)
+				}
+			} else {
+				return fmt.Errorf("proto: wrong wireType = %d for field HashAlgorithms", wireType)
+			}
+		case 22:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TombstoneRetentionDays", wireType)
+			}
+			m.TombstoneRetentionDays = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.TombstoneRetentionDays |= int32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipBep(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthBep
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthBep
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *IndexSummary) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowBep
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: IndexSummary: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: IndexSummary: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Root", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Root = append(m.Root[:0], dAtA[iNdEx:postIndex]...)
+			if m.Root == nil {
+				m.Root = []byte{}
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Buckets", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Buckets = append(m.Buckets, make([]byte, postIndex-iNdEx))
+			copy(m.Buckets[len(m.Buckets)-1], dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipBep(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthBep
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthBep
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *FolderSettings) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowBep
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: FolderSettings: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: FolderSettings: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field IgnorePatterns", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.IgnorePatterns = append(m.IgnorePatterns, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field VersioningType", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.VersioningType = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field VersioningParams", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.VersioningParams == nil {
+				m.VersioningParams = make(map[string]string)
+			}
+			var mapkey string
+			var mapvalue string
+			for iNdEx < postIndex {
+				entryPreIndex := iNdEx
+				var wire uint64
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowBep
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					wire |= uint64(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				fieldNum := int32(wire >> 3)
+				if fieldNum == 1 {
+					var stringLenmapkey uint64
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowBep
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						stringLenmapkey |= uint64(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					intStringLenmapkey := int(stringLenmapkey)
+					if intStringLenmapkey < 0 {
+						return ErrInvalidLengthBep
+					}
+					postStringIndexmapkey := iNdEx + intStringLenmapkey
+					if postStringIndexmapkey < 0 {
+						return ErrInvalidLengthBep
+					}
+					if postStringIndexmapkey > l {
+						return io.ErrUnexpectedEOF
+					}
+					mapkey = string(dAtA[iNdEx:postStringIndexmapkey])
+					iNdEx = postStringIndexmapkey
+				} else if fieldNum == 2 {
+					var stringLenmapvalue uint64
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowBep
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						stringLenmapvalue |= uint64(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					intStringLenmapvalue := int(stringLenmapvalue)
+					if intStringLenmapvalue < 0 {
+						return ErrInvalidLengthBep
+					}
+					postStringIndexmapvalue := iNdEx + intStringLenmapvalue
+					if postStringIndexmapvalue < 0 {
+						return ErrInvalidLengthBep
+					}
+					if postStringIndexmapvalue > l {
+						return io.ErrUnexpectedEOF
+					}
+					mapvalue = string(dAtA[iNdEx:postStringIndexmapvalue])
+					iNdEx = postStringIndexmapvalue
+				} else {
+					iNdEx = entryPreIndex
+					skippy, err := skipBep(dAtA[iNdEx:])
+					if err != nil {
+						return err
+					}
+					if skippy < 0 {
+						return ErrInvalidLengthBep
+					}
+					if (iNdEx + skippy) > postIndex {
+						return io.ErrUnexpectedEOF
+					}
+					iNdEx += skippy
+				}
+			}
+			m.VersioningParams[mapkey] = mapvalue
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipBep(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthBep
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthBep
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Device) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowBep
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Device: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Device: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ID", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.ID.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Name = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Addresses", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Addresses = append(m.Addresses, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Compression", wireType)
+			}
+			m.Compression = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Compression |= Compression(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field CertName", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.CertName = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxSequence", wireType)
+			}
+			m.MaxSequence = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MaxSequence |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Introducer", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Introducer = bool(v != 0)
+		case 8:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field IndexID", wireType)
+			}
+			m.IndexID = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.IndexID |= IndexID(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 9:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SkipIntroductionRemovals", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.SkipIntroductionRemovals = bool(v != 0)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipBep(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthBep
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthBep
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Index) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowBep
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Index: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Index: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Folder", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Folder = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Files", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Files = append(m.Files, FileInfo{})
+			if err := m.Files[len(m.Files)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipBep(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthBep
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthBep
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *IndexUpdate) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowBep
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: IndexUpdate: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: IndexUpdate: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Folder", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Folder = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Files", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Files = append(m.Files, FileInfo{})
+			if err := m.Files[len(m.Files)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipBep(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthBep
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthBep
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *FileInfo) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowBep
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: FileInfo: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: FileInfo: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Name = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Type", wireType)
+			}
+			m.Type = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Type |= FileInfoType(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Size", wireType)
+			}
+			m.Size = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Size |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Permissions", wireType)
+			}
+			m.Permissions = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Permissions |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ModifiedS", wireType)
+			}
+			m.ModifiedS = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ModifiedS |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Deleted", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Deleted = bool(v != 0)
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field RawInvalid", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.RawInvalid = bool(v != 0)
+		case 8:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field NoPermissions", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.NoPermissions = bool(v != 0)
+		case 9:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Version", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.Version.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 10:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Sequence", wireType)
+			}
+			m.Sequence = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Sequence |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 11:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ModifiedNs", wireType)
+			}
+			m.ModifiedNs = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ModifiedNs |= int32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 12:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ModifiedBy", wireType)
+			}
+			m.ModifiedBy = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ModifiedBy |= ShortID(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 13:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field RawBlockSize", wireType)
+			}
+			m.RawBlockSize = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.RawBlockSize |= int32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 16:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Blocks", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Blocks = append(m.Blocks, BlockInfo{})
+			if err := m.Blocks[len(m.Blocks)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 17:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SymlinkTarget", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.SymlinkTarget = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 18:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Gid", wireType)
+			}
+			m.Gid = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Gid |= int32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 19:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Uid", wireType)
+			}
+			m.Uid = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Uid |= int32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 20:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Xattrs", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Xattrs = append(m.Xattrs, Xattr{})
+			if err := m.Xattrs[len(m.Xattrs)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 21:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field OwnerName", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.OwnerName = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 22:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field GroupName", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.GroupName = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 23:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field BlockScheme", wireType)
+			}
+			m.BlockScheme = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.BlockScheme |= BlockScheme(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 24:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field HashAlgorithm", wireType)
+			}
+			m.HashAlgorithm = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.HashAlgorithm |= HashAlgorithm(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 25:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Platform", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.Platform.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 26:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Holes", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Holes = append(m.Holes, FileHole{})
+			if err := m.Holes[len(m.Holes)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 1000:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LocalFlags", wireType)
+			}
+			m.LocalFlags = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.LocalFlags |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipBep(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthBep
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthBep
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *BlockInfo) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowBep
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: BlockInfo: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: BlockInfo: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Offset", wireType)
+			}
+			m.Offset = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Offset |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Size", wireType)
+			}
+			m.Size = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Size |= int32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Hash", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Hash = append(m.Hash[:0], dAtA[iNdEx:postIndex]...)
+			if m.Hash == nil {
+				m.Hash = []byte{}
+			}
+			iNdEx = postIndex
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field WeakHash", wireType)
+			}
+			m.WeakHash = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.WeakHash |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipBep(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthBep
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthBep
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Xattr) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowBep
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Xattr: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Xattr: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Name = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Value", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Value = append(m.Value[:0], dAtA[iNdEx:postIndex]...)
+			if m.Value == nil {
+				m.Value = []byte{}
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipBep(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthBep
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthBep
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *PlatformData) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowBep
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: PlatformData: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: PlatformData: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Windows", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.Windows.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipBep(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthBep
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthBep
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *WindowsData) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowBep
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: WindowsData: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: WindowsData: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Streams", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Streams = append(m.Streams, DataStream{})
+			if err := m.Streams[len(m.Streams)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 2:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Compression", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Junction", wireType)
 			}
-			m.Compression = 0
+			var v int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowBep
@@ -2603,11 +7570,12 @@ func (m *Header) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Compression |= MessageCompression(b&0x7F) << shift
+				v |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
+			m.Junction = bool(v != 0)
 		default:
 			iNdEx = preIndex
 			skippy, err := skipBep(dAtA[iNdEx:])
@@ -2632,7 +7600,7 @@ func (m *Header) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *ClusterConfig) Unmarshal(dAtA []byte) error {
+func (m *DataStream) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -2655,15 +7623,225 @@ func (m *ClusterConfig) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: ClusterConfig: wiretype end group for non-group")
+			return fmt.Errorf("proto: DataStream: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: ClusterConfig: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: DataStream: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Name = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Data", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Data = append(m.Data[:0], dAtA[iNdEx:postIndex]...)
+			if m.Data == nil {
+				m.Data = []byte{}
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipBep(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthBep
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthBep
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *FileHole) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowBep
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: FileHole: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: FileHole: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Offset", wireType)
+			}
+			m.Offset = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Offset |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Length", wireType)
+			}
+			m.Length = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Length |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipBep(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthBep
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthBep
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Vector) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowBep
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Vector: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Vector: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Folders", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Counters", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -2690,8 +7868,8 @@ func (m *ClusterConfig) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Folders = append(m.Folders, Folder{})
-			if err := m.Folders[len(m.Folders)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+			m.Counters = append(m.Counters, Counter{})
+			if err := m.Counters[len(m.Counters)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
 			iNdEx = postIndex
@@ -2719,7 +7897,7 @@ func (m *ClusterConfig) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *Folder) Unmarshal(dAtA []byte) error {
+func (m *Counter) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -2742,17 +7920,17 @@ func (m *Folder) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Folder: wiretype end group for non-group")
+			return fmt.Errorf("proto: Counter: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Folder: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: Counter: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 2 {
+			if wireType != 0 {
 				return fmt.Errorf("proto: wrong wireType = %d for field ID", wireType)
 			}
-			var stringLen uint64
+			m.ID = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowBep
@@ -2762,161 +7940,16 @@ func (m *Folder) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				m.ID |= ShortID(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthBep
-			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
-				return ErrInvalidLengthBep
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.ID = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
 		case 2:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Label", wireType)
-			}
-			var stringLen uint64
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowBep
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthBep
-			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
-				return ErrInvalidLengthBep
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Label = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
-		case 3:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ReadOnly", wireType)
-			}
-			var v int
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowBep
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				v |= int(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-			m.ReadOnly = bool(v != 0)
-		case 4:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field IgnorePermissions", wireType)
-			}
-			var v int
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowBep
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				v |= int(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-			m.IgnorePermissions = bool(v != 0)
-		case 5:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field IgnoreDelete", wireType)
-			}
-			var v int
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowBep
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				v |= int(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-			m.IgnoreDelete = bool(v != 0)
-		case 6:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field DisableTempIndexes", wireType)
-			}
-			var v int
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowBep
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				v |= int(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-			m.DisableTempIndexes = bool(v != 0)
-		case 7:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Paused", wireType)
-			}
-			var v int
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowBep
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				v |= int(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-			m.Paused = bool(v != 0)
-		case 16:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Devices", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Value", wireType)
 			}
-			var msglen int
+			m.Value = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowBep
@@ -2926,26 +7959,11 @@ func (m *Folder) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				m.Value |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
-				return ErrInvalidLengthBep
-			}
-			postIndex := iNdEx + msglen
-			if postIndex < 0 {
-				return ErrInvalidLengthBep
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Devices = append(m.Devices, Device{})
-			if err := m.Devices[len(m.Devices)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
-			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipBep(dAtA[iNdEx:])
@@ -2970,7 +7988,7 @@ func (m *Folder) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *Device) Unmarshal(dAtA []byte) error {
+func (m *Request) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -2993,17 +8011,17 @@ func (m *Device) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Device: wiretype end group for non-group")
+			return fmt.Errorf("proto: Request: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Device: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: Request: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 2 {
+			if wireType != 0 {
 				return fmt.Errorf("proto: wrong wireType = %d for field ID", wireType)
 			}
-			var byteLen int
+			m.ID = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowBep
@@ -3013,28 +8031,14 @@ func (m *Device) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= int(b&0x7F) << shift
+				m.ID |= int32(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if byteLen < 0 {
-				return ErrInvalidLengthBep
-			}
-			postIndex := iNdEx + byteLen
-			if postIndex < 0 {
-				return ErrInvalidLengthBep
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			if err := m.ID.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
-			iNdEx = postIndex
 		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Folder", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -3062,11 +8066,11 @@ func (m *Device) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Name = string(dAtA[iNdEx:postIndex])
+			m.Folder = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
 		case 3:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Addresses", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -3094,13 +8098,13 @@ func (m *Device) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Addresses = append(m.Addresses, string(dAtA[iNdEx:postIndex]))
+			m.Name = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
 		case 4:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Compression", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Offset", wireType)
 			}
-			m.Compression = 0
+			m.Offset = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowBep
@@ -3110,16 +8114,16 @@ func (m *Device) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Compression |= Compression(b&0x7F) << shift
+				m.Offset |= int64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
 		case 5:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field CertName", wireType)
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Size", wireType)
 			}
-			var stringLen uint64
+			m.Size = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowBep
@@ -3129,29 +8133,16 @@ func (m *Device) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				m.Size |= int32(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthBep
-			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
-				return ErrInvalidLengthBep
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.CertName = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
 		case 6:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field MaxSequence", wireType)
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Hash", wireType)
 			}
-			m.MaxSequence = 0
+			var byteLen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowBep
@@ -3161,14 +8152,29 @@ func (m *Device) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.MaxSequence |= int64(b&0x7F) << shift
+				byteLen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
+			if byteLen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Hash = append(m.Hash[:0], dAtA[iNdEx:postIndex]...)
+			if m.Hash == nil {
+				m.Hash = []byte{}
+			}
+			iNdEx = postIndex
 		case 7:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Introducer", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field FromTemporary", wireType)
 			}
 			var v int
 			for shift := uint(0); ; shift += 7 {
@@ -3185,12 +8191,12 @@ func (m *Device) Unmarshal(dAtA []byte) error {
 					break
 				}
 			}
-			m.Introducer = bool(v != 0)
+			m.FromTemporary = bool(v != 0)
 		case 8:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field IndexID", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field WeakHash", wireType)
 			}
-			m.IndexID = 0
+			m.WeakHash = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowBep
@@ -3200,16 +8206,16 @@ func (m *Device) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.IndexID |= IndexID(b&0x7F) << shift
+				m.WeakHash |= uint32(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
 		case 9:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field SkipIntroductionRemovals", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Priority", wireType)
 			}
-			var v int
+			m.Priority = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowBep
@@ -3219,12 +8225,11 @@ func (m *Device) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= int(b&0x7F) << shift
+				m.Priority |= RequestPriority(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			m.SkipIntroductionRemovals = bool(v != 0)
 		default:
 			iNdEx = preIndex
 			skippy, err := skipBep(dAtA[iNdEx:])
@@ -3249,7 +8254,7 @@ func (m *Device) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *Index) Unmarshal(dAtA []byte) error {
+func (m *Response) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -3272,17 +8277,17 @@ func (m *Index) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Index: wiretype end group for non-group")
+			return fmt.Errorf("proto: Response: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Index: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: Response: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Folder", wireType)
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ID", wireType)
 			}
-			var stringLen uint64
+			m.ID = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowBep
@@ -3292,29 +8297,16 @@ func (m *Index) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				m.ID |= int32(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthBep
-			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
-				return ErrInvalidLengthBep
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Folder = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
 		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Files", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Data", wireType)
 			}
-			var msglen int
+			var byteLen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowBep
@@ -3324,26 +8316,45 @@ func (m *Index) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				byteLen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			if byteLen < 0 {
 				return ErrInvalidLengthBep
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + byteLen
 			if postIndex < 0 {
 				return ErrInvalidLengthBep
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Files = append(m.Files, FileInfo{})
-			if err := m.Files[len(m.Files)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
+			m.Data = append(m.Data[:0], dAtA[iNdEx:postIndex]...)
+			if m.Data == nil {
+				m.Data = []byte{}
 			}
 			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Code", wireType)
+			}
+			m.Code = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Code |= ErrorCode(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipBep(dAtA[iNdEx:])
@@ -3368,7 +8379,7 @@ func (m *Index) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *IndexUpdate) Unmarshal(dAtA []byte) error {
+func (m *BatchRequest) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -3391,13 +8402,32 @@ func (m *IndexUpdate) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: IndexUpdate: wiretype end group for non-group")
+			return fmt.Errorf("proto: BatchRequest: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: IndexUpdate: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: BatchRequest: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ID", wireType)
+			}
+			m.ID = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ID |= int32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
 			if wireType != 2 {
 				return fmt.Errorf("proto: wrong wireType = %d for field Folder", wireType)
 			}
@@ -3429,7 +8459,7 @@ func (m *IndexUpdate) Unmarshal(dAtA []byte) error {
 			}
 			m.Folder = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 2:
+		case 3:
 			if wireType != 2 {
 				return fmt.Errorf("proto: wrong wireType = %d for field Files", wireType)
 			}
@@ -3458,7 +8488,7 @@ func (m *IndexUpdate) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Files = append(m.Files, FileInfo{})
+			m.Files = append(m.Files, BatchRequestFile{})
 			if err := m.Files[len(m.Files)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
@@ -3487,7 +8517,7 @@ func (m *IndexUpdate) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *FileInfo) Unmarshal(dAtA []byte) error {
+func (m *BatchRequestFile) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -3506,149 +8536,21 @@ func (m *FileInfo) Unmarshal(dAtA []byte) error {
 			if b < 0x80 {
 				break
 			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: FileInfo: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: FileInfo: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
-			}
-			var stringLen uint64
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowBep
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthBep
-			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
-				return ErrInvalidLengthBep
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Name = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
-		case 2:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Type", wireType)
-			}
-			m.Type = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowBep
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.Type |= FileInfoType(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-		case 3:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Size", wireType)
-			}
-			m.Size = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowBep
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.Size |= int64(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-		case 4:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Permissions", wireType)
-			}
-			m.Permissions = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowBep
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.Permissions |= uint32(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-		case 5:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ModifiedS", wireType)
-			}
-			m.ModifiedS = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowBep
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.ModifiedS |= int64(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-		case 6:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Deleted", wireType)
-			}
-			var v int
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowBep
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				v |= int(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-			m.Deleted = bool(v != 0)
-		case 7:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field RawInvalid", wireType)
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: BatchRequestFile: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: BatchRequestFile: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
 			}
-			var v int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowBep
@@ -3658,17 +8560,29 @@ func (m *FileInfo) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			m.RawInvalid = bool(v != 0)
-		case 8:
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Name = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field NoPermissions", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Size", wireType)
 			}
-			var v int
+			m.Size = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowBep
@@ -3678,17 +8592,16 @@ func (m *FileInfo) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= int(b&0x7F) << shift
+				m.Size |= int32(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			m.NoPermissions = bool(v != 0)
-		case 9:
+		case 3:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Version", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Hash", wireType)
 			}
-			var msglen int
+			var byteLen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowBep
@@ -3698,30 +8611,31 @@ func (m *FileInfo) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				byteLen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			if byteLen < 0 {
 				return ErrInvalidLengthBep
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + byteLen
 			if postIndex < 0 {
 				return ErrInvalidLengthBep
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if err := m.Version.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
+			m.Hash = append(m.Hash[:0], dAtA[iNdEx:postIndex]...)
+			if m.Hash == nil {
+				m.Hash = []byte{}
 			}
 			iNdEx = postIndex
-		case 10:
+		case 4:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Sequence", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field WeakHash", wireType)
 			}
-			m.Sequence = 0
+			m.WeakHash = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowBep
@@ -3731,73 +8645,69 @@ func (m *FileInfo) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Sequence |= int64(b&0x7F) << shift
+				m.WeakHash |= uint32(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 11:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ModifiedNs", wireType)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipBep(dAtA[iNdEx:])
+			if err != nil {
+				return err
 			}
-			m.ModifiedNs = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowBep
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.ModifiedNs |= int32(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
+			if skippy < 0 {
+				return ErrInvalidLengthBep
 			}
-		case 12:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ModifiedBy", wireType)
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthBep
 			}
-			m.ModifiedBy = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowBep
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.ModifiedBy |= ShortID(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
 			}
-		case 13:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field RawBlockSize", wireType)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *BatchResponse) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowBep
 			}
-			m.RawBlockSize = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowBep
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.RawBlockSize |= int32(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
 			}
-		case 16:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Blocks", wireType)
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
 			}
-			var msglen int
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: BatchResponse: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: BatchResponse: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ID", wireType)
+			}
+			m.ID = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowBep
@@ -3807,31 +8717,16 @@ func (m *FileInfo) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				m.ID |= int32(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
-				return ErrInvalidLengthBep
-			}
-			postIndex := iNdEx + msglen
-			if postIndex < 0 {
-				return ErrInvalidLengthBep
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Blocks = append(m.Blocks, BlockInfo{})
-			if err := m.Blocks[len(m.Blocks)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
-			iNdEx = postIndex
-		case 17:
+		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field SymlinkTarget", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Data", wireType)
 			}
-			var stringLen uint64
+			var byteLen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowBep
@@ -3841,80 +8736,94 @@ func (m *FileInfo) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				byteLen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
+			if byteLen < 0 {
 				return ErrInvalidLengthBep
 			}
-			postIndex := iNdEx + intStringLen
+			postIndex := iNdEx + byteLen
 			if postIndex < 0 {
 				return ErrInvalidLengthBep
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.SymlinkTarget = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
-		case 18:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Gid", wireType)
-			}
-			m.Gid = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowBep
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.Gid |= int32(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-		case 19:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Uid", wireType)
+			m.Data = append(m.Data[:0], dAtA[iNdEx:postIndex]...)
+			if m.Data == nil {
+				m.Data = []byte{}
 			}
-			m.Uid = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowBep
+			iNdEx = postIndex
+		case 3:
+			if wireType == 0 {
+				var v ErrorCode
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowBep
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					v |= ErrorCode(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
 				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
+				m.Codes = append(m.Codes, v)
+			} else if wireType == 2 {
+				var packedLen int
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowBep
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					packedLen |= int(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
 				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.Uid |= int32(b&0x7F) << shift
-				if b < 0x80 {
-					break
+				if packedLen < 0 {
+					return ErrInvalidLengthBep
 				}
-			}
-		case 1000:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field LocalFlags", wireType)
-			}
-			m.LocalFlags = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowBep
+				postIndex := iNdEx + packedLen
+				if postIndex < 0 {
+					return ErrInvalidLengthBep
 				}
-				if iNdEx >= l {
+				if postIndex > l {
 					return io.ErrUnexpectedEOF
 				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.LocalFlags |= uint32(b&0x7F) << shift
-				if b < 0x80 {
-					break
+				var elementCount int
+				if elementCount != 0 && len(m.Codes) == 0 {
+					m.Codes = make([]ErrorCode, 0, elementCount)
+				}
+				for iNdEx < postIndex {
+					var v ErrorCode
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowBep
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						v |= ErrorCode(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					m.Codes = append(m.Codes, v)
 				}
+			} else {
+				return fmt.Errorf("proto: wrong wireType = %d for field Codes", wireType)
 			}
 		default:
 			iNdEx = preIndex
@@ -3940,7 +8849,7 @@ func (m *FileInfo) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *BlockInfo) Unmarshal(dAtA []byte) error {
+func (m *DownloadProgress) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -3963,17 +8872,17 @@ func (m *BlockInfo) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: BlockInfo: wiretype end group for non-group")
+			return fmt.Errorf("proto: DownloadProgress: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: BlockInfo: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: DownloadProgress: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Offset", wireType)
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Folder", wireType)
 			}
-			m.Offset = 0
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowBep
@@ -3983,35 +8892,29 @@ func (m *BlockInfo) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Offset |= int64(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 2:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Size", wireType)
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthBep
 			}
-			m.Size = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowBep
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.Size |= int32(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
 			}
-		case 3:
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Folder = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Hash", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Updates", wireType)
 			}
-			var byteLen int
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowBep
@@ -4021,45 +8924,26 @@ func (m *BlockInfo) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= int(b&0x7F) << shift
+				msglen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if byteLen < 0 {
+			if msglen < 0 {
 				return ErrInvalidLengthBep
 			}
-			postIndex := iNdEx + byteLen
+			postIndex := iNdEx + msglen
 			if postIndex < 0 {
 				return ErrInvalidLengthBep
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Hash = append(m.Hash[:0], dAtA[iNdEx:postIndex]...)
-			if m.Hash == nil {
-				m.Hash = []byte{}
+			m.Updates = append(m.Updates, FileDownloadProgressUpdate{})
+			if err := m.Updates[len(m.Updates)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
 			}
 			iNdEx = postIndex
-		case 4:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field WeakHash", wireType)
-			}
-			m.WeakHash = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowBep
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.WeakHash |= uint32(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipBep(dAtA[iNdEx:])
@@ -4084,7 +8968,7 @@ func (m *BlockInfo) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *Vector) Unmarshal(dAtA []byte) error {
+func (m *FileDownloadProgressUpdate) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -4107,15 +8991,66 @@ func (m *Vector) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Vector: wiretype end group for non-group")
+			return fmt.Errorf("proto: FileDownloadProgressUpdate: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Vector: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: FileDownloadProgressUpdate: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field UpdateType", wireType)
+			}
+			m.UpdateType = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.UpdateType |= FileDownloadProgressUpdateType(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Counters", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Name = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Version", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -4132,21 +9067,96 @@ func (m *Vector) Unmarshal(dAtA []byte) error {
 					break
 				}
 			}
-			if msglen < 0 {
-				return ErrInvalidLengthBep
-			}
-			postIndex := iNdEx + msglen
-			if postIndex < 0 {
-				return ErrInvalidLengthBep
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Counters = append(m.Counters, Counter{})
-			if err := m.Counters[len(m.Counters)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
-			iNdEx = postIndex
+			if msglen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.Version.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 4:
+			if wireType == 0 {
+				var v int32
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowBep
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					v |= int32(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				m.BlockIndexes = append(m.BlockIndexes, v)
+			} else if wireType == 2 {
+				var packedLen int
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowBep
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					packedLen |= int(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				if packedLen < 0 {
+					return ErrInvalidLengthBep
+				}
+				postIndex := iNdEx + packedLen
+				if postIndex < 0 {
+					return ErrInvalidLengthBep
+				}
+				if postIndex > l {
+					return io.ErrUnexpectedEOF
+				}
+				var elementCount int
+				var count int
+				for _, integer := range dAtA[iNdEx:postIndex] {
+					if integer < 128 {
+						count++
+					}
+				}
+				elementCount = count
+				if elementCount != 0 && len(m.BlockIndexes) == 0 {
+					m.BlockIndexes = make([]int32, 0, elementCount)
+				}
+				for iNdEx < postIndex {
+					var v int32
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowBep
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						v |= int32(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					m.BlockIndexes = append(m.BlockIndexes, v)
+				}
+			} else {
+				return fmt.Errorf("proto: wrong wireType = %d for field BlockIndexes", wireType)
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipBep(dAtA[iNdEx:])
@@ -4171,7 +9181,7 @@ func (m *Vector) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *Counter) Unmarshal(dAtA []byte) error {
+func (m *Ping) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -4194,50 +9204,12 @@ func (m *Counter) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Counter: wiretype end group for non-group")
+			return fmt.Errorf("proto: Ping: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Counter: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: Ping: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
-		case 1:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ID", wireType)
-			}
-			m.ID = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowBep
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.ID |= ShortID(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-		case 2:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Value", wireType)
-			}
-			m.Value = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowBep
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.Value |= uint64(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipBep(dAtA[iNdEx:])
@@ -4262,7 +9234,7 @@ func (m *Counter) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *Request) Unmarshal(dAtA []byte) error {
+func (m *Close) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -4285,66 +9257,15 @@ func (m *Request) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Request: wiretype end group for non-group")
+			return fmt.Errorf("proto: Close: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Request: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: Close: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ID", wireType)
-			}
-			m.ID = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowBep
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.ID |= int32(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-		case 2:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Folder", wireType)
-			}
-			var stringLen uint64
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowBep
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthBep
-			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
-				return ErrInvalidLengthBep
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Folder = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
-		case 3:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Reason", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -4372,119 +9293,8 @@ func (m *Request) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Name = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
-		case 4:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Offset", wireType)
-			}
-			m.Offset = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowBep
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.Offset |= int64(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-		case 5:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Size", wireType)
-			}
-			m.Size = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowBep
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.Size |= int32(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-		case 6:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Hash", wireType)
-			}
-			var byteLen int
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowBep
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				byteLen |= int(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-			if byteLen < 0 {
-				return ErrInvalidLengthBep
-			}
-			postIndex := iNdEx + byteLen
-			if postIndex < 0 {
-				return ErrInvalidLengthBep
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Hash = append(m.Hash[:0], dAtA[iNdEx:postIndex]...)
-			if m.Hash == nil {
-				m.Hash = []byte{}
-			}
+			m.Reason = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 7:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field FromTemporary", wireType)
-			}
-			var v int
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowBep
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				v |= int(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-			m.FromTemporary = bool(v != 0)
-		case 8:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field WeakHash", wireType)
-			}
-			m.WeakHash = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowBep
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.WeakHash |= uint32(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipBep(dAtA[iNdEx:])
@@ -4509,7 +9319,7 @@ func (m *Request) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *Response) Unmarshal(dAtA []byte) error {
+func (m *ApplicationMessage) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -4532,17 +9342,17 @@ func (m *Response) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Response: wiretype end group for non-group")
+			return fmt.Errorf("proto: ApplicationMessage: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Response: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: ApplicationMessage: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ID", wireType)
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Type", wireType)
 			}
-			m.ID = 0
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowBep
@@ -4552,11 +9362,24 @@ func (m *Response) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.ID |= int32(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Type = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
 		case 2:
 			if wireType != 2 {
 				return fmt.Errorf("proto: wrong wireType = %d for field Data", wireType)
@@ -4591,25 +9414,6 @@ func (m *Response) Unmarshal(dAtA []byte) error {
 				m.Data = []byte{}
 			}
 			iNdEx = postIndex
-		case 3:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Code", wireType)
-			}
-			m.Code = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowBep
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.Code |= ErrorCode(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipBep(dAtA[iNdEx:])
@@ -4634,7 +9438,7 @@ func (m *Response) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *DownloadProgress) Unmarshal(dAtA []byte) error {
+func (m *ScanRequest) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -4657,10 +9461,10 @@ func (m *DownloadProgress) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: DownloadProgress: wiretype end group for non-group")
+			return fmt.Errorf("proto: ScanRequest: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: DownloadProgress: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: ScanRequest: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
@@ -4697,9 +9501,9 @@ func (m *DownloadProgress) Unmarshal(dAtA []byte) error {
 			iNdEx = postIndex
 		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Updates", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Subdirs", wireType)
 			}
-			var msglen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowBep
@@ -4709,25 +9513,23 @@ func (m *DownloadProgress) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthBep
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + intStringLen
 			if postIndex < 0 {
 				return ErrInvalidLengthBep
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Updates = append(m.Updates, FileDownloadProgressUpdate{})
-			if err := m.Updates[len(m.Updates)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
+			m.Subdirs = append(m.Subdirs, string(dAtA[iNdEx:postIndex]))
 			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
@@ -4753,7 +9555,7 @@ func (m *DownloadProgress) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *FileDownloadProgressUpdate) Unmarshal(dAtA []byte) error {
+func (m *IndexSnapshot) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -4776,34 +9578,15 @@ func (m *FileDownloadProgressUpdate) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: FileDownloadProgressUpdate: wiretype end group for non-group")
+			return fmt.Errorf("proto: IndexSnapshot: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: FileDownloadProgressUpdate: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: IndexSnapshot: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field UpdateType", wireType)
-			}
-			m.UpdateType = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowBep
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.UpdateType |= FileDownloadProgressUpdateType(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Folder", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -4831,13 +9614,13 @@ func (m *FileDownloadProgressUpdate) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Name = string(dAtA[iNdEx:postIndex])
+			m.Folder = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 3:
+		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Version", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Data", wireType)
 			}
-			var msglen int
+			var byteLen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowBep
@@ -4847,154 +9630,46 @@ func (m *FileDownloadProgressUpdate) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				byteLen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			if byteLen < 0 {
 				return ErrInvalidLengthBep
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + byteLen
 			if postIndex < 0 {
 				return ErrInvalidLengthBep
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if err := m.Version.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
+			m.Data = append(m.Data[:0], dAtA[iNdEx:postIndex]...)
+			if m.Data == nil {
+				m.Data = []byte{}
 			}
 			iNdEx = postIndex
-		case 4:
-			if wireType == 0 {
-				var v int32
-				for shift := uint(0); ; shift += 7 {
-					if shift >= 64 {
-						return ErrIntOverflowBep
-					}
-					if iNdEx >= l {
-						return io.ErrUnexpectedEOF
-					}
-					b := dAtA[iNdEx]
-					iNdEx++
-					v |= int32(b&0x7F) << shift
-					if b < 0x80 {
-						break
-					}
-				}
-				m.BlockIndexes = append(m.BlockIndexes, v)
-			} else if wireType == 2 {
-				var packedLen int
-				for shift := uint(0); ; shift += 7 {
-					if shift >= 64 {
-						return ErrIntOverflowBep
-					}
-					if iNdEx >= l {
-						return io.ErrUnexpectedEOF
-					}
-					b := dAtA[iNdEx]
-					iNdEx++
-					packedLen |= int(b&0x7F) << shift
-					if b < 0x80 {
-						break
-					}
-				}
-				if packedLen < 0 {
-					return ErrInvalidLengthBep
-				}
-				postIndex := iNdEx + packedLen
-				if postIndex < 0 {
-					return ErrInvalidLengthBep
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field First", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
 				}
-				if postIndex > l {
+				if iNdEx >= l {
 					return io.ErrUnexpectedEOF
 				}
-				var elementCount int
-				var count int
-				for _, integer := range dAtA[iNdEx:postIndex] {
-					if integer < 128 {
-						count++
-					}
-				}
-				elementCount = count
-				if elementCount != 0 && len(m.BlockIndexes) == 0 {
-					m.BlockIndexes = make([]int32, 0, elementCount)
-				}
-				for iNdEx < postIndex {
-					var v int32
-					for shift := uint(0); ; shift += 7 {
-						if shift >= 64 {
-							return ErrIntOverflowBep
-						}
-						if iNdEx >= l {
-							return io.ErrUnexpectedEOF
-						}
-						b := dAtA[iNdEx]
-						iNdEx++
-						v |= int32(b&0x7F) << shift
-						if b < 0x80 {
-							break
-						}
-					}
-					m.BlockIndexes = append(m.BlockIndexes, v)
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
 				}
-			} else {
-				return fmt.Errorf("proto: wrong wireType = %d for field BlockIndexes", wireType)
-			}
-		default:
-			iNdEx = preIndex
-			skippy, err := skipBep(dAtA[iNdEx:])
-			if err != nil {
-				return err
-			}
-			if skippy < 0 {
-				return ErrInvalidLengthBep
-			}
-			if (iNdEx + skippy) < 0 {
-				return ErrInvalidLengthBep
-			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
-			}
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *Ping) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowBep
-			}
-			if iNdEx >= l {
-				return io.ErrUnexpectedEOF
 			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= uint64(b&0x7F) << shift
-			if b < 0x80 {
-				break
-			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: Ping: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Ping: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
+			m.First = bool(v != 0)
 		default:
 			iNdEx = preIndex
 			skippy, err := skipBep(dAtA[iNdEx:])
@@ -5019,7 +9694,7 @@ func (m *Ping) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *Close) Unmarshal(dAtA []byte) error {
+func (m *IndexAck) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -5042,17 +9717,17 @@ func (m *Close) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Close: wiretype end group for non-group")
+			return fmt.Errorf("proto: IndexAck: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Close: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: IndexAck: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Reason", wireType)
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Size", wireType)
 			}
-			var stringLen uint64
+			m.Size = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowBep
@@ -5062,24 +9737,11 @@ func (m *Close) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				m.Size |= int64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthBep
-			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
-				return ErrInvalidLengthBep
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Reason = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipBep(dAtA[iNdEx:])
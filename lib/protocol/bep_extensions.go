@@ -66,6 +66,10 @@ func (f FileInfo) IsReceiveOnlyChanged() bool {
 	return f.LocalFlags&FlagLocalReceiveOnly != 0
 }
 
+func (f FileInfo) IsPlaceholder() bool {
+	return f.LocalFlags&FlagLocalPlaceholder != 0
+}
+
 func (f FileInfo) IsDirectory() bool {
 	return f.Type == FileInfoTypeDirectory
 }
@@ -104,6 +108,17 @@ func (f FileInfo) BlockSize() int {
 	return int(f.RawBlockSize)
 }
 
+// InHole returns whether all of the length bytes at offset are in a hole
+// of the file.
+func (f FileInfo) InHole(offset, length int64) bool {
+	for _, h := range f.Holes {
+		if h.Offset <= offset && offset+length <= h.Offset+h.Length {
+			return true
+		}
+	}
+	return false
+}
+
 func (f FileInfo) FileName() string {
 	return f.Name
 }
@@ -0,0 +1,36 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import "sort"
+
+// SupportedBlockSchemes are the block schemes we understand, in the files
+// of others as well as our own.
+var SupportedBlockSchemes = []BlockScheme{BlockSchemeFixed, BlockSchemeFastCDC}
+
+// SupportsBlockScheme returns whether the other side understands files
+// divided into blocks using the given scheme. Everyone understands fixed
+// size blocks.
+func (h HelloResult) SupportsBlockScheme(scheme BlockScheme) bool {
+	if scheme == BlockSchemeFixed {
+		return true
+	}
+	for _, s := range h.BlockSchemes {
+		if s == scheme {
+			return true
+		}
+	}
+	return false
+}
+
+// BlockIndex returns the index of the block containing the given offset.
+// For offsets past the end of the file, the index is past the last block.
+func (f FileInfo) BlockIndex(offset int64) int {
+	if f.BlockScheme == BlockSchemeFixed {
+		return int(offset / int64(f.BlockSize()))
+	}
+	// Content defined blocks vary in size, but are in order.
+	return sort.Search(len(f.Blocks), func(i int) bool {
+		return f.Blocks[i].Offset+int64(f.Blocks[i].Size) > offset
+	})
+}
@@ -0,0 +1,48 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import "testing"
+
+func TestBlockIndex(t *testing.T) {
+	fixed := FileInfo{RawBlockSize: MinBlockSize}
+	cdc := FileInfo{
+		BlockScheme: BlockSchemeFastCDC,
+		Blocks: []BlockInfo{
+			{Offset: 0, Size: 1000},
+			{Offset: 1000, Size: 3000},
+			{Offset: 4000, Size: 2000},
+		},
+	}
+
+	cases := []struct {
+		file   FileInfo
+		offset int64
+		index  int
+	}{
+		{fixed, 0, 0},
+		{fixed, MinBlockSize - 1, 0},
+		{fixed, 3 * MinBlockSize, 3},
+		{cdc, 0, 0},
+		{cdc, 999, 0},
+		{cdc, 1000, 1},
+		{cdc, 3999, 1},
+		{cdc, 5999, 2},
+		{cdc, 6000, 3},
+	}
+	for _, tc := range cases {
+		if index := tc.file.BlockIndex(tc.offset); index != tc.index {
+			t.Errorf("scheme %v offset %d: expected index %d, got %d", tc.file.BlockScheme, tc.offset, tc.index, index)
+		}
+	}
+
+	if !(HelloResult{}).SupportsBlockScheme(BlockSchemeFixed) {
+		t.Error("expected everyone to support fixed size blocks")
+	}
+	if (HelloResult{}).SupportsBlockScheme(BlockSchemeFastCDC) {
+		t.Error("expected an old device not to support content defined blocks")
+	}
+	if !(HelloResult{BlockSchemes: SupportedBlockSchemes}).SupportsBlockScheme(BlockSchemeFastCDC) {
+		t.Error("expected content defined blocks to be supported")
+	}
+}
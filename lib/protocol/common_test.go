@@ -13,8 +13,12 @@ type TestModel struct {
 	hash          []byte
 	weakHash      uint32
 	fromTemporary bool
+	priority      RequestPriority
 	indexFn       func(DeviceID, string, []FileInfo)
+	indexUpdateFn func(DeviceID, string, []FileInfo)
+	requestFn     func(folder, name string) ([]byte, error)
 	ccFn          func(DeviceID, ClusterConfig)
+	appMsgFn      func(DeviceID, string, []byte)
 	closedCh      chan struct{}
 	closedErr     error
 }
@@ -33,10 +37,13 @@ func (t *TestModel) Index(deviceID DeviceID, folder string, files []FileInfo) er
 }
 
 func (t *TestModel) IndexUpdate(deviceID DeviceID, folder string, files []FileInfo) error {
+	if t.indexUpdateFn != nil {
+		t.indexUpdateFn(deviceID, folder, files)
+	}
 	return nil
 }
 
-func (t *TestModel) Request(deviceID DeviceID, folder, name string, size int32, offset int64, hash []byte, weakHash uint32, fromTemporary bool) (RequestResponse, error) {
+func (t *TestModel) Request(deviceID DeviceID, folder, name string, size int32, offset int64, hash []byte, weakHash uint32, fromTemporary bool, priority RequestPriority) (RequestResponse, error) {
 	t.folder = folder
 	t.name = name
 	t.offset = offset
@@ -44,6 +51,14 @@ func (t *TestModel) Request(deviceID DeviceID, folder, name string, size int32,
 	t.hash = hash
 	t.weakHash = weakHash
 	t.fromTemporary = fromTemporary
+	t.priority = priority
+	if t.requestFn != nil {
+		data, err := t.requestFn(folder, name)
+		if err != nil {
+			return nil, err
+		}
+		return &fakeRequestResponse{data}, nil
+	}
 	buf := make([]byte, len(t.data))
 	copy(buf, t.data)
 	return &fakeRequestResponse{buf}, nil
@@ -65,6 +80,17 @@ func (t *TestModel) DownloadProgress(DeviceID, string, []FileDownloadProgressUpd
 	return nil
 }
 
+func (t *TestModel) ApplicationMessage(deviceID DeviceID, msgType string, data []byte) error {
+	if t.appMsgFn != nil {
+		t.appMsgFn(deviceID, msgType, data)
+	}
+	return nil
+}
+
+func (t *TestModel) ScanRequest(DeviceID, string, []string) error {
+	return nil
+}
+
 func (t *TestModel) closedError() error {
 	select {
 	case <-t.closedCh:
@@ -2,7 +2,12 @@
 
 package protocol
 
-import "fmt"
+import (
+	"fmt"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
 
 const (
 	compressionThreshold = 128 // don't bother compressing messages smaller than this many bytes
@@ -37,3 +42,68 @@ func (c *Compression) UnmarshalText(bs []byte) error {
 	*c = compressionUnmarshal[string(bs)]
 	return nil
 }
+
+var compressionAlgorithmMarshal = map[CompressionAlgorithm]string{
+	CompressionAlgorithmLZ4:  "lz4",
+	CompressionAlgorithmZstd: "zstd",
+}
+
+var compressionAlgorithmUnmarshal = map[string]CompressionAlgorithm{
+	"lz4":  CompressionAlgorithmLZ4,
+	"zstd": CompressionAlgorithmZstd,
+}
+
+// SupportedCompressionAlgorithms are the algorithms we can decompress
+// messages in, as announced in the Hello message.
+var SupportedCompressionAlgorithms = []CompressionAlgorithm{
+	CompressionAlgorithmLZ4,
+	CompressionAlgorithmZstd,
+}
+
+func (a CompressionAlgorithm) GoString() string {
+	return fmt.Sprintf("%q", a.String())
+}
+
+func (a CompressionAlgorithm) MarshalText() ([]byte, error) {
+	return []byte(compressionAlgorithmMarshal[a]), nil
+}
+
+func (a *CompressionAlgorithm) UnmarshalText(bs []byte) error {
+	*a = compressionAlgorithmUnmarshal[string(bs)]
+	return nil
+}
+
+// CompressionAlgorithmFor returns the algorithm to compress messages to the
+// other side with: the preferred one if the other side announced support
+// for it, otherwise LZ4, which all versions support.
+func (h HelloResult) CompressionAlgorithmFor(preferred CompressionAlgorithm) CompressionAlgorithm {
+	for _, a := range h.CompressionAlgorithms {
+		if a == preferred {
+			return preferred
+		}
+	}
+	return CompressionAlgorithmLZ4
+}
+
+// The zstd encoder and decoder are safe for concurrent use and expensive to
+// set up, so all connections share one of each.
+var (
+	zstdEncoder     *zstd.Encoder
+	zstdDecoder     *zstd.Decoder
+	zstdEncoderOnce sync.Once
+	zstdDecoderOnce sync.Once
+)
+
+func zstdCompress(src []byte) []byte {
+	zstdEncoderOnce.Do(func() {
+		zstdEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault), zstd.WithEncoderConcurrency(1))
+	})
+	return zstdEncoder.EncodeAll(src, BufferPool.Get(len(src))[:0])
+}
+
+func zstdDecompress(src []byte) ([]byte, error) {
+	zstdDecoderOnce.Do(func() {
+		zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderMaxMemory(MaxMessageLen), zstd.WithDecoderConcurrency(1))
+	})
+	return zstdDecoder.DecodeAll(src, nil)
+}
@@ -0,0 +1,60 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"hash"
+
+	"github.com/syncthing/syncthing/lib/blake3"
+	"github.com/syncthing/syncthing/lib/sha256"
+)
+
+// SupportedHashAlgorithms are the hash algorithms we understand, in the
+// files of others as well as our own.
+var SupportedHashAlgorithms = []HashAlgorithm{HashAlgorithmSHA256, HashAlgorithmBLAKE3}
+
+// SupportsHashAlgorithm returns whether the device that sent the folder in
+// its cluster config understands files of it hashed with the given
+// algorithm. Everyone understands SHA-256.
+func (f Folder) SupportsHashAlgorithm(algo HashAlgorithm) bool {
+	if algo == HashAlgorithmSHA256 {
+		return true
+	}
+	for _, a := range f.HashAlgorithms {
+		if a == algo {
+			return true
+		}
+	}
+	return false
+}
+
+// The BLAKE3 implementation. That of SHA-256 is in lib/sha256.
+var newBLAKE3 = blake3.New
+
+// NewHash returns a new hash of the algorithm, SHA-256 for any we don't
+// know.
+func (a HashAlgorithm) NewHash() hash.Hash {
+	if a == HashAlgorithmBLAKE3 {
+		return newBLAKE3()
+	}
+	return sha256.New()
+}
+
+// SetHashImplementation switches the implementation of the algorithm for
+// another, such as one that is faster on this system. It must be called
+// at startup, before anything is hashed.
+func SetHashImplementation(algo HashAlgorithm, newFn func() hash.Hash) {
+	switch algo {
+	case HashAlgorithmSHA256:
+		sha256.New = newFn
+		sha256.Sum256 = func(data []byte) [32]byte {
+			var sum [32]byte
+			h := newFn()
+			h.Write(data)
+			h.Sum(sum[:0])
+			return sum
+		}
+	case HashAlgorithmBLAKE3:
+		newBLAKE3 = newFn
+	}
+}
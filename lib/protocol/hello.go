@@ -20,9 +20,18 @@ type HelloIntf interface {
 // The HelloResult is the non version specific interpretation of the other
 // side's Hello message.
 type HelloResult struct {
-	DeviceName    string
-	ClientName    string
-	ClientVersion string
+	DeviceName                  string
+	ClientName                  string
+	ClientVersion               string
+	SupportsBatchRequests       bool
+	CompressionAlgorithms       []CompressionAlgorithm
+	SupportsMultipath           bool
+	SupportsPadding             bool
+	SupportsApplicationMessages bool
+	SupportsScanRequests        bool
+	BlockSchemes                []BlockScheme
+	SupportsIndexSnapshots      bool
+	IndexWindow                 int64
 }
 
 var (
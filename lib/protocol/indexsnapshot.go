@@ -0,0 +1,111 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// MaxIndexSnapshotSize is how large the files in an index snapshot message
+// should be in total, before compression. Each message is compressed on
+// its own, so the larger it is the better the compression, while the
+// receiver must hold all of it in memory.
+const MaxIndexSnapshotSize = 16 << MiB
+
+var errIndexSnapshotCorrupt = errors.New("corrupt file list")
+
+// IndexSnapshot sends the files as part of a snapshot of the index, in a
+// single compressed message. A snapshot takes the place of the initial
+// index and its updates for large folders: the first message of it replaces
+// the index the other device has of ours, as an Index message does, the
+// rest add to it. The other device must support index snapshots.
+func (c *rawConnection) IndexSnapshot(ctx context.Context, folder string, files []FileInfo, first bool) error {
+	select {
+	case <-c.closed:
+		return ErrClosed
+	default:
+	}
+	data, err := encodeIndexSnapshot(files)
+	if err != nil {
+		return err
+	}
+	msg := &IndexSnapshot{
+		Folder: folder,
+		Data:   data,
+		First:  first,
+	}
+	c.idxMut.Lock()
+	defer c.idxMut.Unlock()
+	if !c.takeIndexWindow(msg.ProtoSize()) {
+		BufferPool.Put(data)
+		return ErrClosed
+	}
+	done := make(chan struct{})
+	if !c.send(ctx, msg, done) {
+		return ErrClosed
+	}
+	select {
+	case <-done:
+		BufferPool.Put(data)
+	case <-c.closed:
+	}
+	return nil
+}
+
+func (c *rawConnection) handleIndexSnapshot(folder string, files []FileInfo, first bool) error {
+	l.Debugf("IndexSnapshot(%v, %v, %d files, first=%v)", c.id, folder, len(files), first)
+	if first {
+		return c.receiver.Index(c.id, folder, files)
+	}
+	return c.receiver.IndexUpdate(c.id, folder, files)
+}
+
+// encodeIndexSnapshot returns the files, each preceded by its length as a
+// four byte big endian integer, compressed with zstd. The returned slice
+// is from the BufferPool.
+func encodeIndexSnapshot(files []FileInfo) ([]byte, error) {
+	size := 0
+	for _, f := range files {
+		size += 4 + f.ProtoSize()
+	}
+	buf := BufferPool.Get(size)
+	defer BufferPool.Put(buf)
+	offset := 0
+	for _, f := range files {
+		n, err := f.MarshalTo(buf[offset+4:])
+		if err != nil {
+			return nil, err
+		}
+		binary.BigEndian.PutUint32(buf[offset:], uint32(n))
+		offset += 4 + n
+	}
+	return zstdCompress(buf[:offset]), nil
+}
+
+func decodeIndexSnapshot(data []byte) ([]FileInfo, error) {
+	buf, err := zstdDecompress(data)
+	if err != nil {
+		return nil, fmt.Errorf("decompressing: %v", err)
+	}
+	var files []FileInfo
+	for len(buf) > 0 {
+		if len(buf) < 4 {
+			return nil, errIndexSnapshotCorrupt
+		}
+		n := binary.BigEndian.Uint32(buf)
+		buf = buf[4:]
+		if uint64(n) > uint64(len(buf)) {
+			return nil, errIndexSnapshotCorrupt
+		}
+		var f FileInfo
+		if err := f.Unmarshal(buf[:n]); err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+		buf = buf[n:]
+	}
+	return files, nil
+}
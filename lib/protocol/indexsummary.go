@@ -0,0 +1,92 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/fnv"
+	"strings"
+
+	"github.com/syncthing/syncthing/lib/sha256"
+)
+
+// IndexSummaryBuckets is the number of buckets in an index summary. Files
+// are put in buckets by their top level directory, so that the divergent
+// buckets of two summaries tell roughly where the indexes differ.
+const IndexSummaryBuckets = 64
+
+const indexSummaryHashSize = 32
+
+// IndexSummaryBucket returns the bucket of the file with the given name.
+func IndexSummaryBucket(name string) int {
+	if i := strings.IndexAny(name, `/\`); i >= 0 {
+		name = name[:i]
+	}
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return int(h.Sum32() % IndexSummaryBuckets)
+}
+
+// An IndexSummaryBuilder summarizes an index, taking the files in any
+// order. The hash of a bucket is the XOR of the hashes of the name, version
+// and deletion of its files.
+type IndexSummaryBuilder struct {
+	buckets [IndexSummaryBuckets][indexSummaryHashSize]byte
+	buf     []byte
+}
+
+// Add adds a file to the summary. Invalid files, which aren't synced, are
+// to be left out.
+func (b *IndexSummaryBuilder) Add(name string, version Vector, deleted bool) {
+	b.buf = append(b.buf[:0], name...)
+	b.buf = append(b.buf, 0)
+	var counter [16]byte
+	for _, c := range version.Counters {
+		binary.BigEndian.PutUint64(counter[:], uint64(c.ID))
+		binary.BigEndian.PutUint64(counter[8:], c.Value)
+		b.buf = append(b.buf, counter[:]...)
+	}
+	if deleted {
+		b.buf = append(b.buf, 1)
+	}
+	hash := sha256.Sum256(b.buf)
+	bucket := &b.buckets[IndexSummaryBucket(name)]
+	for i := range bucket {
+		bucket[i] ^= hash[i]
+	}
+}
+
+// Summary returns the summary of the files added so far.
+func (b *IndexSummaryBuilder) Summary() IndexSummary {
+	s := IndexSummary{Buckets: make([][]byte, IndexSummaryBuckets)}
+	all := make([]byte, 0, IndexSummaryBuckets*indexSummaryHashSize)
+	for i := range b.buckets {
+		s.Buckets[i] = append([]byte(nil), b.buckets[i][:]...)
+		all = append(all, b.buckets[i][:]...)
+	}
+	root := sha256.Sum256(all)
+	s.Root = root[:]
+	return s
+}
+
+// Equal returns whether the summarized indexes are the same. Empty
+// summaries are equal to none.
+func (s IndexSummary) Equal(other IndexSummary) bool {
+	return len(s.Root) > 0 && bytes.Equal(s.Root, other.Root)
+}
+
+// DivergentBuckets returns the buckets in which the summarized indexes
+// differ.
+func (s IndexSummary) DivergentBuckets(other IndexSummary) []int {
+	if s.Equal(other) {
+		return nil
+	}
+	var buckets []int
+	for i := 0; i < IndexSummaryBuckets; i++ {
+		if i >= len(s.Buckets) || i >= len(other.Buckets) || !bytes.Equal(s.Buckets[i], other.Buckets[i]) {
+			buckets = append(buckets, i)
+		}
+	}
+	return buckets
+}
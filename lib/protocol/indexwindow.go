@@ -0,0 +1,77 @@
+// Copyright (C) 2020 The Protocol Authors.
+
+package protocol
+
+import (
+	"context"
+	"sync"
+)
+
+// indexWindow limits how many bytes of index messages are on their way to
+// the other device or being handled there. The other device acknowledges
+// each index message once it has handled it, freeing up its size.
+type indexWindow struct {
+	max         int
+	outstanding int
+	closed      bool
+	mut         sync.Mutex
+	cond        *sync.Cond
+}
+
+func newIndexWindow(max int) *indexWindow {
+	w := &indexWindow{max: max}
+	w.cond = sync.NewCond(&w.mut)
+	return w
+}
+
+// take waits for there to be room for the given number of bytes, and takes
+// it. A message larger than the window goes when nothing else is
+// outstanding. It returns false if the connection was closed meanwhile.
+func (w *indexWindow) take(bytes int) bool {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+	for !w.closed && w.outstanding > 0 && w.outstanding+bytes > w.max {
+		w.cond.Wait()
+	}
+	if w.closed {
+		return false
+	}
+	w.outstanding += bytes
+	return true
+}
+
+func (w *indexWindow) give(bytes int) {
+	w.mut.Lock()
+	w.outstanding -= bytes
+	if w.outstanding < 0 {
+		w.outstanding = 0
+	}
+	w.cond.Broadcast()
+	w.mut.Unlock()
+}
+
+func (w *indexWindow) close() {
+	w.mut.Lock()
+	w.closed = true
+	w.cond.Broadcast()
+	w.mut.Unlock()
+}
+
+// takeIndexWindow waits for the other side to have room for an index
+// message of the given size. It returns false if the connection was closed.
+func (c *rawConnection) takeIndexWindow(size int) bool {
+	if c.indexWindow == nil {
+		return true
+	}
+	return c.indexWindow.take(size)
+}
+
+// ackIndex tells the other side we've handled an index message of the given
+// size, if it waits for that. The acknowledgement is sent asynchronously,
+// for the dispatcher not to wait on the writer.
+func (c *rawConnection) ackIndex(size int) {
+	if !c.ackIndexes {
+		return
+	}
+	go c.send(context.Background(), &IndexAck{Size: int64(size)}, nil)
+}
@@ -15,6 +15,7 @@ import (
 
 	lz4 "github.com/bkaradzic/go-lz4"
 	"github.com/pkg/errors"
+	"github.com/syncthing/syncthing/lib/chaos"
 )
 
 const (
@@ -36,6 +37,10 @@ const (
 
 	// DesiredPerFileBlocks is the number of blocks we aim for per file
 	DesiredPerFileBlocks = 2000
+
+	// MaxBatchSize is the largest total amount of file data returned in
+	// response to a single batch request
+	MaxBatchSize = MaxBlockSize
 )
 
 // BlockSizes is the list of valid block sizes, from min to max
@@ -86,16 +91,17 @@ const (
 	FlagLocalIgnored     = 1 << 1 // Matches local ignore patterns
 	FlagLocalMustRescan  = 1 << 2 // Doesn't match content on disk, must be rechecked fully
 	FlagLocalReceiveOnly = 1 << 3 // Change detected on receive only folder
+	FlagLocalPlaceholder = 1 << 4 // Only a zero-sized placeholder is on disk, the contents haven't been pulled
 
 	// Flags that should result in the Invalid bit on outgoing updates
-	LocalInvalidFlags = FlagLocalUnsupported | FlagLocalIgnored | FlagLocalMustRescan | FlagLocalReceiveOnly
+	LocalInvalidFlags = FlagLocalUnsupported | FlagLocalIgnored | FlagLocalMustRescan | FlagLocalReceiveOnly | FlagLocalPlaceholder
 
 	// Flags that should result in a file being in conflict with its
 	// successor, due to us not having an up to date picture of its state on
 	// disk.
 	LocalConflictFlags = FlagLocalUnsupported | FlagLocalIgnored | FlagLocalReceiveOnly
 
-	LocalAllFlags = FlagLocalUnsupported | FlagLocalIgnored | FlagLocalMustRescan | FlagLocalReceiveOnly
+	LocalAllFlags = FlagLocalUnsupported | FlagLocalIgnored | FlagLocalMustRescan | FlagLocalReceiveOnly | FlagLocalPlaceholder
 )
 
 var (
@@ -107,21 +113,32 @@ var (
 	errDeletedHasBlocks   = errors.New("deleted file with non-empty block list")
 	errDirectoryHasBlocks = errors.New("directory with non-empty block list")
 	errFileHasNoBlocks    = errors.New("file with empty block list")
+	errMalformedBatch     = errors.New("malformed batch response")
+	errMessageTooLarge    = errors.New("application message too large")
 )
 
+// MaxApplicationMessageSize is the largest amount of data an application
+// message may carry. They are meant for small control messages, not for
+// transferring files.
+const MaxApplicationMessageSize = 64 << 10
+
 type Model interface {
 	// An index was received from the peer device
 	Index(deviceID DeviceID, folder string, files []FileInfo) error
 	// An index update was received from the peer device
 	IndexUpdate(deviceID DeviceID, folder string, files []FileInfo) error
 	// A request was made by the peer device
-	Request(deviceID DeviceID, folder, name string, size int32, offset int64, hash []byte, weakHash uint32, fromTemporary bool) (RequestResponse, error)
+	Request(deviceID DeviceID, folder, name string, size int32, offset int64, hash []byte, weakHash uint32, fromTemporary bool, priority RequestPriority) (RequestResponse, error)
 	// A cluster configuration message was received
 	ClusterConfig(deviceID DeviceID, config ClusterConfig) error
 	// The peer device closed the connection
 	Closed(conn Connection, err error)
 	// The peer device sent progress updates for the files it is currently downloading
 	DownloadProgress(deviceID DeviceID, folder string, updates []FileDownloadProgressUpdate) error
+	// An application message was received from the peer device
+	ApplicationMessage(deviceID DeviceID, msgType string, data []byte) error
+	// The peer device asks us to rescan a folder
+	ScanRequest(deviceID DeviceID, folder string, subdirs []string) error
 }
 
 type RequestResponse interface {
@@ -137,9 +154,13 @@ type Connection interface {
 	Name() string
 	Index(ctx context.Context, folder string, files []FileInfo) error
 	IndexUpdate(ctx context.Context, folder string, files []FileInfo) error
+	IndexSnapshot(ctx context.Context, folder string, files []FileInfo, first bool) error
 	Request(ctx context.Context, folder string, name string, offset int64, size int, hash []byte, weakHash uint32, fromTemporary bool) ([]byte, error)
+	BatchRequest(ctx context.Context, folder string, files []BatchRequestFile) ([][]byte, []error, error)
 	ClusterConfig(config ClusterConfig)
 	DownloadProgress(ctx context.Context, folder string, updates []FileDownloadProgressUpdate)
+	ApplicationMessage(ctx context.Context, msgType string, data []byte) error
+	ScanRequest(ctx context.Context, folder string, subdirs []string) error
 	Statistics() Statistics
 	Closed() bool
 }
@@ -170,11 +191,15 @@ type rawConnection struct {
 	closeOnce             sync.Once
 	sendCloseOnce         sync.Once
 	compression           Compression
+	compressionAlgorithm  CompressionAlgorithm
+	indexWindow           *indexWindow // nil when the other side doesn't limit our index data
+	ackIndexes            bool
 }
 
 type asyncResult struct {
-	val []byte
-	err error
+	val   []byte
+	err   error
+	codes []ErrorCode // for batch responses
 }
 
 type message interface {
@@ -203,7 +228,37 @@ const (
 // Should not be modified in production code, just for testing.
 var CloseTimeout = 10 * time.Second
 
+// ConnectionOptions are the settings of a connection that depend on what
+// the two sides announced in their Hello messages.
+type ConnectionOptions struct {
+	Compression          Compression
+	CompressionAlgorithm CompressionAlgorithm
+	// IndexSendWindow is how many bytes of index messages the other side
+	// lets us have outstanding before it acknowledges them, as per its
+	// Hello; 0 for no limit.
+	IndexSendWindow int
+	// AckIndexes is whether we acknowledge the index messages we've
+	// handled, which we must when we announced an index window.
+	AckIndexes bool
+}
+
 func NewConnection(deviceID DeviceID, reader io.Reader, writer io.Writer, receiver Model, name string, compress Compression) Connection {
+	return NewConnectionWithAlgorithm(deviceID, reader, writer, receiver, name, compress, CompressionAlgorithmLZ4)
+}
+
+// NewConnectionWithAlgorithm is like NewConnection, but compresses messages
+// with the given algorithm. It must be one the other side announced support
+// for in its Hello message.
+func NewConnectionWithAlgorithm(deviceID DeviceID, reader io.Reader, writer io.Writer, receiver Model, name string, compress Compression, algorithm CompressionAlgorithm) Connection {
+	return NewConnectionWithOptions(deviceID, reader, writer, receiver, name, ConnectionOptions{
+		Compression:          compress,
+		CompressionAlgorithm: algorithm,
+	})
+}
+
+// NewConnectionWithOptions is like NewConnection, with all the settings
+// given in the options.
+func NewConnectionWithOptions(deviceID DeviceID, reader io.Reader, writer io.Writer, receiver Model, name string, opts ConnectionOptions) Connection {
 	cr := &countingReader{Reader: reader}
 	cw := &countingWriter{Writer: writer}
 
@@ -221,7 +276,12 @@ func NewConnection(deviceID DeviceID, reader io.Reader, writer io.Writer, receiv
 		dispatcherLoopStopped: make(chan struct{}),
 		preventSends:          make(chan struct{}),
 		closed:                make(chan struct{}),
-		compression:           compress,
+		compression:           opts.Compression,
+		compressionAlgorithm:  opts.CompressionAlgorithm,
+		ackIndexes:            opts.AckIndexes,
+	}
+	if opts.IndexSendWindow > 0 {
+		c.indexWindow = newIndexWindow(opts.IndexSendWindow)
 	}
 
 	return wireFormatConnection{&c}
@@ -255,12 +315,16 @@ func (c *rawConnection) Index(ctx context.Context, folder string, idx []FileInfo
 		return ErrClosed
 	default:
 	}
-	c.idxMut.Lock()
-	c.send(ctx, &Index{
+	msg := &Index{
 		Folder: folder,
 		Files:  idx,
-	}, nil)
-	c.idxMut.Unlock()
+	}
+	c.idxMut.Lock()
+	defer c.idxMut.Unlock()
+	if !c.takeIndexWindow(msg.ProtoSize()) {
+		return ErrClosed
+	}
+	c.send(ctx, msg, nil)
 	return nil
 }
 
@@ -271,29 +335,39 @@ func (c *rawConnection) IndexUpdate(ctx context.Context, folder string, idx []Fi
 		return ErrClosed
 	default:
 	}
-	c.idxMut.Lock()
-	c.send(ctx, &IndexUpdate{
+	msg := &IndexUpdate{
 		Folder: folder,
 		Files:  idx,
-	}, nil)
-	c.idxMut.Unlock()
+	}
+	c.idxMut.Lock()
+	defer c.idxMut.Unlock()
+	if !c.takeIndexWindow(msg.ProtoSize()) {
+		return ErrClosed
+	}
+	c.send(ctx, msg, nil)
 	return nil
 }
 
 // Request returns the bytes for the specified block after fetching them from the connected peer.
-func (c *rawConnection) Request(ctx context.Context, folder string, name string, offset int64, size int, hash []byte, weakHash uint32, fromTemporary bool) ([]byte, error) {
-	c.nextIDMut.Lock()
-	id := c.nextID
-	c.nextID++
-	c.nextIDMut.Unlock()
+type requestPriorityKey struct{}
+
+// WithRequestPriority returns a context that makes requests sent with it
+// have the given priority. The other side serves requests of higher
+// priority first, so interactive requests don't wait for a backlog of
+// others.
+func WithRequestPriority(ctx context.Context, priority RequestPriority) context.Context {
+	return context.WithValue(ctx, requestPriorityKey{}, priority)
+}
 
-	c.awaitingMut.Lock()
-	if _, ok := c.awaiting[id]; ok {
-		panic("id taken")
-	}
-	rc := make(chan asyncResult, 1)
-	c.awaiting[id] = rc
-	c.awaitingMut.Unlock()
+func requestPriority(ctx context.Context) RequestPriority {
+	priority, _ := ctx.Value(requestPriorityKey{}).(RequestPriority)
+	return priority
+}
+
+// Request requests a block from the peer, with the priority set on the
+// context by WithRequestPriority, if any.
+func (c *rawConnection) Request(ctx context.Context, folder string, name string, offset int64, size int, hash []byte, weakHash uint32, fromTemporary bool) ([]byte, error) {
+	id, rc := c.newAwaiting()
 
 	ok := c.send(ctx, &Request{
 		ID:            id,
@@ -304,6 +378,7 @@ func (c *rawConnection) Request(ctx context.Context, folder string, name string,
 		Hash:          hash,
 		WeakHash:      weakHash,
 		FromTemporary: fromTemporary,
+		Priority:      requestPriority(ctx),
 	}, nil)
 	if !ok {
 		return nil, ErrClosed
@@ -320,6 +395,75 @@ func (c *rawConnection) Request(ctx context.Context, folder string, name string,
 	}
 }
 
+// BatchRequest returns the complete contents of each of the given files,
+// fetched from the connected peer in a single round trip. This is meant for
+// small files that consist of a single block and must only be used when the
+// other side announced support for it in its Hello. The returned data and
+// errors have one entry per requested file.
+func (c *rawConnection) BatchRequest(ctx context.Context, folder string, files []BatchRequestFile) ([][]byte, []error, error) {
+	id, rc := c.newAwaiting()
+
+	ok := c.send(ctx, &BatchRequest{
+		ID:     id,
+		Folder: folder,
+		Files:  files,
+	}, nil)
+	if !ok {
+		return nil, nil, ErrClosed
+	}
+
+	var res asyncResult
+	select {
+	case res, ok = <-rc:
+		if !ok {
+			return nil, nil, ErrClosed
+		}
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+
+	if len(res.codes) != len(files) {
+		return nil, nil, errMalformedBatch
+	}
+	datas := make([][]byte, len(files))
+	errs := make([]error, len(files))
+	offset := 0
+	for i, file := range files {
+		if errs[i] = codeToError(res.codes[i]); errs[i] != nil {
+			continue
+		}
+		end := offset + int(file.Size)
+		if end > len(res.val) {
+			return nil, nil, errMalformedBatch
+		}
+		datas[i] = res.val[offset:end]
+		offset = end
+	}
+	if offset != len(res.val) {
+		return nil, nil, errMalformedBatch
+	}
+	return datas, errs, nil
+}
+
+// newAwaiting allocates a new message ID and registers a channel for the
+// response to it.
+func (c *rawConnection) newAwaiting() (int32, chan asyncResult) {
+	c.nextIDMut.Lock()
+	id := c.nextID
+	c.nextID++
+	c.nextIDMut.Unlock()
+
+	c.awaitingMut.Lock()
+	if _, ok := c.awaiting[id]; ok {
+		panic("id taken")
+	}
+	rc := make(chan asyncResult, 1)
+	c.awaiting[id] = rc
+	c.awaitingMut.Unlock()
+
+	return id, rc
+}
+
 // ClusterConfig sends the cluster configuration message to the peer.
 // It must be called just once (as per BEP), otherwise it will panic.
 func (c *rawConnection) ClusterConfig(config ClusterConfig) {
@@ -347,6 +491,33 @@ func (c *rawConnection) DownloadProgress(ctx context.Context, folder string, upd
 	}, nil)
 }
 
+// ApplicationMessage sends an opaque message of the given type to the peer
+// device, for applications built on top of the protocol.
+func (c *rawConnection) ApplicationMessage(ctx context.Context, msgType string, data []byte) error {
+	if len(data) > MaxApplicationMessageSize {
+		return errMessageTooLarge
+	}
+	if !c.send(ctx, &ApplicationMessage{
+		Type: msgType,
+		Data: data,
+	}, nil) {
+		return ErrClosed
+	}
+	return nil
+}
+
+// ScanRequest asks the peer device to rescan the given subdirectories of a
+// folder, or all of it if there are none.
+func (c *rawConnection) ScanRequest(ctx context.Context, folder string, subdirs []string) error {
+	if !c.send(ctx, &ScanRequest{
+		Folder:  folder,
+		Subdirs: subdirs,
+	}, nil) {
+		return ErrClosed
+	}
+	return nil
+}
+
 func (c *rawConnection) ping() bool {
 	return c.send(context.Background(), &Ping{}, nil)
 }
@@ -401,9 +572,11 @@ func (c *rawConnection) dispatcherLoop() (err error) {
 			if err := checkIndexConsistency(msg.Files); err != nil {
 				return errors.Wrap(err, "protocol error: index")
 			}
+			size := msg.ProtoSize()
 			if err := c.handleIndex(*msg); err != nil {
 				return errors.Wrap(err, "receiver error")
 			}
+			c.ackIndex(size)
 			state = stateReady
 
 		case *IndexUpdate:
@@ -414,11 +587,36 @@ func (c *rawConnection) dispatcherLoop() (err error) {
 			if err := checkIndexConsistency(msg.Files); err != nil {
 				return errors.Wrap(err, "protocol error: index update")
 			}
+			size := msg.ProtoSize()
 			if err := c.handleIndexUpdate(*msg); err != nil {
 				return errors.Wrap(err, "receiver error")
 			}
+			c.ackIndex(size)
 			state = stateReady
 
+		case *IndexSnapshot:
+			l.Debugln("read IndexSnapshot message")
+			if state != stateReady {
+				return fmt.Errorf("protocol error: index snapshot message in state %d", state)
+			}
+			files, err := decodeIndexSnapshot(msg.Data)
+			if err != nil {
+				return errors.Wrap(err, "protocol error: index snapshot")
+			}
+			if err := checkIndexConsistency(files); err != nil {
+				return errors.Wrap(err, "protocol error: index snapshot")
+			}
+			if err := c.handleIndexSnapshot(msg.Folder, files, msg.First); err != nil {
+				return errors.Wrap(err, "receiver error")
+			}
+			c.ackIndex(msg.ProtoSize())
+
+		case *IndexAck:
+			l.Debugln("read IndexAck message")
+			if c.indexWindow != nil {
+				c.indexWindow.give(int(msg.Size))
+			}
+
 		case *Request:
 			l.Debugln("read Request message")
 			if state != stateReady {
@@ -436,6 +634,25 @@ func (c *rawConnection) dispatcherLoop() (err error) {
 			}
 			c.handleResponse(*msg)
 
+		case *BatchRequest:
+			l.Debugln("read BatchRequest message")
+			if state != stateReady {
+				return fmt.Errorf("protocol error: batch request message in state %d", state)
+			}
+			for _, file := range msg.Files {
+				if err := checkFilename(file.Name); err != nil {
+					return errors.Wrapf(err, "protocol error: batch request: %q", file.Name)
+				}
+			}
+			go c.handleBatchRequest(*msg)
+
+		case *BatchResponse:
+			l.Debugln("read BatchResponse message")
+			if state != stateReady {
+				return fmt.Errorf("protocol error: batch response message in state %d", state)
+			}
+			c.handleBatchResponse(*msg)
+
 		case *DownloadProgress:
 			l.Debugln("read DownloadProgress message")
 			if state != stateReady {
@@ -445,6 +662,32 @@ func (c *rawConnection) dispatcherLoop() (err error) {
 				return errors.Wrap(err, "receiver error")
 			}
 
+		case *ApplicationMessage:
+			l.Debugln("read ApplicationMessage message")
+			if state != stateReady {
+				return fmt.Errorf("protocol error: application message in state %d", state)
+			}
+			if len(msg.Data) > MaxApplicationMessageSize {
+				return errMessageTooLarge
+			}
+			if err := c.receiver.ApplicationMessage(c.id, msg.Type, msg.Data); err != nil {
+				return errors.Wrap(err, "receiver error")
+			}
+
+		case *ScanRequest:
+			l.Debugln("read ScanRequest message")
+			if state != stateReady {
+				return fmt.Errorf("protocol error: scan request message in state %d", state)
+			}
+			for _, sub := range msg.Subdirs {
+				if err := checkFilename(sub); err != nil {
+					return errors.Wrapf(err, "protocol error: scan request: %q", sub)
+				}
+			}
+			if err := c.receiver.ScanRequest(c.id, msg.Folder, msg.Subdirs); err != nil {
+				return errors.Wrap(err, "receiver error")
+			}
+
 		case *Ping:
 			l.Debugln("read Ping message")
 			if state != stateReady {
@@ -506,6 +749,14 @@ func (c *rawConnection) readMessageAfterHeader(hdr Header, fourByteBuf []byte) (
 		}
 		buf = decomp
 
+	case MessageCompressionZstd:
+		decomp, err := zstdDecompress(buf)
+		BufferPool.Put(buf)
+		if err != nil {
+			return nil, errors.Wrap(err, "decompressing message")
+		}
+		buf = decomp
+
 	default:
 		return nil, fmt.Errorf("unknown message compression %d", hdr.Compression)
 	}
@@ -624,7 +875,7 @@ func checkFilename(name string) error {
 }
 
 func (c *rawConnection) handleRequest(req Request) {
-	res, err := c.receiver.Request(c.id, req.Folder, req.Name, req.Size, req.Offset, req.Hash, req.WeakHash, req.FromTemporary)
+	res, err := c.receiver.Request(c.id, req.Folder, req.Name, req.Size, req.Offset, req.Hash, req.WeakHash, req.FromTemporary, req.Priority)
 	if err != nil {
 		c.send(context.Background(), &Response{
 			ID:   req.ID,
@@ -646,7 +897,61 @@ func (c *rawConnection) handleResponse(resp Response) {
 	c.awaitingMut.Lock()
 	if rc := c.awaiting[resp.ID]; rc != nil {
 		delete(c.awaiting, resp.ID)
-		rc <- asyncResult{resp.Data, codeToError(resp.Code)}
+		rc <- asyncResult{val: resp.Data, err: codeToError(resp.Code)}
+		close(rc)
+	}
+	c.awaitingMut.Unlock()
+}
+
+// handleBatchRequest answers all files in the batch with a single
+// response, containing the concatenated data of the files that could be
+// read and an error code for each file.
+func (c *rawConnection) handleBatchRequest(req BatchRequest) {
+	codes := make([]ErrorCode, len(req.Files))
+	total := 0
+	for _, file := range req.Files {
+		if file.Size > 0 {
+			total += int(file.Size)
+		}
+	}
+	if total > MaxBatchSize {
+		total = MaxBatchSize
+	}
+	buf := BufferPool.Get(total)[:0]
+
+	for i, file := range req.Files {
+		if file.Size < 0 || len(buf)+int(file.Size) > MaxBatchSize {
+			codes[i] = ErrorCodeGeneric
+			continue
+		}
+		res, err := c.receiver.Request(c.id, req.Folder, file.Name, file.Size, 0, file.Hash, file.WeakHash, false, RequestPriorityNormal)
+		if err != nil {
+			codes[i] = errorToCode(err)
+			continue
+		}
+		if data := res.Data(); len(data) == int(file.Size) {
+			buf = append(buf, data...)
+		} else {
+			codes[i] = ErrorCodeGeneric
+		}
+		res.Close()
+	}
+
+	done := make(chan struct{})
+	c.send(context.Background(), &BatchResponse{
+		ID:    req.ID,
+		Data:  buf,
+		Codes: codes,
+	}, done)
+	<-done
+	BufferPool.Put(buf)
+}
+
+func (c *rawConnection) handleBatchResponse(resp BatchResponse) {
+	c.awaitingMut.Lock()
+	if rc := c.awaiting[resp.ID]; rc != nil {
+		delete(c.awaiting, resp.ID)
+		rc <- asyncResult{val: resp.Data, codes: resp.Codes}
 		close(rc)
 	}
 	c.awaitingMut.Unlock()
@@ -684,6 +989,12 @@ func (c *rawConnection) writerLoop() {
 	for {
 		select {
 		case hm := <-c.outbox:
+			if chaos.Enabled && chaos.DropMessage(c.typeOf(hm.msg).String()) {
+				if hm.done != nil {
+					close(hm.done)
+				}
+				continue
+			}
 			err := c.writeMessage(hm.msg)
 			if hm.done != nil {
 				close(hm.done)
@@ -718,15 +1029,23 @@ func (c *rawConnection) writeCompressedMessage(msg message) error {
 		return errors.Wrap(err, "marshalling message")
 	}
 
-	compressed, err := c.lz4Compress(buf)
-	if err != nil {
-		return errors.Wrap(err, "compressing message")
-	}
-
+	var compressed []byte
+	var err error
 	hdr := Header{
-		Type:        c.typeOf(msg),
-		Compression: MessageCompressionLZ4,
+		Type: c.typeOf(msg),
 	}
+	switch c.compressionAlgorithm {
+	case CompressionAlgorithmZstd:
+		compressed = zstdCompress(buf)
+		hdr.Compression = MessageCompressionZstd
+	default:
+		compressed, err = c.lz4Compress(buf)
+		if err != nil {
+			return errors.Wrap(err, "compressing message")
+		}
+		hdr.Compression = MessageCompressionLZ4
+	}
+
 	hdrSize := hdr.ProtoSize()
 	if hdrSize > 1<<16-1 {
 		panic("impossibly large header")
@@ -812,6 +1131,18 @@ func (c *rawConnection) typeOf(msg message) MessageType {
 		return messageTypePing
 	case *Close:
 		return messageTypeClose
+	case *BatchRequest:
+		return messageTypeBatchRequest
+	case *BatchResponse:
+		return messageTypeBatchResponse
+	case *ApplicationMessage:
+		return messageTypeApplicationMessage
+	case *ScanRequest:
+		return messageTypeScanRequest
+	case *IndexSnapshot:
+		return messageTypeIndexSnapshot
+	case *IndexAck:
+		return messageTypeIndexAck
 	default:
 		panic("bug: unknown message type")
 	}
@@ -835,12 +1166,29 @@ func (c *rawConnection) newMessage(t MessageType) (message, error) {
 		return new(Ping), nil
 	case messageTypeClose:
 		return new(Close), nil
+	case messageTypeBatchRequest:
+		return new(BatchRequest), nil
+	case messageTypeBatchResponse:
+		return new(BatchResponse), nil
+	case messageTypeApplicationMessage:
+		return new(ApplicationMessage), nil
+	case messageTypeScanRequest:
+		return new(ScanRequest), nil
+	case messageTypeIndexSnapshot:
+		return new(IndexSnapshot), nil
+	case messageTypeIndexAck:
+		return new(IndexAck), nil
 	default:
 		return nil, errUnknownMessage
 	}
 }
 
 func (c *rawConnection) shouldCompressMessage(msg message) bool {
+	if _, ok := msg.(*IndexSnapshot); ok {
+		// Already compressed
+		return false
+	}
+
 	switch c.compression {
 	case CompressNever:
 		return false
@@ -850,7 +1198,11 @@ func (c *rawConnection) shouldCompressMessage(msg message) bool {
 		return msg.ProtoSize() >= compressionThreshold
 
 	case CompressMetadata:
-		_, isResponse := msg.(*Response)
+		var isResponse bool
+		switch msg.(type) {
+		case *Response, *BatchResponse:
+			isResponse = true
+		}
 		// Compress if it's large enough and not a response message
 		return !isResponse && msg.ProtoSize() >= compressionThreshold
 
@@ -890,6 +1242,9 @@ func (c *rawConnection) internalClose(err error) {
 	c.closeOnce.Do(func() {
 		l.Debugln("close due to", err)
 		close(c.closed)
+		if c.indexWindow != nil {
+			c.indexWindow.close()
+		}
 
 		c.awaitingMut.Lock()
 		for i, ch := range c.awaiting {
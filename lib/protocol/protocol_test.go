@@ -9,8 +9,10 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
+	"reflect"
 	"runtime"
 	"sync"
 	"testing"
@@ -273,6 +275,27 @@ func TestMarshalIndexMessage(t *testing.T) {
 			if len(f.Version.Counters) == 0 {
 				m1.Files[i].Version.Counters = nil
 			}
+			if len(f.Xattrs) == 0 {
+				m1.Files[i].Xattrs = nil
+			} else {
+				for j := range f.Xattrs {
+					if len(f.Xattrs[j].Value) == 0 {
+						f.Xattrs[j].Value = nil
+					}
+				}
+			}
+			if len(f.Platform.Windows.Streams) == 0 {
+				m1.Files[i].Platform.Windows.Streams = nil
+			} else {
+				for j := range f.Platform.Windows.Streams {
+					if len(f.Platform.Windows.Streams[j].Data) == 0 {
+						f.Platform.Windows.Streams[j].Data = nil
+					}
+				}
+			}
+			if len(f.Holes) == 0 {
+				m1.Files[i].Holes = nil
+			}
 		}
 
 		return testMarshal(t, "index", &m1, &Index{})
@@ -331,6 +354,9 @@ func TestMarshalClusterConfigMessage(t *testing.T) {
 				m1.Folders[i].Devices = nil
 			}
 		}
+		if len(m1.Decommissioned) == 0 {
+			m1.Decommissioned = nil
+		}
 		return testMarshal(t, "clusterconfig", &m1, &ClusterConfig{})
 	}
 
@@ -867,3 +893,339 @@ func TestDispatcherToCloseDeadlock(t *testing.T) {
 		t.Fatal("timed out before dispatcher loop terminated")
 	}
 }
+
+func TestBatchRequest(t *testing.T) {
+	m0 := newTestModel()
+	m1 := newTestModel()
+	m1.requestFn = func(folder, name string) ([]byte, error) {
+		switch name {
+		case "a":
+			return []byte("aaa"), nil
+		case "c":
+			return []byte("c"), nil
+		default:
+			return nil, ErrNoSuchFile
+		}
+	}
+
+	ar, aw := io.Pipe()
+	br, bw := io.Pipe()
+
+	c0 := NewConnection(c0ID, ar, bw, m0, "c0", CompressNever)
+	c0.Start()
+	c1 := NewConnection(c1ID, br, aw, m1, "c1", CompressNever)
+	c1.Start()
+	c0.ClusterConfig(ClusterConfig{})
+	c1.ClusterConfig(ClusterConfig{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	datas, errs, err := c0.BatchRequest(ctx, "default", []BatchRequestFile{
+		{Name: "a", Size: 3},
+		{Name: "b", Size: 2},
+		{Name: "c", Size: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(datas[0]) != "aaa" || errs[0] != nil {
+		t.Errorf("unexpected result for a: %q, %v", datas[0], errs[0])
+	}
+	if datas[1] != nil || errs[1] != ErrNoSuchFile {
+		t.Errorf("unexpected result for b: %q, %v", datas[1], errs[1])
+	}
+	if string(datas[2]) != "c" || errs[2] != nil {
+		t.Errorf("unexpected result for c: %q, %v", datas[2], errs[2])
+	}
+}
+
+func TestZstdCompression(t *testing.T) {
+	data := bytes.Repeat([]byte("syncthing "), 1000)
+	m0 := newTestModel()
+	m1 := newTestModel()
+	m1.requestFn = func(folder, name string) ([]byte, error) {
+		return data, nil
+	}
+
+	ar, aw := io.Pipe()
+	br, bw := io.Pipe()
+
+	// Each side may compress with a different algorithm, depending on
+	// what the other side supports.
+	c0 := NewConnectionWithAlgorithm(c0ID, ar, bw, m0, "c0", CompressAlways, CompressionAlgorithmLZ4)
+	c0.Start()
+	c1 := NewConnectionWithAlgorithm(c1ID, br, aw, m1, "c1", CompressAlways, CompressionAlgorithmZstd)
+	c1.Start()
+	c0.ClusterConfig(ClusterConfig{})
+	c1.ClusterConfig(ClusterConfig{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	res, err := c0.Request(ctx, "default", "a", 0, len(data), nil, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(res, data) {
+		t.Error("incorrect response data")
+	}
+	if out := c1.Statistics().OutBytesTotal; out > int64(len(data))/10 {
+		t.Errorf("response should have been compressed, sent %d bytes", out)
+	}
+
+	if a := (HelloResult{}).CompressionAlgorithmFor(CompressionAlgorithmZstd); a != CompressionAlgorithmLZ4 {
+		t.Errorf("expected fallback to LZ4 for old devices, got %v", a)
+	}
+	hello := HelloResult{CompressionAlgorithms: SupportedCompressionAlgorithms}
+	if a := hello.CompressionAlgorithmFor(CompressionAlgorithmZstd); a != CompressionAlgorithmZstd {
+		t.Errorf("expected zstd, got %v", a)
+	}
+}
+
+func TestRequestPriority(t *testing.T) {
+	m0 := newTestModel()
+	m1 := newTestModel()
+
+	ar, aw := io.Pipe()
+	br, bw := io.Pipe()
+
+	c0 := NewConnection(c0ID, ar, bw, m0, "c0", CompressAlways)
+	c0.Start()
+	c1 := NewConnection(c1ID, br, aw, m1, "c1", CompressAlways)
+	c1.Start()
+	c0.ClusterConfig(ClusterConfig{})
+	c1.ClusterConfig(ClusterConfig{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := c0.Request(ctx, "default", "a", 0, 0, nil, 0, false); err != nil {
+		t.Fatal(err)
+	}
+	if m1.priority != RequestPriorityNormal {
+		t.Errorf("expected normal priority, got %v", m1.priority)
+	}
+
+	if _, err := c0.Request(WithRequestPriority(ctx, RequestPriorityInteractive), "default", "a", 0, 0, nil, 0, false); err != nil {
+		t.Fatal(err)
+	}
+	if m1.priority != RequestPriorityInteractive {
+		t.Errorf("expected interactive priority, got %v", m1.priority)
+	}
+}
+
+func TestApplicationMessage(t *testing.T) {
+	m0 := newTestModel()
+	m1 := newTestModel()
+	type appMsg struct {
+		from    DeviceID
+		msgType string
+		data    []byte
+	}
+	received := make(chan appMsg, 1)
+	m1.appMsgFn = func(from DeviceID, msgType string, data []byte) {
+		received <- appMsg{from, msgType, data}
+	}
+
+	ar, aw := io.Pipe()
+	br, bw := io.Pipe()
+
+	c0 := NewConnection(c0ID, ar, bw, m0, "c0", CompressNever)
+	c0.Start()
+	c1 := NewConnection(c1ID, br, aw, m1, "c1", CompressNever)
+	c1.Start()
+	c0.ClusterConfig(ClusterConfig{})
+	c1.ClusterConfig(ClusterConfig{})
+
+	ctx := context.Background()
+	if err := c0.ApplicationMessage(ctx, "rescan", []byte("please")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-received:
+		if msg.from != c1ID || msg.msgType != "rescan" || string(msg.data) != "please" {
+			t.Errorf("unexpected message %+v", msg)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the message")
+	}
+
+	if err := c0.ApplicationMessage(ctx, "big", make([]byte, MaxApplicationMessageSize+1)); err != errMessageTooLarge {
+		t.Error("expected a too large error, got", err)
+	}
+}
+
+func TestIndexSnapshot(t *testing.T) {
+	m0 := newTestModel()
+	m1 := newTestModel()
+	type index struct {
+		update bool
+		folder string
+		files  []FileInfo
+	}
+	received := make(chan index, 2)
+	m1.indexFn = func(_ DeviceID, folder string, files []FileInfo) {
+		received <- index{false, folder, files}
+	}
+	m1.indexUpdateFn = func(_ DeviceID, folder string, files []FileInfo) {
+		received <- index{true, folder, files}
+	}
+
+	ar, aw := io.Pipe()
+	br, bw := io.Pipe()
+
+	c0 := NewConnection(c0ID, ar, bw, m0, "c0", CompressAlways)
+	c0.Start()
+	c1 := NewConnection(c1ID, br, aw, m1, "c1", CompressAlways)
+	c1.Start()
+	c0.ClusterConfig(ClusterConfig{})
+	c1.ClusterConfig(ClusterConfig{})
+
+	files := make([]FileInfo, 1000)
+	for i := range files {
+		files[i] = FileInfo{
+			Name:     fmt.Sprintf("dir/file%d", i),
+			Size:     int64(i),
+			Sequence: int64(i + 1),
+			Version:  Vector{Counters: []Counter{{ID: 1, Value: uint64(i)}}},
+			Blocks:   []BlockInfo{{Size: int32(i), Hash: make([]byte, 32)}},
+		}
+	}
+
+	ctx := context.Background()
+	if err := c0.IndexSnapshot(ctx, "default", files[:600], true); err != nil {
+		t.Fatal(err)
+	}
+	if err := c0.IndexSnapshot(ctx, "default", files[600:], false); err != nil {
+		t.Fatal(err)
+	}
+	for i, exp := range []index{{false, "default", files[:600]}, {true, "default", files[600:]}} {
+		select {
+		case idx := <-received:
+			if idx.update != exp.update || idx.folder != exp.folder || len(idx.files) != len(exp.files) {
+				t.Fatalf("snapshot message %d: got update=%v folder=%q with %d files", i, idx.update, idx.folder, len(idx.files))
+			}
+			for j := range idx.files {
+				if !idx.files[j].IsEquivalent(exp.files[j], 0) || idx.files[j].Sequence != exp.files[j].Sequence {
+					t.Fatalf("snapshot message %d: file %d is %v, expected %v", i, j, idx.files[j], exp.files[j])
+				}
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("timed out waiting for the index")
+		}
+	}
+}
+
+func TestDecodeIndexSnapshotCorrupt(t *testing.T) {
+	data, err := encodeIndexSnapshot([]FileInfo{{Name: "a"}, {Name: "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := zstdDecompress(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := decodeIndexSnapshot(zstdCompress(buf[:len(buf)-1])); err != errIndexSnapshotCorrupt {
+		t.Error("expected truncated snapshot to be corrupt, got", err)
+	}
+	if _, err := decodeIndexSnapshot(buf); err == nil {
+		t.Error("expected uncompressed snapshot to fail")
+	}
+}
+
+func TestIndexSummary(t *testing.T) {
+	v1 := Vector{}.Update(1)
+	v2 := v1.Update(2)
+
+	var a, b IndexSummaryBuilder
+	a.Add("dir/file", v1, false)
+	a.Add("other", v1, false)
+	// The order of the files doesn't matter.
+	b.Add("other", v1, false)
+	b.Add("dir/file", v1, false)
+	if !a.Summary().Equal(b.Summary()) {
+		t.Fatal("expected equal summaries")
+	}
+	if (IndexSummary{}).Equal(IndexSummary{}) {
+		t.Error("expected empty summaries not to be equal")
+	}
+
+	// Only the bucket of a changed file diverges.
+
+	var c IndexSummaryBuilder
+	c.Add("dir/file", v2, false)
+	c.Add("other", v1, false)
+	expected := []int{IndexSummaryBucket("dir/file")}
+	if buckets := a.Summary().DivergentBuckets(c.Summary()); !reflect.DeepEqual(buckets, expected) {
+		t.Errorf("divergent buckets %v, expected %v", buckets, expected)
+	}
+	if IndexSummaryBucket("dir/file") != IndexSummaryBucket(`dir\other`) {
+		t.Error("expected files in the same top level directory to share a bucket")
+	}
+
+	var d IndexSummaryBuilder
+	d.Add("dir/file", v1, true)
+	d.Add("other", v1, false)
+	if a.Summary().Equal(d.Summary()) {
+		t.Error("expected a deletion to change the summary")
+	}
+}
+
+func TestIndexWindow(t *testing.T) {
+	m0 := newTestModel()
+	m1 := newTestModel()
+	handled := make(chan struct{})
+	release := make(chan struct{})
+	m1.indexUpdateFn = func(DeviceID, string, []FileInfo) {
+		handled <- struct{}{}
+		<-release
+	}
+
+	ar, aw := io.Pipe()
+	br, bw := io.Pipe()
+
+	// The window is smaller than any message, leaving room for one at a time.
+	c0 := NewConnectionWithOptions(c0ID, ar, bw, m0, "c0", ConnectionOptions{IndexSendWindow: 1})
+	c0.Start()
+	c1 := NewConnectionWithOptions(c1ID, br, aw, m1, "c1", ConnectionOptions{AckIndexes: true})
+	c1.Start()
+	c0.ClusterConfig(ClusterConfig{})
+	c1.ClusterConfig(ClusterConfig{})
+
+	files := []FileInfo{{Name: "dir", Type: FileInfoTypeDirectory, Version: Vector{Counters: []Counter{{ID: 1, Value: 1}}}}}
+	sent := make(chan error)
+	go func() {
+		for i := 0; i < 4; i++ {
+			sent <- c0.IndexUpdate(context.Background(), "default", files)
+		}
+	}()
+
+	for i := 0; i < 2; i++ {
+		if err := <-sent; err != nil {
+			t.Fatal(err)
+		}
+		<-handled
+		// The next message must wait for this one to be acknowledged.
+		select {
+		case <-sent:
+			t.Fatalf("index update %d sent before the previous was handled", i+2)
+		case <-time.After(100 * time.Millisecond):
+		}
+		release <- struct{}{}
+	}
+
+	// Closing the connection stops the wait.
+	if err := <-sent; err != nil {
+		t.Fatal(err)
+	}
+	<-handled
+	c0.Close(errManual)
+	select {
+	case err := <-sent:
+		if err != ErrClosed {
+			t.Fatal("expected ErrClosed, got", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the index update to fail")
+	}
+	close(release)
+}
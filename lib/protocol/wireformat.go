@@ -35,7 +35,29 @@ func (c wireFormatConnection) IndexUpdate(ctx context.Context, folder string, fs
 	return c.Connection.IndexUpdate(ctx, folder, myFs)
 }
 
+func (c wireFormatConnection) IndexSnapshot(ctx context.Context, folder string, fs []FileInfo, first bool) error {
+	var myFs = make([]FileInfo, len(fs))
+	copy(myFs, fs)
+
+	for i := range fs {
+		myFs[i].Name = norm.NFC.String(filepath.ToSlash(myFs[i].Name))
+	}
+
+	return c.Connection.IndexSnapshot(ctx, folder, myFs, first)
+}
+
 func (c wireFormatConnection) Request(ctx context.Context, folder string, name string, offset int64, size int, hash []byte, weakHash uint32, fromTemporary bool) ([]byte, error) {
 	name = norm.NFC.String(filepath.ToSlash(name))
 	return c.Connection.Request(ctx, folder, name, offset, size, hash, weakHash, fromTemporary)
 }
+
+func (c wireFormatConnection) BatchRequest(ctx context.Context, folder string, files []BatchRequestFile) ([][]byte, []error, error) {
+	var myFiles = make([]BatchRequestFile, len(files))
+	copy(myFiles, files)
+
+	for i := range files {
+		myFiles[i].Name = norm.NFC.String(filepath.ToSlash(myFiles[i].Name))
+	}
+
+	return c.Connection.BatchRequest(ctx, folder, myFiles)
+}
@@ -32,6 +32,11 @@ type RelayClient interface {
 	Latency() time.Duration
 	String() string
 	Invitations() chan protocol.SessionInvitation
+	// Punches returns the punch invitations from devices wanting to
+	// connect directly, on relays that support it.
+	Punches() <-chan protocol.PunchInvitation
+	// ReplyPunch sends our addresses in answer to a punch invitation.
+	ReplyPunch(ctx context.Context, inv protocol.PunchInvitation, addrs []string) error
 	URI() *url.URL
 }
 
@@ -49,13 +54,17 @@ type commonClient struct {
 
 	invitations              chan protocol.SessionInvitation
 	closeInvitationsOnFinish bool
+	punches                  chan protocol.PunchInvitation
+	punchReplies             chan protocol.PunchResponse
 	mut                      sync.RWMutex
 }
 
 func newCommonClient(invitations chan protocol.SessionInvitation, serve func(context.Context) error, creator string) commonClient {
 	c := commonClient{
-		invitations: invitations,
-		mut:         sync.NewRWMutex(),
+		invitations:  invitations,
+		punches:      make(chan protocol.PunchInvitation, 1),
+		punchReplies: make(chan protocol.PunchResponse),
+		mut:          sync.NewRWMutex(),
 	}
 	newServe := func(ctx context.Context) error {
 		defer c.cleanup()
@@ -82,3 +91,20 @@ func (c *commonClient) Invitations() chan protocol.SessionInvitation {
 	defer c.mut.RUnlock()
 	return c.invitations
 }
+
+func (c *commonClient) Punches() <-chan protocol.PunchInvitation {
+	return c.punches
+}
+
+func (c *commonClient) ReplyPunch(ctx context.Context, inv protocol.PunchInvitation, addrs []string) error {
+	res := protocol.PunchResponse{
+		ID:        inv.From,
+		Addresses: addrs,
+	}
+	select {
+	case c.punchReplies <- res:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -82,6 +82,9 @@ func (c *dynamicClient) serve(ctx context.Context) error {
 				continue
 			}
 			client := newStaticClient(ruri, c.certs, c.invitations, c.timeout)
+			// Punching goes through whichever relay we're on.
+			client.(*staticClient).punches = c.punches
+			client.(*staticClient).punchReplies = c.punchReplies
 			c.mut.Lock()
 			c.client = client
 			c.mut.Unlock()
@@ -0,0 +1,140 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/dialer"
+	"github.com/syncthing/syncthing/lib/relay/protocol"
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+// The relays we have shared session connections to, by session address.
+var (
+	muxes    = make(map[string]*protocol.Mux)
+	muxesMut = sync.NewMutex()
+)
+
+// SupportsMultiplexing returns true if the relay with the given URI
+// advertises that it can carry several sessions on one connection.
+func SupportsMultiplexing(uri *url.URL) bool {
+	version, err := strconv.Atoi(uri.Query().Get("protocolVersion"))
+	return err == nil && version >= 2
+}
+
+// JoinSharedSession joins the session like JoinSession, but on a connection
+// shared with the other sessions to the same relay when the relay with the
+// given URI supports it.
+func JoinSharedSession(ctx context.Context, uri *url.URL, invitation protocol.SessionInvitation) (net.Conn, error) {
+	if !SupportsMultiplexing(uri) {
+		return JoinSession(ctx, invitation)
+	}
+
+	addr := net.JoinHostPort(net.IP(invitation.Address).String(), strconv.Itoa(int(invitation.Port)))
+
+	for i := 0; i < 2; i++ {
+		mux, err := getMux(ctx, addr)
+		if err != nil {
+			// The key hasn't been used yet.
+			l.Debugln("Joining shared session on", addr, "failed, falling back:", err)
+			return JoinSession(ctx, invitation)
+		}
+
+		conn, err := mux.Join(invitation.Key)
+		if err == protocol.ErrMuxClosed {
+			// Closed after its last session was, just as we got it.
+			continue
+		}
+		return conn, err
+	}
+	return JoinSession(ctx, invitation)
+}
+
+func getMux(ctx context.Context, addr string) (*protocol.Mux, error) {
+	muxesMut.Lock()
+	mux, ok := muxes[addr]
+	muxesMut.Unlock()
+	if ok && !mux.Closed() {
+		return mux, nil
+	}
+
+	// Dialing may take a while, so it's done without holding the lock, as
+	// not to hold up joining sessions on other relays.
+	conn, err := dialMux(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	muxesMut.Lock()
+	defer muxesMut.Unlock()
+
+	if mux, ok := muxes[addr]; ok && !mux.Closed() {
+		// Someone else got there first.
+		conn.Close()
+		return mux, nil
+	}
+
+	mux = protocol.NewMuxClient(conn)
+	muxes[addr] = mux
+	go func() {
+		err := mux.Serve()
+		l.Debugln("Shared session connection to", addr, "closed:", err)
+		muxesMut.Lock()
+		if muxes[addr] == mux {
+			delete(muxes, addr)
+		}
+		muxesMut.Unlock()
+	}()
+	return mux, nil
+}
+
+// dialMux connects to the relay and asks for the connection to carry
+// shared sessions.
+func dialMux(ctx context.Context, addr string) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if err := dialer.SetTCPOptions(conn); err != nil {
+		l.Debugln("Shared session connection to", addr, "setting tcp options:", err)
+	}
+
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	if err := protocol.WriteMessage(conn, protocol.JoinMuxRequest{}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	message, err := protocol.ReadMessage(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	conn.SetDeadline(time.Time{})
+
+	switch msg := message.(type) {
+	case protocol.Response:
+		if msg.Code != 0 {
+			conn.Close()
+			return nil, fmt.Errorf("Incorrect response code %d: %s", msg.Code, msg.Message)
+		}
+	default:
+		conn.Close()
+		return nil, fmt.Errorf("protocol error: expecting response got %v", msg)
+	}
+
+	return conn, nil
+}
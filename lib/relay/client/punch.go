@@ -0,0 +1,80 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/dialer"
+	syncthingprotocol "github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/relay/protocol"
+)
+
+// SupportsPunching returns true if the relay with the given URI advertises
+// that it can pass addresses between devices for hole punching.
+func SupportsPunching(uri *url.URL) bool {
+	version, err := strconv.Atoi(uri.Query().Get("protocolVersion"))
+	return err == nil && version >= 3
+}
+
+// RequestPunch sends our candidate addresses to the device with the given
+// ID, which must be joined to the relay, and returns the addresses the
+// device answers with. Both sides are expected to start connecting to each
+// other as soon as they have the addresses.
+func RequestPunch(ctx context.Context, uri *url.URL, id syncthingprotocol.DeviceID, certs []tls.Certificate, addrs []string, timeout time.Duration) ([]string, error) {
+	if uri.Scheme != "relay" {
+		return nil, fmt.Errorf("unsupported relay scheme: %v", uri.Scheme)
+	}
+	if !SupportsPunching(uri) {
+		return nil, fmt.Errorf("relay %s does not support hole punching", uri.Host)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	rconn, err := dialer.DialContext(ctx, "tcp", uri.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := tls.Client(rconn, configForCerts(certs))
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	if err := performHandshakeAndValidation(conn, uri); err != nil {
+		return nil, err
+	}
+
+	request := protocol.PunchRequest{
+		ID:        id[:],
+		Addresses: addrs,
+	}
+	if err := protocol.WriteMessage(conn, request); err != nil {
+		return nil, err
+	}
+
+	message, err := protocol.ReadMessage(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	switch msg := message.(type) {
+	case protocol.Response:
+		return nil, fmt.Errorf("incorrect response code %d: %s", msg.Code, msg.Message)
+	case protocol.PunchInvitation:
+		if from := syncthingprotocol.DeviceIDFromBytes(msg.From); from != id {
+			return nil, fmt.Errorf("punch response from %s, expected %s", from, id)
+		}
+		return msg.Addresses, nil
+	default:
+		return nil, fmt.Errorf("protocol error: unexpected message %v", msg)
+	}
+}
@@ -68,6 +68,13 @@ func (c *staticClient) serve(ctx context.Context) error {
 	l.Infof("Joined relay %s://%s", c.uri.Scheme, c.uri.Host)
 	defer l.Infof("Disconnected from relay %s://%s", c.uri.Scheme, c.uri.Host)
 
+	if SupportsPunching(c.uri) {
+		if err := protocol.WriteMessage(c.conn, protocol.JoinPunchRequest{}); err != nil {
+			l.Infoln("Relay write:", err)
+			return err
+		}
+	}
+
 	c.mut.Lock()
 	c.connected = true
 	c.mut.Unlock()
@@ -100,6 +107,13 @@ func (c *staticClient) serve(ctx context.Context) error {
 				}
 				c.invitations <- msg
 
+			case protocol.PunchInvitation:
+				select {
+				case c.punches <- msg:
+				default:
+					l.Debugln(c, "dropping punch invitation from", syncthingprotocol.DeviceIDFromBytes(msg.From))
+				}
+
 			case protocol.RelayFull:
 				l.Infof("Disconnected from relay %s due to it becoming full.", c.uri)
 				return fmt.Errorf("relay full")
@@ -109,6 +123,12 @@ func (c *staticClient) serve(ctx context.Context) error {
 				return fmt.Errorf("protocol error: unexpected message %v", msg)
 			}
 
+		case res := <-c.punchReplies:
+			if err := protocol.WriteMessage(c.conn, res); err != nil {
+				l.Infoln("Relay write:", err)
+				return err
+			}
+
 		case <-ctx.Done():
 			l.Debugln(c, "stopping")
 			return nil
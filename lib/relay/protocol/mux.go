@@ -0,0 +1,525 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package protocol
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+const (
+	muxMaxData       = 32 << 10  // the most data sent in one MuxData message
+	muxWindow        = 256 << 10 // how much unread data a stream may have in flight
+	muxPingInterval  = time.Minute
+	muxTimeout       = 2 * muxPingInterval
+	muxJoinTimeout   = 10 * time.Second
+	muxWriteTimeout  = muxTimeout
+	muxNotifyBufSize = 1
+)
+
+var (
+	// ErrMuxClosed is returned by Join when the Mux was closed before the
+	// join request was sent.
+	ErrMuxClosed      = errors.New("multiplexed connection closed")
+	errStreamClosed   = errors.New("session closed")
+	errWindowExceeded = errors.New("protocol error: flow control window exceeded")
+	errMuxJoinTimeout = errors.New("timed out joining session")
+)
+
+// A Mux carries any number of sessions over a single connection to the
+// relay, each as a stream with its own flow control: a side only sends as
+// much data on a stream as the other side has announced room for, so a
+// session that isn't read from doesn't hold up the others.
+//
+// Streams are opened by the client with Join. On the relay side, joins are
+// passed to the function given to NewMuxServer.
+type Mux struct {
+	conn      net.Conn
+	joiner    func(key []byte, conn net.Conn) Response
+	idleClose bool
+
+	wmut    sync.Mutex // serializes writes to conn
+	mut     sync.Mutex // protects the below
+	streams map[uint32]*muxStream
+	pending map[uint32]chan MuxResponse
+	nextID  uint32
+	err     error
+	closed  chan struct{}
+}
+
+// NewMuxClient returns a Mux for a connection on which a JoinMuxRequest
+// was accepted. It closes the connection once its last session is closed.
+func NewMuxClient(conn net.Conn) *Mux {
+	m := newMux(conn)
+	m.idleClose = true
+	return m
+}
+
+// NewMuxServer returns a Mux that passes sessions joined by the client to
+// joiner, which returns the response to send back.
+func NewMuxServer(conn net.Conn, joiner func(key []byte, conn net.Conn) Response) *Mux {
+	m := newMux(conn)
+	m.joiner = joiner
+	return m
+}
+
+func newMux(conn net.Conn) *Mux {
+	return &Mux{
+		conn:    conn,
+		wmut:    sync.NewMutex(),
+		mut:     sync.NewMutex(),
+		streams: make(map[uint32]*muxStream),
+		pending: make(map[uint32]chan MuxResponse),
+		closed:  make(chan struct{}),
+	}
+}
+
+// Serve handles incoming messages until the connection fails or the Mux is
+// closed.
+func (m *Mux) Serve() error {
+	go m.pinger()
+
+	for {
+		m.conn.SetReadDeadline(time.Now().Add(muxTimeout))
+		message, err := ReadMessage(m.conn)
+		if err != nil {
+			m.fail(err)
+			return err
+		}
+
+		switch msg := message.(type) {
+		case Ping:
+			// Only keeps the connection alive.
+
+		case MuxData:
+			if s := m.stream(msg.Stream); s != nil {
+				if err := s.deliver(msg.Data); err != nil {
+					s.remoteClose(err)
+					m.removeStream(s.id)
+					go m.send(MuxClose{Stream: s.id})
+				}
+			}
+
+		case MuxWindowUpdate:
+			if s := m.stream(msg.Stream); s != nil {
+				s.grow(int(msg.Increment))
+			}
+
+		case MuxClose:
+			if s := m.stream(msg.Stream); s != nil {
+				s.remoteClose(io.EOF)
+				m.removeStream(s.id)
+			}
+
+		case MuxJoin:
+			m.handleJoin(msg)
+
+		case MuxResponse:
+			m.mut.Lock()
+			resp, ok := m.pending[msg.Stream]
+			delete(m.pending, msg.Stream)
+			m.mut.Unlock()
+			if ok {
+				resp <- msg
+			}
+
+		default:
+			err := fmt.Errorf("protocol error: unexpected message %v", msg)
+			m.fail(err)
+			return err
+		}
+	}
+}
+
+// Join joins the session with the given key, returning a connection to the
+// other side of it.
+func (m *Mux) Join(key []byte) (net.Conn, error) {
+	m.mut.Lock()
+	if m.err != nil {
+		// Nothing was sent, so the key can still be used to join on
+		// another connection.
+		m.mut.Unlock()
+		return nil, ErrMuxClosed
+	}
+	m.nextID++
+	s := newMuxStream(m, m.nextID)
+	m.streams[s.id] = s
+	resp := make(chan MuxResponse, 1)
+	m.pending[s.id] = resp
+	m.mut.Unlock()
+
+	if err := m.send(MuxJoin{Stream: s.id, Key: key}); err != nil {
+		m.removeStream(s.id)
+		return nil, err
+	}
+
+	timeout := time.NewTimer(muxJoinTimeout)
+	defer timeout.Stop()
+	select {
+	case msg := <-resp:
+		if msg.Code != 0 {
+			m.removeStream(s.id)
+			return nil, fmt.Errorf("Incorrect response code %d: %s", msg.Code, msg.Message)
+		}
+		return s, nil
+	case <-timeout.C:
+		s.Close()
+		return nil, errMuxJoinTimeout
+	case <-m.closed:
+		m.mut.Lock()
+		err := m.err
+		m.mut.Unlock()
+		if err == ErrMuxClosed {
+			err = errStreamClosed
+		}
+		return nil, err
+	}
+}
+
+// Closed returns true once the Mux can't be used for new sessions.
+func (m *Mux) Closed() bool {
+	select {
+	case <-m.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close closes the connection and all sessions on it.
+func (m *Mux) Close() error {
+	m.fail(ErrMuxClosed)
+	return nil
+}
+
+func (m *Mux) handleJoin(msg MuxJoin) {
+	if m.joiner == nil {
+		go m.send(MuxResponse{Stream: msg.Stream, Code: ResponseUnexpectedMessage.Code, Message: ResponseUnexpectedMessage.Message})
+		return
+	}
+
+	m.mut.Lock()
+	if _, ok := m.streams[msg.Stream]; ok {
+		m.mut.Unlock()
+		go m.send(MuxResponse{Stream: msg.Stream, Code: ResponseAlreadyConnected.Code, Message: ResponseAlreadyConnected.Message})
+		return
+	}
+	s := newMuxStream(m, msg.Stream)
+	m.streams[s.id] = s
+	m.mut.Unlock()
+
+	res := m.joiner(msg.Key, s)
+	if res.Code != 0 {
+		m.removeStream(s.id)
+	}
+	// Replies are sent asynchronously, so that reading never waits for
+	// writing and two sides with full buffers can't block each other.
+	go m.send(MuxResponse{Stream: msg.Stream, Code: res.Code, Message: res.Message})
+}
+
+func (m *Mux) send(msg interface{}) error {
+	m.wmut.Lock()
+	defer m.wmut.Unlock()
+	m.conn.SetWriteDeadline(time.Now().Add(muxWriteTimeout))
+	if err := WriteMessage(m.conn, msg); err != nil {
+		m.fail(err)
+		return err
+	}
+	return nil
+}
+
+func (m *Mux) pinger() {
+	ticker := time.NewTicker(muxPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := m.send(Ping{}); err != nil {
+				return
+			}
+		case <-m.closed:
+			return
+		}
+	}
+}
+
+func (m *Mux) stream(id uint32) *muxStream {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	return m.streams[id]
+}
+
+func (m *Mux) removeStream(id uint32) {
+	m.mut.Lock()
+	delete(m.streams, id)
+	delete(m.pending, id)
+	idle := m.idleClose && len(m.streams) == 0
+	m.mut.Unlock()
+
+	if idle {
+		m.fail(ErrMuxClosed)
+	}
+}
+
+func (m *Mux) fail(err error) {
+	m.mut.Lock()
+	if m.err != nil {
+		m.mut.Unlock()
+		return
+	}
+	m.err = err
+	close(m.closed)
+	streams := m.streams
+	m.streams = make(map[uint32]*muxStream)
+	m.pending = make(map[uint32]chan MuxResponse)
+	m.mut.Unlock()
+
+	for _, s := range streams {
+		s.remoteClose(err)
+	}
+	m.conn.Close()
+}
+
+// A muxStream is one session on a Mux.
+type muxStream struct {
+	mux *Mux
+	id  uint32
+
+	mut           sync.Mutex // protects the below
+	buf           []byte     // received but not yet read
+	consumed      int        // read since the last window update we sent
+	sendWindow    int        // how much we may send before the other side has room for more
+	err           error      // set when the other side closed or the connection failed
+	closed        bool       // set when closed on our side
+	readDeadline  time.Time
+	writeDeadline time.Time
+
+	readNotify  chan struct{}
+	writeNotify chan struct{}
+}
+
+func newMuxStream(m *Mux, id uint32) *muxStream {
+	return &muxStream{
+		mux:         m,
+		id:          id,
+		mut:         sync.NewMutex(),
+		sendWindow:  muxWindow,
+		readNotify:  make(chan struct{}, muxNotifyBufSize),
+		writeNotify: make(chan struct{}, muxNotifyBufSize),
+	}
+}
+
+func (s *muxStream) Read(p []byte) (int, error) {
+	for {
+		s.mut.Lock()
+		if s.closed {
+			s.mut.Unlock()
+			return 0, errStreamClosed
+		}
+		if len(s.buf) > 0 {
+			n := copy(p, s.buf)
+			s.buf = s.buf[n:]
+			if len(s.buf) == 0 {
+				s.buf = nil
+			}
+			s.consumed += n
+			inc := 0
+			if s.consumed >= muxWindow/2 {
+				inc = s.consumed
+				s.consumed = 0
+			}
+			s.mut.Unlock()
+
+			if inc > 0 {
+				// A failure here fails the whole connection, which the
+				// next read notices.
+				s.mux.send(MuxWindowUpdate{Stream: s.id, Increment: uint32(inc)})
+			}
+			return n, nil
+		}
+		if s.err != nil {
+			err := s.err
+			s.mut.Unlock()
+			return 0, err
+		}
+		deadline := s.readDeadline
+		s.mut.Unlock()
+
+		if err := waitFor(s.readNotify, deadline); err != nil {
+			return 0, err
+		}
+	}
+}
+
+func (s *muxStream) Write(p []byte) (int, error) {
+	written := 0
+	for written < len(p) {
+		s.mut.Lock()
+		if s.closed {
+			s.mut.Unlock()
+			return written, errStreamClosed
+		}
+		if s.err != nil {
+			s.mut.Unlock()
+			return written, errStreamClosed
+		}
+		if s.sendWindow == 0 {
+			deadline := s.writeDeadline
+			s.mut.Unlock()
+			if err := waitFor(s.writeNotify, deadline); err != nil {
+				return written, err
+			}
+			continue
+		}
+		n := len(p) - written
+		if n > s.sendWindow {
+			n = s.sendWindow
+		}
+		if n > muxMaxData {
+			n = muxMaxData
+		}
+		s.sendWindow -= n
+		s.mut.Unlock()
+
+		if err := s.mux.send(MuxData{Stream: s.id, Data: p[written : written+n]}); err != nil {
+			return written, err
+		}
+		written += n
+	}
+	return written, nil
+}
+
+func (s *muxStream) Close() error {
+	s.mut.Lock()
+	if s.closed {
+		s.mut.Unlock()
+		return nil
+	}
+	s.closed = true
+	tellRemote := s.err == nil
+	s.mut.Unlock()
+	s.poke()
+
+	s.mux.removeStream(s.id)
+	if tellRemote {
+		go s.mux.send(MuxClose{Stream: s.id})
+	}
+	return nil
+}
+
+func (s *muxStream) LocalAddr() net.Addr {
+	return s.mux.conn.LocalAddr()
+}
+
+func (s *muxStream) RemoteAddr() net.Addr {
+	return s.mux.conn.RemoteAddr()
+}
+
+func (s *muxStream) SetDeadline(t time.Time) error {
+	s.mut.Lock()
+	s.readDeadline = t
+	s.writeDeadline = t
+	s.mut.Unlock()
+	s.poke()
+	return nil
+}
+
+func (s *muxStream) SetReadDeadline(t time.Time) error {
+	s.mut.Lock()
+	s.readDeadline = t
+	s.mut.Unlock()
+	s.poke()
+	return nil
+}
+
+func (s *muxStream) SetWriteDeadline(t time.Time) error {
+	s.mut.Lock()
+	s.writeDeadline = t
+	s.mut.Unlock()
+	s.poke()
+	return nil
+}
+
+// deliver queues data received from the other side for reading.
+func (s *muxStream) deliver(data []byte) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if s.closed {
+		return nil
+	}
+	if len(s.buf)+s.consumed+len(data) > muxWindow {
+		return errWindowExceeded
+	}
+	s.buf = append(s.buf, data...)
+	notify(s.readNotify)
+	return nil
+}
+
+// grow adds room announced by the other side to the send window.
+func (s *muxStream) grow(n int) {
+	s.mut.Lock()
+	s.sendWindow += n
+	s.mut.Unlock()
+	notify(s.writeNotify)
+}
+
+// remoteClose makes reads return err once the received data is consumed,
+// and writes fail.
+func (s *muxStream) remoteClose(err error) {
+	s.mut.Lock()
+	if s.err == nil {
+		s.err = err
+	}
+	s.mut.Unlock()
+	s.poke()
+}
+
+func (s *muxStream) poke() {
+	notify(s.readNotify)
+	notify(s.writeNotify)
+}
+
+func notify(c chan struct{}) {
+	select {
+	case c <- struct{}{}:
+	default:
+	}
+}
+
+// waitFor waits for a notification on c, or returns a timeout error when the
+// deadline passes first.
+func waitFor(c <-chan struct{}, deadline time.Time) error {
+	if deadline.IsZero() {
+		<-c
+		return nil
+	}
+	d := time.Until(deadline)
+	if d <= 0 {
+		return timeoutError{}
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-c:
+		return nil
+	case <-timer.C:
+		return timeoutError{}
+	}
+}
+
+// timeoutError is returned when a deadline passes. Like the errors of the
+// net package it has a Timeout method.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
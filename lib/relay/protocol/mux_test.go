@@ -0,0 +1,77 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package protocol
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestMux(t *testing.T) {
+	c0, c1 := net.Pipe()
+
+	// The relay side echoes everything on sessions with a known key.
+	server := NewMuxServer(c1, func(key []byte, conn net.Conn) Response {
+		if string(key) != "known" {
+			return ResponseNotFound
+		}
+		go func() {
+			io.Copy(conn, conn)
+			conn.Close()
+		}()
+		return ResponseSuccess
+	})
+	go server.Serve()
+	defer server.Close()
+
+	client := NewMuxClient(c0)
+	go client.Serve()
+	defer client.Close()
+
+	// Several sessions at once, each sending more than fits the window so
+	// that the echo side has to wait for reads.
+	data := make([]byte, 4*muxWindow+123)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+	errs := make(chan error)
+	for i := 0; i < 3; i++ {
+		conn, err := client.Join([]byte("known"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		go func() {
+			defer conn.Close()
+			go conn.Write(data)
+			res := make([]byte, len(data))
+			conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+			if _, err := io.ReadFull(conn, res); err != nil {
+				errs <- err
+				return
+			}
+			if !bytes.Equal(res, data) {
+				errs <- io.ErrUnexpectedEOF
+				return
+			}
+			errs <- nil
+		}()
+	}
+
+	if _, err := client.Join([]byte("unknown")); err == nil {
+		t.Error("unexpected success joining an unknown session")
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -21,6 +21,16 @@ const (
 	messageTypeConnectRequest
 	messageTypeSessionInvitation
 	messageTypeRelayFull
+	messageTypeJoinMuxRequest
+	messageTypeMuxJoin
+	messageTypeMuxResponse
+	messageTypeMuxData
+	messageTypeMuxWindowUpdate
+	messageTypeMuxClose
+	messageTypeJoinPunchRequest
+	messageTypePunchRequest
+	messageTypePunchInvitation
+	messageTypePunchResponse
 )
 
 type header struct {
@@ -38,6 +48,60 @@ type JoinSessionRequest struct {
 	Key []byte // max:32
 }
 
+// JoinMuxRequest makes a session connection carry any number of sessions,
+// using the Mux messages below.
+type JoinMuxRequest struct{}
+
+type MuxJoin struct {
+	Stream uint32
+	Key    []byte // max:32
+}
+
+type MuxResponse struct {
+	Stream  uint32
+	Code    int32
+	Message string
+}
+
+type MuxData struct {
+	Stream uint32
+	Data   []byte // max:32768
+}
+
+type MuxWindowUpdate struct {
+	Stream    uint32
+	Increment uint32
+}
+
+type MuxClose struct {
+	Stream uint32
+}
+
+// JoinPunchRequest, sent by a joined client, asks to be passed on
+// PunchInvitations from peers wanting to connect directly.
+type JoinPunchRequest struct{}
+
+// PunchRequest asks the relay to pass our candidate addresses on to the
+// given joined peer, and to answer with a PunchInvitation carrying the
+// peer's, after which both sides try to connect to each other directly at
+// the same time.
+type PunchRequest struct {
+	ID        []byte   // max:32
+	Addresses []string // max:16
+}
+
+type PunchInvitation struct {
+	From      []byte   // max:32
+	Addresses []string // max:16
+}
+
+// PunchResponse answers the PunchInvitation from the peer with the given
+// ID with our own candidate addresses.
+type PunchResponse struct {
+	ID        []byte   // max:32
+	Addresses []string // max:16
+}
+
 type Response struct {
 	Code    int32
 	Message string
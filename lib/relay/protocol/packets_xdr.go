@@ -259,6 +259,635 @@ func (o *JoinSessionRequest) UnmarshalXDRFrom(u *xdr.Unmarshaller) error {
 
 /*
 
+JoinMuxRequest Structure:
+(contains no fields)
+
+
+struct JoinMuxRequest {
+}
+
+*/
+
+func (o JoinMuxRequest) XDRSize() int {
+	return 0
+}
+func (o JoinMuxRequest) MarshalXDR() ([]byte, error) {
+	return nil, nil
+}
+
+func (o JoinMuxRequest) MustMarshalXDR() []byte {
+	return nil
+}
+
+func (o JoinMuxRequest) MarshalXDRInto(m *xdr.Marshaller) error {
+	return nil
+}
+
+func (o *JoinMuxRequest) UnmarshalXDR(bs []byte) error {
+	return nil
+}
+
+func (o *JoinMuxRequest) UnmarshalXDRFrom(u *xdr.Unmarshaller) error {
+	return nil
+}
+
+/*
+
+MuxJoin Structure:
+
+ 0                   1                   2                   3
+ 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+|                            Stream                             |
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+/                                                               /
+\                  Key (length + padded data)                   \
+/                                                               /
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+
+
+struct MuxJoin {
+	unsigned int Stream;
+	opaque Key<32>;
+}
+
+*/
+
+func (o MuxJoin) XDRSize() int {
+	return 4 +
+		4 + len(o.Key) + xdr.Padding(len(o.Key))
+}
+
+func (o MuxJoin) MarshalXDR() ([]byte, error) {
+	buf := make([]byte, o.XDRSize())
+	m := &xdr.Marshaller{Data: buf}
+	return buf, o.MarshalXDRInto(m)
+}
+
+func (o MuxJoin) MustMarshalXDR() []byte {
+	bs, err := o.MarshalXDR()
+	if err != nil {
+		panic(err)
+	}
+	return bs
+}
+
+func (o MuxJoin) MarshalXDRInto(m *xdr.Marshaller) error {
+	m.MarshalUint32(o.Stream)
+	if l := len(o.Key); l > 32 {
+		return xdr.ElementSizeExceeded("Key", l, 32)
+	}
+	m.MarshalBytes(o.Key)
+	return m.Error
+}
+
+func (o *MuxJoin) UnmarshalXDR(bs []byte) error {
+	u := &xdr.Unmarshaller{Data: bs}
+	return o.UnmarshalXDRFrom(u)
+}
+func (o *MuxJoin) UnmarshalXDRFrom(u *xdr.Unmarshaller) error {
+	o.Stream = u.UnmarshalUint32()
+	o.Key = u.UnmarshalBytesMax(32)
+	return u.Error
+}
+
+/*
+
+MuxResponse Structure:
+
+ 0                   1                   2                   3
+ 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+|                            Stream                             |
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+|                             Code                              |
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+/                                                               /
+\                Message (length + padded data)                 \
+/                                                               /
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+
+
+struct MuxResponse {
+	unsigned int Stream;
+	int Code;
+	string Message<>;
+}
+
+*/
+
+func (o MuxResponse) XDRSize() int {
+	return 4 + 4 +
+		4 + len(o.Message) + xdr.Padding(len(o.Message))
+}
+
+func (o MuxResponse) MarshalXDR() ([]byte, error) {
+	buf := make([]byte, o.XDRSize())
+	m := &xdr.Marshaller{Data: buf}
+	return buf, o.MarshalXDRInto(m)
+}
+
+func (o MuxResponse) MustMarshalXDR() []byte {
+	bs, err := o.MarshalXDR()
+	if err != nil {
+		panic(err)
+	}
+	return bs
+}
+
+func (o MuxResponse) MarshalXDRInto(m *xdr.Marshaller) error {
+	m.MarshalUint32(o.Stream)
+	m.MarshalUint32(uint32(o.Code))
+	m.MarshalString(o.Message)
+	return m.Error
+}
+
+func (o *MuxResponse) UnmarshalXDR(bs []byte) error {
+	u := &xdr.Unmarshaller{Data: bs}
+	return o.UnmarshalXDRFrom(u)
+}
+func (o *MuxResponse) UnmarshalXDRFrom(u *xdr.Unmarshaller) error {
+	o.Stream = u.UnmarshalUint32()
+	o.Code = int32(u.UnmarshalUint32())
+	o.Message = u.UnmarshalString()
+	return u.Error
+}
+
+/*
+
+MuxData Structure:
+
+ 0                   1                   2                   3
+ 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+|                            Stream                             |
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+/                                                               /
+\                  Data (length + padded data)                  \
+/                                                               /
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+
+
+struct MuxData {
+	unsigned int Stream;
+	opaque Data<32768>;
+}
+
+*/
+
+func (o MuxData) XDRSize() int {
+	return 4 +
+		4 + len(o.Data) + xdr.Padding(len(o.Data))
+}
+
+func (o MuxData) MarshalXDR() ([]byte, error) {
+	buf := make([]byte, o.XDRSize())
+	m := &xdr.Marshaller{Data: buf}
+	return buf, o.MarshalXDRInto(m)
+}
+
+func (o MuxData) MustMarshalXDR() []byte {
+	bs, err := o.MarshalXDR()
+	if err != nil {
+		panic(err)
+	}
+	return bs
+}
+
+func (o MuxData) MarshalXDRInto(m *xdr.Marshaller) error {
+	m.MarshalUint32(o.Stream)
+	if l := len(o.Data); l > 32768 {
+		return xdr.ElementSizeExceeded("Data", l, 32768)
+	}
+	m.MarshalBytes(o.Data)
+	return m.Error
+}
+
+func (o *MuxData) UnmarshalXDR(bs []byte) error {
+	u := &xdr.Unmarshaller{Data: bs}
+	return o.UnmarshalXDRFrom(u)
+}
+func (o *MuxData) UnmarshalXDRFrom(u *xdr.Unmarshaller) error {
+	o.Stream = u.UnmarshalUint32()
+	o.Data = u.UnmarshalBytesMax(32768)
+	return u.Error
+}
+
+/*
+
+MuxWindowUpdate Structure:
+
+ 0                   1                   2                   3
+ 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+|                            Stream                             |
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+|                           Increment                           |
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+
+
+struct MuxWindowUpdate {
+	unsigned int Stream;
+	unsigned int Increment;
+}
+
+*/
+
+func (o MuxWindowUpdate) XDRSize() int {
+	return 4 + 4
+}
+
+func (o MuxWindowUpdate) MarshalXDR() ([]byte, error) {
+	buf := make([]byte, o.XDRSize())
+	m := &xdr.Marshaller{Data: buf}
+	return buf, o.MarshalXDRInto(m)
+}
+
+func (o MuxWindowUpdate) MustMarshalXDR() []byte {
+	bs, err := o.MarshalXDR()
+	if err != nil {
+		panic(err)
+	}
+	return bs
+}
+
+func (o MuxWindowUpdate) MarshalXDRInto(m *xdr.Marshaller) error {
+	m.MarshalUint32(o.Stream)
+	m.MarshalUint32(o.Increment)
+	return m.Error
+}
+
+func (o *MuxWindowUpdate) UnmarshalXDR(bs []byte) error {
+	u := &xdr.Unmarshaller{Data: bs}
+	return o.UnmarshalXDRFrom(u)
+}
+func (o *MuxWindowUpdate) UnmarshalXDRFrom(u *xdr.Unmarshaller) error {
+	o.Stream = u.UnmarshalUint32()
+	o.Increment = u.UnmarshalUint32()
+	return u.Error
+}
+
+/*
+
+MuxClose Structure:
+
+ 0                   1                   2                   3
+ 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+|                            Stream                             |
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+
+
+struct MuxClose {
+	unsigned int Stream;
+}
+
+*/
+
+func (o MuxClose) XDRSize() int {
+	return 4
+}
+
+func (o MuxClose) MarshalXDR() ([]byte, error) {
+	buf := make([]byte, o.XDRSize())
+	m := &xdr.Marshaller{Data: buf}
+	return buf, o.MarshalXDRInto(m)
+}
+
+func (o MuxClose) MustMarshalXDR() []byte {
+	bs, err := o.MarshalXDR()
+	if err != nil {
+		panic(err)
+	}
+	return bs
+}
+
+func (o MuxClose) MarshalXDRInto(m *xdr.Marshaller) error {
+	m.MarshalUint32(o.Stream)
+	return m.Error
+}
+
+func (o *MuxClose) UnmarshalXDR(bs []byte) error {
+	u := &xdr.Unmarshaller{Data: bs}
+	return o.UnmarshalXDRFrom(u)
+}
+func (o *MuxClose) UnmarshalXDRFrom(u *xdr.Unmarshaller) error {
+	o.Stream = u.UnmarshalUint32()
+	return u.Error
+}
+
+/*
+
+JoinPunchRequest Structure:
+(contains no fields)
+
+
+struct JoinPunchRequest {
+}
+
+*/
+
+func (o JoinPunchRequest) XDRSize() int {
+	return 0
+}
+func (o JoinPunchRequest) MarshalXDR() ([]byte, error) {
+	return nil, nil
+}
+
+func (o JoinPunchRequest) MustMarshalXDR() []byte {
+	return nil
+}
+
+func (o JoinPunchRequest) MarshalXDRInto(m *xdr.Marshaller) error {
+	return nil
+}
+
+func (o *JoinPunchRequest) UnmarshalXDR(bs []byte) error {
+	return nil
+}
+
+func (o *JoinPunchRequest) UnmarshalXDRFrom(u *xdr.Unmarshaller) error {
+	return nil
+}
+
+/*
+
+PunchRequest Structure:
+
+ 0                   1                   2                   3
+ 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+/                                                               /
+\                   ID (length + padded data)                   \
+/                                                               /
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+|                      Number of Addresses                      |
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+/                                                               /
+/                                                               /
+\               Addresses (length + padded data)                \
+/                                                               /
+/                                                               /
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+
+
+struct PunchRequest {
+	opaque ID<32>;
+	string Addresses<16>;
+}
+
+*/
+
+func (o PunchRequest) XDRSize() int {
+	return 4 + len(o.ID) + xdr.Padding(len(o.ID)) +
+		4 + xdr.SizeOfSlice(o.Addresses)
+}
+
+func (o PunchRequest) MarshalXDR() ([]byte, error) {
+	buf := make([]byte, o.XDRSize())
+	m := &xdr.Marshaller{Data: buf}
+	return buf, o.MarshalXDRInto(m)
+}
+
+func (o PunchRequest) MustMarshalXDR() []byte {
+	bs, err := o.MarshalXDR()
+	if err != nil {
+		panic(err)
+	}
+	return bs
+}
+
+func (o PunchRequest) MarshalXDRInto(m *xdr.Marshaller) error {
+	if l := len(o.ID); l > 32 {
+		return xdr.ElementSizeExceeded("ID", l, 32)
+	}
+	m.MarshalBytes(o.ID)
+	if l := len(o.Addresses); l > 16 {
+		return xdr.ElementSizeExceeded("Addresses", l, 16)
+	}
+	m.MarshalUint32(uint32(len(o.Addresses)))
+	for i := range o.Addresses {
+		m.MarshalString(o.Addresses[i])
+	}
+	return m.Error
+}
+
+func (o *PunchRequest) UnmarshalXDR(bs []byte) error {
+	u := &xdr.Unmarshaller{Data: bs}
+	return o.UnmarshalXDRFrom(u)
+}
+func (o *PunchRequest) UnmarshalXDRFrom(u *xdr.Unmarshaller) error {
+	o.ID = u.UnmarshalBytesMax(32)
+	_AddressesSize := int(u.UnmarshalUint32())
+	if _AddressesSize < 0 {
+		return xdr.ElementSizeExceeded("Addresses", _AddressesSize, 16)
+	} else if _AddressesSize == 0 {
+		o.Addresses = nil
+	} else {
+		if _AddressesSize > 16 {
+			return xdr.ElementSizeExceeded("Addresses", _AddressesSize, 16)
+		}
+		if _AddressesSize <= len(o.Addresses) {
+			for i := _AddressesSize; i < len(o.Addresses); i++ {
+				o.Addresses[i] = ""
+			}
+			o.Addresses = o.Addresses[:_AddressesSize]
+		} else {
+			o.Addresses = make([]string, _AddressesSize)
+		}
+		for i := range o.Addresses {
+			o.Addresses[i] = u.UnmarshalString()
+		}
+	}
+	return u.Error
+}
+
+/*
+
+PunchInvitation Structure:
+
+ 0                   1                   2                   3
+ 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+/                                                               /
+\                  From (length + padded data)                  \
+/                                                               /
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+|                      Number of Addresses                      |
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+/                                                               /
+/                                                               /
+\               Addresses (length + padded data)                \
+/                                                               /
+/                                                               /
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+
+
+struct PunchInvitation {
+	opaque From<32>;
+	string Addresses<16>;
+}
+
+*/
+
+func (o PunchInvitation) XDRSize() int {
+	return 4 + len(o.From) + xdr.Padding(len(o.From)) +
+		4 + xdr.SizeOfSlice(o.Addresses)
+}
+
+func (o PunchInvitation) MarshalXDR() ([]byte, error) {
+	buf := make([]byte, o.XDRSize())
+	m := &xdr.Marshaller{Data: buf}
+	return buf, o.MarshalXDRInto(m)
+}
+
+func (o PunchInvitation) MustMarshalXDR() []byte {
+	bs, err := o.MarshalXDR()
+	if err != nil {
+		panic(err)
+	}
+	return bs
+}
+
+func (o PunchInvitation) MarshalXDRInto(m *xdr.Marshaller) error {
+	if l := len(o.From); l > 32 {
+		return xdr.ElementSizeExceeded("From", l, 32)
+	}
+	m.MarshalBytes(o.From)
+	if l := len(o.Addresses); l > 16 {
+		return xdr.ElementSizeExceeded("Addresses", l, 16)
+	}
+	m.MarshalUint32(uint32(len(o.Addresses)))
+	for i := range o.Addresses {
+		m.MarshalString(o.Addresses[i])
+	}
+	return m.Error
+}
+
+func (o *PunchInvitation) UnmarshalXDR(bs []byte) error {
+	u := &xdr.Unmarshaller{Data: bs}
+	return o.UnmarshalXDRFrom(u)
+}
+func (o *PunchInvitation) UnmarshalXDRFrom(u *xdr.Unmarshaller) error {
+	o.From = u.UnmarshalBytesMax(32)
+	_AddressesSize := int(u.UnmarshalUint32())
+	if _AddressesSize < 0 {
+		return xdr.ElementSizeExceeded("Addresses", _AddressesSize, 16)
+	} else if _AddressesSize == 0 {
+		o.Addresses = nil
+	} else {
+		if _AddressesSize > 16 {
+			return xdr.ElementSizeExceeded("Addresses", _AddressesSize, 16)
+		}
+		if _AddressesSize <= len(o.Addresses) {
+			for i := _AddressesSize; i < len(o.Addresses); i++ {
+				o.Addresses[i] = ""
+			}
+			o.Addresses = o.Addresses[:_AddressesSize]
+		} else {
+			o.Addresses = make([]string, _AddressesSize)
+		}
+		for i := range o.Addresses {
+			o.Addresses[i] = u.UnmarshalString()
+		}
+	}
+	return u.Error
+}
+
+/*
+
+PunchResponse Structure:
+
+ 0                   1                   2                   3
+ 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+/                                                               /
+\                   ID (length + padded data)                   \
+/                                                               /
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+|                      Number of Addresses                      |
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+/                                                               /
+/                                                               /
+\               Addresses (length + padded data)                \
+/                                                               /
+/                                                               /
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+
+
+struct PunchResponse {
+	opaque ID<32>;
+	string Addresses<16>;
+}
+
+*/
+
+func (o PunchResponse) XDRSize() int {
+	return 4 + len(o.ID) + xdr.Padding(len(o.ID)) +
+		4 + xdr.SizeOfSlice(o.Addresses)
+}
+
+func (o PunchResponse) MarshalXDR() ([]byte, error) {
+	buf := make([]byte, o.XDRSize())
+	m := &xdr.Marshaller{Data: buf}
+	return buf, o.MarshalXDRInto(m)
+}
+
+func (o PunchResponse) MustMarshalXDR() []byte {
+	bs, err := o.MarshalXDR()
+	if err != nil {
+		panic(err)
+	}
+	return bs
+}
+
+func (o PunchResponse) MarshalXDRInto(m *xdr.Marshaller) error {
+	if l := len(o.ID); l > 32 {
+		return xdr.ElementSizeExceeded("ID", l, 32)
+	}
+	m.MarshalBytes(o.ID)
+	if l := len(o.Addresses); l > 16 {
+		return xdr.ElementSizeExceeded("Addresses", l, 16)
+	}
+	m.MarshalUint32(uint32(len(o.Addresses)))
+	for i := range o.Addresses {
+		m.MarshalString(o.Addresses[i])
+	}
+	return m.Error
+}
+
+func (o *PunchResponse) UnmarshalXDR(bs []byte) error {
+	u := &xdr.Unmarshaller{Data: bs}
+	return o.UnmarshalXDRFrom(u)
+}
+func (o *PunchResponse) UnmarshalXDRFrom(u *xdr.Unmarshaller) error {
+	o.ID = u.UnmarshalBytesMax(32)
+	_AddressesSize := int(u.UnmarshalUint32())
+	if _AddressesSize < 0 {
+		return xdr.ElementSizeExceeded("Addresses", _AddressesSize, 16)
+	} else if _AddressesSize == 0 {
+		o.Addresses = nil
+	} else {
+		if _AddressesSize > 16 {
+			return xdr.ElementSizeExceeded("Addresses", _AddressesSize, 16)
+		}
+		if _AddressesSize <= len(o.Addresses) {
+			for i := _AddressesSize; i < len(o.Addresses); i++ {
+				o.Addresses[i] = ""
+			}
+			o.Addresses = o.Addresses[:_AddressesSize]
+		} else {
+			o.Addresses = make([]string, _AddressesSize)
+		}
+		for i := range o.Addresses {
+			o.Addresses[i] = u.UnmarshalString()
+		}
+	}
+	return u.Error
+}
+
+/*
+
 Response Structure:
 
  0                   1                   2                   3
@@ -16,6 +16,8 @@ var (
 	ResponseSuccess           = Response{0, "success"}
 	ResponseNotFound          = Response{1, "not found"}
 	ResponseAlreadyConnected  = Response{2, "already connected"}
+	ResponseNotSupported      = Response{3, "not supported"}
+	ResponseTimeout           = Response{4, "timeout"}
 	ResponseUnexpectedMessage = Response{100, "unexpected message"}
 )
 
@@ -52,6 +54,36 @@ func WriteMessage(w io.Writer, message interface{}) error {
 	case RelayFull:
 		payload, err = msg.MarshalXDR()
 		header.messageType = messageTypeRelayFull
+	case JoinMuxRequest:
+		payload, err = msg.MarshalXDR()
+		header.messageType = messageTypeJoinMuxRequest
+	case MuxJoin:
+		payload, err = msg.MarshalXDR()
+		header.messageType = messageTypeMuxJoin
+	case MuxResponse:
+		payload, err = msg.MarshalXDR()
+		header.messageType = messageTypeMuxResponse
+	case MuxData:
+		payload, err = msg.MarshalXDR()
+		header.messageType = messageTypeMuxData
+	case MuxWindowUpdate:
+		payload, err = msg.MarshalXDR()
+		header.messageType = messageTypeMuxWindowUpdate
+	case MuxClose:
+		payload, err = msg.MarshalXDR()
+		header.messageType = messageTypeMuxClose
+	case JoinPunchRequest:
+		payload, err = msg.MarshalXDR()
+		header.messageType = messageTypeJoinPunchRequest
+	case PunchRequest:
+		payload, err = msg.MarshalXDR()
+		header.messageType = messageTypePunchRequest
+	case PunchInvitation:
+		payload, err = msg.MarshalXDR()
+		header.messageType = messageTypePunchInvitation
+	case PunchResponse:
+		payload, err = msg.MarshalXDR()
+		header.messageType = messageTypePunchResponse
 	default:
 		err = fmt.Errorf("Unknown message type")
 	}
@@ -125,6 +157,46 @@ func ReadMessage(r io.Reader) (interface{}, error) {
 		var msg RelayFull
 		err := msg.UnmarshalXDR(buf)
 		return msg, err
+	case messageTypeJoinMuxRequest:
+		var msg JoinMuxRequest
+		err := msg.UnmarshalXDR(buf)
+		return msg, err
+	case messageTypeMuxJoin:
+		var msg MuxJoin
+		err := msg.UnmarshalXDR(buf)
+		return msg, err
+	case messageTypeMuxResponse:
+		var msg MuxResponse
+		err := msg.UnmarshalXDR(buf)
+		return msg, err
+	case messageTypeMuxData:
+		var msg MuxData
+		err := msg.UnmarshalXDR(buf)
+		return msg, err
+	case messageTypeMuxWindowUpdate:
+		var msg MuxWindowUpdate
+		err := msg.UnmarshalXDR(buf)
+		return msg, err
+	case messageTypeMuxClose:
+		var msg MuxClose
+		err := msg.UnmarshalXDR(buf)
+		return msg, err
+	case messageTypeJoinPunchRequest:
+		var msg JoinPunchRequest
+		err := msg.UnmarshalXDR(buf)
+		return msg, err
+	case messageTypePunchRequest:
+		var msg PunchRequest
+		err := msg.UnmarshalXDR(buf)
+		return msg, err
+	case messageTypePunchInvitation:
+		var msg PunchInvitation
+		err := msg.UnmarshalXDR(buf)
+		return msg, err
+	case messageTypePunchResponse:
+		var msg PunchResponse
+		err := msg.UnmarshalXDR(buf)
+		return msg, err
 	}
 
 	return nil, fmt.Errorf("Unknown message type")
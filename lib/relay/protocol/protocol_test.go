@@ -0,0 +1,45 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package protocol
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPunchMessages(t *testing.T) {
+	id := bytes.Repeat([]byte{1}, 32)
+	addrs := []string{"quic://192.0.2.1:22000", "quic://[2001:db8::1]:22000"}
+
+	msgs := []interface{}{
+		JoinPunchRequest{},
+		PunchRequest{ID: id, Addresses: addrs},
+		PunchInvitation{From: id, Addresses: addrs},
+		PunchResponse{ID: id, Addresses: addrs},
+		ResponseTimeout,
+	}
+	for _, msg := range msgs {
+		var buf bytes.Buffer
+		if err := WriteMessage(&buf, msg); err != nil {
+			t.Fatal(err)
+		}
+		res, err := ReadMessage(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(res, msg) {
+			t.Errorf("got %#v, expected %#v", res, msg)
+		}
+	}
+
+	tooMany := strings.Split(strings.Repeat("quic://192.0.2.1:22000 ", 17), " ")
+	if err := WriteMessage(new(bytes.Buffer), PunchRequest{ID: id, Addresses: tooMany}); err == nil {
+		t.Error("expected error for too many addresses")
+	}
+}
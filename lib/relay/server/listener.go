@@ -1,6 +1,6 @@
 // Copyright (C) 2015 Audrius Butkevicius and Contributors.
 
-package main
+package server
 
 import (
 	"crypto/tls"
@@ -23,23 +23,22 @@ var (
 	numConnections int64
 )
 
-func listener(proto, addr string, config *tls.Config) {
-	tcpListener, err := net.Listen("tcp", addr)
-	if err != nil {
-		log.Fatalln(err)
-	}
-
+func listener(tcpListener net.Listener, config *tls.Config) {
 	listener := tlsutil.DowngradingListener{
 		Listener: tcpListener,
 	}
 
 	for {
 		conn, isTLS, err := listener.AcceptNoWrapTLS()
-		if err != nil {
+		if err == tlsutil.ErrIdentificationFailed {
 			if debug {
 				log.Println("Listener failed to accept connection from", conn.RemoteAddr(), ". Possibly a TCP Ping.")
 			}
+			conn.Close()
 			continue
+		} else if err != nil {
+			// The listener was closed.
+			return
 		}
 
 		setTCPOptions(conn)
@@ -190,6 +189,27 @@ func protocolConnectionHandler(tcpConn net.Conn, config *tls.Config) {
 				}
 				conn.Close()
 
+			case protocol.JoinPunchRequest:
+				if !joined {
+					protocol.WriteMessage(conn, protocol.ResponseUnexpectedMessage)
+					conn.Close()
+					continue
+				}
+				outboxesMut.Lock()
+				punchable[id] = true
+				outboxesMut.Unlock()
+
+			case protocol.PunchRequest:
+				// Like a connect request, a punch request comes on a
+				// connection of its own, which ends with the answer.
+				handlePunchRequest(conn, id, msg)
+				conn.Close()
+
+			case protocol.PunchResponse:
+				if joined {
+					handlePunchResponse(conn, id, msg)
+				}
+
 			case protocol.Ping:
 				if err := protocol.WriteMessage(conn, protocol.Pong{}); err != nil {
 					if debug {
@@ -223,6 +243,7 @@ func protocolConnectionHandler(tcpConn net.Conn, config *tls.Config) {
 				// a lookup request coming from the same client.
 				outboxesMut.Lock()
 				delete(outboxes, id)
+				delete(punchable, id)
 				outboxesMut.Unlock()
 				// Also, kill all sessions related to this node, as it probably
 				// went offline. This is for the other end to realize the client
@@ -298,30 +319,35 @@ func sessionConnectionHandler(conn net.Conn) {
 
 	switch msg := message.(type) {
 	case protocol.JoinSessionRequest:
-		ses := findSession(string(msg.Key))
-		if debug {
-			log.Println(conn.RemoteAddr(), "session lookup", ses, hex.EncodeToString(msg.Key)[:5])
+		res := joinSession(msg.Key, conn)
+		if res.Code != 0 {
+			protocol.WriteMessage(conn, res)
+			conn.Close()
+			return
 		}
 
-		if ses == nil {
-			protocol.WriteMessage(conn, protocol.ResponseNotFound)
-			conn.Close()
+		if err := protocol.WriteMessage(conn, protocol.ResponseSuccess); err != nil {
+			if debug {
+				log.Println("Failed to send session join response to ", conn.RemoteAddr())
+			}
 			return
 		}
 
-		if !ses.AddConnection(conn) {
+		if err := conn.SetDeadline(time.Time{}); err != nil {
 			if debug {
-				log.Println("Failed to add", conn.RemoteAddr(), "to session", ses)
+				log.Println("Weird error setting deadline:", err, "on", conn.RemoteAddr())
 			}
-			protocol.WriteMessage(conn, protocol.ResponseAlreadyConnected)
 			conn.Close()
 			return
 		}
 
+	case protocol.JoinMuxRequest:
+		// Protocol v2 clients carry all their sessions on one connection.
 		if err := protocol.WriteMessage(conn, protocol.ResponseSuccess); err != nil {
 			if debug {
-				log.Println("Failed to send session join response to ", conn.RemoteAddr(), "for", ses)
+				log.Println("Failed to send mux join response to ", conn.RemoteAddr())
 			}
+			conn.Close()
 			return
 		}
 
@@ -333,6 +359,14 @@ func sessionConnectionHandler(conn net.Conn) {
 			return
 		}
 
+		atomic.AddInt64(&numConnections, 1)
+		defer atomic.AddInt64(&numConnections, -1)
+
+		err := protocol.NewMuxServer(conn, joinSession).Serve()
+		if debug {
+			log.Println("Mux connection from", conn.RemoteAddr(), "closed:", err)
+		}
+
 	default:
 		if debug {
 			log.Println("Unexpected message from", conn.RemoteAddr(), message)
@@ -342,6 +376,28 @@ func sessionConnectionHandler(conn net.Conn) {
 	}
 }
 
+// joinSession adds conn to the session with the given key, returning the
+// response to send to the client.
+func joinSession(key []byte, conn net.Conn) protocol.Response {
+	ses := findSession(string(key))
+	if debug {
+		log.Println(conn.RemoteAddr(), "session lookup", ses, hex.EncodeToString(key)[:5])
+	}
+
+	if ses == nil {
+		return protocol.ResponseNotFound
+	}
+
+	if !ses.AddConnection(conn) {
+		if debug {
+			log.Println("Failed to add", conn.RemoteAddr(), "to session", ses)
+		}
+		return protocol.ResponseAlreadyConnected
+	}
+
+	return protocol.ResponseSuccess
+}
+
 func messageReader(conn net.Conn, messages chan<- interface{}, errors chan<- error) {
 	atomic.AddInt64(&numConnections, 1)
 	defer atomic.AddInt64(&numConnections, -1)
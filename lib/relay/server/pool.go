@@ -1,9 +1,10 @@
 // Copyright (C) 2015 Audrius Butkevicius and Contributors.
 
-package main
+package server
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io/ioutil"
 	"log"
@@ -11,7 +12,7 @@ import (
 	"time"
 )
 
-func poolHandler(pool string, uri *url.URL, mapping mapping) {
+func poolHandler(ctx context.Context, pool string, uri *url.URL, mapping mapping) {
 	if debug {
 		log.Println("Joining", pool)
 	}
@@ -39,7 +40,9 @@ func poolHandler(pool string, uri *url.URL, mapping mapping) {
 			resp.Body.Close()
 		} else if resp.StatusCode == 429 {
 			log.Println(pool, "under load, will retry in a minute")
-			time.Sleep(time.Minute)
+			if !sleep(ctx, time.Minute) {
+				return
+			}
 			continue
 		} else if resp.StatusCode == 401 {
 			log.Println(pool, "failed to join due to IP address not matching external address. Aborting")
@@ -52,7 +55,9 @@ func poolHandler(pool string, uri *url.URL, mapping mapping) {
 			if err == nil {
 				rejoin := x.EvictionIn - (x.EvictionIn / 5)
 				log.Println("Joined", pool, "rejoining in", rejoin)
-				time.Sleep(rejoin)
+				if !sleep(ctx, rejoin) {
+					return
+				}
 				continue
 			} else {
 				log.Println("Failed to deserialize response", err)
@@ -60,6 +65,19 @@ func poolHandler(pool string, uri *url.URL, mapping mapping) {
 		} else {
 			log.Println(pool, "unknown response type from server", resp.StatusCode)
 		}
-		time.Sleep(time.Hour)
+		if !sleep(ctx, time.Hour) {
+			return
+		}
+	}
+}
+
+// sleep waits for the given time, returning false if the context is
+// cancelled first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-time.After(d):
+		return true
+	case <-ctx.Done():
+		return false
 	}
 }
@@ -0,0 +1,139 @@
+// Copyright (C) 2020 Audrius Butkevicius and Contributors.
+
+package server
+
+import (
+	"log"
+	"net"
+	"net/url"
+	"time"
+
+	syncthingprotocol "github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/relay/protocol"
+)
+
+// How long the peer has to answer a punch request.
+const punchTimeout = 10 * time.Second
+
+// A punchKey identifies a punch request waiting for the response of the
+// peer it was sent to.
+type punchKey struct {
+	to, from syncthingprotocol.DeviceID
+}
+
+// Both guarded by outboxesMut.
+var (
+	punchable      = make(map[syncthingprotocol.DeviceID]bool)
+	pendingPunches = make(map[punchKey]chan protocol.PunchResponse)
+)
+
+// handlePunchRequest passes the addresses in the request from id on to the
+// requested peer, and answers with the addresses the peer responds with.
+func handlePunchRequest(conn net.Conn, id syncthingprotocol.DeviceID, msg protocol.PunchRequest) {
+	requestedPeer := syncthingprotocol.DeviceIDFromBytes(msg.ID)
+	key := punchKey{to: requestedPeer, from: id}
+	responses := make(chan protocol.PunchResponse, 1)
+
+	outboxesMut.Lock()
+	peerOutbox, ok := outboxes[requestedPeer]
+	canPunch := punchable[requestedPeer]
+	_, pending := pendingPunches[key]
+	if ok && canPunch && !pending {
+		pendingPunches[key] = responses
+	}
+	outboxesMut.Unlock()
+
+	switch {
+	case !ok:
+		if debug {
+			log.Println(id, "wants to punch through to", requestedPeer, "which does not exist")
+		}
+		protocol.WriteMessage(conn, protocol.ResponseNotFound)
+		return
+	case !canPunch:
+		protocol.WriteMessage(conn, protocol.ResponseNotSupported)
+		return
+	case pending:
+		protocol.WriteMessage(conn, protocol.ResponseAlreadyConnected)
+		return
+	}
+
+	defer func() {
+		outboxesMut.Lock()
+		delete(pendingPunches, key)
+		outboxesMut.Unlock()
+	}()
+
+	timeout := time.NewTimer(punchTimeout)
+	defer timeout.Stop()
+
+	invitation := protocol.PunchInvitation{
+		From:      id[:],
+		Addresses: fillInAddresses(msg.Addresses, conn.RemoteAddr()),
+	}
+	select {
+	case peerOutbox <- invitation:
+		if debug {
+			log.Println("Sent punch invitation from", id, "to", requestedPeer)
+		}
+	case <-timeout.C:
+		protocol.WriteMessage(conn, protocol.ResponseTimeout)
+		return
+	}
+
+	select {
+	case res := <-responses:
+		protocol.WriteMessage(conn, protocol.PunchInvitation{
+			From:      requestedPeer[:],
+			Addresses: res.Addresses,
+		})
+	case <-timeout.C:
+		if debug {
+			log.Println(requestedPeer, "did not respond to punch request from", id)
+		}
+		protocol.WriteMessage(conn, protocol.ResponseTimeout)
+	}
+}
+
+// handlePunchResponse passes the response from id on to the request it
+// answers, if still waiting.
+func handlePunchResponse(conn net.Conn, id syncthingprotocol.DeviceID, msg protocol.PunchResponse) {
+	key := punchKey{to: id, from: syncthingprotocol.DeviceIDFromBytes(msg.ID)}
+	outboxesMut.RLock()
+	responses, ok := pendingPunches[key]
+	outboxesMut.RUnlock()
+	if !ok {
+		return
+	}
+	msg.Addresses = fillInAddresses(msg.Addresses, conn.RemoteAddr())
+	select {
+	case responses <- msg:
+	default:
+	}
+}
+
+// fillInAddresses replaces unspecified hosts in the addresses with the IP
+// the device is seen connecting from, like the discovery server does, as
+// that's the address the other side needs behind a NAT.
+func fillInAddresses(addrs []string, remote net.Addr) []string {
+	tcpAddr, ok := remote.(*net.TCPAddr)
+	if !ok {
+		return addrs
+	}
+	res := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		uri, err := url.Parse(addr)
+		if err != nil {
+			continue
+		}
+		host, port, err := net.SplitHostPort(uri.Host)
+		if err != nil {
+			continue
+		}
+		if ip := net.ParseIP(host); host == "" || ip != nil && ip.IsUnspecified() {
+			uri.Host = net.JoinHostPort(tcpAddr.IP.String(), port)
+		}
+		res = append(res, uri.String())
+	}
+	return res
+}
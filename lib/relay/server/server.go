@@ -0,0 +1,327 @@
+// Copyright (C) 2015 Audrius Butkevicius and Contributors.
+
+// Package server implements the relay server, as run by strelaysrv and by
+// "syncthing relay".
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/nat"
+	"github.com/syncthing/syncthing/lib/osutil"
+	syncthingprotocol "github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/relay/protocol"
+)
+
+// DefaultPool is the pool public relays join.
+const DefaultPool = "https://relays.syncthing.net/endpoint"
+
+// Options are the settings of the relay, as given on the strelaysrv
+// command line.
+type Options struct {
+	Listen            string // protocol listen address
+	ExtAddress        string // address to advertise, if not the listen address
+	Protocol          string // "tcp", "tcp4" or "tcp6"
+	NetworkTimeout    time.Duration
+	PingInterval      time.Duration
+	MessageTimeout    time.Duration
+	SessionLimitBps   int
+	GlobalLimitBps    int
+	StatusAddr        string // blank to disable
+	Pools             []string
+	ProvidedBy        string
+	NATEnabled        bool
+	NATLeaseM         int
+	NATRenewalM       int
+	NATTimeoutS       int
+	PprofEnabled      bool
+	NetworkBufferSize int
+	Debug             bool
+}
+
+// DefaultOptions returns the defaults of strelaysrv.
+func DefaultOptions() Options {
+	return Options{
+		Listen:            ":22067",
+		Protocol:          "tcp",
+		NetworkTimeout:    2 * time.Minute,
+		PingInterval:      time.Minute,
+		MessageTimeout:    time.Minute,
+		StatusAddr:        ":22070",
+		Pools:             []string{DefaultPool},
+		NATLeaseM:         60,
+		NATRenewalM:       30,
+		NATTimeoutS:       10,
+		NetworkBufferSize: 2048,
+	}
+}
+
+// The settings in use, set up by Serve. There's only the one relay per
+// process.
+var (
+	running int32
+
+	debug bool
+
+	sessionAddress []byte
+	sessionPort    uint16
+
+	networkTimeout time.Duration
+	pingInterval   time.Duration
+	messageTimeout time.Duration
+
+	limitCheckTimer *time.Timer
+
+	sessionLimitBps   int
+	globalLimitBps    int
+	overLimit         int32
+	descriptorLimit   int64
+	sessionLimiter    *rate.Limiter
+	globalLimiter     *rate.Limiter
+	networkBufferSize int
+
+	pools        []string
+	providedBy   string
+	pprofEnabled bool
+)
+
+// httpClient is the HTTP client we use for outbound requests. It has a
+// timeout and may get further options set during initialization.
+var httpClient = &http.Client{
+	Timeout: 30 * time.Second,
+}
+
+var errRunning = errors.New("relay already running")
+
+// Serve runs the relay with the given certificate until the context is
+// cancelled. Only one relay can run at a time in a process.
+func Serve(ctx context.Context, cert tls.Certificate, opts Options) error {
+	if !atomic.CompareAndSwapInt32(&running, 0, 1) {
+		return errRunning
+	}
+	defer atomic.StoreInt32(&running, 0)
+
+	debug = opts.Debug
+	networkTimeout = opts.NetworkTimeout
+	pingInterval = opts.PingInterval
+	messageTimeout = opts.MessageTimeout
+	sessionLimitBps = opts.SessionLimitBps
+	globalLimitBps = opts.GlobalLimitBps
+	networkBufferSize = opts.NetworkBufferSize
+	pools = opts.Pools
+	providedBy = opts.ProvidedBy
+	pprofEnabled = opts.PprofEnabled
+
+	extAddress := opts.ExtAddress
+	if extAddress == "" {
+		extAddress = opts.Listen
+	}
+
+	if len(providedBy) > 30 {
+		return errors.New("provided-by cannot be longer than 30 characters")
+	}
+
+	addr, err := net.ResolveTCPAddr(opts.Protocol, extAddress)
+	if err != nil {
+		return err
+	}
+
+	laddr, err := net.ResolveTCPAddr(opts.Protocol, opts.Listen)
+	if err != nil {
+		return err
+	}
+
+	if laddr.IP != nil && !laddr.IP.IsUnspecified() {
+		// We bind to a specific address. Our outgoing HTTP requests should
+		// also come from that address.
+		laddr.Port = 0
+		boundDialer := &net.Dialer{LocalAddr: laddr}
+		httpClient.Transport = &http.Transport{
+			DialContext: boundDialer.DialContext,
+		}
+	}
+
+	maxDescriptors, err := osutil.MaximizeOpenFileLimit()
+	if maxDescriptors > 0 {
+		// Assume that 20% of FD's are leaked/unaccounted for.
+		descriptorLimit = int64(maxDescriptors*80) / 100
+		log.Println("Connection limit", descriptorLimit)
+
+		go monitorLimits(ctx)
+	} else if err != nil && runtime.GOOS != "windows" {
+		log.Println("Assuming no connection limit, due to error retrieving rlimits:", err)
+	}
+
+	sessionAddress = addr.IP[:]
+	sessionPort = uint16(addr.Port)
+
+	tlsCfg := &tls.Config{
+		Certificates:           []tls.Certificate{cert},
+		NextProtos:             []string{protocol.ProtocolName},
+		ClientAuth:             tls.RequestClientCert,
+		SessionTicketsDisabled: true,
+		InsecureSkipVerify:     true,
+		MinVersion:             tls.VersionTLS12,
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+		},
+	}
+
+	id := syncthingprotocol.NewDeviceID(cert.Certificate[0])
+	if debug {
+		log.Println("ID:", id)
+	}
+
+	wrapper := config.Wrap("config", config.New(id), events.NoopLogger)
+	wrapper.SetOptions(config.OptionsConfiguration{
+		NATLeaseM:   opts.NATLeaseM,
+		NATRenewalM: opts.NATRenewalM,
+		NATTimeoutS: opts.NATTimeoutS,
+	})
+	natSvc := nat.NewService(id, wrapper)
+	mapping := mapping{natSvc.NewMapping(nat.TCP, addr.IP, addr.Port)}
+
+	if opts.NATEnabled {
+		go natSvc.Serve()
+		defer natSvc.Stop()
+		found := make(chan struct{})
+		mapping.OnChanged(func(_ *nat.Mapping, _, _ []nat.Address) {
+			select {
+			case found <- struct{}{}:
+			default:
+			}
+		})
+
+		// Need to wait a few extra seconds, since NAT library waits exactly natTimeout seconds on all interfaces.
+		timeout := time.Duration(opts.NATTimeoutS+2) * time.Second
+		log.Printf("Waiting %s to acquire NAT mapping", timeout)
+
+		select {
+		case <-found:
+			log.Printf("Found NAT mapping: %s", mapping.ExternalAddresses())
+		case <-time.After(timeout):
+			log.Println("Timeout out waiting for NAT mapping.")
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
+	if sessionLimitBps > 0 {
+		sessionLimiter = rate.NewLimiter(rate.Limit(sessionLimitBps), 2*sessionLimitBps)
+	}
+	if globalLimitBps > 0 {
+		globalLimiter = rate.NewLimiter(rate.Limit(globalLimitBps), 2*globalLimitBps)
+	}
+
+	tcpListener, err := net.Listen(opts.Protocol, opts.Listen)
+	if err != nil {
+		return err
+	}
+	defer tcpListener.Close()
+
+	if opts.StatusAddr != "" {
+		statusListener, err := net.Listen("tcp", opts.StatusAddr)
+		if err != nil {
+			return err
+		}
+		defer statusListener.Close()
+		go statusService(statusListener)
+	}
+
+	uri, err := url.Parse(fmt.Sprintf("relay://%s/?id=%s&pingInterval=%s&networkTimeout=%s&sessionLimitBps=%d&globalLimitBps=%d&statusAddr=%s&providedBy=%s&protocolVersion=3", mapping.Address(), id, pingInterval, networkTimeout, sessionLimitBps, globalLimitBps, opts.StatusAddr, providedBy))
+	if err != nil {
+		return fmt.Errorf("failed to construct URI: %v", err)
+	}
+
+	log.Println("URI:", uri.String())
+
+	for _, pool := range pools {
+		if pool == DefaultPool {
+			log.Println("!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!")
+			log.Println("!!  Joining default relay pools, this relay will be available for public use. !!")
+			log.Println(`!!      Use the -pools="" command line option to make the relay private.      !!`)
+			log.Println("!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!")
+			break
+		}
+	}
+
+	for _, pool := range pools {
+		go poolHandler(ctx, pool, uri, mapping)
+	}
+
+	go listener(tcpListener, tlsCfg)
+
+	<-ctx.Done()
+
+	// Gracefully close all connections, hoping that clients will be faster
+	// to realize that the relay is now gone.
+
+	sessionMut.RLock()
+	for _, session := range activeSessions {
+		session.CloseConns()
+	}
+
+	for _, session := range pendingSessions {
+		session.CloseConns()
+	}
+	sessionMut.RUnlock()
+
+	outboxesMut.Lock()
+	for id, outbox := range outboxes {
+		close(outbox)
+		delete(outboxes, id)
+	}
+	outboxesMut.Unlock()
+
+	time.Sleep(500 * time.Millisecond)
+	return nil
+}
+
+func monitorLimits(ctx context.Context) {
+	limitCheckTimer = time.NewTimer(time.Minute)
+	for {
+		select {
+		case <-limitCheckTimer.C:
+		case <-ctx.Done():
+			return
+		}
+		if atomic.LoadInt64(&numConnections)+atomic.LoadInt64(&numProxies) > descriptorLimit {
+			atomic.StoreInt32(&overLimit, 1)
+			log.Println("Gone past our connection limits. Starting to refuse new/drop idle connections.")
+		} else if atomic.CompareAndSwapInt32(&overLimit, 1, 0) {
+			log.Println("Dropped below our connection limits. Accepting new connections.")
+		}
+		limitCheckTimer.Reset(time.Minute)
+	}
+}
+
+type mapping struct {
+	*nat.Mapping
+}
+
+func (m *mapping) Address() nat.Address {
+	ext := m.ExternalAddresses()
+	if len(ext) > 0 {
+		return ext[0]
+	}
+	return m.Mapping.Address()
+}
@@ -0,0 +1,137 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	syncthingprotocol "github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/relay/client"
+	"github.com/syncthing/syncthing/lib/tlsutil"
+)
+
+func TestServe(t *testing.T) {
+	dir, err := ioutil.TempDir("", "relay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	newCert := func(name string) tls.Certificate {
+		cert, err := tlsutil.NewCertificate(filepath.Join(dir, name+".pem"), filepath.Join(dir, name+"-key.pem"), "syncthing", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cert
+	}
+	relayCert, serverCert, clientCert := newCert("relay"), newCert("server"), newCert("client")
+	serverID := syncthingprotocol.NewDeviceID(serverCert.Certificate[0])
+
+	// Find a free port to listen on.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	opts := DefaultOptions()
+	opts.Listen = addr
+	opts.StatusAddr = ""
+	opts.Pools = nil
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- Serve(ctx, relayCert, opts)
+	}()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	}()
+
+	// Wait for the relay to listen.
+	for i := 0; ; i++ {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+			break
+		}
+		if i == 50 {
+			t.Fatal(err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	uri, _ := url.Parse(fmt.Sprintf("relay://%s/?id=%s&protocolVersion=3", addr, syncthingprotocol.NewDeviceID(relayCert.Certificate[0])))
+
+	// The server side joins the relay and waits.
+	clnt, err := client.NewClient(uri, []tls.Certificate{serverCert}, nil, 10*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go clnt.Serve()
+	defer clnt.Stop()
+
+	// The client side asks for a session, once the other is there.
+	for i := 0; ; i++ {
+		_, err := client.GetInvitationFromRelay(ctx, uri, serverID, []tls.Certificate{clientCert}, 10*time.Second)
+		if err == nil {
+			break
+		}
+		if i == 50 {
+			t.Fatal(err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if err := Serve(ctx, relayCert, opts); err != errRunning {
+		t.Error("expected a second relay to be refused, got", err)
+	}
+
+	select {
+	case inv := <-clnt.Invitations():
+		if !inv.ServerSocket {
+			t.Error("expected the server socket side of the session")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("no invitation for the joined side")
+	}
+
+	// Punch requests pass addresses both ways.
+	go func() {
+		inv := <-clnt.Punches()
+		clnt.ReplyPunch(ctx, inv, []string{"quic://192.0.2.2:22000"})
+	}()
+	addrs, err := client.RequestPunch(ctx, uri, serverID, []tls.Certificate{clientCert}, []string{"quic://0.0.0.0:22000"}, 10*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := []string{"quic://192.0.2.2:22000"}; !reflect.DeepEqual(addrs, exp) {
+		t.Errorf("got addresses %v, expected %v", addrs, exp)
+	}
+}
+
+func TestFillInAddresses(t *testing.T) {
+	remote := &net.TCPAddr{IP: net.ParseIP("192.0.2.42"), Port: 1234}
+	addrs := []string{"quic://0.0.0.0:22000", "quic://:22001", "quic://192.0.2.1:22000", "quic://[::]:22000", "::invalid"}
+	exp := []string{"quic://192.0.2.42:22000", "quic://192.0.2.42:22001", "quic://192.0.2.1:22000", "quic://192.0.2.42:22000"}
+	if res := fillInAddresses(addrs, remote); !reflect.DeepEqual(res, exp) {
+		t.Errorf("got %v, expected %v", res, exp)
+	}
+}
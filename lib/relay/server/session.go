@@ -1,6 +1,6 @@
 // Copyright (C) 2015 Audrius Butkevicius and Contributors.
 
-package main
+package server
 
 import (
 	"crypto/rand"
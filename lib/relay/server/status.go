@@ -1,10 +1,11 @@
 // Copyright (C) 2015 Audrius Butkevicius and Contributors.
 
-package main
+package server
 
 import (
 	"encoding/json"
 	"log"
+	"net"
 	"net/http"
 	"net/http/pprof"
 	"runtime"
@@ -16,7 +17,7 @@ import (
 
 var rc *rateCalculator
 
-func statusService(addr string) {
+func statusService(listener net.Listener) {
 	rc = newRateCalculator(360, 10*time.Second, &bytesProxied)
 
 	handler := http.NewServeMux()
@@ -26,13 +27,12 @@ func statusService(addr string) {
 	}
 
 	srv := http.Server{
-		Addr:        addr,
 		Handler:     handler,
 		ReadTimeout: 15 * time.Second,
 	}
 	srv.SetKeepAlivesEnabled(false)
-	if err := srv.ListenAndServe(); err != nil {
-		log.Fatal(err)
+	if err := srv.Serve(listener); err != nil && debug {
+		log.Println("Status service:", err)
 	}
 }
 
@@ -17,6 +17,12 @@ import (
 
 // HashFile hashes the files and returns a list of blocks representing the file.
 func HashFile(ctx context.Context, fs fs.Filesystem, path string, blockSize int, counter Counter, useWeakHashes bool) ([]protocol.BlockInfo, error) {
+	return HashFileScheme(ctx, fs, path, protocol.BlockSchemeFixed, protocol.HashAlgorithmSHA256, blockSize, counter, useWeakHashes)
+}
+
+// HashFileScheme is like HashFile, but divides the file into blocks using
+// the given scheme and hashes them with the given algorithm.
+func HashFileScheme(ctx context.Context, fs fs.Filesystem, path string, scheme protocol.BlockScheme, algo protocol.HashAlgorithm, blockSize int, counter Counter, useWeakHashes bool) ([]protocol.BlockInfo, error) {
 	fd, err := fs.Open(path)
 	if err != nil {
 		l.Debugln("open:", err)
@@ -36,7 +42,11 @@ func HashFile(ctx context.Context, fs fs.Filesystem, path string, blockSize int,
 
 	// Hash the file. This may take a while for large files.
 
-	blocks, err := Blocks(ctx, fd, blockSize, size, counter, useWeakHashes)
+	blocksFn := hashBlocks
+	if scheme == protocol.BlockSchemeFastCDC {
+		blocksFn = hashChunks
+	}
+	blocks, err := blocksFn(ctx, fd, algo, blockSize, size, counter, useWeakHashes)
 	if err != nil {
 		l.Debugln("blocks:", err)
 		return nil, err
@@ -68,10 +78,14 @@ type parallelHasher struct {
 	inbox   <-chan protocol.FileInfo
 	counter Counter
 	done    chan<- struct{}
+	cache   HashCache
+	limiter *DiskLimiter
+	disk    string
+	status  *hashStatus
 	wg      sync.WaitGroup
 }
 
-func newParallelHasher(ctx context.Context, fs fs.Filesystem, workers int, outbox chan<- ScanResult, inbox <-chan protocol.FileInfo, counter Counter, done chan<- struct{}) {
+func newParallelHasher(ctx context.Context, fs fs.Filesystem, workers int, outbox chan<- ScanResult, inbox <-chan protocol.FileInfo, counter Counter, done chan<- struct{}, cache HashCache, limiter *DiskLimiter, disk string, status *hashStatus) {
 	ph := &parallelHasher{
 		fs:      fs,
 		workers: workers,
@@ -79,6 +93,10 @@ func newParallelHasher(ctx context.Context, fs fs.Filesystem, workers int, outbo
 		inbox:   inbox,
 		counter: counter,
 		done:    done,
+		cache:   cache,
+		limiter: limiter,
+		disk:    disk,
+		status:  status,
 		wg:      sync.NewWaitGroup(),
 	}
 
@@ -104,13 +122,16 @@ func (ph *parallelHasher) hashFiles(ctx context.Context) {
 				panic("Bug. Asked to hash a directory or a deleted file.")
 			}
 
-			blocks, err := HashFile(ctx, ph.fs, f.Name, f.BlockSize(), ph.counter, true)
+			ph.status.hashing(f.Name)
+			blocks, err := ph.hashFile(ctx, f)
 			if err != nil {
 				l.Debugln("hash error:", f.Name, err)
 				continue
 			}
+			ph.status.hashed()
 
 			f.Blocks = blocks
+			f.Holes = fileHoles(ph.fs, f.Name)
 
 			// The size we saw when initially deciding to hash the file
 			// might not have been the size it actually had when we hashed
@@ -121,6 +142,13 @@ func (ph *parallelHasher) hashFiles(ctx context.Context) {
 				f.Size += int64(b.Size)
 			}
 
+			// Only cache the result if the file is still the same as
+			// when we decided to hash it.
+			// Not all files are cached, see cachedBlocks.
+			if info, err := ph.fs.Lstat(f.Name); err == nil && cacheable(f) && info.Size() == f.Size && info.ModTime().Equal(f.ModTime()) {
+				ph.cache.Put(info, f.BlockSize(), blocks)
+			}
+
 			select {
 			case ph.outbox <- ScanResult{File: f}:
 			case <-ctx.Done():
@@ -133,6 +161,18 @@ func (ph *parallelHasher) hashFiles(ctx context.Context) {
 	}
 }
 
+// hashFile hashes the file once the disk limiter, if any, allows.
+func (ph *parallelHasher) hashFile(ctx context.Context, f protocol.FileInfo) ([]protocol.BlockInfo, error) {
+	if ph.limiter != nil {
+		release, err := ph.limiter.acquire(ctx, ph.disk)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+	return HashFileScheme(ctx, ph.fs, f.Name, f.BlockScheme, f.HashAlgorithm, f.BlockSize(), ph.counter, true)
+}
+
 func (ph *parallelHasher) closeWhenDone() {
 	ph.wg.Wait()
 	if ph.done != nil {
@@ -14,7 +14,6 @@ import (
 	"io"
 
 	"github.com/syncthing/syncthing/lib/protocol"
-	"github.com/syncthing/syncthing/lib/sha256"
 )
 
 var SHA256OfNothing = []uint8{0xe3, 0xb0, 0xc4, 0x42, 0x98, 0xfc, 0x1c, 0x14, 0x9a, 0xfb, 0xf4, 0xc8, 0x99, 0x6f, 0xb9, 0x24, 0x27, 0xae, 0x41, 0xe4, 0x64, 0x9b, 0x93, 0x4c, 0xa4, 0x95, 0x99, 0x1b, 0x78, 0x52, 0xb8, 0x55}
@@ -25,11 +24,15 @@ type Counter interface {
 
 // Blocks returns the blockwise hash of the reader.
 func Blocks(ctx context.Context, r io.Reader, blocksize int, sizehint int64, counter Counter, useWeakHashes bool) ([]protocol.BlockInfo, error) {
+	return hashBlocks(ctx, r, protocol.HashAlgorithmSHA256, blocksize, sizehint, counter, useWeakHashes)
+}
+
+func hashBlocks(ctx context.Context, r io.Reader, algo protocol.HashAlgorithm, blocksize int, sizehint int64, counter Counter, useWeakHashes bool) ([]protocol.BlockInfo, error) {
 	if counter == nil {
 		counter = &noopCounter{}
 	}
 
-	hf := sha256.New()
+	hf := algo.NewHash()
 	hashLength := hf.Size()
 
 	var weakHf hash.Hash32 = noopHash{}
@@ -101,14 +104,23 @@ func Blocks(ctx context.Context, r io.Reader, blocksize int, sizehint int64, cou
 		blocks = append(blocks, protocol.BlockInfo{
 			Offset: 0,
 			Size:   0,
-			Hash:   SHA256OfNothing,
+			Hash:   hashOfNothing(algo),
 		})
 	}
 
 	return blocks, nil
 }
 
-func Validate(buf, hash []byte, weakHash uint32) bool {
+func hashOfNothing(algo protocol.HashAlgorithm) []byte {
+	if algo == protocol.HashAlgorithmSHA256 {
+		return SHA256OfNothing
+	}
+	return algo.NewHash().Sum(nil)
+}
+
+// Validate returns whether the data matches the weak hash or, failing
+// that, the hash of the given algorithm.
+func Validate(buf, hash []byte, weakHash uint32, algo protocol.HashAlgorithm) bool {
 	rd := bytes.NewReader(buf)
 	if weakHash != 0 {
 		whf := adler32.New()
@@ -120,7 +132,7 @@ func Validate(buf, hash []byte, weakHash uint32) bool {
 	}
 
 	if len(hash) > 0 {
-		hf := sha256.New()
+		hf := algo.NewHash()
 		if _, err := io.Copy(hf, rd); err == nil {
 			// Sum allocates, so let's hope we don't hit this often.
 			return bytes.Equal(hf.Sum(nil), hash)
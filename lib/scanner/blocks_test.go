@@ -104,6 +104,31 @@ func TestBlocks(t *testing.T) {
 	}
 }
 
+func TestBlocksBLAKE3(t *testing.T) {
+	hashes := map[string]string{
+		"":    "af1349b9f5f9a1a6a0404dea36dcc9499bcb25c9adc112b7cc9a93cae41f3262",
+		"abc": "6437b3ac38465133ffb63b75273a8db548c558465d79db03fd359c6cd5bd9d85",
+	}
+	for data, hash := range hashes {
+		blocks, err := hashBlocks(context.TODO(), bytes.NewBufferString(data), protocol.HashAlgorithmBLAKE3, 1024, -1, nil, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(blocks) != 1 {
+			t.Fatalf("%q: expected one block, got %d", data, len(blocks))
+		}
+		if h := fmt.Sprintf("%x", blocks[0].Hash); h != hash {
+			t.Errorf("%q: incorrect block hash %q != %q", data, h, hash)
+		}
+		if !Validate([]byte(data), blocks[0].Hash, 0, protocol.HashAlgorithmBLAKE3) {
+			t.Errorf("%q: block doesn't validate", data)
+		}
+		if Validate([]byte(data), blocks[0].Hash, 0, protocol.HashAlgorithmSHA256) {
+			t.Errorf("%q: block validates as SHA-256", data)
+		}
+	}
+}
+
 func TestAdler32Variants(t *testing.T) {
 	// Verify that the two adler32 functions give matching results for a few
 	// different blocks of data.
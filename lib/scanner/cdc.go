@@ -0,0 +1,178 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package scanner
+
+import (
+	"context"
+	"hash"
+	"hash/adler32"
+	"io"
+	"math/bits"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// The gear table of the rolling hash used to find chunk boundaries. It
+// must be the same everywhere for devices to find the same boundaries, so
+// it is generated from a fixed seed.
+var gear [256]uint64
+
+func init() {
+	// splitmix64
+	x := uint64(0x5374436863646331) // "StChcdc1"
+	for i := range gear {
+		x += 0x9e3779b97f4a7c15
+		z := x
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		gear[i] = z ^ (z >> 31)
+	}
+}
+
+// A chunker finds content defined chunk boundaries, as described in
+// "FastCDC: a Fast and Efficient Content-Defined Chunking Approach for Data
+// Deduplication" (Xia et al., 2016). Chunks are between a quarter of the
+// block size and the block size, and half the block size on average. Cut
+// points are harder to find before the average size and easier after it,
+// which narrows the spread of chunk sizes.
+type chunker struct {
+	min, avg, max int
+	maskS, maskL  uint64
+}
+
+func newChunker(blockSize int) chunker {
+	avg := blockSize / 2
+	n := bits.Len(uint(avg)) - 1 // log2 of the average, a power of two
+	return chunker{
+		min:   blockSize / 4,
+		avg:   avg,
+		max:   blockSize,
+		maskS: topBits(n + 2),
+		maskL: topBits(n - 2),
+	}
+}
+
+// topBits returns a mask of the n highest bits, which depend on the last 64
+// bytes seen by the gear hash.
+func topBits(n int) uint64 {
+	return ^uint64(0) << uint(64-n)
+}
+
+// cut returns the length of the first chunk of buf. It is all of buf if
+// buf is no longer than the minimum chunk size or no boundary is found.
+func (c chunker) cut(buf []byte) int {
+	if len(buf) <= c.min {
+		return len(buf)
+	}
+	end := len(buf)
+	if end > c.max {
+		end = c.max
+	}
+	avg := c.avg
+	if avg > end {
+		avg = end
+	}
+
+	var fp uint64
+	i := c.min
+	for ; i < avg; i++ {
+		fp = (fp << 1) + gear[buf[i]]
+		if fp&c.maskS == 0 {
+			return i + 1
+		}
+	}
+	for ; i < end; i++ {
+		fp = (fp << 1) + gear[buf[i]]
+		if fp&c.maskL == 0 {
+			return i + 1
+		}
+	}
+	return end
+}
+
+// Chunks returns the hashes of the content defined chunks of the reader,
+// none larger than the block size. It is otherwise like Blocks.
+func Chunks(ctx context.Context, r io.Reader, blocksize int, sizehint int64, counter Counter, useWeakHashes bool) ([]protocol.BlockInfo, error) {
+	return hashChunks(ctx, r, protocol.HashAlgorithmSHA256, blocksize, sizehint, counter, useWeakHashes)
+}
+
+func hashChunks(ctx context.Context, r io.Reader, algo protocol.HashAlgorithm, blocksize int, sizehint int64, counter Counter, useWeakHashes bool) ([]protocol.BlockInfo, error) {
+	if counter == nil {
+		counter = &noopCounter{}
+	}
+	if sizehint >= 0 {
+		r = io.LimitReader(r, sizehint)
+	}
+
+	hf := algo.NewHash()
+	var weakHf hash.Hash32 = noopHash{}
+	if useWeakHashes {
+		weakHf = adler32.New()
+	}
+
+	c := newChunker(blocksize)
+	buf := protocol.BufferPool.Get(blocksize)
+	defer func() {
+		protocol.BufferPool.Put(buf)
+	}()
+
+	var blocks []protocol.BlockInfo
+	var offset int64
+	filled := 0
+	eof := false
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		if !eof && filled < len(buf) {
+			n, err := io.ReadFull(r, buf[filled:])
+			filled += n
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				eof = true
+			} else if err != nil {
+				return nil, err
+			}
+		}
+		if filled == 0 {
+			break
+		}
+
+		// Short of the end of the file the buffer is full, so that the
+		// boundary can be anywhere up to the block size.
+		n := c.cut(buf[:filled])
+		chunk := buf[:n]
+		hf.Write(chunk)
+		weakHf.Write(chunk)
+		counter.Update(int64(n))
+
+		blocks = append(blocks, protocol.BlockInfo{
+			Size:     int32(n),
+			Offset:   offset,
+			Hash:     hf.Sum(nil),
+			WeakHash: weakHf.Sum32(),
+		})
+		offset += int64(n)
+		hf.Reset()
+		weakHf.Reset()
+
+		filled = copy(buf, buf[n:filled])
+	}
+
+	if len(blocks) == 0 {
+		// Empty file
+		blocks = append(blocks, protocol.BlockInfo{
+			Offset: 0,
+			Size:   0,
+			Hash:   hashOfNothing(algo),
+		})
+	}
+
+	return blocks, nil
+}
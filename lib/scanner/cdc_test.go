@@ -0,0 +1,76 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+func TestChunksInsertion(t *testing.T) {
+	const blockSize = protocol.MinBlockSize
+
+	data := make([]byte, 64*blockSize)
+	rand.New(rand.NewSource(42)).Read(data)
+
+	chunks := func(data []byte) []protocol.BlockInfo {
+		t.Helper()
+		blocks, err := Chunks(context.Background(), bytes.NewReader(data), blockSize, int64(len(data)), nil, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var offset int64
+		for i, b := range blocks {
+			if b.Offset != offset {
+				t.Fatalf("block %d at offset %d, expected %d", i, b.Offset, offset)
+			}
+			if b.Size > blockSize || (b.Size < blockSize/4 && i < len(blocks)-1) {
+				t.Fatalf("block %d of unexpected size %d", i, b.Size)
+			}
+			if !Validate(data[b.Offset:b.Offset+int64(b.Size)], b.Hash, b.WeakHash, protocol.HashAlgorithmSHA256) {
+				t.Fatalf("block %d doesn't validate", i)
+			}
+			offset += int64(b.Size)
+		}
+		if offset != int64(len(data)) {
+			t.Fatalf("blocks cover %d bytes, expected %d", offset, len(data))
+		}
+		return blocks
+	}
+
+	orig := chunks(data)
+	if len(orig) < 64 || len(orig) > 4*64 {
+		t.Errorf("unexpected number of blocks %d, expected about 128", len(orig))
+	}
+
+	// Insert a few bytes in the middle; only the blocks around them should
+	// change.
+	mid := len(data) / 2
+	changed := append(append(append([]byte{}, data[:mid]...), "inserted"...), data[mid:]...)
+	hashes := make(map[string]bool)
+	for _, b := range orig {
+		hashes[string(b.Hash)] = true
+	}
+	differ := 0
+	for _, b := range chunks(changed) {
+		if !hashes[string(b.Hash)] {
+			differ++
+		}
+	}
+	if differ == 0 || differ > 2 {
+		t.Errorf("expected one or two blocks to change, got %d", differ)
+	}
+
+	// Empty files have the one empty block.
+	if blocks := chunks(nil); len(blocks) != 1 || blocks[0].Size != 0 {
+		t.Errorf("unexpected blocks for an empty file: %v", blocks)
+	}
+}
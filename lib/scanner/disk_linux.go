@@ -0,0 +1,44 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package scanner
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// DetectDisk returns the disk the path is on, according to sysfs.
+// Partitions of a disk are the same disk.
+func DetectDisk(path string) Disk {
+	var st syscall.Stat_t
+	if err := syscall.Stat(path, &st); err != nil {
+		l.Debugln("detecting disk:", err)
+		return Disk{}
+	}
+	dev := uint64(st.Dev) // not uint64 on all platforms
+	dir, err := filepath.EvalSymlinks(fmt.Sprintf("/sys/dev/block/%d:%d", unix.Major(dev), unix.Minor(dev)))
+	if err != nil {
+		// Not a block device, like tmpfs or a network filesystem.
+		l.Debugln("detecting disk:", err)
+		return Disk{}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "partition")); err == nil {
+		dir = filepath.Dir(dir)
+	}
+
+	disk := Disk{ID: filepath.Base(dir)}
+	if bs, err := ioutil.ReadFile(filepath.Join(dir, "queue", "rotational")); err == nil {
+		disk.Rotational = strings.TrimSpace(string(bs)) == "1"
+	}
+	return disk
+}
@@ -0,0 +1,122 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package scanner
+
+import (
+	"context"
+
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+// A Disk is the physical disk a folder is on, as far as can be told.
+type Disk struct {
+	ID         string // empty if unknown
+	Rotational bool   // a spinning disk, where parallel reads are slow
+}
+
+// A DiskLimiter limits how many files are hashed at the same time on each
+// disk, across all walkers hashing files on it.
+type DiskLimiter struct {
+	mut   sync.Mutex
+	disks map[string]*diskSemaphore
+}
+
+func NewDiskLimiter() *DiskLimiter {
+	return &DiskLimiter{
+		mut:   sync.NewMutex(),
+		disks: make(map[string]*diskSemaphore),
+	}
+}
+
+// SetLimit sets how many files may be hashed at the same time on the disk.
+// Zero means no limit, as for unknown disks, whose ID is empty. Files
+// already being hashed are not interrupted.
+func (d *DiskLimiter) SetLimit(disk string, limit int) {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	if limit <= 0 || disk == "" {
+		delete(d.disks, disk)
+		return
+	}
+	if s, ok := d.disks[disk]; ok {
+		s.setCapacity(limit)
+		return
+	}
+	d.disks[disk] = newDiskSemaphore(limit)
+}
+
+// acquire waits until a file may be hashed on the disk and returns the
+// function to call when done hashing it.
+func (d *DiskLimiter) acquire(ctx context.Context, disk string) (func(), error) {
+	d.mut.Lock()
+	s, ok := d.disks[disk]
+	d.mut.Unlock()
+	if !ok {
+		return func() {}, nil
+	}
+	if err := s.take(ctx); err != nil {
+		return nil, err
+	}
+	return s.give, nil
+}
+
+type diskSemaphore struct {
+	mut       sync.Mutex
+	max       int
+	available int
+	freed     chan struct{} // closed and replaced when capacity is freed
+}
+
+func newDiskSemaphore(max int) *diskSemaphore {
+	return &diskSemaphore{
+		mut:       sync.NewMutex(),
+		max:       max,
+		available: max,
+		freed:     make(chan struct{}),
+	}
+}
+
+func (s *diskSemaphore) take(ctx context.Context) error {
+	for {
+		s.mut.Lock()
+		if s.available > 0 {
+			s.available--
+			s.mut.Unlock()
+			return nil
+		}
+		freed := s.freed
+		s.mut.Unlock()
+
+		select {
+		case <-freed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *diskSemaphore) give() {
+	s.mut.Lock()
+	if s.available < s.max {
+		s.available++
+	}
+	s.notifyLocked()
+	s.mut.Unlock()
+}
+
+func (s *diskSemaphore) setCapacity(max int) {
+	s.mut.Lock()
+	s.available += max - s.max
+	s.max = max
+	s.notifyLocked()
+	s.mut.Unlock()
+}
+
+func (s *diskSemaphore) notifyLocked() {
+	close(s.freed)
+	s.freed = make(chan struct{})
+}
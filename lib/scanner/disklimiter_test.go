@@ -0,0 +1,65 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package scanner
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDiskLimiter(t *testing.T) {
+	d := NewDiskLimiter()
+	ctx := context.Background()
+
+	// Unlimited disks never block.
+	for i := 0; i < 3; i++ {
+		if _, err := d.acquire(ctx, "nvme0n1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d.SetLimit("sda", 1)
+	release, err := d.acquire(ctx, "sda")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The second file waits for the first.
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := d.acquire(timeout, "sda"); err != context.DeadlineExceeded {
+		t.Fatal("expected to time out waiting, got", err)
+	}
+
+	acquired := make(chan struct{})
+	go func() {
+		if _, err := d.acquire(ctx, "sda"); err == nil {
+			close(acquired)
+		}
+	}()
+	release()
+	select {
+	case <-acquired:
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected to acquire after release")
+	}
+
+	// Raising the limit lets waiting files go ahead.
+	acquired = make(chan struct{})
+	go func() {
+		if _, err := d.acquire(ctx, "sda"); err == nil {
+			close(acquired)
+		}
+	}()
+	d.SetLimit("sda", 2)
+	select {
+	case <-acquired:
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected to acquire after raising the limit")
+	}
+}
@@ -0,0 +1,301 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package scanner
+
+import (
+	"bytes"
+	"crypto/rand"
+	cryptoSha256 "crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"os"
+	"strings"
+	"time"
+
+	minioSha256 "github.com/minio/sha256-simd"
+	"github.com/syncthing/syncthing/lib/blake3"
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+const (
+	hashBenchmarkIterations = 3
+	hashBenchmarkDuration   = 150 * time.Millisecond
+	hashBenchmarkKey        = "hashBackends"
+)
+
+// A HashBackend implements a hash algorithm, using the Go standard library,
+// particular CPU extensions or an external accelerator. Backends are
+// registered with RegisterHashBackend and the fastest available one for
+// each algorithm is used once SelectHashBackends has been called.
+type HashBackend interface {
+	// Name identifies the backend, in logs, usage reports and the
+	// STHASHING environment variable.
+	Name() string
+	Algorithm() protocol.HashAlgorithm
+	// Available returns whether the backend can be used on this system,
+	// for example whether the CPU has the extensions it needs.
+	Available() bool
+	New() hash.Hash
+}
+
+// HashBackendResult is the measured single threaded throughput of a
+// backend, in MB/s.
+type HashBackendResult struct {
+	Name      string  `json:"name"`
+	Algorithm string  `json:"algorithm"`
+	Rate      float64 `json:"rate"`
+	Selected  bool    `json:"selected"`
+}
+
+// A HashBenchmarkStore keeps the results of benchmarking the backends
+// between runs, such as a db.NamespacedKV.
+type HashBenchmarkStore interface {
+	Bytes(key string) ([]byte, bool, error)
+	PutBytes(key string, val []byte) error
+}
+
+type funcHashBackend struct {
+	name  string
+	algo  protocol.HashAlgorithm
+	newFn func() hash.Hash
+}
+
+// NewHashBackend returns an always available backend for the algorithm,
+// which uses the given hash constructor.
+func NewHashBackend(name string, algo protocol.HashAlgorithm, newFn func() hash.Hash) HashBackend {
+	return &funcHashBackend{name: name, algo: algo, newFn: newFn}
+}
+
+func (b *funcHashBackend) Name() string                      { return b.name }
+func (b *funcHashBackend) Algorithm() protocol.HashAlgorithm { return b.algo }
+func (b *funcHashBackend) Available() bool                   { return true }
+func (b *funcHashBackend) New() hash.Hash                    { return b.newFn() }
+
+var (
+	hashBackendsMut = sync.NewMutex()
+	hashBackends    []HashBackend // the first of each algorithm is the reference for the others
+	hashResults     []HashBackendResult
+)
+
+func init() {
+	RegisterHashBackend(NewHashBackend("crypto/sha256", protocol.HashAlgorithmSHA256, cryptoSha256.New))
+	RegisterHashBackend(NewHashBackend("minio/sha256-simd", protocol.HashAlgorithmSHA256, minioSha256.New))
+	RegisterHashBackend(NewHashBackend("lib/blake3", protocol.HashAlgorithmBLAKE3, blake3.New))
+}
+
+// RegisterHashBackend makes the backend available for selection. It must
+// be called before SelectHashBackends, typically from an init function.
+func RegisterHashBackend(b HashBackend) {
+	hashBackendsMut.Lock()
+	defer hashBackendsMut.Unlock()
+	for _, other := range hashBackends {
+		if other.Name() == b.Name() {
+			panic("bug: hash backend " + b.Name() + " registered twice")
+		}
+	}
+	hashBackends = append(hashBackends, b)
+}
+
+// HashBackends returns the registered backends.
+func HashBackends() []HashBackend {
+	hashBackendsMut.Lock()
+	defer hashBackendsMut.Unlock()
+	return append([]HashBackend(nil), hashBackends...)
+}
+
+// SelectHashBackends benchmarks the available backends that compute
+// correct hashes and switches to the fastest one for each algorithm. The
+// results are kept in the store, if given, and reused by later calls as
+// long as the same backends are available, so that the benchmark only
+// slows down the first run.
+//
+// The STHASHING environment variable may name a backend to use for its
+// algorithm without considering the others. For compatibility "standard"
+// and "minio" are the SHA-256 implementations of the Go standard library
+// and minio/sha256-simd.
+func SelectHashBackends(store HashBenchmarkStore) []HashBackendResult {
+	hashBackendsMut.Lock()
+	defer hashBackendsMut.Unlock()
+
+	forced := forcedHashBackend(os.Getenv("STHASHING"))
+	var candidates []HashBackend
+	for _, b := range hashBackends {
+		if forced != nil && b.Algorithm() == forced.Algorithm() && b.Name() != forced.Name() {
+			// Don't touch the others at all, as they may be set aside
+			// for being incompatible with the system.
+			continue
+		}
+		if !b.Available() {
+			continue
+		}
+		if !verifyHashBackend(b) {
+			l.Warnf("Not using hashing backend %s, which computes incorrect hashes", b.Name())
+			continue
+		}
+		candidates = append(candidates, b)
+	}
+
+	results := loadHashResults(store, candidates)
+	if results == nil {
+		results = benchmarkHashBackends(candidates)
+		if store != nil {
+			bs, _ := json.Marshal(results)
+			if err := store.PutBytes(hashBenchmarkKey, bs); err != nil {
+				l.Warnln("Storing hash benchmark results:", err)
+			}
+		}
+	}
+
+	fastest := make(map[protocol.HashAlgorithm]int)
+	for i, b := range candidates {
+		if j, ok := fastest[b.Algorithm()]; !ok || results[i].Rate > results[j].Rate {
+			fastest[b.Algorithm()] = i
+		}
+	}
+	for algo, i := range fastest {
+		results[i].Selected = true
+		protocol.SetHashImplementation(algo, candidates[i].New)
+
+		var others []string
+		for j, res := range results {
+			if j != i && candidates[j].Algorithm() == algo {
+				others = append(others, fmt.Sprintf("%s using %s", formatRate(res.Rate), res.Name))
+			}
+		}
+		msg := fmt.Sprintf("Single thread %s performance is %s using %s", hashAlgorithmName(algo), formatRate(results[i].Rate), results[i].Name)
+		if len(others) > 0 {
+			msg += " (" + strings.Join(others, ", ") + ")"
+		}
+		l.Infoln(msg + ".")
+	}
+
+	hashResults = results
+	return append([]HashBackendResult(nil), results...)
+}
+
+// HashBackendResults returns the results of the last SelectHashBackends.
+func HashBackendResults() []HashBackendResult {
+	hashBackendsMut.Lock()
+	defer hashBackendsMut.Unlock()
+	return append([]HashBackendResult(nil), hashResults...)
+}
+
+func forcedHashBackend(name string) HashBackend {
+	switch name {
+	case "":
+		return nil
+	case "minio":
+		name = "minio/sha256-simd"
+	case "standard":
+		name = "crypto/sha256"
+	}
+	for _, b := range hashBackends {
+		if b.Name() == name {
+			return b
+		}
+	}
+	// As before there were backends, anything else means the standard
+	// library.
+	return forcedHashBackend("standard")
+}
+
+// verifyHashBackend returns whether the backend computes the same hashes
+// as the first one registered for its algorithm, which is trusted.
+func verifyHashBackend(b HashBackend) bool {
+	var reference HashBackend
+	for _, other := range hashBackends {
+		if other.Algorithm() == b.Algorithm() {
+			reference = other
+			break
+		}
+	}
+	if reference.Name() == b.Name() {
+		return true
+	}
+
+	// Long enough to span a few internal blocks of any algorithm.
+	input := make([]byte, 10<<10)
+	for i := range input {
+		input[i] = byte(i % 251)
+	}
+	expected := reference.New()
+	expected.Write(input)
+	actual := b.New()
+	actual.Write(input)
+	return bytes.Equal(actual.Sum(nil), expected.Sum(nil))
+}
+
+// loadHashResults returns the stored results, if they are of the same
+// backends.
+func loadHashResults(store HashBenchmarkStore, backends []HashBackend) []HashBackendResult {
+	if store == nil {
+		return nil
+	}
+	bs, ok, _ := store.Bytes(hashBenchmarkKey)
+	if !ok {
+		return nil
+	}
+	var results []HashBackendResult
+	if err := json.Unmarshal(bs, &results); err != nil || len(results) != len(backends) {
+		return nil
+	}
+	for i, b := range backends {
+		if results[i].Name != b.Name() {
+			return nil
+		}
+		results[i].Selected = false
+	}
+	return results
+}
+
+func benchmarkHashBackends(backends []HashBackend) []HashBackendResult {
+	buf := make([]byte, 100<<10)
+	rand.Read(buf)
+
+	results := make([]HashBackendResult, len(backends))
+	for i, b := range backends {
+		results[i] = HashBackendResult{Name: b.Name(), Algorithm: hashAlgorithmName(b.Algorithm())}
+	}
+	// Interleave the measurements to achieve some sort of fairness if the
+	// CPU is just in the process of spinning up to full speed.
+	for iter := 0; iter < hashBenchmarkIterations; iter++ {
+		for i, b := range backends {
+			if rate := hashRate(b.New(), buf); rate > results[i].Rate {
+				results[i].Rate = rate
+			}
+		}
+	}
+	return results
+}
+
+func hashRate(h hash.Hash, buf []byte) float64 {
+	t0 := time.Now()
+	n := 0
+	for time.Since(t0) < hashBenchmarkDuration {
+		h.Write(buf)
+		n += len(buf)
+	}
+	h.Sum(nil)
+	d := time.Since(t0)
+	return float64(int(float64(n)/d.Seconds()/(1<<20)*100)) / 100
+}
+
+func hashAlgorithmName(algo protocol.HashAlgorithm) string {
+	return strings.TrimPrefix(algo.String(), "HASH_ALGORITHM_")
+}
+
+func formatRate(rate float64) string {
+	decimals := 0
+	if rate < 1 {
+		decimals = 2
+	} else if rate < 10 {
+		decimals = 1
+	}
+	return fmt.Sprintf("%.*f MB/s", decimals, rate)
+}
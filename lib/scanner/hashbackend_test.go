@@ -0,0 +1,117 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package scanner
+
+import (
+	"crypto/sha1"
+	cryptoSha256 "crypto/sha256"
+	"encoding/json"
+	"hash"
+	"os"
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+type memoryHashStore map[string][]byte
+
+func (s memoryHashStore) Bytes(key string) ([]byte, bool, error) {
+	bs, ok := s[key]
+	return bs, ok, nil
+}
+
+func (s memoryHashStore) PutBytes(key string, val []byte) error {
+	s[key] = val
+	return nil
+}
+
+// withTestHashBackends replaces the registered backends, returning a
+// function that restores them.
+func withTestHashBackends(backends ...HashBackend) func() {
+	oldBackends, oldResults := hashBackends, hashResults
+	oldEnv, hadEnv := os.LookupEnv("STHASHING")
+	hashBackends = backends
+	os.Unsetenv("STHASHING")
+	return func() {
+		hashBackends, hashResults = oldBackends, oldResults
+		if hadEnv {
+			os.Setenv("STHASHING", oldEnv)
+		} else {
+			os.Unsetenv("STHASHING")
+		}
+		protocol.SetHashImplementation(protocol.HashAlgorithmSHA256, cryptoSha256.New)
+	}
+}
+
+func TestSelectHashBackends(t *testing.T) {
+	fastCreated := 0
+	fast := NewHashBackend("fast", protocol.HashAlgorithmSHA256, func() hash.Hash {
+		fastCreated++
+		return cryptoSha256.New()
+	})
+	broken := NewHashBackend("broken", protocol.HashAlgorithmSHA256, sha1.New)
+	defer withTestHashBackends(NewHashBackend("crypto/sha256", protocol.HashAlgorithmSHA256, cryptoSha256.New), broken, fast)()
+
+	// The broken backend isn't benchmarked, and the stored results of the
+	// others are used instead of benchmarking them again.
+	stored := []HashBackendResult{
+		{Name: "crypto/sha256", Algorithm: "SHA256", Rate: 100},
+		{Name: "fast", Algorithm: "SHA256", Rate: 1000},
+	}
+	bs, _ := json.Marshal(stored)
+	store := memoryHashStore{hashBenchmarkKey: bs}
+
+	res := SelectHashBackends(store)
+	if len(res) != 2 || res[0].Name != "crypto/sha256" || res[1].Name != "fast" {
+		t.Fatalf("unexpected results %+v", res)
+	}
+	if res[0].Selected || !res[1].Selected || res[1].Rate != 1000 {
+		t.Errorf("the stored faster backend should be selected, got %+v", res)
+	}
+	before := fastCreated
+	protocol.HashAlgorithmSHA256.NewHash()
+	if fastCreated != before+1 {
+		t.Error("the selected backend should be in use")
+	}
+
+	// Forcing a backend skips the others of its algorithm, so the stored
+	// results don't apply and are replaced.
+	os.Setenv("STHASHING", "standard")
+	res = SelectHashBackends(store)
+	if len(res) != 1 || res[0].Name != "crypto/sha256" || !res[0].Selected {
+		t.Fatalf("unexpected results %+v", res)
+	}
+	var newStored []HashBackendResult
+	if err := json.Unmarshal(store[hashBenchmarkKey], &newStored); err != nil {
+		t.Fatal(err)
+	}
+	if len(newStored) != 1 || newStored[0].Name != "crypto/sha256" || newStored[0].Rate <= 0 {
+		t.Errorf("unexpected stored results %+v", newStored)
+	}
+}
+
+func TestForcedHashBackend(t *testing.T) {
+	defer withTestHashBackends(
+		NewHashBackend("crypto/sha256", protocol.HashAlgorithmSHA256, cryptoSha256.New),
+		NewHashBackend("minio/sha256-simd", protocol.HashAlgorithmSHA256, cryptoSha256.New),
+	)()
+
+	cases := map[string]string{
+		"minio":             "minio/sha256-simd",
+		"minio/sha256-simd": "minio/sha256-simd",
+		"standard":          "crypto/sha256",
+		"unknown":           "crypto/sha256",
+	}
+	for env, name := range cases {
+		if b := forcedHashBackend(env); b == nil || b.Name() != name {
+			t.Errorf("%q should force %s, got %v", env, name, b)
+		}
+	}
+	if b := forcedHashBackend(""); b != nil {
+		t.Errorf("nothing should be forced, got %v", b.Name())
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package scanner
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+// ScanProgress describes how far the hashing part of a scan has come.
+type ScanProgress struct {
+	Dir            string  `json:"dir"`  // of the file being hashed
+	File           string  `json:"file"` // most recently started
+	Files          int     `json:"files"`
+	FilesTotal     int     `json:"filesTotal"`
+	Bytes          int64   `json:"bytes"`
+	BytesTotal     int64   `json:"bytesTotal"`
+	FilesPerSecond float64 `json:"filesPerSecond"`
+	BytesPerSecond float64 `json:"bytesPerSecond"`
+	ETA            float64 `json:"eta"` // seconds, or zero if unknown
+}
+
+// hashStatus keeps track of the files the parallel hasher is working on.
+// A nil hashStatus tracks nothing.
+type hashStatus struct {
+	mut     sync.Mutex
+	file    string
+	files   int
+	started time.Time
+}
+
+func newHashStatus() *hashStatus {
+	return &hashStatus{
+		mut:     sync.NewMutex(),
+		started: time.Now(),
+	}
+}
+
+func (s *hashStatus) hashing(name string) {
+	if s == nil {
+		return
+	}
+	s.mut.Lock()
+	s.file = name
+	s.mut.Unlock()
+}
+
+func (s *hashStatus) hashed() {
+	if s == nil {
+		return
+	}
+	s.mut.Lock()
+	s.files++
+	s.mut.Unlock()
+}
+
+// progress returns the current progress, given the byte counts and the
+// rate at which bytes are hashed.
+func (s *hashStatus) progress(filesTotal int, bytes, bytesTotal int64, rate float64) ScanProgress {
+	s.mut.Lock()
+	p := ScanProgress{
+		File:           s.file,
+		Files:          s.files,
+		FilesTotal:     filesTotal,
+		Bytes:          bytes,
+		BytesTotal:     bytesTotal,
+		BytesPerSecond: rate,
+	}
+	elapsed := time.Since(s.started).Seconds()
+	s.mut.Unlock()
+
+	if p.File != "" {
+		p.Dir = filepath.Dir(p.File)
+	}
+	if elapsed > 0 {
+		p.FilesPerSecond = float64(p.Files) / elapsed
+	}
+	if rate > 0 && bytesTotal > bytes {
+		p.ETA = float64(bytesTotal-bytes) / rate
+	}
+	return p
+}
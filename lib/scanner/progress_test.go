@@ -0,0 +1,43 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package scanner
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHashStatusProgress(t *testing.T) {
+	var none *hashStatus
+	none.hashing("a") // must not panic
+	none.hashed()
+
+	s := newHashStatus()
+	s.started = time.Now().Add(-2 * time.Second)
+	s.hashing(filepath.Join("a", "b", "c"))
+	s.hashed()
+	s.hashed()
+
+	p := s.progress(10, 100, 1100, 50)
+	if p.File != filepath.Join("a", "b", "c") || p.Dir != filepath.Join("a", "b") {
+		t.Errorf("unexpected file %q in dir %q", p.File, p.Dir)
+	}
+	if p.Files != 2 || p.FilesTotal != 10 {
+		t.Errorf("expected 2 of 10 files, got %d of %d", p.Files, p.FilesTotal)
+	}
+	if p.FilesPerSecond <= 0 || p.FilesPerSecond > 1 {
+		t.Errorf("expected about one file per second, got %v", p.FilesPerSecond)
+	}
+	if p.BytesPerSecond != 50 || p.ETA != 20 {
+		t.Errorf("expected 50 B/s and 20 s left, got %v and %v", p.BytesPerSecond, p.ETA)
+	}
+
+	if p := s.progress(10, 100, 1100, 0); p.ETA != 0 {
+		t.Errorf("expected an unknown ETA without a rate, got %v", p.ETA)
+	}
+}
@@ -111,6 +111,7 @@ func (f fakeInfo) IsRegular() bool    { return !f.IsDir() }
 func (f fakeInfo) IsSymlink() bool    { return false }
 func (f fakeInfo) Owner() int         { return 0 }
 func (f fakeInfo) Group() int         { return 0 }
+func (f fakeInfo) Inode() uint64      { return 0 }
 
 type fakeFile struct {
 	name       string
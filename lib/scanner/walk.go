@@ -7,6 +7,7 @@
 package scanner
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -23,7 +24,6 @@ import (
 	"github.com/syncthing/syncthing/lib/ignore"
 	"github.com/syncthing/syncthing/lib/osutil"
 	"github.com/syncthing/syncthing/lib/protocol"
-	"golang.org/x/text/unicode/norm"
 )
 
 type Config struct {
@@ -46,8 +46,15 @@ type Config struct {
 	// When AutoNormalize is set, file names that are in UTF8 but incorrect
 	// normalization form will be corrected.
 	AutoNormalize bool
+	// The normalization form file names should be in.
+	Normalization fs.Normalization
 	// Number of routines to use for hashing
 	Hashers int
+	// If DiskLimiter is not nil, it limits how many files are hashed at
+	// the same time on the Disk, together with other walkers.
+	DiskLimiter *DiskLimiter
+	// The ID of the disk the files are on, as in Disk.
+	Disk string
 	// Our vector clock id
 	ShortID protocol.ShortID
 	// Optional progress tick interval which defines how often FolderScanProgress
@@ -59,6 +66,41 @@ type Config struct {
 	ModTimeWindow time.Duration
 	// Event logger to which the scan progress events are sent
 	EventLogger events.Logger
+	// If HashCache is not nil, it is queried for the blocks of files that
+	// would otherwise need to be hashed, and is kept up to date with the
+	// blocks of the files that are scanned.
+	HashCache HashCache
+	// If SyncXattrs is true, the extended attributes of files and
+	// directories are recorded and changes to them are detected.
+	SyncXattrs bool
+	// If SyncPlatformData is true, the platform data of files and
+	// directories is recorded and changes to it are detected, and
+	// junctions are walked as symlinks on Windows.
+	SyncPlatformData bool
+	// If SyncOwnership is true, the names of the owner and group of files,
+	// directories and symlinks are recorded and changes to them are
+	// detected.
+	SyncOwnership bool
+	// If ProgressFn is not nil, it is called for every item walked and
+	// every block hashed, so that a slow scan can be told from a stuck one.
+	ProgressFn func()
+	// The scheme by which new and changed files are divided into blocks.
+	// Unchanged files keep theirs.
+	BlockScheme protocol.BlockScheme
+	// The algorithm by which the blocks of new and changed files are
+	// hashed. Unchanged files keep theirs.
+	HashAlgorithm protocol.HashAlgorithm
+	// If ProgressStatusFn is not nil, it is called with the progress of
+	// hashing whenever a FolderScanProgress event is emitted.
+	ProgressStatusFn func(ScanProgress)
+	// If Journal is not nil, directories completed by an earlier scan are
+	// skipped, and a result with Dir set is sent when leaving each
+	// directory.
+	Journal ScanJournal
+	// New and changed files modified less than this long ago are likely
+	// still being written, so they are not scanned but result in a result
+	// with Unstable set instead. Zero scans all files.
+	StabilityWindow time.Duration
 }
 
 type CurrentFiler interface {
@@ -66,18 +108,47 @@ type CurrentFiler interface {
 	CurrentFile(name string) (protocol.FileInfo, bool)
 }
 
+// A HashCache remembers the blocks of files by their identity on disk
+// (inode, size and modification time) rather than by name, so that it
+// remains valid when the database is lost or the folder is re-added.
+type HashCache interface {
+	// Get returns the blocks previously stored for the given file, if
+	// they were hashed with the given block size.
+	Get(info fs.FileInfo, blockSize int) ([]protocol.BlockInfo, bool)
+	// Put stores the blocks of the given file.
+	Put(info fs.FileInfo, blockSize int, blocks []protocol.BlockInfo)
+}
+
+// A ScanJournal tells which directories an interrupted earlier scan
+// completed, so that they need not be walked again.
+type ScanJournal interface {
+	Completed(dir string) bool
+}
+
 type ScanResult struct {
 	File protocol.FileInfo
 	Err  error
 	Path string // to be set in case Err != nil and File == nil
+	// When walking with a Journal, a result with only Dir set is sent when
+	// the walk leaves the directory. Items is the number of results for
+	// the items directly in it, including those yet to come from hashing,
+	// or -1 if the directory couldn't be listed.
+	Dir   string
+	Items int
+	// Unstable is set, along with Path, for a file that was skipped for
+	// having been modified within the StabilityWindow.
+	Unstable bool
 }
 
 func Walk(ctx context.Context, cfg Config) chan ScanResult {
-	w := walker{cfg}
+	w := walker{Config: cfg}
 
 	if w.CurrentFiler == nil {
 		w.CurrentFiler = noCurrentFiler{}
 	}
+	if w.HashCache == nil {
+		w.HashCache = noHashCache{}
+	}
 	if w.Filesystem == nil {
 		panic("no filesystem specified")
 	}
@@ -96,6 +167,12 @@ var (
 
 type walker struct {
 	Config
+	dirs []walkedDir // being walked, when journaling
+}
+
+type walkedDir struct {
+	name  string
+	items int
 }
 
 // Walk returns the list of files found in the local folder by scanning the
@@ -110,15 +187,18 @@ func (w *walker) walk(ctx context.Context) chan ScanResult {
 	// been modified to the counter routine.
 	go func() {
 		hashFiles := w.walkAndHashFiles(ctx, toHashChan, finishedChan)
+		if w.Journal != nil {
+			hashFiles = w.journaled(ctx, hashFiles, finishedChan)
+		}
 		if len(w.Subs) == 0 {
-			w.Filesystem.Walk(".", hashFiles)
+			w.leaveDirs(ctx, w.Filesystem.Walk(".", hashFiles), finishedChan)
 		} else {
 			for _, sub := range w.Subs {
 				if err := osutil.TraversesSymlink(w.Filesystem, filepath.Dir(sub)); err != nil {
 					l.Debugf("Skip walking %v as it is below a symlink", sub)
 					continue
 				}
-				w.Filesystem.Walk(sub, hashFiles)
+				w.leaveDirs(ctx, w.Filesystem.Walk(sub, hashFiles), finishedChan)
 			}
 		}
 		close(toHashChan)
@@ -127,7 +207,7 @@ func (w *walker) walk(ctx context.Context) chan ScanResult {
 	// We're not required to emit scan progress events, just kick off hashers,
 	// and feed inputs directly from the walker.
 	if w.ProgressTickIntervalS < 0 {
-		newParallelHasher(ctx, w.Filesystem, w.Hashers, finishedChan, toHashChan, nil, nil)
+		newParallelHasher(ctx, w.Filesystem, w.Hashers, finishedChan, toHashChan, w.hashCounter(nil), nil, w.HashCache, w.DiskLimiter, w.Disk, nil)
 		return finishedChan
 	}
 
@@ -157,8 +237,9 @@ func (w *walker) walk(ctx context.Context) chan ScanResult {
 		realToHashChan := make(chan protocol.FileInfo)
 		done := make(chan struct{})
 		progress := newByteCounter()
+		status := newHashStatus()
 
-		newParallelHasher(ctx, w.Filesystem, w.Hashers, finishedChan, realToHashChan, progress, done)
+		newParallelHasher(ctx, w.Filesystem, w.Hashers, finishedChan, realToHashChan, w.hashCounter(progress), done, w.HashCache, w.DiskLimiter, w.Disk, status)
 
 		// A routine which actually emits the FolderScanProgress events
 		// every w.ProgressTicker ticks, until the hasher routines terminate.
@@ -174,13 +255,23 @@ func (w *walker) walk(ctx context.Context) chan ScanResult {
 				case <-ticker.C:
 					current := progress.Total()
 					rate := progress.Rate()
-					l.Debugf("Walk %s %s current progress %d/%d at %.01f MiB/s (%d%%)", w.Folder, w.Subs, current, total, rate/1024/1024, current*100/total)
+					p := status.progress(len(filesToHash), current, total-1, rate)
+					l.Debugf("Walk %s %s current progress %d/%d at %.01f MiB/s (%d%%), hashing %s", w.Folder, w.Subs, current, total, rate/1024/1024, current*100/total, p.File)
 					w.EventLogger.Log(events.FolderScanProgress, map[string]interface{}{
-						"folder":  w.Folder,
-						"current": current,
-						"total":   total,
-						"rate":    rate, // bytes per second
+						"folder":     w.Folder,
+						"current":    current,
+						"total":      total,
+						"rate":       rate, // bytes per second
+						"file":       p.File,
+						"dir":        p.Dir,
+						"files":      p.Files,
+						"filesTotal": p.FilesTotal,
+						"filesRate":  p.FilesPerSecond,
+						"eta":        p.ETA, // seconds, zero if unknown
 					})
+					if w.ProgressStatusFn != nil {
+						w.ProgressStatusFn(p)
+					}
 				case <-ctx.Done():
 					ticker.Stop()
 					return
@@ -214,6 +305,10 @@ func (w *walker) walkAndHashFiles(ctx context.Context, toHashChan chan<- protoco
 		default:
 		}
 
+		if w.ProgressFn != nil {
+			w.ProgressFn()
+		}
+
 		// Return value used when we are returning early and don't want to
 		// process the item. For directories, this means do-not-descend.
 		var skip error // nil
@@ -241,7 +336,7 @@ func (w *walker) walkAndHashFiles(ctx context.Context, toHashChan chan<- protoco
 			return skip
 		}
 
-		if w.Matcher.Match(path).IsIgnored() {
+		if w.ignored(path, info) {
 			l.Debugln("ignored (patterns):", path)
 			// Only descend if matcher says so and the current file is not a symlink.
 			if err != nil || w.Matcher.SkipIgnoredDirs() || info.IsSymlink() {
@@ -294,6 +389,88 @@ func (w *walker) walkAndHashFiles(ctx context.Context, toHashChan chan<- protoco
 	}
 }
 
+// journaled wraps the walk function to skip the directories completed
+// according to the journal, and to keep track of the directories being
+// walked so that a result is sent when leaving them.
+func (w *walker) journaled(ctx context.Context, walkFn fs.WalkFunc, finishedChan chan<- ScanResult) fs.WalkFunc {
+	return func(path string, info fs.FileInfo, err error) error {
+		if n := len(w.dirs); err != nil && n > 0 && w.dirs[n-1].name == path {
+			// Listing the directory failed, so it can't be completed.
+			w.dirs[n-1].items = -1
+		} else if err := w.leaveDirsUntil(ctx, path, finishedChan); err != nil {
+			return err
+		}
+
+		isDir := info != nil && info.IsDir()
+		if isDir && path != "." && w.Journal.Completed(path) {
+			l.Debugln("completed by an earlier scan:", path)
+			return fs.SkipDir
+		}
+		if err := walkFn(path, info, err); err != nil {
+			return err
+		}
+		if isDir {
+			w.dirs = append(w.dirs, walkedDir{name: path})
+		}
+		return nil
+	}
+}
+
+// leaveDirs sends a result for each directory still being walked, once the
+// walk returned. If the walk failed they are dropped instead, as they
+// weren't walked completely.
+func (w *walker) leaveDirs(ctx context.Context, walkErr error, finishedChan chan<- ScanResult) {
+	if walkErr != nil || ctx.Err() != nil {
+		w.dirs = w.dirs[:0]
+		return
+	}
+	w.leaveDirsUntil(ctx, "", finishedChan)
+}
+
+// leaveDirsUntil sends a result for each directory being walked that does
+// not contain the given path, which is the next one walked.
+func (w *walker) leaveDirsUntil(ctx context.Context, path string, finishedChan chan<- ScanResult) error {
+	for len(w.dirs) > 0 {
+		dir := w.dirs[len(w.dirs)-1]
+		if path != "" && (path == dir.name || fs.IsParent(path, dir.name)) {
+			return nil
+		}
+		select {
+		case finishedChan <- ScanResult{Dir: dir.name, Items: dir.items}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		w.dirs = w.dirs[:len(w.dirs)-1]
+	}
+	return nil
+}
+
+// counted counts a result for the given item towards the directory it is
+// in, when journaling.
+func (w *walker) counted(name string) {
+	if len(w.dirs) == 0 {
+		return
+	}
+	dir := filepath.Dir(name)
+	for i := len(w.dirs) - 1; i >= 0; i-- {
+		if w.dirs[i].name == dir {
+			if w.dirs[i].items >= 0 {
+				w.dirs[i].items++
+			}
+			return
+		}
+	}
+}
+
+// ignored returns whether the ignore patterns match the path, taking the
+// size and age of regular files into account.
+func (w *walker) ignored(path string, info fs.FileInfo) bool {
+	if info != nil && info.IsRegular() {
+		return w.Matcher.MatchFile(path, info.Size(), info.ModTime()).IsIgnored()
+	}
+	return w.Matcher.Match(path).IsIgnored()
+}
+
 func (w *walker) handleItem(ctx context.Context, path string, toHashChan chan<- protocol.FileInfo, finishedChan chan<- ScanResult, skip error) error {
 	info, err := w.Filesystem.Lstat(path)
 	// An error here would be weird as we've already gotten to this point, but act on it nonetheless
@@ -324,15 +501,21 @@ func (w *walker) handleItem(ctx context.Context, path string, toHashChan chan<-
 		err = w.walkDir(ctx, path, info, finishedChan)
 
 	case info.IsRegular():
-		err = w.walkRegular(ctx, path, info, toHashChan)
+		err = w.walkRegular(ctx, path, info, toHashChan, finishedChan)
 	}
 
 	return err
 }
 
-func (w *walker) walkRegular(ctx context.Context, relPath string, info fs.FileInfo, toHashChan chan<- protocol.FileInfo) error {
+func (w *walker) walkRegular(ctx context.Context, relPath string, info fs.FileInfo, toHashChan chan<- protocol.FileInfo, finishedChan chan<- ScanResult) error {
 	curFile, hasCurFile := w.CurrentFiler.CurrentFile(relPath)
 
+	if hasCurFile && curFile.IsPlaceholder() && info.Size() == 0 {
+		// An empty file where we have a placeholder is the placeholder,
+		// not the file having been truncated.
+		return nil
+	}
+
 	blockSize := protocol.BlockSize(info.Size())
 
 	if hasCurFile {
@@ -353,9 +536,19 @@ func (w *walker) walkRegular(ctx context.Context, relPath string, info fs.FileIn
 	f = w.updateFileInfo(f, curFile)
 	f.NoPermissions = w.IgnorePerms
 	f.RawBlockSize = int32(blockSize)
+	f.BlockScheme = w.BlockScheme
+	f.HashAlgorithm = w.HashAlgorithm
+	f.Xattrs = w.xattrs(relPath)
+	f.Platform = w.platformData(relPath, f, curFile, hasCurFile)
+	f = w.ownerNames(f)
 
 	if hasCurFile {
-		if curFile.IsEquivalentOptional(f, w.ModTimeWindow, w.IgnorePerms, true, w.LocalFlags) {
+		if curFile.IsEquivalentOptional(f, w.ModTimeWindow, w.IgnorePerms, true, w.LocalFlags) && w.xattrsEqual(curFile, f) && platformDataEqual(curFile, f) && w.ownerNamesEqual(curFile, f) {
+			if len(curFile.Blocks) > 0 && cacheable(curFile) {
+				// Make sure the cache knows about files hashed before
+				// it existed.
+				w.HashCache.Put(info, curFile.BlockSize(), curFile.Blocks)
+			}
 			return nil
 		}
 		if curFile.ShouldConflict() {
@@ -369,6 +562,32 @@ func (w *walker) walkRegular(ctx context.Context, relPath string, info fs.FileIn
 		l.Debugln("rescan:", curFile, info.ModTime().Unix(), info.Mode()&fs.ModePerm)
 	}
 
+	// A file modified in the future would never become stable, so it is
+	// scanned right away.
+	if age := time.Since(info.ModTime()); age >= 0 && age < w.StabilityWindow {
+		l.Debugln("unstable, modified", age, "ago:", relPath)
+		select {
+		case finishedChan <- ScanResult{Path: relPath, Unstable: true}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		w.counted(relPath)
+		return nil
+	}
+
+	if blocks, ok := w.cachedBlocks(info, f); ok {
+		l.Debugln("hash cache hit:", relPath, f)
+		f.Blocks = blocks
+		f.Holes = fileHoles(w.Filesystem, relPath)
+		select {
+		case finishedChan <- ScanResult{File: f}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		w.counted(f.Name)
+		return nil
+	}
+
 	l.Debugln("to hash:", relPath, f)
 
 	select {
@@ -376,6 +595,7 @@ func (w *walker) walkRegular(ctx context.Context, relPath string, info fs.FileIn
 	case <-ctx.Done():
 		return ctx.Err()
 	}
+	w.counted(f.Name)
 
 	return nil
 }
@@ -386,9 +606,12 @@ func (w *walker) walkDir(ctx context.Context, relPath string, info fs.FileInfo,
 	f, _ := CreateFileInfo(info, relPath, nil)
 	f = w.updateFileInfo(f, curFile)
 	f.NoPermissions = w.IgnorePerms
+	f.Xattrs = w.xattrs(relPath)
+	f.Platform = w.platformData(relPath, f, curFile, hasCurFile)
+	f = w.ownerNames(f)
 
 	if hasCurFile {
-		if curFile.IsEquivalentOptional(f, w.ModTimeWindow, w.IgnorePerms, true, w.LocalFlags) {
+		if curFile.IsEquivalentOptional(f, w.ModTimeWindow, w.IgnorePerms, true, w.LocalFlags) && w.xattrsEqual(curFile, f) && platformDataEqual(curFile, f) && w.ownerNamesEqual(curFile, f) {
 			return nil
 		}
 		if curFile.ShouldConflict() {
@@ -408,6 +631,7 @@ func (w *walker) walkDir(ctx context.Context, relPath string, info fs.FileInfo,
 	case <-ctx.Done():
 		return ctx.Err()
 	}
+	w.counted(f.Name)
 
 	return nil
 }
@@ -416,9 +640,14 @@ func (w *walker) walkDir(ctx context.Context, relPath string, info fs.FileInfo,
 // it should stop the entire walk.
 func (w *walker) walkSymlink(ctx context.Context, relPath string, info fs.FileInfo, finishedChan chan<- ScanResult) error {
 	// Symlinks are not supported on Windows. We ignore instead of returning
-	// an error.
+	// an error. Junctions are, as part of the platform data.
 	if runtime.GOOS == "windows" {
-		return nil
+		if !w.SyncPlatformData {
+			return nil
+		}
+		if data, err := w.Filesystem.GetPlatformData(relPath); err != nil || !data.Windows.Junction {
+			return nil
+		}
 	}
 
 	f, err := CreateFileInfo(info, relPath, w.Filesystem)
@@ -430,9 +659,11 @@ func (w *walker) walkSymlink(ctx context.Context, relPath string, info fs.FileIn
 	curFile, hasCurFile := w.CurrentFiler.CurrentFile(relPath)
 
 	f = w.updateFileInfo(f, curFile)
+	f.Platform = w.platformData(relPath, f, curFile, hasCurFile)
+	f = w.ownerNames(f)
 
 	if hasCurFile {
-		if curFile.IsEquivalentOptional(f, w.ModTimeWindow, w.IgnorePerms, true, w.LocalFlags) {
+		if curFile.IsEquivalentOptional(f, w.ModTimeWindow, w.IgnorePerms, true, w.LocalFlags) && platformDataEqual(curFile, f) && w.ownerNamesEqual(curFile, f) {
 			return nil
 		}
 		if curFile.ShouldConflict() {
@@ -452,6 +683,7 @@ func (w *walker) walkSymlink(ctx context.Context, relPath string, info fs.FileIn
 	case <-ctx.Done():
 		return ctx.Err()
 	}
+	w.counted(f.Name)
 
 	return nil
 }
@@ -459,15 +691,11 @@ func (w *walker) walkSymlink(ctx context.Context, relPath string, info fs.FileIn
 // normalizePath returns the normalized relative path (possibly after fixing
 // it on disk), or skip is true.
 func (w *walker) normalizePath(path string, info fs.FileInfo) (normPath string, err error) {
-	if runtime.GOOS == "darwin" {
-		// Mac OS X file names should always be NFD normalized.
-		normPath = norm.NFD.String(path)
-	} else {
-		// Every other OS in the known universe uses NFC or just plain
-		// doesn't bother to define an encoding. In our case *we* do care,
-		// so we enforce NFC regardless.
-		normPath = norm.NFC.String(path)
-	}
+	// Unless configured otherwise, Mac OS X file names should be NFD
+	// normalized. Every other OS in the known universe uses NFC or just
+	// plain doesn't bother to define an encoding. In our case *we* do
+	// care, so we enforce NFC regardless.
+	normPath = w.Normalization.Normalize(path)
 
 	if path == normPath {
 		// The file name is already normalized: nothing to do
@@ -528,6 +756,143 @@ func (w *walker) updateFileInfo(file, curFile protocol.FileInfo) protocol.FileIn
 	return file
 }
 
+// hashCounter returns the counter to give the hashers, which also reports
+// progress to ProgressFn if set.
+func (w *walker) hashCounter(c Counter) Counter {
+	if w.ProgressFn == nil {
+		return c
+	}
+	return progressCounter{Counter: c, fn: w.ProgressFn}
+}
+
+// cachedBlocks returns the blocks of the file from the hash cache, if they
+// are there.
+func (w *walker) cachedBlocks(info fs.FileInfo, f protocol.FileInfo) ([]protocol.BlockInfo, bool) {
+	if !cacheable(f) {
+		return nil, false
+	}
+	return w.HashCache.Get(info, f.BlockSize())
+}
+
+// cacheable returns whether the blocks of the file may be in the hash
+// cache. Only fixed size blocks hashed with SHA-256 are, as the cache
+// doesn't tell them apart from others.
+func cacheable(f protocol.FileInfo) bool {
+	return f.BlockScheme == protocol.BlockSchemeFixed && f.HashAlgorithm == protocol.HashAlgorithmSHA256
+}
+
+type progressCounter struct {
+	Counter
+	fn func()
+}
+
+func (c progressCounter) Update(bytes int64) {
+	if c.Counter != nil {
+		c.Counter.Update(bytes)
+	}
+	c.fn()
+}
+
+// fileHoles returns the holes of the given file, which lets the file be
+// pulled as sparse as it is here. Failing to find them is not fatal to the
+// scan; the file is then treated as having none.
+func fileHoles(filesystem fs.Filesystem, name string) []protocol.FileHole {
+	fd, err := filesystem.Open(name)
+	if err != nil {
+		l.Debugln("holes:", name, err)
+		return nil
+	}
+	defer fd.Close()
+	holes, err := fs.Holes(fd)
+	if err != nil {
+		l.Debugln("holes:", name, err)
+		return nil
+	}
+	return holes
+}
+
+// xattrs returns the extended attributes of the given item, if they are to
+// be synced. Failing to read them is not fatal to the scan; the item is
+// then treated as having none.
+func (w *walker) xattrs(relPath string) []protocol.Xattr {
+	if !w.SyncXattrs {
+		return nil
+	}
+	xattrs, err := w.Filesystem.GetXattr(relPath)
+	if err != nil && err != fs.ErrXattrsNotSupported {
+		l.Debugln("reading xattrs:", relPath, err)
+	}
+	return xattrs
+}
+
+func (w *walker) xattrsEqual(a, b protocol.FileInfo) bool {
+	if !w.SyncXattrs {
+		return true
+	}
+	if len(a.Xattrs) != len(b.Xattrs) {
+		return false
+	}
+	for i := range a.Xattrs {
+		if a.Xattrs[i].Name != b.Xattrs[i].Name || !bytes.Equal(a.Xattrs[i].Value, b.Xattrs[i].Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// platformData returns the platform data of the given item, if it is to be
+// synced. Otherwise, or if the platform has none, that of the current
+// version of the item is kept, so that it isn't lost by passing through
+// other platforms.
+func (w *walker) platformData(relPath string, f, curFile protocol.FileInfo, hasCurFile bool) protocol.PlatformData {
+	var keep protocol.PlatformData
+	if hasCurFile && curFile.Type == f.Type {
+		keep = curFile.Platform
+	}
+	if !w.SyncPlatformData {
+		return keep
+	}
+	data, err := w.Filesystem.GetPlatformData(relPath)
+	if err == fs.ErrPlatformDataNotSupported {
+		return keep
+	}
+	if err != nil {
+		l.Debugln("reading platform data:", relPath, err)
+	}
+	return data
+}
+
+func platformDataEqual(a, b protocol.FileInfo) bool {
+	wa, wb := a.Platform.Windows, b.Platform.Windows
+	if wa.Junction != wb.Junction || len(wa.Streams) != len(wb.Streams) {
+		return false
+	}
+	for i := range wa.Streams {
+		if wa.Streams[i].Name != wb.Streams[i].Name || !bytes.Equal(wa.Streams[i].Data, wb.Streams[i].Data) {
+			return false
+		}
+	}
+	return true
+}
+
+// ownerNames returns f with the names of its owner and group set, if they
+// are to be synced. Owners and groups without a name are left nameless.
+func (w *walker) ownerNames(f protocol.FileInfo) protocol.FileInfo {
+	if !w.SyncOwnership {
+		return f
+	}
+	f.OwnerName, _ = osutil.UserName(int(f.Uid))
+	f.GroupName, _ = osutil.GroupName(int(f.Gid))
+	return f
+}
+
+func (w *walker) ownerNamesEqual(a, b protocol.FileInfo) bool {
+	if !w.SyncOwnership || w.IgnorePerms {
+		return true
+	}
+	return a.OwnerName == b.OwnerName && a.GroupName == b.GroupName
+}
+
 func (w *walker) handleError(ctx context.Context, context, path string, err error, finishedChan chan<- ScanResult) {
 	// Ignore missing items, as deletions are not handled by the scanner.
 	if fs.IsNotExist(err) {
@@ -539,6 +904,7 @@ func (w *walker) handleError(ctx context.Context, context, path string, err erro
 		Err:  fmt.Errorf("%s: %s", context, err.Error()),
 		Path: path,
 	}:
+		w.counted(path)
 	case <-ctx.Done():
 	}
 }
@@ -596,6 +962,14 @@ func (noCurrentFiler) CurrentFile(name string) (protocol.FileInfo, bool) {
 	return protocol.FileInfo{}, false
 }
 
+type noHashCache struct{}
+
+func (noHashCache) Get(fs.FileInfo, int) ([]protocol.BlockInfo, bool) {
+	return nil, false
+}
+
+func (noHashCache) Put(fs.FileInfo, int, []protocol.BlockInfo) {}
+
 func CreateFileInfo(fi fs.FileInfo, name string, filesystem fs.Filesystem) (protocol.FileInfo, error) {
 	f := protocol.FileInfo{Name: name}
 	f.Uid = int32(fi.Owner())
@@ -15,11 +15,13 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	rdebug "runtime/debug"
 	"sort"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/d4l3k/messagediff"
 	"github.com/syncthing/syncthing/lib/events"
@@ -247,6 +249,53 @@ func TestNormalization(t *testing.T) {
 	}
 }
 
+func TestNormalizationPolicy(t *testing.T) {
+	const (
+		nfc = "\xC3\x84"     // NFC 'Ä'
+		nfd = "\x41\xCC\x88" // NFD 'Ä'
+	)
+
+	cases := []struct {
+		policy   fs.Normalization
+		onDisk   string
+		expected string
+	}{
+		{fs.NormalizationNFC, nfd, nfc},
+		{fs.NormalizationNFD, nfc, nfd},
+		{fs.NormalizationPreserve, nfc, nfc},
+		{fs.NormalizationPreserve, nfd, nfd},
+	}
+
+	for i, tc := range cases {
+		ffs := fs.NewFilesystem(fs.FilesystemTypeFake, fmt.Sprintf("normpolicy%d", i))
+		fd, err := ffs.Create(tc.onDisk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fd.Close()
+
+		cfg := testConfig()
+		cfg.Filesystem = ffs
+		cfg.AutoNormalize = true
+		cfg.Normalization = tc.policy
+		var names []string
+		for res := range Walk(context.TODO(), cfg) {
+			if res.Err != nil {
+				t.Errorf("%v: unexpected error: %v", tc.policy, res.Err)
+				continue
+			}
+			names = append(names, res.File.Name)
+		}
+
+		if len(names) != 1 || names[0] != tc.expected {
+			t.Errorf("%v: scanned %q, expected %q", tc.policy, names, tc.expected)
+		}
+		if _, err := ffs.Lstat(tc.expected); err != nil {
+			t.Errorf("%v: %q isn't on disk: %v", tc.policy, tc.expected, err)
+		}
+	}
+}
+
 func TestIssue1507(t *testing.T) {
 	w := &walker{}
 	w.Matcher = ignore.New(w.Filesystem)
@@ -482,6 +531,165 @@ func TestWalkReceiveOnly(t *testing.T) {
 	}
 }
 
+func TestWalkHashCache(t *testing.T) {
+	sf := fs.NewWalkFilesystem(&singleFileFS{
+		name:     "testfile.dat",
+		filesize: 1024,
+	})
+
+	walk := func(cfiler CurrentFiler, cache HashCache) []protocol.FileInfo {
+		cfg := testConfig()
+		cfg.Filesystem = sf
+		cfg.CurrentFiler = cfiler
+		cfg.HashCache = cache
+		var files []protocol.FileInfo
+		for f := range Walk(context.TODO(), cfg) {
+			if f.Err != nil {
+				t.Fatal(f.Err)
+			}
+			files = append(files, f.File)
+		}
+		return files
+	}
+
+	// A file that gets hashed ends up in the cache.
+
+	cache := make(fakeHashCache)
+	files := walk(nil, cache)
+	if len(files) != 1 {
+		t.Fatal("Should have scanned one file")
+	}
+	hashed := files[0].Blocks
+	if blocks, ok := cache["testfile.dat"]; !ok || !protocol.BlocksEqual(blocks, hashed) {
+		t.Fatal("Hashed blocks should have been cached")
+	}
+
+	// A file found in the cache is not hashed.
+
+	fakeBlocks := []protocol.BlockInfo{{Size: 1024, Hash: []byte("not a real hash")}}
+	cache["testfile.dat"] = fakeBlocks
+	files = walk(nil, cache)
+	if len(files) != 1 {
+		t.Fatal("Should have scanned one file")
+	}
+	if !protocol.BlocksEqual(files[0].Blocks, fakeBlocks) {
+		t.Fatal("Should have used the cached blocks")
+	}
+
+	// An unchanged file known to the current filer is added to the cache.
+
+	cur := files[0]
+	cur.Blocks = hashed
+	cache = make(fakeHashCache)
+	files = walk(fakeCurrentFiler{cur.Name: cur}, cache)
+	if len(files) != 0 {
+		t.Fatal("Should not have scanned anything")
+	}
+	if blocks, ok := cache["testfile.dat"]; !ok || !protocol.BlocksEqual(blocks, hashed) {
+		t.Fatal("Current blocks should have been cached")
+	}
+}
+
+func TestWalkXattrs(t *testing.T) {
+	xfs := &xattrFS{Filesystem: fs.NewWalkFilesystem(&singleFileFS{
+		name:     "testfile.dat",
+		filesize: 1024,
+	})}
+	xfs.xattrs = []protocol.Xattr{{Name: "user.tag", Value: []byte("red")}}
+
+	walk := func(cfiler CurrentFiler, syncXattrs bool) []protocol.FileInfo {
+		cfg := testConfig()
+		cfg.Filesystem = xfs
+		cfg.CurrentFiler = cfiler
+		cfg.SyncXattrs = syncXattrs
+		var files []protocol.FileInfo
+		for f := range Walk(context.TODO(), cfg) {
+			if f.Err != nil {
+				t.Fatal(f.Err)
+			}
+			files = append(files, f.File)
+		}
+		return files
+	}
+
+	// Attributes are only recorded when enabled.
+
+	files := walk(nil, false)
+	if len(files) != 1 || len(files[0].Xattrs) != 0 {
+		t.Fatal("Should have scanned one file without xattrs, got", files)
+	}
+	files = walk(nil, true)
+	if len(files) != 1 || len(files[0].Xattrs) != 1 || string(files[0].Xattrs[0].Value) != "red" {
+		t.Fatal("Should have scanned one file with xattrs, got", files)
+	}
+
+	// Unchanged attributes don't cause a rescan, changed ones do.
+
+	cur := fakeCurrentFiler{files[0].Name: files[0]}
+	if files := walk(cur, true); len(files) != 0 {
+		t.Fatal("Should not have scanned anything")
+	}
+	xfs.xattrs = []protocol.Xattr{{Name: "user.tag", Value: []byte("blue")}}
+	if files := walk(cur, false); len(files) != 0 {
+		t.Fatal("Should not have scanned anything with xattrs disabled")
+	}
+	if files := walk(cur, true); len(files) != 1 || string(files[0].Xattrs[0].Value) != "blue" {
+		t.Fatal("Should have rescanned the file, got", files)
+	}
+}
+
+func TestWalkPlatformData(t *testing.T) {
+	pfs := &platformDataFS{Filesystem: fs.NewWalkFilesystem(&singleFileFS{
+		name:     "testfile.dat",
+		filesize: 1024,
+	}), supported: true}
+	pfs.data.Windows.Streams = []protocol.DataStream{{Name: "Zone.Identifier", Data: []byte("[ZoneTransfer]")}}
+
+	walk := func(cfiler CurrentFiler) []protocol.FileInfo {
+		cfg := testConfig()
+		cfg.Filesystem = pfs
+		cfg.CurrentFiler = cfiler
+		cfg.SyncPlatformData = true
+		var files []protocol.FileInfo
+		for f := range Walk(context.TODO(), cfg) {
+			if f.Err != nil {
+				t.Fatal(f.Err)
+			}
+			files = append(files, f.File)
+		}
+		return files
+	}
+
+	files := walk(nil)
+	if len(files) != 1 || len(files[0].Platform.Windows.Streams) != 1 {
+		t.Fatal("Should have scanned one file with a stream, got", files)
+	}
+
+	// Changed streams cause a rescan.
+
+	cur := fakeCurrentFiler{files[0].Name: files[0]}
+	if files := walk(cur); len(files) != 0 {
+		t.Fatal("Should not have scanned anything")
+	}
+	pfs.data.Windows.Streams = []protocol.DataStream{{Name: "Zone.Identifier", Data: []byte("[ZoneTransfer]\r\nZoneId=3")}}
+	if files := walk(cur); len(files) != 1 || string(files[0].Platform.Windows.Streams[0].Data) != "[ZoneTransfer]\r\nZoneId=3" {
+		t.Fatal("Should have rescanned the file, got", files)
+	}
+
+	// Where there's no platform data, that of the current version is kept
+	// when the file changes otherwise.
+
+	pfs.supported = false
+	cur["testfile.dat"] = protocol.FileInfo{
+		Name:     "testfile.dat",
+		Size:     1,
+		Platform: files[0].Platform,
+	}
+	if files := walk(cur); len(files) != 1 || len(files[0].Platform.Windows.Streams) != 1 {
+		t.Fatal("Should have kept the stream, got", files)
+	}
+}
+
 func walkDir(fs fs.Filesystem, dir string, cfiler CurrentFiler, matcher *ignore.Matcher, localFlags uint32) []protocol.FileInfo {
 	cfg := testConfig()
 	cfg.Filesystem = fs
@@ -849,6 +1057,42 @@ func (fcf fakeCurrentFiler) CurrentFile(name string) (protocol.FileInfo, bool) {
 	return f, ok
 }
 
+// fakeHashCache is a HashCache keyed on the file name, as the test
+// filesystems don't have inodes.
+type fakeHashCache map[string][]protocol.BlockInfo
+
+func (c fakeHashCache) Get(info fs.FileInfo, blockSize int) ([]protocol.BlockInfo, bool) {
+	blocks, ok := c[info.Name()]
+	return blocks, ok
+}
+
+func (c fakeHashCache) Put(info fs.FileInfo, blockSize int, blocks []protocol.BlockInfo) {
+	c[info.Name()] = blocks
+}
+
+// xattrFS returns the same extended attributes for every file.
+type xattrFS struct {
+	fs.Filesystem
+	xattrs []protocol.Xattr
+}
+
+func (f *xattrFS) GetXattr(name string) ([]protocol.Xattr, error) {
+	return f.xattrs, nil
+}
+
+type platformDataFS struct {
+	fs.Filesystem
+	data      protocol.PlatformData
+	supported bool
+}
+
+func (f *platformDataFS) GetPlatformData(name string) (protocol.PlatformData, error) {
+	if !f.supported {
+		return protocol.PlatformData{}, fs.ErrPlatformDataNotSupported
+	}
+	return f.data, nil
+}
+
 func testConfig() Config {
 	evLogger := events.NewLogger()
 	go evLogger.Serve()
@@ -858,3 +1102,188 @@ func testConfig() Config {
 		EventLogger: evLogger,
 	}
 }
+
+func TestWalkOwnerNames(t *testing.T) {
+	sfs := fs.NewWalkFilesystem(&singleFileFS{
+		name:     "testfile.dat",
+		filesize: 1024,
+	})
+	owner, ok := osutil.UserName(0)
+	if !ok {
+		t.Skip("no name for user 0")
+	}
+
+	walk := func(cfiler CurrentFiler) []protocol.FileInfo {
+		cfg := testConfig()
+		cfg.Filesystem = sfs
+		cfg.CurrentFiler = cfiler
+		cfg.SyncOwnership = true
+		var files []protocol.FileInfo
+		for f := range Walk(context.TODO(), cfg) {
+			if f.Err != nil {
+				t.Fatal(f.Err)
+			}
+			files = append(files, f.File)
+		}
+		return files
+	}
+
+	files := walk(nil)
+	if len(files) != 1 || files[0].OwnerName != owner {
+		t.Fatalf("Should have scanned one file owned by %q, got %v", owner, files)
+	}
+
+	// The same numeric owner under another name is a change.
+
+	cur := fakeCurrentFiler{files[0].Name: files[0]}
+	if files := walk(cur); len(files) != 0 {
+		t.Fatal("Should not have scanned anything")
+	}
+	renamed := files[0]
+	renamed.OwnerName = "someone-else"
+	cur = fakeCurrentFiler{renamed.Name: renamed}
+	if files := walk(cur); len(files) != 1 || files[0].OwnerName != owner {
+		t.Fatal("Should have rescanned the file, got", files)
+	}
+}
+
+type fakeJournal map[string]bool
+
+func (j fakeJournal) Completed(dir string) bool {
+	return j[dir]
+}
+
+func TestWalkJournal(t *testing.T) {
+	ffs := fs.NewFilesystem(fs.FilesystemTypeFake, "walkjournal")
+	for _, dir := range []string{"a", "b"} {
+		if err := ffs.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"a/1", "a/2", "b/1", "c"} {
+		fd, err := ffs.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fd.Write([]byte(name))
+		fd.Close()
+	}
+
+	cfg := testConfig()
+	cfg.Filesystem = ffs
+	cfg.Journal = fakeJournal{"b": true}
+
+	var files []string
+	var dirs []ScanResult
+	for res := range Walk(context.TODO(), cfg) {
+		switch {
+		case res.Err != nil:
+			t.Fatal(res.Err)
+		case res.Dir != "":
+			dirs = append(dirs, res)
+		default:
+			files = append(files, res.File.Name)
+		}
+	}
+
+	// The directory completed earlier isn't walked.
+
+	sort.Strings(files)
+	expectedFiles := []string{"a", filepath.Join("a", "1"), filepath.Join("a", "2"), "c"}
+	if !reflect.DeepEqual(files, expectedFiles) {
+		t.Errorf("Walked %v, expected %v", files, expectedFiles)
+	}
+
+	// The others are left in depth first order, counting the items directly
+	// in them.
+
+	expectedDirs := []ScanResult{{Dir: "a", Items: 2}, {Dir: ".", Items: 2}}
+	if !reflect.DeepEqual(dirs, expectedDirs) {
+		t.Errorf("Left directories %v, expected %v", dirs, expectedDirs)
+	}
+}
+
+func TestWalkStabilityWindow(t *testing.T) {
+	ffs := fs.NewFilesystem(fs.FilesystemTypeFake, "walkstability")
+	for _, name := range []string{"settled", "writing", "future"} {
+		fd, err := ffs.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fd.Write([]byte(name))
+		fd.Close()
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := ffs.Chtimes("settled", old, old); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(time.Hour)
+	if err := ffs.Chtimes("future", future, future); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := testConfig()
+	cfg.Filesystem = ffs
+	cfg.StabilityWindow = time.Minute
+
+	// The file modified just now is left out, but a file modified in the
+	// future would never become stable and isn't.
+	var files, unstable []string
+	for res := range Walk(context.TODO(), cfg) {
+		switch {
+		case res.Err != nil:
+			t.Fatal(res.Err)
+		case res.Unstable:
+			unstable = append(unstable, res.Path)
+		default:
+			files = append(files, res.File.Name)
+		}
+	}
+	sort.Strings(files)
+	if expected := []string{"future", "settled"}; !reflect.DeepEqual(files, expected) {
+		t.Errorf("Scanned %v, expected %v", files, expected)
+	}
+	if expected := []string{"writing"}; !reflect.DeepEqual(unstable, expected) {
+		t.Errorf("Unstable %v, expected %v", unstable, expected)
+	}
+}
+
+func TestWalkHoles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "syncthing-holes-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ffs := fs.NewFilesystem(fs.FilesystemTypeBasic, dir)
+
+	fd, err := ffs.Create("sparse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const size = 4 * protocol.MinBlockSize
+	if err := fd.Truncate(size); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd.WriteAt([]byte("data"), protocol.MinBlockSize); err != nil {
+		t.Fatal(err)
+	}
+	holes, err := fs.Holes(fd)
+	fd.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(holes) == 0 {
+		t.Skip("No holes found, probably not supported here")
+	}
+
+	files := walkDir(ffs, ".", nil, nil, 0)
+	if len(files) != 1 {
+		t.Fatalf("Expected one file, got %v", files)
+	}
+	if !reflect.DeepEqual(files[0].Holes, holes) {
+		t.Errorf("Expected holes %v, got %v", holes, files[0].Holes)
+	}
+	if !files[0].InHole(0, protocol.MinBlockSize) || files[0].InHole(protocol.MinBlockSize, protocol.MinBlockSize) {
+		t.Errorf("Unexpected holes %v", files[0].Holes)
+	}
+}
@@ -129,6 +129,59 @@ func Verify(pubKeyPEM []byte, signature []byte, data io.Reader) error {
 	return nil
 }
 
+// CountValid computes the hash of data and returns the number of the given
+// public keys that made one of the signatures, which are concatenated PEM
+// blocks. Each key is counted at most once, so that repeating a signature
+// doesn't count for more.
+func CountValid(pubKeysPEM [][]byte, signatures []byte, data io.Reader) (int, error) {
+	// Parse the public keys
+	keys := make([]*ecdsa.PublicKey, len(pubKeysPEM))
+	for i, bs := range pubKeysPEM {
+		key, err := loadPublicKey(bs)
+		if err != nil {
+			return 0, err
+		}
+		keys[i] = key
+	}
+
+	// Parse the signatures
+	var sigs [][2]*big.Int
+	for {
+		var block *pem.Block
+		block, signatures = pem.Decode(signatures)
+		if block == nil {
+			break
+		}
+		r, s, err := unmarshalSignature(block.Bytes)
+		if err != nil {
+			return 0, err
+		}
+		sigs = append(sigs, [2]*big.Int{r, s})
+	}
+	if len(sigs) == 0 {
+		return 0, errors.New("unsupported signature format")
+	}
+
+	// Compute the hash of the data
+	hash, err := hashReader(data)
+	if err != nil {
+		return 0, err
+	}
+
+	// Count the keys with a matching signature
+	valid := 0
+	for _, key := range keys {
+		for _, sig := range sigs {
+			if ecdsa.Verify(key, hash, sig[0], sig[1]) {
+				valid++
+				break
+			}
+		}
+	}
+
+	return valid, nil
+}
+
 // hashReader returns the SHA256 hash of the reader
 func hashReader(r io.Reader) ([]byte, error) {
 	h := sha256.New()
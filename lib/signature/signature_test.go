@@ -79,3 +79,39 @@ func TestVerify(t *testing.T) {
 		t.Fatal("signature should not match")
 	}
 }
+
+func TestCountValid(t *testing.T) {
+	otherPriv, otherPub, err := signature.GenerateKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherSig, err := signature.Sign(otherPriv, bytes.NewReader([]byte("this is a string to sign")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, unusedPub, err := signature.GenerateKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := [][]byte{pubKey, otherPub, unusedPub}
+	cases := []struct {
+		sigs  []byte
+		data  string
+		valid int
+	}{
+		{exampleSig, "this is a string to sign", 1},
+		{bytes.Join([][]byte{exampleSig, otherSig}, []byte("\n")), "this is a string to sign", 2},
+		{bytes.Join([][]byte{exampleSig, exampleSig}, []byte("\n")), "this is a string to sign", 1},
+		{bytes.Join([][]byte{exampleSig, otherSig}, []byte("\n")), "thus is a string to sign", 0},
+	}
+	for i, tc := range cases {
+		valid, err := signature.CountValid(keys, tc.sigs, bytes.NewReader([]byte(tc.data)))
+		if err != nil {
+			t.Fatal(i, err)
+		}
+		if valid != tc.valid {
+			t.Errorf("%d: got %d valid signatures, expected %d", i, valid, tc.valid)
+		}
+	}
+}
@@ -37,4 +37,9 @@ func init() {
 		l.Debugf("Enabling lock deadlocking at %v", deadlock.Opts.DeadlockTimeout)
 		useDeadlock = true
 	}
+
+	if n, _ := strconv.Atoi(os.Getenv("STLOCKPROFILE")); n > 0 {
+		profileRate = uint32(n)
+		l.Infof("Sampling the wait time of one in %d locks", n)
+	}
 }
@@ -0,0 +1,134 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package sync
+
+import (
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// profileRate is one in how many locks have their wait time sampled, or
+// zero if the mutexes are not profiled. It is set by STLOCKPROFILE.
+var profileRate uint32
+
+// LockStats are the sampled wait times for taking the mutexes created at a
+// site, either for writing or for reading.
+type LockStats struct {
+	Site      string        `json:"site"`
+	Read      bool          `json:"read"`
+	Samples   int           `json:"samples"`
+	TotalWait time.Duration `json:"totalWait"`
+	MaxWait   time.Duration `json:"maxWait"`
+}
+
+type lockKey struct {
+	site string
+	read bool
+}
+
+var (
+	profileMut   sync.Mutex
+	profileStats = make(map[lockKey]*LockStats)
+)
+
+// LockProfile returns the sampling rate and the stats of the mutexes by
+// decreasing total wait time. The rate is zero unless profiling was enabled
+// by STLOCKPROFILE.
+func LockProfile() (int, []LockStats) {
+	profileMut.Lock()
+	stats := make([]LockStats, 0, len(profileStats))
+	for _, s := range profileStats {
+		stats = append(stats, *s)
+	}
+	profileMut.Unlock()
+
+	sort.Slice(stats, func(a, b int) bool {
+		return stats[a].TotalWait > stats[b].TotalWait
+	})
+	return int(profileRate), stats
+}
+
+// ResetLockProfile discards the samples collected so far.
+func ResetLockProfile() {
+	profileMut.Lock()
+	profileStats = make(map[lockKey]*LockStats)
+	profileMut.Unlock()
+}
+
+func recordWait(site string, read bool, wait time.Duration) {
+	profileMut.Lock()
+	s, ok := profileStats[lockKey{site, read}]
+	if !ok {
+		s = &LockStats{Site: site, Read: read}
+		profileStats[lockKey{site, read}] = s
+	}
+	s.Samples++
+	s.TotalWait += wait
+	if wait > s.MaxWait {
+		s.MaxWait = wait
+	}
+	profileMut.Unlock()
+}
+
+// callerSite returns where the mutex is created, as the caller of the New*
+// function.
+func callerSite() string {
+	_, file, line, _ := runtime.Caller(2)
+	return filepath.Join(filepath.Base(filepath.Dir(file)), filepath.Base(file)) + ":" + strconv.Itoa(line)
+}
+
+// sampled counts a lock of a mutex, returning whether it is to be sampled.
+func sampled(n *uint32) bool {
+	return atomic.AddUint32(n, 1)%profileRate == 0
+}
+
+type profiledMutex struct {
+	sync.Mutex
+	site string
+	n    uint32
+}
+
+func (m *profiledMutex) Lock() {
+	if !sampled(&m.n) {
+		m.Mutex.Lock()
+		return
+	}
+	start := defaultClock.Now()
+	m.Mutex.Lock()
+	recordWait(m.site, false, defaultClock.Now().Sub(start))
+}
+
+type profiledRWMutex struct {
+	sync.RWMutex
+	site string
+	n    uint32
+}
+
+func (m *profiledRWMutex) Lock() {
+	if !sampled(&m.n) {
+		m.RWMutex.Lock()
+		return
+	}
+	start := defaultClock.Now()
+	m.RWMutex.Lock()
+	recordWait(m.site, false, defaultClock.Now().Sub(start))
+}
+
+func (m *profiledRWMutex) RLock() {
+	if !sampled(&m.n) {
+		m.RWMutex.RLock()
+		return
+	}
+	start := defaultClock.Now()
+	m.RWMutex.RLock()
+	recordWait(m.site, true, defaultClock.Now().Sub(start))
+}
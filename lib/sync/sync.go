@@ -51,6 +51,9 @@ func NewMutex() Mutex {
 		mutex.holder.Store(holder{})
 		return mutex
 	}
+	if profileRate > 0 {
+		return &profiledMutex{site: callerSite()}
+	}
 	return &sync.Mutex{}
 }
 
@@ -66,6 +69,9 @@ func NewRWMutex() RWMutex {
 		mutex.holder.Store(holder{})
 		return mutex
 	}
+	if profileRate > 0 {
+		return &profiledRWMutex{site: callerSite()}
+	}
 	return &sync.RWMutex{}
 }
 
@@ -347,3 +347,51 @@ func (t *testClock) wind(d time.Duration) {
 	t.time = t.time.Add(d)
 	t.mut.Unlock()
 }
+
+func TestLockProfile(t *testing.T) {
+	debug = false
+	l.SetDebug("sync", false)
+	profileRate = 2
+	defer func() { profileRate = 0 }()
+	ResetLockProfile()
+	defer ResetLockProfile()
+
+	mut := NewRWMutex()
+	if _, ok := mut.(*profiledRWMutex); !ok {
+		t.Fatal("Wrong type")
+	}
+
+	// Every second lock is sampled, be it for reading or writing.
+
+	mut.RLock()
+	mut.RUnlock()
+	mut.RLock()
+	mut.RUnlock()
+	mut.Lock()
+	done := make(chan struct{})
+	go func() {
+		mut.Lock()
+		mut.Unlock()
+		close(done)
+	}()
+	time.Sleep(shortWait)
+	mut.Unlock()
+	<-done
+
+	rate, stats := LockProfile()
+	if rate != 2 {
+		t.Errorf("Rate %d != 2", rate)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("Expected stats for writing and reading, got %v", stats)
+	}
+	if !strings.HasPrefix(stats[0].Site, "sync/sync_test.go:") || stats[0].Read {
+		t.Errorf("Expected the write lock first, got %+v", stats[0])
+	}
+	if stats[0].Samples != 1 || stats[0].TotalWait < shortWait || stats[0].MaxWait != stats[0].TotalWait {
+		t.Errorf("Expected one sample of at least %v, got %+v", shortWait, stats[0])
+	}
+	if !stats[1].Read || stats[1].Samples != 1 {
+		t.Errorf("Expected one read sample, got %+v", stats[1])
+	}
+}
@@ -23,15 +23,17 @@ import (
 	"github.com/syncthing/syncthing/lib/config"
 	"github.com/syncthing/syncthing/lib/connections"
 	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/db/backend"
 	"github.com/syncthing/syncthing/lib/discover"
 	"github.com/syncthing/syncthing/lib/events"
 	"github.com/syncthing/syncthing/lib/locations"
 	"github.com/syncthing/syncthing/lib/logger"
 	"github.com/syncthing/syncthing/lib/model"
+	"github.com/syncthing/syncthing/lib/mount"
 	"github.com/syncthing/syncthing/lib/osutil"
 	"github.com/syncthing/syncthing/lib/protocol"
 	"github.com/syncthing/syncthing/lib/rand"
-	"github.com/syncthing/syncthing/lib/sha256"
+	"github.com/syncthing/syncthing/lib/scanner"
 	"github.com/syncthing/syncthing/lib/tlsutil"
 	"github.com/syncthing/syncthing/lib/ur"
 )
@@ -130,6 +132,8 @@ func (a *App) startup() error {
 		a.mainService.Add(newVerboseService(a.evLogger))
 	}
 
+	a.mainService.Add(newWakeService(a.evLogger))
+
 	errors := logger.NewRecorder(l, logger.LevelWarn, maxSystemErrors, 0)
 	systemLog := logger.NewRecorder(l, logger.LevelDebug, maxSystemLog, initialSystemLog)
 
@@ -138,6 +142,7 @@ func (a *App) startup() error {
 	// receiver in some situations so we will not subscribe to it here.
 	defaultSub := events.NewBufferedSubscription(a.evLogger.Subscribe(api.DefaultEventMask), api.EventSubBufferSize)
 	diskSub := events.NewBufferedSubscription(a.evLogger.Subscribe(api.DiskEventMask), api.EventSubBufferSize)
+	criticalSub := events.NewBufferedSubscription(a.evLogger.Subscribe(api.CriticalEventMask), api.EventSubBufferSize)
 
 	// Attempt to increase the limit on number of open files to the maximum
 	// allowed, in case we have many peers. We don't really care enough to
@@ -149,11 +154,6 @@ func (a *App) startup() error {
 	l.SetPrefix(fmt.Sprintf("[%s] ", a.myID.String()[:5]))
 	l.Infoln("My ID:", a.myID)
 
-	// Select SHA256 implementation and report. Affected by the
-	// STHASHING environment variable.
-	sha256.SelectAlgo()
-	sha256.Report()
-
 	// Emit the Starting event, now that we know who we are.
 
 	a.evLogger.Log(events.Starting, map[string]string{
@@ -166,6 +166,10 @@ func (a *App) startup() error {
 		return err
 	}
 
+	// Select the hashing backends, benchmarking them on the first run, and
+	// report. Affected by the STHASHING environment variable.
+	scanner.SelectHashBackends(db.NewMiscDataNamespace(a.ll))
+
 	if len(a.opts.ProfilerURL) > 0 {
 		go func() {
 			l.Debugln("Starting profiler on", a.opts.ProfilerURL)
@@ -183,6 +187,9 @@ func (a *App) startup() error {
 
 	if err := db.UpdateSchema(a.ll); err != nil {
 		l.Warnln("Database schema:", err)
+		a.evLogger.Log(events.DatabaseError, map[string]interface{}{
+			"error": err.Error(),
+		})
 		return err
 	}
 
@@ -191,12 +198,11 @@ func (a *App) startup() error {
 		db.DropDeltaIndexIDs(a.ll)
 	}
 
-	protectedFiles := []string{
-		locations.Get(locations.Database),
+	protectedFiles := append(backend.Locations(locations.Get(locations.Database)),
 		locations.Get(locations.ConfigFile),
 		locations.Get(locations.CertFile),
 		locations.Get(locations.KeyFile),
-	}
+	)
 
 	// Remove database entries for folders that no longer exist in the config
 	folders := a.cfg.Folders()
@@ -213,6 +219,9 @@ func (a *App) startup() error {
 	prevVersion, _, err := miscDB.String("prevVersion")
 	if err != nil {
 		l.Warnln("Database:", err)
+		a.evLogger.Log(events.DatabaseError, map[string]interface{}{
+			"error": err.Error(),
+		})
 		return err
 	}
 
@@ -245,6 +254,10 @@ func (a *App) startup() error {
 
 	a.mainService.Add(m)
 
+	// Folders mounted as FUSE filesystems
+
+	a.mainService.Add(mount.New(a.cfg, m))
+
 	// Start discovery
 
 	cachedDiscovery := discover.NewCachingMux()
@@ -262,7 +275,7 @@ func (a *App) startup() error {
 
 	// Start connection management
 
-	connectionsService := connections.NewService(a.cfg, a.myID, m, tlsCfg, cachedDiscovery, bepProtocolName, tlsDefaultCommonName, a.evLogger)
+	connectionsService := connections.NewService(a.cfg, a.myID, m, tlsCfg, cachedDiscovery, discover.NewAddressCache(a.ll), bepProtocolName, tlsDefaultCommonName, a.evLogger)
 	a.mainService.Add(connectionsService)
 
 	if a.cfg.Options().GlobalAnnEnabled {
@@ -322,7 +335,7 @@ func (a *App) startup() error {
 
 	// GUI
 
-	if err := a.setupGUI(m, defaultSub, diskSub, cachedDiscovery, connectionsService, usageReportingSvc, errors, systemLog); err != nil {
+	if err := a.setupGUI(m, defaultSub, diskSub, criticalSub, cachedDiscovery, connectionsService, usageReportingSvc, errors, systemLog); err != nil {
 		l.Warnln("Failed starting API:", err)
 		return err
 	}
@@ -406,7 +419,7 @@ func (a *App) stopWithErr(stopReason ExitStatus, err error) ExitStatus {
 	return a.exitStatus
 }
 
-func (a *App) setupGUI(m model.Model, defaultSub, diskSub events.BufferedSubscription, discoverer discover.CachingMux, connectionsService connections.Service, urService *ur.Service, errors, systemLog logger.Recorder) error {
+func (a *App) setupGUI(m model.Model, defaultSub, diskSub, criticalSub events.BufferedSubscription, discoverer discover.CachingMux, connectionsService connections.Service, urService *ur.Service, errors, systemLog logger.Recorder) error {
 	guiCfg := a.cfg.GUI()
 
 	if !guiCfg.Enabled {
@@ -423,7 +436,7 @@ func (a *App) setupGUI(m model.Model, defaultSub, diskSub events.BufferedSubscri
 	summaryService := model.NewFolderSummaryService(a.cfg, m, a.myID, a.evLogger)
 	a.mainService.Add(summaryService)
 
-	apiSvc := api.New(a.myID, a.cfg, a.opts.AssetDir, tlsDefaultCommonName, m, defaultSub, diskSub, a.evLogger, discoverer, connectionsService, urService, summaryService, errors, systemLog, cpu, &controller{a}, a.opts.NoUpgrade)
+	apiSvc := api.New(a.myID, a.cfg, a.opts.AssetDir, tlsDefaultCommonName, m, defaultSub, diskSub, criticalSub, a.evLogger, discoverer, connectionsService, urService, summaryService, errors, systemLog, cpu, &controller{a}, a.opts.NoUpgrade)
 	a.mainService.Add(apiSvc)
 
 	if err := apiSvc.WaitForStart(); err != nil {
@@ -131,3 +131,13 @@ func OpenGoleveldb(path string, tuning config.Tuning) (*db.Lowlevel, error) {
 	}
 	return db.NewLowlevel(ldb), nil
 }
+
+// OpenDatabase opens the database of the given backend based at path,
+// migrating the contents of a database of another backend there if need be.
+func OpenDatabase(path string, be config.Backend, tuning config.Tuning) (*db.Lowlevel, error) {
+	ldb, err := backend.OpenMigrating(path, backend.Type(be), backend.Tuning(tuning))
+	if err != nil {
+		return nil, err
+	}
+	return db.NewLowlevel(ldb), nil
+}
@@ -115,6 +115,49 @@ func (s *verboseService) formatEvent(ev events.Event) string {
 		}
 		return fmt.Sprintf("Finished syncing %q / %q (%v %v): Success", data["folder"], data["item"], data["action"], data["type"])
 
+	case events.ItemVerified:
+		data := ev.Data.(map[string]interface{})
+		if err, ok := data["error"].(*string); ok && err != nil {
+			return fmt.Sprintf("Verification of %q / %q failed: %v", data["folder"], data["item"], *err)
+		}
+		return fmt.Sprintf("Verified %q / %q", data["folder"], data["item"])
+
+	case events.LocalCorruptionDetected:
+		data := ev.Data.(map[string]interface{})
+		return fmt.Sprintf("Detected corruption in %v blocks of %q / %q (repaired: %v)", data["blocks"], data["folder"], data["item"], data["repaired"])
+
+	case events.FolderStuck:
+		data := ev.Data.(map[string]interface{})
+		return fmt.Sprintf("Folder %q was stuck %s for %vs and has been restarted (diagnostics in %v)", data["folder"], data["state"], data["duration"], data["diagnostics"])
+
+	case events.FolderStopped:
+		data := ev.Data.(map[string]interface{})
+		return fmt.Sprintf("Folder %q stopped: %v", data["folder"], data["error"])
+
+	case events.DatabaseError:
+		data := ev.Data.(map[string]interface{})
+		return fmt.Sprintf("Database error: %v", data["error"])
+
+	case events.FolderScanSummary:
+		data := ev.Data.(map[string]interface{})
+		return fmt.Sprintf("Scan of folder %q found %v added, %v modified, %v deleted and %v metadata only changes", data["folder"], data["added"], data["modified"], data["deleted"], data["metadata"])
+
+	case events.ApplicationMessageReceived:
+		data := ev.Data.(map[string]interface{})
+		return fmt.Sprintf("Application message %q from device %v (%d bytes)", data["type"], data["device"], len(data["data"].([]byte)))
+
+	case events.FolderSettingsPending:
+		data := ev.Data.(map[string]string)
+		return fmt.Sprintf("Device %v pushed new settings for folder %q, to be accepted", data["device"], data["folder"])
+
+	case events.DeviceDecommissionPending:
+		data := ev.Data.(map[string]string)
+		return fmt.Sprintf("Device %v suggests decommissioning device %v, to be accepted", data["suggestedBy"], data["device"])
+
+	case events.SystemWoke:
+		data := ev.Data.(map[string]interface{})
+		return fmt.Sprintf("System woke after sleeping for about %vs", data["sleptS"])
+
 	case events.ConfigSaved:
 		return "Configuration was saved"
 
@@ -143,6 +186,9 @@ func (s *verboseService) formatEvent(ev events.Event) string {
 		if total > 0 {
 			pct = 100 * current / total
 		}
+		if file, _ := data["file"].(string); file != "" {
+			return fmt.Sprintf("Scanning folder %q, %d%% done (%.01f MiB/s), hashing %q", folder, pct, rate, file)
+		}
 		return fmt.Sprintf("Scanning folder %q, %d%% done (%.01f MiB/s)", folder, pct, rate)
 
 	case events.DevicePaused:
@@ -0,0 +1,81 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package syncthing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/thejerf/suture"
+
+	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/util"
+)
+
+const (
+	wakeCheckInterval = 5 * time.Second
+	// Gaps shorter than this are not worth tearing connections down for;
+	// they survive them anyway.
+	wakeMinSleep = 30 * time.Second
+)
+
+// The wake service notices when the system comes back from sleep or
+// hibernation and emits a SystemWoke event, so that connections,
+// discovery and folders can be revalidated right away instead of at the
+// next timeout.
+type wakeService struct {
+	suture.Service
+	evLogger events.Logger
+}
+
+func newWakeService(evLogger events.Logger) *wakeService {
+	s := &wakeService{
+		evLogger: evLogger,
+	}
+	s.Service = util.AsService(s.serve, s.String())
+	return s
+}
+
+func (s *wakeService) serve(ctx context.Context) {
+	ticker := time.NewTicker(wakeCheckInterval)
+	defer ticker.Stop()
+
+	last := time.Now()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		now := time.Now()
+		if slept := sleptFor(last, now, wakeCheckInterval); slept >= wakeMinSleep {
+			l.Infof("System woke after sleeping for about %v", slept.Truncate(time.Second))
+			s.evLogger.Log(events.SystemWoke, map[string]interface{}{
+				"sleptS": int(slept.Seconds()),
+			})
+		}
+		last = now
+	}
+}
+
+func (s *wakeService) String() string {
+	return fmt.Sprintf("wakeService@%p", s)
+}
+
+// sleptFor returns how much longer than the interval passed between two
+// consecutive checks. Depending on the OS the monotonic clock either stops
+// during sleep, in which case only the wall clock shows the gap, or keeps
+// running, in which case timers can't fire and both clocks show it.
+func sleptFor(last, now time.Time, interval time.Duration) time.Duration {
+	gap := now.Sub(last)
+	if wall := now.Round(0).Sub(last.Round(0)); wall > gap {
+		gap = wall
+	}
+	return gap - interval
+}
@@ -0,0 +1,32 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package syncthing
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSleptFor(t *testing.T) {
+	last := time.Now()
+	wall := last.Round(0)
+
+	cases := []struct {
+		name      string
+		last, now time.Time
+		slept     time.Duration
+	}{
+		{"on time", last, last.Add(wakeCheckInterval), 0},
+		{"both clocks jumped", last, last.Add(time.Minute + wakeCheckInterval), time.Minute},
+		{"wall clock jumped", wall, wall.Add(time.Minute + wakeCheckInterval), time.Minute},
+	}
+	for _, tc := range cases {
+		if slept := sleptFor(tc.last, tc.now, wakeCheckInterval); slept != tc.slept {
+			t.Errorf("%s: slept for %v, expected %v", tc.name, slept, tc.slept)
+		}
+	}
+}
@@ -36,6 +36,30 @@ type Asset struct {
 	BrowserURL string `json:"browser_download_url"`
 }
 
+// Verification describes what it takes for a downloaded release to be
+// accepted.
+type Verification struct {
+	// PEM encoded public keys trusted to sign releases. The built in
+	// SigningKey is trusted when there are none.
+	SigningKeys [][]byte
+	// The number of different trusted keys that must have signed the
+	// release. At least one signature is always required.
+	MinSignatures int
+	// If set, the release must also be listed in this transparency log,
+	// so that a release signed with stolen keys is still refused unless it
+	// has been published where it would be noticed. The log is queried
+	// with GET <url>?name=<archive name> and answers with a JSON list of
+	// entries, each with the archive name and the hex encoded SHA-256 of
+	// the signed data (the archive name, a newline and the binary).
+	TransparencyLogURL string
+}
+
+// A TransparencyLogEntry is an entry returned by a transparency log.
+type TransparencyLogEntry struct {
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256"`
+}
+
 var (
 	ErrNoReleaseDownload  = errors.New("couldn't find a release to download")
 	ErrNoVersionToSelect  = errors.New("no version to select")
@@ -48,7 +72,7 @@ func init() {
 	upgradeUnlocked <- true
 }
 
-func To(rel Release) error {
+func To(rel Release, verify Verification) error {
 	select {
 	case <-upgradeUnlocked:
 		path, err := os.Executable()
@@ -56,7 +80,7 @@ func To(rel Release) error {
 			upgradeUnlocked <- true
 			return err
 		}
-		err = upgradeTo(path, rel)
+		err = upgradeTo(path, rel, verify)
 		// If we've failed to upgrade, unlock so that another attempt could be made
 		if err != nil {
 			upgradeUnlocked <- true
@@ -67,7 +91,7 @@ func To(rel Release) error {
 	}
 }
 
-func ToURL(url string) error {
+func ToURL(url string, verify Verification) error {
 	select {
 	case <-upgradeUnlocked:
 		binary, err := os.Executable()
@@ -75,7 +99,7 @@ func ToURL(url string) error {
 			upgradeUnlocked <- true
 			return err
 		}
-		err = upgradeToURL(path.Base(url), binary, url)
+		err = upgradeToURL(path.Base(url), binary, url, verify)
 		// If we've failed to upgrade, unlock so that another attempt could be made
 		if err != nil {
 			upgradeUnlocked <- true
@@ -19,6 +19,7 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"os"
 	"path"
 	"path/filepath"
@@ -28,6 +29,7 @@ import (
 	"time"
 
 	"github.com/syncthing/syncthing/lib/dialer"
+	"github.com/syncthing/syncthing/lib/sha256"
 	"github.com/syncthing/syncthing/lib/signature"
 )
 
@@ -58,6 +60,17 @@ const (
 	maxMetadataSize = 10 << 20 // 10 MiB
 )
 
+// The transparency log is what protects against a release signed with
+// stolen keys, so unlike the download itself it must come from where we
+// think it does.
+var secureHTTP = &http.Client{
+	Timeout: readTimeout,
+	Transport: &http.Transport{
+		DialContext: dialer.DialContext,
+		Proxy:       http.ProxyFromEnvironment,
+	},
+}
+
 // This is an HTTP/HTTPS client that does *not* perform certificate
 // validation. We do this because some systems where Syncthing runs have
 // issues with old or missing CA roots. It doesn't actually matter that we
@@ -171,7 +184,7 @@ func SelectLatestRelease(rels []Release, current string, upgradeToPreReleases bo
 }
 
 // Upgrade to the given release, saving the previous binary with a ".old" extension.
-func upgradeTo(binary string, rel Release) error {
+func upgradeTo(binary string, rel Release, verify Verification) error {
 	expectedReleases := releaseNames(rel.Tag)
 	for _, asset := range rel.Assets {
 		assetName := path.Base(asset.Name)
@@ -179,7 +192,7 @@ func upgradeTo(binary string, rel Release) error {
 
 		for _, expRel := range expectedReleases {
 			if strings.HasPrefix(assetName, expRel) {
-				return upgradeToURL(assetName, binary, asset.URL)
+				return upgradeToURL(assetName, binary, asset.URL, verify)
 			}
 		}
 	}
@@ -188,8 +201,8 @@ func upgradeTo(binary string, rel Release) error {
 }
 
 // Upgrade to the given release, saving the previous binary with a ".old" extension.
-func upgradeToURL(archiveName, binary string, url string) error {
-	fname, err := readRelease(archiveName, filepath.Dir(binary), url)
+func upgradeToURL(archiveName, binary string, url string, verify Verification) error {
+	fname, err := readRelease(archiveName, filepath.Dir(binary), url, verify)
 	if err != nil {
 		return err
 	}
@@ -208,7 +221,7 @@ func upgradeToURL(archiveName, binary string, url string) error {
 	return nil
 }
 
-func readRelease(archiveName, dir, url string) (string, error) {
+func readRelease(archiveName, dir, url string, verify Verification) (string, error) {
 	l.Debugf("loading %q", url)
 
 	req, err := http.NewRequest("GET", url, nil)
@@ -225,13 +238,13 @@ func readRelease(archiveName, dir, url string) (string, error) {
 
 	switch runtime.GOOS {
 	case "windows":
-		return readZip(archiveName, dir, io.LimitReader(resp.Body, maxArchiveSize))
+		return readZip(archiveName, dir, io.LimitReader(resp.Body, maxArchiveSize), verify)
 	default:
-		return readTarGz(archiveName, dir, io.LimitReader(resp.Body, maxArchiveSize))
+		return readTarGz(archiveName, dir, io.LimitReader(resp.Body, maxArchiveSize), verify)
 	}
 }
 
-func readTarGz(archiveName, dir string, r io.Reader) (string, error) {
+func readTarGz(archiveName, dir string, r io.Reader, verify Verification) (string, error) {
 	gr, err := gzip.NewReader(r)
 	if err != nil {
 		return "", err
@@ -274,14 +287,14 @@ func readTarGz(archiveName, dir string, r io.Reader) (string, error) {
 		}
 	}
 
-	if err := verifyUpgrade(archiveName, tempName, sig); err != nil {
+	if err := verifyUpgrade(archiveName, tempName, sig, verify); err != nil {
 		return "", err
 	}
 
 	return tempName, nil
 }
 
-func readZip(archiveName, dir string, r io.Reader) (string, error) {
+func readZip(archiveName, dir string, r io.Reader, verify Verification) (string, error) {
 	body, err := ioutil.ReadAll(r)
 	if err != nil {
 		return "", err
@@ -325,7 +338,7 @@ func readZip(archiveName, dir string, r io.Reader) (string, error) {
 		}
 	}
 
-	if err := verifyUpgrade(archiveName, tempName, sig); err != nil {
+	if err := verifyUpgrade(archiveName, tempName, sig, verify); err != nil {
 		return "", err
 	}
 
@@ -364,7 +377,7 @@ func archiveFileVisitor(dir string, tempFile *string, signature *[]byte, archive
 	return nil
 }
 
-func verifyUpgrade(archiveName, tempName string, sig []byte) error {
+func verifyUpgrade(archiveName, tempName string, sig []byte, verify Verification) error {
 	if tempName == "" {
 		return fmt.Errorf("no upgrade found")
 	}
@@ -390,9 +403,30 @@ func verifyUpgrade(archiveName, tempName string, sig []byte) error {
 	// multireader. This ensures that it is not only a bonafide syncthing
 	// binary, but it is also of exactly the platform and version we expect.
 
+	//
+	// The release may carry several signatures, concatenated in the
+	// signature file, and we may require more than one of them to be from
+	// a trusted key.
+
+	keys := verify.SigningKeys
+	if len(keys) == 0 {
+		keys = [][]byte{SigningKey}
+	}
+	minSigs := verify.MinSignatures
+	if minSigs < 1 {
+		minSigs = 1
+	}
+
+	h := sha256.New()
 	mr := io.MultiReader(bytes.NewBufferString(archiveName+"\n"), fd)
-	err = signature.Verify(SigningKey, sig, mr)
+	valid, err := signature.CountValid(keys, sig, io.TeeReader(mr, h))
 	fd.Close()
+	if err == nil && valid < minSigs {
+		err = fmt.Errorf("release has %d valid signatures, %d required", valid, minSigs)
+	}
+	if err == nil && verify.TransparencyLogURL != "" {
+		err = checkTransparencyLog(verify.TransparencyLogURL, archiveName, fmt.Sprintf("%x", h.Sum(nil)))
+	}
 
 	if err != nil {
 		os.Remove(tempName)
@@ -402,6 +436,40 @@ func verifyUpgrade(archiveName, tempName string, sig []byte) error {
 	return nil
 }
 
+// checkTransparencyLog returns nil if the log has an entry for the archive
+// with the given hash of the signed data.
+func checkTransparencyLog(logURL, archiveName, hash string) error {
+	u, err := url.Parse(logURL)
+	if err != nil {
+		return err
+	}
+	q := u.Query()
+	q.Set("name", archiveName)
+	u.RawQuery = q.Encode()
+
+	l.Debugf("checking transparency log %s", u)
+
+	resp, err := secureHTTP.Get(u.String())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("transparency log: %s", resp.Status)
+	}
+
+	var entries []TransparencyLogEntry
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxMetadataSize)).Decode(&entries); err != nil {
+		return fmt.Errorf("transparency log: %v", err)
+	}
+	for _, entry := range entries {
+		if entry.Name == archiveName && strings.EqualFold(entry.SHA256, hash) {
+			return nil
+		}
+	}
+	return fmt.Errorf("release %s is not in the transparency log", archiveName)
+}
+
 func writeBinary(dir string, inFile io.Reader) (filename string, err error) {
 	// Write the binary to a temporary file.
 
@@ -9,10 +9,20 @@
 package upgrade
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"testing"
+
+	"github.com/syncthing/syncthing/lib/sha256"
+	"github.com/syncthing/syncthing/lib/signature"
 )
 
 var versions = []struct {
@@ -154,3 +164,81 @@ func TestSelectedReleaseMacOS(t *testing.T) {
 		}
 	}
 }
+
+func TestVerifyUpgrade(t *testing.T) {
+	dir, err := ioutil.TempDir("", "syncthing-upgrade-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	const archiveName = "syncthing-linux-amd64-v1.2.3.tar.gz"
+	binary := []byte("a new and improved binary")
+	signed := append([]byte(archiveName+"\n"), binary...)
+
+	var pubs, sigs [][]byte
+	for i := 0; i < 2; i++ {
+		priv, pub, err := signature.GenerateKeys()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, err := signature.Sign(priv, bytes.NewReader(signed))
+		if err != nil {
+			t.Fatal(err)
+		}
+		pubs = append(pubs, pub)
+		sigs = append(sigs, sig)
+	}
+	bothSigs := bytes.Join(sigs, nil)
+
+	logged := fmt.Sprintf("%x", sha256.Sum256(signed))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var entries []TransparencyLogEntry
+		if r.URL.Query().Get("name") == archiveName {
+			entries = append(entries, TransparencyLogEntry{Name: archiveName, SHA256: logged})
+		}
+		json.NewEncoder(w).Encode(entries)
+	}))
+	defer srv.Close()
+
+	cases := []struct {
+		sig    []byte
+		verify Verification
+		ok     bool
+	}{
+		{sigs[0], Verification{SigningKeys: pubs}, true},
+		{sigs[0], Verification{SigningKeys: pubs[1:]}, false},
+		{sigs[0], Verification{SigningKeys: pubs, MinSignatures: 2}, false},
+		{bothSigs, Verification{SigningKeys: pubs, MinSignatures: 2}, true},
+		{bytes.Join([][]byte{sigs[0], sigs[0]}, nil), Verification{SigningKeys: pubs, MinSignatures: 2}, false},
+		{sigs[0], Verification{}, false}, // the built in key didn't sign it
+		{sigs[0], Verification{SigningKeys: pubs, TransparencyLogURL: srv.URL}, true},
+	}
+
+	for i, tc := range cases {
+		tempName := filepath.Join(dir, "binary")
+		if err := ioutil.WriteFile(tempName, binary, 0644); err != nil {
+			t.Fatal(err)
+		}
+		err := verifyUpgrade(archiveName, tempName, tc.sig, tc.verify)
+		if tc.ok && err != nil {
+			t.Errorf("%d: unexpected error: %v", i, err)
+		} else if !tc.ok && err == nil {
+			t.Errorf("%d: unexpected success", i)
+		}
+		if _, err := os.Stat(tempName); tc.ok == os.IsNotExist(err) {
+			t.Errorf("%d: binary should be removed only on failure", i)
+		}
+	}
+
+	// A release that isn't in the log is refused.
+
+	logged = "0000"
+	tempName := filepath.Join(dir, "binary")
+	if err := ioutil.WriteFile(tempName, binary, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyUpgrade(archiveName, tempName, sigs[0], Verification{SigningKeys: pubs, TransparencyLogURL: srv.URL}); err == nil {
+		t.Error("unexpected success for release missing from the log")
+	}
+}
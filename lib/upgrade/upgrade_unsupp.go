@@ -10,11 +10,11 @@ package upgrade
 
 const DisabledByCompilation = true
 
-func upgradeTo(binary string, rel Release) error {
+func upgradeTo(binary string, rel Release, verify Verification) error {
 	return ErrUpgradeUnsupported
 }
 
-func upgradeToURL(archiveName, binary, url string) error {
+func upgradeToURL(archiveName, binary, url string, verify Verification) error {
 	return ErrUpgradeUnsupported
 }
 
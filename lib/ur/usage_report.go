@@ -121,6 +121,7 @@ func (s *Service) reportData(urVersion int, preview bool) map[string]interface{}
 		"sendonly":            0,
 		"sendreceive":         0,
 		"receiveonly":         0,
+		"mirror":              0,
 		"ignorePerms":         0,
 		"ignoreDelete":        0,
 		"autoNormalize":       0,
@@ -139,6 +140,8 @@ func (s *Service) reportData(urVersion int, preview bool) map[string]interface{}
 			folderUses["sendreceive"]++
 		case config.FolderTypeReceiveOnly:
 			folderUses["receiveonly"]++
+		case config.FolderTypeMirror:
+			folderUses["mirror"]++
 		}
 		if cfg.IgnorePerms {
 			folderUses["ignorePerms"]++
@@ -242,6 +245,7 @@ func (s *Service) reportData(urVersion int, preview bool) map[string]interface{}
 		res["limitBandwidthInLan"] = opts.LimitBandwidthInLan
 		res["customReleaseURL"] = opts.ReleasesURL != "https://upgrades.syncthing.net/meta.json"
 		res["restartOnWakeup"] = opts.RestartOnWakeup
+		res["hashBackends"] = scanner.HashBackendResults()
 
 		folderUsesV3 := map[string]int{
 			"scanProgressDisabled":    0,
@@ -0,0 +1,59 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package ur
+
+import (
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/model"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+type fakeModel struct {
+	model.Model
+}
+
+func (fakeModel) GlobalSize(string) db.Counts {
+	return db.Counts{}
+}
+
+func (fakeModel) UsageReportingStats(int, bool) map[string]interface{} {
+	return nil
+}
+
+func TestFolderUses(t *testing.T) {
+	cfg := config.New(protocol.LocalDeviceID)
+	for id, folderType := range map[string]config.FolderType{
+		"sendreceive": config.FolderTypeSendReceive,
+		"sendonly":    config.FolderTypeSendOnly,
+		"receiveonly": config.FolderTypeReceiveOnly,
+		"mirror1":     config.FolderTypeMirror,
+		"mirror2":     config.FolderTypeMirror,
+	} {
+		fcfg := config.NewFolderConfiguration(protocol.LocalDeviceID, id, id, fs.FilesystemTypeBasic, id)
+		fcfg.Type = folderType
+		cfg.Folders = append(cfg.Folders, fcfg)
+	}
+	w := config.Wrap("/dev/null", cfg, events.NoopLogger)
+
+	res := New(w, fakeModel{}, nil, false).ReportDataPreview(2)
+	uses := res["folderUses"].(map[string]int)
+	for key, expected := range map[string]int{
+		"sendreceive": 1,
+		"sendonly":    1,
+		"receiveonly": 1,
+		"mirror":      2,
+	} {
+		if uses[key] != expected {
+			t.Errorf("expected %d %s folders, got %d", expected, key, uses[key])
+		}
+	}
+}
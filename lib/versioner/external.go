@@ -114,3 +114,7 @@ func (v external) GetVersions() (map[string][]FileVersion, error) {
 func (v external) Restore(filePath string, versionTime time.Time) error {
 	return ErrRestorationNotSupported
 }
+
+func (v external) Open(filePath string, versionTime time.Time) (fs.File, error) {
+	return nil, ErrRestorationNotSupported
+}
@@ -0,0 +1,174 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package versioner
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/thejerf/suture"
+
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/sync"
+	"github.com/syncthing/syncthing/lib/util"
+)
+
+func init() {
+	// Register the constructor for this type of versioner with the name "tiered"
+	factories["tiered"] = newTiered
+}
+
+// tiered keeps versions in grandfather-father-son fashion: all of them for
+// the first day, the latest of each hour for the rest of the first week,
+// of each day for the rest of the first month and of each week for the
+// rest of the first year, by default. Older versions are removed. The
+// tiers are set by the keepAllHours, hourlyDays, dailyDays and weeklyDays
+// parameters.
+type tiered struct {
+	suture.Service
+	cleanInterval time.Duration
+	folderFs      fs.Filesystem
+	versionsFs    fs.Filesystem
+	keepAll       time.Duration // keep all versions this young
+	hourly        time.Duration // then one per hour up to this age
+	daily         time.Duration // then one per day up to this age
+	weekly        time.Duration // then one per week up to this age, none after; zero for no limit
+	mutex         sync.Mutex
+}
+
+func newTiered(folderFs fs.Filesystem, params map[string]string) Versioner {
+	hours := func(key string, def int) time.Duration {
+		n, err := strconv.Atoi(params[key])
+		if err != nil || n < 0 {
+			n = def
+		}
+		return time.Duration(n) * time.Hour
+	}
+	cleanInterval, err := strconv.Atoi(params["cleanInterval"])
+	if err != nil || cleanInterval <= 0 {
+		cleanInterval = 3600 // Default: clean once per hour
+	}
+
+	v := &tiered{
+		cleanInterval: time.Duration(cleanInterval) * time.Second,
+		folderFs:      folderFs,
+		versionsFs:    fsFromParams(folderFs, params),
+		keepAll:       hours("keepAllHours", 24),
+		hourly:        hours("hourlyDays", 7) * 24,
+		daily:         hours("dailyDays", 30) * 24,
+		weekly:        hours("weeklyDays", 365) * 24,
+		mutex:         sync.NewMutex(),
+	}
+	v.Service = util.AsService(v.serve, v.String())
+
+	l.Debugf("instantiated %#v", v)
+	return v
+}
+
+func (v *tiered) serve(ctx context.Context) {
+	v.clean()
+
+	tck := time.NewTicker(v.cleanInterval)
+	defer tck.Stop()
+	for {
+		select {
+		case <-tck.C:
+			v.clean()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (v *tiered) clean() {
+	l.Debugln("Versioner clean: Waiting for lock on", v.versionsFs)
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	l.Debugln("Versioner clean: Cleaning", v.versionsFs)
+
+	cleanVersions(v.versionsFs, v.toRemove)
+}
+
+// toRemove returns the versions to remove: those older than the last tier,
+// and all but the latest in each hour, day or week of the tier they are in.
+func (v *tiered) toRemove(versions []string, now time.Time) []string {
+	var remove []string
+	kept := make(map[string]bool)
+
+	// Latest first, so that it's the latest of each period that's kept.
+	sort.Sort(sort.Reverse(sort.StringSlice(versions)))
+
+	for _, version := range versions {
+		versionTime, err := time.ParseInLocation(TimeFormat, extractTag(version), time.Local)
+		if err != nil {
+			l.Debugf("Versioner: file name %q is invalid: %v", version, err)
+			continue
+		}
+		age := now.Sub(versionTime)
+
+		var period string
+		switch {
+		case age < v.keepAll:
+			continue
+		case age < v.hourly:
+			period = "hourly " + versionTime.Format("2006010215")
+		case age < v.daily:
+			period = "daily " + versionTime.Format("20060102")
+		case age < v.weekly || v.weekly == 0:
+			year, week := versionTime.ISOWeek()
+			period = fmt.Sprintf("weekly %d-%d", year, week)
+		default:
+			l.Debugln("Versioner: File over maximum age -> delete ", version)
+			remove = append(remove, version)
+			continue
+		}
+
+		if kept[period] {
+			l.Debugln("Versioner: later version in the same", period, "-> delete", version)
+			remove = append(remove, version)
+			continue
+		}
+		kept[period] = true
+	}
+
+	return remove
+}
+
+// Archive moves the named file away to a version archive. If this function
+// returns nil, the named file does not exist any more (has been archived).
+func (v *tiered) Archive(filePath string) error {
+	l.Debugln("Waiting for lock on ", v.versionsFs)
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	if err := archiveFile(v.folderFs, v.versionsFs, filePath, TagFilename); err != nil {
+		return err
+	}
+
+	expireVersions(v.versionsFs, findAllVersions(v.versionsFs, filePath), v.toRemove)
+
+	return nil
+}
+
+func (v *tiered) GetVersions() (map[string][]FileVersion, error) {
+	return retrieveVersions(v.versionsFs)
+}
+
+func (v *tiered) Restore(filepath string, versionTime time.Time) error {
+	return restoreFile(v.versionsFs, v.folderFs, filepath, versionTime, TagFilename)
+}
+
+func (v *tiered) Open(filePath string, versionTime time.Time) (fs.File, error) {
+	return openVersion(v.versionsFs, filePath, versionTime, TagFilename)
+}
+
+func (v *tiered) String() string {
+	return fmt.Sprintf("Tiered/@%p", v)
+}
@@ -0,0 +1,53 @@
+// Copyright (C) 2020 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package versioner
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/d4l3k/messagediff"
+	"github.com/syncthing/syncthing/lib/fs"
+)
+
+func TestTieredVersioningVersionCount(t *testing.T) {
+	// Default settings: all versions for a day, hourly for a week, daily
+	// for a month and weekly for a year.
+
+	now := parseTime("20160415-140000")
+	versions := []string{
+		"test~20160415-135959", // 1 second ago
+		"test~20160415-120000", // 2 hours ago
+		"test~20160414-150000", // 23 hours ago
+		"test~20160413-105500", // 2 days ago
+		"test~20160413-103000", // same hour
+		"test~20160413-093000", // hour before
+		"test~20160401-180000", // 2 weeks ago
+		"test~20160401-090000", // same day
+		"test~20160331-090000", // day before
+		"test~20160101-120000", // Friday, 15 weeks ago
+		"test~20151231-120000", // Thursday, same week
+		"test~20151227-120000", // Sunday, week before
+		"test~20150101-120000", // over a year ago
+	}
+
+	delete := []string{
+		"test~20160413-103000",
+		"test~20160401-090000",
+		"test~20151231-120000",
+		"test~20150101-120000",
+	}
+	sort.Strings(delete)
+
+	v := newTiered(fs.NewFilesystem(fs.FilesystemTypeFake, "testdata"), map[string]string{}).(*tiered)
+	rem := v.toRemove(versions, now)
+	sort.Strings(rem)
+
+	if diff, equal := messagediff.PrettyDiff(delete, rem); !equal {
+		t.Errorf("Incorrect deleted files; got %v, expected %v\n%v", rem, delete, diff)
+	}
+}
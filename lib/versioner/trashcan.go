@@ -151,3 +151,7 @@ func (t *trashcan) Restore(filepath string, versionTime time.Time) error {
 
 	return t.versionsFs.Rename(taggedName, filepath)
 }
+
+func (t *trashcan) Open(filePath string, versionTime time.Time) (fs.File, error) {
+	return openVersion(t.versionsFs, filePath, versionTime, TagFilename)
+}
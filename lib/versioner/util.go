@@ -183,6 +183,39 @@ func archiveFile(srcFs, dstFs fs.Filesystem, filePath string, tagger fileTagger)
 	return err
 }
 
+// findVersion returns the name and modification time of the version of
+// the file with the given version time, looking for the tagged name first.
+func findVersion(versionsFs fs.Filesystem, filePath, taggedFilePath string, versionTime time.Time) (string, time.Time, error) {
+	// Try and find a file that has the correct mtime
+	if info, err := versionsFs.Lstat(taggedFilePath); err == nil && info.IsRegular() {
+		return taggedFilePath, info.ModTime(), nil
+	} else if err == nil {
+		l.Debugln("restore:", taggedFilePath, "not regular")
+	} else {
+		l.Debugln("restore:", taggedFilePath, err.Error())
+	}
+
+	// Check for untagged file
+	info, err := versionsFs.Lstat(filePath)
+	if err == nil && info.IsRegular() && info.ModTime().Truncate(time.Second).Equal(versionTime) {
+		return filePath, info.ModTime(), nil
+	}
+
+	return "", time.Time{}, errNotFound
+}
+
+// openVersion opens the version of the file with the given version time
+// for reading.
+func openVersion(versionsFs fs.Filesystem, filePath string, versionTime time.Time, tagger fileTagger) (fs.File, error) {
+	tag := versionTime.In(time.Local).Truncate(time.Second).Format(TimeFormat)
+	filePath = osutil.NativeFilename(filePath)
+	name, _, err := findVersion(versionsFs, filePath, tagger(filePath, tag), versionTime)
+	if err != nil {
+		return nil, err
+	}
+	return versionsFs.Open(name)
+}
+
 func restoreFile(src, dst fs.Filesystem, filePath string, versionTime time.Time, tagger fileTagger) error {
 	tag := versionTime.In(time.Local).Truncate(time.Second).Format(TimeFormat)
 	taggedFilePath := tagger(filePath, tag)
@@ -211,30 +244,9 @@ func restoreFile(src, dst fs.Filesystem, filePath string, versionTime time.Time,
 
 	filePath = osutil.NativeFilename(filePath)
 
-	// Try and find a file that has the correct mtime
-	sourceFile := ""
-	sourceMtime := time.Time{}
-	if info, err := src.Lstat(taggedFilePath); err == nil && info.IsRegular() {
-		sourceFile = taggedFilePath
-		sourceMtime = info.ModTime()
-	} else if err == nil {
-		l.Debugln("restore:", taggedFilePath, "not regular")
-	} else {
-		l.Debugln("restore:", taggedFilePath, err.Error())
-	}
-
-	// Check for untagged file
-	if sourceFile == "" {
-		info, err := src.Lstat(filePath)
-		if err == nil && info.IsRegular() && info.ModTime().Truncate(time.Second).Equal(versionTime) {
-			sourceFile = filePath
-			sourceMtime = info.ModTime()
-		}
-
-	}
-
-	if sourceFile == "" {
-		return errNotFound
+	sourceFile, sourceMtime, err := findVersion(src, filePath, taggedFilePath, versionTime)
+	if err != nil {
+		return err
 	}
 
 	// Check that the target location of where we are supposed to restore does not exist.
@@ -246,7 +258,7 @@ func restoreFile(src, dst fs.Filesystem, filePath string, versionTime time.Time,
 	}
 
 	_ = dst.MkdirAll(filepath.Dir(filePath), 0755)
-	err := osutil.RenameOrCopy(src, dst, sourceFile, filePath)
+	err = osutil.RenameOrCopy(src, dst, sourceFile, filePath)
 	_ = dst.Chtimes(filePath, sourceMtime, sourceMtime)
 	return err
 }
@@ -288,3 +300,70 @@ func findAllVersions(fs fs.Filesystem, filePath string) []string {
 
 	return versions
 }
+
+// cleanVersions goes through the versions of all files, removing those the
+// toRemove function picks for each file, and then the directories left
+// empty.
+func cleanVersions(versionsFs fs.Filesystem, toRemove func(versions []string, now time.Time) []string) {
+	if _, err := versionsFs.Stat("."); fs.IsNotExist(err) {
+		// There is no need to clean a nonexistent dir.
+		return
+	}
+
+	versionsPerFile := make(map[string][]string)
+	dirTracker := make(emptyDirTracker)
+
+	walkFn := func(path string, f fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if f.IsDir() && !f.IsSymlink() {
+			dirTracker.addDir(path)
+			return nil
+		}
+
+		// Regular file, or possibly a symlink.
+		dirTracker.addFile(path)
+
+		name, _ := UntagFilename(path)
+		if name == "" {
+			return nil
+		}
+
+		versionsPerFile[name] = append(versionsPerFile[name], path)
+
+		return nil
+	}
+
+	if err := versionsFs.Walk(".", walkFn); err != nil {
+		l.Warnln("Versioner: error scanning versions dir", err)
+		return
+	}
+
+	for _, versionList := range versionsPerFile {
+		expireVersions(versionsFs, versionList, toRemove)
+	}
+
+	dirTracker.deleteEmptyDirs(versionsFs)
+
+	l.Debugln("Cleaner: Finished cleaning", versionsFs)
+}
+
+// expireVersions removes the versions of a file the toRemove function picks.
+func expireVersions(versionsFs fs.Filesystem, versions []string, toRemove func(versions []string, now time.Time) []string) {
+	l.Debugln("Versioner: Expiring versions", versions)
+	for _, file := range toRemove(versions, time.Now()) {
+		if fi, err := versionsFs.Lstat(file); err != nil {
+			l.Warnln("versioner:", err)
+			continue
+		} else if fi.IsDir() {
+			l.Infof("non-file %q is named like a file version", file)
+			continue
+		}
+
+		if err := versionsFs.Remove(file); err != nil {
+			l.Warnf("Versioner: can't remove %q: %v", file, err)
+		}
+	}
+}
@@ -20,6 +20,9 @@ type Versioner interface {
 	Archive(filePath string) error
 	GetVersions() (map[string][]FileVersion, error)
 	Restore(filePath string, versionTime time.Time) error
+	// Open opens the version of the file with the given version time for
+	// reading, leaving it in place.
+	Open(filePath string, versionTime time.Time) (fs.File, error)
 }
 
 type FileVersion struct {
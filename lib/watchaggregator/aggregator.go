@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/syncthing/syncthing/lib/config"
@@ -22,8 +23,36 @@ import (
 var (
 	maxFiles       = 512
 	maxFilesPerDir = 128
+	// Events arriving at this rate or faster are a burst.
+	burstEvents = 1000
+	burstWindow = time.Second
+	// During a burst, events are delayed this many times longer.
+	burstDelayFactor time.Duration = 4
 )
 
+// Mode is how events are currently aggregated.
+type Mode int32
+
+const (
+	ModeNormal Mode = iota
+	// Events arrive in a burst, such as during a checkout of a large
+	// repository. They are tracked by their parent directories rather
+	// than one by one, and delayed for longer, so that the burst is scanned
+	// in one go once it is over.
+	ModeBurst
+)
+
+func (m Mode) String() string {
+	switch m {
+	case ModeNormal:
+		return "normal"
+	case ModeBurst:
+		return "burst"
+	default:
+		return "unknown"
+	}
+}
+
 // aggregatedEvent represents potentially multiple events at and/or recursively
 // below one path until it times out and a scan is scheduled.
 // If it represents multiple events and there are events of both Remove and
@@ -107,6 +136,13 @@ type aggregator struct {
 	counts                map[fs.EventType]int
 	root                  *eventDir
 	ctx                   context.Context
+	evLogger              events.Logger
+	// The number of events in the current burstWindow, and until when the
+	// aggregator is in burst mode.
+	windowStart  time.Time
+	windowEvents int
+	burstUntil   time.Time
+	mode         int32 // Mode, accessed atomically
 }
 
 func newAggregator(ctx context.Context, folderCfg config.FolderConfiguration) *aggregator {
@@ -118,6 +154,7 @@ func newAggregator(ctx context.Context, folderCfg config.FolderConfiguration) *a
 		counts:                make(map[fs.EventType]int),
 		root:                  newEventDir(),
 		ctx:                   ctx,
+		evLogger:              events.NoopLogger,
 	}
 
 	a.updateConfig(folderCfg)
@@ -125,17 +162,23 @@ func newAggregator(ctx context.Context, folderCfg config.FolderConfiguration) *a
 	return a
 }
 
-func Aggregate(ctx context.Context, in <-chan fs.Event, out chan<- []string, folderCfg config.FolderConfiguration, cfg config.Wrapper, evLogger events.Logger) {
+// Aggregate aggregates the events until they are due for scanning and sends
+// their paths to out. It returns a function that reports the current mode.
+func Aggregate(ctx context.Context, in <-chan fs.Event, out chan<- []string, folderCfg config.FolderConfiguration, cfg config.Wrapper, evLogger events.Logger) func() Mode {
 	a := newAggregator(ctx, folderCfg)
 
 	// Necessary for unit tests where the backend is mocked
 	go a.mainLoop(in, out, cfg, evLogger)
+
+	return a.getMode
 }
 
 func (a *aggregator) mainLoop(in <-chan fs.Event, out chan<- []string, cfg config.Wrapper, evLogger events.Logger) {
 	a.notifyTimer = time.NewTimer(a.notifyDelay)
 	defer a.notifyTimer.Stop()
 
+	a.evLogger = evLogger
+
 	inProgressItemSubscription := evLogger.Subscribe(events.ItemStarted | events.ItemFinished)
 	defer inProgressItemSubscription.Unsubscribe()
 
@@ -164,18 +207,64 @@ func (a *aggregator) mainLoop(in <-chan fs.Event, out chan<- []string, cfg confi
 }
 
 func (a *aggregator) newEvent(event fs.Event, inProgress map[string]struct{}) {
+	if _, ok := inProgress[event.Name]; ok {
+		l.Debugln(a, "Skipping path we modified:", event.Name)
+		return
+	}
+	// Events are counted even when the entire folder is to be scanned, as
+	// a burst delays that too.
+	now := time.Now()
+	a.updateMode(now)
+	a.countEvent(now)
 	if _, ok := a.root.events["."]; ok {
 		l.Debugln(a, "Will scan entire folder anyway; dropping:", event.Name)
 		return
 	}
-	if _, ok := inProgress[event.Name]; ok {
-		l.Debugln(a, "Skipping path we modified:", event.Name)
+	a.aggregateEvent(event, now)
+}
+
+// countEvent keeps track of the rate of events, entering burst mode when
+// burstEvents arrive within burstWindow. Burst mode lasts until there have
+// been no such windows for the notify delay.
+func (a *aggregator) countEvent(evTime time.Time) {
+	if evTime.Sub(a.windowStart) >= burstWindow {
+		a.windowStart = evTime
+		a.windowEvents = 0
+	}
+	a.windowEvents++
+	if a.windowEvents >= burstEvents {
+		a.setMode(ModeBurst)
+		a.burstUntil = evTime.Add(a.notifyDelay)
+	}
+}
+
+// updateMode leaves burst mode once it's over.
+func (a *aggregator) updateMode(currTime time.Time) {
+	if currTime.After(a.burstUntil) {
+		a.setMode(ModeNormal)
+	}
+}
+
+func (a *aggregator) setMode(mode Mode) {
+	if Mode(atomic.SwapInt32(&a.mode, int32(mode))) == mode {
 		return
 	}
-	a.aggregateEvent(event, time.Now())
+	l.Debugf("%v Aggregation mode changed to %v", a, mode)
+	a.evLogger.Log(events.FolderWatchStateChanged, map[string]interface{}{
+		"folder":          a.folderID,
+		"aggregationMode": mode.String(),
+	})
+}
+
+func (a *aggregator) getMode() Mode {
+	return Mode(atomic.LoadInt32(&a.mode))
 }
 
 func (a *aggregator) aggregateEvent(event fs.Event, evTime time.Time) {
+	if a.getMode() == ModeBurst && event.Name != "." {
+		event.Name = filepath.Dir(event.Name)
+	}
+
 	if event.Name == "." || a.eventCount() == maxFiles {
 		l.Debugln(a, "Scan entire folder")
 		firstModTime := evTime
@@ -302,6 +391,7 @@ func (a *aggregator) resetNotifyTimer(duration time.Duration) {
 }
 
 func (a *aggregator) actOnTimer(out chan<- []string) {
+	a.updateMode(time.Now())
 	c := a.eventCount()
 	if c == 0 {
 		l.Debugln(a, "No tracked events, waiting for new event.")
@@ -390,7 +480,12 @@ func (a *aggregator) isOld(ev *aggregatedEvent, currTime time.Time, delayRem boo
 	// picking up a modification and scanning it. As scheduling scans happens at
 	// regular intervals of a.notifyDelay the delay of a single event is not exactly
 	// a.notifyDelay, but lies in the range of 0.5 to 1.5 times a.notifyDelay.
-	if (!delayRem || ev.evType == fs.NonRemove) && 2*currTime.Sub(ev.lastModTime) > a.notifyDelay {
+	delay, timeout := a.notifyDelay, a.notifyTimeout
+	if a.getMode() == ModeBurst {
+		delay *= burstDelayFactor
+		timeout *= burstDelayFactor
+	}
+	if (!delayRem || ev.evType == fs.NonRemove) && 2*currTime.Sub(ev.lastModTime) > delay {
 		return true
 	}
 	// When an event registers repeat modifications or involves removals it
@@ -398,7 +493,7 @@ func (a *aggregator) isOld(ev *aggregatedEvent, currTime time.Time, delayRem boo
 	// passed it is scanned anyway.
 	// If only removals are remaining to be scanned, there is no point to delay
 	// removals further, so this behaviour is overriden by delayRem == false.
-	return currTime.Sub(ev.firstModTime) > a.notifyTimeout
+	return currTime.Sub(ev.firstModTime) > timeout
 }
 
 func (a *aggregator) eventCount() int {
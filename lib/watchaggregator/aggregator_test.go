@@ -214,6 +214,61 @@ func TestDelay(t *testing.T) {
 	testScenario(t, "Delay", testCase, expectedBatches, nil)
 }
 
+// TestBurst checks that a burst of events is tracked by directory and delayed
+// for longer until it's over.
+func TestBurst(t *testing.T) {
+	oldBurstEvents := burstEvents
+	burstEvents = 4
+	defer func() { burstEvents = oldBurstEvents }()
+
+	inProgress := make(map[string]struct{})
+	folderCfg := defaultFolderCfg.Copy()
+	folderCfg.ID = "Burst"
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a := newAggregator(ctx, folderCfg)
+
+	for i := 0; i < burstEvents-1; i++ {
+		a.newEvent(fs.Event{
+			Name: filepath.Join("dir", strconv.Itoa(i)),
+			Type: fs.NonRemove,
+		}, inProgress)
+	}
+	if a.getMode() != ModeNormal {
+		t.Fatal("Expected normal mode before reaching the burst rate")
+	}
+
+	a.newEvent(fs.Event{
+		Name: filepath.Join("other", "sub", "file"),
+		Type: fs.NonRemove,
+	}, inProgress)
+	if a.getMode() != ModeBurst {
+		t.Fatal("Expected burst mode after reaching the burst rate")
+	}
+	compareBatchToExpectedDirect(t, getEventPaths(a.root, ".", a), []string{
+		filepath.Join("dir", "0"),
+		filepath.Join("dir", "1"),
+		filepath.Join("dir", "2"),
+		filepath.Join("other", "sub"),
+	})
+
+	// Events aren't due after the normal delay during a burst, but are
+	// once it's over.
+	ev := a.root.dirs["dir"].events["0"]
+	later := ev.lastModTime.Add(a.notifyDelay)
+	if a.isOld(ev, later, true) {
+		t.Error("Event is due during a burst")
+	}
+	later = a.burstUntil.Add(time.Millisecond)
+	a.updateMode(later)
+	if a.getMode() != ModeNormal {
+		t.Fatal("Expected normal mode after the burst")
+	}
+	if !a.isOld(ev, later, true) {
+		t.Error("Event isn't due after a burst")
+	}
+}
+
 // TestNoDelay checks that no delay occurs if there are no non-remove events
 func TestNoDelay(t *testing.T) {
 	mixed := "foo"